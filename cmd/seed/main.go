@@ -109,7 +109,7 @@ func createSwims(db *sql.DB, userID, numSwims, daysBack int) error {
 	// Create a local random number generator
 	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
 
-	stmt := `INSERT INTO swims (date, distance_m, assessment, user_id) VALUES ($1, $2, $3, $4)`
+	stmt := `INSERT INTO swims (date, distance_m, effort, feel, user_id) VALUES ($1, $2, $3, $4, $5)`
 
 	// Generate random swims
 	for i := 0; i < numSwims; i++ {
@@ -122,10 +122,11 @@ func createSwims(db *sql.DB, userID, numSwims, daysBack int) error {
 		distanceOptions := []int{500, 750, 1000, 1200, 1500, 1800, 2000, 2500, 3000, 3500, 4000}
 		distance := distanceOptions[rng.Intn(len(distanceOptions))]
 
-		// Random assessment (0-2 stars)
-		assessment := rng.Intn(3)
+		// Random effort (RPE 1-10) and feel (1-5 stars)
+		effort := rng.Intn(10) + 1
+		feel := rng.Intn(5) + 1
 
-		_, err := db.Exec(stmt, swimDate, distance, assessment, userID)
+		_, err := db.Exec(stmt, swimDate, distance, effort, feel, userID)
 		if err != nil {
 			return fmt.Errorf("failed to insert swim entry: %w", err)
 		}
@@ -0,0 +1,656 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/rockstaedt/swimmate/internal/models"
+	"github.com/rockstaedt/swimmate/ui"
+)
+
+const (
+	// apiPathPrefix starts the paths of the JSON API. Errors below it are
+	// answered with problem documents instead of plain text.
+	apiPathPrefix = "/api/"
+	// maxAPIBodySize limits request bodies of the API, a swim with long notes
+	// and many sets stays well below it.
+	maxAPIBodySize = 1 << 20
+	// apiDefaultLimit and apiMaxLimit are the default and largest number of
+	// swims of a list page.
+	apiDefaultLimit = 20
+	apiMaxLimit     = 100
+)
+
+// problem is an error response of the API as described in RFC 9457.
+type problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// apiSwim is a swim in requests and responses of the API. Like in backups,
+// distances are in meters and durations in seconds regardless of the unit of
+// the user. The id is ignored in requests.
+type apiSwim struct {
+	Id         int           `json:"id"`
+	Date       string        `json:"date"`
+	DistanceM  int           `json:"distance_m"`
+	Stroke     models.Stroke `json:"stroke"`
+	Feel       models.Feel   `json:"feel"`
+	Effort     models.Effort `json:"effort,omitempty"`
+	DurationS  int           `json:"duration_s,omitempty"`
+	PoolLength int           `json:"pool_length,omitempty"`
+	PoolUnit   models.Unit   `json:"pool_unit,omitempty"`
+	Laps       int           `json:"laps,omitempty"`
+	Notes      string        `json:"notes,omitempty"`
+	LocationId int           `json:"location_id,omitempty"`
+	Tags       []string      `json:"tags,omitempty"`
+	Sets       []apiSet      `json:"sets,omitempty"`
+}
+
+type apiSet struct {
+	Repetitions int           `json:"repetitions"`
+	DistanceM   int           `json:"distance_m"`
+	Stroke      models.Stroke `json:"stroke"`
+	IntervalS   int           `json:"interval_s,omitempty"`
+	RestS       int           `json:"rest_s,omitempty"`
+}
+
+// apiSwimPage is a page of a swim list. The next page is requested with the
+// cursor, which is left out on the last page.
+type apiSwimPage struct {
+	Swims      []apiSwim `json:"swims"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+}
+
+// swimCursor is the place of the last swim of a page in a swim list. The key
+// only has a meaning in the order it was taken from, so the cursor carries
+// the sort along. Clients only see it encoded.
+type swimCursor struct {
+	Sort      string         `json:"sort"`
+	Direction string         `json:"direction"`
+	After     models.SwimKey `json:"after"`
+}
+
+// apiFigures are the totals of a group of swims. The pace is the average time
+// per 100 m of the timed swims in seconds, the effort the average RPE of the
+// rated swims.
+type apiFigures struct {
+	Count     int     `json:"count"`
+	DistanceM int     `json:"distance_m"`
+	PaceS     int     `json:"pace_s,omitempty"`
+	Effort    float64 `json:"effort,omitempty"`
+}
+
+type apiSummary struct {
+	Total   apiFigures                   `json:"total"`
+	Month   apiFigures                   `json:"month"`
+	Week    apiFigures                   `json:"week"`
+	Years   []apiYear                    `json:"years"`
+	Strokes map[models.Stroke]apiFigures `json:"strokes"`
+}
+
+type apiYear struct {
+	Year int `json:"year"`
+	apiFigures
+	Months  []apiMonth                   `json:"months"`
+	Strokes map[models.Stroke]apiFigures `json:"strokes"`
+}
+
+type apiMonth struct {
+	Month int `json:"month"`
+	apiFigures
+}
+
+// apiSwimsList returns a page of the swims of the user. It takes the sort
+// and filter parameters of the swims list plus a from/to date range.
+func (app *application) apiSwimsList(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	sort, direction, err := parseAPISwimSort(query)
+	if err != nil {
+		app.apiError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	filter := parseSwimFilter(r)
+	filter.From, filter.To, err = parseDateRange(r)
+	if err != nil {
+		app.apiError(w, http.StatusBadRequest, fmt.Sprintf("invalid date range: %s", err))
+		return
+	}
+
+	limit := apiDefaultLimit
+	if value := query.Get("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > apiMaxLimit {
+			app.apiError(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", apiMaxLimit))
+			return
+		}
+	}
+
+	var after *models.SwimKey
+	if value := query.Get("cursor"); value != "" {
+		cursor, err := decodeSwimCursor(value)
+		if err != nil || cursor.Sort != sort || cursor.Direction != direction {
+			app.apiError(w, http.StatusBadRequest, "invalid cursor")
+			return
+		}
+		after = &cursor.After
+	}
+
+	// One swim more than asked for tells whether there is a next page
+	userId := app.authenticatedUserID(r)
+	swims, err := app.swims.GetAfter(userId, limit+1, after, sort, direction, filter)
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+
+	page := apiSwimPage{Swims: make([]apiSwim, 0, min(len(swims), limit))}
+	if len(swims) > limit {
+		swims = swims[:limit]
+		page.NextCursor = encodeSwimCursor(swimCursor{Sort: sort, Direction: direction, After: swims[limit-1].Key()})
+	}
+	for _, swim := range swims {
+		page.Swims = append(page.Swims, newAPISwim(swim))
+	}
+
+	app.writeJSON(w, r, http.StatusOK, page)
+}
+
+// apiSwimView returns a swim of the user with its sets.
+func (app *application) apiSwimView(w http.ResponseWriter, r *http.Request) {
+	swimID, ok := app.apiSwimID(w, r)
+	if !ok {
+		return
+	}
+
+	swim, err := app.swims.GetByID(app.authenticatedUserID(r), swimID)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.apiError(w, http.StatusNotFound, "")
+		} else {
+			app.apiServerError(w, r, err)
+		}
+		return
+	}
+
+	app.writeJSON(w, r, http.StatusOK, newAPISwim(swim))
+}
+
+// apiStoreSwim creates a swim and returns it with its id.
+func (app *application) apiStoreSwim(w http.ResponseWriter, r *http.Request) {
+	swim, ok := app.readAPISwim(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.swims.Insert(swim, app.authenticatedUserID(r))
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/swims/%d", swim.Id))
+	app.writeJSON(w, r, http.StatusCreated, newAPISwim(swim))
+}
+
+// apiUpdateSwim replaces a swim of the user as a whole, including its sets and
+// tags.
+func (app *application) apiUpdateSwim(w http.ResponseWriter, r *http.Request) {
+	swimID, ok := app.apiSwimID(w, r)
+	if !ok {
+		return
+	}
+
+	swim, ok := app.readAPISwim(w, r)
+	if !ok {
+		return
+	}
+	swim.Id = swimID
+
+	err := app.swims.Update(swim, app.authenticatedUserID(r))
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.apiError(w, http.StatusNotFound, "")
+		} else {
+			app.apiServerError(w, r, err)
+		}
+		return
+	}
+
+	app.writeJSON(w, r, http.StatusOK, newAPISwim(swim))
+}
+
+func (app *application) apiDeleteSwim(w http.ResponseWriter, r *http.Request) {
+	swimID, ok := app.apiSwimID(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.swims.Delete(swimID, app.authenticatedUserID(r))
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.apiError(w, http.StatusNotFound, "")
+		} else {
+			app.apiServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// apiSummary returns the figures of the dashboard and the yearly figures for
+// the swims that match the filter parameters of the swims list.
+func (app *application) apiSummary(w http.ResponseWriter, r *http.Request) {
+	filter := parseSwimFilter(r)
+
+	var err error
+	filter.From, filter.To, err = parseDateRange(r)
+	if err != nil {
+		app.apiError(w, http.StatusBadRequest, fmt.Sprintf("invalid date range: %s", err))
+		return
+	}
+
+	summary := app.swims.Summarize(app.authenticatedUserID(r), filter)
+
+	app.writeJSON(w, r, http.StatusOK, newAPISummary(summary, time.Now()))
+}
+
+// openAPI sends the OpenAPI document of the API and the downloads. Clients
+// need it before they can authenticate, so it is public.
+func (app *application) openAPI(w http.ResponseWriter, r *http.Request) {
+	spec, err := fs.ReadFile(ui.Files, "api/openapi.json")
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	_, err = w.Write(spec)
+	if err != nil {
+		app.logger.Error(err.Error(), "method", r.Method, "uri", requestURI(r))
+	}
+}
+
+// apiSwimID reads the swim id of the path, responding with Not Found for ids
+// that cannot exist.
+func (app *application) apiSwimID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	params := httprouter.ParamsFromContext(r.Context())
+	swimID, err := strconv.Atoi(params.ByName("id"))
+	if err != nil || swimID <= 0 {
+		app.apiError(w, http.StatusNotFound, "")
+		return 0, false
+	}
+	return swimID, true
+}
+
+// readAPISwim reads and validates the swim of a request body. Locations must
+// belong to the user.
+func (app *application) readAPISwim(w http.ResponseWriter, r *http.Request) (*models.Swim, bool) {
+	var in apiSwim
+	if !app.readJSON(w, r, &in) {
+		return nil, false
+	}
+
+	swim, err := swimFromAPI(in)
+	if err != nil {
+		app.apiError(w, http.StatusUnprocessableEntity, err.Error())
+		return nil, false
+	}
+
+	err = app.checkLocation(app.authenticatedUserID(r), swim.LocationId)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.apiError(w, http.StatusUnprocessableEntity, fmt.Sprintf("unknown location %d", swim.LocationId))
+		} else {
+			app.apiServerError(w, r, err)
+		}
+		return nil, false
+	}
+
+	return swim, true
+}
+
+// readJSON decodes a JSON request body into dst. Only bodies declared as JSON
+// are read, which also keeps plain HTML forms of other sites from posting to
+// the API with the session of the user.
+func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst any) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/json" {
+		app.apiError(w, http.StatusUnsupportedMediaType, "the request body must be application/json")
+		return false
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxAPIBodySize)
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	err = dec.Decode(dst)
+	if err == nil && dec.Decode(&struct{}{}) != io.EOF {
+		err = errors.New("body must hold a single JSON value")
+	}
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			app.apiError(w, http.StatusRequestEntityTooLarge, "")
+			return false
+		}
+		app.apiError(w, http.StatusBadRequest, fmt.Sprintf("invalid JSON: %s", strings.TrimPrefix(err.Error(), "json: ")))
+		return false
+	}
+
+	return true
+}
+
+// writeJSON sends data as the JSON body of a response.
+func (app *application) writeJSON(w http.ResponseWriter, r *http.Request, status int, data any) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	_, err = w.Write(append(body, '\n'))
+	if err != nil {
+		app.logger.Error(err.Error(), "method", r.Method, "uri", requestURI(r))
+	}
+}
+
+// apiError responds with a problem document for a status. The detail is left
+// out when the status says it all.
+func (app *application) apiError(w http.ResponseWriter, status int, detail string) {
+	body, _ := json.Marshal(problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	})
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	_, _ = w.Write(append(body, '\n'))
+}
+
+// apiServerError is the serverError of the API.
+func (app *application) apiServerError(w http.ResponseWriter, r *http.Request, err error) {
+	app.logger.Error(err.Error(), "method", r.Method, "uri", requestURI(r))
+	app.apiError(w, http.StatusInternalServerError, "")
+}
+
+// apiUnauthorized asks the client to authenticate. The challenge adds the
+// error of a rejected access token, if any, to the Bearer scheme.
+func (app *application) apiUnauthorized(w http.ResponseWriter, challenge string, detail string) {
+	header := `Bearer realm="SwimMate"`
+	if challenge != "" {
+		header += ", " + challenge
+	}
+	w.Header().Set("WWW-Authenticate", header)
+	app.apiError(w, http.StatusUnauthorized, detail)
+}
+
+// parseAPISwimSort reads the sort parameters of a swim list. Unlike the swims
+// page, the API rejects unknown values instead of falling back to the default.
+func parseAPISwimSort(query url.Values) (string, string, error) {
+	sort := query.Get("sort")
+	if sort == "" {
+		sort = models.SwimSortDate
+	}
+	if sort != models.SwimSortDate && sort != models.SwimSortDistance && sort != models.SwimSortEffort {
+		return "", "", fmt.Errorf("invalid sort %q", sort)
+	}
+
+	direction := query.Get("direction")
+	if direction == "" {
+		direction = models.SortDirectionDesc
+	}
+	if direction != models.SortDirectionAsc && direction != models.SortDirectionDesc {
+		return "", "", fmt.Errorf("invalid direction %q", direction)
+	}
+
+	return sort, direction, nil
+}
+
+func encodeSwimCursor(cursor swimCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeSwimCursor(value string) (swimCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return swimCursor{}, err
+	}
+
+	var cursor swimCursor
+	err = json.Unmarshal(data, &cursor)
+	if err != nil {
+		return swimCursor{}, err
+	}
+	if cursor.After.Id < 1 {
+		return swimCursor{}, errors.New("missing swim id")
+	}
+
+	return cursor, nil
+}
+
+func newAPISwim(swim *models.Swim) apiSwim {
+	out := apiSwim{
+		Id:         swim.Id,
+		Date:       swim.Date.Format("2006-01-02"),
+		DistanceM:  swim.DistanceM,
+		Stroke:     swim.Stroke,
+		Feel:       swim.Feel,
+		Effort:     swim.Effort,
+		DurationS:  int(swim.Duration / time.Second),
+		PoolLength: swim.Pool.Length,
+		PoolUnit:   swim.Pool.Unit,
+		Laps:       swim.Laps,
+		Notes:      swim.Notes,
+		LocationId: swim.LocationId,
+		Tags:       swim.Tags,
+	}
+	for _, set := range swim.Sets {
+		out.Sets = append(out.Sets, apiSet{
+			Repetitions: set.Repetitions,
+			DistanceM:   set.DistanceM,
+			Stroke:      set.Stroke,
+			IntervalS:   int(set.Interval / time.Second),
+			RestS:       int(set.Rest / time.Second),
+		})
+	}
+	return out
+}
+
+// swimFromAPI validates a swim of a request with the rules of the swim forms.
+// The distance may be left out if the swim has sets or laps in a pool, it is
+// then computed from them.
+func swimFromAPI(in apiSwim) (*models.Swim, error) {
+	date, err := time.Parse("2006-01-02", in.Date)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date %q", in.Date)
+	}
+
+	pool := models.Pool{Length: in.PoolLength, Unit: in.PoolUnit}
+	if in.PoolLength != 0 || in.PoolUnit != "" {
+		pool, err = models.ParsePool(pool.String())
+		if err != nil {
+			return nil, fmt.Errorf("invalid pool %d %s", in.PoolLength, in.PoolUnit)
+		}
+	}
+
+	var sets []models.SwimSet
+	for i, set := range in.Sets {
+		stroke, err := parseStroke(string(set.Stroke))
+		if err != nil {
+			return nil, fmt.Errorf("set %d: %w", i+1, err)
+		}
+		if set.Repetitions <= 0 || set.DistanceM <= 0 || set.IntervalS < 0 || set.RestS < 0 {
+			return nil, fmt.Errorf("set %d: invalid repetitions, distance or times", i+1)
+		}
+		sets = append(sets, models.SwimSet{
+			Repetitions: set.Repetitions,
+			DistanceM:   set.DistanceM,
+			Stroke:      stroke,
+			Interval:    time.Duration(set.IntervalS) * time.Second,
+			Rest:        time.Duration(set.RestS) * time.Second,
+		})
+	}
+
+	if in.Laps < 0 {
+		return nil, fmt.Errorf("invalid laps %d", in.Laps)
+	}
+	if in.Laps > 0 && pool.IsZero() {
+		return nil, errors.New("laps require a pool length")
+	}
+
+	distanceM := in.DistanceM
+	switch {
+	case len(sets) > 0:
+		// The sets are the breakdown of the swim, so they define its distance
+		if distanceM != 0 && distanceM != models.SetsDistanceM(sets) {
+			return nil, fmt.Errorf("distance %d does not match the %d m of the sets", distanceM, models.SetsDistanceM(sets))
+		}
+		distanceM = models.SetsDistanceM(sets)
+	case distanceM == 0 && in.Laps > 0:
+		distanceM = pool.DistanceM(in.Laps)
+	}
+	if distanceM <= 0 {
+		return nil, fmt.Errorf("invalid distance %d", distanceM)
+	}
+
+	if in.DurationS < 0 {
+		return nil, fmt.Errorf("invalid duration %d", in.DurationS)
+	}
+
+	stroke, err := parseStroke(string(in.Stroke))
+	if err != nil {
+		return nil, err
+	}
+
+	if !in.Feel.Valid() {
+		return nil, fmt.Errorf("invalid feel %d", in.Feel)
+	}
+	if in.Effort != 0 && !in.Effort.Valid() {
+		return nil, fmt.Errorf("invalid effort %d", in.Effort)
+	}
+
+	notes := strings.TrimSpace(in.Notes)
+	if utf8.RuneCountInString(notes) > models.MaxNotesLength {
+		return nil, errors.New("notes too long")
+	}
+
+	if in.LocationId < 0 {
+		return nil, fmt.Errorf("invalid location %d", in.LocationId)
+	}
+
+	var tags []string
+	for _, value := range in.Tags {
+		tag := models.NormalizeTag(value)
+		if tag == "" || slices.Contains(tags, tag) {
+			continue
+		}
+		if utf8.RuneCountInString(tag) > models.MaxTagLength {
+			return nil, fmt.Errorf("tag %q too long", tag)
+		}
+		tags = append(tags, tag)
+	}
+	slices.Sort(tags)
+
+	return &models.Swim{
+		Date:       date,
+		DistanceM:  distanceM,
+		Effort:     in.Effort,
+		Feel:       in.Feel,
+		Duration:   time.Duration(in.DurationS) * time.Second,
+		Stroke:     stroke,
+		Pool:       pool,
+		Laps:       in.Laps,
+		Notes:      notes,
+		Tags:       tags,
+		LocationId: in.LocationId,
+		Sets:       sets,
+	}, nil
+}
+
+// newAPISummary converts a summary, taking the current month and year from
+// now like the dashboard.
+func newAPISummary(summary *models.SwimSummary, now time.Time) apiSummary {
+	out := apiSummary{
+		Month: newAPIFigures(summary.YearMap[now.Year()].MonthMap[now.Month()]),
+		Week: apiFigures{
+			Count:     summary.WeeklyCount,
+			DistanceM: summary.WeeklyDistance,
+			PaceS:     int(summary.WeeklyPace / time.Second),
+			Effort:    summary.WeeklyEffort,
+		},
+		Years:   []apiYear{},
+		Strokes: newAPIStrokes(summary.StrokeMap),
+	}
+
+	var total models.SwimFigures
+	for year, figures := range summary.YearMap {
+		total.Count += figures.Count
+		total.DistanceM += figures.DistanceM
+		total.Duration += figures.Duration
+		total.TimedDistanceM += figures.TimedDistanceM
+		total.EffortTotal += figures.EffortTotal
+		total.RatedCount += figures.RatedCount
+
+		y := apiYear{
+			Year:       year,
+			apiFigures: newAPIFigures(figures.SwimFigures),
+			Months:     []apiMonth{},
+			Strokes:    newAPIStrokes(figures.StrokeMap),
+		}
+		for month := time.January; month <= time.December; month++ {
+			if m, ok := figures.MonthMap[month]; ok {
+				y.Months = append(y.Months, apiMonth{Month: int(month), apiFigures: newAPIFigures(m)})
+			}
+		}
+		out.Years = append(out.Years, y)
+	}
+	slices.SortFunc(out.Years, func(a, b apiYear) int {
+		return a.Year - b.Year
+	})
+	out.Total = newAPIFigures(total)
+
+	return out
+}
+
+func newAPIFigures(figures models.SwimFigures) apiFigures {
+	return apiFigures{
+		Count:     figures.Count,
+		DistanceM: figures.DistanceM,
+		PaceS:     int(figures.Pace() / time.Second),
+		Effort:    figures.Effort(),
+	}
+}
+
+func newAPIStrokes(strokes map[models.Stroke]models.SwimFigures) map[models.Stroke]apiFigures {
+	out := make(map[models.Stroke]apiFigures, len(strokes))
+	for stroke, figures := range strokes {
+		out[stroke] = newAPIFigures(figures)
+	}
+	return out
+}
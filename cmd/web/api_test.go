@@ -0,0 +1,750 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/rockstaedt/swimmate/internal/models"
+	"github.com/rockstaedt/swimmate/internal/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+// newAPIRequest creates a request of the logged-in user 1 with a JSON body
+// and the swim id of the path.
+func newAPIRequest(t *testing.T, app *application, method string, target string, id string, body string) *http.Request {
+	t.Helper()
+
+	r := newImportRequest(t, app, method, target, "application/json", bytes.NewBufferString(body))
+	if id != "" {
+		r = r.WithContext(context.WithValue(r.Context(), httprouter.ParamsKey, httprouter.Params{{Key: "id", Value: id}}))
+	}
+	return r
+}
+
+// decodeProblem reads the problem document of an error response.
+func decodeProblem(t *testing.T, rr *httptest.ResponseRecorder) problem {
+	t.Helper()
+
+	assert.Equal(t, "application/problem+json", rr.Header().Get("Content-Type"))
+	var p problem
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&p))
+	assert.Equal(t, rr.Code, p.Status)
+	assert.Equal(t, http.StatusText(rr.Code), p.Title)
+	return p
+}
+
+func testSwims(n int) []*models.Swim {
+	swims := make([]*models.Swim, n)
+	for i := range swims {
+		swims[i] = &models.Swim{
+			Id:        i + 1,
+			Date:      time.Date(2024, 1, 15+i, 0, 0, 0, 0, time.UTC),
+			DistanceM: 1500,
+			Stroke:    models.StrokeFreestyle,
+			Feel:      models.FeelGood,
+			Tags:      []string{"technique"},
+		}
+	}
+	return swims
+}
+
+func TestAPISwimsList(t *testing.T) {
+	lastKey := models.SwimKey{Id: 10, Date: time.Date(2024, 1, 24, 0, 0, 0, 0, time.UTC), DistanceM: 1500}
+	tests := []struct {
+		name              string
+		query             string
+		swims             int
+		getErr            error
+		expectedStatus    int
+		expectedLimit     int
+		expectedAfter     *models.SwimKey
+		expectedSort      string
+		expectedDirection string
+		expectedFilter    models.SwimFilter
+		expectedCount     int
+		expectedNext      bool
+		expectedDetail    string
+	}{
+		{
+			name:              "first page with defaults",
+			swims:             21,
+			expectedStatus:    http.StatusOK,
+			expectedLimit:     21,
+			expectedSort:      models.SwimSortDate,
+			expectedDirection: models.SortDirectionDesc,
+			expectedCount:     20,
+			expectedNext:      true,
+		},
+		{
+			name:              "last page of the cursor",
+			query:             "?limit=5&cursor=" + encodeSwimCursor(swimCursor{Sort: models.SwimSortDate, Direction: models.SortDirectionDesc, After: lastKey}),
+			swims:             3,
+			expectedStatus:    http.StatusOK,
+			expectedLimit:     6,
+			expectedAfter:     &lastKey,
+			expectedSort:      models.SwimSortDate,
+			expectedDirection: models.SortDirectionDesc,
+			expectedCount:     3,
+		},
+		{
+			name:              "sorted and filtered",
+			query:             "?sort=distance&direction=asc&q=tight&tag=Technique&from=2024-01-01&to=2024-01-31",
+			swims:             1,
+			expectedStatus:    http.StatusOK,
+			expectedLimit:     21,
+			expectedSort:      models.SwimSortDistance,
+			expectedDirection: models.SortDirectionAsc,
+			expectedFilter: models.SwimFilter{
+				Search: "tight",
+				Tags:   []string{"technique"},
+				From:   time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				To:     time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC),
+			},
+			expectedCount: 1,
+		},
+		{
+			name:              "no swims",
+			expectedStatus:    http.StatusOK,
+			expectedLimit:     21,
+			expectedSort:      models.SwimSortDate,
+			expectedDirection: models.SortDirectionDesc,
+		},
+		{
+			name:           "unknown sort",
+			query:          "?sort=pace",
+			expectedStatus: http.StatusBadRequest,
+			expectedDetail: `invalid sort "pace"`,
+		},
+		{
+			name:           "unknown direction",
+			query:          "?direction=up",
+			expectedStatus: http.StatusBadRequest,
+			expectedDetail: `invalid direction "up"`,
+		},
+		{
+			name:           "limit too large",
+			query:          "?limit=101",
+			expectedStatus: http.StatusBadRequest,
+			expectedDetail: "limit must be between 1 and 100",
+		},
+		{
+			name:           "invalid cursor",
+			query:          "?cursor=abc",
+			expectedStatus: http.StatusBadRequest,
+			expectedDetail: "invalid cursor",
+		},
+		{
+			name:           "cursor without a swim",
+			query:          "?cursor=" + encodeSwimCursor(swimCursor{Sort: models.SwimSortDate, Direction: models.SortDirectionDesc}),
+			expectedStatus: http.StatusBadRequest,
+			expectedDetail: "invalid cursor",
+		},
+		{
+			name:           "cursor of another sort",
+			query:          "?sort=distance&cursor=" + encodeSwimCursor(swimCursor{Sort: models.SwimSortDate, Direction: models.SortDirectionDesc, After: lastKey}),
+			expectedStatus: http.StatusBadRequest,
+			expectedDetail: "invalid cursor",
+		},
+		{
+			name:           "invalid date range",
+			query:          "?from=2024-02-01&to=2024-01-01",
+			expectedStatus: http.StatusBadRequest,
+			expectedDetail: "invalid date range: date range ends before it starts",
+		},
+		{
+			name:           "database error",
+			getErr:         errors.New("database error"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication()
+			app.swims = &testutils.MockSwimModel{
+				GetAfterFunc: func(userId int, limit int, after *models.SwimKey, sort string, direction string, filter models.SwimFilter) ([]*models.Swim, error) {
+					assert.Equal(t, 1, userId)
+					assert.Equal(t, tt.expectedLimit, limit)
+					assert.Equal(t, tt.expectedAfter, after)
+					assert.Equal(t, tt.expectedSort, sort)
+					assert.Equal(t, tt.expectedDirection, direction)
+					assert.Equal(t, tt.expectedFilter, filter)
+					if tt.getErr != nil {
+						return nil, tt.getErr
+					}
+					return testSwims(tt.swims), nil
+				},
+			}
+			if tt.getErr != nil {
+				tt.expectedLimit, tt.expectedSort, tt.expectedDirection = 21, models.SwimSortDate, models.SortDirectionDesc
+			}
+
+			rr := httptest.NewRecorder()
+			r := newAPIRequest(t, app, http.MethodGet, "/api/v1/swims"+tt.query, "", "")
+
+			app.apiSwimsList(rr, r)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			if tt.expectedStatus != http.StatusOK {
+				assert.Equal(t, tt.expectedDetail, decodeProblem(t, rr).Detail)
+				return
+			}
+
+			assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+			var page apiSwimPage
+			assert.NoError(t, json.NewDecoder(rr.Body).Decode(&page))
+			assert.NotNil(t, page.Swims)
+			assert.Len(t, page.Swims, tt.expectedCount)
+			if tt.expectedNext {
+				cursor, err := decodeSwimCursor(page.NextCursor)
+				assert.NoError(t, err)
+				assert.Equal(t, swimCursor{Sort: tt.expectedSort, Direction: tt.expectedDirection, After: testSwims(tt.swims)[tt.expectedCount-1].Key()}, cursor)
+			} else {
+				assert.Empty(t, page.NextCursor)
+			}
+		})
+	}
+}
+
+func TestAPISwimsListBody(t *testing.T) {
+	app := newTestApplication()
+	app.swims = &testutils.MockSwimModel{
+		GetAfterFunc: func(int, int, *models.SwimKey, string, string, models.SwimFilter) ([]*models.Swim, error) {
+			return []*models.Swim{{
+				Id:         7,
+				Date:       time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+				DistanceM:  1500,
+				Stroke:     models.StrokeMixed,
+				Feel:       models.FeelGood,
+				Effort:     7,
+				Duration:   30 * time.Minute,
+				Pool:       models.Pool{Length: 25, Unit: models.UnitMeters},
+				Laps:       60,
+				Notes:      "Cold",
+				LocationId: 2,
+				Tags:       []string{"open water"},
+			}}, nil
+		},
+	}
+
+	rr := httptest.NewRecorder()
+	app.apiSwimsList(rr, newAPIRequest(t, app, http.MethodGet, "/api/v1/swims", "", ""))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"swims": [{
+		"id": 7,
+		"date": "2024-01-15",
+		"distance_m": 1500,
+		"stroke": "mixed",
+		"feel": 4,
+		"effort": 7,
+		"duration_s": 1800,
+		"pool_length": 25,
+		"pool_unit": "m",
+		"laps": 60,
+		"notes": "Cold",
+		"location_id": 2,
+		"tags": ["open water"]
+	}]}`, rr.Body.String())
+}
+
+func TestAPISwimView(t *testing.T) {
+	tests := []struct {
+		name           string
+		id             string
+		getErr         error
+		expectedStatus int
+	}{
+		{name: "swim with sets", id: "7", expectedStatus: http.StatusOK},
+		{name: "swim of another user", id: "8", getErr: models.ErrNoRecord, expectedStatus: http.StatusNotFound},
+		{name: "invalid id", id: "abc", expectedStatus: http.StatusNotFound},
+		{name: "database error", id: "7", getErr: errors.New("database error"), expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication()
+			app.swims = &testutils.MockSwimModel{
+				GetByIDFunc: func(userId int, swimId int) (*models.Swim, error) {
+					assert.Equal(t, 1, userId)
+					if tt.getErr != nil {
+						return &models.Swim{}, tt.getErr
+					}
+					return &models.Swim{
+						Id:        swimId,
+						Date:      time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+						DistanceM: 800,
+						Stroke:    models.StrokeFreestyle,
+						Feel:      models.FeelOkay,
+						Sets:      []models.SwimSet{{Repetitions: 8, DistanceM: 100, Stroke: models.StrokeFreestyle, Interval: 105 * time.Second}},
+					}, nil
+				},
+			}
+
+			rr := httptest.NewRecorder()
+			app.apiSwimView(rr, newAPIRequest(t, app, http.MethodGet, "/api/v1/swims/"+tt.id, tt.id, ""))
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			if tt.expectedStatus != http.StatusOK {
+				decodeProblem(t, rr)
+				return
+			}
+			assert.JSONEq(t, `{
+				"id": 7,
+				"date": "2024-01-15",
+				"distance_m": 800,
+				"stroke": "freestyle",
+				"feel": 3,
+				"sets": [{"repetitions": 8, "distance_m": 100, "stroke": "freestyle", "interval_s": 105}]
+			}`, rr.Body.String())
+		})
+	}
+}
+
+func TestAPIStoreSwim(t *testing.T) {
+	tests := []struct {
+		name             string
+		contentType      string
+		body             string
+		locationErr      error
+		insertErr        error
+		expectedStatus   int
+		expectedSwim     *models.Swim
+		expectedDetail   string
+		expectedLocation string
+	}{
+		{
+			name:        "swim created",
+			contentType: "application/json; charset=utf-8",
+			body:        `{"date": "2024-01-15", "distance_m": 1500, "feel": 4, "effort": 7, "duration_s": 1800, "location_id": 2, "tags": ["Open  Water", "open water"]}`,
+			expectedSwim: &models.Swim{
+				Date:       time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+				DistanceM:  1500,
+				Stroke:     models.StrokeFreestyle,
+				Feel:       models.FeelGood,
+				Effort:     7,
+				Duration:   30 * time.Minute,
+				LocationId: 2,
+				Tags:       []string{"open water"},
+			},
+			expectedStatus:   http.StatusCreated,
+			expectedLocation: "/api/v1/swims/42",
+		},
+		{
+			name:           "form body",
+			contentType:    "application/x-www-form-urlencoded",
+			body:           "date=2024-01-15&distance=1500",
+			expectedStatus: http.StatusUnsupportedMediaType,
+			expectedDetail: "the request body must be application/json",
+		},
+		{
+			name:           "malformed JSON",
+			contentType:    "application/json",
+			body:           `{"date": "2024-01-15",`,
+			expectedStatus: http.StatusBadRequest,
+			expectedDetail: "invalid JSON: unexpected EOF",
+		},
+		{
+			name:           "unknown field",
+			contentType:    "application/json",
+			body:           `{"date": "2024-01-15", "distance_m": 1500, "feel": 4, "assessment": 2}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedDetail: `invalid JSON: unknown field "assessment"`,
+		},
+		{
+			name:           "several values",
+			contentType:    "application/json",
+			body:           `{"date": "2024-01-15", "distance_m": 1500, "feel": 4} {}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedDetail: "invalid JSON: body must hold a single JSON value",
+		},
+		{
+			name:           "invalid swim",
+			contentType:    "application/json",
+			body:           `{"date": "2024-01-15", "distance_m": 1500, "feel": 6}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedDetail: "invalid feel 6",
+		},
+		{
+			name:           "location of another user",
+			contentType:    "application/json",
+			body:           `{"date": "2024-01-15", "distance_m": 1500, "feel": 4, "location_id": 9}`,
+			locationErr:    models.ErrNoRecord,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedDetail: "unknown location 9",
+		},
+		{
+			name:           "database error",
+			contentType:    "application/json",
+			body:           `{"date": "2024-01-15", "distance_m": 1500, "feel": 4}`,
+			insertErr:      errors.New("database error"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication()
+			app.locations = &testutils.MockLocationModel{
+				GetByIDFunc: func(userId int, id int) (*models.Location, error) {
+					assert.Equal(t, 1, userId)
+					return &models.Location{Id: id}, tt.locationErr
+				},
+			}
+			inserted := false
+			app.swims = &testutils.MockSwimModel{
+				InsertFunc: func(swim *models.Swim, userId int) error {
+					inserted = true
+					assert.Equal(t, 1, userId)
+					if tt.expectedSwim != nil {
+						assert.Equal(t, tt.expectedSwim, swim)
+					}
+					swim.Id = 42
+					return tt.insertErr
+				},
+			}
+
+			rr := httptest.NewRecorder()
+			r := newAPIRequest(t, app, http.MethodPost, "/api/v1/swims", "", tt.body)
+			r.Header.Set("Content-Type", tt.contentType)
+
+			app.apiStoreSwim(rr, r)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			if tt.expectedStatus != http.StatusCreated {
+				assert.Equal(t, tt.expectedDetail, decodeProblem(t, rr).Detail)
+				assert.Equal(t, tt.insertErr != nil, inserted)
+				return
+			}
+
+			assert.Equal(t, tt.expectedLocation, rr.Header().Get("Location"))
+			var swim apiSwim
+			assert.NoError(t, json.NewDecoder(rr.Body).Decode(&swim))
+			assert.Equal(t, 42, swim.Id)
+		})
+	}
+}
+
+func TestAPIStoreSwimTooLarge(t *testing.T) {
+	app := newTestApplication()
+
+	rr := httptest.NewRecorder()
+	body := `{"date": "2024-01-15", "distance_m": 1500, "feel": 4, "notes": "` + strings.Repeat("a", maxAPIBodySize) + `"}`
+	app.apiStoreSwim(rr, newAPIRequest(t, app, http.MethodPost, "/api/v1/swims", "", body))
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+	decodeProblem(t, rr)
+}
+
+func TestAPIUpdateSwim(t *testing.T) {
+	tests := []struct {
+		name           string
+		id             string
+		body           string
+		updateErr      error
+		expectedStatus int
+	}{
+		{
+			name:           "swim replaced",
+			id:             "7",
+			body:           `{"date": "2024-01-15", "feel": 4, "sets": [{"repetitions": 4, "distance_m": 100, "stroke": "backstroke"}]}`,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "swim of another user",
+			id:             "8",
+			body:           `{"date": "2024-01-15", "distance_m": 400, "feel": 4}`,
+			updateErr:      models.ErrNoRecord,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "invalid id",
+			id:             "0",
+			body:           `{"date": "2024-01-15", "distance_m": 400, "feel": 4}`,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "invalid swim",
+			id:             "7",
+			body:           `{"date": "15.01.2024", "distance_m": 400, "feel": 4}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:           "database error",
+			id:             "7",
+			body:           `{"date": "2024-01-15", "distance_m": 400, "feel": 4}`,
+			updateErr:      errors.New("database error"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication()
+			app.swims = &testutils.MockSwimModel{
+				UpdateFunc: func(swim *models.Swim, userId int) error {
+					assert.Equal(t, 1, userId)
+					assert.Equal(t, 400, swim.DistanceM)
+					return tt.updateErr
+				},
+			}
+
+			rr := httptest.NewRecorder()
+			app.apiUpdateSwim(rr, newAPIRequest(t, app, http.MethodPut, "/api/v1/swims/"+tt.id, tt.id, tt.body))
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			if tt.expectedStatus != http.StatusOK {
+				decodeProblem(t, rr)
+				return
+			}
+			assert.JSONEq(t, `{
+				"id": 7,
+				"date": "2024-01-15",
+				"distance_m": 400,
+				"stroke": "freestyle",
+				"feel": 4,
+				"sets": [{"repetitions": 4, "distance_m": 100, "stroke": "backstroke"}]
+			}`, rr.Body.String())
+		})
+	}
+}
+
+func TestAPIDeleteSwim(t *testing.T) {
+	tests := []struct {
+		name           string
+		id             string
+		deleteErr      error
+		expectedStatus int
+	}{
+		{name: "swim deleted", id: "7", expectedStatus: http.StatusNoContent},
+		{name: "swim of another user", id: "8", deleteErr: models.ErrNoRecord, expectedStatus: http.StatusNotFound},
+		{name: "invalid id", id: "-1", expectedStatus: http.StatusNotFound},
+		{name: "database error", id: "7", deleteErr: errors.New("database error"), expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication()
+			app.swims = &testutils.MockSwimModel{
+				DeleteFunc: func(id int, userId int) error {
+					assert.Equal(t, 1, userId)
+					return tt.deleteErr
+				},
+			}
+
+			rr := httptest.NewRecorder()
+			app.apiDeleteSwim(rr, newAPIRequest(t, app, http.MethodDelete, "/api/v1/swims/"+tt.id, tt.id, ""))
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			if tt.expectedStatus == http.StatusNoContent {
+				assert.Empty(t, rr.Body.String())
+			} else {
+				decodeProblem(t, rr)
+			}
+		})
+	}
+}
+
+func TestAPISummary(t *testing.T) {
+	now := time.Now()
+	january := models.SwimFigures{Count: 2, DistanceM: 2500, Duration: 30 * time.Minute, TimedDistanceM: 1500, EffortTotal: 13, RatedCount: 2}
+
+	app := newTestApplication()
+	app.swims = &testutils.MockSwimModel{
+		SummarizeFunc: func(userId int, filter models.SwimFilter) *models.SwimSummary {
+			assert.Equal(t, 1, userId)
+			assert.Equal(t, []string{"technique"}, filter.Tags)
+			return &models.SwimSummary{
+				TotalCount:     3,
+				TotalDistance:  3500,
+				WeeklyCount:    1,
+				WeeklyDistance: 1000,
+				WeeklyPace:     2 * time.Minute,
+				WeeklyEffort:   6,
+				YearMap: map[int]models.YearMap{
+					2023: {SwimFigures: models.SwimFigures{Count: 1, DistanceM: 1000}, MonthMap: map[time.Month]models.SwimFigures{time.May: {Count: 1, DistanceM: 1000}}},
+					2024: {
+						SwimFigures: january,
+						MonthMap:    map[time.Month]models.SwimFigures{time.January: january},
+						StrokeMap:   map[models.Stroke]models.SwimFigures{models.StrokeFreestyle: january},
+					},
+				},
+				StrokeMap: map[models.Stroke]models.SwimFigures{models.StrokeFreestyle: {Count: 3, DistanceM: 3500}},
+			}
+		},
+	}
+
+	rr := httptest.NewRecorder()
+	app.apiSummary(rr, newAPIRequest(t, app, http.MethodGet, "/api/v1/summary?tag=technique", "", ""))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var summary apiSummary
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&summary))
+	assert.Equal(t, apiFigures{Count: 3, DistanceM: 3500, PaceS: 120, Effort: 6.5}, summary.Total)
+	assert.Equal(t, apiFigures{Count: 1, DistanceM: 1000, PaceS: 120, Effort: 6}, summary.Week)
+	if now.Year() != 2024 || now.Month() != time.January {
+		assert.Equal(t, apiFigures{}, summary.Month)
+	}
+	assert.Len(t, summary.Years, 2)
+	assert.Equal(t, 2023, summary.Years[0].Year)
+	assert.Equal(t, []apiMonth{{Month: 5, apiFigures: apiFigures{Count: 1, DistanceM: 1000}}}, summary.Years[0].Months)
+	assert.Equal(t, 2024, summary.Years[1].Year)
+	assert.Equal(t, apiFigures{Count: 2, DistanceM: 2500, PaceS: 120, Effort: 6.5}, summary.Years[1].apiFigures)
+	assert.Equal(t, apiFigures{Count: 2, DistanceM: 2500, PaceS: 120, Effort: 6.5}, summary.Years[1].Strokes[models.StrokeFreestyle])
+	assert.Equal(t, apiFigures{Count: 3, DistanceM: 3500}, summary.Strokes[models.StrokeFreestyle])
+}
+
+func TestAPISummaryInvalidDateRange(t *testing.T) {
+	app := newTestApplication()
+
+	rr := httptest.NewRecorder()
+	app.apiSummary(rr, newAPIRequest(t, app, http.MethodGet, "/api/v1/summary?from=2024-13-01", "", ""))
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	decodeProblem(t, rr)
+}
+
+func TestSwimFromAPI(t *testing.T) {
+	date := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		in            apiSwim
+		expectedSwim  *models.Swim
+		expectedError string
+	}{
+		{
+			name:         "distance",
+			in:           apiSwim{Date: "2024-01-15", DistanceM: 1500, Feel: 4, Stroke: "Butterfly", Notes: "  cold  "},
+			expectedSwim: &models.Swim{Date: date, DistanceM: 1500, Feel: 4, Stroke: models.StrokeButterfly, Notes: "cold"},
+		},
+		{
+			name:         "laps in a pool",
+			in:           apiSwim{Date: "2024-01-15", Laps: 40, PoolLength: 25, PoolUnit: models.UnitYards, Feel: 4},
+			expectedSwim: &models.Swim{Date: date, DistanceM: 914, Laps: 40, Pool: models.Pool{Length: 25, Unit: models.UnitYards}, Feel: 4, Stroke: models.StrokeFreestyle},
+		},
+		{
+			name: "sets",
+			in:   apiSwim{Date: "2024-01-15", DistanceM: 1000, Feel: 4, Sets: []apiSet{{Repetitions: 10, DistanceM: 100, RestS: 15}}},
+			expectedSwim: &models.Swim{Date: date, DistanceM: 1000, Feel: 4, Stroke: models.StrokeFreestyle, Sets: []models.SwimSet{
+				{Repetitions: 10, DistanceM: 100, Stroke: models.StrokeFreestyle, Rest: 15 * time.Second},
+			}},
+		},
+		{
+			name:          "distance not matching the sets",
+			in:            apiSwim{Date: "2024-01-15", DistanceM: 1500, Feel: 4, Sets: []apiSet{{Repetitions: 10, DistanceM: 100}}},
+			expectedError: "distance 1500 does not match the 1000 m of the sets",
+		},
+		{
+			name:          "invalid set",
+			in:            apiSwim{Date: "2024-01-15", Feel: 4, Sets: []apiSet{{Repetitions: 0, DistanceM: 100}}},
+			expectedError: "set 1: invalid repetitions, distance or times",
+		},
+		{
+			name:          "missing date",
+			in:            apiSwim{DistanceM: 1500, Feel: 4},
+			expectedError: `invalid date ""`,
+		},
+		{
+			name:          "missing distance",
+			in:            apiSwim{Date: "2024-01-15", Feel: 4},
+			expectedError: "invalid distance 0",
+		},
+		{
+			name:          "laps without pool",
+			in:            apiSwim{Date: "2024-01-15", Laps: 40, Feel: 4},
+			expectedError: "laps require a pool length",
+		},
+		{
+			name:          "unknown pool",
+			in:            apiSwim{Date: "2024-01-15", DistanceM: 1500, PoolLength: 33, PoolUnit: models.UnitMeters, Feel: 4},
+			expectedError: "invalid pool 33 m",
+		},
+		{
+			name:          "missing feel",
+			in:            apiSwim{Date: "2024-01-15", DistanceM: 1500},
+			expectedError: "invalid feel 0",
+		},
+		{
+			name:          "invalid effort",
+			in:            apiSwim{Date: "2024-01-15", DistanceM: 1500, Feel: 4, Effort: 11},
+			expectedError: "invalid effort 11",
+		},
+		{
+			name:          "invalid stroke",
+			in:            apiSwim{Date: "2024-01-15", DistanceM: 1500, Feel: 4, Stroke: "crawl"},
+			expectedError: `invalid stroke "crawl"`,
+		},
+		{
+			name:          "negative duration",
+			in:            apiSwim{Date: "2024-01-15", DistanceM: 1500, Feel: 4, DurationS: -1},
+			expectedError: "invalid duration -1",
+		},
+		{
+			name:          "notes too long",
+			in:            apiSwim{Date: "2024-01-15", DistanceM: 1500, Feel: 4, Notes: strings.Repeat("a", models.MaxNotesLength+1)},
+			expectedError: "notes too long",
+		},
+		{
+			name:          "tag too long",
+			in:            apiSwim{Date: "2024-01-15", DistanceM: 1500, Feel: 4, Tags: []string{strings.Repeat("a", models.MaxTagLength+1)}},
+			expectedError: `tag "` + strings.Repeat("a", models.MaxTagLength+1) + `" too long`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			swim, err := swimFromAPI(tt.in)
+
+			if tt.expectedError != "" {
+				assert.EqualError(t, err, tt.expectedError)
+				assert.Nil(t, swim)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedSwim, swim)
+		})
+	}
+}
+
+func TestAPIErrorsOfTheRouter(t *testing.T) {
+	app := newTestApplication()
+	handler := app.routes()
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		authenticated  bool
+		expectedStatus int
+	}{
+		{name: "not logged in", method: http.MethodGet, path: "/api/v1/swims", expectedStatus: http.StatusUnauthorized},
+		{name: "unknown path", method: http.MethodGet, path: "/api/v1/laps", authenticated: true, expectedStatus: http.StatusNotFound},
+		{name: "unknown method", method: http.MethodPatch, path: "/api/v1/swims/1", authenticated: true, expectedStatus: http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.authenticated {
+				ctx, _ := app.sessionManager.Load(r.Context(), "")
+				app.sessionManager.Put(ctx, "authenticatedUserID", 1)
+				r = r.WithContext(ctx)
+			}
+
+			handler.ServeHTTP(rr, r)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			decodeProblem(t, rr)
+		})
+	}
+}
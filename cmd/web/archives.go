@@ -0,0 +1,371 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/rockstaedt/swimmate/internal/importer"
+	"github.com/rockstaedt/swimmate/internal/models"
+)
+
+const (
+	// defaultMaxArchiveSize limits uploaded export archives, which hold the
+	// files of every activity of an account, unless MAX_ARCHIVE_SIZE sets
+	// another limit.
+	defaultMaxArchiveSize = 1 << 30
+	// maxRunningArchives is how many archive imports a user may have that are
+	// not done, each keeping its archive on disk.
+	maxRunningArchives = 3
+	// archiveLifetime is how long an archive import may take. Jobs that were
+	// left before are ended and their archives removed.
+	archiveLifetime = 3 * 24 * time.Hour
+	// archiveBatchTime is the time a request spends on reading the swims of
+	// an archive, well below the write timeout of the server. The page asks
+	// for the next batch until the archive is done.
+	archiveBatchTime = 3 * time.Second
+)
+
+// uploadArchive stores an uploaded export archive and starts an import job
+// for its swims. The swims are read by continueArchive, batch by batch.
+func (app *application) uploadArchive(w http.ResponseWriter, r *http.Request) {
+	err := extendDeadlines(w, largeUploadTimeout)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+	running, err := app.importJobs.CountRunning(userId)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	if running >= maxRunningArchives {
+		app.sessionManager.Put(r.Context(), "flashText",
+			fmt.Sprintf("You have %d archive imports that are not done. Finish them before uploading another archive.", running))
+		app.sessionManager.Put(r.Context(), "flashType", "flash-error")
+		http.Redirect(w, r, "/swims/import", http.StatusSeeOther)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, app.maxArchiveSize)
+	part, err := uploadedFile(r, "file")
+	if errors.Is(err, http.ErrMissingFile) {
+		app.sessionManager.Put(r.Context(), "flashText", "Choose an export archive.")
+		app.sessionManager.Put(r.Context(), "flashType", "flash-error")
+		http.Redirect(w, r, "/swims/import", http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		app.logger.Error(err.Error())
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	file, err := app.storeArchive(part)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			app.clientError(w, http.StatusRequestEntityTooLarge)
+			return
+		}
+		app.serverError(w, r, err)
+		return
+	}
+
+	total, err := countArchiveSwims(file)
+	if err != nil {
+		app.removeArchive(file)
+		app.sessionManager.Put(r.Context(), "flashText", fmt.Sprintf("The archive cannot be imported: %s.", err))
+		app.sessionManager.Put(r.Context(), "flashType", "flash-error")
+		http.Redirect(w, r, "/swims/import", http.StatusSeeOther)
+		return
+	}
+
+	job := &models.ImportJob{File: file, Total: total, Done: total == 0}
+	err = app.importJobs.Insert(job, userId)
+	if err != nil {
+		app.removeArchive(file)
+		app.serverError(w, r, err)
+		return
+	}
+	if job.Done {
+		app.removeArchive(file)
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/swims/import/archive/%d", job.Id), http.StatusSeeOther)
+}
+
+// showArchive shows the progress of an archive import. The page continues
+// the import as long as it is open.
+func (app *application) showArchive(w http.ResponseWriter, r *http.Request) {
+	job, ok := app.importJob(w, r)
+	if !ok {
+		return
+	}
+
+	app.render(w, r, http.StatusOK, importTemplate, app.newTemplateData(r, importPageData{Archive: job}))
+}
+
+// continueArchive reads the next batch of swims of an archive and returns
+// the progress, which polls for the following batch until the job is done.
+// The position is stored after every batch, so an import that was left or
+// interrupted continues where it stopped.
+func (app *application) continueArchive(w http.ResponseWriter, r *http.Request) {
+	job, ok := app.importJob(w, r)
+	if !ok {
+		return
+	}
+
+	if !job.Done {
+		err := app.readArchiveBatch(job, app.sessionManager.GetInt(r.Context(), "authenticatedUserID"))
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+	}
+
+	app.renderPartial(w, r, importTemplate, "archive-progress", importPageData{}, job)
+}
+
+// importJob loads the import job of the id in the path, responding with Not
+// Found for jobs of other users.
+func (app *application) importJob(w http.ResponseWriter, r *http.Request) (*models.ImportJob, bool) {
+	params := httprouter.ParamsFromContext(r.Context())
+	jobID, err := strconv.Atoi(params.ByName("id"))
+	if err != nil || jobID <= 0 {
+		app.notFound(w)
+		return nil, false
+	}
+
+	job, err := app.importJobs.Get(jobID, app.sessionManager.GetInt(r.Context(), "authenticatedUserID"))
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return nil, false
+	}
+
+	return job, true
+}
+
+// readArchiveBatch imports the next swims of a job and stores its progress.
+// The progress is stored also when the batch fails, so the swims stored
+// before are not taken for swims imported before when it is retried.
+func (app *application) readArchiveBatch(job *models.ImportJob, userId int) error {
+	err := app.insertArchiveSwims(job, userId, time.Now().Add(archiveBatchTime))
+	if errors.Is(err, fs.ErrNotExist) {
+		// The archive is gone, e.g. with the temporary files of the server.
+		// Its remaining swims can not be read anymore.
+		app.logger.Error("archive of import job missing", "job", job.Id, "file", job.File)
+		job.Failed += job.Total - job.Processed
+		job.Processed = job.Total
+		err = nil
+	}
+
+	job.Done = job.Processed >= job.Total
+	errUpdate := app.importJobs.Update(job, userId)
+	if err != nil {
+		return err
+	}
+	if errUpdate != nil {
+		return errUpdate
+	}
+
+	if job.Done {
+		app.removeArchive(job.File)
+	}
+
+	return nil
+}
+
+// insertArchiveSwims stores the swims of an archive from the position of the
+// job on until the deadline has passed. Activities that cannot be read or
+// stored are counted as failed, only errors that stop the whole job, such as
+// a database that is down, are returned.
+func (app *application) insertArchiveSwims(job *models.ImportJob, userId int, deadline time.Time) error {
+	return withArchive(job.File, func(archive *importer.Archive) error {
+		activities, err := archive.Swims()
+		if err != nil {
+			return err
+		}
+
+		for job.Processed < min(job.Total, len(activities)) && time.Now().Before(deadline) {
+			activity := activities[job.Processed]
+
+			swim, err := archive.Swim(activity)
+			if err != nil {
+				app.logger.Info("archive activity not imported", "job", job.Id, "activity", activity.Id, "error", err)
+				job.Failed++
+				job.Processed++
+				continue
+			}
+
+			err = app.swims.Insert(swim, userId)
+			switch {
+			case errors.Is(err, models.ErrDuplicateSource):
+				job.Skipped++
+			case errors.Is(err, models.ErrInvalidSwim):
+				// Retrying would fail on the same swim with every batch
+				app.logger.Info("archive activity not imported", "job", job.Id, "activity", activity.Id, "error", err)
+				job.Failed++
+			case err != nil:
+				return err
+			default:
+				job.Imported++
+			}
+			job.Processed++
+		}
+
+		// Swims listed in the job but no longer in the archive are lost
+		if len(activities) < job.Total && job.Processed >= len(activities) {
+			job.Failed += job.Total - job.Processed
+			job.Processed = job.Total
+		}
+		return nil
+	})
+}
+
+// cleanArchives ends abandoned archive imports every interval until ctx is
+// done.
+func (app *application) cleanArchives(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		app.expireArchives(time.Now().Add(-archiveLifetime))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// expireArchives ends the archive imports started before a time and removes
+// their archives. Archives in the import directory that are older belong to
+// no job that is still running, such as uploads the server stopped during, so
+// they are removed as well.
+func (app *application) expireArchives(before time.Time) {
+	files, err := app.importJobs.Expire(before)
+	if err != nil {
+		app.logger.Error("expiring import jobs", "error", err)
+		return
+	}
+	for _, file := range files {
+		app.removeArchive(file)
+	}
+
+	entries, err := os.ReadDir(app.importDir)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			app.logger.Error("reading import directory", "error", err)
+		}
+		return
+	}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !strings.HasPrefix(entry.Name(), "archive-") {
+			continue
+		}
+		info, err := entry.Info()
+		if err == nil && info.ModTime().Before(before) {
+			app.removeArchive(filepath.Join(app.importDir, entry.Name()))
+		}
+	}
+}
+
+// parseArchiveSize reads the MAX_ARCHIVE_SIZE setting, a size in megabytes.
+func parseArchiveSize(value string) (int64, error) {
+	if value == "" {
+		return defaultMaxArchiveSize, nil
+	}
+
+	megabytes, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || megabytes <= 0 || megabytes > 1<<20 {
+		return 0, fmt.Errorf("invalid MAX_ARCHIVE_SIZE %q, expected a size in megabytes", value)
+	}
+
+	return megabytes << 20, nil
+}
+
+// storeArchive writes an uploaded archive to the import directory and
+// returns its path.
+func (app *application) storeArchive(r io.Reader) (string, error) {
+	err := os.MkdirAll(app.importDir, 0o700)
+	if err != nil {
+		return "", err
+	}
+
+	f, err := os.CreateTemp(app.importDir, "archive-*.zip")
+	if err != nil {
+		return "", err
+	}
+
+	_, err = io.Copy(f, r)
+	if errClose := f.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		app.removeArchive(f.Name())
+		return "", err
+	}
+
+	return f.Name(), nil
+}
+
+// countArchiveSwims checks an archive and returns the number of swims it
+// lists.
+func countArchiveSwims(file string) (int, error) {
+	total := 0
+	err := withArchive(file, func(archive *importer.Archive) error {
+		activities, err := archive.Swims()
+		total = len(activities)
+		return err
+	})
+	return total, err
+}
+
+// withArchive opens the archive at a path for reading.
+func withArchive(file string, read func(archive *importer.Archive) error) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	archive, err := importer.NewArchive(f, info.Size())
+	if err != nil {
+		return err
+	}
+
+	return read(archive)
+}
+
+// removeArchive deletes an archive that is no longer needed. A failure only
+// leaves a file behind, so it is logged instead of failing the request.
+func (app *application) removeArchive(file string) {
+	err := os.Remove(file)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		app.logger.Error("archive not removed", "file", file, "error", err)
+	}
+}
@@ -0,0 +1,461 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/rockstaedt/swimmate/internal/models"
+	"github.com/rockstaedt/swimmate/internal/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+const archiveProgressTemplate = `{{define "base"}}{{with .Data.Archive}}{{template "archive-progress" withPartial $ .}}{{end}}{{end}}` +
+	`{{define "archive-progress"}}{{with .Partial}}{{.Processed}}/{{.Total}} {{.Imported}}:{{.Skipped}}:{{.Failed}}{{if .Done}} done{{end}}{{end}}{{end}}`
+
+const testArchiveIndex = `Activity ID,Activity Date,Activity Name,Activity Type,Elapsed Time,Distance,Filename,Distance
+1,"Jan 15, 2024, 7:00:00 AM",Pool,Swim,1800,1.5,activities/1.fit,1500.0
+2,"Jan 16, 2024, 6:00:00 PM",Run,Run,1900,5.0,activities/2.gpx.gz,5000.0
+3,"Jan 17, 2024, 7:00:00 AM",Manual,Swim,1200,1.0,,1000.0
+4,"Jan 18, 2024, 7:00:00 AM",Broken,Swim,1200,0,activities/4.fit,0
+`
+
+func testArchive(t *testing.T, index string) []byte {
+	t.Helper()
+
+	fit, err := os.ReadFile("testdata/pool-swim.fit")
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range map[string][]byte{
+		"activities.csv":   []byte(index),
+		"activities/1.fit": fit,
+		"activities/4.fit": []byte("broken"),
+	} {
+		w, err := zw.Create(name)
+		assert.NoError(t, err)
+		_, err = w.Write(content)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func archiveFiles(t *testing.T, dir string) []string {
+	t.Helper()
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	assert.NoError(t, err)
+	return files
+}
+
+func TestUploadArchive(t *testing.T) {
+	tests := []struct {
+		name             string
+		content          []byte
+		expectedTotal    int
+		expectedLocation string
+		expectedFlash    string
+		expectedFiles    int
+	}{
+		{
+			name:             "export archive",
+			content:          testArchive(t, testArchiveIndex),
+			expectedTotal:    3,
+			expectedLocation: "/swims/import/archive/4",
+			expectedFiles:    1,
+		},
+		{
+			name:             "archive without swims",
+			content:          testArchive(t, "Activity ID,Activity Date,Activity Type\n"),
+			expectedLocation: "/swims/import/archive/4",
+		},
+		{
+			name:             "not a ZIP",
+			content:          []byte("not a zip"),
+			expectedLocation: "/swims/import",
+			expectedFlash:    "The archive cannot be imported: zip: not a valid zip file.",
+		},
+		{
+			name:             "invalid index",
+			content:          testArchive(t, "Activity ID,Activity Date,Activity Type\n1,yesterday,Swim\n"),
+			expectedLocation: "/swims/import",
+			expectedFlash:    `The archive cannot be imported: activities.csv line 2: invalid date "yesterday".`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var inserted *models.ImportJob
+			app := newTestApplication()
+			app.importDir = filepath.Join(t.TempDir(), "imports")
+			app.importJobs = &testutils.MockImportJobModel{
+				InsertFunc: func(job *models.ImportJob, userId int) error {
+					assert.Equal(t, 1, userId)
+					job.Id = 4
+					inserted = job
+					return nil
+				},
+			}
+
+			body := &bytes.Buffer{}
+			mw := multipart.NewWriter(body)
+			fw, _ := mw.CreateFormFile("file", "export_1234.zip")
+			_, _ = fw.Write(tt.content)
+			_ = mw.Close()
+
+			rr := httptest.NewRecorder()
+			r := newImportRequest(t, app, http.MethodPost, "/swims/import/archive", mw.FormDataContentType(), body)
+
+			app.uploadArchive(rr, r)
+
+			assert.Equal(t, http.StatusSeeOther, rr.Code)
+			assert.Equal(t, tt.expectedLocation, rr.Header().Get("Location"))
+			assert.Equal(t, tt.expectedFlash, app.sessionManager.GetString(r.Context(), "flashText"))
+			files := archiveFiles(t, app.importDir)
+			assert.Len(t, files, tt.expectedFiles)
+			if tt.expectedFlash == "" {
+				assert.Equal(t, tt.expectedTotal, inserted.Total)
+				assert.Equal(t, tt.expectedTotal == 0, inserted.Done)
+			} else {
+				assert.Nil(t, inserted)
+			}
+			if tt.expectedFiles > 0 {
+				assert.Equal(t, files[0], inserted.File)
+			}
+		})
+	}
+}
+
+func TestUploadArchiveWithoutFile(t *testing.T) {
+	app := newTestApplication()
+	app.importDir = t.TempDir()
+
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	_ = mw.WriteField("file", "")
+	_ = mw.Close()
+
+	rr := httptest.NewRecorder()
+	r := newImportRequest(t, app, http.MethodPost, "/swims/import/archive", mw.FormDataContentType(), body)
+
+	app.uploadArchive(rr, r)
+
+	assert.Equal(t, http.StatusSeeOther, rr.Code)
+	assert.Equal(t, "/swims/import", rr.Header().Get("Location"))
+	assert.Equal(t, "Choose an export archive.", app.sessionManager.GetString(r.Context(), "flashText"))
+}
+
+func TestUploadArchiveLimits(t *testing.T) {
+	tests := []struct {
+		name             string
+		running          int
+		maxArchiveSize   int64
+		expectedStatus   int
+		expectedLocation string
+		expectedFlash    string
+	}{
+		{
+			name:             "too many running imports",
+			running:          maxRunningArchives,
+			maxArchiveSize:   defaultMaxArchiveSize,
+			expectedStatus:   http.StatusSeeOther,
+			expectedLocation: "/swims/import",
+			expectedFlash:    "You have 3 archive imports that are not done. Finish them before uploading another archive.",
+		},
+		{
+			name:           "archive too large",
+			maxArchiveSize: 1 << 10,
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication()
+			app.importDir = t.TempDir()
+			app.maxArchiveSize = tt.maxArchiveSize
+			app.importJobs = &testutils.MockImportJobModel{
+				CountRunningFunc: func(userId int) (int, error) {
+					assert.Equal(t, 1, userId)
+					return tt.running, nil
+				},
+				InsertFunc: func(job *models.ImportJob, userId int) error {
+					t.Error("no job is started")
+					return nil
+				},
+			}
+
+			body := &bytes.Buffer{}
+			mw := multipart.NewWriter(body)
+			fw, _ := mw.CreateFormFile("file", "export_1234.zip")
+			_, _ = fw.Write(bytes.Repeat([]byte("x"), 4<<10))
+			_ = mw.Close()
+
+			rr := httptest.NewRecorder()
+			r := newImportRequest(t, app, http.MethodPost, "/swims/import/archive", mw.FormDataContentType(), body)
+
+			app.uploadArchive(rr, r)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.Equal(t, tt.expectedLocation, rr.Header().Get("Location"))
+			assert.Equal(t, tt.expectedFlash, app.sessionManager.GetString(r.Context(), "flashText"))
+			assert.Empty(t, archiveFiles(t, app.importDir), "nothing is kept on disk")
+		})
+	}
+}
+
+func TestParseArchiveSize(t *testing.T) {
+	tests := []struct {
+		value        string
+		expectedSize int64
+		expectedErr  bool
+	}{
+		{value: "", expectedSize: defaultMaxArchiveSize},
+		{value: "100", expectedSize: 100 << 20},
+		{value: "0", expectedErr: true},
+		{value: "-5", expectedErr: true},
+		{value: "1GB", expectedErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			size, err := parseArchiveSize(tt.value)
+
+			assert.Equal(t, tt.expectedErr, err != nil)
+			assert.Equal(t, tt.expectedSize, size)
+		})
+	}
+}
+
+func TestExpireArchives(t *testing.T) {
+	dir := t.TempDir()
+	before := time.Now().Add(-archiveLifetime)
+	old := before.Add(-time.Hour)
+
+	files := map[string]time.Time{
+		"archive-expired.zip": time.Now(),
+		"archive-orphan.zip":  old,
+		"archive-running.zip": time.Now(),
+		"notes.txt":           old,
+	}
+	for name, modified := range files {
+		file := filepath.Join(dir, name)
+		assert.NoError(t, os.WriteFile(file, []byte("zip"), 0o600))
+		assert.NoError(t, os.Chtimes(file, modified, modified))
+	}
+
+	app := newTestApplication()
+	app.importDir = dir
+	app.importJobs = &testutils.MockImportJobModel{
+		ExpireFunc: func(expireBefore time.Time) ([]string, error) {
+			assert.Equal(t, before, expireBefore)
+			return []string{filepath.Join(dir, "archive-expired.zip"), filepath.Join(dir, "archive-gone.zip")}, nil
+		},
+	}
+
+	app.expireArchives(before)
+
+	assert.Equal(t, []string{filepath.Join(dir, "archive-running.zip"), filepath.Join(dir, "notes.txt")}, archiveFiles(t, dir))
+}
+
+func newArchiveRequest(t *testing.T, app *application, method string, id string) *http.Request {
+	t.Helper()
+
+	r := newImportRequest(t, app, method, "/swims/import/archive/"+id, "", &bytes.Buffer{})
+	return r.WithContext(context.WithValue(r.Context(), httprouter.ParamsKey, httprouter.Params{{Key: "id", Value: id}}))
+}
+
+func TestShowArchive(t *testing.T) {
+	tests := []struct {
+		name           string
+		id             string
+		getErr         error
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "running job", id: "4", expectedStatus: http.StatusOK, expectedBody: "1/3 1:0:0"},
+		{name: "job of another user", id: "4", getErr: models.ErrNoRecord, expectedStatus: http.StatusNotFound},
+		{name: "invalid id", id: "abc", expectedStatus: http.StatusNotFound},
+		{name: "database error", id: "4", getErr: errors.New("database error"), expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication()
+			app.importJobs = &testutils.MockImportJobModel{
+				GetFunc: func(id int, userId int) (*models.ImportJob, error) {
+					assert.Equal(t, 4, id)
+					assert.Equal(t, 1, userId)
+					if tt.getErr != nil {
+						return nil, tt.getErr
+					}
+					return &models.ImportJob{Id: id, Total: 3, Processed: 1, Imported: 1}, nil
+				},
+			}
+			app.templateCache[importTemplate] = createTestTemplate("base", archiveProgressTemplate)
+
+			rr := httptest.NewRecorder()
+			app.showArchive(rr, newArchiveRequest(t, app, http.MethodGet, tt.id))
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			if tt.expectedBody != "" {
+				assert.Equal(t, tt.expectedBody, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestContinueArchive(t *testing.T) {
+	tests := []struct {
+		name            string
+		job             models.ImportJob
+		missingArchive  bool
+		insertErr       error
+		expectedSources []string
+		expectedStatus  int
+		expectedBody    string
+		expectedUpdated bool
+		expectedRemoved bool
+	}{
+		{
+			name:            "whole archive",
+			job:             models.ImportJob{Total: 3},
+			expectedSources: []string{"fit:3912345678:1074236400", "archive:3"},
+			expectedStatus:  http.StatusOK,
+			expectedBody:    "3/3 2:0:1 done",
+			expectedUpdated: true,
+			expectedRemoved: true,
+		},
+		{
+			name:            "continued where it stopped",
+			job:             models.ImportJob{Total: 3, Processed: 1, Imported: 1},
+			expectedSources: []string{"archive:3"},
+			expectedStatus:  http.StatusOK,
+			expectedBody:    "3/3 2:0:1 done",
+			expectedUpdated: true,
+			expectedRemoved: true,
+		},
+		{
+			name:            "activities imported before",
+			job:             models.ImportJob{Total: 3},
+			insertErr:       models.ErrDuplicateSource,
+			expectedSources: []string{"fit:3912345678:1074236400", "archive:3"},
+			expectedStatus:  http.StatusOK,
+			expectedBody:    "3/3 0:2:1 done",
+			expectedUpdated: true,
+			expectedRemoved: true,
+		},
+		{
+			name:            "activities the database rejects",
+			job:             models.ImportJob{Total: 3},
+			insertErr:       fmt.Errorf("%w: distance out of range", models.ErrInvalidSwim),
+			expectedSources: []string{"fit:3912345678:1074236400", "archive:3"},
+			expectedStatus:  http.StatusOK,
+			expectedBody:    "3/3 0:0:3 done",
+			expectedUpdated: true,
+			expectedRemoved: true,
+		},
+		{
+			name:            "archive gone",
+			job:             models.ImportJob{Total: 3, Processed: 1, Imported: 1},
+			missingArchive:  true,
+			expectedStatus:  http.StatusOK,
+			expectedBody:    "3/3 1:0:2 done",
+			expectedUpdated: true,
+		},
+		{
+			name:           "job done",
+			job:            models.ImportJob{Total: 3, Processed: 3, Imported: 3, Done: true},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "3/3 3:0:0 done",
+		},
+		{
+			name:            "database error",
+			job:             models.ImportJob{Total: 3},
+			insertErr:       errors.New("database error"),
+			expectedSources: []string{"fit:3912345678:1074236400"},
+			expectedStatus:  http.StatusInternalServerError,
+			expectedUpdated: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "archive-1.zip")
+			if !tt.missingArchive {
+				assert.NoError(t, os.WriteFile(file, testArchive(t, testArchiveIndex), 0o600))
+			}
+
+			var sources []string
+			var updated *models.ImportJob
+			app := newTestApplication()
+			app.swims = &testutils.MockSwimModel{
+				InsertFunc: func(swim *models.Swim, userId int) error {
+					assert.Equal(t, 1, userId)
+					sources = append(sources, swim.Source)
+					return tt.insertErr
+				},
+			}
+			app.importJobs = &testutils.MockImportJobModel{
+				GetFunc: func(id int, userId int) (*models.ImportJob, error) {
+					job := tt.job
+					job.Id = id
+					job.File = file
+					return &job, nil
+				},
+				UpdateFunc: func(job *models.ImportJob, userId int) error {
+					assert.Equal(t, 1, userId)
+					updated = job
+					return nil
+				},
+			}
+			app.templateCache[importTemplate] = createTestTemplate("base", archiveProgressTemplate)
+
+			rr := httptest.NewRecorder()
+			app.continueArchive(rr, newArchiveRequest(t, app, http.MethodPost, "4"))
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.Equal(t, tt.expectedSources, sources)
+			if tt.expectedBody != "" {
+				assert.Equal(t, tt.expectedBody, rr.Body.String())
+			}
+			assert.Equal(t, tt.expectedUpdated, updated != nil)
+			if tt.insertErr != nil && !errors.Is(tt.insertErr, models.ErrDuplicateSource) &&
+				!errors.Is(tt.insertErr, models.ErrInvalidSwim) {
+				assert.Equal(t, 0, updated.Processed, "the failed swim is read again")
+				assert.False(t, updated.Done)
+			}
+			_, err := os.Stat(file)
+			assert.Equal(t, tt.expectedRemoved || tt.missingArchive, errors.Is(err, os.ErrNotExist))
+		})
+	}
+}
+
+func TestImportSwimsShowsRunningArchive(t *testing.T) {
+	app := newTestApplication()
+	app.importJobs = &testutils.MockImportJobModel{
+		GetRunningFunc: func(userId int) (*models.ImportJob, error) {
+			assert.Equal(t, 1, userId)
+			return &models.ImportJob{Id: 4, Total: 10, Processed: 4, Imported: 4}, nil
+		},
+	}
+	app.templateCache[importTemplate] = createTestTemplate("base", archiveProgressTemplate)
+
+	rr := httptest.NewRecorder()
+	app.importSwims(rr, newImportRequest(t, app, http.MethodGet, "/swims/import", "", &bytes.Buffer{}))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "4/10 4:0:0", rr.Body.String())
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/rockstaedt/swimmate/internal/backup"
+	"github.com/rockstaedt/swimmate/internal/models"
+)
+
+// maxBackupSize limits uploaded backups, a decade of daily swims with sets
+// and notes stays well below it.
+const maxBackupSize = 10 << 20
+
+// downloadBackup sends all data of the user as a backup document.
+func (app *application) downloadBackup(w http.ResponseWriter, r *http.Request) {
+	b, err := app.backups.Get(app.sessionManager.GetInt(r.Context(), "authenticatedUserID"))
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	now := time.Now()
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="swimmate-backup-%s.json"`, now.Format("2006-01-02")))
+
+	err = backup.Encode(w, b, now)
+	if err != nil {
+		app.logger.Error(err.Error(), "method", r.Method, "uri", r.URL.RequestURI())
+	}
+}
+
+// restoreBackup loads an uploaded backup document into the account of the
+// user, merging it with the existing swims or replacing them.
+func (app *application) restoreBackup(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBackupSize)
+	err := r.ParseMultipartForm(maxBackupSize)
+	if err != nil {
+		app.logger.Error(err.Error())
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	mode := models.RestoreMode(r.PostForm.Get("mode"))
+	if !mode.Valid() {
+		app.logger.Error("invalid restore mode")
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		if !errors.Is(err, http.ErrMissingFile) {
+			app.logger.Error(err.Error())
+		}
+		app.sessionManager.Put(r.Context(), "flashText", "Choose a backup file to restore.")
+		app.sessionManager.Put(r.Context(), "flashType", "flash-error")
+		http.Redirect(w, r, "/account", http.StatusSeeOther)
+		return
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	b, err := backup.Decode(file)
+	if err != nil {
+		app.sessionManager.Put(r.Context(), "flashText", fmt.Sprintf("The backup cannot be restored: %s.", err))
+		app.sessionManager.Put(r.Context(), "flashType", "flash-error")
+		http.Redirect(w, r, "/account", http.StatusSeeOther)
+		return
+	}
+
+	restored, err := app.backups.Restore(app.sessionManager.GetInt(r.Context(), "authenticatedUserID"), b, mode)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	flash := fmt.Sprintf("Restored %d swims.", len(restored.Swims))
+	if skipped := len(b.Swims) - len(restored.Swims); skipped > 0 {
+		flash += fmt.Sprintf(" Skipped %d swims that were already there.", skipped)
+	}
+	app.sessionManager.Put(r.Context(), "flashText", flash)
+	app.sessionManager.Put(r.Context(), "flashType", "flash-success")
+
+	http.Redirect(w, r, "/swims", http.StatusSeeOther)
+}
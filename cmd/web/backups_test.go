@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rockstaedt/swimmate/internal/backup"
+	"github.com/rockstaedt/swimmate/internal/models"
+	"github.com/rockstaedt/swimmate/internal/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDownloadBackup(t *testing.T) {
+	tests := []struct {
+		name           string
+		getErr         error
+		expectedStatus int
+	}{
+		{
+			name:           "backup of the user",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "database error",
+			getErr:         errors.New("database error"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication()
+			app.backups = &testutils.MockBackupModel{
+				GetFunc: func(userId int) (*models.Backup, error) {
+					assert.Equal(t, 1, userId)
+					if tt.getErr != nil {
+						return nil, tt.getErr
+					}
+					return &models.Backup{
+						User:  models.User{Username: "jane", DistanceUnit: models.UnitMeters},
+						Swims: []*models.Swim{{Date: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), DistanceM: 1500, Stroke: models.StrokeFreestyle, Feel: models.FeelGood}},
+					}, nil
+				},
+			}
+
+			rr := httptest.NewRecorder()
+			r := newImportRequest(t, app, http.MethodGet, "/account/backup.json", "", &bytes.Buffer{})
+
+			app.downloadBackup(rr, r)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			if tt.expectedStatus == http.StatusOK {
+				assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+				assert.Contains(t, rr.Header().Get("Content-Disposition"), `attachment; filename="swimmate-backup-`)
+
+				b, err := backup.Decode(rr.Body)
+				assert.NoError(t, err)
+				assert.Equal(t, "jane", b.User.Username)
+				assert.Len(t, b.Swims, 1)
+			}
+		})
+	}
+}
+
+func TestRestoreBackup(t *testing.T) {
+	var document bytes.Buffer
+	err := backup.Encode(&document, &models.Backup{
+		User: models.User{DistanceUnit: models.UnitMeters},
+		Swims: []*models.Swim{
+			{Date: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), DistanceM: 1500, Stroke: models.StrokeFreestyle, Feel: models.FeelGood},
+			{Date: time.Date(2024, 1, 17, 0, 0, 0, 0, time.UTC), DistanceM: 800, Stroke: models.StrokeFreestyle, Feel: models.FeelOkay},
+		},
+	}, time.Now())
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name             string
+		mode             string
+		file             string
+		restored         int
+		restoreErr       error
+		expectedStatus   int
+		expectedLocation string
+		expectedFlash    string
+	}{
+		{
+			name:             "merge",
+			mode:             "merge",
+			file:             document.String(),
+			restored:         1,
+			expectedStatus:   http.StatusSeeOther,
+			expectedLocation: "/swims",
+			expectedFlash:    "Restored 1 swims. Skipped 1 swims that were already there.",
+		},
+		{
+			name:             "replace",
+			mode:             "replace",
+			file:             document.String(),
+			restored:         2,
+			expectedStatus:   http.StatusSeeOther,
+			expectedLocation: "/swims",
+			expectedFlash:    "Restored 2 swims.",
+		},
+		{
+			name:             "not a backup",
+			mode:             "merge",
+			file:             "date,distance\n2024-01-15,1500\n",
+			expectedStatus:   http.StatusSeeOther,
+			expectedLocation: "/account",
+			expectedFlash:    "The backup cannot be restored: backup: not a SwimMate backup.",
+		},
+		{
+			name:             "no file",
+			mode:             "merge",
+			expectedStatus:   http.StatusSeeOther,
+			expectedLocation: "/account",
+			expectedFlash:    "Choose a backup file to restore.",
+		},
+		{
+			name:           "unknown mode",
+			mode:           "append",
+			file:           document.String(),
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "database error",
+			mode:           "replace",
+			file:           document.String(),
+			restoreErr:     errors.New("database error"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication()
+			app.backups = &testutils.MockBackupModel{
+				RestoreFunc: func(userId int, b *models.Backup, mode models.RestoreMode) (*models.Restored, error) {
+					assert.Equal(t, 1, userId)
+					assert.Equal(t, models.RestoreMode(tt.mode), mode)
+					assert.Len(t, b.Swims, 2)
+					if tt.restoreErr != nil {
+						return nil, tt.restoreErr
+					}
+					return &models.Restored{Swims: b.Swims[:tt.restored]}, nil
+				},
+			}
+
+			body := &bytes.Buffer{}
+			mw := multipart.NewWriter(body)
+			_ = mw.WriteField("mode", tt.mode)
+			if tt.file != "" {
+				fw, _ := mw.CreateFormFile("file", "backup.json")
+				_, _ = fw.Write([]byte(tt.file))
+			}
+			_ = mw.Close()
+
+			rr := httptest.NewRecorder()
+			r := newImportRequest(t, app, http.MethodPost, "/account/restore", mw.FormDataContentType(), body)
+
+			app.restoreBackup(rr, r)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			if tt.expectedLocation != "" {
+				assert.Equal(t, tt.expectedLocation, rr.Header().Get("Location"))
+			}
+			if tt.expectedFlash != "" {
+				assert.Equal(t, tt.expectedFlash, app.sessionManager.GetString(r.Context(), "flashText"))
+			}
+		})
+	}
+}
+
+func TestRestoreBackupTooLarge(t *testing.T) {
+	app := newTestApplication()
+
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	_ = mw.WriteField("mode", "merge")
+	fw, _ := mw.CreateFormFile("file", "backup.json")
+	_, _ = fw.Write([]byte(strings.Repeat(" ", maxBackupSize+1)))
+	_ = mw.Close()
+
+	rr := httptest.NewRecorder()
+	r := newImportRequest(t, app, http.MethodPost, "/account/restore", mw.FormDataContentType(), body)
+
+	app.restoreBackup(rr, r)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/rockstaedt/swimmate/internal/ical"
+	"github.com/rockstaedt/swimmate/internal/models"
+)
+
+// calendarPathPrefix starts the URLs of calendar feeds, which are followed by
+// the secret token of the feed.
+const calendarPathPrefix = "/calendar/"
+
+// calendarFeed sends the swims of the user a feed token belongs to as an
+// iCalendar feed. Calendar apps cannot log in, so the token in the URL takes
+// the place of the session.
+func (app *application) calendarFeed(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+	userId, err := app.calendars.UserID(params.ByName("token"))
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	user, err := app.users.Get(userId)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	swims, err := app.swims.GetAll(userId)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	var buf bytes.Buffer
+	err = ical.Encode(&buf, ical.Calendar{
+		Name:   "SwimMate",
+		Domain: app.publicHostname(r),
+		Unit:   user.DistanceUnit,
+		Swims:  swims,
+	}, time.Now())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Cache-Control", "private, no-cache")
+
+	_, err = buf.WriteTo(w)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// resetCalendar creates a new feed URL for the user, which revokes the URL
+// before. The URL is shown once on the account page.
+func (app *application) resetCalendar(w http.ResponseWriter, r *http.Request) {
+	token, err := app.calendars.Reset(app.sessionManager.GetInt(r.Context(), "authenticatedUserID"))
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "calendarURL", app.calendarURL(r, token))
+	app.sessionManager.Put(r.Context(), "flashText", "Your calendar feed is ready. Copy its URL now, it is shown only once.")
+	app.sessionManager.Put(r.Context(), "flashType", "flash-success")
+
+	http.Redirect(w, r, "/account", http.StatusSeeOther)
+}
+
+// deleteCalendar revokes the feed URL of the user.
+func (app *application) deleteCalendar(w http.ResponseWriter, r *http.Request) {
+	err := app.calendars.Delete(app.sessionManager.GetInt(r.Context(), "authenticatedUserID"))
+	if err != nil && !errors.Is(err, models.ErrNoRecord) {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flashText", "Calendar feed revoked.")
+	app.sessionManager.Put(r.Context(), "flashType", "flash-success")
+
+	http.Redirect(w, r, "/account", http.StatusSeeOther)
+}
+
+// calendarURL is the absolute URL of the feed of a token, for pasting into a
+// calendar app.
+func (app *application) calendarURL(r *http.Request, token string) string {
+	return app.publicURL(r, calendarPathPrefix+token+"/swims.ics")
+}
+
+// absoluteURL is the URL of a path on the host a request was sent to, for
+// links that are used outside of the app.
+func absoluteURL(r *http.Request, path string) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + path
+}
+
+// publicHostname is the host of BASE_URL without its port, or the one of the
+// request if BASE_URL is not set. It names the app in the UIDs of calendar
+// events, which must not change with the name the app is reached by.
+func (app *application) publicHostname(r *http.Request) string {
+	if app.baseURL != "" {
+		if u, err := url.Parse(app.baseURL); err == nil && u.Hostname() != "" {
+			return u.Hostname()
+		}
+	}
+	return hostname(r)
+}
+
+// hostname is the host of a request without its port.
+func hostname(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		return r.Host
+	}
+	return host
+}
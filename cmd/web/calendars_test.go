@@ -0,0 +1,309 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/rockstaedt/swimmate/internal/models"
+	"github.com/rockstaedt/swimmate/internal/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalendarFeed(t *testing.T) {
+	tests := []struct {
+		name           string
+		token          string
+		baseURL        string
+		userIdErr      error
+		getAllErr      error
+		expectedStatus int
+		expectedBody   string
+		expectedUID    string
+	}{
+		{
+			name:           "feed of the token",
+			token:          "SECRET",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "SUMMARY:Swim 1640 yd · Good · RPE 7\r\n",
+			expectedUID:    "UID:swim-12@swimmate.example.com\r\n",
+		},
+		{
+			name:           "feed with a base URL",
+			token:          "SECRET",
+			baseURL:        "https://swim.example.org:8443",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "SUMMARY:Swim 1640 yd · Good · RPE 7\r\n",
+			expectedUID:    "UID:swim-12@swim.example.org\r\n",
+		},
+		{
+			name:           "unknown or revoked token",
+			token:          "WRONG",
+			userIdErr:      models.ErrNoRecord,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "token lookup fails",
+			token:          "SECRET",
+			userIdErr:      errors.New("database error"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name:           "swims cannot be loaded",
+			token:          "SECRET",
+			getAllErr:      errors.New("database error"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication()
+			app.baseURL = tt.baseURL
+			app.calendars = &testutils.MockCalendarModel{
+				UserIDFunc: func(token string) (int, error) {
+					assert.Equal(t, tt.token, token)
+					return 3, tt.userIdErr
+				},
+			}
+			app.users = &testutils.MockUserModel{
+				GetFunc: func(id int) (*models.User, error) {
+					assert.Equal(t, 3, id)
+					return &models.User{ID: id, DistanceUnit: models.UnitYards}, nil
+				},
+			}
+			app.swims = &testutils.MockSwimModel{
+				GetAllFunc: func(userId int) ([]*models.Swim, error) {
+					assert.Equal(t, 3, userId)
+					if tt.getAllErr != nil {
+						return nil, tt.getAllErr
+					}
+					return []*models.Swim{{Id: 12, Date: time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC), DistanceM: 1500, Feel: models.FeelGood, Effort: 7, Stroke: models.StrokeFreestyle, Tags: []string{"technique"}}}, nil
+				},
+			}
+
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "http://swimmate.example.com:8998/calendar/"+tt.token+"/swims.ics", nil)
+			ctx := context.WithValue(r.Context(), httprouter.ParamsKey, httprouter.Params{{Key: "token", Value: tt.token}})
+			r = r.WithContext(ctx)
+
+			app.calendarFeed(rr, r)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			if tt.expectedStatus == http.StatusOK {
+				assert.Equal(t, "text/calendar; charset=utf-8", rr.Header().Get("Content-Type"))
+				assert.Contains(t, rr.Body.String(), tt.expectedUID, "the UIDs do not depend on the Host header if BASE_URL is set")
+				assert.Contains(t, rr.Body.String(), tt.expectedBody)
+				assert.Contains(t, rr.Body.String(), `\nTags: technique`)
+			}
+		})
+	}
+}
+
+func TestResetCalendar(t *testing.T) {
+	tests := []struct {
+		name             string
+		resetErr         error
+		tls              bool
+		baseURL          string
+		expectedStatus   int
+		expectedURL      string
+		expectedLocation string
+	}{
+		{
+			name:             "new feed URL",
+			expectedStatus:   http.StatusSeeOther,
+			expectedURL:      "http://example.com/calendar/TOKEN/swims.ics",
+			expectedLocation: "/account",
+		},
+		{
+			name:             "new feed URL over TLS",
+			tls:              true,
+			expectedStatus:   http.StatusSeeOther,
+			expectedURL:      "https://example.com/calendar/TOKEN/swims.ics",
+			expectedLocation: "/account",
+		},
+		{
+			name:             "new feed URL with a base URL",
+			baseURL:          "https://swim.example.org",
+			expectedStatus:   http.StatusSeeOther,
+			expectedURL:      "https://swim.example.org/calendar/TOKEN/swims.ics",
+			expectedLocation: "/account",
+		},
+		{
+			name:           "database error",
+			resetErr:       errors.New("database error"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication()
+			app.baseURL = tt.baseURL
+			app.calendars = &testutils.MockCalendarModel{
+				ResetFunc: func(userId int) (string, error) {
+					assert.Equal(t, 1, userId)
+					if tt.resetErr != nil {
+						return "", tt.resetErr
+					}
+					return "TOKEN", nil
+				},
+			}
+
+			rr := httptest.NewRecorder()
+			r := newImportRequest(t, app, http.MethodPost, "/account/calendar", "", &bytes.Buffer{})
+			if tt.tls {
+				r.TLS = &tls.ConnectionState{}
+			}
+
+			app.resetCalendar(rr, r)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.Equal(t, tt.expectedLocation, rr.Header().Get("Location"))
+			assert.Equal(t, tt.expectedURL, app.sessionManager.GetString(r.Context(), "calendarURL"))
+		})
+	}
+}
+
+func TestDeleteCalendar(t *testing.T) {
+	tests := []struct {
+		name           string
+		deleteErr      error
+		expectedStatus int
+	}{
+		{
+			name:           "feed revoked",
+			expectedStatus: http.StatusSeeOther,
+		},
+		{
+			name:           "no feed",
+			deleteErr:      models.ErrNoRecord,
+			expectedStatus: http.StatusSeeOther,
+		},
+		{
+			name:           "database error",
+			deleteErr:      errors.New("database error"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication()
+			app.calendars = &testutils.MockCalendarModel{
+				DeleteFunc: func(userId int) error {
+					assert.Equal(t, 1, userId)
+					return tt.deleteErr
+				},
+			}
+
+			rr := httptest.NewRecorder()
+			r := newImportRequest(t, app, http.MethodPost, "/account/calendar/delete", "", &bytes.Buffer{})
+
+			app.deleteCalendar(rr, r)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			if tt.expectedStatus == http.StatusSeeOther {
+				assert.Equal(t, "/account", rr.Header().Get("Location"))
+				assert.Equal(t, "Calendar feed revoked.", app.sessionManager.GetString(r.Context(), "flashText"))
+			}
+		})
+	}
+}
+
+func TestAccountCalendar(t *testing.T) {
+	created := time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		feed         *models.CalendarFeed
+		getErr       error
+		calendarURL  string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "no feed",
+			getErr:       models.ErrNoRecord,
+			expectedCode: http.StatusOK,
+			expectedBody: "jane none",
+		},
+		{
+			name:         "existing feed",
+			feed:         &models.CalendarFeed{Created: created},
+			expectedCode: http.StatusOK,
+			expectedBody: "jane 2024-02-01",
+		},
+		{
+			name:         "feed just created",
+			feed:         &models.CalendarFeed{Created: created},
+			calendarURL:  "http://example.com/calendar/TOKEN/swims.ics",
+			expectedCode: http.StatusOK,
+			expectedBody: "jane 2024-02-01 http://example.com/calendar/TOKEN/swims.ics",
+		},
+		{
+			name:         "database error",
+			getErr:       errors.New("database error"),
+			expectedCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication()
+			app.users = &testutils.MockUserModel{
+				GetFunc: func(id int) (*models.User, error) {
+					return &models.User{ID: id, Username: "jane", DistanceUnit: models.UnitMeters}, nil
+				},
+			}
+			app.calendars = &testutils.MockCalendarModel{
+				GetFunc: func(userId int) (*models.CalendarFeed, error) {
+					assert.Equal(t, 1, userId)
+					return tt.feed, tt.getErr
+				},
+			}
+			app.templateCache["account.tmpl"] = createTestTemplate("base", `{{define "base"}}{{.Data.Username}} {{with .Data.Calendar}}{{.Created.Format "2006-01-02"}}{{else}}none{{end}}{{with .Data.CalendarURL}} {{.}}{{end}}{{end}}`)
+
+			rr := httptest.NewRecorder()
+			r := newImportRequest(t, app, http.MethodGet, "/account", "", &bytes.Buffer{})
+			if tt.calendarURL != "" {
+				app.sessionManager.Put(r.Context(), "calendarURL", tt.calendarURL)
+			}
+
+			app.account(rr, r)
+
+			assert.Equal(t, tt.expectedCode, rr.Code)
+			if tt.expectedBody != "" {
+				assert.Equal(t, tt.expectedBody, rr.Body.String())
+			}
+			assert.Empty(t, app.sessionManager.GetString(r.Context(), "calendarURL"), "the URL is shown once")
+		})
+	}
+}
+
+func TestRequestURI(t *testing.T) {
+	tests := []struct {
+		target   string
+		expected string
+	}{
+		{target: "/swims?page=2", expected: "/swims?page=2"},
+		{target: "/calendar/SECRET/swims.ics", expected: "/calendar/[token]/swims.ics"},
+		{target: "/calendar/SECRET", expected: "/calendar/[token]/"},
+		{target: "/reset-password/SECRET", expected: "/reset-password/[token]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+
+			assert.Equal(t, tt.expected, requestURI(r))
+		})
+	}
+}
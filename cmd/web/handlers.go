@@ -1,12 +1,16 @@
 package main
 
 import (
+	"encoding/csv"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/url"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/rockstaedt/swimmate/internal/models"
@@ -22,19 +26,44 @@ type swimsPageData struct {
 	Offset    int
 	Sort      string
 	Direction string
-	LoadMore  *loadMoreData
+	Filter    models.SwimFilter
+	// Tags are all tags of the user, offered as filters.
+	Tags     []string
+	LoadMore *loadMoreData
+}
+
+type createSwimPageData struct {
+	Tags      []tagOption
+	Locations []locationOption
+	// Pool is preselected from the default location.
+	Pool models.Pool
 }
 
 type editSwimPageData struct {
 	Swim      *models.Swim
 	Sort      string
 	Direction string
+	Tags      []tagOption
+	Locations []locationOption
+}
+
+// tagOption is a tag offered as a checkbox on the swim forms.
+type tagOption struct {
+	Name    string
+	Checked bool
+}
+
+// locationOption is a location offered in the picker of the swim forms.
+type locationOption struct {
+	*models.Location
+	Selected bool
 }
 
 type loadMoreData struct {
 	NextOffset int
 	Sort       string
 	Direction  string
+	Filter     models.SwimFilter
 }
 
 func (app *application) home(w http.ResponseWriter, r *http.Request) {
@@ -44,11 +73,11 @@ func (app *application) home(w http.ResponseWriter, r *http.Request) {
 
 	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
 
-	app.render(w, r, http.StatusOK, "home.tmpl", app.newTemplateData(r, app.swims.Summarize(userId)))
+	app.render(w, r, http.StatusOK, "home.tmpl", app.newTemplateData(r, app.swims.Summarize(userId, models.SwimFilter{})))
 }
 
 func (app *application) login(w http.ResponseWriter, r *http.Request) {
-	app.render(w, r, http.StatusOK, "login.tmpl", app.newTemplateData(r, nil))
+	app.render(w, r, http.StatusOK, "login.tmpl", app.newTemplateData(r, app.loginPageData()))
 }
 
 func (app *application) authenticate(w http.ResponseWriter, r *http.Request) {
@@ -64,7 +93,7 @@ func (app *application) authenticate(w http.ResponseWriter, r *http.Request) {
 		if errors.Is(err, models.ErrInvalidCredentials) {
 			app.sessionManager.Put(r.Context(), "flashText", "Invalid credentials.")
 			app.sessionManager.Put(r.Context(), "flashType", "flash-error")
-			app.render(w, r, http.StatusOK, "login.tmpl", app.newTemplateData(r, nil))
+			app.render(w, r, http.StatusOK, "login.tmpl", app.newTemplateData(r, app.loginPageData()))
 			return
 		}
 
@@ -72,18 +101,46 @@ func (app *application) authenticate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = app.sessionManager.RenewToken(r.Context())
+	twoFactor, err := app.twoFactor.Enabled(id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if twoFactor {
+		app.startTwoFactorLogin(w, r, id)
+		return
+	}
+
+	app.logIn(w, r, id, "Successfully logged in.")
+}
+
+// logIn renews the session and logs a user in whose credentials were
+// checked.
+func (app *application) logIn(w http.ResponseWriter, r *http.Request, id int, text string) {
+	err := app.sessionManager.RenewToken(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	user, err := app.users.Get(id)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
 
 	app.sessionManager.Put(r.Context(), "authenticatedUserID", id)
-	app.sessionManager.Put(r.Context(), "flashText", "Successfully logged in.")
+	app.sessionManager.Put(r.Context(), "distanceUnit", string(user.DistanceUnit))
+	app.sessionManager.Put(r.Context(), "flashText", text)
 
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
 
+func (app *application) loginPageData() loginPageData {
+	return loginPageData{SignupOpen: app.registration == registrationOpen}
+}
+
 func (app *application) logout(w http.ResponseWriter, r *http.Request) {
 	err := app.sessionManager.RenewToken(r.Context())
 	if err != nil {
@@ -92,6 +149,7 @@ func (app *application) logout(w http.ResponseWriter, r *http.Request) {
 	}
 
 	app.sessionManager.Remove(r.Context(), "authenticatedUserID")
+	app.sessionManager.Remove(r.Context(), "distanceUnit")
 	app.sessionManager.Put(r.Context(), "flashText", "Successfully logged out.")
 
 	http.Redirect(w, r, "/login", http.StatusSeeOther)
@@ -103,11 +161,21 @@ func (app *application) yearlyFigures(w http.ResponseWriter, r *http.Request) {
 		year, _ = strconv.Atoi(r.URL.Query().Get("year"))
 	}
 
-	summary := app.swims.Summarize(app.sessionManager.GetInt(r.Context(), "authenticatedUserID"))
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+	tags, err := app.tags.GetAll(userId)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	filter := parseSwimFilter(r)
+	summary := app.swims.Summarize(userId, filter)
 	data := struct {
 		Summary *models.SwimSummary
 		Year    int
-	}{summary, year}
+		Filter  models.SwimFilter
+		Tags    []string
+	}{summary, year, filter, tags}
 
 	app.render(w, r, http.StatusOK, "yearly-figures.tmpl", app.newTemplateData(r, data))
 }
@@ -116,8 +184,116 @@ func (app *application) about(w http.ResponseWriter, r *http.Request) {
 	app.render(w, r, http.StatusOK, "about.tmpl", app.newTemplateData(r, nil))
 }
 
+type accountPageData struct {
+	*models.User
+	// Calendar is the calendar feed of the user, nil if there is none.
+	Calendar *models.CalendarFeed
+	// CalendarURL is the URL of a feed that was just created. It cannot be
+	// shown later, since only a hash of its token is stored.
+	CalendarURL string
+	// CanInvite is set while registration is invite-only.
+	CanInvite bool
+	// InviteURL is the sign up link of an invite that was just created.
+	InviteURL string
+	// TwoFactorAvailable is set if the server can store TOTP secrets.
+	TwoFactorAvailable bool
+	TwoFactorEnabled   bool
+}
+
+func (app *application) account(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+	user, err := app.users.Get(userId)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	calendar, err := app.calendars.Get(userId)
+	if err != nil && !errors.Is(err, models.ErrNoRecord) {
+		app.serverError(w, r, err)
+		return
+	}
+
+	twoFactor, err := app.twoFactor.Enabled(userId)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := accountPageData{
+		User:               user,
+		Calendar:           calendar,
+		CalendarURL:        app.sessionManager.PopString(r.Context(), "calendarURL"),
+		CanInvite:          app.registration == registrationInvite,
+		InviteURL:          app.sessionManager.PopString(r.Context(), "inviteURL"),
+		TwoFactorAvailable: app.twoFactorAvailable,
+		TwoFactorEnabled:   twoFactor,
+	}
+
+	app.render(w, r, http.StatusOK, "account.tmpl", app.newTemplateData(r, data))
+}
+
+func (app *application) updatePreferences(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		app.logger.Error(err.Error())
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	unit := models.Unit(r.PostForm.Get("distance_unit"))
+	if !unit.Valid() {
+		app.logger.Error("invalid distance unit")
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+	err = app.users.UpdateDistanceUnit(userId, unit)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "distanceUnit", string(unit))
+	app.sessionManager.Put(r.Context(), "flashText", "Preferences saved.")
+	app.sessionManager.Put(r.Context(), "flashType", "flash-success")
+
+	http.Redirect(w, r, "/account", http.StatusSeeOther)
+}
+
 func (app *application) createSwim(w http.ResponseWriter, r *http.Request) {
-	app.render(w, r, http.StatusOK, "swim-create.tmpl", app.newTemplateData(r, nil))
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+	tags, err := app.tags.GetAll(userId)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	locations, err := app.locations.GetAll(userId)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	// New swims default to the location of the last swim
+	lastUsed, err := app.locations.LastUsedID(userId)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := createSwimPageData{
+		Tags:      tagOptions(tags, nil),
+		Locations: locationOptions(locations, lastUsed),
+	}
+	for _, location := range locations {
+		if location.Id == lastUsed {
+			data.Pool = location.Pool
+		}
+	}
+
+	app.render(w, r, http.StatusOK, "swim-create.tmpl", app.newTemplateData(r, data))
 }
 
 func (app *application) editSwim(w http.ResponseWriter, r *http.Request) {
@@ -139,11 +315,25 @@ func (app *application) editSwim(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	tags, err := app.tags.GetAll(userId)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	locations, err := app.locations.GetAll(userId)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
 	sort, direction := parseSwimSort(r)
 	data := editSwimPageData{
 		Swim:      swim,
 		Sort:      sort,
 		Direction: direction,
+		Tags:      tagOptions(tags, swim.Tags),
+		Locations: locationOptions(locations, swim.LocationId),
 	}
 
 	app.render(w, r, http.StatusOK, "swim-edit.tmpl", app.newTemplateData(r, data))
@@ -152,8 +342,15 @@ func (app *application) editSwim(w http.ResponseWriter, r *http.Request) {
 func (app *application) swimsList(w http.ResponseWriter, r *http.Request) {
 	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
 	sort, direction := parseSwimSort(r)
+	filter := parseSwimFilter(r)
 
-	swims, err := app.swims.GetPaginated(userId, itemsPerPage, 0, sort, direction)
+	swims, err := app.swims.GetPaginated(userId, itemsPerPage, 0, sort, direction, filter)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	tags, err := app.tags.GetAll(userId)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
@@ -164,7 +361,9 @@ func (app *application) swimsList(w http.ResponseWriter, r *http.Request) {
 		Offset:    0,
 		Sort:      sort,
 		Direction: direction,
-		LoadMore:  newLoadMoreData(len(swims) == itemsPerPage, itemsPerPage, sort, direction),
+		Filter:    filter,
+		Tags:      tags,
+		LoadMore:  newLoadMoreData(len(swims) == itemsPerPage, itemsPerPage, sort, direction, filter),
 	}
 
 	app.render(w, r, http.StatusOK, swimsTemplate, app.newTemplateData(r, data))
@@ -173,6 +372,7 @@ func (app *application) swimsList(w http.ResponseWriter, r *http.Request) {
 func (app *application) swimsMore(w http.ResponseWriter, r *http.Request) {
 	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
 	sort, direction := parseSwimSort(r)
+	filter := parseSwimFilter(r)
 
 	offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
 	if err != nil {
@@ -182,19 +382,19 @@ func (app *application) swimsMore(w http.ResponseWriter, r *http.Request) {
 	// Check if this is an HTMX request
 	if r.Header.Get("HX-Request") == "true" {
 		// Return partial HTML for HTMX
-		swims, err := app.swims.GetPaginated(userId, itemsPerPage, offset, sort, direction)
+		swims, err := app.swims.GetPaginated(userId, itemsPerPage, offset, sort, direction, filter)
 		if err != nil {
 			app.serverError(w, r, err)
 			return
 		}
 
-		partialPageData := &swimsPageData{Sort: sort, Direction: direction}
+		partialPageData := &swimsPageData{Sort: sort, Direction: direction, Filter: filter}
 		for _, swim := range swims {
 			app.renderPartial(w, r, swimsTemplate, "swim-row", partialPageData, swim)
 		}
 
 		// Add the new button row or end
-		if loadMore := newLoadMoreData(len(swims) == itemsPerPage, offset+itemsPerPage, sort, direction); loadMore != nil {
+		if loadMore := newLoadMoreData(len(swims) == itemsPerPage, offset+itemsPerPage, sort, direction, filter); loadMore != nil {
 			app.renderPartial(w, r, swimsTemplate, "load-more-button", partialPageData, loadMore)
 		}
 		return
@@ -202,7 +402,13 @@ func (app *application) swimsMore(w http.ResponseWriter, r *http.Request) {
 
 	// For direct browser requests, show full page with all swims up to offset + 20
 	limit := offset + itemsPerPage
-	swims, err := app.swims.GetPaginated(userId, limit, 0, sort, direction)
+	swims, err := app.swims.GetPaginated(userId, limit, 0, sort, direction, filter)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	tags, err := app.tags.GetAll(userId)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
@@ -213,53 +419,123 @@ func (app *application) swimsMore(w http.ResponseWriter, r *http.Request) {
 		Offset:    offset,
 		Sort:      sort,
 		Direction: direction,
-		LoadMore:  newLoadMoreData(len(swims) == limit, limit, sort, direction),
+		Filter:    filter,
+		Tags:      tags,
+		LoadMore:  newLoadMoreData(len(swims) == limit, limit, sort, direction, filter),
 	}
 
 	app.render(w, r, http.StatusOK, swimsTemplate, app.newTemplateData(r, data))
 }
 
-func (app *application) storeSwim(w http.ResponseWriter, r *http.Request) {
-	err := r.ParseForm()
+// swimsCSVHeader names the columns of the CSV export. Distances are in meters
+// and durations in seconds regardless of the user's unit, so exports stay
+// comparable.
+var swimsCSVHeader = []string{"date", "distance_m", "stroke", "duration_s", "pool_length", "pool_unit", "laps", "effort", "feel", "notes"}
+
+// exportSwims streams all swims of the user as CSV. It takes the sort and
+// filter parameters of the swims list plus an optional from/to date range.
+// Rows are written while they are read from the database instead of being
+// buffered like rendered pages.
+func (app *application) exportSwims(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+	sort, direction := parseSwimSort(r)
+	filter := parseSwimFilter(r)
+
+	var err error
+	filter.From, filter.To, err = parseDateRange(r)
 	if err != nil {
 		app.logger.Error(err.Error())
 		app.clientError(w, http.StatusBadRequest)
 		return
 	}
 
-	date, err := time.Parse("2006-01-02", r.PostForm.Get("date"))
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="swims.csv"`)
+
+	cw := csv.NewWriter(w)
+	err = cw.Write(swimsCSVHeader)
 	if err != nil {
-		app.logger.Error(err.Error())
-		app.clientError(w, http.StatusBadRequest)
+		app.serverError(w, r, err)
 		return
 	}
 
-	distanceM, err := strconv.Atoi(r.PostForm.Get("distance_m"))
+	rows := 0
+	err = app.swims.Export(userId, sort, direction, filter, func(swim *models.Swim) error {
+		rows++
+		return cw.Write(swimCSVRecord(swim))
+	})
 	if err != nil {
-		app.logger.Error(err.Error())
-		app.clientError(w, http.StatusBadRequest)
+		if rows == 0 {
+			// Nothing but the buffered header row has been produced, so the
+			// response can still turn into an error page.
+			w.Header().Del("Content-Disposition")
+			app.serverError(w, r, err)
+			return
+		}
+		// Rows may already be on their way to the client, so the status can
+		// no longer change. All that is left is to cut the download short.
+		app.logger.Error(err.Error(), "method", r.Method, "uri", r.URL.RequestURI(), "rows", rows)
 		return
 	}
-	if distanceM <= 0 {
-		app.logger.Error("invalid distance value")
-		app.clientError(w, http.StatusBadRequest)
-		return
+
+	cw.Flush()
+	err = cw.Error()
+	if err != nil {
+		app.logger.Error(err.Error(), "method", r.Method, "uri", r.URL.RequestURI())
 	}
+}
 
-	assessment, err := strconv.Atoi(r.PostForm.Get("assessment"))
+func swimCSVRecord(swim *models.Swim) []string {
+	return []string{
+		swim.Date.Format("2006-01-02"),
+		strconv.Itoa(swim.DistanceM),
+		string(swim.Stroke),
+		optionalInt(int(swim.Duration / time.Second)),
+		optionalInt(swim.Pool.Length),
+		string(swim.Pool.Unit),
+		optionalInt(swim.Laps),
+		optionalInt(int(swim.Effort)),
+		strconv.Itoa(int(swim.Feel)),
+		swim.Notes,
+	}
+}
+
+// optionalInt leaves a CSV cell empty for optional values that are not set.
+func optionalInt(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+func (app *application) storeSwim(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
 	if err != nil {
 		app.logger.Error(err.Error())
 		app.clientError(w, http.StatusBadRequest)
 		return
 	}
-	if assessment < 0 || assessment > 2 {
-		app.logger.Error("invalid assessment value")
+
+	swim, err := swimFromForm(r.PostForm, app.distanceUnit(r))
+	if err != nil {
+		app.logger.Error(err.Error())
 		app.clientError(w, http.StatusBadRequest)
 		return
 	}
 
 	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
-	err = app.swims.Insert(date, distanceM, assessment, userId)
+	err = app.checkLocation(userId, swim.LocationId)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.logger.Error("unknown location")
+			app.clientError(w, http.StatusBadRequest)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	err = app.swims.Insert(swim, userId)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
@@ -285,42 +561,30 @@ func (app *application) updateSwim(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	date, err := time.Parse("2006-01-02", r.PostForm.Get("date"))
+	swim, err := swimFromForm(r.PostForm, app.distanceUnit(r))
 	if err != nil {
 		app.logger.Error(err.Error())
 		app.clientError(w, http.StatusBadRequest)
 		return
 	}
+	swim.Id = swimID
 
-	distanceM, err := strconv.Atoi(r.PostForm.Get("distance_m"))
-	if err != nil {
-		app.logger.Error(err.Error())
-		app.clientError(w, http.StatusBadRequest)
-		return
-	}
-	if distanceM <= 0 {
-		app.logger.Error("invalid distance value")
-		app.clientError(w, http.StatusBadRequest)
-		return
-	}
+	sort := normalizeSwimSortValue(r.PostForm.Get("sort"))
+	direction := normalizeSortDirectionValue(r.PostForm.Get("direction"))
 
-	assessment, err := strconv.Atoi(r.PostForm.Get("assessment"))
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+	err = app.checkLocation(userId, swim.LocationId)
 	if err != nil {
-		app.logger.Error(err.Error())
-		app.clientError(w, http.StatusBadRequest)
-		return
-	}
-	if assessment < 0 || assessment > 2 {
-		app.logger.Error("invalid assessment value")
-		app.clientError(w, http.StatusBadRequest)
+		if errors.Is(err, models.ErrNoRecord) {
+			app.logger.Error("unknown location")
+			app.clientError(w, http.StatusBadRequest)
+		} else {
+			app.serverError(w, r, err)
+		}
 		return
 	}
 
-	sort := normalizeSwimSortValue(r.PostForm.Get("sort"))
-	direction := normalizeSortDirectionValue(r.PostForm.Get("direction"))
-
-	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
-	err = app.swims.Update(swimID, userId, date, distanceM, assessment)
+	err = app.swims.Update(swim, userId)
 	if err != nil {
 		if errors.Is(err, models.ErrNoRecord) {
 			app.notFound(w)
@@ -372,13 +636,457 @@ func (app *application) deleteSwim(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, "/swims?"+values.Encode(), http.StatusSeeOther)
 }
 
+func (app *application) locationsList(w http.ResponseWriter, r *http.Request) {
+	stats, err := app.locations.Stats(app.sessionManager.GetInt(r.Context(), "authenticatedUserID"))
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.render(w, r, http.StatusOK, "locations.tmpl", app.newTemplateData(r, stats))
+}
+
+func (app *application) storeLocation(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		app.logger.Error(err.Error())
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	location, err := locationFromForm(r.PostForm)
+	if err != nil {
+		app.logger.Error(err.Error())
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	err = app.locations.Insert(location, app.sessionManager.GetInt(r.Context(), "authenticatedUserID"))
+	if err != nil {
+		if errors.Is(err, models.ErrDuplicateName) {
+			app.sessionManager.Put(r.Context(), "flashText", fmt.Sprintf("There already is a location named %q.", location.Name))
+			app.sessionManager.Put(r.Context(), "flashType", "flash-error")
+			http.Redirect(w, r, "/locations", http.StatusSeeOther)
+			return
+		}
+
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flashText", "Location added.")
+	app.sessionManager.Put(r.Context(), "flashType", "flash-success")
+
+	http.Redirect(w, r, "/locations", http.StatusSeeOther)
+}
+
+// checkLocation makes sure that a swim only refers to a location of its own
+// user. Swims without a location always pass.
+func (app *application) checkLocation(userId int, locationId int) error {
+	if locationId == 0 {
+		return nil
+	}
+
+	_, err := app.locations.GetByID(userId, locationId)
+	return err
+}
+
 func parseSwimSort(r *http.Request) (string, string) {
 	sort := normalizeSwimSortValue(r.URL.Query().Get("sort"))
 	direction := normalizeSortDirectionValue(r.URL.Query().Get("direction"))
 	return sort, direction
 }
 
-func newLoadMoreData(hasMore bool, nextOffset int, sort, direction string) *loadMoreData {
+// parseSwimFilter reads the filter of the swims list from the query string.
+// The filter is carried through sorting and "load more" requests.
+func parseSwimFilter(r *http.Request) models.SwimFilter {
+	filter := models.SwimFilter{
+		Search: strings.TrimSpace(r.URL.Query().Get("q")),
+	}
+
+	for _, value := range r.URL.Query()["tag"] {
+		tag := models.NormalizeTag(value)
+		if tag != "" && !filter.HasTag(tag) {
+			filter.Tags = append(filter.Tags, tag)
+		}
+	}
+
+	return filter
+}
+
+// parseDateRange reads the optional from and to query parameters as ISO dates.
+// Missing parameters leave the range open on that end.
+func parseDateRange(r *http.Request) (time.Time, time.Time, error) {
+	var from, to time.Time
+	var err error
+
+	if value := r.URL.Query().Get("from"); value != "" {
+		from, err = time.Parse("2006-01-02", value)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+	}
+	if value := r.URL.Query().Get("to"); value != "" {
+		to, err = time.Parse("2006-01-02", value)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+	}
+	if !from.IsZero() && !to.IsZero() && to.Before(from) {
+		return time.Time{}, time.Time{}, errors.New("date range ends before it starts")
+	}
+
+	return from, to, nil
+}
+
+// swimFromForm validates the fields of the create and edit swim forms. A swim
+// is entered either as a distance in the user's unit or as a number of laps in
+// a given pool, in which case the distance is computed from the laps, or as
+// sets, which then define the distance. A form with more than one of them is
+// rejected unless they agree, so that no change is dropped.
+func swimFromForm(form url.Values, unit models.Unit) (*models.Swim, error) {
+	date, err := time.Parse("2006-01-02", form.Get("date"))
+	if err != nil {
+		return nil, err
+	}
+
+	pool, err := parsePool(form.Get("pool"))
+	if err != nil {
+		return nil, err
+	}
+
+	sets, setsDistance, err := setsFromForm(form, unit)
+	if err != nil {
+		return nil, err
+	}
+
+	var laps int
+	if form.Get("laps") != "" {
+		laps, err = strconv.Atoi(form.Get("laps"))
+		if err != nil {
+			return nil, err
+		}
+		if laps <= 0 {
+			return nil, errors.New("invalid laps value")
+		}
+		if pool.IsZero() {
+			return nil, errors.New("laps require a pool length")
+		}
+	}
+
+	var distanceM int
+	switch {
+	case len(sets) > 0:
+		// The sets are the breakdown of the swim, so they define its distance
+		// and replace the laps
+		distanceM = unit.ToMeters(float64(setsDistance))
+		if distance := form.Get("distance"); distance != "" && distance != strconv.Itoa(setsDistance) {
+			return nil, errors.New("distance and sets disagree")
+		}
+		if laps > 0 && unit.FromMeters(pool.DistanceM(laps)) != setsDistance {
+			return nil, errors.New("laps and sets disagree")
+		}
+		laps = 0
+	case laps > 0:
+		distanceM = pool.DistanceM(laps)
+		if distance := form.Get("distance"); distance != "" && distance != strconv.Itoa(unit.FromMeters(distanceM)) {
+			return nil, errors.New("distance and laps disagree")
+		}
+	default:
+		distance, err := strconv.Atoi(form.Get("distance"))
+		if err != nil {
+			return nil, err
+		}
+		distanceM = unit.ToMeters(float64(distance))
+	}
+	if distanceM <= 0 {
+		return nil, errors.New("invalid distance value")
+	}
+
+	duration, err := parseSwimDuration(form.Get("duration"))
+	if err != nil {
+		return nil, err
+	}
+
+	stroke, err := parseStroke(form.Get("stroke"))
+	if err != nil {
+		return nil, err
+	}
+
+	notes := strings.TrimSpace(form.Get("notes"))
+	if utf8.RuneCountInString(notes) > models.MaxNotesLength {
+		return nil, errors.New("notes too long")
+	}
+
+	tags, err := tagsFromForm(form)
+	if err != nil {
+		return nil, err
+	}
+
+	locationId, err := parseLocationID(form.Get("location"))
+	if err != nil {
+		return nil, err
+	}
+
+	effort, err := parseEffort(form.Get("effort"))
+	if err != nil {
+		return nil, err
+	}
+
+	feel, err := strconv.Atoi(form.Get("feel"))
+	if err != nil {
+		return nil, err
+	}
+	if !models.Feel(feel).Valid() {
+		return nil, errors.New("invalid feel value")
+	}
+
+	return &models.Swim{
+		Date:       date,
+		DistanceM:  distanceM,
+		Effort:     effort,
+		Feel:       models.Feel(feel),
+		Duration:   duration,
+		Stroke:     stroke,
+		Pool:       pool,
+		Laps:       laps,
+		Notes:      notes,
+		Tags:       tags,
+		LocationId: locationId,
+		Sets:       sets,
+	}, nil
+}
+
+// tagsFromForm collects the checked existing tags and the comma separated new
+// tags of a swim form. Tags are normalized, so entering an existing tag again
+// with different case does not create a duplicate.
+func tagsFromForm(form url.Values) ([]string, error) {
+	values := slices.Clone(form["tags"])
+	values = append(values, strings.Split(form.Get("new_tags"), ",")...)
+
+	var tags []string
+	for _, value := range values {
+		tag := models.NormalizeTag(value)
+		if tag == "" || slices.Contains(tags, tag) {
+			continue
+		}
+		if utf8.RuneCountInString(tag) > models.MaxTagLength {
+			return nil, fmt.Errorf("tag %q too long", tag)
+		}
+		tags = append(tags, tag)
+	}
+
+	slices.Sort(tags)
+
+	return tags, nil
+}
+
+// tagOptions marks which of the user's tags are set on the swim being edited.
+func tagOptions(all []string, selected []string) []tagOption {
+	options := make([]tagOption, len(all))
+	for i, name := range all {
+		options[i] = tagOption{Name: name, Checked: slices.Contains(selected, name)}
+	}
+	return options
+}
+
+// locationOptions marks the location that is selected in the picker.
+func locationOptions(all []*models.Location, selected int) []locationOption {
+	options := make([]locationOption, len(all))
+	for i, location := range all {
+		options[i] = locationOption{Location: location, Selected: location.Id == selected}
+	}
+	return options
+}
+
+// locationFromForm validates the form for adding a location.
+func locationFromForm(form url.Values) (*models.Location, error) {
+	name := strings.Join(strings.Fields(form.Get("name")), " ")
+	if name == "" {
+		return nil, errors.New("missing location name")
+	}
+	if utf8.RuneCountInString(name) > models.MaxLocationNameLength {
+		return nil, errors.New("location name too long")
+	}
+
+	pool, err := parsePool(form.Get("pool"))
+	if err != nil {
+		return nil, err
+	}
+
+	indoor, err := strconv.ParseBool(form.Get("indoor"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Location{Name: name, Pool: pool, Indoor: indoor}, nil
+}
+
+// parseLocationID parses the optional location of a swim. An empty value means
+// that the location is unknown.
+func parseLocationID(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	id, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, err
+	}
+	if id <= 0 {
+		return 0, fmt.Errorf("invalid location %q", value)
+	}
+
+	return id, nil
+}
+
+// setsFromForm parses the rows of the set editor and returns the sets with
+// their total distance in the unit they were entered in. Each row submits one
+// value per set_* field, so the fields are read as parallel lists. Rows
+// without any input are skipped, an empty repetition count means a single
+// repetition. The total is added up before it is converted, as the distance
+// of a repetition is rounded to whole meters.
+func setsFromForm(form url.Values, unit models.Unit) ([]models.SwimSet, int, error) {
+	repetitions := form["set_repetitions"]
+	distances := form["set_distance"]
+	strokes := form["set_stroke"]
+	intervals := form["set_interval"]
+	rests := form["set_rest"]
+
+	n := len(repetitions)
+	if len(distances) != n || len(strokes) != n || len(intervals) != n || len(rests) != n {
+		return nil, 0, errors.New("incomplete set rows")
+	}
+
+	var sets []models.SwimSet
+	total := 0
+	for i := 0; i < n; i++ {
+		if repetitions[i] == "" && distances[i] == "" && intervals[i] == "" && rests[i] == "" {
+			continue
+		}
+
+		reps := 1
+		if repetitions[i] != "" {
+			var err error
+			reps, err = strconv.Atoi(repetitions[i])
+			if err != nil {
+				return nil, 0, err
+			}
+			if reps <= 0 {
+				return nil, 0, errors.New("invalid set repetitions")
+			}
+		}
+
+		distance, err := strconv.Atoi(distances[i])
+		if err != nil {
+			return nil, 0, err
+		}
+		distanceM := unit.ToMeters(float64(distance))
+		if distanceM <= 0 {
+			return nil, 0, errors.New("invalid set distance")
+		}
+
+		stroke, err := parseStroke(strokes[i])
+		if err != nil {
+			return nil, 0, err
+		}
+
+		interval, err := parseSwimDuration(intervals[i])
+		if err != nil {
+			return nil, 0, err
+		}
+
+		rest, err := parseSwimDuration(rests[i])
+		if err != nil {
+			return nil, 0, err
+		}
+
+		total += reps * distance
+		sets = append(sets, models.SwimSet{
+			Repetitions: reps,
+			DistanceM:   distanceM,
+			Stroke:      stroke,
+			Interval:    interval,
+			Rest:        rest,
+		})
+	}
+
+	return sets, total, nil
+}
+
+// parsePool parses the optional pool length. An empty value means that the
+// pool is unknown.
+func parsePool(value string) (models.Pool, error) {
+	if value == "" {
+		return models.Pool{}, nil
+	}
+	return models.ParsePool(value)
+}
+
+// parseSwimDuration parses an optional swim duration given as "mm:ss" or
+// "h:mm:ss". An empty value means that no duration was recorded.
+func parseSwimDuration(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+
+	parts := strings.Split(value, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+
+	var duration time.Duration
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 || (i > 0 && n >= 60) {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		duration = duration*60 + time.Duration(n)
+	}
+
+	if duration == 0 {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+
+	return duration * time.Second, nil
+}
+
+// parseStroke validates the submitted stroke. Forms that do not send a stroke
+// default to freestyle, matching the column default.
+func parseStroke(value string) (models.Stroke, error) {
+	if value == "" {
+		return models.StrokeFreestyle, nil
+	}
+
+	stroke := models.Stroke(strings.ToLower(value))
+	if !stroke.Valid() {
+		return "", fmt.Errorf("invalid stroke %q", value)
+	}
+
+	return stroke, nil
+}
+
+// parseEffort parses the optional RPE of a swim, an empty value means that no
+// effort was recorded.
+func parseEffort(value string) (models.Effort, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, err
+	}
+	effort := models.Effort(n)
+	if !effort.Valid() {
+		return 0, fmt.Errorf("invalid effort %q", value)
+	}
+
+	return effort, nil
+}
+
+func newLoadMoreData(hasMore bool, nextOffset int, sort, direction string, filter models.SwimFilter) *loadMoreData {
 	if !hasMore {
 		return nil
 	}
@@ -387,12 +1095,13 @@ func newLoadMoreData(hasMore bool, nextOffset int, sort, direction string) *load
 		NextOffset: nextOffset,
 		Sort:       sort,
 		Direction:  direction,
+		Filter:     filter,
 	}
 }
 
 func normalizeSwimSortValue(sort string) string {
 	sort = strings.ToLower(sort)
-	if sort != models.SwimSortDate && sort != models.SwimSortDistance && sort != models.SwimSortAssessment {
+	if sort != models.SwimSortDate && sort != models.SwimSortDistance && sort != models.SwimSortEffort {
 		return models.SwimSortDate
 	}
 	return sort
@@ -14,6 +14,7 @@ import (
 	"github.com/julienschmidt/httprouter"
 	"github.com/rockstaedt/swimmate/internal/models"
 	"github.com/rockstaedt/swimmate/internal/testutils"
+	"github.com/rockstaedt/swimmate/internal/webhooks"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -23,6 +24,20 @@ func newTestApplication() *application {
 		logger:         testutils.NewTestLogger(),
 		swims:          &testutils.MockSwimModel{},
 		users:          &testutils.MockUserModel{},
+		invites:        &testutils.MockInviteModel{},
+		resets:         &testutils.MockPasswordResetModel{},
+		twoFactor:      &testutils.MockTwoFactorModel{},
+		tags:           &testutils.MockTagModel{},
+		locations:      &testutils.MockLocationModel{},
+		backups:        &testutils.MockBackupModel{},
+		importJobs:     &testutils.MockImportJobModel{},
+		calendars:      &testutils.MockCalendarModel{},
+		accessTokens:   &testutils.MockAccessTokenModel{},
+		webhooks:       &testutils.MockWebhookModel{},
+		dispatcher:     webhooks.NewDispatcher(&testutils.MockWebhookModel{}, http.DefaultClient, testutils.NewTestLogger()),
+		mailer:         &testutils.MockMailer{},
+		registration:   registrationClosed,
+		maxArchiveSize: defaultMaxArchiveSize,
 		sessionManager: testutils.NewTestSessionManager(),
 		templateCache:  make(map[string]*template.Template),
 		version:        "test",
@@ -46,7 +61,7 @@ func TestHome(t *testing.T) {
 			name: "successful home page render",
 			path: "/",
 			setupMock: func(m *testutils.MockSwimModel) {
-				m.SummarizeFunc = func(userId int) *models.SwimSummary {
+				m.SummarizeFunc = func(userId int, filter models.SwimFilter) *models.SwimSummary {
 					return &models.SwimSummary{
 						TotalDistance: 5000,
 						TotalCount:    10,
@@ -161,6 +176,22 @@ func TestAuthenticate(t *testing.T) {
 			},
 			expectedStatus: http.StatusInternalServerError,
 		},
+		{
+			name: "user lookup error",
+			formData: url.Values{
+				"username": []string{"testuser"},
+				"password": []string{"password123"},
+			},
+			setupMock: func(m *testutils.MockUserModel) {
+				m.AuthenticateFunc = func(username, password string) (int, error) {
+					return 1, nil
+				}
+				m.GetFunc = func(id int) (*models.User, error) {
+					return nil, errors.New("database error")
+				}
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
 		{
 			name: "empty username",
 			formData: url.Values{
@@ -238,6 +269,35 @@ func TestAuthenticate(t *testing.T) {
 	}
 }
 
+func TestAuthenticateStoresDistanceUnit(t *testing.T) {
+	app := newTestApplication()
+	app.users = &testutils.MockUserModel{
+		AuthenticateFunc: func(username, password string) (int, error) {
+			return 1, nil
+		},
+		GetFunc: func(id int) (*models.User, error) {
+			return &models.User{ID: id, DistanceUnit: models.UnitYards}, nil
+		},
+	}
+
+	formData := url.Values{
+		"username": []string{"testuser"},
+		"password": []string{"password123"},
+	}
+
+	rr := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/authenticate", strings.NewReader(formData.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	ctx, _ := app.sessionManager.Load(r.Context(), "")
+	r = r.WithContext(ctx)
+
+	app.authenticate(rr, r)
+
+	assert.Equal(t, http.StatusSeeOther, rr.Code)
+	assert.Equal(t, "yd", app.sessionManager.GetString(ctx, "distanceUnit"))
+}
+
 func TestLogout(t *testing.T) {
 	tests := []struct {
 		name               string
@@ -301,7 +361,7 @@ func TestYearlyFigures(t *testing.T) {
 			name:       "current year",
 			queryParam: "",
 			setupMock: func(m *testutils.MockSwimModel) {
-				m.SummarizeFunc = func(userId int) *models.SwimSummary {
+				m.SummarizeFunc = func(userId int, filter models.SwimFilter) *models.SwimSummary {
 					return &models.SwimSummary{YearMap: make(map[int]models.YearMap)}
 				}
 			},
@@ -310,7 +370,7 @@ func TestYearlyFigures(t *testing.T) {
 			name:       "specific year",
 			queryParam: "?year=2023",
 			setupMock: func(m *testutils.MockSwimModel) {
-				m.SummarizeFunc = func(userId int) *models.SwimSummary {
+				m.SummarizeFunc = func(userId int, filter models.SwimFilter) *models.SwimSummary {
 					return &models.SwimSummary{YearMap: make(map[int]models.YearMap)}
 				}
 			},
@@ -356,6 +416,290 @@ func TestAbout(t *testing.T) {
 	assert.Contains(t, rr.Body.String(), "About")
 }
 
+func TestAccount(t *testing.T) {
+	tests := []struct {
+		name           string
+		setupMock      func(*testutils.MockUserModel)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "renders account page",
+			setupMock: func(m *testutils.MockUserModel) {
+				m.GetFunc = func(id int) (*models.User, error) {
+					return &models.User{ID: id, Username: "swimmer", DistanceUnit: models.UnitYards}, nil
+				}
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "swimmer yd",
+		},
+		{
+			name: "database error",
+			setupMock: func(m *testutils.MockUserModel) {
+				m.GetFunc = func(id int) (*models.User, error) {
+					return nil, errors.New("database error")
+				}
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication()
+			mockUsers := &testutils.MockUserModel{}
+			tt.setupMock(mockUsers)
+			app.users = mockUsers
+			app.templateCache["account.tmpl"] = createTestTemplate("base", `{{define "base"}}{{.Data.Username}} {{.Data.DistanceUnit}}{{end}}`)
+
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/account", nil)
+
+			ctx, _ := app.sessionManager.Load(r.Context(), "")
+			app.sessionManager.Put(ctx, "authenticatedUserID", 1)
+			r = r.WithContext(ctx)
+
+			app.account(rr, r)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			if tt.expectedBody != "" {
+				assert.Contains(t, rr.Body.String(), tt.expectedBody)
+			}
+		})
+	}
+}
+
+func TestUpdatePreferences(t *testing.T) {
+	tests := []struct {
+		name           string
+		formData       url.Values
+		setupMock      func(*testutils.MockUserModel)
+		expectedStatus int
+		expectedUnit   string
+	}{
+		{
+			name:     "switch to yards",
+			formData: url.Values{"distance_unit": []string{"yd"}},
+			setupMock: func(m *testutils.MockUserModel) {
+				m.UpdateDistanceUnitFunc = func(id int, unit models.Unit) error {
+					assert.Equal(t, 1, id)
+					assert.Equal(t, models.UnitYards, unit)
+					return nil
+				}
+			},
+			expectedStatus: http.StatusSeeOther,
+			expectedUnit:   "yd",
+		},
+		{
+			name:           "invalid unit",
+			formData:       url.Values{"distance_unit": []string{"km"}},
+			setupMock:      func(m *testutils.MockUserModel) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:     "database error",
+			formData: url.Values{"distance_unit": []string{"m"}},
+			setupMock: func(m *testutils.MockUserModel) {
+				m.UpdateDistanceUnitFunc = func(id int, unit models.Unit) error {
+					return errors.New("database error")
+				}
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication()
+			mockUsers := &testutils.MockUserModel{}
+			tt.setupMock(mockUsers)
+			app.users = mockUsers
+
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/account/preferences", strings.NewReader(tt.formData.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+			ctx, _ := app.sessionManager.Load(r.Context(), "")
+			app.sessionManager.Put(ctx, "authenticatedUserID", 1)
+			r = r.WithContext(ctx)
+
+			app.updatePreferences(rr, r)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			if tt.expectedUnit != "" {
+				assert.Equal(t, "/account", rr.Header().Get("Location"))
+				assert.Equal(t, tt.expectedUnit, app.sessionManager.GetString(ctx, "distanceUnit"))
+			}
+		})
+	}
+}
+
+func TestLocationsList(t *testing.T) {
+	tests := []struct {
+		name           string
+		setupMock      func(*testutils.MockLocationModel)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "renders location figures",
+			setupMock: func(m *testutils.MockLocationModel) {
+				m.StatsFunc = func(userId int) ([]*models.LocationStats, error) {
+					return []*models.LocationStats{
+						{Location: models.Location{Id: 1, Name: "Stadtbad"}, Count: 3, DistanceM: 4500},
+					}, nil
+				}
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "Stadtbad 3 4500",
+		},
+		{
+			name: "database error",
+			setupMock: func(m *testutils.MockLocationModel) {
+				m.StatsFunc = func(userId int) ([]*models.LocationStats, error) {
+					return nil, errors.New("database error")
+				}
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication()
+			mockLocations := &testutils.MockLocationModel{}
+			tt.setupMock(mockLocations)
+			app.locations = mockLocations
+			app.templateCache["locations.tmpl"] = createTestTemplate("base",
+				`{{define "base"}}{{range .Data}}{{.Name}} {{.Count}} {{.DistanceM}}{{end}}{{end}}`)
+
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/locations", nil)
+
+			ctx, _ := app.sessionManager.Load(r.Context(), "")
+			app.sessionManager.Put(ctx, "authenticatedUserID", 1)
+			r = r.WithContext(ctx)
+
+			app.locationsList(rr, r)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			if tt.expectedBody != "" {
+				assert.Contains(t, rr.Body.String(), tt.expectedBody)
+			}
+		})
+	}
+}
+
+func TestStoreLocation(t *testing.T) {
+	tests := []struct {
+		name           string
+		formData       url.Values
+		setupMock      func(*testutils.MockLocationModel)
+		expectedStatus int
+		expectedFlash  string
+	}{
+		{
+			name: "indoor pool",
+			formData: url.Values{
+				"name":   []string{"  Stadtbad   Mitte "},
+				"pool":   []string{"25m"},
+				"indoor": []string{"true"},
+			},
+			setupMock: func(m *testutils.MockLocationModel) {
+				m.InsertFunc = func(location *models.Location, userId int) error {
+					assert.Equal(t, &models.Location{Name: "Stadtbad Mitte", Pool: models.Pool{Length: 25, Unit: models.UnitMeters}, Indoor: true}, location)
+					return nil
+				}
+			},
+			expectedStatus: http.StatusSeeOther,
+			expectedFlash:  "Location added.",
+		},
+		{
+			name: "outdoor without pool",
+			formData: url.Values{
+				"name":   []string{"Lake"},
+				"pool":   []string{""},
+				"indoor": []string{"false"},
+			},
+			setupMock: func(m *testutils.MockLocationModel) {
+				m.InsertFunc = func(location *models.Location, userId int) error {
+					assert.Equal(t, &models.Location{Name: "Lake"}, location)
+					return nil
+				}
+			},
+			expectedStatus: http.StatusSeeOther,
+			expectedFlash:  "Location added.",
+		},
+		{
+			name: "duplicate name",
+			formData: url.Values{
+				"name":   []string{"Lake"},
+				"indoor": []string{"false"},
+			},
+			setupMock: func(m *testutils.MockLocationModel) {
+				m.InsertFunc = func(location *models.Location, userId int) error {
+					return models.ErrDuplicateName
+				}
+			},
+			expectedStatus: http.StatusSeeOther,
+			expectedFlash:  `There already is a location named "Lake".`,
+		},
+		{
+			name:           "missing name",
+			formData:       url.Values{"name": []string{"  "}, "indoor": []string{"true"}},
+			setupMock:      func(m *testutils.MockLocationModel) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "name too long",
+			formData:       url.Values{"name": []string{strings.Repeat("x", models.MaxLocationNameLength+1)}, "indoor": []string{"true"}},
+			setupMock:      func(m *testutils.MockLocationModel) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unknown pool",
+			formData:       url.Values{"name": []string{"Lake"}, "pool": []string{"33m"}, "indoor": []string{"false"}},
+			setupMock:      func(m *testutils.MockLocationModel) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:     "database error",
+			formData: url.Values{"name": []string{"Lake"}, "indoor": []string{"false"}},
+			setupMock: func(m *testutils.MockLocationModel) {
+				m.InsertFunc = func(location *models.Location, userId int) error {
+					return errors.New("database error")
+				}
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication()
+			mockLocations := &testutils.MockLocationModel{}
+			tt.setupMock(mockLocations)
+			app.locations = mockLocations
+
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/locations", strings.NewReader(tt.formData.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+			ctx, _ := app.sessionManager.Load(r.Context(), "")
+			app.sessionManager.Put(ctx, "authenticatedUserID", 1)
+			r = r.WithContext(ctx)
+
+			app.storeLocation(rr, r)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			if tt.expectedFlash != "" {
+				assert.Equal(t, "/locations", rr.Header().Get("Location"))
+				assert.Equal(t, tt.expectedFlash, app.sessionManager.GetString(ctx, "flashText"))
+			}
+		})
+	}
+}
+
 func TestCreateSwim(t *testing.T) {
 	app := newTestApplication()
 	app.templateCache["swim-create.tmpl"] = createTestTemplate("base", `{{define "base"}}Create{{end}}`)
@@ -372,6 +716,35 @@ func TestCreateSwim(t *testing.T) {
 	assert.Contains(t, rr.Body.String(), "Create")
 }
 
+func TestCreateSwimDefaultsToLastLocation(t *testing.T) {
+	app := newTestApplication()
+	app.locations = &testutils.MockLocationModel{
+		GetAllFunc: func(userId int) ([]*models.Location, error) {
+			return []*models.Location{
+				{Id: 1, Name: "Lake"},
+				{Id: 2, Name: "Stadtbad", Pool: models.Pool{Length: 25, Unit: models.UnitYards}},
+			}, nil
+		},
+		LastUsedIDFunc: func(userId int) (int, error) {
+			return 2, nil
+		},
+	}
+	app.templateCache["swim-create.tmpl"] = createTestTemplate("base",
+		`{{define "base"}}{{range .Data.Locations}}{{.Name}}={{.Selected}} {{end}}{{.Data.Pool}}{{end}}`)
+
+	rr := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/swim", nil)
+
+	ctx, _ := app.sessionManager.Load(r.Context(), "")
+	app.sessionManager.Put(ctx, "authenticatedUserID", 1)
+	r = r.WithContext(ctx)
+
+	app.createSwim(rr, r)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "Lake=false Stadtbad=true 25yd", rr.Body.String())
+}
+
 func TestSwimsList(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -383,11 +756,11 @@ func TestSwimsList(t *testing.T) {
 			name:       "successful list with default sort",
 			requestURL: "/swims",
 			setupMock: func(m *testutils.MockSwimModel, t *testing.T) {
-				m.GetPaginatedFunc = func(userId int, limit int, offset int, sort string, direction string) ([]*models.Swim, error) {
+				m.GetPaginatedFunc = func(userId int, limit int, offset int, sort string, direction string, filter models.SwimFilter) ([]*models.Swim, error) {
 					assert.Equal(t, models.SwimSortDate, sort)
 					assert.Equal(t, models.SortDirectionDesc, direction)
 					return []*models.Swim{
-						{Date: time.Now(), DistanceM: 1000, Assessment: 2},
+						{Date: time.Now(), DistanceM: 1000, Feel: 4},
 					}, nil
 				}
 			},
@@ -396,11 +769,37 @@ func TestSwimsList(t *testing.T) {
 			name:       "custom sort parameters",
 			requestURL: "/swims?sort=distance&direction=asc",
 			setupMock: func(m *testutils.MockSwimModel, t *testing.T) {
-				m.GetPaginatedFunc = func(userId int, limit int, offset int, sort string, direction string) ([]*models.Swim, error) {
+				m.GetPaginatedFunc = func(userId int, limit int, offset int, sort string, direction string, filter models.SwimFilter) ([]*models.Swim, error) {
+					assert.Equal(t, models.SwimSortDistance, sort)
+					assert.Equal(t, models.SortDirectionAsc, direction)
+					return []*models.Swim{
+						{Date: time.Now(), DistanceM: 850, Feel: 3},
+					}, nil
+				}
+			},
+		},
+		{
+			name:       "search is passed on with sort parameters",
+			requestURL: "/swims?sort=distance&direction=asc&q=+new+goggles+",
+			setupMock: func(m *testutils.MockSwimModel, t *testing.T) {
+				m.GetPaginatedFunc = func(userId int, limit int, offset int, sort string, direction string, filter models.SwimFilter) ([]*models.Swim, error) {
 					assert.Equal(t, models.SwimSortDistance, sort)
 					assert.Equal(t, models.SortDirectionAsc, direction)
+					assert.Equal(t, models.SwimFilter{Search: "new goggles"}, filter)
+					return []*models.Swim{
+						{Date: time.Now(), DistanceM: 850, Feel: 3, Notes: "Tried new goggles"},
+					}, nil
+				}
+			},
+		},
+		{
+			name:       "tags are normalized and deduplicated",
+			requestURL: "/swims?tag=Open-Water&tag=technique&tag=open-water+&tag=",
+			setupMock: func(m *testutils.MockSwimModel, t *testing.T) {
+				m.GetPaginatedFunc = func(userId int, limit int, offset int, sort string, direction string, filter models.SwimFilter) ([]*models.Swim, error) {
+					assert.Equal(t, models.SwimFilter{Tags: []string{"open-water", "technique"}}, filter)
 					return []*models.Swim{
-						{Date: time.Now(), DistanceM: 850, Assessment: 1},
+						{Date: time.Now(), DistanceM: 2000, Feel: 4, Tags: []string{"open-water", "technique"}},
 					}, nil
 				}
 			},
@@ -409,7 +808,7 @@ func TestSwimsList(t *testing.T) {
 			name:       "database error",
 			requestURL: "/swims",
 			setupMock: func(m *testutils.MockSwimModel, t *testing.T) {
-				m.GetPaginatedFunc = func(userId int, limit int, offset int, sort string, direction string) ([]*models.Swim, error) {
+				m.GetPaginatedFunc = func(userId int, limit int, offset int, sort string, direction string, filter models.SwimFilter) ([]*models.Swim, error) {
 					return nil, errors.New("database error")
 				}
 			},
@@ -457,12 +856,12 @@ func TestSwimsMore(t *testing.T) {
 			requestURL:  "/swims/more?offset=20",
 			htmxRequest: true,
 			setupMock: func(m *testutils.MockSwimModel, t *testing.T) {
-				m.GetPaginatedFunc = func(userId int, limit int, offset int, sort string, direction string) ([]*models.Swim, error) {
+				m.GetPaginatedFunc = func(userId int, limit int, offset int, sort string, direction string, filter models.SwimFilter) ([]*models.Swim, error) {
 					assert.Equal(t, models.SwimSortDate, sort)
 					assert.Equal(t, models.SortDirectionDesc, direction)
 					swims := make([]*models.Swim, 20)
 					for i := 0; i < 20; i++ {
-						swims[i] = &models.Swim{Date: time.Now(), DistanceM: 1000, Assessment: 2}
+						swims[i] = &models.Swim{Date: time.Now(), DistanceM: 1000, Feel: 4}
 					}
 					return swims, nil
 				}
@@ -471,14 +870,43 @@ func TestSwimsMore(t *testing.T) {
 		},
 		{
 			name:        "regular request with custom sort",
-			requestURL:  "/swims/more?offset=20&sort=assessment&direction=asc",
+			requestURL:  "/swims/more?offset=20&sort=effort&direction=asc",
 			htmxRequest: false,
 			setupMock: func(m *testutils.MockSwimModel, t *testing.T) {
-				m.GetPaginatedFunc = func(userId int, limit int, offset int, sort string, direction string) ([]*models.Swim, error) {
-					assert.Equal(t, models.SwimSortAssessment, sort)
+				m.GetPaginatedFunc = func(userId int, limit int, offset int, sort string, direction string, filter models.SwimFilter) ([]*models.Swim, error) {
+					assert.Equal(t, models.SwimSortEffort, sort)
 					assert.Equal(t, models.SortDirectionAsc, direction)
 					return []*models.Swim{
-						{Date: time.Now(), DistanceM: 1000, Assessment: 2},
+						{Date: time.Now(), DistanceM: 1000, Feel: 4},
+					}, nil
+				}
+			},
+			expectStatus: http.StatusOK,
+		},
+		{
+			name:        "HTMX request keeps the search",
+			requestURL:  "/swims/more?offset=20&q=goggles",
+			htmxRequest: true,
+			setupMock: func(m *testutils.MockSwimModel, t *testing.T) {
+				m.GetPaginatedFunc = func(userId int, limit int, offset int, sort string, direction string, filter models.SwimFilter) ([]*models.Swim, error) {
+					assert.Equal(t, 20, offset)
+					assert.Equal(t, "goggles", filter.Search)
+					return []*models.Swim{
+						{Date: time.Now(), DistanceM: 1000, Feel: 4, Notes: "Goggles fogged up"},
+					}, nil
+				}
+			},
+			expectStatus: http.StatusOK,
+		},
+		{
+			name:        "HTMX request keeps the tags",
+			requestURL:  "/swims/more?offset=20&tag=race-pace&tag=with+club",
+			htmxRequest: true,
+			setupMock: func(m *testutils.MockSwimModel, t *testing.T) {
+				m.GetPaginatedFunc = func(userId int, limit int, offset int, sort string, direction string, filter models.SwimFilter) ([]*models.Swim, error) {
+					assert.Equal(t, []string{"race-pace", "with club"}, filter.Tags)
+					return []*models.Swim{
+						{Date: time.Now(), DistanceM: 1000, Feel: 4},
 					}, nil
 				}
 			},
@@ -489,7 +917,7 @@ func TestSwimsMore(t *testing.T) {
 			requestURL:  "/swims/more?offset=20",
 			htmxRequest: true,
 			setupMock: func(m *testutils.MockSwimModel, t *testing.T) {
-				m.GetPaginatedFunc = func(userId int, limit int, offset int, sort string, direction string) ([]*models.Swim, error) {
+				m.GetPaginatedFunc = func(userId int, limit int, offset int, sort string, direction string, filter models.SwimFilter) ([]*models.Swim, error) {
 					return nil, errors.New("database error")
 				}
 			},
@@ -500,7 +928,7 @@ func TestSwimsMore(t *testing.T) {
 			requestURL:  "/swims/more?offset=20",
 			htmxRequest: false,
 			setupMock: func(m *testutils.MockSwimModel, t *testing.T) {
-				m.GetPaginatedFunc = func(userId int, limit int, offset int, sort string, direction string) ([]*models.Swim, error) {
+				m.GetPaginatedFunc = func(userId int, limit int, offset int, sort string, direction string, filter models.SwimFilter) ([]*models.Swim, error) {
 					return nil, errors.New("database error")
 				}
 			},
@@ -511,10 +939,10 @@ func TestSwimsMore(t *testing.T) {
 			requestURL:  "/swims/more?offset=abc",
 			htmxRequest: true,
 			setupMock: func(m *testutils.MockSwimModel, t *testing.T) {
-				m.GetPaginatedFunc = func(userId int, limit int, offset int, sort string, direction string) ([]*models.Swim, error) {
+				m.GetPaginatedFunc = func(userId int, limit int, offset int, sort string, direction string, filter models.SwimFilter) ([]*models.Swim, error) {
 					assert.Equal(t, 0, offset, "invalid offset should default to 0")
 					return []*models.Swim{
-						{Date: time.Now(), DistanceM: 1000, Assessment: 2},
+						{Date: time.Now(), DistanceM: 1000, Feel: 4},
 					}, nil
 				}
 			},
@@ -525,9 +953,9 @@ func TestSwimsMore(t *testing.T) {
 			requestURL:  "/swims/more?offset=-10",
 			htmxRequest: true,
 			setupMock: func(m *testutils.MockSwimModel, t *testing.T) {
-				m.GetPaginatedFunc = func(userId int, limit int, offset int, sort string, direction string) ([]*models.Swim, error) {
+				m.GetPaginatedFunc = func(userId int, limit int, offset int, sort string, direction string, filter models.SwimFilter) ([]*models.Swim, error) {
 					return []*models.Swim{
-						{Date: time.Now(), DistanceM: 1000, Assessment: 2},
+						{Date: time.Now(), DistanceM: 1000, Feel: 4},
 					}, nil
 				}
 			},
@@ -538,7 +966,7 @@ func TestSwimsMore(t *testing.T) {
 			requestURL:  "/swims/more?offset=100",
 			htmxRequest: true,
 			setupMock: func(m *testutils.MockSwimModel, t *testing.T) {
-				m.GetPaginatedFunc = func(userId int, limit int, offset int, sort string, direction string) ([]*models.Swim, error) {
+				m.GetPaginatedFunc = func(userId int, limit int, offset int, sort string, direction string, filter models.SwimFilter) ([]*models.Swim, error) {
 					return []*models.Swim{}, nil
 				}
 			},
@@ -549,7 +977,7 @@ func TestSwimsMore(t *testing.T) {
 			requestURL:  "/swims/more?offset=100",
 			htmxRequest: false,
 			setupMock: func(m *testutils.MockSwimModel, t *testing.T) {
-				m.GetPaginatedFunc = func(userId int, limit int, offset int, sort string, direction string) ([]*models.Swim, error) {
+				m.GetPaginatedFunc = func(userId int, limit int, offset int, sort string, direction string, filter models.SwimFilter) ([]*models.Swim, error) {
 					return []*models.Swim{}, nil
 				}
 			},
@@ -588,6 +1016,108 @@ func TestSwimsMore(t *testing.T) {
 	}
 }
 
+func TestExportSwims(t *testing.T) {
+	tests := []struct {
+		name           string
+		url            string
+		setupMock      func(*testing.T, *testutils.MockSwimModel)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "streams swims as CSV",
+			url:  "/swims/export.csv?sort=distance&direction=asc",
+			setupMock: func(t *testing.T, m *testutils.MockSwimModel) {
+				m.ExportFunc = func(userId int, sort string, direction string, filter models.SwimFilter, each func(*models.Swim) error) error {
+					assert.Equal(t, 1, userId)
+					assert.Equal(t, models.SwimSortDistance, sort)
+					assert.Equal(t, models.SortDirectionAsc, direction)
+					assert.Equal(t, models.SwimFilter{}, filter)
+					for _, swim := range []*models.Swim{
+						{Date: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), DistanceM: 1000, Stroke: models.StrokeBackstroke, Duration: 21*time.Minute + 5*time.Second, Pool: models.Pool{Length: 25, Unit: models.UnitYards}, Laps: 40, Effort: 6, Feel: models.FeelGood, Notes: "Cold, \"windy\""},
+						{Date: time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC), DistanceM: 2000, Stroke: models.StrokeFreestyle, Feel: models.FeelOkay},
+					} {
+						if err := each(swim); err != nil {
+							return err
+						}
+					}
+					return nil
+				}
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: "date,distance_m,stroke,duration_s,pool_length,pool_unit,laps,effort,feel,notes\n" +
+				"2024-03-01,1000,backstroke,1265,25,yd,40,6,4,\"Cold, \"\"windy\"\"\"\n" +
+				"2024-03-04,2000,freestyle,,,,,,3,\n",
+		},
+		{
+			name: "date range and filters",
+			url:  "/swims/export.csv?from=2024-01-01&to=2024-06-30&tag=Open+Water&q=goggles",
+			setupMock: func(t *testing.T, m *testutils.MockSwimModel) {
+				m.ExportFunc = func(userId int, sort string, direction string, filter models.SwimFilter, each func(*models.Swim) error) error {
+					assert.Equal(t, models.SwimSortDate, sort)
+					assert.Equal(t, models.SortDirectionDesc, direction)
+					assert.Equal(t, models.SwimFilter{
+						Search: "goggles",
+						Tags:   []string{"open water"},
+						From:   time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+						To:     time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC),
+					}, filter)
+					return nil
+				}
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "date,distance_m,stroke,duration_s,pool_length,pool_unit,laps,effort,feel,notes\n",
+		},
+		{
+			name:           "invalid date",
+			url:            "/swims/export.csv?from=01.01.2024",
+			setupMock:      func(t *testing.T, m *testutils.MockSwimModel) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "range ends before it starts",
+			url:            "/swims/export.csv?from=2024-02-01&to=2024-01-01",
+			setupMock:      func(t *testing.T, m *testutils.MockSwimModel) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "database error",
+			url:  "/swims/export.csv",
+			setupMock: func(t *testing.T, m *testutils.MockSwimModel) {
+				m.ExportFunc = func(userId int, sort string, direction string, filter models.SwimFilter, each func(*models.Swim) error) error {
+					return errors.New("database error")
+				}
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication()
+			mockSwims := &testutils.MockSwimModel{}
+			tt.setupMock(t, mockSwims)
+			app.swims = mockSwims
+
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, tt.url, nil)
+
+			ctx, _ := app.sessionManager.Load(r.Context(), "")
+			app.sessionManager.Put(ctx, "authenticatedUserID", 1)
+			r = r.WithContext(ctx)
+
+			app.exportSwims(rr, r)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			if tt.expectedStatus == http.StatusOK {
+				assert.Equal(t, "text/csv; charset=utf-8", rr.Header().Get("Content-Type"))
+				assert.Equal(t, `attachment; filename="swims.csv"`, rr.Header().Get("Content-Disposition"))
+				assert.Equal(t, tt.expectedBody, rr.Body.String())
+			}
+		})
+	}
+}
+
 func TestParseSwimSort(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -615,8 +1145,8 @@ func TestParseSwimSort(t *testing.T) {
 		},
 		{
 			name:       "upper case parameters are normalized",
-			url:        "/swims?sort=ASSESSMENT&direction=ASC",
-			wantSort:   models.SwimSortAssessment,
+			url:        "/swims?sort=EFFORT&direction=ASC",
+			wantSort:   models.SwimSortEffort,
 			wantDirect: models.SortDirectionAsc,
 		},
 	}
@@ -631,35 +1161,618 @@ func TestParseSwimSort(t *testing.T) {
 	}
 }
 
+func TestParseSwimDuration(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       string
+		expected    time.Duration
+		expectError bool
+	}{
+		{name: "empty value", value: "", expected: 0},
+		{name: "whitespace only", value: "  ", expected: 0},
+		{name: "minutes and seconds", value: "42:30", expected: 42*time.Minute + 30*time.Second},
+		{name: "minutes above one hour", value: "75:00", expected: 75 * time.Minute},
+		{name: "hours, minutes and seconds", value: "1:05:09", expected: time.Hour + 5*time.Minute + 9*time.Second},
+		{name: "missing seconds", value: "42", expectError: true},
+		{name: "too many parts", value: "1:2:3:4", expectError: true},
+		{name: "seconds out of range", value: "10:60", expectError: true},
+		{name: "minutes out of range with hours", value: "1:60:00", expectError: true},
+		{name: "negative value", value: "-5:00", expectError: true},
+		{name: "non-numeric", value: "ab:cd", expectError: true},
+		{name: "zero duration", value: "0:00", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			duration, err := parseSwimDuration(tt.value)
+
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, duration)
+		})
+	}
+}
+
+func TestParseStroke(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       string
+		expected    models.Stroke
+		expectError bool
+	}{
+		{name: "empty defaults to freestyle", value: "", expected: models.StrokeFreestyle},
+		{name: "valid stroke", value: "backstroke", expected: models.StrokeBackstroke},
+		{name: "upper case is normalized", value: "Butterfly", expected: models.StrokeButterfly},
+		{name: "unknown stroke", value: "doggy", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stroke, err := parseStroke(tt.value)
+
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, stroke)
+		})
+	}
+}
+
+func TestSetsFromForm(t *testing.T) {
+	setRows := func(reps, distances, strokes, intervals, rests []string) url.Values {
+		return url.Values{
+			"set_repetitions": reps,
+			"set_distance":    distances,
+			"set_stroke":      strokes,
+			"set_interval":    intervals,
+			"set_rest":        rests,
+		}
+	}
+
+	tests := []struct {
+		name          string
+		form          url.Values
+		unit          models.Unit
+		expected      []models.SwimSet
+		expectedTotal int
+		expectError   bool
+	}{
+		{
+			name: "no sets",
+			form: url.Values{},
+			unit: models.UnitMeters,
+		},
+		{
+			name: "warm-up, main set and cool-down",
+			form: setRows(
+				[]string{"", "8", "1"},
+				[]string{"400", "100", "200"},
+				[]string{"freestyle", "freestyle", "backstroke"},
+				[]string{"", "1:45", ""},
+				[]string{"", "", "0:30"},
+			),
+			unit:          models.UnitMeters,
+			expectedTotal: 1400,
+			expected: []models.SwimSet{
+				{Repetitions: 1, DistanceM: 400, Stroke: models.StrokeFreestyle},
+				{Repetitions: 8, DistanceM: 100, Stroke: models.StrokeFreestyle, Interval: 105 * time.Second},
+				{Repetitions: 1, DistanceM: 200, Stroke: models.StrokeBackstroke, Rest: 30 * time.Second},
+			},
+		},
+		{
+			name: "empty rows are skipped",
+			form: setRows(
+				[]string{"", "4"},
+				[]string{"", "50"},
+				[]string{"freestyle", "butterfly"},
+				[]string{"", ""},
+				[]string{"", ""},
+			),
+			unit:          models.UnitMeters,
+			expected:      []models.SwimSet{{Repetitions: 4, DistanceM: 50, Stroke: models.StrokeButterfly}},
+			expectedTotal: 200,
+		},
+		{
+			name:          "distance in yards",
+			form:          setRows([]string{"10"}, []string{"100"}, []string{"freestyle"}, []string{""}, []string{""}),
+			unit:          models.UnitYards,
+			expected:      []models.SwimSet{{Repetitions: 10, DistanceM: 91, Stroke: models.StrokeFreestyle}},
+			expectedTotal: 1000,
+		},
+		{
+			name:        "missing distance",
+			form:        setRows([]string{"8"}, []string{""}, []string{"freestyle"}, []string{""}, []string{""}),
+			unit:        models.UnitMeters,
+			expectError: true,
+		},
+		{
+			name:        "zero repetitions",
+			form:        setRows([]string{"0"}, []string{"100"}, []string{"freestyle"}, []string{""}, []string{""}),
+			unit:        models.UnitMeters,
+			expectError: true,
+		},
+		{
+			name:        "invalid interval",
+			form:        setRows([]string{"8"}, []string{"100"}, []string{"freestyle"}, []string{"1:75"}, []string{""}),
+			unit:        models.UnitMeters,
+			expectError: true,
+		},
+		{
+			name:        "invalid stroke",
+			form:        setRows([]string{"8"}, []string{"100"}, []string{"doggy"}, []string{""}, []string{""}),
+			unit:        models.UnitMeters,
+			expectError: true,
+		},
+		{
+			name:        "incomplete rows",
+			form:        setRows([]string{"8", "4"}, []string{"100"}, []string{"freestyle"}, []string{""}, []string{""}),
+			unit:        models.UnitMeters,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sets, total, err := setsFromForm(tt.form, tt.unit)
+
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, sets)
+			assert.Equal(t, tt.expectedTotal, total)
+		})
+	}
+}
+
 func TestStoreSwim(t *testing.T) {
 	tests := []struct {
 		name             string
 		formData         url.Values
 		setupMock        func(*testutils.MockSwimModel)
+		distanceUnit     models.Unit
 		expectedStatus   int
 		expectedLocation string
 	}{
 		{
 			name: "successful swim creation",
 			formData: url.Values{
-				"date":       []string{"2024-01-15"},
-				"distance_m": []string{"1500"},
-				"assessment": []string{"2"},
+				"date":     []string{"2024-01-15"},
+				"distance": []string{"1500"},
+				"feel":     []string{"4"},
+			},
+			setupMock: func(m *testutils.MockSwimModel) {
+				m.InsertFunc = func(swim *models.Swim, userId int) error {
+					return nil
+				}
+			},
+			expectedStatus:   http.StatusSeeOther,
+			expectedLocation: "/",
+		},
+		{
+			name: "successful swim creation with duration",
+			formData: url.Values{
+				"date":     []string{"2024-01-15"},
+				"distance": []string{"1500"},
+				"duration": []string{"1:02:05"},
+				"feel":     []string{"4"},
+			},
+			setupMock: func(m *testutils.MockSwimModel) {
+				m.InsertFunc = func(swim *models.Swim, userId int) error {
+					assert.Equal(t, time.Hour+2*time.Minute+5*time.Second, swim.Duration)
+					return nil
+				}
+			},
+			expectedStatus:   http.StatusSeeOther,
+			expectedLocation: "/",
+		},
+		{
+			name: "successful swim creation with effort",
+			formData: url.Values{
+				"date":     []string{"2024-01-15"},
+				"distance": []string{"1500"},
+				"effort":   []string{"7"},
+				"feel":     []string{"5"},
+			},
+			setupMock: func(m *testutils.MockSwimModel) {
+				m.InsertFunc = func(swim *models.Swim, userId int) error {
+					assert.Equal(t, models.Effort(7), swim.Effort)
+					assert.Equal(t, models.FeelGreat, swim.Feel)
+					return nil
+				}
+			},
+			expectedStatus:   http.StatusSeeOther,
+			expectedLocation: "/",
+		},
+		{
+			name: "successful swim creation with stroke",
+			formData: url.Values{
+				"date":     []string{"2024-01-15"},
+				"distance": []string{"1500"},
+				"stroke":   []string{"breaststroke"},
+				"feel":     []string{"4"},
+			},
+			setupMock: func(m *testutils.MockSwimModel) {
+				m.InsertFunc = func(swim *models.Swim, userId int) error {
+					assert.Equal(t, models.StrokeBreaststroke, swim.Stroke)
+					return nil
+				}
+			},
+			expectedStatus:   http.StatusSeeOther,
+			expectedLocation: "/",
+		},
+		{
+			name: "successful swim creation in yards",
+			formData: url.Values{
+				"date":     []string{"2024-01-15"},
+				"distance": []string{"1000"},
+				"feel":     []string{"4"},
+			},
+			setupMock: func(m *testutils.MockSwimModel) {
+				m.InsertFunc = func(swim *models.Swim, userId int) error {
+					assert.Equal(t, 914, swim.DistanceM)
+					return nil
+				}
+			},
+			distanceUnit:     models.UnitYards,
+			expectedStatus:   http.StatusSeeOther,
+			expectedLocation: "/",
+		},
+		{
+			name: "successful swim creation with sets",
+			formData: url.Values{
+				"date":            []string{"2024-01-15"},
+				"distance":        []string{""},
+				"set_repetitions": []string{"1", "8", "1"},
+				"set_distance":    []string{"400", "100", "200"},
+				"set_stroke":      []string{"freestyle", "freestyle", "freestyle"},
+				"set_interval":    []string{"", "1:45", ""},
+				"set_rest":        []string{"", "", ""},
+				"feel":            []string{"4"},
+			},
+			setupMock: func(m *testutils.MockSwimModel) {
+				m.InsertFunc = func(swim *models.Swim, userId int) error {
+					assert.Equal(t, 1400, swim.DistanceM)
+					assert.Len(t, swim.Sets, 3)
+					return nil
+				}
+			},
+			expectedStatus:   http.StatusSeeOther,
+			expectedLocation: "/",
+		},
+		{
+			name: "successful swim creation with sets in yards",
+			formData: url.Values{
+				"date":            []string{"2024-01-15"},
+				"set_repetitions": []string{"10"},
+				"set_distance":    []string{"100"},
+				"set_stroke":      []string{"freestyle"},
+				"set_interval":    []string{""},
+				"set_rest":        []string{""},
+				"feel":            []string{"4"},
+			},
+			setupMock: func(m *testutils.MockSwimModel) {
+				m.InsertFunc = func(swim *models.Swim, userId int) error {
+					assert.Equal(t, 914, swim.DistanceM, "1000 yd, not 10 times 91 m")
+					assert.Equal(t, 1000, models.UnitYards.FromMeters(swim.DistanceM))
+					return nil
+				}
+			},
+			distanceUnit:     models.UnitYards,
+			expectedStatus:   http.StatusSeeOther,
+			expectedLocation: "/",
+		},
+		{
+			name: "sets and the distance they make",
+			formData: url.Values{
+				"date":            []string{"2024-01-15"},
+				"distance":        []string{"800"},
+				"set_repetitions": []string{"8"},
+				"set_distance":    []string{"100"},
+				"set_stroke":      []string{"freestyle"},
+				"set_interval":    []string{""},
+				"set_rest":        []string{""},
+				"feel":            []string{"4"},
+			},
+			setupMock: func(m *testutils.MockSwimModel) {
+				m.InsertFunc = func(swim *models.Swim, userId int) error {
+					assert.Equal(t, 800, swim.DistanceM)
+					return nil
+				}
+			},
+			expectedStatus:   http.StatusSeeOther,
+			expectedLocation: "/",
+		},
+		{
+			name: "sets and a different distance",
+			formData: url.Values{
+				"date":            []string{"2024-01-15"},
+				"distance":        []string{"1500"},
+				"set_repetitions": []string{"8"},
+				"set_distance":    []string{"100"},
+				"set_stroke":      []string{"freestyle"},
+				"set_interval":    []string{""},
+				"set_rest":        []string{""},
+				"feel":            []string{"4"},
+			},
+			setupMock:      func(m *testutils.MockSwimModel) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "sets and the laps they make",
+			formData: url.Values{
+				"date":            []string{"2024-01-15"},
+				"laps":            []string{"32"},
+				"pool":            []string{"25m"},
+				"set_repetitions": []string{"8"},
+				"set_distance":    []string{"100"},
+				"set_stroke":      []string{"freestyle"},
+				"set_interval":    []string{""},
+				"set_rest":        []string{""},
+				"feel":            []string{"4"},
+			},
+			setupMock: func(m *testutils.MockSwimModel) {
+				m.InsertFunc = func(swim *models.Swim, userId int) error {
+					assert.Equal(t, 800, swim.DistanceM)
+					assert.Zero(t, swim.Laps, "the sets replace the laps")
+					return nil
+				}
+			},
+			expectedStatus:   http.StatusSeeOther,
+			expectedLocation: "/",
+		},
+		{
+			name: "sets and different laps",
+			formData: url.Values{
+				"date":            []string{"2024-01-15"},
+				"laps":            []string{"60"},
+				"pool":            []string{"25m"},
+				"set_repetitions": []string{"8"},
+				"set_distance":    []string{"100"},
+				"set_stroke":      []string{"freestyle"},
+				"set_interval":    []string{""},
+				"set_rest":        []string{""},
+				"feel":            []string{"4"},
+			},
+			setupMock:      func(m *testutils.MockSwimModel) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "successful swim creation at location",
+			formData: url.Values{
+				"date":     []string{"2024-01-15"},
+				"distance": []string{"1500"},
+				"location": []string{"2"},
+				"feel":     []string{"4"},
+			},
+			setupMock: func(m *testutils.MockSwimModel) {
+				m.InsertFunc = func(swim *models.Swim, userId int) error {
+					assert.Equal(t, 2, swim.LocationId)
+					return nil
+				}
+			},
+			expectedStatus:   http.StatusSeeOther,
+			expectedLocation: "/",
+		},
+		{
+			name: "successful swim creation with tags",
+			formData: url.Values{
+				"date":     []string{"2024-01-15"},
+				"distance": []string{"1500"},
+				"tags":     []string{"technique"},
+				"new_tags": []string{"With Club, technique,,race-pace"},
+				"feel":     []string{"4"},
+			},
+			setupMock: func(m *testutils.MockSwimModel) {
+				m.InsertFunc = func(swim *models.Swim, userId int) error {
+					assert.Equal(t, []string{"race-pace", "technique", "with club"}, swim.Tags)
+					return nil
+				}
+			},
+			expectedStatus:   http.StatusSeeOther,
+			expectedLocation: "/",
+		},
+		{
+			name: "successful swim creation with notes",
+			formData: url.Values{
+				"date":     []string{"2024-01-15"},
+				"distance": []string{"1500"},
+				"notes":    []string{"  Shoulder felt tight\n"},
+				"feel":     []string{"2"},
+			},
+			setupMock: func(m *testutils.MockSwimModel) {
+				m.InsertFunc = func(swim *models.Swim, userId int) error {
+					assert.Equal(t, "Shoulder felt tight", swim.Notes)
+					return nil
+				}
+			},
+			expectedStatus:   http.StatusSeeOther,
+			expectedLocation: "/",
+		},
+		{
+			name: "notes too long",
+			formData: url.Values{
+				"date":     []string{"2024-01-15"},
+				"distance": []string{"1500"},
+				"notes":    []string{strings.Repeat("x", 2001)},
+				"feel":     []string{"2"},
+			},
+			setupMock:      func(m *testutils.MockSwimModel) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "invalid location",
+			formData: url.Values{
+				"date":     []string{"2024-01-15"},
+				"distance": []string{"1500"},
+				"location": []string{"pool"},
+				"feel":     []string{"3"},
+			},
+			setupMock:      func(m *testutils.MockSwimModel) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "tag too long",
+			formData: url.Values{
+				"date":     []string{"2024-01-15"},
+				"distance": []string{"1500"},
+				"new_tags": []string{strings.Repeat("x", models.MaxTagLength+1)},
+				"feel":     []string{"2"},
+			},
+			setupMock:      func(m *testutils.MockSwimModel) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "invalid set",
+			formData: url.Values{
+				"date":            []string{"2024-01-15"},
+				"distance":        []string{"1500"},
+				"set_repetitions": []string{"8"},
+				"set_distance":    []string{"-100"},
+				"set_stroke":      []string{"freestyle"},
+				"set_interval":    []string{""},
+				"set_rest":        []string{""},
+				"feel":            []string{"4"},
+			},
+			setupMock:      func(m *testutils.MockSwimModel) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "successful swim creation with laps",
+			formData: url.Values{
+				"date":     []string{"2024-01-15"},
+				"distance": []string{""},
+				"laps":     []string{"80"},
+				"pool":     []string{"25yd"},
+				"feel":     []string{"4"},
+			},
+			setupMock: func(m *testutils.MockSwimModel) {
+				m.InsertFunc = func(swim *models.Swim, userId int) error {
+					assert.Equal(t, 1829, swim.DistanceM)
+					assert.Equal(t, 80, swim.Laps)
+					assert.Equal(t, models.Pool{Length: 25, Unit: models.UnitYards}, swim.Pool)
+					return nil
+				}
+			},
+			expectedStatus:   http.StatusSeeOther,
+			expectedLocation: "/",
+		},
+		{
+			name: "laps and the distance they make",
+			formData: url.Values{
+				"date":     []string{"2024-01-15"},
+				"distance": []string{"1500"},
+				"laps":     []string{"30"},
+				"pool":     []string{"50m"},
+				"feel":     []string{"4"},
+			},
+			setupMock: func(m *testutils.MockSwimModel) {
+				m.InsertFunc = func(swim *models.Swim, userId int) error {
+					assert.Equal(t, 1500, swim.DistanceM)
+					assert.Equal(t, 30, swim.Laps)
+					return nil
+				}
+			},
+			expectedStatus:   http.StatusSeeOther,
+			expectedLocation: "/",
+		},
+		{
+			name: "laps and a different distance",
+			formData: url.Values{
+				"date":     []string{"2024-01-15"},
+				"distance": []string{"1000"},
+				"laps":     []string{"30"},
+				"pool":     []string{"50m"},
+				"feel":     []string{"4"},
+			},
+			setupMock:      func(m *testutils.MockSwimModel) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "distance with pool length",
+			formData: url.Values{
+				"date":     []string{"2024-01-15"},
+				"distance": []string{"1500"},
+				"pool":     []string{"25m"},
+				"feel":     []string{"4"},
 			},
 			setupMock: func(m *testutils.MockSwimModel) {
-				m.InsertFunc = func(date time.Time, distanceM int, assessment int, userId int) error {
+				m.InsertFunc = func(swim *models.Swim, userId int) error {
+					assert.Equal(t, 1500, swim.DistanceM)
+					assert.Equal(t, 0, swim.Laps)
+					assert.Equal(t, models.Pool{Length: 25, Unit: models.UnitMeters}, swim.Pool)
 					return nil
 				}
 			},
 			expectedStatus:   http.StatusSeeOther,
 			expectedLocation: "/",
 		},
+		{
+			name: "laps without pool",
+			formData: url.Values{
+				"date": []string{"2024-01-15"},
+				"laps": []string{"40"},
+				"feel": []string{"4"},
+			},
+			setupMock:      func(m *testutils.MockSwimModel) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "zero laps",
+			formData: url.Values{
+				"date": []string{"2024-01-15"},
+				"laps": []string{"0"},
+				"pool": []string{"25m"},
+				"feel": []string{"4"},
+			},
+			setupMock:      func(m *testutils.MockSwimModel) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "unknown pool",
+			formData: url.Values{
+				"date":     []string{"2024-01-15"},
+				"distance": []string{"1500"},
+				"pool":     []string{"33m"},
+				"feel":     []string{"4"},
+			},
+			setupMock:      func(m *testutils.MockSwimModel) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "invalid stroke",
+			formData: url.Values{
+				"date":     []string{"2024-01-15"},
+				"distance": []string{"1500"},
+				"stroke":   []string{"doggy"},
+				"feel":     []string{"4"},
+			},
+			setupMock:      func(m *testutils.MockSwimModel) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "invalid duration",
+			formData: url.Values{
+				"date":     []string{"2024-01-15"},
+				"distance": []string{"1500"},
+				"duration": []string{"30:75"},
+				"feel":     []string{"4"},
+			},
+			setupMock:      func(m *testutils.MockSwimModel) {},
+			expectedStatus: http.StatusBadRequest,
+		},
 		{
 			name: "invalid date format",
 			formData: url.Values{
-				"date":       []string{"invalid-date"},
-				"distance_m": []string{"1500"},
-				"assessment": []string{"2"},
+				"date":     []string{"invalid-date"},
+				"distance": []string{"1500"},
+				"feel":     []string{"4"},
 			},
 			setupMock:      func(m *testutils.MockSwimModel) {},
 			expectedStatus: http.StatusBadRequest,
@@ -667,19 +1780,19 @@ func TestStoreSwim(t *testing.T) {
 		{
 			name: "invalid distance",
 			formData: url.Values{
-				"date":       []string{"2024-01-15"},
-				"distance_m": []string{"not-a-number"},
-				"assessment": []string{"2"},
+				"date":     []string{"2024-01-15"},
+				"distance": []string{"not-a-number"},
+				"feel":     []string{"4"},
 			},
 			setupMock:      func(m *testutils.MockSwimModel) {},
 			expectedStatus: http.StatusBadRequest,
 		},
 		{
-			name: "invalid assessment",
+			name: "invalid feel",
 			formData: url.Values{
-				"date":       []string{"2024-01-15"},
-				"distance_m": []string{"1500"},
-				"assessment": []string{"invalid"},
+				"date":     []string{"2024-01-15"},
+				"distance": []string{"1500"},
+				"feel":     []string{"invalid"},
 			},
 			setupMock:      func(m *testutils.MockSwimModel) {},
 			expectedStatus: http.StatusBadRequest,
@@ -687,12 +1800,12 @@ func TestStoreSwim(t *testing.T) {
 		{
 			name: "database error on insert",
 			formData: url.Values{
-				"date":       []string{"2024-01-15"},
-				"distance_m": []string{"1500"},
-				"assessment": []string{"2"},
+				"date":     []string{"2024-01-15"},
+				"distance": []string{"1500"},
+				"feel":     []string{"4"},
 			},
 			setupMock: func(m *testutils.MockSwimModel) {
-				m.InsertFunc = func(date time.Time, distanceM int, assessment int, userId int) error {
+				m.InsertFunc = func(swim *models.Swim, userId int) error {
 					return errors.New("database error")
 				}
 			},
@@ -701,9 +1814,9 @@ func TestStoreSwim(t *testing.T) {
 		{
 			name: "zero distance",
 			formData: url.Values{
-				"date":       []string{"2024-01-15"},
-				"distance_m": []string{"0"},
-				"assessment": []string{"2"},
+				"date":     []string{"2024-01-15"},
+				"distance": []string{"0"},
+				"feel":     []string{"4"},
 			},
 			setupMock:      func(m *testutils.MockSwimModel) {},
 			expectedStatus: http.StatusBadRequest,
@@ -711,29 +1824,40 @@ func TestStoreSwim(t *testing.T) {
 		{
 			name: "negative distance",
 			formData: url.Values{
-				"date":       []string{"2024-01-15"},
-				"distance_m": []string{"-100"},
-				"assessment": []string{"2"},
+				"date":     []string{"2024-01-15"},
+				"distance": []string{"-100"},
+				"feel":     []string{"4"},
 			},
 			setupMock:      func(m *testutils.MockSwimModel) {},
 			expectedStatus: http.StatusBadRequest,
 		},
 		{
-			name: "negative assessment",
+			name: "feel too low",
 			formData: url.Values{
-				"date":       []string{"2024-01-15"},
-				"distance_m": []string{"1500"},
-				"assessment": []string{"-1"},
+				"date":     []string{"2024-01-15"},
+				"distance": []string{"1500"},
+				"feel":     []string{"0"},
 			},
 			setupMock:      func(m *testutils.MockSwimModel) {},
 			expectedStatus: http.StatusBadRequest,
 		},
 		{
-			name: "assessment too large",
+			name: "feel too large",
 			formData: url.Values{
-				"date":       []string{"2024-01-15"},
-				"distance_m": []string{"1500"},
-				"assessment": []string{"3"},
+				"date":     []string{"2024-01-15"},
+				"distance": []string{"1500"},
+				"feel":     []string{"6"},
+			},
+			setupMock:      func(m *testutils.MockSwimModel) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "effort too large",
+			formData: url.Values{
+				"date":     []string{"2024-01-15"},
+				"distance": []string{"1500"},
+				"effort":   []string{"11"},
+				"feel":     []string{"3"},
 			},
 			setupMock:      func(m *testutils.MockSwimModel) {},
 			expectedStatus: http.StatusBadRequest,
@@ -753,6 +1877,9 @@ func TestStoreSwim(t *testing.T) {
 
 			ctx, _ := app.sessionManager.Load(r.Context(), "")
 			app.sessionManager.Put(ctx, "authenticatedUserID", 1)
+			if tt.distanceUnit != "" {
+				app.sessionManager.Put(ctx, "distanceUnit", string(tt.distanceUnit))
+			}
 			r = r.WithContext(ctx)
 
 			app.storeSwim(rr, r)
@@ -766,6 +1893,42 @@ func TestStoreSwim(t *testing.T) {
 	}
 }
 
+func TestStoreSwimRejectsForeignLocation(t *testing.T) {
+	app := newTestApplication()
+	app.swims = &testutils.MockSwimModel{
+		InsertFunc: func(swim *models.Swim, userId int) error {
+			t.Error("swim with a foreign location must not be stored")
+			return nil
+		},
+	}
+	app.locations = &testutils.MockLocationModel{
+		GetByIDFunc: func(userId int, locationId int) (*models.Location, error) {
+			assert.Equal(t, 1, userId)
+			assert.Equal(t, 7, locationId)
+			return nil, models.ErrNoRecord
+		},
+	}
+
+	form := url.Values{
+		"date":     []string{"2024-01-15"},
+		"distance": []string{"1500"},
+		"location": []string{"7"},
+		"feel":     []string{"3"},
+	}
+
+	rr := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/swim", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	ctx, _ := app.sessionManager.Load(r.Context(), "")
+	app.sessionManager.Put(ctx, "authenticatedUserID", 1)
+	r = r.WithContext(ctx)
+
+	app.storeSwim(rr, r)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
 func TestEditSwim(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -829,11 +1992,13 @@ func TestEditSwim(t *testing.T) {
 
 func TestUpdateSwim(t *testing.T) {
 	validForm := url.Values{
-		"date":       []string{"2024-02-01"},
-		"distance_m": []string{"2000"},
-		"assessment": []string{"2"},
-		"sort":       []string{models.SwimSortDistance},
-		"direction":  []string{models.SortDirectionAsc},
+		"date":      []string{"2024-02-01"},
+		"distance":  []string{"2000"},
+		"duration":  []string{"40:00"},
+		"stroke":    []string{"mixed"},
+		"feel":      []string{"4"},
+		"sort":      []string{models.SwimSortDistance},
+		"direction": []string{models.SortDirectionAsc},
 	}
 
 	tests := []struct {
@@ -849,11 +2014,13 @@ func TestUpdateSwim(t *testing.T) {
 			swimID: "5",
 			form:   validForm,
 			setupMock: func(m *testutils.MockSwimModel) {
-				m.UpdateFunc = func(id int, userId int, date time.Time, distanceM int, assessment int) error {
-					assert.Equal(t, 5, id)
+				m.UpdateFunc = func(swim *models.Swim, userId int) error {
+					assert.Equal(t, 5, swim.Id)
 					assert.Equal(t, 1, userId)
-					assert.Equal(t, 2000, distanceM)
-					assert.Equal(t, 2, assessment)
+					assert.Equal(t, 2000, swim.DistanceM)
+					assert.Equal(t, 40*time.Minute, swim.Duration)
+					assert.Equal(t, models.StrokeMixed, swim.Stroke)
+					assert.Equal(t, models.FeelGood, swim.Feel)
 					return nil
 				}
 			},
@@ -864,13 +2031,51 @@ func TestUpdateSwim(t *testing.T) {
 			name:   "successful update with missing sort parameters",
 			swimID: "9",
 			form: url.Values{
-				"date":       []string{"2024-02-01"},
-				"distance_m": []string{"2000"},
-				"assessment": []string{"2"},
+				"date":     []string{"2024-02-01"},
+				"distance": []string{"2000"},
+				"feel":     []string{"4"},
+			},
+			setupMock: func(m *testutils.MockSwimModel) {
+				m.UpdateFunc = func(swim *models.Swim, userId int) error {
+					assert.Equal(t, 9, swim.Id)
+					return nil
+				}
+			},
+			expectedStatus:   http.StatusSeeOther,
+			expectedLocation: "/swims?direction=desc&sort=date",
+		},
+		{
+			name:   "distance edited on a swim with laps",
+			swimID: "5",
+			form: url.Values{
+				"date":     []string{"2024-02-01"},
+				"distance": []string{"1200"},
+				"laps":     []string{"40"},
+				"pool":     []string{"25m"},
+				"feel":     []string{"4"},
+			},
+			setupMock: func(m *testutils.MockSwimModel) {
+				m.UpdateFunc = func(swim *models.Swim, userId int) error {
+					t.Error("the distance must not be replaced by the laps")
+					return nil
+				}
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "laps edited on a swim with laps",
+			swimID: "5",
+			form: url.Values{
+				"date":     []string{"2024-02-01"},
+				"distance": []string{""},
+				"laps":     []string{"48"},
+				"pool":     []string{"25m"},
+				"feel":     []string{"4"},
 			},
 			setupMock: func(m *testutils.MockSwimModel) {
-				m.UpdateFunc = func(id int, userId int, date time.Time, distanceM int, assessment int) error {
-					assert.Equal(t, 9, id)
+				m.UpdateFunc = func(swim *models.Swim, userId int) error {
+					assert.Equal(t, 1200, swim.DistanceM)
+					assert.Equal(t, 48, swim.Laps)
 					return nil
 				}
 			},
@@ -882,7 +2087,7 @@ func TestUpdateSwim(t *testing.T) {
 			swimID: "10",
 			form:   validForm,
 			setupMock: func(m *testutils.MockSwimModel) {
-				m.UpdateFunc = func(id int, userId int, date time.Time, distanceM int, assessment int) error {
+				m.UpdateFunc = func(swim *models.Swim, userId int) error {
 					return models.ErrNoRecord
 				}
 			},
@@ -892,9 +2097,9 @@ func TestUpdateSwim(t *testing.T) {
 			name:   "invalid form data",
 			swimID: "3",
 			form: url.Values{
-				"date":       []string{"invalid-date"},
-				"distance_m": []string{"2000"},
-				"assessment": []string{"2"},
+				"date":     []string{"invalid-date"},
+				"distance": []string{"2000"},
+				"feel":     []string{"4"},
 			},
 			setupMock:      func(m *testutils.MockSwimModel) {},
 			expectedStatus: http.StatusBadRequest,
@@ -904,7 +2109,7 @@ func TestUpdateSwim(t *testing.T) {
 			swimID: "6",
 			form:   validForm,
 			setupMock: func(m *testutils.MockSwimModel) {
-				m.UpdateFunc = func(id int, userId int, date time.Time, distanceM int, assessment int) error {
+				m.UpdateFunc = func(swim *models.Swim, userId int) error {
 					return errors.New("db error")
 				}
 			},
@@ -935,20 +2140,20 @@ func TestUpdateSwim(t *testing.T) {
 			name:   "invalid distance format",
 			swimID: "5",
 			form: url.Values{
-				"date":       []string{"2024-02-01"},
-				"distance_m": []string{"not-a-number"},
-				"assessment": []string{"2"},
+				"date":     []string{"2024-02-01"},
+				"distance": []string{"not-a-number"},
+				"feel":     []string{"4"},
 			},
 			setupMock:      func(m *testutils.MockSwimModel) {},
 			expectedStatus: http.StatusBadRequest,
 		},
 		{
-			name:   "invalid assessment format",
+			name:   "invalid feel format",
 			swimID: "5",
 			form: url.Values{
-				"date":       []string{"2024-02-01"},
-				"distance_m": []string{"2000"},
-				"assessment": []string{"invalid"},
+				"date":     []string{"2024-02-01"},
+				"distance": []string{"2000"},
+				"feel":     []string{"invalid"},
 			},
 			setupMock:      func(m *testutils.MockSwimModel) {},
 			expectedStatus: http.StatusBadRequest,
@@ -957,13 +2162,13 @@ func TestUpdateSwim(t *testing.T) {
 			name:   "extremely large distance",
 			swimID: "5",
 			form: url.Values{
-				"date":       []string{"2024-02-01"},
-				"distance_m": []string{"999999"},
-				"assessment": []string{"2"},
+				"date":     []string{"2024-02-01"},
+				"distance": []string{"999999"},
+				"feel":     []string{"4"},
 			},
 			setupMock: func(m *testutils.MockSwimModel) {
-				m.UpdateFunc = func(id int, userId int, date time.Time, distanceM int, assessment int) error {
-					assert.Equal(t, 999999, distanceM)
+				m.UpdateFunc = func(swim *models.Swim, userId int) error {
+					assert.Equal(t, 999999, swim.DistanceM)
 					return nil
 				}
 			},
@@ -974,9 +2179,9 @@ func TestUpdateSwim(t *testing.T) {
 			name:   "zero distance",
 			swimID: "5",
 			form: url.Values{
-				"date":       []string{"2024-02-01"},
-				"distance_m": []string{"0"},
-				"assessment": []string{"2"},
+				"date":     []string{"2024-02-01"},
+				"distance": []string{"0"},
+				"feel":     []string{"4"},
 			},
 			setupMock:      func(m *testutils.MockSwimModel) {},
 			expectedStatus: http.StatusBadRequest,
@@ -985,31 +2190,31 @@ func TestUpdateSwim(t *testing.T) {
 			name:   "negative distance",
 			swimID: "5",
 			form: url.Values{
-				"date":       []string{"2024-02-01"},
-				"distance_m": []string{"-100"},
-				"assessment": []string{"2"},
+				"date":     []string{"2024-02-01"},
+				"distance": []string{"-100"},
+				"feel":     []string{"4"},
 			},
 			setupMock:      func(m *testutils.MockSwimModel) {},
 			expectedStatus: http.StatusBadRequest,
 		},
 		{
-			name:   "negative assessment",
+			name:   "feel too low",
 			swimID: "5",
 			form: url.Values{
-				"date":       []string{"2024-02-01"},
-				"distance_m": []string{"2000"},
-				"assessment": []string{"-1"},
+				"date":     []string{"2024-02-01"},
+				"distance": []string{"2000"},
+				"feel":     []string{"0"},
 			},
 			setupMock:      func(m *testutils.MockSwimModel) {},
 			expectedStatus: http.StatusBadRequest,
 		},
 		{
-			name:   "assessment too large",
+			name:   "feel too large",
 			swimID: "5",
 			form: url.Values{
-				"date":       []string{"2024-02-01"},
-				"distance_m": []string{"2000"},
-				"assessment": []string{"3"},
+				"date":     []string{"2024-02-01"},
+				"distance": []string{"2000"},
+				"feel":     []string{"6"},
 			},
 			setupMock:      func(m *testutils.MockSwimModel) {},
 			expectedStatus: http.StatusBadRequest,
@@ -2,15 +2,35 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"net/http"
+	"strings"
+
+	"github.com/rockstaedt/swimmate/internal/models"
 )
 
 func (app *application) serverError(w http.ResponseWriter, r *http.Request, err error) {
-	app.logger.Error(err.Error(), "method", r.Method, "uri", r.URL.RequestURI())
+	app.logger.Error(err.Error(), "method", r.Method, "uri", requestURI(r))
 	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 }
 
+// requestURI returns the URI of a request for the logs. The secret tokens of
+// calendar feed and password reset URLs are left out, since anyone who knows
+// them can read the swims of their user or take over the account.
+func requestURI(r *http.Request) string {
+	if _, ok := strings.CutPrefix(r.URL.Path, resetPathPrefix); ok {
+		return resetPathPrefix + "[token]"
+	}
+
+	rest, ok := strings.CutPrefix(r.URL.Path, calendarPathPrefix)
+	if !ok {
+		return r.URL.RequestURI()
+	}
+	_, file, _ := strings.Cut(rest, "/")
+	return calendarPathPrefix + "[token]/" + file
+}
+
 func (app *application) clientError(w http.ResponseWriter, status int) {
 	http.Error(w, http.StatusText(status), status)
 }
@@ -69,6 +89,39 @@ func (app *application) renderPartial(w http.ResponseWriter, r *http.Request, te
 	}
 }
 
+// logoutOtherSessions destroys all sessions of a user except the one of the
+// request.
+func (app *application) logoutOtherSessions(r *http.Request, userId int) error {
+	current := app.sessionManager.Token(r.Context())
+
+	return app.sessionManager.Iterate(r.Context(), func(ctx context.Context) error {
+		if app.sessionManager.GetInt(ctx, "authenticatedUserID") != userId || app.sessionManager.Token(ctx) == current {
+			return nil
+		}
+		return app.sessionManager.Destroy(ctx)
+	})
+}
+
 func (app *application) isAuthenticated(r *http.Request) bool {
 	return app.sessionManager.Exists(r.Context(), "authenticatedUserID")
 }
+
+// authenticatedUserID returns the id of the current user. API requests with
+// an access token belong to the user of the token, all others to the user of
+// the session.
+func (app *application) authenticatedUserID(r *http.Request) int {
+	if auth, ok := r.Context().Value(accessTokenContextKey).(tokenAuthentication); ok {
+		return auth.userId
+	}
+	return app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+}
+
+// distanceUnit returns the preferred distance unit of the current user, which
+// is kept in the session so it does not need to be loaded on every request.
+func (app *application) distanceUnit(r *http.Request) models.Unit {
+	unit := models.Unit(app.sessionManager.GetString(r.Context(), "distanceUnit"))
+	if !unit.Valid() {
+		return models.UnitMeters
+	}
+	return unit
+}
@@ -0,0 +1,486 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rockstaedt/swimmate/internal/importer"
+	"github.com/rockstaedt/swimmate/internal/models"
+)
+
+const (
+	importTemplate = "swim-import.tmpl"
+	// maxImportSize limits uploaded files, a CSV file with a swim per day
+	// for a decade stays well below it.
+	maxImportSize = 2 << 20
+	// maxActivitiesSize limits the activity files uploaded at once. A FIT
+	// file of a pool swim has a few hundred kilobytes at most, GPX and TCX
+	// files of long open water swims a few megabytes.
+	maxActivitiesSize = 32 << 20
+	// maxHealthSize limits the upload of an Apple Health export.xml, which
+	// holds every record of the phone and is read as a stream.
+	maxHealthSize = 4 << 30
+	// largeUploadTimeout is the time to upload and read an export.xml or an
+	// archive.
+	largeUploadTimeout = 10 * time.Minute
+)
+
+type importPageData struct {
+	// CSV is the uploaded file. The preview form carries it along, so the
+	// mapping can be changed and the rows imported without another upload.
+	CSV     string
+	Columns []string
+	Mapping importer.CSVMapping
+	Rows    []importer.CSVRow
+	// Activities are the results of uploaded activity files.
+	Activities []activityResult
+	// Archive is the archive import in progress, if any.
+	Archive *models.ImportJob
+}
+
+// columnSelect is a select of the mapping form that picks the column of a
+// swim field.
+type columnSelect struct {
+	Name     string
+	Label    string
+	Selected int
+	Required bool
+}
+
+func (d importPageData) ColumnSelects() []columnSelect {
+	return []columnSelect{
+		{Name: "date_column", Label: "Date", Selected: d.Mapping.Date, Required: true},
+		{Name: "distance_column", Label: "Distance", Selected: d.Mapping.Distance, Required: true},
+		{Name: "feel_column", Label: "Feel (1–5)", Selected: d.Mapping.Feel},
+		{Name: "assessment_column", Label: "Assessment (0–2)", Selected: d.Mapping.Assessment},
+		{Name: "effort_column", Label: "Effort (RPE 1–10)", Selected: d.Mapping.Effort},
+		{Name: "notes_column", Label: "Notes", Selected: d.Mapping.Notes},
+	}
+}
+
+// Importable is the number of rows that are selected for the import by
+// default.
+func (d importPageData) Importable() int {
+	count := 0
+	for _, row := range d.Rows {
+		if row.Importable() {
+			count++
+		}
+	}
+	return count
+}
+
+// importSwims shows the import forms, along with an archive import that was
+// left before it was done.
+func (app *application) importSwims(w http.ResponseWriter, r *http.Request) {
+	job, err := app.importJobs.GetRunning(app.sessionManager.GetInt(r.Context(), "authenticatedUserID"))
+	if err != nil && !errors.Is(err, models.ErrNoRecord) {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.render(w, r, http.StatusOK, importTemplate, app.newTemplateData(r, importPageData{Archive: job}))
+}
+
+// previewImport reads an uploaded CSV file and shows the swims found in it,
+// flagging invalid rows and duplicates of existing swims. Without a mapping in
+// the form, the columns are detected from the first row.
+func (app *application) previewImport(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	err := r.ParseMultipartForm(maxImportSize)
+	if err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		app.logger.Error(err.Error())
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	content, err := uploadedCSV(r)
+	if err != nil {
+		app.logger.Error(err.Error())
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	data, err := app.parseImport(r, content)
+	if err != nil {
+		app.sessionManager.Put(r.Context(), "flashText", fmt.Sprintf("The file cannot be imported: %s.", err))
+		app.sessionManager.Put(r.Context(), "flashType", "flash-error")
+		http.Redirect(w, r, "/swims/import", http.StatusSeeOther)
+		return
+	}
+
+	app.render(w, r, http.StatusOK, importTemplate, app.newTemplateData(r, data))
+}
+
+// storeImport inserts the rows confirmed in the preview. The file is parsed
+// and checked again, so only valid rows make it into the database, all of
+// them in one transaction.
+func (app *application) storeImport(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	err := r.ParseForm()
+	if err != nil {
+		app.logger.Error(err.Error())
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	data, err := app.parseImport(r, r.PostForm.Get("csv"))
+	if err != nil {
+		app.logger.Error(err.Error())
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	var swims []*models.Swim
+	for _, row := range data.Rows {
+		if row.Err == nil && slices.Contains(r.PostForm["row"], strconv.Itoa(row.Number)) {
+			swims = append(swims, row.Swim)
+		}
+	}
+	if len(swims) == 0 {
+		app.sessionManager.Put(r.Context(), "flashText", "Select at least one row to import.")
+		app.sessionManager.Put(r.Context(), "flashType", "flash-error")
+		app.render(w, r, http.StatusUnprocessableEntity, importTemplate, app.newTemplateData(r, data))
+		return
+	}
+
+	err = app.swims.InsertMany(swims, app.sessionManager.GetInt(r.Context(), "authenticatedUserID"))
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flashText", fmt.Sprintf("Imported %d swims.", len(swims)))
+	app.sessionManager.Put(r.Context(), "flashType", "flash-success")
+
+	http.Redirect(w, r, "/swims", http.StatusSeeOther)
+}
+
+// activityResult is the outcome of an uploaded activity file.
+type activityResult struct {
+	File     string
+	Imported int
+	Skipped  int
+	// Failed counts the activities that were left out of a file that could
+	// be read otherwise, because they miss or have invalid values.
+	Failed int
+	Err    error
+}
+
+// importActivities stores the swims of uploaded activity files, such as FIT,
+// TCX and GPX files of sports watches, and shows the outcome per file.
+// Activities imported before are skipped.
+func (app *application) importActivities(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxActivitiesSize)
+	err := r.ParseMultipartForm(maxImportSize)
+	if err != nil {
+		app.logger.Error(err.Error())
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	files := r.MultipartForm.File["files"]
+	if len(files) == 0 {
+		app.sessionManager.Put(r.Context(), "flashText", "Choose at least one activity file.")
+		app.sessionManager.Put(r.Context(), "flashType", "flash-error")
+		http.Redirect(w, r, "/swims/import", http.StatusSeeOther)
+		return
+	}
+
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+	results := make([]activityResult, 0, len(files))
+	for _, header := range files {
+		result := activityResult{File: header.Filename}
+
+		swims, failed, err := parseActivityFile(header)
+		result.Failed = failed
+		if failed > 0 {
+			app.logger.Info("activities not imported", "file", header.Filename, "failed", failed)
+		}
+		if err != nil {
+			app.logger.Info("activity file not imported", "file", header.Filename, "error", err)
+			result.Err = err
+		}
+
+		err = app.insertActivities(&result, swims, userId)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		results = append(results, result)
+	}
+
+	app.renderActivityResults(w, r, results)
+}
+
+// importHealth stores the swimming workouts of an Apple Health export.xml.
+// The upload is streamed into the parser instead of being parsed as a form,
+// since the file easily outgrows memory and the usual upload limits.
+func (app *application) importHealth(w http.ResponseWriter, r *http.Request) {
+	err := extendDeadlines(w, largeUploadTimeout)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxHealthSize)
+	part, err := uploadedFile(r, "file")
+	if errors.Is(err, http.ErrMissingFile) {
+		app.sessionManager.Put(r.Context(), "flashText", "Choose the export.xml of Apple Health.")
+		app.sessionManager.Put(r.Context(), "flashType", "flash-error")
+		http.Redirect(w, r, "/swims/import", http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		app.logger.Error(err.Error())
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	result := activityResult{File: part.FileName()}
+	swims, failed, err := importer.ParseHealth(part)
+	result.Failed = failed
+	if failed > 0 {
+		app.logger.Info("health workouts not imported", "file", result.File, "failed", failed)
+	}
+	if err != nil {
+		app.logger.Info("health export not imported", "file", result.File, "error", err)
+		result.Err = err
+	}
+
+	err = app.insertActivities(&result, swims, app.sessionManager.GetInt(r.Context(), "authenticatedUserID"))
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.renderActivityResults(w, r, []activityResult{result})
+}
+
+// extendDeadlines lets a request upload and read large files beyond the
+// timeouts of the server. Writers that cannot change their deadlines, such as
+// recorders in tests, are left as they are.
+func extendDeadlines(w http.ResponseWriter, timeout time.Duration) error {
+	rc := http.NewResponseController(w)
+	for _, err := range []error{
+		rc.SetReadDeadline(time.Now().Add(timeout)),
+		rc.SetWriteDeadline(time.Now().Add(timeout)),
+	} {
+		if err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return err
+		}
+	}
+	return nil
+}
+
+// uploadedFile returns the file of a multipart form field as a stream, so
+// that large uploads are neither kept in memory nor buffered on disk. Parts
+// in front of it are skipped, the ones after it can not be read anymore.
+func uploadedFile(r *http.Request, name string) (*multipart.Part, error) {
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil, http.ErrMissingFile
+		}
+		if err != nil {
+			return nil, err
+		}
+		if part.FormName() == name && part.FileName() != "" {
+			return part, nil
+		}
+	}
+}
+
+// insertActivities stores the swims read from an activity file and counts
+// them in its result. Swims of activities imported before are skipped and
+// swims the database rejects are counted as failed.
+func (app *application) insertActivities(result *activityResult, swims []*models.Swim, userId int) error {
+	for _, swim := range swims {
+		err := app.swims.Insert(swim, userId)
+		if errors.Is(err, models.ErrDuplicateSource) {
+			result.Skipped++
+			continue
+		}
+		if errors.Is(err, models.ErrInvalidSwim) {
+			app.logger.Info("activity not imported", "file", result.File, "error", err)
+			result.Failed++
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		result.Imported++
+	}
+	return nil
+}
+
+// renderActivityResults shows the outcome of imported activity files with a
+// flash summing them up.
+func (app *application) renderActivityResults(w http.ResponseWriter, r *http.Request, results []activityResult) {
+	imported, skipped, failedActivities, failed := 0, 0, 0, 0
+	for _, result := range results {
+		imported += result.Imported
+		skipped += result.Skipped
+		failedActivities += result.Failed
+		if result.Err != nil {
+			failed++
+		}
+	}
+
+	flash := fmt.Sprintf("Imported %d swims.", imported)
+	if skipped > 0 {
+		flash += fmt.Sprintf(" Skipped %d swims that were imported before.", skipped)
+	}
+	flashType := "flash-success"
+	if failedActivities > 0 {
+		flash += fmt.Sprintf(" Left out %d activities with missing or invalid values.", failedActivities)
+		flashType = "flash-error"
+	}
+	if failed > 0 {
+		flash += fmt.Sprintf(" %d files could not be read.", failed)
+		flashType = "flash-error"
+	}
+	app.sessionManager.Put(r.Context(), "flashText", flash)
+	app.sessionManager.Put(r.Context(), "flashType", flashType)
+
+	app.render(w, r, http.StatusOK, importTemplate, app.newTemplateData(r, importPageData{Activities: results}))
+}
+
+// parseActivityFile reads the swims of an uploaded activity file and the
+// number of activities that were left out of it.
+func parseActivityFile(header *multipart.FileHeader) ([]*models.Swim, int, error) {
+	file, err := header.Open()
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	return importer.ParseActivity(header.Filename, file)
+}
+
+// parseImport reads the swims of a CSV file with the mapping of the form, or
+// a detected one, and flags duplicates of the user's swims.
+func (app *application) parseImport(r *http.Request, content string) (importPageData, error) {
+	rows, err := importer.ReadCSV(strings.NewReader(content))
+	if err != nil {
+		return importPageData{}, err
+	}
+	if len(rows) == 0 {
+		return importPageData{}, errors.New("the file is empty")
+	}
+
+	mapping := importer.DetectMapping(rows[0], app.distanceUnit(r))
+	if r.PostForm.Has("date_column") {
+		mapping, err = mappingFromForm(r.PostForm)
+		if err != nil {
+			return importPageData{}, err
+		}
+	}
+
+	columns := importColumns(rows, mapping.Header)
+	err = mapping.Validate(len(columns))
+	if err != nil {
+		return importPageData{}, err
+	}
+
+	existing, err := app.swims.GetAll(app.sessionManager.GetInt(r.Context(), "authenticatedUserID"))
+	if err != nil {
+		return importPageData{}, err
+	}
+
+	parsed := importer.ParseCSV(rows, mapping)
+	importer.MarkDuplicates(parsed, existing)
+
+	return importPageData{CSV: content, Columns: columns, Mapping: mapping, Rows: parsed}, nil
+}
+
+// uploadedCSV returns the content of the uploaded file, or of the csv field
+// when the preview is updated with another mapping.
+func uploadedCSV(r *http.Request) (string, error) {
+	file, _, err := r.FormFile("file")
+	if errors.Is(err, http.ErrMissingFile) || errors.Is(err, http.ErrNotMultipart) {
+		return r.PostForm.Get("csv"), nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return "", err
+	}
+
+	return string(content), nil
+}
+
+func mappingFromForm(form url.Values) (importer.CSVMapping, error) {
+	column := func(name string) (int, error) {
+		value := form.Get(name)
+		if value == "" {
+			return importer.NoColumn, nil
+		}
+		return strconv.Atoi(value)
+	}
+
+	m := importer.CSVMapping{
+		Header: form.Get("header") == "true",
+		Unit:   models.Unit(form.Get("unit")),
+	}
+
+	var err error
+	for _, field := range []struct {
+		name   string
+		target *int
+	}{
+		{"date_column", &m.Date},
+		{"distance_column", &m.Distance},
+		{"feel_column", &m.Feel},
+		{"assessment_column", &m.Assessment},
+		{"effort_column", &m.Effort},
+		{"notes_column", &m.Notes},
+	} {
+		*field.target, err = column(field.name)
+		if err != nil {
+			return importer.CSVMapping{}, err
+		}
+	}
+
+	return m, nil
+}
+
+// importColumns names the columns of a file for the mapping form, by the
+// header row if there is one.
+func importColumns(rows [][]string, header bool) []string {
+	count := 0
+	for _, row := range rows {
+		count = max(count, len(row))
+	}
+
+	columns := make([]string, count)
+	for i := range columns {
+		columns[i] = fmt.Sprintf("Column %d", i+1)
+		if header && i < len(rows[0]) && strings.TrimSpace(rows[0][i]) != "" {
+			columns[i] = strings.TrimSpace(rows[0][i])
+		}
+	}
+
+	return columns
+}
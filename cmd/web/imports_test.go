@@ -0,0 +1,472 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rockstaedt/swimmate/internal/models"
+	"github.com/rockstaedt/swimmate/internal/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+const importPreviewTemplate = `{{define "base"}}{{range .Data.Rows}}{{.Number}}:{{if .Err}}invalid{{else if .Duplicate}}duplicate{{else}}ok{{end}} {{end}}{{end}}`
+
+const importResultsTemplate = `{{define "base"}}{{with .Flash}}{{.Text}}|{{.Type}}|{{end}}{{range .Data.Activities}}{{.File}}:{{.Imported}}/{{.Skipped}}:{{if .Err}}failed{{else}}ok{{end}} {{end}}{{end}}`
+
+func newImportRequest(t *testing.T, app *application, method string, target string, contentType string, body *bytes.Buffer) *http.Request {
+	t.Helper()
+
+	r := httptest.NewRequest(method, target, body)
+	r.Header.Set("Content-Type", contentType)
+
+	ctx, _ := app.sessionManager.Load(r.Context(), "")
+	app.sessionManager.Put(ctx, "authenticatedUserID", 1)
+
+	return r.WithContext(ctx)
+}
+
+func TestPreviewImport(t *testing.T) {
+	existing := []*models.Swim{{Date: time.Date(2024, 1, 16, 0, 0, 0, 0, time.UTC), DistanceM: 1000}}
+	file := "date,distance,assessment\n2024-01-15,1500,2\n2024-01-16,1000,1\n2024-01-17,abc,1\n"
+
+	tests := []struct {
+		name             string
+		body             func() (*bytes.Buffer, string)
+		expectedStatus   int
+		expectedBody     string
+		expectedLocation string
+	}{
+		{
+			name: "uploaded file with detected columns",
+			body: func() (*bytes.Buffer, string) {
+				body := &bytes.Buffer{}
+				mw := multipart.NewWriter(body)
+				fw, _ := mw.CreateFormFile("file", "swims.csv")
+				_, _ = fw.Write([]byte(file))
+				_ = mw.Close()
+				return body, mw.FormDataContentType()
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "2:ok 3:duplicate 4:invalid ",
+		},
+		{
+			name: "mapping changed in the preview",
+			body: func() (*bytes.Buffer, string) {
+				form := url.Values{
+					"csv":               {file},
+					"date_column":       {"0"},
+					"distance_column":   {"2"},
+					"assessment_column": {""},
+					"unit":              {"m"},
+				}
+				return bytes.NewBufferString(form.Encode()), "application/x-www-form-urlencoded"
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "1:invalid 2:ok 3:ok 4:ok ",
+		},
+		{
+			name: "mapping to a missing column",
+			body: func() (*bytes.Buffer, string) {
+				form := url.Values{
+					"csv":             {file},
+					"header":          {"true"},
+					"date_column":     {"0"},
+					"distance_column": {"5"},
+					"unit":            {"m"},
+				}
+				return bytes.NewBufferString(form.Encode()), "application/x-www-form-urlencoded"
+			},
+			expectedStatus:   http.StatusSeeOther,
+			expectedLocation: "/swims/import",
+		},
+		{
+			name: "unreadable file",
+			body: func() (*bytes.Buffer, string) {
+				form := url.Values{"csv": {"2024-01-15,\"1500\n"}}
+				return bytes.NewBufferString(form.Encode()), "application/x-www-form-urlencoded"
+			},
+			expectedStatus:   http.StatusSeeOther,
+			expectedLocation: "/swims/import",
+		},
+		{
+			name: "empty file",
+			body: func() (*bytes.Buffer, string) {
+				form := url.Values{"csv": {""}}
+				return bytes.NewBufferString(form.Encode()), "application/x-www-form-urlencoded"
+			},
+			expectedStatus:   http.StatusSeeOther,
+			expectedLocation: "/swims/import",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication()
+			app.swims = &testutils.MockSwimModel{
+				GetAllFunc: func(userId int) ([]*models.Swim, error) {
+					return existing, nil
+				},
+			}
+			app.templateCache[importTemplate] = createTestTemplate("base", importPreviewTemplate)
+
+			body, contentType := tt.body()
+			rr := httptest.NewRecorder()
+			r := newImportRequest(t, app, http.MethodPost, "/swims/import/preview", contentType, body)
+
+			app.previewImport(rr, r)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			if tt.expectedBody != "" {
+				assert.Equal(t, tt.expectedBody, rr.Body.String())
+			}
+			if tt.expectedLocation != "" {
+				assert.Equal(t, tt.expectedLocation, rr.Header().Get("Location"))
+			}
+		})
+	}
+}
+
+func TestStoreImport(t *testing.T) {
+	file := "date,distance,assessment\n2024-01-15,1500,2\n2024-01-16,1000,1\n2024-01-17,abc,1\n"
+
+	tests := []struct {
+		name           string
+		rows           []string
+		insertErr      error
+		expectedSwims  []*models.Swim
+		expectedStatus int
+	}{
+		{
+			name: "selected rows in one call",
+			rows: []string{"2", "3"},
+			expectedSwims: []*models.Swim{
+				{Date: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), DistanceM: 1500, Stroke: models.StrokeFreestyle, Feel: models.FeelGood},
+				{Date: time.Date(2024, 1, 16, 0, 0, 0, 0, time.UTC), DistanceM: 1000, Stroke: models.StrokeFreestyle, Feel: models.FeelOkay},
+			},
+			expectedStatus: http.StatusSeeOther,
+		},
+		{
+			name: "invalid rows are never imported",
+			rows: []string{"3", "4"},
+			expectedSwims: []*models.Swim{
+				{Date: time.Date(2024, 1, 16, 0, 0, 0, 0, time.UTC), DistanceM: 1000, Stroke: models.StrokeFreestyle, Feel: models.FeelOkay},
+			},
+			expectedStatus: http.StatusSeeOther,
+		},
+		{
+			name:           "nothing selected",
+			rows:           []string{"4"},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:      "database error",
+			rows:      []string{"2"},
+			insertErr: errors.New("database error"),
+			expectedSwims: []*models.Swim{
+				{Date: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), DistanceM: 1500, Stroke: models.StrokeFreestyle, Feel: models.FeelGood},
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var inserted []*models.Swim
+			app := newTestApplication()
+			app.swims = &testutils.MockSwimModel{
+				InsertManyFunc: func(swims []*models.Swim, userId int) error {
+					assert.Equal(t, 1, userId)
+					inserted = swims
+					return tt.insertErr
+				},
+			}
+			app.templateCache[importTemplate] = createTestTemplate("base", importPreviewTemplate)
+
+			form := url.Values{
+				"csv":               {file},
+				"header":            {"true"},
+				"date_column":       {"0"},
+				"distance_column":   {"1"},
+				"assessment_column": {"2"},
+				"unit":              {"m"},
+				"row":               tt.rows,
+			}
+			rr := httptest.NewRecorder()
+			r := newImportRequest(t, app, http.MethodPost, "/swims/import", "application/x-www-form-urlencoded", bytes.NewBufferString(form.Encode()))
+
+			app.storeImport(rr, r)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.Equal(t, tt.expectedSwims, inserted)
+			if tt.expectedStatus == http.StatusSeeOther {
+				assert.Equal(t, "/swims", rr.Header().Get("Location"))
+				assert.Equal(t, fmt.Sprintf("Imported %d swims.", len(tt.expectedSwims)), app.sessionManager.GetString(r.Context(), "flashText"))
+			}
+		})
+	}
+}
+
+func TestImportActivities(t *testing.T) {
+	fit, err := os.ReadFile("testdata/pool-swim.fit")
+	assert.NoError(t, err)
+	gpx := []byte(`<gpx><trk><type>swimming</type><trkseg>
+		<trkpt lat="0" lon="0"><time>2024-07-01T08:00:00Z</time></trkpt>
+		<trkpt lat="0.01" lon="0"><time>2024-07-01T08:25:00Z</time></trkpt>
+	</trkseg></trk></gpx>`)
+	tcx := []byte(`<TrainingCenterDatabase><Activities><Activity Sport="Running">
+		<Id>2024-07-01T08:00:00Z</Id><Lap><DistanceMeters>5000</DistanceMeters></Lap>
+	</Activity></Activities></TrainingCenterDatabase>`)
+
+	type file struct {
+		name    string
+		content []byte
+	}
+
+	tests := []struct {
+		name             string
+		files            []file
+		insertErr        func(swim *models.Swim) error
+		expectedSources  []string
+		expectedStatus   int
+		expectedLocation string
+		expectedBody     string
+	}{
+		{
+			name:            "FIT file",
+			files:           []file{{"Morning_Swim.FIT", fit}},
+			expectedSources: []string{"fit:3912345678:1074236400"},
+			expectedStatus:  http.StatusOK,
+			expectedBody:    "Imported 1 swims.|flash-success|Morning_Swim.FIT:1/0:ok ",
+		},
+		{
+			name:            "FIT, GPX and TCX files",
+			files:           []file{{"pool.fit", fit}, {"lake.gpx", gpx}, {"run.tcx", tcx}},
+			expectedSources: []string{"fit:3912345678:1074236400", "gpx:1719820800"},
+			expectedStatus:  http.StatusOK,
+			expectedBody:    "Imported 2 swims.|flash-success|pool.fit:1/0:ok lake.gpx:1/0:ok run.tcx:0/0:ok ",
+		},
+		{
+			name:  "activity imported before",
+			files: []file{{"pool.fit", fit}, {"lake.gpx", gpx}},
+			insertErr: func(swim *models.Swim) error {
+				if strings.HasPrefix(swim.Source, "fit:") {
+					return models.ErrDuplicateSource
+				}
+				return nil
+			},
+			expectedSources: []string{"fit:3912345678:1074236400", "gpx:1719820800"},
+			expectedStatus:  http.StatusOK,
+			expectedBody:    "Imported 1 swims. Skipped 1 swims that were imported before.|flash-success|pool.fit:0/1:ok lake.gpx:1/0:ok ",
+		},
+		{
+			name: "track without distance",
+			files: []file{{"lake.gpx", bytes.Replace(gpx, []byte("<gpx>"),
+				[]byte(`<gpx><trk><trkseg><trkpt lat="0" lon="0"><time>2024-07-02T08:00:00Z</time></trkpt></trkseg></trk>`), 1)}},
+			expectedSources: []string{"gpx:1719820800"},
+			expectedStatus:  http.StatusOK,
+			expectedBody:    "Imported 1 swims. Left out 1 activities with missing or invalid values.|flash-error|lake.gpx:1/0:ok ",
+		},
+		{
+			name:  "activity the database rejects",
+			files: []file{{"pool.fit", fit}, {"lake.gpx", gpx}},
+			insertErr: func(swim *models.Swim) error {
+				if strings.HasPrefix(swim.Source, "fit:") {
+					return models.ErrInvalidSwim
+				}
+				return nil
+			},
+			expectedSources: []string{"fit:3912345678:1074236400", "gpx:1719820800"},
+			expectedStatus:  http.StatusOK,
+			expectedBody:    "Imported 1 swims. Left out 1 activities with missing or invalid values.|flash-error|pool.fit:0/0:ok lake.gpx:1/0:ok ",
+		},
+		{
+			name:            "unreadable and unsupported files",
+			files:           []file{{"swim.fit", []byte("not a fit file")}, {"swims.xlsx", nil}, {"lake.gpx", gpx}},
+			expectedSources: []string{"gpx:1719820800"},
+			expectedStatus:  http.StatusOK,
+			expectedBody:    "Imported 1 swims. 2 files could not be read.|flash-error|swim.fit:0/0:failed swims.xlsx:0/0:failed lake.gpx:1/0:ok ",
+		},
+		{
+			name:             "no files",
+			expectedStatus:   http.StatusSeeOther,
+			expectedLocation: "/swims/import",
+		},
+		{
+			name:  "database error",
+			files: []file{{"swim.fit", fit}},
+			insertErr: func(swim *models.Swim) error {
+				return errors.New("database error")
+			},
+			expectedSources: []string{"fit:3912345678:1074236400"},
+			expectedStatus:  http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sources []string
+			app := newTestApplication()
+			app.swims = &testutils.MockSwimModel{
+				InsertFunc: func(swim *models.Swim, userId int) error {
+					assert.Equal(t, 1, userId)
+					sources = append(sources, swim.Source)
+					if tt.insertErr != nil {
+						return tt.insertErr(swim)
+					}
+					return nil
+				},
+			}
+			app.templateCache[importTemplate] = createTestTemplate("base", importResultsTemplate)
+
+			body := &bytes.Buffer{}
+			mw := multipart.NewWriter(body)
+			for _, f := range tt.files {
+				fw, _ := mw.CreateFormFile("files", f.name)
+				_, _ = fw.Write(f.content)
+			}
+			_ = mw.Close()
+
+			rr := httptest.NewRecorder()
+			r := newImportRequest(t, app, http.MethodPost, "/swims/import/activities", mw.FormDataContentType(), body)
+
+			app.importActivities(rr, r)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.Equal(t, tt.expectedSources, sources)
+			assert.Equal(t, tt.expectedLocation, rr.Header().Get("Location"))
+			if tt.expectedBody != "" {
+				assert.Equal(t, tt.expectedBody, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestImportHealth(t *testing.T) {
+	export := `<?xml version="1.0" encoding="UTF-8"?>
+<HealthData locale="en_US">
+ <Record type="HKQuantityTypeIdentifierStepCount" unit="count" value="830"/>
+ <Workout workoutActivityType="HKWorkoutActivityTypeSwimming" duration="30" durationUnit="min" totalDistance="1500" totalDistanceUnit="m" startDate="2024-01-15 08:00:00 +0100"/>
+ <Workout workoutActivityType="HKWorkoutActivityTypeSwimming" duration="20" durationUnit="min" totalDistance="1000" totalDistanceUnit="m" startDate="2024-01-17 08:00:00 +0100"/>
+</HealthData>`
+
+	tests := []struct {
+		name             string
+		fileName         string
+		content          string
+		insertErr        func(swim *models.Swim) error
+		expectedSources  []string
+		expectedStatus   int
+		expectedLocation string
+		expectedBody     string
+	}{
+		{
+			name:            "export",
+			fileName:        "export.xml",
+			content:         export,
+			expectedSources: []string{"health:1705302000", "health:1705474800"},
+			expectedStatus:  http.StatusOK,
+			expectedBody:    "Imported 2 swims.|flash-success|export.xml:2/0:ok ",
+		},
+		{
+			name:     "workouts imported before",
+			fileName: "export.xml",
+			content:  export,
+			insertErr: func(swim *models.Swim) error {
+				if swim.Source == "health:1705302000" {
+					return models.ErrDuplicateSource
+				}
+				return nil
+			},
+			expectedSources: []string{"health:1705302000", "health:1705474800"},
+			expectedStatus:  http.StatusOK,
+			expectedBody:    "Imported 1 swims. Skipped 1 swims that were imported before.|flash-success|export.xml:1/1:ok ",
+		},
+		{
+			name:     "workouts without distance",
+			fileName: "export.xml",
+			content: strings.Replace(export, "</HealthData>",
+				` <Workout workoutActivityType="HKWorkoutActivityTypeSwimming" duration="45" durationUnit="min" startDate="2024-07-01 08:00:00 +0200"/>
+</HealthData>`, 1),
+			expectedSources: []string{"health:1705302000", "health:1705474800"},
+			expectedStatus:  http.StatusOK,
+			expectedBody:    "Imported 2 swims. Left out 1 activities with missing or invalid values.|flash-error|export.xml:2/0:ok ",
+		},
+		{
+			name:           "not an export",
+			fileName:       "export_cda.xml",
+			content:        `<ClinicalDocument/>`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   "Imported 0 swims. 1 files could not be read.|flash-error|export_cda.xml:0/0:failed ",
+		},
+		{
+			name:             "no file",
+			expectedStatus:   http.StatusSeeOther,
+			expectedLocation: "/swims/import",
+		},
+		{
+			name:     "database error",
+			fileName: "export.xml",
+			content:  export,
+			insertErr: func(swim *models.Swim) error {
+				return errors.New("database error")
+			},
+			expectedSources: []string{"health:1705302000"},
+			expectedStatus:  http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sources []string
+			app := newTestApplication()
+			app.swims = &testutils.MockSwimModel{
+				InsertFunc: func(swim *models.Swim, userId int) error {
+					assert.Equal(t, 1, userId)
+					sources = append(sources, swim.Source)
+					if tt.insertErr != nil {
+						return tt.insertErr(swim)
+					}
+					return nil
+				},
+			}
+			app.templateCache[importTemplate] = createTestTemplate("base", importResultsTemplate)
+
+			body := &bytes.Buffer{}
+			mw := multipart.NewWriter(body)
+			fw, _ := mw.CreateFormFile("file", tt.fileName)
+			_, _ = fw.Write([]byte(tt.content))
+			_ = mw.Close()
+
+			rr := httptest.NewRecorder()
+			r := newImportRequest(t, app, http.MethodPost, "/swims/import/health", mw.FormDataContentType(), body)
+
+			app.importHealth(rr, r)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.Equal(t, tt.expectedSources, sources)
+			assert.Equal(t, tt.expectedLocation, rr.Header().Get("Location"))
+			if tt.expectedBody != "" {
+				assert.Equal(t, tt.expectedBody, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestImportHealthNotMultipart(t *testing.T) {
+	app := newTestApplication()
+	rr := httptest.NewRecorder()
+	r := newImportRequest(t, app, http.MethodPost, "/swims/import/health", "application/x-www-form-urlencoded", bytes.NewBufferString("file=export.xml"))
+
+	app.importHealth(rr, r)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
@@ -3,13 +3,19 @@ package main
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"github.com/alexedwards/scs/postgresstore"
 	"github.com/alexedwards/scs/v2"
+	"github.com/rockstaedt/swimmate/internal/mailer"
 	"github.com/rockstaedt/swimmate/internal/models"
+	"github.com/rockstaedt/swimmate/internal/webhooks"
 	"html/template"
 	"log/slog"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	_ "github.com/lib/pq"
@@ -18,9 +24,35 @@ import (
 var version string
 
 type application struct {
-	logger         *slog.Logger
-	swims          models.SwimModel
-	users          models.UserModel
+	logger       *slog.Logger
+	swims        models.SwimModel
+	users        models.UserModel
+	invites      models.InviteModel
+	resets       models.PasswordResetModel
+	twoFactor    models.TwoFactorModel
+	tags         models.TagModel
+	locations    models.LocationModel
+	backups      models.BackupModel
+	importJobs   models.ImportJobModel
+	calendars    models.CalendarModel
+	accessTokens models.AccessTokenModel
+	webhooks     models.WebhookModel
+	// dispatcher sends the webhook deliveries in the background.
+	dispatcher *webhooks.Dispatcher
+	// mailer sends the links to reset a password.
+	mailer mailer.Mailer
+	// twoFactorAvailable is set once TWO_FACTOR_KEY is, which encrypts the
+	// TOTP secrets.
+	twoFactorAvailable bool
+	// registration decides who may sign up.
+	registration registrationMode
+	// baseURL is the public address of the app for links used outside of it,
+	// such as https://swimmate.example.com.
+	baseURL string
+	// importDir keeps uploaded archives until their import is done.
+	importDir string
+	// maxArchiveSize limits uploaded archives in bytes.
+	maxArchiveSize int64
 	templateCache  map[string]*template.Template
 	version        string
 	sessionManager *scs.SessionManager
@@ -29,6 +61,38 @@ type application struct {
 func main() {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
 
+	registration, err := parseRegistrationMode(os.Getenv("REGISTRATION"))
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
+	twoFactorKey, err := parseTwoFactorKey(os.Getenv("TWO_FACTOR_KEY"))
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+	if twoFactorKey == nil {
+		logger.Warn("TWO_FACTOR_KEY is not set, two-factor authentication cannot be set up")
+	}
+
+	mail, err := newMailer(logger)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
+	maxArchiveSize, err := parseArchiveSize(os.Getenv("MAX_ARCHIVE_SIZE"))
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
+	baseURL := strings.TrimSuffix(os.Getenv("BASE_URL"), "/")
+	if baseURL == "" {
+		logger.Warn("BASE_URL is not set, links in emails and calendar feeds use the host of the request")
+	}
+
 	templateCache, err := newTemplateCache()
 	if err != nil {
 		logger.Error(err.Error())
@@ -48,12 +112,41 @@ func main() {
 
 	logger.Info("database connection pool established")
 
+	webhookModel := models.NewWebhookModel(db)
+	dispatcher := webhooks.NewDispatcher(webhookModel, webhooks.NewClient(), logger)
+	swims := &webhookSwimModel{
+		SwimModel:  models.NewSwimModel(db),
+		webhooks:   webhookModel,
+		dispatcher: dispatcher,
+		logger:     logger,
+	}
+
 	app := &application{
 		logger:        logger,
 		templateCache: templateCache,
 		version:       version,
-		swims:         models.NewSwimModel(db),
+		swims:         swims,
 		users:         models.NewUserModel(db),
+		invites:       models.NewInviteModel(db),
+		resets:        models.NewPasswordResetModel(db),
+		twoFactor:     models.NewTwoFactorModel(db, twoFactorKey),
+		tags:          models.NewTagModel(db),
+		locations:     models.NewLocationModel(db),
+		backups:       &webhookBackupModel{BackupModel: models.NewBackupModel(db), swims: swims},
+		importJobs:    models.NewImportJobModel(db),
+		calendars:     models.NewCalendarModel(db),
+		accessTokens:  models.NewAccessTokenModel(db),
+		webhooks:      webhookModel,
+		dispatcher:    dispatcher,
+		mailer:        mail,
+		registration:  registration,
+		baseURL:       baseURL,
+		importDir:     os.Getenv("IMPORT_DIR"),
+	}
+	app.twoFactorAvailable = twoFactorKey != nil
+	app.maxArchiveSize = maxArchiveSize
+	if app.importDir == "" {
+		app.importDir = filepath.Join(os.TempDir(), "swimmate-imports")
 	}
 
 	sessionManager := scs.New()
@@ -62,6 +155,9 @@ func main() {
 
 	app.sessionManager = sessionManager
 
+	go dispatcher.Run(context.Background(), time.Minute)
+	go app.cleanArchives(context.Background(), time.Hour)
+
 	port := ":8998"
 	srv := &http.Server{
 		Addr:         port,
@@ -79,6 +175,34 @@ func main() {
 	os.Exit(1)
 }
 
+// newMailer picks how emails are sent. They go to SMTP_HOST if it is set,
+// otherwise into MAIL_DIR or, without that either, into the log.
+func newMailer(logger *slog.Logger) (mailer.Mailer, error) {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "SwimMate <swimmate@localhost>"
+	}
+
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		if dir := os.Getenv("MAIL_DIR"); dir != "" {
+			return mailer.NewFileMailer(dir, from), nil
+		}
+		return mailer.NewLogMailer(logger), nil
+	}
+
+	port := 587
+	if value := os.Getenv("SMTP_PORT"); value != "" {
+		var err error
+		port, err = strconv.Atoi(value)
+		if err != nil || port < 1 || port > 65535 {
+			return nil, fmt.Errorf("invalid SMTP_PORT %q", value)
+		}
+	}
+
+	return mailer.NewSMTPMailer(host, port, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"), from), nil
+}
+
 func openDB() (*sql.DB, error) {
 	db, err := sql.Open("postgres", os.Getenv("DB_DSN"))
 	if err != nil {
@@ -1,10 +1,27 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
+
+	"github.com/rockstaedt/swimmate/internal/models"
 )
 
+type contextKey string
+
+// accessTokenContextKey holds the tokenAuthentication of API requests that
+// were authenticated with an access token instead of the session.
+const accessTokenContextKey = contextKey("accessToken")
+
+// tokenAuthentication is the user and the access token of a request.
+type tokenAuthentication struct {
+	userId int
+	token  *models.AccessToken
+}
+
 func secureHeaders(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Referrer-Policy", "origin-when-cross-origin")
@@ -22,7 +39,7 @@ func (app *application) logRequest(next http.Handler) http.Handler {
 			ip     = r.RemoteAddr
 			proto  = r.Proto
 			method = r.Method
-			uri    = r.URL.RequestURI()
+			uri    = requestURI(r)
 		)
 
 		app.logger.Info("received request", "ip", ip, "proto", proto, "method", method, "uri", uri)
@@ -56,3 +73,68 @@ func (app *application) requireAuthentication(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// requireAPIAuthentication is the requireAuthentication of the API, which
+// answers with a problem document instead of redirecting to the login page.
+// Besides the session, it accepts access tokens in an "Authorization: Bearer"
+// header, which take precedence over a session cookie.
+func (app *application) requireAPIAuthentication(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		switch {
+		case header != "":
+			secret, ok := bearerToken(header)
+			if !ok {
+				app.apiUnauthorized(w, `error="invalid_request"`, "the Authorization header must hold a Bearer token")
+				return
+			}
+
+			userId, token, err := app.accessTokens.Authenticate(secret)
+			if err != nil {
+				if errors.Is(err, models.ErrNoRecord) {
+					app.apiUnauthorized(w, `error="invalid_token"`, "unknown or revoked access token")
+				} else {
+					app.apiServerError(w, r, err)
+				}
+				return
+			}
+
+			r = r.WithContext(context.WithValue(r.Context(), accessTokenContextKey, tokenAuthentication{userId: userId, token: token}))
+		case !app.isAuthenticated(r):
+			app.apiUnauthorized(w, "", "log in or send an access token to use the API")
+			return
+		}
+
+		w.Header().Add("Cache-Control", "no-store")
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireScope only lets requests with an access token through if the token
+// grants scope. Requests of a session may do anything their user may do.
+func (app *application) requireScope(scope models.Scope) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			auth, ok := r.Context().Value(accessTokenContextKey).(tokenAuthentication)
+			if ok && !auth.token.HasScope(scope) {
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="SwimMate", error="insufficient_scope", scope="%s"`, scope))
+				app.apiError(w, http.StatusForbidden, fmt.Sprintf("the access token lacks the %s scope", scope))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// bearerToken returns the token of an Authorization header of the Bearer
+// scheme.
+func bearerToken(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(strings.TrimSpace(header), " ")
+	token = strings.TrimSpace(token)
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return token, true
+}
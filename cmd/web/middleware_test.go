@@ -1,10 +1,13 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
+	"github.com/rockstaedt/swimmate/internal/models"
 	"github.com/rockstaedt/swimmate/internal/testutils"
 	"github.com/stretchr/testify/assert"
 )
@@ -277,6 +280,183 @@ func TestRequireAuthentication(t *testing.T) {
 	}
 }
 
+func TestRequireAPIAuthentication(t *testing.T) {
+	readToken := &models.AccessToken{Id: 3, Name: "phone", Scopes: []models.Scope{models.ScopeSwimsRead}}
+
+	tests := []struct {
+		name              string
+		authorization     string
+		sessionUserId     int
+		authenticateErr   error
+		expectedStatus    int
+		expectedChallenge string
+		expectedUserId    int
+	}{
+		{
+			name:           "session",
+			sessionUserId:  1,
+			expectedStatus: http.StatusOK,
+			expectedUserId: 1,
+		},
+		{
+			name:           "access token",
+			authorization:  "Bearer swm_SECRET",
+			expectedStatus: http.StatusOK,
+			expectedUserId: 2,
+		},
+		{
+			name:           "access token takes precedence over the session",
+			authorization:  "bearer  swm_SECRET ",
+			sessionUserId:  1,
+			expectedStatus: http.StatusOK,
+			expectedUserId: 2,
+		},
+		{
+			name:              "neither session nor token",
+			expectedStatus:    http.StatusUnauthorized,
+			expectedChallenge: `Bearer realm="SwimMate"`,
+		},
+		{
+			name:              "other scheme",
+			authorization:     "Basic dXNlcjpwYXNz",
+			sessionUserId:     1,
+			expectedStatus:    http.StatusUnauthorized,
+			expectedChallenge: `Bearer realm="SwimMate", error="invalid_request"`,
+		},
+		{
+			name:              "unknown token",
+			authorization:     "Bearer swm_REVOKED",
+			authenticateErr:   models.ErrNoRecord,
+			expectedStatus:    http.StatusUnauthorized,
+			expectedChallenge: `Bearer realm="SwimMate", error="invalid_token"`,
+		},
+		{
+			name:            "database error",
+			authorization:   "Bearer swm_SECRET",
+			authenticateErr: errors.New("database error"),
+			expectedStatus:  http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication()
+			secret := ""
+			app.accessTokens = &testutils.MockAccessTokenModel{
+				AuthenticateFunc: func(token string) (int, *models.AccessToken, error) {
+					secret = token
+					if tt.authenticateErr != nil {
+						return 0, nil, tt.authenticateErr
+					}
+					return 2, readToken, nil
+				},
+			}
+
+			userId := 0
+			handler := app.requireAPIAuthentication(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				userId = app.authenticatedUserID(r)
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/swims", nil)
+			ctx, _ := app.sessionManager.Load(r.Context(), "")
+			if tt.sessionUserId != 0 {
+				app.sessionManager.Put(ctx, "authenticatedUserID", tt.sessionUserId)
+			}
+			r = r.WithContext(ctx)
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, r)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.Equal(t, tt.expectedUserId, userId)
+			assert.Equal(t, tt.expectedChallenge, rr.Header().Get("WWW-Authenticate"))
+			if tt.expectedUserId == 2 {
+				assert.Equal(t, "swm_SECRET", secret)
+			}
+			if tt.expectedStatus == http.StatusOK {
+				assert.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
+			} else {
+				assert.Equal(t, "application/problem+json", rr.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	tests := []struct {
+		name              string
+		token             *models.AccessToken
+		expectedStatus    int
+		expectedChallenge string
+	}{
+		{
+			name:           "session",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "token with the scope",
+			token:          &models.AccessToken{Scopes: []models.Scope{models.ScopeSwimsRead, models.ScopeSwimsWrite}},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:              "token without the scope",
+			token:             &models.AccessToken{Scopes: []models.Scope{models.ScopeSwimsRead}},
+			expectedStatus:    http.StatusForbidden,
+			expectedChallenge: `Bearer realm="SwimMate", error="insufficient_scope", scope="swims:write"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication()
+
+			nextHandlerCalled := false
+			handler := app.requireScope(models.ScopeSwimsWrite)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				nextHandlerCalled = true
+			}))
+
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/swims", nil)
+			if tt.token != nil {
+				r = r.WithContext(context.WithValue(r.Context(), accessTokenContextKey, tokenAuthentication{userId: 1, token: tt.token}))
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, r)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.Equal(t, tt.expectedStatus == http.StatusOK, nextHandlerCalled)
+			assert.Equal(t, tt.expectedChallenge, rr.Header().Get("WWW-Authenticate"))
+		})
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	tests := []struct {
+		header        string
+		expectedToken string
+		expectedOk    bool
+	}{
+		{header: "Bearer swm_SECRET", expectedToken: "swm_SECRET", expectedOk: true},
+		{header: "BEARER swm_SECRET", expectedToken: "swm_SECRET", expectedOk: true},
+		{header: "Bearer", expectedOk: false},
+		{header: "Bearer ", expectedOk: false},
+		{header: "Basic dXNlcjpwYXNz", expectedOk: false},
+		{header: "swm_SECRET", expectedOk: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			token, ok := bearerToken(tt.header)
+
+			assert.Equal(t, tt.expectedOk, ok)
+			assert.Equal(t, tt.expectedToken, token)
+		})
+	}
+}
+
 func TestMiddlewareChaining(t *testing.T) {
 	// Test that middleware can be chained together
 	app := &application{
@@ -0,0 +1,379 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rockstaedt/swimmate/internal/models"
+	"github.com/rockstaedt/swimmate/internal/testutils"
+	"github.com/rockstaedt/swimmate/ui"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// openAPISpec is the OpenAPI document as a JSON tree, for looking up the
+// operations that contract tests check responses against.
+type openAPISpec map[string]any
+
+func loadOpenAPISpec(t *testing.T) openAPISpec {
+	t.Helper()
+
+	data, err := fs.ReadFile(ui.Files, "api/openapi.json")
+	require.NoError(t, err)
+
+	var spec openAPISpec
+	require.NoError(t, json.Unmarshal(data, &spec))
+	return spec
+}
+
+// operation returns the path template and the operation of the spec that a
+// request path and method belong to.
+func (s openAPISpec) operation(method string, path string) (string, map[string]any, bool) {
+	for template, item := range s["paths"].(map[string]any) {
+		pattern := "^" + regexp.MustCompile(`\\\{[^/]+\\\}`).ReplaceAllString(regexp.QuoteMeta(template), "[^/]+") + "$"
+		if !regexp.MustCompile(pattern).MatchString(path) {
+			continue
+		}
+		op, ok := item.(map[string]any)[strings.ToLower(method)].(map[string]any)
+		return template, op, ok
+	}
+	return "", nil, false
+}
+
+// resolve follows the $ref of a node within the document.
+func (s openAPISpec) resolve(node map[string]any) map[string]any {
+	ref, ok := node["$ref"].(string)
+	if !ok {
+		return node
+	}
+
+	var target any = map[string]any(s)
+	for _, key := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		target = target.(map[string]any)[key]
+	}
+	return s.resolve(target.(map[string]any))
+}
+
+// validate checks a JSON document against a schema of the spec. References
+// of the schema are resolved against the components of the spec.
+func (s openAPISpec) validate(t *testing.T, schema any, document []byte) {
+	t.Helper()
+
+	root := map[string]any{
+		"components": s["components"],
+		"allOf":      []any{schema},
+	}
+	result, err := gojsonschema.Validate(gojsonschema.NewGoLoader(root), gojsonschema.NewBytesLoader(document))
+	require.NoError(t, err)
+	for _, e := range result.Errors() {
+		t.Errorf("%s: %s", e.Field(), e.Description())
+	}
+}
+
+// checkResponse checks that a response is documented for its operation: its
+// status, its media type, the headers and the schema of a JSON body.
+func (s openAPISpec) checkResponse(t *testing.T, op map[string]any, rr *httptest.ResponseRecorder) {
+	t.Helper()
+
+	responses := op["responses"].(map[string]any)
+	node, ok := responses[strconv.Itoa(rr.Code)].(map[string]any)
+	if !ok {
+		t.Fatalf("status %d is not documented", rr.Code)
+	}
+	response := s.resolve(node)
+
+	headers, _ := response["headers"].(map[string]any)
+	for name := range headers {
+		assert.NotEmpty(t, rr.Header().Get(name), "documented header %s", name)
+	}
+
+	content, ok := response["content"].(map[string]any)
+	if !ok {
+		assert.Empty(t, rr.Body.String(), "undocumented body")
+		return
+	}
+
+	mediaType, _, err := mime.ParseMediaType(rr.Header().Get("Content-Type"))
+	require.NoError(t, err)
+	media, ok := content[mediaType].(map[string]any)
+	if !ok {
+		t.Fatalf("media type %s is not documented for status %d", mediaType, rr.Code)
+	}
+
+	if mediaType == "application/json" || mediaType == "application/problem+json" {
+		s.validate(t, media["schema"], rr.Body.Bytes())
+	}
+}
+
+func TestOpenAPI(t *testing.T) {
+	app := newTestApplication()
+
+	rr := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil)
+
+	app.routes().ServeHTTP(rr, r)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	var spec openAPISpec
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &spec))
+	assert.Equal(t, "3.1.0", spec["openapi"])
+}
+
+// contractApplication is a test application whose models return data for
+// every documented response.
+func contractApplication() *application {
+	date := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	swim := &models.Swim{
+		Id:         7,
+		Date:       date,
+		DistanceM:  1500,
+		Stroke:     models.StrokeMixed,
+		Feel:       models.FeelGood,
+		Effort:     7,
+		Duration:   30 * time.Minute,
+		Pool:       models.Pool{Length: 25, Unit: models.UnitMeters},
+		Laps:       60,
+		Notes:      "Cold",
+		LocationId: 2,
+		Tags:       []string{"open water"},
+		Sets: []models.SwimSet{
+			{Repetitions: 15, DistanceM: 100, Stroke: models.StrokeMixed, Interval: 105 * time.Second, Rest: 15 * time.Second},
+		},
+		Source: "fit:1",
+	}
+	figures := models.SwimFigures{Count: 2, DistanceM: 2500, Duration: 30 * time.Minute, TimedDistanceM: 1500, EffortTotal: 13, RatedCount: 2}
+	now := time.Now()
+
+	app := newTestApplication()
+	app.swims = &testutils.MockSwimModel{
+		GetAfterFunc: func(userId int, limit int, after *models.SwimKey, sort string, direction string, filter models.SwimFilter) ([]*models.Swim, error) {
+			swims := make([]*models.Swim, limit)
+			for i := range swims {
+				swims[i] = swim
+			}
+			return swims, nil
+		},
+		GetByIDFunc: func(userId int, swimId int) (*models.Swim, error) {
+			if swimId != swim.Id {
+				return nil, models.ErrNoRecord
+			}
+			return swim, nil
+		},
+		GetAllFunc: func(userId int) ([]*models.Swim, error) {
+			return []*models.Swim{swim}, nil
+		},
+		ExportFunc: func(userId int, sort string, direction string, filter models.SwimFilter, each func(*models.Swim) error) error {
+			return each(swim)
+		},
+		InsertFunc: func(s *models.Swim, userId int) error {
+			s.Id = 8
+			return nil
+		},
+		UpdateFunc: func(s *models.Swim, userId int) error {
+			if s.Id != swim.Id {
+				return models.ErrNoRecord
+			}
+			return nil
+		},
+		DeleteFunc: func(id int, userId int) error {
+			if id != swim.Id {
+				return models.ErrNoRecord
+			}
+			return nil
+		},
+		SummarizeFunc: func(userId int, filter models.SwimFilter) *models.SwimSummary {
+			return &models.SwimSummary{
+				TotalCount:     2,
+				TotalDistance:  2500,
+				WeeklyCount:    1,
+				WeeklyDistance: 1500,
+				WeeklyPace:     2 * time.Minute,
+				WeeklyEffort:   7,
+				YearMap: map[int]models.YearMap{
+					now.Year(): {
+						SwimFigures: figures,
+						MonthMap:    map[time.Month]models.SwimFigures{now.Month(): figures},
+						StrokeMap:   map[models.Stroke]models.SwimFigures{models.StrokeMixed: figures},
+					},
+				},
+				StrokeMap: map[models.Stroke]models.SwimFigures{models.StrokeMixed: figures},
+			}
+		},
+	}
+	app.locations = &testutils.MockLocationModel{
+		GetByIDFunc: func(userId int, locationId int) (*models.Location, error) {
+			return &models.Location{Id: locationId, Name: "Stadtbad"}, nil
+		},
+	}
+	app.backups = &testutils.MockBackupModel{
+		GetFunc: func(userId int) (*models.Backup, error) {
+			return &models.Backup{
+				User: models.User{
+					ID:           userId,
+					FirstName:    "Jane",
+					LastName:     "Doe",
+					Username:     "jane",
+					Email:        "jane@example.com",
+					DateJoined:   date,
+					DistanceUnit: models.UnitMeters,
+				},
+				Locations: []*models.Location{{Id: 2, Name: "Stadtbad", Pool: models.Pool{Length: 25, Unit: models.UnitMeters}, Indoor: true}},
+				Swims:     []*models.Swim{swim},
+			}, nil
+		},
+	}
+	app.users = &testutils.MockUserModel{
+		GetFunc: func(id int) (*models.User, error) {
+			return &models.User{ID: id, DistanceUnit: models.UnitMeters}, nil
+		},
+	}
+	app.calendars = &testutils.MockCalendarModel{
+		UserIDFunc: func(token string) (int, error) {
+			if token != "FEEDTOKEN" {
+				return 0, models.ErrNoRecord
+			}
+			return 1, nil
+		},
+	}
+	app.accessTokens = &testutils.MockAccessTokenModel{
+		AuthenticateFunc: func(token string) (int, *models.AccessToken, error) {
+			switch token {
+			case "swm_READ":
+				return 1, &models.AccessToken{Scopes: []models.Scope{models.ScopeSwimsRead}}, nil
+			case "swm_WRITE":
+				return 1, &models.AccessToken{Scopes: []models.Scope{models.ScopeSwimsRead, models.ScopeSwimsWrite}}, nil
+			}
+			return 0, nil, models.ErrNoRecord
+		},
+	}
+
+	return app
+}
+
+type contractCase struct {
+	name   string
+	method string
+	path   string
+	body   string
+	// token is sent as Bearer token, session starts a session of user 1.
+	token          string
+	session        bool
+	expectedStatus int
+}
+
+var contractCases = []contractCase{
+	{name: "spec", method: http.MethodGet, path: "/api/openapi.json", expectedStatus: http.StatusOK},
+
+	{name: "list", method: http.MethodGet, path: "/api/v1/swims?limit=2&sort=distance&direction=asc&tag=open+water&from=2024-01-01", token: "swm_READ", expectedStatus: http.StatusOK},
+	{name: "list with session", method: http.MethodGet, path: "/api/v1/swims", session: true, expectedStatus: http.StatusOK},
+	{name: "list with invalid limit", method: http.MethodGet, path: "/api/v1/swims?limit=0", token: "swm_READ", expectedStatus: http.StatusBadRequest},
+	{name: "list without authentication", method: http.MethodGet, path: "/api/v1/swims", expectedStatus: http.StatusUnauthorized},
+	{name: "list with revoked token", method: http.MethodGet, path: "/api/v1/swims", token: "swm_REVOKED", expectedStatus: http.StatusUnauthorized},
+
+	{name: "create", method: http.MethodPost, path: "/api/v1/swims", token: "swm_WRITE", body: `{"date": "2024-01-15", "feel": 4, "effort": 6, "laps": 40, "pool_length": 25, "pool_unit": "m", "location_id": 2, "tags": ["Technique"]}`, expectedStatus: http.StatusCreated},
+	{name: "create with sets", method: http.MethodPost, path: "/api/v1/swims", token: "swm_WRITE", body: `{"date": "2024-01-15", "feel": 4, "sets": [{"repetitions": 4, "distance_m": 100, "stroke": "backstroke", "rest_s": 20}]}`, expectedStatus: http.StatusCreated},
+	{name: "create with read token", method: http.MethodPost, path: "/api/v1/swims", token: "swm_READ", body: `{"date": "2024-01-15", "distance_m": 1000, "feel": 4}`, expectedStatus: http.StatusForbidden},
+	{name: "create with malformed JSON", method: http.MethodPost, path: "/api/v1/swims", token: "swm_WRITE", body: `{"date"`, expectedStatus: http.StatusBadRequest},
+	{name: "create invalid swim", method: http.MethodPost, path: "/api/v1/swims", token: "swm_WRITE", body: `{"date": "2024-01-15", "feel": 4}`, expectedStatus: http.StatusUnprocessableEntity},
+	{name: "create with too large body", method: http.MethodPost, path: "/api/v1/swims", token: "swm_WRITE", body: `{"notes": "` + strings.Repeat("a", maxAPIBodySize) + `"}`, expectedStatus: http.StatusRequestEntityTooLarge},
+
+	{name: "get", method: http.MethodGet, path: "/api/v1/swims/7", token: "swm_READ", expectedStatus: http.StatusOK},
+	{name: "get unknown swim", method: http.MethodGet, path: "/api/v1/swims/9", token: "swm_READ", expectedStatus: http.StatusNotFound},
+
+	{name: "replace", method: http.MethodPut, path: "/api/v1/swims/7", token: "swm_WRITE", body: `{"id": 7, "date": "2024-01-15", "distance_m": 1000, "stroke": "butterfly", "feel": 5, "duration_s": 1200, "notes": "Fast"}`, expectedStatus: http.StatusOK},
+	{name: "replace unknown swim", method: http.MethodPut, path: "/api/v1/swims/9", token: "swm_WRITE", body: `{"date": "2024-01-15", "distance_m": 1000, "feel": 5}`, expectedStatus: http.StatusNotFound},
+
+	{name: "delete", method: http.MethodDelete, path: "/api/v1/swims/7", token: "swm_WRITE", expectedStatus: http.StatusNoContent},
+	{name: "delete unknown swim", method: http.MethodDelete, path: "/api/v1/swims/9", token: "swm_WRITE", expectedStatus: http.StatusNotFound},
+	{name: "delete with read token", method: http.MethodDelete, path: "/api/v1/swims/7", token: "swm_READ", expectedStatus: http.StatusForbidden},
+
+	{name: "summary", method: http.MethodGet, path: "/api/v1/summary?tag=open+water", token: "swm_READ", expectedStatus: http.StatusOK},
+	{name: "summary with invalid date range", method: http.MethodGet, path: "/api/v1/summary?from=2024-02-01&to=2024-01-01", token: "swm_READ", expectedStatus: http.StatusBadRequest},
+
+	{name: "export", method: http.MethodGet, path: "/swims/export.csv?sort=date&direction=asc", session: true, expectedStatus: http.StatusOK},
+	{name: "export without session", method: http.MethodGet, path: "/swims/export.csv", expectedStatus: http.StatusSeeOther},
+	{name: "export with invalid date range", method: http.MethodGet, path: "/swims/export.csv?from=2024-13-01", session: true, expectedStatus: http.StatusBadRequest},
+
+	{name: "backup", method: http.MethodGet, path: "/account/backup.json", session: true, expectedStatus: http.StatusOK},
+	{name: "backup without session", method: http.MethodGet, path: "/account/backup.json", expectedStatus: http.StatusSeeOther},
+
+	{name: "calendar feed", method: http.MethodGet, path: "/calendar/FEEDTOKEN/swims.ics", expectedStatus: http.StatusOK},
+	{name: "calendar feed of unknown token", method: http.MethodGet, path: "/calendar/UNKNOWN/swims.ics", expectedStatus: http.StatusNotFound},
+}
+
+// TestOpenAPIContract runs requests through the router and checks the
+// responses against the OpenAPI document, so that the document cannot drift
+// from the handlers.
+func TestOpenAPIContract(t *testing.T) {
+	spec := loadOpenAPISpec(t)
+	app := contractApplication()
+	handler := app.routes()
+
+	for _, tt := range contractCases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, op, ok := spec.operation(tt.method, strings.Split(tt.path, "?")[0])
+			require.True(t, ok, "%s %s is not documented", tt.method, tt.path)
+
+			if tt.body != "" && tt.expectedStatus < http.StatusBadRequest {
+				body := spec.resolve(op["requestBody"].(map[string]any))
+				media := body["content"].(map[string]any)["application/json"].(map[string]any)
+				spec.validate(t, media["schema"], []byte(tt.body))
+			}
+
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body))
+			if tt.body != "" {
+				r.Header.Set("Content-Type", "application/json")
+			}
+			if tt.token != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			if tt.session {
+				ctx, _ := app.sessionManager.Load(r.Context(), "")
+				app.sessionManager.Put(ctx, "authenticatedUserID", 1)
+				r = r.WithContext(ctx)
+			}
+
+			handler.ServeHTTP(rr, r)
+
+			require.Equal(t, tt.expectedStatus, rr.Code, rr.Body.String())
+			spec.checkResponse(t, op, rr)
+		})
+	}
+}
+
+// TestOpenAPIOperationsCovered makes sure that every operation of the
+// document is checked by a successful contract case, so that documented
+// routes are known to exist.
+func TestOpenAPIOperationsCovered(t *testing.T) {
+	spec := loadOpenAPISpec(t)
+
+	covered := map[string]bool{}
+	for _, tt := range contractCases {
+		template, _, ok := spec.operation(tt.method, strings.Split(tt.path, "?")[0])
+		if ok && tt.expectedStatus < http.StatusMultipleChoices {
+			covered[tt.method+" "+template] = true
+		}
+	}
+
+	for template, item := range spec["paths"].(map[string]any) {
+		for method := range item.(map[string]any) {
+			if method == "parameters" {
+				continue
+			}
+			key := strings.ToUpper(method) + " " + template
+			assert.True(t, covered[key], "%s has no successful contract case", key)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rockstaedt/swimmate/internal/models"
+)
+
+const (
+	minPasswordLength = 10
+	// maxPasswordBytes is the limit of bcrypt, which ignores all later bytes.
+	maxPasswordBytes = 72
+)
+
+// commonPasswords are passwords that are long enough but among the first
+// ones tried by anyone guessing.
+var commonPasswords = map[string]bool{
+	"0123456789":       true,
+	"1234567890":       true,
+	"12345678910":      true,
+	"123456789a":       true,
+	"1q2w3e4r5t":       true,
+	"1qaz2wsx3edc":     true,
+	"abcdefghij":       true,
+	"abc1234567":       true,
+	"football123":      true,
+	"iloveyou12":       true,
+	"letmein123":       true,
+	"password12":       true,
+	"password123":      true,
+	"password1234":     true,
+	"passwordpassword": true,
+	"qwerty1234":       true,
+	"qwerty12345":      true,
+	"qwertyuiop":       true,
+	"sunshine123":      true,
+	"swimmate123":      true,
+	"swimming123":      true,
+	"welcome123":       true,
+}
+
+// checkPassword reports why a password must not be used, nil if it is fine.
+// A password needs at least 10 characters, is not one of the common
+// passwords, not a single repeated character and does not contain any of the
+// names of the account, such as its username.
+func checkPassword(password string, names ...string) error {
+	if utf8.RuneCountInString(password) < minPasswordLength {
+		return fmt.Errorf("the password needs at least %d characters", minPasswordLength)
+	}
+	if len(password) > maxPasswordBytes {
+		return fmt.Errorf("the password is longer than %d bytes", maxPasswordBytes)
+	}
+
+	lower := strings.ToLower(password)
+	if commonPasswords[lower] {
+		return errors.New("the password is too common")
+	}
+
+	first, _ := utf8.DecodeRuneInString(password)
+	if strings.Trim(password, string(first)) == "" {
+		return errors.New("the password repeats a single character")
+	}
+
+	for _, name := range names {
+		if utf8.RuneCountInString(name) >= 3 && strings.Contains(lower, strings.ToLower(name)) {
+			return errors.New("the password contains your username or email address")
+		}
+	}
+
+	return nil
+}
+
+// checkNewPassword checks a new password of user and that it was typed the
+// same way twice.
+func checkNewPassword(password, confirmation string, user *models.User) error {
+	localPart, _, _ := strings.Cut(user.Email, "@")
+	err := checkPassword(password, user.Username, localPart)
+	if err != nil {
+		return err
+	}
+
+	if confirmation != password {
+		return errors.New("the passwords do not match")
+	}
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckPassword(t *testing.T) {
+	tests := []struct {
+		name          string
+		password      string
+		expectedError string
+	}{
+		{name: "long enough", password: "lanes and laps"},
+		{name: "multibyte characters", password: "Schwimmbäder"},
+		{name: "too short", password: "short", expectedError: "the password needs at least 10 characters"},
+		{name: "ten bytes but fewer characters", password: "äöüäöü", expectedError: "the password needs at least 10 characters"},
+		{name: "longer than bcrypt allows", password: strings.Repeat("ab", 37), expectedError: "the password is longer than 72 bytes"},
+		{name: "common password", password: "Password123", expectedError: "the password is too common"},
+		{name: "repeated character", password: "xxxxxxxxxxxx", expectedError: "the password repeats a single character"},
+		{name: "contains the username", password: "my name is JaneDoe", expectedError: "the password contains your username or email address"},
+		{name: "contains the email address", password: "jane.doe.swims", expectedError: "the password contains your username or email address"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkPassword(tt.password, "janedoe", "jane.doe")
+
+			if tt.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.expectedError)
+			}
+		})
+	}
+}
+
+func TestCheckPasswordShortNames(t *testing.T) {
+	assert.NoError(t, checkPassword("jo swims every day", "jo", ""), "names shorter than 3 characters are ignored")
+}
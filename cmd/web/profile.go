@@ -0,0 +1,160 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rockstaedt/swimmate/internal/models"
+)
+
+const profileTemplate = "profile.tmpl"
+
+// profile shows the forms for the profile and the password of the user.
+func (app *application) profile(w http.ResponseWriter, r *http.Request) {
+	user, err := app.users.Get(app.sessionManager.GetInt(r.Context(), "authenticatedUserID"))
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.render(w, r, http.StatusOK, profileTemplate, app.newTemplateData(r, user))
+}
+
+// updateProfile saves the names and email address of the user.
+func (app *application) updateProfile(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		app.logger.Error(err.Error())
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	user, err := app.users.Get(app.sessionManager.GetInt(r.Context(), "authenticatedUserID"))
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	user.FirstName = strings.TrimSpace(r.PostForm.Get("first_name"))
+	user.LastName = strings.TrimSpace(r.PostForm.Get("last_name"))
+	user.Email = strings.TrimSpace(r.PostForm.Get("email"))
+	failed := func(text string) {
+		app.sessionManager.Put(r.Context(), "flashText", text)
+		app.sessionManager.Put(r.Context(), "flashType", "flash-error")
+		app.render(w, r, http.StatusUnprocessableEntity, profileTemplate, app.newTemplateData(r, user))
+	}
+
+	err = checkProfile(user)
+	if err != nil {
+		failed(fmt.Sprintf("Your profile cannot be saved: %s.", err))
+		return
+	}
+
+	err = app.users.UpdateProfile(user)
+	if err != nil {
+		if errors.Is(err, models.ErrDuplicateEmail) {
+			failed("There already is an account with this email address.")
+			return
+		}
+
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flashText", "Profile saved.")
+	app.sessionManager.Put(r.Context(), "flashType", "flash-success")
+
+	http.Redirect(w, r, "/account/profile", http.StatusSeeOther)
+}
+
+// updatePassword changes the password of the user, who has to confirm the
+// current one. All other sessions of the user are logged out, so that
+// whoever knew the old password loses access.
+func (app *application) updatePassword(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		app.logger.Error(err.Error())
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+	user, err := app.users.Get(userId)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	failed := func(text string) {
+		app.sessionManager.Put(r.Context(), "flashText", fmt.Sprintf("Your password cannot be changed: %s.", text))
+		app.sessionManager.Put(r.Context(), "flashType", "flash-error")
+		app.render(w, r, http.StatusUnprocessableEntity, profileTemplate, app.newTemplateData(r, user))
+	}
+
+	password := r.PostForm.Get("password")
+	err = checkNewPassword(password, r.PostForm.Get("password_confirmation"), user)
+	if err != nil {
+		failed(err.Error())
+		return
+	}
+
+	err = app.users.ChangePassword(userId, r.PostForm.Get("current_password"), password)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidCredentials) {
+			failed("the current password is wrong")
+			return
+		}
+
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.sessionManager.RenewToken(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.logoutOtherSessions(r, userId)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flashText", "Password changed. You were logged out everywhere else.")
+	app.sessionManager.Put(r.Context(), "flashType", "flash-success")
+
+	http.Redirect(w, r, "/account/profile", http.StatusSeeOther)
+}
+
+// checkProfile checks the names and email address of an account.
+func checkProfile(user *models.User) error {
+	if user.FirstName == "" {
+		return errors.New("the first name is missing")
+	}
+	if utf8.RuneCountInString(user.FirstName) > models.MaxNameLength || utf8.RuneCountInString(user.LastName) > models.MaxNameLength {
+		return fmt.Errorf("names may have at most %d characters", models.MaxNameLength)
+	}
+
+	return checkEmail(user.Email)
+}
+
+func checkEmail(email string) error {
+	if email == "" {
+		return errors.New("the email address is missing")
+	}
+	if len(email) > models.MaxEmailLength {
+		return fmt.Errorf("the email address is longer than %d characters", models.MaxEmailLength)
+	}
+
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email {
+		return fmt.Errorf("%q is not an email address", email)
+	}
+
+	return nil
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/rockstaedt/swimmate/internal/models"
+	"github.com/rockstaedt/swimmate/internal/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfile(t *testing.T) {
+	tests := []struct {
+		name           string
+		getErr         error
+		expectedStatus int
+	}{
+		{name: "profile of the user", expectedStatus: http.StatusOK},
+		{name: "database error", getErr: errors.New("database error"), expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication()
+			app.users = &testutils.MockUserModel{
+				GetFunc: func(id int) (*models.User, error) {
+					assert.Equal(t, 1, id)
+					if tt.getErr != nil {
+						return nil, tt.getErr
+					}
+					return &models.User{ID: id, Username: "jane", FirstName: "Jane", LastName: "Doe", Email: "jane@example.com"}, nil
+				},
+			}
+			app.templateCache[profileTemplate] = createTestTemplate("base",
+				`{{define "base"}}{{.Data.Username}} {{.Data.FirstName}} {{.Data.LastName}} {{.Data.Email}}{{end}}`)
+
+			rr := httptest.NewRecorder()
+			r := newImportRequest(t, app, http.MethodGet, "/account/profile", "", &bytes.Buffer{})
+
+			app.profile(rr, r)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			if tt.expectedStatus == http.StatusOK {
+				assert.Equal(t, "jane Jane Doe jane@example.com", rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestUpdateProfile(t *testing.T) {
+	tests := []struct {
+		name           string
+		form           url.Values
+		updateErr      error
+		expectedStatus int
+		expectedUser   *models.User
+		expectedFlash  string
+		expectedBody   string
+	}{
+		{
+			name:           "profile saved",
+			form:           url.Values{"first_name": {" Jane "}, "last_name": {"Smith"}, "email": {"jane@example.org"}},
+			expectedStatus: http.StatusSeeOther,
+			expectedUser:   &models.User{ID: 1, Username: "jane", FirstName: "Jane", LastName: "Smith", Email: "jane@example.org"},
+			expectedFlash:  "Profile saved.",
+		},
+		{
+			name:           "missing first name",
+			form:           url.Values{"last_name": {"Smith"}, "email": {"jane@example.org"}},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   "Your profile cannot be saved: the first name is missing. Smith jane@example.org",
+		},
+		{
+			name:           "invalid email",
+			form:           url.Values{"first_name": {"Jane"}, "email": {"jane"}},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `Your profile cannot be saved: "jane" is not an email address.  jane`,
+		},
+		{
+			name:           "email taken",
+			form:           url.Values{"first_name": {"Jane"}, "last_name": {"Doe"}, "email": {"john@example.com"}},
+			updateErr:      models.ErrDuplicateEmail,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedUser:   &models.User{ID: 1, Username: "jane", FirstName: "Jane", LastName: "Doe", Email: "john@example.com"},
+			expectedBody:   "There already is an account with this email address. Doe john@example.com",
+		},
+		{
+			name:           "database error",
+			form:           url.Values{"first_name": {"Jane"}, "last_name": {"Doe"}, "email": {"jane@example.com"}},
+			updateErr:      errors.New("database error"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedUser:   &models.User{ID: 1, Username: "jane", FirstName: "Jane", LastName: "Doe", Email: "jane@example.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication()
+			var updated *models.User
+			app.users = &testutils.MockUserModel{
+				GetFunc: func(id int) (*models.User, error) {
+					return &models.User{ID: id, Username: "jane", FirstName: "Jane", LastName: "Doe", Email: "jane@example.com"}, nil
+				},
+				UpdateProfileFunc: func(user *models.User) error {
+					updated = user
+					return tt.updateErr
+				},
+			}
+			app.templateCache[profileTemplate] = createTestTemplate("base",
+				`{{define "base"}}{{with .Flash}}{{.Text}} {{end}}{{.Data.LastName}} {{.Data.Email}}{{end}}`)
+
+			rr := httptest.NewRecorder()
+			r := newImportRequest(t, app, http.MethodPost, "/account/profile", "application/x-www-form-urlencoded",
+				bytes.NewBufferString(tt.form.Encode()))
+
+			app.updateProfile(rr, r)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.Equal(t, tt.expectedUser, updated)
+			switch tt.expectedStatus {
+			case http.StatusSeeOther:
+				assert.Equal(t, "/account/profile", rr.Header().Get("Location"))
+				assert.Equal(t, tt.expectedFlash, app.sessionManager.GetString(r.Context(), "flashText"))
+			case http.StatusUnprocessableEntity:
+				assert.Equal(t, tt.expectedBody, html.UnescapeString(rr.Body.String()), "the form keeps the entered values")
+			}
+		})
+	}
+}
+
+func TestUpdatePassword(t *testing.T) {
+	form := func(current, password, confirmation string) url.Values {
+		return url.Values{"current_password": {current}, "password": {password}, "password_confirmation": {confirmation}}
+	}
+
+	tests := []struct {
+		name           string
+		form           url.Values
+		changeErr      error
+		expectedStatus int
+		expectedChange bool
+		expectedFlash  string
+	}{
+		{
+			name:           "password changed",
+			form:           form("old password", "lanes and laps", "lanes and laps"),
+			expectedStatus: http.StatusSeeOther,
+			expectedChange: true,
+			expectedFlash:  "Password changed. You were logged out everywhere else.",
+		},
+		{
+			name:           "wrong current password",
+			form:           form("guess", "lanes and laps", "lanes and laps"),
+			changeErr:      models.ErrInvalidCredentials,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedChange: true,
+			expectedFlash:  "Your password cannot be changed: the current password is wrong.",
+		},
+		{
+			name:           "weak password",
+			form:           form("old password", "jane.doe.2024", "jane.doe.2024"),
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedFlash:  "Your password cannot be changed: the password contains your username or email address.",
+		},
+		{
+			name:           "passwords differ",
+			form:           form("old password", "lanes and laps", "lanes and lapz"),
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedFlash:  "Your password cannot be changed: the passwords do not match.",
+		},
+		{
+			name:           "database error",
+			form:           form("old password", "lanes and laps", "lanes and laps"),
+			changeErr:      errors.New("database error"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedChange: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication()
+			changed := false
+			app.users = &testutils.MockUserModel{
+				GetFunc: func(id int) (*models.User, error) {
+					return &models.User{ID: id, Username: "jane", Email: "jane.doe@example.com"}, nil
+				},
+				ChangePasswordFunc: func(id int, currentPassword, newPassword string) error {
+					changed = true
+					assert.Equal(t, 1, id)
+					assert.Equal(t, tt.form.Get("current_password"), currentPassword)
+					assert.Equal(t, "lanes and laps", newPassword)
+					return tt.changeErr
+				},
+			}
+			app.templateCache[profileTemplate] = createTestTemplate("base",
+				`{{define "base"}}{{with .Flash}}{{.Text}}{{end}}{{end}}`)
+
+			// Another session of the user, say on the phone, and one of
+			// another user
+			other := storeSession(t, app, 1)
+			stranger := storeSession(t, app, 2)
+
+			rr := httptest.NewRecorder()
+			r := newImportRequest(t, app, http.MethodPost, "/account/password", "application/x-www-form-urlencoded",
+				bytes.NewBufferString(tt.form.Encode()))
+			token := app.sessionManager.Token(r.Context())
+
+			app.updatePassword(rr, r)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.Equal(t, tt.expectedChange, changed)
+			switch tt.expectedStatus {
+			case http.StatusSeeOther:
+				assert.Equal(t, "/account/profile", rr.Header().Get("Location"))
+				assert.Equal(t, tt.expectedFlash, app.sessionManager.GetString(r.Context(), "flashText"))
+				assert.NotEqual(t, token, app.sessionManager.Token(r.Context()), "the session gets a new token")
+				assert.Equal(t, 1, app.sessionManager.GetInt(r.Context(), "authenticatedUserID"), "the user stays logged in")
+				assert.Zero(t, sessionUserID(t, app, other), "the other session of the user is logged out")
+			case http.StatusUnprocessableEntity:
+				assert.Equal(t, tt.expectedFlash, html.UnescapeString(rr.Body.String()))
+				assert.Equal(t, 1, sessionUserID(t, app, other))
+			}
+			assert.Equal(t, 2, sessionUserID(t, app, stranger), "sessions of other users are kept")
+		})
+	}
+}
+
+// storeSession commits a session of a user to the store of app and returns
+// its token.
+func storeSession(t *testing.T, app *application, userId int) string {
+	t.Helper()
+
+	ctx, err := app.sessionManager.Load(context.Background(), "")
+	assert.NoError(t, err)
+	app.sessionManager.Put(ctx, "authenticatedUserID", userId)
+	token, _, err := app.sessionManager.Commit(ctx)
+	assert.NoError(t, err)
+
+	return token
+}
+
+// sessionUserID is the user of a stored session, zero once it is gone.
+func sessionUserID(t *testing.T, app *application, token string) int {
+	t.Helper()
+
+	ctx, err := app.sessionManager.Load(context.Background(), token)
+	assert.NoError(t, err)
+
+	return app.sessionManager.GetInt(ctx, "authenticatedUserID")
+}
+
+func TestLogoutOtherSessions(t *testing.T) {
+	app := newTestApplication()
+	other := storeSession(t, app, 1)
+
+	r := newImportRequest(t, app, http.MethodPost, "/account/password", "", &bytes.Buffer{})
+	current, _, err := app.sessionManager.Commit(r.Context())
+	assert.NoError(t, err)
+
+	assert.NoError(t, app.logoutOtherSessions(r, 1))
+
+	assert.Zero(t, sessionUserID(t, app, other))
+	assert.Equal(t, 1, sessionUserID(t, app, current), "the session of the request is kept")
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/rockstaedt/swimmate/internal/mailer"
+	"github.com/rockstaedt/swimmate/internal/models"
+)
+
+const (
+	forgotPasswordTemplate = "forgot-password.tmpl"
+	resetPasswordTemplate  = "reset-password.tmpl"
+)
+
+const resetPathPrefix = "/reset-password/"
+
+// resetSentText is the answer to every request of a reset link, so that the
+// form does not tell which email addresses have an account.
+const resetSentText = "If an account with this email address exists, we sent it a link to reset the password. " +
+	"The link works for one hour."
+
+// invalidResetText is shown for links that are unknown, used or expired.
+const invalidResetText = "The link to reset your password is unknown, used or expired. Ask for a new one."
+
+type forgotPasswordPageData struct {
+	Email string
+}
+
+type resetPasswordPageData struct {
+	Token    string
+	Username string
+}
+
+func (app *application) forgotPassword(w http.ResponseWriter, r *http.Request) {
+	if app.isAuthenticated(r) {
+		http.Redirect(w, r, "/account/profile", http.StatusSeeOther)
+		return
+	}
+
+	app.render(w, r, http.StatusOK, forgotPasswordTemplate, app.newTemplateData(r, forgotPasswordPageData{}))
+}
+
+// storeForgotPassword emails a reset link to the account of an email
+// address. The answer is the same whether the account exists or not, and
+// the account is looked up after answering, so that neither the text nor the
+// time of the answer reveals it.
+func (app *application) storeForgotPassword(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		app.logger.Error(err.Error())
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	data := forgotPasswordPageData{Email: strings.TrimSpace(r.PostForm.Get("email"))}
+	err = checkEmail(data.Email)
+	if err != nil {
+		app.sessionManager.Put(r.Context(), "flashText", fmt.Sprintf("No link can be sent: %s.", err))
+		app.sessionManager.Put(r.Context(), "flashType", "flash-error")
+		app.render(w, r, http.StatusUnprocessableEntity, forgotPasswordTemplate, app.newTemplateData(r, data))
+		return
+	}
+
+	go app.sendPasswordReset(data.Email, app.publicURL(r, resetPathPrefix))
+
+	app.sessionManager.Put(r.Context(), "flashText", resetSentText)
+	app.sessionManager.Put(r.Context(), "flashType", "flash-success")
+
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+// sendPasswordReset emails a reset link to the account of an email address,
+// if there is one. It runs after the request is answered, so errors are
+// only logged.
+func (app *application) sendPasswordReset(email, resetURL string) {
+	defer func() {
+		if err := recover(); err != nil {
+			app.logger.Error("panic while sending a password reset", "error", err)
+		}
+	}()
+
+	user, err := app.users.GetByEmail(email)
+	if err != nil {
+		if !errors.Is(err, models.ErrNoRecord) {
+			app.logger.Error("error looking up account for password reset", "error", err)
+		}
+		return
+	}
+
+	token, err := app.resets.Insert(user.ID)
+	if err != nil {
+		app.logger.Error("error creating password reset", "user", user.ID, "error", err)
+		return
+	}
+
+	err = app.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your SwimMate password",
+		Body: fmt.Sprintf("Hello %s,\n\n"+
+			"someone asked to reset the password of your SwimMate account %q. Open this link to choose a new one:\n\n"+
+			"%s\n\n"+
+			"The link works once within one hour. If you did not ask for it, ignore this email, your password stays "+
+			"the same.\n", user.FirstName, user.Username, resetURL+token),
+	})
+	if err != nil {
+		app.logger.Error("error sending password reset", "user", user.ID, "error", err)
+	}
+}
+
+// resetPassword shows the form for a new password of a reset link.
+func (app *application) resetPassword(w http.ResponseWriter, r *http.Request) {
+	token := httprouter.ParamsFromContext(r.Context()).ByName("token")
+
+	userId, err := app.resets.Lookup(token)
+	if err != nil {
+		app.invalidReset(w, r, err)
+		return
+	}
+
+	user, err := app.users.Get(userId)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := resetPasswordPageData{Token: token, Username: user.Username}
+	app.render(w, r, http.StatusOK, resetPasswordTemplate, app.newTemplateData(r, data))
+}
+
+// updateResetPassword sets the new password of a reset link and uses the
+// link up. All sessions of the user are logged out, the user logs in with
+// the new password.
+func (app *application) updateResetPassword(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		app.logger.Error(err.Error())
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	token := httprouter.ParamsFromContext(r.Context()).ByName("token")
+	userId, err := app.resets.Lookup(token)
+	if err != nil {
+		app.invalidReset(w, r, err)
+		return
+	}
+
+	user, err := app.users.Get(userId)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	password := r.PostForm.Get("password")
+	err = checkNewPassword(password, r.PostForm.Get("password_confirmation"), user)
+	if err != nil {
+		app.sessionManager.Put(r.Context(), "flashText", fmt.Sprintf("Your password cannot be changed: %s.", err))
+		app.sessionManager.Put(r.Context(), "flashType", "flash-error")
+		data := resetPasswordPageData{Token: token, Username: user.Username}
+		app.render(w, r, http.StatusUnprocessableEntity, resetPasswordTemplate, app.newTemplateData(r, data))
+		return
+	}
+
+	// Consuming the token again makes sure that two requests with the same
+	// link cannot both set a password
+	userId, err = app.resets.Consume(token)
+	if err != nil {
+		app.invalidReset(w, r, err)
+		return
+	}
+
+	err = app.users.SetPassword(userId, password)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.sessionManager.RenewToken(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.logoutOtherSessions(r, userId)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	if app.sessionManager.GetInt(r.Context(), "authenticatedUserID") == userId {
+		app.sessionManager.Remove(r.Context(), "authenticatedUserID")
+	}
+
+	app.sessionManager.Put(r.Context(), "flashText", "Password changed. Log in with your new password.")
+	app.sessionManager.Put(r.Context(), "flashType", "flash-success")
+
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+// invalidReset sends the user of a link that does not work back to the form
+// that asks for a new one.
+func (app *application) invalidReset(w http.ResponseWriter, r *http.Request, err error) {
+	if !errors.Is(err, models.ErrNoRecord) {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flashText", invalidResetText)
+	app.sessionManager.Put(r.Context(), "flashType", "flash-error")
+
+	http.Redirect(w, r, "/forgot-password", http.StatusSeeOther)
+}
+
+// publicURL is the URL of a path for links used outside of the app, such as
+// in emails or calendar apps. It is built from BASE_URL if set, since the
+// Host header of a request is chosen by whoever sends it.
+func (app *application) publicURL(r *http.Request, path string) string {
+	if app.baseURL != "" {
+		return app.baseURL + path
+	}
+	return absoluteURL(r, path)
+}
@@ -0,0 +1,357 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/rockstaedt/swimmate/internal/mailer"
+	"github.com/rockstaedt/swimmate/internal/models"
+	"github.com/rockstaedt/swimmate/internal/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+// newResetRequest is a request of a visitor to the reset link of a token.
+func newResetRequest(t *testing.T, app *application, method string, token string, form url.Values) *http.Request {
+	t.Helper()
+
+	r := newSignupRequest(t, app, method, resetPathPrefix+token, form)
+	ctx := context.WithValue(r.Context(), httprouter.ParamsKey, httprouter.Params{{Key: "token", Value: token}})
+
+	return r.WithContext(ctx)
+}
+
+func TestStoreForgotPassword(t *testing.T) {
+	tests := []struct {
+		name           string
+		email          string
+		accountExists  bool
+		expectedStatus int
+		expectedFlash  string
+		expectedMail   bool
+	}{
+		{
+			name:           "existing account",
+			email:          " Jane@Example.com ",
+			accountExists:  true,
+			expectedStatus: http.StatusSeeOther,
+			expectedFlash:  resetSentText,
+			expectedMail:   true,
+		},
+		{
+			name:           "unknown account",
+			email:          "john@example.com",
+			expectedStatus: http.StatusSeeOther,
+			expectedFlash:  resetSentText,
+		},
+		{
+			name:           "invalid email",
+			email:          "jane",
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedFlash:  `No link can be sent: "jane" is not an email address.`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication()
+			app.baseURL = "https://swimmate.example.com"
+			lookedUp := make(chan string, 1)
+			app.users = &testutils.MockUserModel{
+				GetByEmailFunc: func(email string) (*models.User, error) {
+					lookedUp <- email
+					if !tt.accountExists {
+						return nil, models.ErrNoRecord
+					}
+					return &models.User{ID: 1, Username: "jane", FirstName: "Jane", Email: "jane@example.com"}, nil
+				},
+			}
+			sent := make(chan mailer.Message, 1)
+			app.mailer = &testutils.MockMailer{
+				SendFunc: func(msg mailer.Message) error {
+					sent <- msg
+					return nil
+				},
+			}
+			app.templateCache[forgotPasswordTemplate] = createTestTemplate("base",
+				`{{define "base"}}{{with .Flash}}{{.Text}}{{end}}{{end}}`)
+
+			rr := httptest.NewRecorder()
+			r := newSignupRequest(t, app, http.MethodPost, "/forgot-password", url.Values{"email": {tt.email}})
+
+			app.storeForgotPassword(rr, r)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			if tt.expectedStatus == http.StatusSeeOther {
+				assert.Equal(t, "/login", rr.Header().Get("Location"))
+				assert.Equal(t, tt.expectedFlash, app.sessionManager.GetString(r.Context(), "flashText"),
+					"the answer does not tell whether the account exists")
+
+				select {
+				case email := <-lookedUp:
+					assert.Equal(t, strings.TrimSpace(tt.email), email)
+				case <-time.After(time.Second):
+					t.Fatal("the account was not looked up")
+				}
+			} else {
+				assert.Equal(t, tt.expectedFlash, html.UnescapeString(rr.Body.String()))
+			}
+
+			if tt.expectedMail {
+				select {
+				case msg := <-sent:
+					assert.Equal(t, "jane@example.com", msg.To)
+					assert.Contains(t, msg.Body, "https://swimmate.example.com/reset-password/TESTRESET")
+				case <-time.After(time.Second):
+					t.Fatal("no email was sent")
+				}
+			} else {
+				select {
+				case <-sent:
+					t.Fatal("an email was sent")
+				case <-time.After(50 * time.Millisecond):
+				}
+			}
+		})
+	}
+}
+
+func TestSendPasswordReset(t *testing.T) {
+	tests := []struct {
+		name         string
+		getErr       error
+		insertErr    error
+		sendErr      error
+		expectedMail bool
+	}{
+		{name: "email sent", expectedMail: true},
+		{name: "unknown account", getErr: models.ErrNoRecord},
+		{name: "database error", getErr: errors.New("database error")},
+		{name: "token error", insertErr: errors.New("database error")},
+		{name: "mail error", sendErr: errors.New("connection refused"), expectedMail: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication()
+			app.users = &testutils.MockUserModel{
+				GetByEmailFunc: func(email string) (*models.User, error) {
+					if tt.getErr != nil {
+						return nil, tt.getErr
+					}
+					return &models.User{ID: 3, Username: "jane", FirstName: "Jane", Email: "jane@example.com"}, nil
+				},
+			}
+			app.resets = &testutils.MockPasswordResetModel{
+				InsertFunc: func(userId int) (string, error) {
+					assert.Equal(t, 3, userId)
+					return "TOKEN", tt.insertErr
+				},
+			}
+			var sent []mailer.Message
+			app.mailer = &testutils.MockMailer{
+				SendFunc: func(msg mailer.Message) error {
+					sent = append(sent, msg)
+					return tt.sendErr
+				},
+			}
+
+			app.sendPasswordReset("jane@example.com", "http://localhost/reset-password/")
+
+			if tt.expectedMail {
+				if assert.Len(t, sent, 1) {
+					assert.Equal(t, "Reset your SwimMate password", sent[0].Subject)
+					assert.Contains(t, sent[0].Body, "Hello Jane,")
+					assert.Contains(t, sent[0].Body, "\nhttp://localhost/reset-password/TOKEN\n")
+				}
+			} else {
+				assert.Empty(t, sent)
+			}
+		})
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	tests := []struct {
+		name             string
+		lookupErr        error
+		expectedStatus   int
+		expectedLocation string
+	}{
+		{name: "open link", expectedStatus: http.StatusOK},
+		{
+			name:             "unknown, used or expired link",
+			lookupErr:        models.ErrNoRecord,
+			expectedStatus:   http.StatusSeeOther,
+			expectedLocation: "/forgot-password",
+		},
+		{name: "database error", lookupErr: errors.New("database error"), expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication()
+			app.resets = &testutils.MockPasswordResetModel{
+				LookupFunc: func(token string) (int, error) {
+					assert.Equal(t, "TOKEN", token)
+					return 3, tt.lookupErr
+				},
+			}
+			app.users = &testutils.MockUserModel{
+				GetFunc: func(id int) (*models.User, error) {
+					return &models.User{ID: id, Username: "jane"}, nil
+				},
+			}
+			app.templateCache[resetPasswordTemplate] = createTestTemplate("base",
+				`{{define "base"}}{{.Data.Username}} {{.Data.Token}}{{end}}`)
+
+			rr := httptest.NewRecorder()
+			r := newResetRequest(t, app, http.MethodGet, "TOKEN", nil)
+
+			app.resetPassword(rr, r)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			switch tt.expectedStatus {
+			case http.StatusOK:
+				assert.Equal(t, "jane TOKEN", rr.Body.String())
+			case http.StatusSeeOther:
+				assert.Equal(t, tt.expectedLocation, rr.Header().Get("Location"))
+				assert.Equal(t, invalidResetText, app.sessionManager.GetString(r.Context(), "flashText"))
+			}
+		})
+	}
+}
+
+func TestUpdateResetPassword(t *testing.T) {
+	form := func(password, confirmation string) url.Values {
+		return url.Values{"password": {password}, "password_confirmation": {confirmation}}
+	}
+
+	tests := []struct {
+		name             string
+		form             url.Values
+		lookupErr        error
+		consumeErr       error
+		setErr           error
+		expectedStatus   int
+		expectedLocation string
+		expectedSet      bool
+		expectedFlash    string
+	}{
+		{
+			name:             "password changed",
+			form:             form("lanes and laps", "lanes and laps"),
+			expectedStatus:   http.StatusSeeOther,
+			expectedLocation: "/login",
+			expectedSet:      true,
+			expectedFlash:    "Password changed. Log in with your new password.",
+		},
+		{
+			name:           "weak password",
+			form:           form("jane.doe.2024", "jane.doe.2024"),
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedFlash:  "Your password cannot be changed: the password contains your username or email address.",
+		},
+		{
+			name:           "passwords differ",
+			form:           form("lanes and laps", "lanes and lapz"),
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedFlash:  "Your password cannot be changed: the passwords do not match.",
+		},
+		{
+			name:             "expired link",
+			form:             form("lanes and laps", "lanes and laps"),
+			lookupErr:        models.ErrNoRecord,
+			expectedStatus:   http.StatusSeeOther,
+			expectedLocation: "/forgot-password",
+			expectedFlash:    invalidResetText,
+		},
+		{
+			name:             "link used in the meantime",
+			form:             form("lanes and laps", "lanes and laps"),
+			consumeErr:       models.ErrNoRecord,
+			expectedStatus:   http.StatusSeeOther,
+			expectedLocation: "/forgot-password",
+			expectedFlash:    invalidResetText,
+		},
+		{
+			name:           "database error",
+			form:           form("lanes and laps", "lanes and laps"),
+			setErr:         errors.New("database error"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedSet:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication()
+			app.resets = &testutils.MockPasswordResetModel{
+				LookupFunc: func(token string) (int, error) {
+					return 1, tt.lookupErr
+				},
+				ConsumeFunc: func(token string) (int, error) {
+					assert.Equal(t, "TOKEN", token)
+					return 1, tt.consumeErr
+				},
+			}
+			set := false
+			app.users = &testutils.MockUserModel{
+				GetFunc: func(id int) (*models.User, error) {
+					return &models.User{ID: id, Username: "jane", Email: "jane.doe@example.com"}, nil
+				},
+				SetPasswordFunc: func(id int, password string) error {
+					set = true
+					assert.Equal(t, 1, id)
+					assert.Equal(t, "lanes and laps", password)
+					return tt.setErr
+				},
+			}
+			app.templateCache[resetPasswordTemplate] = createTestTemplate("base",
+				`{{define "base"}}{{with .Flash}}{{.Text}}{{end}}{{end}}`)
+
+			// Whoever knew the old password and is logged in somewhere
+			other := storeSession(t, app, 1)
+
+			rr := httptest.NewRecorder()
+			r := newResetRequest(t, app, http.MethodPost, "TOKEN", tt.form)
+
+			app.updateResetPassword(rr, r)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.Equal(t, tt.expectedSet, set)
+			switch tt.expectedStatus {
+			case http.StatusSeeOther:
+				assert.Equal(t, tt.expectedLocation, rr.Header().Get("Location"))
+				assert.Equal(t, tt.expectedFlash, app.sessionManager.GetString(r.Context(), "flashText"))
+				if tt.expectedSet {
+					assert.Zero(t, sessionUserID(t, app, other), "the sessions of the user are logged out")
+				}
+			case http.StatusUnprocessableEntity:
+				assert.Equal(t, tt.expectedFlash, html.UnescapeString(rr.Body.String()))
+			}
+			if !tt.expectedSet {
+				assert.Equal(t, 1, sessionUserID(t, app, other))
+			}
+		})
+	}
+}
+
+func TestPublicURL(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/forgot-password", nil)
+	r.Host = "evil.example.com"
+
+	app := newTestApplication()
+	assert.Equal(t, "http://evil.example.com/reset-password/", app.publicURL(r, resetPathPrefix))
+
+	app.baseURL = "https://swimmate.example.com"
+	assert.Equal(t, "https://swimmate.example.com/reset-password/", app.publicURL(r, resetPathPrefix),
+		"BASE_URL wins over the Host header")
+}
@@ -2,9 +2,11 @@ package main
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/justinas/alice"
+	"github.com/rockstaedt/swimmate/internal/models"
 	"github.com/rockstaedt/swimmate/ui"
 )
 
@@ -12,8 +14,19 @@ func (app *application) routes() http.Handler {
 	router := httprouter.New()
 
 	router.NotFound = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, apiPathPrefix) {
+			app.apiError(w, http.StatusNotFound, "")
+			return
+		}
 		app.notFound(w)
 	})
+	router.MethodNotAllowed = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, apiPathPrefix) {
+			app.apiError(w, http.StatusMethodNotAllowed, "")
+			return
+		}
+		app.clientError(w, http.StatusMethodNotAllowed)
+	})
 
 	fileServer := http.FileServer(http.FS(ui.Files))
 	router.Handler(http.MethodGet, "/static/*filepath", fileServer)
@@ -22,7 +35,15 @@ func (app *application) routes() http.Handler {
 
 	router.Handler(http.MethodGet, "/login", dynamic.ThenFunc(app.login))
 	router.Handler(http.MethodPost, "/authenticate", dynamic.ThenFunc(app.authenticate))
+	router.Handler(http.MethodGet, "/login/2fa", dynamic.ThenFunc(app.loginTwoFactor))
+	router.Handler(http.MethodPost, "/login/2fa", dynamic.ThenFunc(app.verifyLoginTwoFactor))
 	router.Handler(http.MethodPost, "/logout", dynamic.ThenFunc(app.logout))
+	router.Handler(http.MethodGet, "/signup", dynamic.ThenFunc(app.signup))
+	router.Handler(http.MethodPost, "/signup", dynamic.ThenFunc(app.storeSignup))
+	router.Handler(http.MethodGet, "/forgot-password", dynamic.ThenFunc(app.forgotPassword))
+	router.Handler(http.MethodPost, "/forgot-password", dynamic.ThenFunc(app.storeForgotPassword))
+	router.Handler(http.MethodGet, resetPathPrefix+":token", dynamic.ThenFunc(app.resetPassword))
+	router.Handler(http.MethodPost, resetPathPrefix+":token", dynamic.ThenFunc(app.updateResetPassword))
 	router.Handler(http.MethodGet, "/about", dynamic.ThenFunc(app.about))
 
 	protected := dynamic.Append(app.requireAuthentication)
@@ -30,6 +51,15 @@ func (app *application) routes() http.Handler {
 	router.Handler(http.MethodGet, "/", protected.ThenFunc(app.home))
 	router.Handler(http.MethodGet, "/swims", protected.ThenFunc(app.swimsList))
 	router.Handler(http.MethodGet, "/swims/more", protected.ThenFunc(app.swimsMore))
+	router.Handler(http.MethodGet, "/swims/export.csv", protected.ThenFunc(app.exportSwims))
+	router.Handler(http.MethodGet, "/swims/import", protected.ThenFunc(app.importSwims))
+	router.Handler(http.MethodPost, "/swims/import/preview", protected.ThenFunc(app.previewImport))
+	router.Handler(http.MethodPost, "/swims/import", protected.ThenFunc(app.storeImport))
+	router.Handler(http.MethodPost, "/swims/import/activities", protected.ThenFunc(app.importActivities))
+	router.Handler(http.MethodPost, "/swims/import/health", protected.ThenFunc(app.importHealth))
+	router.Handler(http.MethodPost, "/swims/import/archive", protected.ThenFunc(app.uploadArchive))
+	router.Handler(http.MethodGet, "/swims/import/archive/:id", protected.ThenFunc(app.showArchive))
+	router.Handler(http.MethodPost, "/swims/import/archive/:id", protected.ThenFunc(app.continueArchive))
 	router.Handler(http.MethodGet, "/yearly-figures", protected.ThenFunc(app.yearlyFigures))
 	router.Handler(http.MethodGet, "/swim", protected.ThenFunc(app.createSwim))
 	router.Handler(http.MethodPost, "/swim", protected.ThenFunc(app.storeSwim))
@@ -37,6 +67,46 @@ func (app *application) routes() http.Handler {
 	router.Handler(http.MethodPost, "/swims/edit/:id", protected.ThenFunc(app.updateSwim))
 	router.Handler(http.MethodPut, "/swims/edit/:id", protected.ThenFunc(app.updateSwim))
 	router.Handler(http.MethodDelete, "/swims/:id", protected.ThenFunc(app.deleteSwim))
+	router.Handler(http.MethodGet, "/locations", protected.ThenFunc(app.locationsList))
+	router.Handler(http.MethodPost, "/locations", protected.ThenFunc(app.storeLocation))
+	router.Handler(http.MethodGet, "/account", protected.ThenFunc(app.account))
+	router.Handler(http.MethodPost, "/account/preferences", protected.ThenFunc(app.updatePreferences))
+	router.Handler(http.MethodGet, "/account/profile", protected.ThenFunc(app.profile))
+	router.Handler(http.MethodPost, "/account/profile", protected.ThenFunc(app.updateProfile))
+	router.Handler(http.MethodPost, "/account/password", protected.ThenFunc(app.updatePassword))
+	router.Handler(http.MethodGet, "/account/2fa", protected.ThenFunc(app.twoFactorSettings))
+	router.Handler(http.MethodPost, "/account/2fa", protected.ThenFunc(app.startTwoFactor))
+	router.Handler(http.MethodPost, "/account/2fa/enable", protected.ThenFunc(app.enableTwoFactor))
+	router.Handler(http.MethodPost, "/account/2fa/recovery-codes", protected.ThenFunc(app.regenerateRecoveryCodes))
+	router.Handler(http.MethodPost, "/account/2fa/disable", protected.ThenFunc(app.disableTwoFactor))
+	router.Handler(http.MethodGet, "/account/backup.json", protected.ThenFunc(app.downloadBackup))
+	router.Handler(http.MethodPost, "/account/restore", protected.ThenFunc(app.restoreBackup))
+	router.Handler(http.MethodPost, "/account/calendar", protected.ThenFunc(app.resetCalendar))
+	router.Handler(http.MethodPost, "/account/calendar/delete", protected.ThenFunc(app.deleteCalendar))
+	router.Handler(http.MethodPost, "/account/invites", protected.ThenFunc(app.createInvite))
+	router.Handler(http.MethodGet, "/account/tokens", protected.ThenFunc(app.accessTokensList))
+	router.Handler(http.MethodPost, "/account/tokens", protected.ThenFunc(app.storeAccessToken))
+	router.Handler(http.MethodPost, "/account/tokens/:id/delete", protected.ThenFunc(app.deleteAccessToken))
+	router.Handler(http.MethodGet, "/account/webhooks", protected.ThenFunc(app.webhooksList))
+	router.Handler(http.MethodPost, "/account/webhooks", protected.ThenFunc(app.storeWebhook))
+	router.Handler(http.MethodPost, "/account/webhooks/:id/delete", protected.ThenFunc(app.deleteWebhook))
+	router.Handler(http.MethodPost, "/account/webhook-deliveries/:id/redeliver", protected.ThenFunc(app.redeliverWebhook))
+
+	router.HandlerFunc(http.MethodGet, "/api/openapi.json", app.openAPI)
+
+	api := dynamic.Append(app.requireAPIAuthentication)
+	apiRead := api.Append(app.requireScope(models.ScopeSwimsRead))
+	apiWrite := api.Append(app.requireScope(models.ScopeSwimsWrite))
+
+	router.Handler(http.MethodGet, "/api/v1/swims", apiRead.ThenFunc(app.apiSwimsList))
+	router.Handler(http.MethodPost, "/api/v1/swims", apiWrite.ThenFunc(app.apiStoreSwim))
+	router.Handler(http.MethodGet, "/api/v1/swims/:id", apiRead.ThenFunc(app.apiSwimView))
+	router.Handler(http.MethodPut, "/api/v1/swims/:id", apiWrite.ThenFunc(app.apiUpdateSwim))
+	router.Handler(http.MethodDelete, "/api/v1/swims/:id", apiWrite.ThenFunc(app.apiDeleteSwim))
+	router.Handler(http.MethodGet, "/api/v1/summary", apiRead.ThenFunc(app.apiSummary))
+
+	// Calendar apps cannot log in, the token in the path authorizes the feed
+	router.HandlerFunc(http.MethodGet, calendarPathPrefix+":token/swims.ics", app.calendarFeed)
 
 	standard := alice.New(app.recoverPanic, app.logRequest, secureHeaders)
 
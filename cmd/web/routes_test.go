@@ -22,6 +22,16 @@ func TestRoutes(t *testing.T) {
 	app.templateCache["yearly-figures.tmpl"] = createTestTemplate("base", `{{define "base"}}Yearly{{end}}`)
 	app.templateCache["swim-create.tmpl"] = createTestTemplate("base", `{{define "base"}}Create{{end}}`)
 	app.templateCache["swim-edit.tmpl"] = createTestTemplate("base", `{{define "base"}}Edit{{end}}`)
+	app.templateCache["account.tmpl"] = createTestTemplate("base", `{{define "base"}}Account{{end}}`)
+	app.templateCache["locations.tmpl"] = createTestTemplate("base", `{{define "base"}}Locations{{end}}`)
+	app.templateCache["swim-import.tmpl"] = createTestTemplate("base", `{{define "base"}}Import{{end}}`)
+	app.templateCache["tokens.tmpl"] = createTestTemplate("base", `{{define "base"}}Tokens{{end}}`)
+	app.templateCache["webhooks.tmpl"] = createTestTemplate("base", `{{define "base"}}Webhooks{{end}}`)
+	app.templateCache["signup.tmpl"] = createTestTemplate("base", `{{define "base"}}Sign Up{{end}}`)
+	app.templateCache["profile.tmpl"] = createTestTemplate("base", `{{define "base"}}Profile{{end}}`)
+	app.templateCache["forgot-password.tmpl"] = createTestTemplate("base", `{{define "base"}}Forgot Password{{end}}`)
+	app.templateCache["two-factor.tmpl"] = createTestTemplate("base", `{{define "base"}}Two-Factor{{end}}`)
+	app.templateCache["login-two-factor.tmpl"] = createTestTemplate("base", `{{define "base"}}Code{{end}}`)
 
 	handler := app.routes()
 
@@ -129,6 +139,302 @@ func TestRoutes(t *testing.T) {
 			expectedStatus: http.StatusOK,
 			description:    "Swims more should be accessible when authenticated",
 		},
+		{
+			name:           "swims export requires authentication",
+			method:         http.MethodGet,
+			path:           "/swims/export.csv",
+			authenticated:  false,
+			expectedStatus: http.StatusSeeOther,
+			description:    "Swims export should redirect to login when not authenticated",
+		},
+		{
+			name:           "swims export with authentication",
+			method:         http.MethodGet,
+			path:           "/swims/export.csv",
+			authenticated:  true,
+			expectedStatus: http.StatusOK,
+			description:    "Swims export should be accessible when authenticated",
+		},
+		{
+			name:           "swims import requires authentication",
+			method:         http.MethodGet,
+			path:           "/swims/import",
+			authenticated:  false,
+			expectedStatus: http.StatusSeeOther,
+			description:    "Swims import should redirect to login when not authenticated",
+		},
+		{
+			name:           "swims import with authentication",
+			method:         http.MethodGet,
+			path:           "/swims/import",
+			authenticated:  true,
+			expectedStatus: http.StatusOK,
+			description:    "Swims import should be accessible when authenticated",
+		},
+		{
+			name:           "activity import requires authentication",
+			method:         http.MethodPost,
+			path:           "/swims/import/activities",
+			authenticated:  false,
+			expectedStatus: http.StatusSeeOther,
+			description:    "Activity import should redirect to login when not authenticated",
+		},
+		{
+			name:           "health import requires authentication",
+			method:         http.MethodPost,
+			path:           "/swims/import/health",
+			authenticated:  false,
+			expectedStatus: http.StatusSeeOther,
+			description:    "Health import should redirect to login when not authenticated",
+		},
+		{
+			name:           "archive upload requires authentication",
+			method:         http.MethodPost,
+			path:           "/swims/import/archive",
+			authenticated:  false,
+			expectedStatus: http.StatusSeeOther,
+			description:    "Archive upload should redirect to login when not authenticated",
+		},
+		{
+			name:           "archive progress requires authentication",
+			method:         http.MethodGet,
+			path:           "/swims/import/archive/1",
+			authenticated:  false,
+			expectedStatus: http.StatusSeeOther,
+			description:    "Archive progress should redirect to login when not authenticated",
+		},
+		{
+			name:           "archive batch requires authentication",
+			method:         http.MethodPost,
+			path:           "/swims/import/archive/1",
+			authenticated:  false,
+			expectedStatus: http.StatusSeeOther,
+			description:    "Archive batch should redirect to login when not authenticated",
+		},
+		{
+			name:           "account requires authentication",
+			method:         http.MethodGet,
+			path:           "/account",
+			authenticated:  false,
+			expectedStatus: http.StatusSeeOther,
+			description:    "Account page should redirect to login when not authenticated",
+		},
+		{
+			name:           "account with authentication",
+			method:         http.MethodGet,
+			path:           "/account",
+			authenticated:  true,
+			expectedStatus: http.StatusOK,
+			description:    "Account page should be accessible when authenticated",
+		},
+		{
+			name:           "backup download requires authentication",
+			method:         http.MethodGet,
+			path:           "/account/backup.json",
+			authenticated:  false,
+			expectedStatus: http.StatusSeeOther,
+			description:    "Backup download should redirect to login when not authenticated",
+		},
+		{
+			name:           "backup download with authentication",
+			method:         http.MethodGet,
+			path:           "/account/backup.json",
+			authenticated:  true,
+			expectedStatus: http.StatusOK,
+			description:    "Backup download should be accessible when authenticated",
+		},
+		{
+			name:           "restore requires authentication",
+			method:         http.MethodPost,
+			path:           "/account/restore",
+			authenticated:  false,
+			expectedStatus: http.StatusSeeOther,
+			description:    "Restore should redirect to login when not authenticated",
+		},
+		{
+			name:           "calendar feed URL requires authentication",
+			method:         http.MethodPost,
+			path:           "/account/calendar",
+			authenticated:  false,
+			expectedStatus: http.StatusSeeOther,
+			description:    "Creating a calendar feed URL should redirect to login when not authenticated",
+		},
+		{
+			name:           "calendar feed revocation requires authentication",
+			method:         http.MethodPost,
+			path:           "/account/calendar/delete",
+			authenticated:  false,
+			expectedStatus: http.StatusSeeOther,
+			description:    "Revoking the calendar feed should redirect to login when not authenticated",
+		},
+		{
+			name:           "calendar feed without session",
+			method:         http.MethodGet,
+			path:           "/calendar/UNKNOWN/swims.ics",
+			authenticated:  false,
+			expectedStatus: http.StatusNotFound,
+			description:    "Calendar feeds are authorized by their token instead of a session",
+		},
+		{
+			name:           "access tokens require authentication",
+			method:         http.MethodGet,
+			path:           "/account/tokens",
+			authenticated:  false,
+			expectedStatus: http.StatusSeeOther,
+			description:    "The access tokens page should redirect to login when not authenticated",
+		},
+		{
+			name:           "access tokens with authentication",
+			method:         http.MethodGet,
+			path:           "/account/tokens",
+			authenticated:  true,
+			expectedStatus: http.StatusOK,
+			description:    "The access tokens page should be accessible when authenticated",
+		},
+		{
+			name:           "access token revocation requires authentication",
+			method:         http.MethodPost,
+			path:           "/account/tokens/1/delete",
+			authenticated:  false,
+			expectedStatus: http.StatusSeeOther,
+			description:    "Revoking an access token should redirect to login when not authenticated",
+		},
+		{
+			name:           "webhooks require authentication",
+			method:         http.MethodGet,
+			path:           "/account/webhooks",
+			authenticated:  false,
+			expectedStatus: http.StatusSeeOther,
+			description:    "The webhooks page should redirect to login when not authenticated",
+		},
+		{
+			name:           "webhooks with authentication",
+			method:         http.MethodGet,
+			path:           "/account/webhooks",
+			authenticated:  true,
+			expectedStatus: http.StatusOK,
+			description:    "The webhooks page should be accessible when authenticated",
+		},
+		{
+			name:           "redelivery requires authentication",
+			method:         http.MethodPost,
+			path:           "/account/webhook-deliveries/1/redeliver",
+			authenticated:  false,
+			expectedStatus: http.StatusSeeOther,
+			description:    "Redelivering a webhook delivery should redirect to login when not authenticated",
+		},
+		{
+			name:           "profile requires authentication",
+			method:         http.MethodGet,
+			path:           "/account/profile",
+			authenticated:  false,
+			expectedStatus: http.StatusSeeOther,
+			description:    "The profile page should redirect to login when not authenticated",
+		},
+		{
+			name:           "profile with authentication",
+			method:         http.MethodGet,
+			path:           "/account/profile",
+			authenticated:  true,
+			expectedStatus: http.StatusOK,
+			description:    "The profile page should be accessible when authenticated",
+		},
+		{
+			name:           "password change requires authentication",
+			method:         http.MethodPost,
+			path:           "/account/password",
+			authenticated:  false,
+			expectedStatus: http.StatusSeeOther,
+			description:    "Changing the password should redirect to login when not authenticated",
+		},
+		{
+			name:           "signup while registration is closed",
+			method:         http.MethodGet,
+			path:           "/signup",
+			authenticated:  false,
+			expectedStatus: http.StatusNotFound,
+			description:    "The sign up page should not exist while registration is closed",
+		},
+		{
+			name:           "public forgot password page",
+			method:         http.MethodGet,
+			path:           "/forgot-password",
+			authenticated:  false,
+			expectedStatus: http.StatusOK,
+			description:    "Asking for a reset link should be possible without authentication",
+		},
+		{
+			name:           "two-factor login without a password",
+			method:         http.MethodGet,
+			path:           "/login/2fa",
+			authenticated:  false,
+			expectedStatus: http.StatusSeeOther,
+			description:    "The code page should send visitors that did not enter a password to the login",
+		},
+		{
+			name:           "two-factor settings require authentication",
+			method:         http.MethodGet,
+			path:           "/account/2fa",
+			authenticated:  false,
+			expectedStatus: http.StatusSeeOther,
+			description:    "Two-factor settings should redirect to login when not authenticated",
+		},
+		{
+			name:           "invite creation requires authentication",
+			method:         http.MethodPost,
+			path:           "/account/invites",
+			authenticated:  false,
+			expectedStatus: http.StatusSeeOther,
+			description:    "Creating an invite should redirect to login when not authenticated",
+		},
+		{
+			name:           "API requires authentication",
+			method:         http.MethodGet,
+			path:           "/api/v1/swims",
+			authenticated:  false,
+			expectedStatus: http.StatusUnauthorized,
+			description:    "The API should answer Unauthorized instead of redirecting to login",
+		},
+		{
+			name:           "API with authentication",
+			method:         http.MethodGet,
+			path:           "/api/v1/swims",
+			authenticated:  true,
+			expectedStatus: http.StatusOK,
+			description:    "The swims of the API should be accessible when authenticated",
+		},
+		{
+			name:           "API summary with authentication",
+			method:         http.MethodGet,
+			path:           "/api/v1/summary",
+			authenticated:  true,
+			expectedStatus: http.StatusOK,
+			description:    "The summary of the API should be accessible when authenticated",
+		},
+		{
+			name:           "OpenAPI document without authentication",
+			method:         http.MethodGet,
+			path:           "/api/openapi.json",
+			authenticated:  false,
+			expectedStatus: http.StatusOK,
+			description:    "The OpenAPI document should be public",
+		},
+		{
+			name:           "locations require authentication",
+			method:         http.MethodGet,
+			path:           "/locations",
+			authenticated:  false,
+			expectedStatus: http.StatusSeeOther,
+			description:    "Locations page should redirect to login when not authenticated",
+		},
+		{
+			name:           "locations with authentication",
+			method:         http.MethodGet,
+			path:           "/locations",
+			authenticated:  true,
+			expectedStatus: http.StatusOK,
+			description:    "Locations page should be accessible when authenticated",
+		},
 		{
 			name:           "not found route",
 			method:         http.MethodGet,
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/rockstaedt/swimmate/internal/models"
+)
+
+const signupTemplate = "signup.tmpl"
+
+const minUsernameLength = 3
+
+// registrationMode decides who may create an account on the sign up page.
+type registrationMode string
+
+const (
+	registrationOpen registrationMode = "open"
+	// registrationInvite lets only people with an invite of a user sign up.
+	registrationInvite registrationMode = "invite"
+	// registrationClosed leaves creating accounts to the admins.
+	registrationClosed registrationMode = "closed"
+)
+
+// parseRegistrationMode reads the REGISTRATION setting. Registration is
+// closed unless it is set.
+func parseRegistrationMode(value string) (registrationMode, error) {
+	switch mode := registrationMode(value); mode {
+	case "":
+		return registrationClosed, nil
+	case registrationOpen, registrationInvite, registrationClosed:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown registration mode %q, use open, invite or closed", value)
+	}
+}
+
+// signupAllowed reports whether the sign up page is available at all.
+func (m registrationMode) signupAllowed() bool {
+	return m == registrationOpen || m == registrationInvite
+}
+
+type signupPageData struct {
+	Username  string
+	FirstName string
+	LastName  string
+	Email     string
+	// Invite is the invite code, which is required while registration is
+	// invite-only.
+	Invite     string
+	InviteOnly bool
+}
+
+// loginPageData tells the login page whether to link to the sign up page.
+type loginPageData struct {
+	SignupOpen bool
+}
+
+func (app *application) signup(w http.ResponseWriter, r *http.Request) {
+	if !app.registration.signupAllowed() {
+		app.notFound(w)
+		return
+	}
+
+	if app.isAuthenticated(r) {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	data := signupPageData{
+		Invite:     r.URL.Query().Get("invite"),
+		InviteOnly: app.registration == registrationInvite,
+	}
+
+	app.render(w, r, http.StatusOK, signupTemplate, app.newTemplateData(r, data))
+}
+
+// storeSignup creates an account and logs it in. While registration is
+// invite-only, the invite is used up by the new account.
+func (app *application) storeSignup(w http.ResponseWriter, r *http.Request) {
+	if !app.registration.signupAllowed() {
+		app.notFound(w)
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		app.logger.Error(err.Error())
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	data := signupPageData{
+		Username:   strings.TrimSpace(r.PostForm.Get("username")),
+		FirstName:  strings.TrimSpace(r.PostForm.Get("first_name")),
+		LastName:   strings.TrimSpace(r.PostForm.Get("last_name")),
+		Email:      strings.TrimSpace(r.PostForm.Get("email")),
+		Invite:     strings.TrimSpace(r.PostForm.Get("invite")),
+		InviteOnly: app.registration == registrationInvite,
+	}
+	failed := func(text string) {
+		app.sessionManager.Put(r.Context(), "flashText", text)
+		app.sessionManager.Put(r.Context(), "flashType", "flash-error")
+		app.render(w, r, http.StatusUnprocessableEntity, signupTemplate, app.newTemplateData(r, data))
+	}
+
+	user, password, err := userFromSignupForm(r.PostForm)
+	if err != nil {
+		failed(fmt.Sprintf("Your account cannot be created: %s.", err))
+		return
+	}
+
+	inviteId := 0
+	if data.InviteOnly {
+		inviteId, err = app.invites.Redeem(data.Invite)
+		if err != nil {
+			if errors.Is(err, models.ErrNoRecord) {
+				failed("The invite is unknown, used or expired. Ask for a new one.")
+				return
+			}
+
+			app.serverError(w, r, err)
+			return
+		}
+	}
+
+	err = app.users.Insert(user, password)
+	if err != nil {
+		if inviteId != 0 {
+			if errRelease := app.invites.Release(inviteId); errRelease != nil {
+				app.logger.Error("error releasing invite", "invite", inviteId, "error", errRelease)
+			}
+		}
+
+		switch {
+		case errors.Is(err, models.ErrDuplicateUsername):
+			failed(fmt.Sprintf("The username %q is taken.", user.Username))
+		case errors.Is(err, models.ErrDuplicateEmail):
+			failed("There already is an account with this email address.")
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	err = app.sessionManager.RenewToken(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "authenticatedUserID", user.ID)
+	app.sessionManager.Put(r.Context(), "distanceUnit", string(user.DistanceUnit))
+	app.sessionManager.Put(r.Context(), "flashText", fmt.Sprintf("Welcome to SwimMate, %s!", user.FirstName))
+	app.sessionManager.Put(r.Context(), "flashType", "flash-success")
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// createInvite creates an invite of the user. Its link is shown once on the
+// account page.
+func (app *application) createInvite(w http.ResponseWriter, r *http.Request) {
+	if app.registration != registrationInvite {
+		app.notFound(w)
+		return
+	}
+
+	code, err := app.invites.Insert(app.sessionManager.GetInt(r.Context(), "authenticatedUserID"))
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "inviteURL", app.publicURL(r, "/signup?"+url.Values{"invite": {code}}.Encode()))
+	app.sessionManager.Put(r.Context(), "flashText", "Your invite is ready. Copy its link now, it is shown only once.")
+	app.sessionManager.Put(r.Context(), "flashType", "flash-success")
+
+	http.Redirect(w, r, "/account", http.StatusSeeOther)
+}
+
+// userFromSignupForm reads and checks the account and password of the sign
+// up form.
+func userFromSignupForm(form url.Values) (*models.User, string, error) {
+	user := &models.User{
+		Username:  strings.TrimSpace(form.Get("username")),
+		FirstName: strings.TrimSpace(form.Get("first_name")),
+		LastName:  strings.TrimSpace(form.Get("last_name")),
+		Email:     strings.TrimSpace(form.Get("email")),
+	}
+
+	err := checkUsername(user.Username)
+	if err != nil {
+		return nil, "", err
+	}
+
+	err = checkProfile(user)
+	if err != nil {
+		return nil, "", err
+	}
+
+	password := form.Get("password")
+	err = checkNewPassword(password, form.Get("password_confirmation"), user)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return user, password, nil
+}
+
+// checkUsername allows the characters of the usernames of existing accounts:
+// letters, digits and @ . + - _.
+func checkUsername(username string) error {
+	length := utf8.RuneCountInString(username)
+	if length < minUsernameLength || length > models.MaxUsernameLength {
+		return fmt.Errorf("the username needs %d to %d characters", minUsernameLength, models.MaxUsernameLength)
+	}
+
+	for _, c := range username {
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) && !strings.ContainsRune("@.+-_", c) {
+			return fmt.Errorf("the username may only have letters, digits and @ . + - _, not %q", c)
+		}
+	}
+
+	return nil
+}
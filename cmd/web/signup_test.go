@@ -0,0 +1,328 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/rockstaedt/swimmate/internal/models"
+	"github.com/rockstaedt/swimmate/internal/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+// newSignupRequest is a request of a visitor, who has a session but is not
+// logged in.
+func newSignupRequest(t *testing.T, app *application, method string, target string, form url.Values) *http.Request {
+	t.Helper()
+
+	r := httptest.NewRequest(method, target, bytes.NewBufferString(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	ctx, _ := app.sessionManager.Load(r.Context(), "")
+
+	return r.WithContext(ctx)
+}
+
+func TestParseRegistrationMode(t *testing.T) {
+	tests := []struct {
+		value         string
+		expectedMode  registrationMode
+		expectedError bool
+	}{
+		{value: "", expectedMode: registrationClosed},
+		{value: "open", expectedMode: registrationOpen},
+		{value: "invite", expectedMode: registrationInvite},
+		{value: "closed", expectedMode: registrationClosed},
+		{value: "Open", expectedError: true},
+		{value: "public", expectedError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			mode, err := parseRegistrationMode(tt.value)
+
+			assert.Equal(t, tt.expectedError, err != nil)
+			assert.Equal(t, tt.expectedMode, mode)
+		})
+	}
+}
+
+func TestSignup(t *testing.T) {
+	tests := []struct {
+		name             string
+		registration     registrationMode
+		target           string
+		authenticated    bool
+		expectedStatus   int
+		expectedBody     string
+		expectedLocation string
+	}{
+		{name: "open", registration: registrationOpen, target: "/signup", expectedStatus: http.StatusOK, expectedBody: "invite only false, code "},
+		{name: "invite only", registration: registrationInvite, target: "/signup?invite=CODE", expectedStatus: http.StatusOK, expectedBody: "invite only true, code CODE"},
+		{name: "closed", registration: registrationClosed, target: "/signup", expectedStatus: http.StatusNotFound},
+		{name: "logged in", registration: registrationOpen, target: "/signup", authenticated: true, expectedStatus: http.StatusSeeOther, expectedLocation: "/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication()
+			app.registration = tt.registration
+			app.templateCache[signupTemplate] = createTestTemplate("base",
+				`{{define "base"}}invite only {{.Data.InviteOnly}}, code {{.Data.Invite}}{{end}}`)
+
+			rr := httptest.NewRecorder()
+			r := newSignupRequest(t, app, http.MethodGet, tt.target, nil)
+			if tt.authenticated {
+				app.sessionManager.Put(r.Context(), "authenticatedUserID", 1)
+			}
+
+			app.signup(rr, r)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.Equal(t, tt.expectedLocation, rr.Header().Get("Location"))
+			if tt.expectedStatus == http.StatusOK {
+				assert.Equal(t, tt.expectedBody, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestStoreSignup(t *testing.T) {
+	form := func(changes map[string]string) url.Values {
+		values := url.Values{
+			"username":              {" jane "},
+			"first_name":            {"Jane"},
+			"last_name":             {"Doe"},
+			"email":                 {"jane@example.com"},
+			"password":              {"lanes and laps"},
+			"password_confirmation": {"lanes and laps"},
+			"invite":                {"CODE"},
+		}
+		for key, value := range changes {
+			values.Set(key, value)
+		}
+		return values
+	}
+
+	tests := []struct {
+		name             string
+		registration     registrationMode
+		form             url.Values
+		redeemErr        error
+		insertErr        error
+		expectedStatus   int
+		expectedUser     *models.User
+		expectedRedeemed bool
+		expectedReleased bool
+		expectedFlash    string
+		expectedLoggedIn bool
+	}{
+		{
+			name:             "account created",
+			registration:     registrationOpen,
+			form:             form(nil),
+			expectedStatus:   http.StatusSeeOther,
+			expectedUser:     &models.User{Username: "jane", FirstName: "Jane", LastName: "Doe", Email: "jane@example.com"},
+			expectedFlash:    "Welcome to SwimMate, Jane!",
+			expectedLoggedIn: true,
+		},
+		{
+			name:             "account created with an invite",
+			registration:     registrationInvite,
+			form:             form(nil),
+			expectedStatus:   http.StatusSeeOther,
+			expectedUser:     &models.User{Username: "jane", FirstName: "Jane", LastName: "Doe", Email: "jane@example.com"},
+			expectedRedeemed: true,
+			expectedFlash:    "Welcome to SwimMate, Jane!",
+			expectedLoggedIn: true,
+		},
+		{
+			name:           "registration closed",
+			registration:   registrationClosed,
+			form:           form(nil),
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:             "unknown invite",
+			registration:     registrationInvite,
+			form:             form(nil),
+			redeemErr:        models.ErrNoRecord,
+			expectedStatus:   http.StatusUnprocessableEntity,
+			expectedRedeemed: true,
+			expectedFlash:    "The invite is unknown, used or expired. Ask for a new one.",
+		},
+		{
+			name:           "invalid username",
+			registration:   registrationOpen,
+			form:           form(map[string]string{"username": "jane doe"}),
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedFlash:  `Your account cannot be created: the username may only have letters, digits and @ . + - _, not ' '.`,
+		},
+		{
+			name:           "short username",
+			registration:   registrationOpen,
+			form:           form(map[string]string{"username": "jd"}),
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedFlash:  "Your account cannot be created: the username needs 3 to 150 characters.",
+		},
+		{
+			name:           "missing first name",
+			registration:   registrationOpen,
+			form:           form(map[string]string{"first_name": " "}),
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedFlash:  "Your account cannot be created: the first name is missing.",
+		},
+		{
+			name:           "invalid email",
+			registration:   registrationOpen,
+			form:           form(map[string]string{"email": "Jane <jane@example.com>"}),
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedFlash:  `Your account cannot be created: "Jane <jane@example.com>" is not an email address.`,
+		},
+		{
+			name:           "weak password",
+			registration:   registrationOpen,
+			form:           form(map[string]string{"password": "jane1234567", "password_confirmation": "jane1234567"}),
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedFlash:  "Your account cannot be created: the password contains your username or email address.",
+		},
+		{
+			name:           "passwords differ",
+			registration:   registrationOpen,
+			form:           form(map[string]string{"password_confirmation": "lanes and lapz"}),
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedFlash:  "Your account cannot be created: the passwords do not match.",
+		},
+		{
+			name:           "username taken",
+			registration:   registrationOpen,
+			form:           form(nil),
+			insertErr:      models.ErrDuplicateUsername,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedUser:   &models.User{Username: "jane", FirstName: "Jane", LastName: "Doe", Email: "jane@example.com"},
+			expectedFlash:  `The username "jane" is taken.`,
+		},
+		{
+			name:             "email taken with an invite",
+			registration:     registrationInvite,
+			form:             form(nil),
+			insertErr:        models.ErrDuplicateEmail,
+			expectedStatus:   http.StatusUnprocessableEntity,
+			expectedUser:     &models.User{Username: "jane", FirstName: "Jane", LastName: "Doe", Email: "jane@example.com"},
+			expectedRedeemed: true,
+			expectedReleased: true,
+			expectedFlash:    "There already is an account with this email address.",
+		},
+		{
+			name:             "database error",
+			registration:     registrationInvite,
+			form:             form(nil),
+			insertErr:        errors.New("database error"),
+			expectedStatus:   http.StatusInternalServerError,
+			expectedUser:     &models.User{Username: "jane", FirstName: "Jane", LastName: "Doe", Email: "jane@example.com"},
+			expectedRedeemed: true,
+			expectedReleased: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication()
+			app.registration = tt.registration
+			app.templateCache[signupTemplate] = createTestTemplate("base",
+				`{{define "base"}}{{with .Flash}}{{.Text}} {{end}}{{.Data.Username}}{{end}}`)
+
+			redeemed, released := false, false
+			app.invites = &testutils.MockInviteModel{
+				RedeemFunc: func(code string) (int, error) {
+					redeemed = true
+					assert.Equal(t, "CODE", code)
+					return 5, tt.redeemErr
+				},
+				ReleaseFunc: func(id int) error {
+					released = true
+					assert.Equal(t, 5, id)
+					return nil
+				},
+			}
+			var inserted *models.User
+			app.users = &testutils.MockUserModel{
+				InsertFunc: func(user *models.User, password string) error {
+					inserted = &models.User{Username: user.Username, FirstName: user.FirstName, LastName: user.LastName, Email: user.Email}
+					assert.Equal(t, "lanes and laps", password)
+					if tt.insertErr != nil {
+						return tt.insertErr
+					}
+					user.ID = 7
+					user.DistanceUnit = models.UnitMeters
+					return nil
+				},
+			}
+
+			rr := httptest.NewRecorder()
+			r := newSignupRequest(t, app, http.MethodPost, "/signup", tt.form)
+
+			app.storeSignup(rr, r)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.Equal(t, tt.expectedUser, inserted)
+			assert.Equal(t, tt.expectedRedeemed, redeemed)
+			assert.Equal(t, tt.expectedReleased, released)
+			if tt.expectedStatus == http.StatusUnprocessableEntity {
+				expectedBody := tt.expectedFlash + " " + strings.TrimSpace(tt.form.Get("username"))
+				assert.Equal(t, expectedBody, html.UnescapeString(rr.Body.String()), "the form keeps the entered values")
+			}
+			if tt.expectedLoggedIn {
+				assert.Equal(t, "/", rr.Header().Get("Location"))
+				assert.Equal(t, 7, app.sessionManager.GetInt(r.Context(), "authenticatedUserID"))
+				assert.Equal(t, "m", app.sessionManager.GetString(r.Context(), "distanceUnit"))
+				assert.Equal(t, tt.expectedFlash, app.sessionManager.GetString(r.Context(), "flashText"))
+			} else {
+				assert.Zero(t, app.sessionManager.GetInt(r.Context(), "authenticatedUserID"))
+			}
+		})
+	}
+}
+
+func TestCreateInvite(t *testing.T) {
+	tests := []struct {
+		name           string
+		registration   registrationMode
+		insertErr      error
+		expectedStatus int
+		expectedURL    string
+	}{
+		{name: "invite created", registration: registrationInvite, expectedStatus: http.StatusSeeOther, expectedURL: "http://example.com/signup?invite=CODE"},
+		{name: "registration open", registration: registrationOpen, expectedStatus: http.StatusNotFound},
+		{name: "database error", registration: registrationInvite, insertErr: errors.New("database error"), expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication()
+			app.registration = tt.registration
+			app.invites = &testutils.MockInviteModel{
+				InsertFunc: func(userId int) (string, error) {
+					assert.Equal(t, 1, userId)
+					return "CODE", tt.insertErr
+				},
+			}
+
+			rr := httptest.NewRecorder()
+			r := newImportRequest(t, app, http.MethodPost, "/account/invites", "", &bytes.Buffer{})
+
+			app.createInvite(rr, r)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.Equal(t, tt.expectedURL, app.sessionManager.GetString(r.Context(), "inviteURL"))
+			if tt.expectedStatus == http.StatusSeeOther {
+				assert.Equal(t, "/account", rr.Header().Get("Location"))
+			}
+		})
+	}
+}
@@ -1,10 +1,12 @@
 package main
 
 import (
+	"github.com/rockstaedt/swimmate/internal/models"
 	"github.com/rockstaedt/swimmate/ui"
 	"html/template"
 	"io/fs"
 	"net/http"
+	"net/url"
 	"path/filepath"
 	"strconv"
 	"time"
@@ -16,6 +18,7 @@ type templateData struct {
 	Partial         interface{}
 	Flash           *Flash
 	IsAuthenticated bool
+	Unit            models.Unit
 	CurrentDate     string
 	CurrentYear     int
 	CurrentMonth    int
@@ -43,6 +46,7 @@ func (app *application) newTemplateData(r *http.Request, data interface{}) templ
 		Data:            data,
 		Flash:           flash,
 		IsAuthenticated: app.isAuthenticated(r),
+		Unit:            app.distanceUnit(r),
 		CurrentDate:     now.Format("2006-01-02"),
 		CurrentYear:     now.Year(),
 		CurrentMonth:    int(now.Month()),
@@ -72,11 +76,23 @@ var functions = template.FuncMap{
 	"div":          div,
 	"seq":          seq,
 	"min":          min,
+	"filledStars":  filledStars,
 	"emptyStars":   emptyStars,
 	"atoi":         atoi,
 	"slice":        slice,
 	"monthAbbr":    monthAbbr,
 	"withPartial":  withPartial,
+	"clock":        models.Clock,
+	"strokes":      strokes,
+	"efforts":      efforts,
+	"feels":        feels,
+	"pools":        pools,
+	"units":        units,
+	"scopes":       scopes,
+	"inUnit":       inUnit,
+	"paceIn":       paceIn,
+	"setsDistance": models.SetsDistance,
+	"filterQuery":  filterQuery,
 }
 
 func numberFormat(n int) string {
@@ -124,9 +140,14 @@ func min(a, b int) int {
 	return b
 }
 
-func emptyStars(assessment int) int {
-	maxStars := 2
-	return maxStars - min(assessment, maxStars)
+// filledStars is the number of filled stars drawn for a feel rating, one per
+// step on the scale.
+func filledStars(feel models.Feel) int {
+	return max(min(int(feel), int(models.FeelGreat)), 0)
+}
+
+func emptyStars(feel models.Feel) int {
+	return int(models.FeelGreat) - filledStars(feel)
 }
 
 func atoi(s string) int {
@@ -158,6 +179,58 @@ func monthAbbr(month int) string {
 	return abbrs[month-1]
 }
 
+func strokes() []models.Stroke {
+	return models.Strokes
+}
+
+func efforts() []models.Effort {
+	return models.Efforts
+}
+
+func feels() []models.Feel {
+	return models.Feels
+}
+
+func pools() []models.Pool {
+	return models.Pools
+}
+
+func units() []models.Unit {
+	return models.Units
+}
+
+func scopes() []models.Scope {
+	return models.Scopes
+}
+
+// inUnit converts a distance in meters to the given display unit.
+func inUnit(unit models.Unit, meters int) int {
+	return unit.FromMeters(meters)
+}
+
+// paceIn converts a pace per 100 m to the pace per 100 of the given unit.
+func paceIn(unit models.Unit, pace time.Duration) time.Duration {
+	return unit.Pace(pace)
+}
+
+// filterQuery encodes a swim filter as query parameters to append to a link
+// that already has a query, e.g. "&q=goggles&tag=club". An empty filter
+// renders as an empty string.
+func filterQuery(filter models.SwimFilter) template.URL {
+	values := url.Values{}
+	if filter.Search != "" {
+		values.Set("q", filter.Search)
+	}
+	for _, tag := range filter.Tags {
+		values.Add("tag", tag)
+	}
+
+	if len(values) == 0 {
+		return ""
+	}
+	return template.URL("&" + values.Encode())
+}
+
 func withPartial(td templateData, partial interface{}) templateData {
 	td.Partial = partial
 	return td
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"html/template"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -135,24 +136,24 @@ func TestMin(t *testing.T) {
 	}
 }
 
-func TestEmptyStars(t *testing.T) {
+func TestStars(t *testing.T) {
 	tests := []struct {
-		name       string
-		assessment int
-		expected   int
+		name           string
+		feel           models.Feel
+		expectedFilled int
+		expectedEmpty  int
 	}{
-		{"zero stars", 0, 2},
-		{"one star", 1, 1},
-		{"two stars", 2, 0},
-		{"three stars (capped at max)", 3, 0},
-		{"five stars (capped at max)", 5, 0},
-		{"negative (edge case)", -1, 3},
+		{"terrible", models.FeelTerrible, 1, 4},
+		{"okay", models.FeelOkay, 3, 2},
+		{"great", models.FeelGreat, 5, 0},
+		{"six stars (capped at max)", 6, 5, 0},
+		{"negative (edge case)", -1, 0, 5},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := emptyStars(tt.assessment)
-			assert.Equal(t, tt.expected, result)
+			assert.Equal(t, tt.expectedFilled, filledStars(tt.feel))
+			assert.Equal(t, tt.expectedEmpty, emptyStars(tt.feel))
 		})
 	}
 }
@@ -269,6 +270,26 @@ func TestMonthAbbr(t *testing.T) {
 	}
 }
 
+func TestInUnit(t *testing.T) {
+	assert.Equal(t, 1500, inUnit(models.UnitMeters, 1500))
+	assert.Equal(t, 1640, inUnit(models.UnitYards, 1500))
+	assert.Equal(t, 0, inUnit(models.UnitYards, 0))
+}
+
+func TestPaceIn(t *testing.T) {
+	pace := 2 * time.Minute
+	assert.Equal(t, pace, paceIn(models.UnitMeters, pace))
+	assert.Equal(t, 110*time.Second, paceIn(models.UnitYards, pace))
+}
+
+func TestFilterQuery(t *testing.T) {
+	assert.Equal(t, template.URL(""), filterQuery(models.SwimFilter{}))
+	assert.Equal(t, template.URL("&q=felt+tight"), filterQuery(models.SwimFilter{Search: "felt tight"}))
+	assert.Equal(t,
+		template.URL("&q=goggles&tag=open-water&tag=with+club"),
+		filterQuery(models.SwimFilter{Search: "goggles", Tags: []string{"open-water", "with club"}}))
+}
+
 func TestNewFlash(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -342,7 +363,7 @@ func TestWithPartial(t *testing.T) {
 				Id:         1,
 				Date:       time.Now(),
 				DistanceM:  1500,
-				Assessment: 2,
+				Feel:       4,
 			},
 			expectedData: templateData{
 				Version: "1.0.0",
@@ -351,7 +372,7 @@ func TestWithPartial(t *testing.T) {
 					Id:         1,
 					Date:       time.Now(),
 					DistanceM:  1500,
-					Assessment: 2,
+					Feel:       4,
 				},
 			},
 		},
@@ -492,6 +513,7 @@ func TestNewTemplateCache(t *testing.T) {
 		"yearly-figures.tmpl",
 		"swim-create.tmpl",
 		"swim-edit.tmpl",
+		"account.tmpl",
 	}
 
 	for _, tmpl := range expectedTemplates {
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/rockstaedt/swimmate/internal/models"
+)
+
+const tokensTemplate = "tokens.tmpl"
+
+type tokensPageData struct {
+	Tokens []*models.AccessToken
+	// NewToken is the secret of a token that was just created. It is shown
+	// once, since only its hash is stored.
+	NewToken string
+}
+
+// accessTokensList shows the access tokens of the user with the form for a
+// new one.
+func (app *application) accessTokensList(w http.ResponseWriter, r *http.Request) {
+	tokens, err := app.accessTokens.GetAll(app.sessionManager.GetInt(r.Context(), "authenticatedUserID"))
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := tokensPageData{
+		Tokens:   tokens,
+		NewToken: app.sessionManager.PopString(r.Context(), "accessToken"),
+	}
+
+	app.render(w, r, http.StatusOK, tokensTemplate, app.newTemplateData(r, data))
+}
+
+// storeAccessToken creates an access token with the name and scopes of the
+// form. The token is shown once on the tokens page.
+func (app *application) storeAccessToken(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		app.logger.Error(err.Error())
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	token, err := accessTokenFromForm(r.PostForm)
+	if err != nil {
+		app.sessionManager.Put(r.Context(), "flashText", fmt.Sprintf("The token cannot be created: %s.", err))
+		app.sessionManager.Put(r.Context(), "flashType", "flash-error")
+		http.Redirect(w, r, "/account/tokens", http.StatusSeeOther)
+		return
+	}
+
+	secret, err := app.accessTokens.Insert(token, app.sessionManager.GetInt(r.Context(), "authenticatedUserID"))
+	if err != nil {
+		if errors.Is(err, models.ErrDuplicateName) {
+			app.sessionManager.Put(r.Context(), "flashText", fmt.Sprintf("There already is a token named %q.", token.Name))
+			app.sessionManager.Put(r.Context(), "flashType", "flash-error")
+			http.Redirect(w, r, "/account/tokens", http.StatusSeeOther)
+			return
+		}
+
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "accessToken", secret)
+	app.sessionManager.Put(r.Context(), "flashText", "Your token is ready. Copy it now, it is shown only once.")
+	app.sessionManager.Put(r.Context(), "flashType", "flash-success")
+
+	http.Redirect(w, r, "/account/tokens", http.StatusSeeOther)
+}
+
+// deleteAccessToken revokes an access token of the user.
+func (app *application) deleteAccessToken(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil || id <= 0 {
+		app.notFound(w)
+		return
+	}
+
+	err = app.accessTokens.Delete(id, app.sessionManager.GetInt(r.Context(), "authenticatedUserID"))
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flashText", "Token revoked.")
+	app.sessionManager.Put(r.Context(), "flashType", "flash-success")
+
+	http.Redirect(w, r, "/account/tokens", http.StatusSeeOther)
+}
+
+// accessTokenFromForm reads the name and scopes of a new token. The errors
+// are meant for the user.
+func accessTokenFromForm(form url.Values) (*models.AccessToken, error) {
+	name := strings.Join(strings.Fields(form.Get("name")), " ")
+	if name == "" {
+		return nil, errors.New("it needs a name")
+	}
+	if utf8.RuneCountInString(name) > models.MaxTokenNameLength {
+		return nil, fmt.Errorf("the name is longer than %d characters", models.MaxTokenNameLength)
+	}
+
+	token := &models.AccessToken{Name: name}
+	for _, value := range form["scope"] {
+		scope := models.Scope(value)
+		if !scope.Valid() {
+			return nil, fmt.Errorf("there is no scope %q", value)
+		}
+		if !slices.Contains(token.Scopes, scope) {
+			token.Scopes = append(token.Scopes, scope)
+		}
+	}
+	if len(token.Scopes) == 0 {
+		return nil, errors.New("choose at least one scope")
+	}
+
+	return token, nil
+}
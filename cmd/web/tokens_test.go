@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/rockstaedt/swimmate/internal/models"
+	"github.com/rockstaedt/swimmate/internal/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccessTokensList(t *testing.T) {
+	created := time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		tokens         []*models.AccessToken
+		getErr         error
+		newToken       string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "tokens of the user",
+			tokens: []*models.AccessToken{
+				{Id: 2, Name: "phone", Scopes: []models.Scope{models.ScopeSwimsRead, models.ScopeSwimsWrite}, Created: created},
+				{Id: 1, Name: "backup", Scopes: []models.Scope{models.ScopeSwimsRead}, Created: created},
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "phone swims:read swims:write;backup swims:read;",
+		},
+		{
+			name:           "token just created",
+			newToken:       "swm_SECRET",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "new swm_SECRET",
+		},
+		{
+			name:           "database error",
+			getErr:         errors.New("database error"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication()
+			app.accessTokens = &testutils.MockAccessTokenModel{
+				GetAllFunc: func(userId int) ([]*models.AccessToken, error) {
+					assert.Equal(t, 1, userId)
+					return tt.tokens, tt.getErr
+				},
+			}
+			app.templateCache[tokensTemplate] = createTestTemplate("base",
+				`{{define "base"}}{{with .Data.NewToken}}new {{.}}{{end}}{{range .Data.Tokens}}{{.Name}}{{range .Scopes}} {{.}}{{end}};{{end}}{{end}}`)
+
+			rr := httptest.NewRecorder()
+			r := newImportRequest(t, app, http.MethodGet, "/account/tokens", "", &bytes.Buffer{})
+			if tt.newToken != "" {
+				app.sessionManager.Put(r.Context(), "accessToken", tt.newToken)
+			}
+
+			app.accessTokensList(rr, r)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			if tt.expectedStatus == http.StatusOK {
+				assert.Equal(t, tt.expectedBody, rr.Body.String())
+				assert.Empty(t, app.sessionManager.GetString(r.Context(), "accessToken"), "the token is shown only once")
+			}
+		})
+	}
+}
+
+func TestStoreAccessToken(t *testing.T) {
+	tests := []struct {
+		name             string
+		form             url.Values
+		insertErr        error
+		expectedStatus   int
+		expectedToken    *models.AccessToken
+		expectedSecret   string
+		expectedFlash    string
+		expectedLocation string
+	}{
+		{
+			name:             "token created",
+			form:             url.Values{"name": {"  Backup   script "}, "scope": {"swims:read", "swims:write", "swims:read"}},
+			expectedStatus:   http.StatusSeeOther,
+			expectedToken:    &models.AccessToken{Name: "Backup script", Scopes: []models.Scope{models.ScopeSwimsRead, models.ScopeSwimsWrite}},
+			expectedSecret:   "swm_SECRET",
+			expectedFlash:    "Your token is ready. Copy it now, it is shown only once.",
+			expectedLocation: "/account/tokens",
+		},
+		{
+			name:             "no scope",
+			form:             url.Values{"name": {"Backup script"}},
+			expectedStatus:   http.StatusSeeOther,
+			expectedFlash:    "The token cannot be created: choose at least one scope.",
+			expectedLocation: "/account/tokens",
+		},
+		{
+			name:             "duplicate name",
+			form:             url.Values{"name": {"Backup script"}, "scope": {"swims:read"}},
+			insertErr:        models.ErrDuplicateName,
+			expectedStatus:   http.StatusSeeOther,
+			expectedToken:    &models.AccessToken{Name: "Backup script", Scopes: []models.Scope{models.ScopeSwimsRead}},
+			expectedFlash:    `There already is a token named "Backup script".`,
+			expectedLocation: "/account/tokens",
+		},
+		{
+			name:           "database error",
+			form:           url.Values{"name": {"Backup script"}, "scope": {"swims:read"}},
+			insertErr:      errors.New("database error"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedToken:  &models.AccessToken{Name: "Backup script", Scopes: []models.Scope{models.ScopeSwimsRead}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication()
+			inserted := false
+			app.accessTokens = &testutils.MockAccessTokenModel{
+				InsertFunc: func(token *models.AccessToken, userId int) (string, error) {
+					inserted = true
+					assert.Equal(t, 1, userId)
+					assert.Equal(t, tt.expectedToken, token)
+					if tt.insertErr != nil {
+						return "", tt.insertErr
+					}
+					return "swm_SECRET", nil
+				},
+			}
+
+			rr := httptest.NewRecorder()
+			r := newImportRequest(t, app, http.MethodPost, "/account/tokens", "application/x-www-form-urlencoded",
+				bytes.NewBufferString(tt.form.Encode()))
+
+			app.storeAccessToken(rr, r)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.Equal(t, tt.expectedToken != nil, inserted)
+			assert.Equal(t, tt.expectedLocation, rr.Header().Get("Location"))
+			assert.Equal(t, tt.expectedSecret, app.sessionManager.GetString(r.Context(), "accessToken"))
+			assert.Equal(t, tt.expectedFlash, app.sessionManager.GetString(r.Context(), "flashText"))
+		})
+	}
+}
+
+func TestDeleteAccessToken(t *testing.T) {
+	tests := []struct {
+		name           string
+		id             string
+		deleteErr      error
+		expectedStatus int
+	}{
+		{name: "token revoked", id: "3", expectedStatus: http.StatusSeeOther},
+		{name: "token of another user", id: "4", deleteErr: models.ErrNoRecord, expectedStatus: http.StatusNotFound},
+		{name: "invalid id", id: "abc", expectedStatus: http.StatusNotFound},
+		{name: "database error", id: "3", deleteErr: errors.New("database error"), expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication()
+			app.accessTokens = &testutils.MockAccessTokenModel{
+				DeleteFunc: func(id int, userId int) error {
+					assert.Equal(t, 1, userId)
+					return tt.deleteErr
+				},
+			}
+
+			rr := httptest.NewRecorder()
+			r := newImportRequest(t, app, http.MethodPost, "/account/tokens/"+tt.id+"/delete", "", &bytes.Buffer{})
+			r = r.WithContext(context.WithValue(r.Context(), httprouter.ParamsKey, httprouter.Params{{Key: "id", Value: tt.id}}))
+
+			app.deleteAccessToken(rr, r)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			if tt.expectedStatus == http.StatusSeeOther {
+				assert.Equal(t, "/account/tokens", rr.Header().Get("Location"))
+				assert.Equal(t, "Token revoked.", app.sessionManager.GetString(r.Context(), "flashText"))
+			}
+		})
+	}
+}
+
+func TestAccessTokenFromForm(t *testing.T) {
+	tests := []struct {
+		name          string
+		form          url.Values
+		expectedToken *models.AccessToken
+		expectedError string
+	}{
+		{
+			name:          "read token",
+			form:          url.Values{"name": {"Backup"}, "scope": {"swims:read"}},
+			expectedToken: &models.AccessToken{Name: "Backup", Scopes: []models.Scope{models.ScopeSwimsRead}},
+		},
+		{
+			name:          "missing name",
+			form:          url.Values{"name": {"  "}, "scope": {"swims:read"}},
+			expectedError: "it needs a name",
+		},
+		{
+			name:          "name too long",
+			form:          url.Values{"name": {strings.Repeat("a", models.MaxTokenNameLength+1)}, "scope": {"swims:read"}},
+			expectedError: "the name is longer than 100 characters",
+		},
+		{
+			name:          "unknown scope",
+			form:          url.Values{"name": {"Backup"}, "scope": {"swims:admin"}},
+			expectedError: `there is no scope "swims:admin"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := accessTokenFromForm(tt.form)
+
+			if tt.expectedError != "" {
+				assert.EqualError(t, err, tt.expectedError)
+				assert.Nil(t, token)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedToken, token)
+		})
+	}
+}
+
+func TestAPIWithAccessToken(t *testing.T) {
+	app := newTestApplication()
+	app.accessTokens = &testutils.MockAccessTokenModel{
+		AuthenticateFunc: func(token string) (int, *models.AccessToken, error) {
+			if token != "swm_SECRET" {
+				return 0, nil, models.ErrNoRecord
+			}
+			return 2, &models.AccessToken{Scopes: []models.Scope{models.ScopeSwimsRead}}, nil
+		},
+	}
+	app.swims = &testutils.MockSwimModel{
+		GetAfterFunc: func(userId int, limit int, after *models.SwimKey, sort string, direction string, filter models.SwimFilter) ([]*models.Swim, error) {
+			assert.Equal(t, 2, userId, "the swims of the user of the token")
+			return nil, nil
+		},
+	}
+	handler := app.routes()
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		token          string
+		expectedStatus int
+	}{
+		{name: "read with a read token", method: http.MethodGet, path: "/api/v1/swims", token: "swm_SECRET", expectedStatus: http.StatusOK},
+		{name: "write with a read token", method: http.MethodDelete, path: "/api/v1/swims/1", token: "swm_SECRET", expectedStatus: http.StatusForbidden},
+		{name: "revoked token", method: http.MethodGet, path: "/api/v1/swims", token: "swm_REVOKED", expectedStatus: http.StatusUnauthorized},
+		{name: "token outside the API", method: http.MethodGet, path: "/account", token: "swm_SECRET", expectedStatus: http.StatusSeeOther},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			r.Header.Set("Authorization", "Bearer "+tt.token)
+
+			handler.ServeHTTP(rr, r)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.Empty(t, rr.Header().Get("Set-Cookie"), "token requests do not start a session")
+		})
+	}
+}
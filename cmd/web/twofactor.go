@@ -0,0 +1,340 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rockstaedt/swimmate/internal/models"
+	"github.com/rockstaedt/swimmate/internal/totp"
+	"github.com/skip2/go-qrcode"
+)
+
+const (
+	twoFactorTemplate      = "two-factor.tmpl"
+	loginTwoFactorTemplate = "login-two-factor.tmpl"
+)
+
+// twoFactorLoginTimeout is how long the code can be entered after the
+// password.
+const twoFactorLoginTimeout = 5 * time.Minute
+
+// twoFactorIssuer names the account in authenticator apps.
+const twoFactorIssuer = "SwimMate"
+
+// twoFactorLockedText tells the user that no code is accepted for now.
+var twoFactorLockedText = fmt.Sprintf("Too many wrong codes. Try again in %d minutes.", int(models.TwoFactorLockout.Minutes()))
+
+type twoFactorPageData struct {
+	// TwoFactor is nil until the user starts the setup.
+	TwoFactor *models.TwoFactor
+	// Secret and QRCode add the secret to an authenticator app while the
+	// setup is not finished.
+	Secret string
+	QRCode template.URL
+	// RecoveryCodes are recovery codes that were just created. They cannot
+	// be shown later, since only their hashes are stored.
+	RecoveryCodes []string
+}
+
+// parseTwoFactorKey reads the TWO_FACTOR_KEY setting, the base64 of the key
+// that encrypts the TOTP secrets. Without it, two-factor authentication is
+// not available.
+func parseTwoFactorKey(value string) ([]byte, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	key, err := base64.StdEncoding.DecodeString(value)
+	if err != nil || len(key) != models.TwoFactorKeySize {
+		return nil, fmt.Errorf("TWO_FACTOR_KEY must be %d bytes in base64, as made by: openssl rand -base64 %d",
+			models.TwoFactorKeySize, models.TwoFactorKeySize)
+	}
+
+	return key, nil
+}
+
+// pendingTwoFactor returns the user that entered the right password but
+// still has to enter a code. It is zero if there is none or the code was not
+// entered in time.
+func (app *application) pendingTwoFactor(r *http.Request) int {
+	userId := app.sessionManager.GetInt(r.Context(), "twoFactorUserID")
+	started := time.Unix(app.sessionManager.GetInt64(r.Context(), "twoFactorStarted"), 0)
+	if userId == 0 || time.Since(started) > twoFactorLoginTimeout {
+		return 0
+	}
+
+	return userId
+}
+
+// startTwoFactorLogin parks a user whose password was right until the code
+// is entered. The user is not logged in yet, and neither is anyone who was
+// logged in with the session before.
+func (app *application) startTwoFactorLogin(w http.ResponseWriter, r *http.Request, userId int) {
+	err := app.sessionManager.RenewToken(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.sessionManager.Remove(r.Context(), "authenticatedUserID")
+	app.sessionManager.Put(r.Context(), "twoFactorUserID", userId)
+	// Unix seconds, since the session codec cannot encode a time.Time
+	app.sessionManager.Put(r.Context(), "twoFactorStarted", time.Now().Unix())
+
+	http.Redirect(w, r, "/login/2fa", http.StatusSeeOther)
+}
+
+// restartLogin drops a pending login and sends the user back to the login
+// page.
+func (app *application) restartLogin(w http.ResponseWriter, r *http.Request, text string) {
+	app.sessionManager.Remove(r.Context(), "twoFactorUserID")
+	app.sessionManager.Remove(r.Context(), "twoFactorStarted")
+	app.sessionManager.Put(r.Context(), "flashText", text)
+	app.sessionManager.Put(r.Context(), "flashType", "flash-error")
+
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+func (app *application) loginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	if app.pendingTwoFactor(r) == 0 {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	app.render(w, r, http.StatusOK, loginTwoFactorTemplate, app.newTemplateData(r, nil))
+}
+
+// verifyLoginTwoFactor finishes a login with a code of the authenticator
+// app or a recovery code.
+func (app *application) verifyLoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userId := app.pendingTwoFactor(r)
+	if userId == 0 {
+		app.restartLogin(w, r, "The code was not entered in time. Log in again.")
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		app.logger.Error(err.Error())
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	recovery, err := app.twoFactor.Verify(userId, strings.TrimSpace(r.PostForm.Get("code")))
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrTwoFactorLocked):
+			app.restartLogin(w, r, twoFactorLockedText)
+		case errors.Is(err, models.ErrInvalidCredentials):
+			app.sessionManager.Put(r.Context(), "flashText", "The code is wrong or was used already.")
+			app.sessionManager.Put(r.Context(), "flashType", "flash-error")
+			app.render(w, r, http.StatusUnprocessableEntity, loginTwoFactorTemplate, app.newTemplateData(r, nil))
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	app.sessionManager.Remove(r.Context(), "twoFactorUserID")
+	app.sessionManager.Remove(r.Context(), "twoFactorStarted")
+
+	text := "Successfully logged in."
+	if recovery {
+		text = "Logged in with a recovery code, which cannot be used again. Create new codes once few are left."
+	}
+	app.logIn(w, r, userId, text)
+}
+
+// twoFactorSettings shows the setup of two-factor authentication, or its
+// state once it is enabled.
+func (app *application) twoFactorSettings(w http.ResponseWriter, r *http.Request) {
+	if !app.twoFactorAvailable {
+		app.notFound(w)
+		return
+	}
+
+	app.renderTwoFactor(w, r, http.StatusOK)
+}
+
+func (app *application) renderTwoFactor(w http.ResponseWriter, r *http.Request, status int) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+	tf, err := app.twoFactor.Get(userId)
+	if err != nil && !errors.Is(err, models.ErrNoRecord) {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := twoFactorPageData{TwoFactor: tf}
+	if codes, ok := app.sessionManager.Pop(r.Context(), "recoveryCodes").([]string); ok {
+		data.RecoveryCodes = codes
+	}
+
+	if tf != nil && !tf.IsEnabled() {
+		user, err := app.users.Get(userId)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		data.Secret = totp.Encode(tf.Secret)
+		data.QRCode, err = qrCode(totp.URI(twoFactorIssuer, user.Username, tf.Secret))
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+	}
+
+	app.render(w, r, status, twoFactorTemplate, app.newTemplateData(r, data))
+}
+
+// qrCode returns a QR code of text as a data URL of a PNG image.
+func qrCode(text string) (template.URL, error) {
+	png, err := qrcode.Encode(text, qrcode.Medium, 256)
+	if err != nil {
+		return "", err
+	}
+
+	return template.URL("data:image/png;base64," + base64.StdEncoding.EncodeToString(png)), nil
+}
+
+// startTwoFactor creates the secret the authenticator app gets. Two-factor
+// authentication is enabled once the user entered a code of it.
+func (app *application) startTwoFactor(w http.ResponseWriter, r *http.Request) {
+	if !app.twoFactorAvailable {
+		app.notFound(w)
+		return
+	}
+
+	_, err := app.twoFactor.Enroll(app.sessionManager.GetInt(r.Context(), "authenticatedUserID"))
+	if err != nil && !errors.Is(err, models.ErrTwoFactorEnabled) {
+		app.serverError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, "/account/2fa", http.StatusSeeOther)
+}
+
+// enableTwoFactor finishes the setup with a code of the authenticator app.
+// The recovery codes are shown once on the two-factor page.
+func (app *application) enableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	if !app.twoFactorAvailable {
+		app.notFound(w)
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		app.logger.Error(err.Error())
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+	codes, err := app.twoFactor.Enable(userId, strings.TrimSpace(r.PostForm.Get("code")))
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrInvalidCredentials):
+			app.sessionManager.Put(r.Context(), "flashText",
+				"The code is wrong. Check that the clock of your phone is right and enter the current code.")
+			app.sessionManager.Put(r.Context(), "flashType", "flash-error")
+			app.renderTwoFactor(w, r, http.StatusUnprocessableEntity)
+		case errors.Is(err, models.ErrNoRecord):
+			http.Redirect(w, r, "/account/2fa", http.StatusSeeOther)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "recoveryCodes", codes)
+	app.sessionManager.Put(r.Context(), "flashText",
+		"Two-factor authentication is on. Keep the recovery codes somewhere safe, they are shown only once.")
+	app.sessionManager.Put(r.Context(), "flashType", "flash-success")
+
+	http.Redirect(w, r, "/account/2fa", http.StatusSeeOther)
+}
+
+// regenerateRecoveryCodes replaces the recovery codes of the user, who has
+// to confirm it with a code.
+func (app *application) regenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	userId, ok := app.confirmTwoFactor(w, r)
+	if !ok {
+		return
+	}
+
+	codes, err := app.twoFactor.RegenerateRecoveryCodes(userId)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "recoveryCodes", codes)
+	app.sessionManager.Put(r.Context(), "flashText", "New recovery codes are ready, the old ones no longer work.")
+	app.sessionManager.Put(r.Context(), "flashType", "flash-success")
+
+	http.Redirect(w, r, "/account/2fa", http.StatusSeeOther)
+}
+
+// disableTwoFactor turns two-factor authentication off, which the user has
+// to confirm with a code.
+func (app *application) disableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userId, ok := app.confirmTwoFactor(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.twoFactor.Disable(userId)
+	if err != nil && !errors.Is(err, models.ErrNoRecord) {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flashText", "Two-factor authentication is off.")
+	app.sessionManager.Put(r.Context(), "flashType", "flash-success")
+
+	http.Redirect(w, r, "/account/2fa", http.StatusSeeOther)
+}
+
+// confirmTwoFactor checks the code of the form before a change of an enabled
+// two-factor authentication, so that a stolen session cannot turn it off.
+// It answers the request itself if the code is wrong.
+func (app *application) confirmTwoFactor(w http.ResponseWriter, r *http.Request) (int, bool) {
+	if !app.twoFactorAvailable {
+		app.notFound(w)
+		return 0, false
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		app.logger.Error(err.Error())
+		app.clientError(w, http.StatusBadRequest)
+		return 0, false
+	}
+
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+	_, err = app.twoFactor.Verify(userId, strings.TrimSpace(r.PostForm.Get("code")))
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrInvalidCredentials):
+			app.sessionManager.Put(r.Context(), "flashText", "The code is wrong or was used already.")
+			app.sessionManager.Put(r.Context(), "flashType", "flash-error")
+			app.renderTwoFactor(w, r, http.StatusUnprocessableEntity)
+		case errors.Is(err, models.ErrTwoFactorLocked):
+			app.sessionManager.Put(r.Context(), "flashText", twoFactorLockedText)
+			app.sessionManager.Put(r.Context(), "flashType", "flash-error")
+			app.renderTwoFactor(w, r, http.StatusTooManyRequests)
+		case errors.Is(err, models.ErrNoRecord):
+			http.Redirect(w, r, "/account/2fa", http.StatusSeeOther)
+		default:
+			app.serverError(w, r, err)
+		}
+		return 0, false
+	}
+
+	return userId, true
+}
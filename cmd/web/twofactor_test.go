@@ -0,0 +1,461 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rockstaedt/swimmate/internal/models"
+	"github.com/rockstaedt/swimmate/internal/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTwoFactorLoginRequest is a request of a visitor that entered the
+// password of user 1 at started.
+func newTwoFactorLoginRequest(t *testing.T, app *application, started time.Time, code string) *http.Request {
+	t.Helper()
+
+	r := newSignupRequest(t, app, http.MethodPost, "/login/2fa", url.Values{"code": {code}})
+	app.sessionManager.Put(r.Context(), "twoFactorUserID", 1)
+	app.sessionManager.Put(r.Context(), "twoFactorStarted", started.Unix())
+
+	return r
+}
+
+// newTwoFactorSettingsRequest is a request of user 1 to the two-factor
+// settings.
+func newTwoFactorSettingsRequest(t *testing.T, app *application, method string, target string, form url.Values) *http.Request {
+	t.Helper()
+
+	r := newSignupRequest(t, app, method, target, form)
+	app.sessionManager.Put(r.Context(), "authenticatedUserID", 1)
+
+	return r
+}
+
+func TestParseTwoFactorKey(t *testing.T) {
+	key := strings.Repeat("k", models.TwoFactorKeySize)
+
+	tests := []struct {
+		name        string
+		value       string
+		expectedKey []byte
+		expectedErr bool
+	}{
+		{name: "unset", value: ""},
+		{name: "valid key", value: base64.StdEncoding.EncodeToString([]byte(key)), expectedKey: []byte(key)},
+		{name: "too short", value: base64.StdEncoding.EncodeToString([]byte("short")), expectedErr: true},
+		{name: "no base64", value: "not base64!", expectedErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := parseTwoFactorKey(tt.value)
+
+			assert.Equal(t, tt.expectedErr, err != nil)
+			assert.Equal(t, tt.expectedKey, key)
+		})
+	}
+}
+
+func TestAuthenticateWithTwoFactor(t *testing.T) {
+	app := newTestApplication()
+	app.users = &testutils.MockUserModel{
+		AuthenticateFunc: func(username, password string) (int, error) {
+			return 1, nil
+		},
+	}
+	app.twoFactor = &testutils.MockTwoFactorModel{
+		EnabledFunc: func(userId int) (bool, error) {
+			return userId == 1, nil
+		},
+	}
+
+	rr := httptest.NewRecorder()
+	r := newSignupRequest(t, app, http.MethodPost, "/authenticate",
+		url.Values{"username": {"testuser"}, "password": {"password123"}})
+	// The session was logged in as another user before
+	app.sessionManager.Put(r.Context(), "authenticatedUserID", 2)
+
+	app.authenticate(rr, r)
+
+	assert.Equal(t, http.StatusSeeOther, rr.Code)
+	assert.Equal(t, "/login/2fa", rr.Header().Get("Location"))
+	assert.Zero(t, app.sessionManager.GetInt(r.Context(), "authenticatedUserID"), "the password alone does not log in")
+	assert.Equal(t, 1, app.sessionManager.GetInt(r.Context(), "twoFactorUserID"))
+}
+
+func TestLoginTwoFactor(t *testing.T) {
+	tests := []struct {
+		name             string
+		started          time.Time
+		expectedStatus   int
+		expectedLocation string
+	}{
+		{
+			name:           "pending login",
+			started:        time.Now(),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:             "expired login",
+			started:          time.Now().Add(-twoFactorLoginTimeout - time.Minute),
+			expectedStatus:   http.StatusSeeOther,
+			expectedLocation: "/login",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication()
+			app.templateCache[loginTwoFactorTemplate] = createTestTemplate("base", `{{define "base"}}Code{{end}}`)
+
+			rr := httptest.NewRecorder()
+			r := newTwoFactorLoginRequest(t, app, tt.started, "")
+
+			app.loginTwoFactor(rr, r)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.Equal(t, tt.expectedLocation, rr.Header().Get("Location"))
+		})
+	}
+}
+
+func TestVerifyLoginTwoFactor(t *testing.T) {
+	tests := []struct {
+		name             string
+		started          time.Time
+		verifyFunc       func(userId int, code string) (bool, error)
+		expectedStatus   int
+		expectedLocation string
+		expectedFlash    string
+		expectedUserID   int
+	}{
+		{
+			name:    "right code",
+			started: time.Now(),
+			verifyFunc: func(userId int, code string) (bool, error) {
+				return false, nil
+			},
+			expectedStatus:   http.StatusSeeOther,
+			expectedLocation: "/",
+			expectedFlash:    "Successfully logged in.",
+			expectedUserID:   1,
+		},
+		{
+			name:    "recovery code",
+			started: time.Now(),
+			verifyFunc: func(userId int, code string) (bool, error) {
+				return true, nil
+			},
+			expectedStatus:   http.StatusSeeOther,
+			expectedLocation: "/",
+			expectedFlash:    "Logged in with a recovery code, which cannot be used again. Create new codes once few are left.",
+			expectedUserID:   1,
+		},
+		{
+			name:           "wrong code",
+			started:        time.Now(),
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedFlash:  "The code is wrong or was used already.",
+		},
+		{
+			name:    "too many wrong codes",
+			started: time.Now(),
+			verifyFunc: func(userId int, code string) (bool, error) {
+				return false, models.ErrTwoFactorLocked
+			},
+			expectedStatus:   http.StatusSeeOther,
+			expectedLocation: "/login",
+			expectedFlash:    "Too many wrong codes. Try again in 15 minutes.",
+		},
+		{
+			name:    "code too late",
+			started: time.Now().Add(-twoFactorLoginTimeout - time.Minute),
+			verifyFunc: func(userId int, code string) (bool, error) {
+				return false, nil
+			},
+			expectedStatus:   http.StatusSeeOther,
+			expectedLocation: "/login",
+			expectedFlash:    "The code was not entered in time. Log in again.",
+		},
+		{
+			name:    "database error",
+			started: time.Now(),
+			verifyFunc: func(userId int, code string) (bool, error) {
+				return false, errors.New("database error")
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication()
+			var verified string
+			app.twoFactor = &testutils.MockTwoFactorModel{
+				VerifyFunc: func(userId int, code string) (bool, error) {
+					verified = code
+					if tt.verifyFunc == nil {
+						return false, models.ErrInvalidCredentials
+					}
+					return tt.verifyFunc(userId, code)
+				},
+			}
+			app.templateCache[loginTwoFactorTemplate] = createTestTemplate("base",
+				`{{define "base"}}{{with .Flash}}{{.Text}}{{end}}{{end}}`)
+
+			rr := httptest.NewRecorder()
+			r := newTwoFactorLoginRequest(t, app, tt.started, " 123 456 ")
+
+			app.verifyLoginTwoFactor(rr, r)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.Equal(t, tt.expectedLocation, rr.Header().Get("Location"))
+			assert.Equal(t, tt.expectedUserID, app.sessionManager.GetInt(r.Context(), "authenticatedUserID"))
+			if tt.expectedStatus == http.StatusUnprocessableEntity {
+				assert.Equal(t, tt.expectedFlash, html.UnescapeString(rr.Body.String()))
+			} else {
+				assert.Equal(t, tt.expectedFlash, app.sessionManager.GetString(r.Context(), "flashText"))
+			}
+			if tt.expectedStatus == http.StatusSeeOther {
+				assert.Zero(t, app.sessionManager.GetInt(r.Context(), "twoFactorUserID"), "the pending login is over")
+			}
+			if tt.expectedLocation != "/login" {
+				assert.Equal(t, "123 456", verified)
+			}
+		})
+	}
+}
+
+func TestTwoFactorSettings(t *testing.T) {
+	tests := []struct {
+		name           string
+		available      bool
+		twoFactor      *models.TwoFactor
+		recoveryCodes  []string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "not available",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "not set up",
+			available:      true,
+			expectedStatus: http.StatusOK,
+			expectedBody:   "off",
+		},
+		{
+			name:           "setup pending",
+			available:      true,
+			twoFactor:      &models.TwoFactor{Secret: []byte("12345678901234567890")},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "pending GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ data:image/png;base64,",
+		},
+		{
+			name:           "enabled with new recovery codes",
+			available:      true,
+			twoFactor:      &models.TwoFactor{Enabled: time.Now(), RecoveryCodes: 10},
+			recoveryCodes:  []string{"AAAA-BBBB-CCCC-DDDD"},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "on 10 [AAAA-BBBB-CCCC-DDDD]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication()
+			app.twoFactorAvailable = tt.available
+			app.twoFactor = &testutils.MockTwoFactorModel{
+				GetFunc: func(userId int) (*models.TwoFactor, error) {
+					if tt.twoFactor == nil {
+						return nil, models.ErrNoRecord
+					}
+					return tt.twoFactor, nil
+				},
+			}
+			app.templateCache[twoFactorTemplate] = createTestTemplate("base", `{{define "base"}}{{with .Data}}`+
+				`{{if not .TwoFactor}}off{{else if not .TwoFactor.IsEnabled}}pending {{.Secret}} {{.QRCode}}`+
+				`{{else}}on {{.TwoFactor.RecoveryCodes}} {{.RecoveryCodes}}{{end}}{{end}}{{end}}`)
+
+			rr := httptest.NewRecorder()
+			r := newTwoFactorSettingsRequest(t, app, http.MethodGet, "/account/2fa", nil)
+			if tt.recoveryCodes != nil {
+				app.sessionManager.Put(r.Context(), "recoveryCodes", tt.recoveryCodes)
+			}
+
+			app.twoFactorSettings(rr, r)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			if tt.expectedBody != "" {
+				assert.True(t, strings.HasPrefix(rr.Body.String(), tt.expectedBody), rr.Body.String())
+			}
+			assert.Nil(t, app.sessionManager.Get(r.Context(), "recoveryCodes"), "recovery codes are shown once")
+		})
+	}
+}
+
+func TestEnableTwoFactor(t *testing.T) {
+	tests := []struct {
+		name             string
+		enableErr        error
+		expectedStatus   int
+		expectedLocation string
+		expectedCodes    []string
+	}{
+		{
+			name:             "right code",
+			expectedStatus:   http.StatusSeeOther,
+			expectedLocation: "/account/2fa",
+			expectedCodes:    []string{"AAAA-BBBB-CCCC-DDDD"},
+		},
+		{
+			name:           "wrong code",
+			enableErr:      models.ErrInvalidCredentials,
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:             "setup not started",
+			enableErr:        models.ErrNoRecord,
+			expectedStatus:   http.StatusSeeOther,
+			expectedLocation: "/account/2fa",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication()
+			app.twoFactorAvailable = true
+			app.twoFactor = &testutils.MockTwoFactorModel{
+				GetFunc: func(userId int) (*models.TwoFactor, error) {
+					return &models.TwoFactor{Secret: []byte("12345678901234567890")}, nil
+				},
+				EnableFunc: func(userId int, code string) ([]string, error) {
+					assert.Equal(t, "123456", code)
+					if tt.enableErr != nil {
+						return nil, tt.enableErr
+					}
+					return []string{"AAAA-BBBB-CCCC-DDDD"}, nil
+				},
+			}
+			app.templateCache[twoFactorTemplate] = createTestTemplate("base",
+				`{{define "base"}}{{with .Flash}}{{.Text}}{{end}}{{end}}`)
+
+			rr := httptest.NewRecorder()
+			r := newTwoFactorSettingsRequest(t, app, http.MethodPost, "/account/2fa/enable",
+				url.Values{"code": {"123456"}})
+
+			app.enableTwoFactor(rr, r)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.Equal(t, tt.expectedLocation, rr.Header().Get("Location"))
+			codes, _ := app.sessionManager.Get(r.Context(), "recoveryCodes").([]string)
+			assert.Equal(t, tt.expectedCodes, codes)
+			if tt.expectedStatus == http.StatusUnprocessableEntity {
+				assert.Contains(t, html.UnescapeString(rr.Body.String()), "The code is wrong.")
+			}
+		})
+	}
+}
+
+func TestConfirmTwoFactor(t *testing.T) {
+	tests := []struct {
+		name             string
+		handler          func(app *application) http.HandlerFunc
+		verifyErr        error
+		expectedStatus   int
+		expectedLocation string
+		expectedDisabled bool
+		expectedCodes    []string
+	}{
+		{
+			name:             "disable with the right code",
+			handler:          func(app *application) http.HandlerFunc { return app.disableTwoFactor },
+			expectedStatus:   http.StatusSeeOther,
+			expectedLocation: "/account/2fa",
+			expectedDisabled: true,
+		},
+		{
+			name:           "disable with a wrong code",
+			handler:        func(app *application) http.HandlerFunc { return app.disableTwoFactor },
+			verifyErr:      models.ErrInvalidCredentials,
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:             "new recovery codes with the right code",
+			handler:          func(app *application) http.HandlerFunc { return app.regenerateRecoveryCodes },
+			expectedStatus:   http.StatusSeeOther,
+			expectedLocation: "/account/2fa",
+			expectedCodes:    []string{"EEEE-FFFF-GGGG-HHHH"},
+		},
+		{
+			name:           "new recovery codes with a wrong code",
+			handler:        func(app *application) http.HandlerFunc { return app.regenerateRecoveryCodes },
+			verifyErr:      models.ErrInvalidCredentials,
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:           "disable with too many wrong codes",
+			handler:        func(app *application) http.HandlerFunc { return app.disableTwoFactor },
+			verifyErr:      models.ErrTwoFactorLocked,
+			expectedStatus: http.StatusTooManyRequests,
+		},
+		{
+			name:             "not enabled",
+			handler:          func(app *application) http.HandlerFunc { return app.disableTwoFactor },
+			verifyErr:        models.ErrNoRecord,
+			expectedStatus:   http.StatusSeeOther,
+			expectedLocation: "/account/2fa",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication()
+			app.twoFactorAvailable = true
+			var disabled bool
+			app.twoFactor = &testutils.MockTwoFactorModel{
+				GetFunc: func(userId int) (*models.TwoFactor, error) {
+					return &models.TwoFactor{Enabled: time.Now()}, nil
+				},
+				VerifyFunc: func(userId int, code string) (bool, error) {
+					return false, tt.verifyErr
+				},
+				RegenerateRecoveryCodesFunc: func(userId int) ([]string, error) {
+					return []string{"EEEE-FFFF-GGGG-HHHH"}, nil
+				},
+				DisableFunc: func(userId int) error {
+					disabled = true
+					return nil
+				},
+			}
+			app.templateCache[twoFactorTemplate] = createTestTemplate("base",
+				`{{define "base"}}{{with .Flash}}{{.Text}}{{end}}{{end}}`)
+
+			rr := httptest.NewRecorder()
+			r := newTwoFactorSettingsRequest(t, app, http.MethodPost, "/account/2fa", url.Values{"code": {"123456"}})
+
+			tt.handler(app)(rr, r)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.Equal(t, tt.expectedLocation, rr.Header().Get("Location"))
+			assert.Equal(t, tt.expectedDisabled, disabled)
+			codes, _ := app.sessionManager.Get(r.Context(), "recoveryCodes").([]string)
+			assert.Equal(t, tt.expectedCodes, codes)
+			if tt.expectedStatus == http.StatusUnprocessableEntity {
+				assert.Equal(t, "The code is wrong or was used already.", html.UnescapeString(rr.Body.String()))
+			}
+			if tt.expectedStatus == http.StatusTooManyRequests {
+				assert.Equal(t, "Too many wrong codes. Try again in 15 minutes.", html.UnescapeString(rr.Body.String()))
+			}
+		})
+	}
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/rockstaedt/swimmate/internal/models"
+	"github.com/rockstaedt/swimmate/internal/webhooks"
+)
+
+const (
+	webhooksTemplate = "webhooks.tmpl"
+	// deliveriesShown is the length of the delivery log on the webhooks page.
+	deliveriesShown = 50
+)
+
+type webhooksPageData struct {
+	Webhooks   []*models.Webhook
+	Deliveries []*models.WebhookDelivery
+	// NewSecret is the secret of a webhook that was just created. It is
+	// shown once, like the secret of a token.
+	NewSecret string
+}
+
+// webhookPayload is the body of a webhook delivery. Swims are sent like in
+// the API, deleted swims only by their id.
+type webhookPayload struct {
+	Event      models.WebhookEvent `json:"event"`
+	OccurredAt time.Time           `json:"occurred_at"`
+	SwimId     int                 `json:"swim_id"`
+	Swim       *apiSwim            `json:"swim,omitempty"`
+}
+
+// webhookSwimModel is the swim model of the app. Once a swim was created,
+// changed or deleted it queues a delivery for each webhook of the user,
+// whichever handler made the change.
+//
+// Events are queued at most once. The deliveries are queued after the change
+// was committed, outside of its transaction, so a change is never undone for
+// a webhook. If queuing fails, the event is logged and not sent, and it is
+// not queued again later.
+type webhookSwimModel struct {
+	models.SwimModel
+	webhooks   models.WebhookModel
+	dispatcher *webhooks.Dispatcher
+	logger     *slog.Logger
+}
+
+func (m *webhookSwimModel) Insert(swim *models.Swim, userId int) error {
+	err := m.SwimModel.Insert(swim, userId)
+	if err != nil {
+		return err
+	}
+
+	m.enqueue(userId, models.EventSwimCreated, swim.Id, swim)
+	return nil
+}
+
+func (m *webhookSwimModel) InsertMany(swims []*models.Swim, userId int) error {
+	err := m.SwimModel.InsertMany(swims, userId)
+	if err != nil {
+		return err
+	}
+
+	for _, swim := range swims {
+		m.enqueue(userId, models.EventSwimCreated, swim.Id, swim)
+	}
+	return nil
+}
+
+func (m *webhookSwimModel) Update(swim *models.Swim, userId int) error {
+	err := m.SwimModel.Update(swim, userId)
+	if err != nil {
+		return err
+	}
+
+	m.enqueue(userId, models.EventSwimUpdated, swim.Id, swim)
+	return nil
+}
+
+func (m *webhookSwimModel) Delete(id int, userId int) error {
+	err := m.SwimModel.Delete(id, userId)
+	if err != nil {
+		return err
+	}
+
+	m.enqueue(userId, models.EventSwimDeleted, id, nil)
+	return nil
+}
+
+// webhookBackupModel is the backup model of the app. Restoring a backup
+// changes the swims of the user like an import, so once a restore is
+// committed it queues the deletion of every swim a replace deleted and the
+// creation of every swim it stored.
+type webhookBackupModel struct {
+	models.BackupModel
+	swims *webhookSwimModel
+}
+
+func (m *webhookBackupModel) Restore(userId int, backup *models.Backup, mode models.RestoreMode) (*models.Restored, error) {
+	restored, err := m.BackupModel.Restore(userId, backup, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range restored.DeletedIds {
+		m.swims.enqueue(userId, models.EventSwimDeleted, id, nil)
+	}
+	for _, swim := range restored.Swims {
+		m.swims.enqueue(userId, models.EventSwimCreated, swim.Id, swim)
+	}
+	return restored, nil
+}
+
+// enqueue queues the deliveries of an event. The swim is stored already, so
+// errors are logged instead of failing the request, and the event is lost.
+func (m *webhookSwimModel) enqueue(userId int, event models.WebhookEvent, swimId int, swim *models.Swim) {
+	payload := webhookPayload{
+		Event:      event,
+		OccurredAt: time.Now().UTC().Truncate(time.Second),
+		SwimId:     swimId,
+	}
+	if swim != nil {
+		out := newAPISwim(swim)
+		payload.Swim = &out
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		m.logger.Error("encoding webhook payload", "event", event, "swim", swimId, "error", err)
+		return
+	}
+
+	queued, err := m.webhooks.Enqueue(userId, event, body)
+	if err != nil {
+		m.logger.Error("queuing webhook deliveries", "event", event, "swim", swimId, "error", err)
+		return
+	}
+	if queued > 0 {
+		m.dispatcher.Notify()
+	}
+}
+
+// webhooksList shows the webhooks of the user with the form for a new one
+// and the latest deliveries.
+func (app *application) webhooksList(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+
+	hooks, err := app.webhooks.GetAll(userId)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	deliveries, err := app.webhooks.GetDeliveries(userId, deliveriesShown)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := webhooksPageData{
+		Webhooks:   hooks,
+		Deliveries: deliveries,
+		NewSecret:  app.sessionManager.PopString(r.Context(), "webhookSecret"),
+	}
+
+	app.render(w, r, http.StatusOK, webhooksTemplate, app.newTemplateData(r, data))
+}
+
+// storeWebhook creates a webhook for the URL of the form. Its secret is shown
+// once on the webhooks page.
+func (app *application) storeWebhook(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		app.logger.Error(err.Error())
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	target, err := webhookURLFromForm(r.PostForm)
+	if err != nil {
+		app.sessionManager.Put(r.Context(), "flashText", fmt.Sprintf("The webhook cannot be added: %s.", err))
+		app.sessionManager.Put(r.Context(), "flashType", "flash-error")
+		http.Redirect(w, r, "/account/webhooks", http.StatusSeeOther)
+		return
+	}
+
+	hook := &models.Webhook{URL: target}
+	err = app.webhooks.Insert(hook, app.sessionManager.GetInt(r.Context(), "authenticatedUserID"))
+	if err != nil {
+		if errors.Is(err, models.ErrDuplicateURL) {
+			app.sessionManager.Put(r.Context(), "flashText", fmt.Sprintf("There already is a webhook for %s.", target))
+			app.sessionManager.Put(r.Context(), "flashType", "flash-error")
+			http.Redirect(w, r, "/account/webhooks", http.StatusSeeOther)
+			return
+		}
+
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "webhookSecret", hook.Secret)
+	app.sessionManager.Put(r.Context(), "flashText", "Your webhook is ready. Copy its secret now, it is shown only once.")
+	app.sessionManager.Put(r.Context(), "flashType", "flash-success")
+
+	http.Redirect(w, r, "/account/webhooks", http.StatusSeeOther)
+}
+
+// deleteWebhook removes a webhook of the user with its deliveries.
+func (app *application) deleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, ok := app.webhookParamID(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.webhooks.Delete(id, app.sessionManager.GetInt(r.Context(), "authenticatedUserID"))
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flashText", "Webhook removed.")
+	app.sessionManager.Put(r.Context(), "flashType", "flash-success")
+
+	http.Redirect(w, r, "/account/webhooks", http.StatusSeeOther)
+}
+
+// redeliverWebhook sends the payload of a delivery of the user again, for
+// instance after the receiver was fixed.
+func (app *application) redeliverWebhook(w http.ResponseWriter, r *http.Request) {
+	id, ok := app.webhookParamID(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.webhooks.Redeliver(id, app.sessionManager.GetInt(r.Context(), "authenticatedUserID"))
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+	app.dispatcher.Notify()
+
+	app.sessionManager.Put(r.Context(), "flashText", "The delivery is sent again.")
+	app.sessionManager.Put(r.Context(), "flashType", "flash-success")
+
+	http.Redirect(w, r, "/account/webhooks", http.StatusSeeOther)
+}
+
+// webhookParamID reads the id of the path, responding with Not Found for ids
+// that cannot exist.
+func (app *application) webhookParamID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	params := httprouter.ParamsFromContext(r.Context())
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil || id <= 0 {
+		app.notFound(w)
+		return 0, false
+	}
+	return id, true
+}
+
+// webhookURLFromForm reads the URL of a new webhook. The errors are meant for
+// the user.
+func webhookURLFromForm(form url.Values) (string, error) {
+	raw := strings.TrimSpace(form.Get("url"))
+	if raw == "" {
+		return "", errors.New("it needs a URL")
+	}
+	if utf8.RuneCountInString(raw) > models.MaxWebhookURLLength {
+		return "", fmt.Errorf("the URL is longer than %d characters", models.MaxWebhookURLLength)
+	}
+
+	target, err := url.Parse(raw)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return "", fmt.Errorf("%q is not an http or https URL", raw)
+	}
+
+	return target.String(), nil
+}
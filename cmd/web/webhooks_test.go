@@ -0,0 +1,545 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/rockstaedt/swimmate/internal/models"
+	"github.com/rockstaedt/swimmate/internal/testutils"
+	"github.com/rockstaedt/swimmate/internal/webhooks"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhooksList(t *testing.T) {
+	created := time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		hooks          []*models.Webhook
+		deliveries     []*models.WebhookDelivery
+		getErr         error
+		newSecret      string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:  "webhooks and deliveries of the user",
+			hooks: []*models.Webhook{{Id: 1, URL: "https://example.com/hook", Created: created}},
+			deliveries: []*models.WebhookDelivery{
+				{Id: 2, Event: models.EventSwimDeleted, State: models.DeliveryPending, StatusCode: 500},
+				{Id: 1, Event: models.EventSwimCreated, State: models.DeliveryDelivered, StatusCode: 204},
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "https://example.com/hook;swim.deleted pending 500;swim.created delivered 204;",
+		},
+		{
+			name:           "webhook just created",
+			newSecret:      "SECRET",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "secret SECRET",
+		},
+		{
+			name:           "database error",
+			getErr:         errors.New("database error"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication()
+			app.webhooks = &testutils.MockWebhookModel{
+				GetAllFunc: func(userId int) ([]*models.Webhook, error) {
+					assert.Equal(t, 1, userId)
+					return tt.hooks, tt.getErr
+				},
+				GetDeliveriesFunc: func(userId int, limit int) ([]*models.WebhookDelivery, error) {
+					assert.Equal(t, 1, userId)
+					assert.Equal(t, deliveriesShown, limit)
+					return tt.deliveries, nil
+				},
+			}
+			app.templateCache[webhooksTemplate] = createTestTemplate("base",
+				`{{define "base"}}{{with .Data.NewSecret}}secret {{.}}{{end}}{{range .Data.Webhooks}}{{.URL}};{{end}}{{range .Data.Deliveries}}{{.Event}} {{.State}} {{.StatusCode}};{{end}}{{end}}`)
+
+			rr := httptest.NewRecorder()
+			r := newImportRequest(t, app, http.MethodGet, "/account/webhooks", "", &bytes.Buffer{})
+			if tt.newSecret != "" {
+				app.sessionManager.Put(r.Context(), "webhookSecret", tt.newSecret)
+			}
+
+			app.webhooksList(rr, r)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			if tt.expectedStatus == http.StatusOK {
+				assert.Equal(t, tt.expectedBody, rr.Body.String())
+				assert.Empty(t, app.sessionManager.GetString(r.Context(), "webhookSecret"), "the secret is shown only once")
+			}
+		})
+	}
+}
+
+func TestStoreWebhook(t *testing.T) {
+	tests := []struct {
+		name           string
+		form           url.Values
+		insertErr      error
+		expectedStatus int
+		expectedURL    string
+		expectedSecret string
+		expectedFlash  string
+	}{
+		{
+			name:           "webhook added",
+			form:           url.Values{"url": {" https://example.com/hook "}},
+			expectedStatus: http.StatusSeeOther,
+			expectedURL:    "https://example.com/hook",
+			expectedSecret: "SECRET",
+			expectedFlash:  "Your webhook is ready. Copy its secret now, it is shown only once.",
+		},
+		{
+			name:           "invalid url",
+			form:           url.Values{"url": {"ftp://example.com"}},
+			expectedStatus: http.StatusSeeOther,
+			expectedFlash:  `The webhook cannot be added: "ftp://example.com" is not an http or https URL.`,
+		},
+		{
+			name:           "duplicate url",
+			form:           url.Values{"url": {"https://example.com/hook"}},
+			insertErr:      models.ErrDuplicateURL,
+			expectedStatus: http.StatusSeeOther,
+			expectedURL:    "https://example.com/hook",
+			expectedFlash:  "There already is a webhook for https://example.com/hook.",
+		},
+		{
+			name:           "database error",
+			form:           url.Values{"url": {"https://example.com/hook"}},
+			insertErr:      errors.New("database error"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedURL:    "https://example.com/hook",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication()
+			inserted := ""
+			app.webhooks = &testutils.MockWebhookModel{
+				InsertFunc: func(hook *models.Webhook, userId int) error {
+					inserted = hook.URL
+					assert.Equal(t, 1, userId)
+					if tt.insertErr != nil {
+						return tt.insertErr
+					}
+					hook.Secret = "SECRET"
+					return nil
+				},
+			}
+
+			rr := httptest.NewRecorder()
+			r := newImportRequest(t, app, http.MethodPost, "/account/webhooks", "application/x-www-form-urlencoded",
+				bytes.NewBufferString(tt.form.Encode()))
+
+			app.storeWebhook(rr, r)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.Equal(t, tt.expectedURL, inserted)
+			if tt.expectedStatus == http.StatusSeeOther {
+				assert.Equal(t, "/account/webhooks", rr.Header().Get("Location"))
+			}
+			assert.Equal(t, tt.expectedSecret, app.sessionManager.GetString(r.Context(), "webhookSecret"))
+			assert.Equal(t, tt.expectedFlash, app.sessionManager.GetString(r.Context(), "flashText"))
+		})
+	}
+}
+
+func TestDeleteWebhook(t *testing.T) {
+	tests := []struct {
+		name           string
+		id             string
+		deleteErr      error
+		expectedStatus int
+	}{
+		{name: "webhook removed", id: "3", expectedStatus: http.StatusSeeOther},
+		{name: "webhook of another user", id: "4", deleteErr: models.ErrNoRecord, expectedStatus: http.StatusNotFound},
+		{name: "invalid id", id: "abc", expectedStatus: http.StatusNotFound},
+		{name: "database error", id: "3", deleteErr: errors.New("database error"), expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication()
+			app.webhooks = &testutils.MockWebhookModel{
+				DeleteFunc: func(id int, userId int) error {
+					assert.Equal(t, 1, userId)
+					return tt.deleteErr
+				},
+			}
+
+			rr := httptest.NewRecorder()
+			r := newImportRequest(t, app, http.MethodPost, "/account/webhooks/"+tt.id+"/delete", "", &bytes.Buffer{})
+			r = r.WithContext(context.WithValue(r.Context(), httprouter.ParamsKey, httprouter.Params{{Key: "id", Value: tt.id}}))
+
+			app.deleteWebhook(rr, r)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			if tt.expectedStatus == http.StatusSeeOther {
+				assert.Equal(t, "/account/webhooks", rr.Header().Get("Location"))
+				assert.Equal(t, "Webhook removed.", app.sessionManager.GetString(r.Context(), "flashText"))
+			}
+		})
+	}
+}
+
+func TestRedeliverWebhook(t *testing.T) {
+	tests := []struct {
+		name           string
+		id             string
+		redeliverErr   error
+		expectedStatus int
+	}{
+		{name: "delivery sent again", id: "7", expectedStatus: http.StatusSeeOther},
+		{name: "delivery of another user", id: "8", redeliverErr: models.ErrNoRecord, expectedStatus: http.StatusNotFound},
+		{name: "invalid id", id: "0", expectedStatus: http.StatusNotFound},
+		{name: "database error", id: "7", redeliverErr: errors.New("database error"), expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication()
+			app.webhooks = &testutils.MockWebhookModel{
+				RedeliverFunc: func(id int, userId int) error {
+					assert.Equal(t, 1, userId)
+					assert.Equal(t, tt.id, strconv.Itoa(id))
+					return tt.redeliverErr
+				},
+			}
+
+			rr := httptest.NewRecorder()
+			r := newImportRequest(t, app, http.MethodPost, "/account/webhook-deliveries/"+tt.id+"/redeliver", "", &bytes.Buffer{})
+			r = r.WithContext(context.WithValue(r.Context(), httprouter.ParamsKey, httprouter.Params{{Key: "id", Value: tt.id}}))
+
+			app.redeliverWebhook(rr, r)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			if tt.expectedStatus == http.StatusSeeOther {
+				assert.Equal(t, "/account/webhooks", rr.Header().Get("Location"))
+				assert.Equal(t, "The delivery is sent again.", app.sessionManager.GetString(r.Context(), "flashText"))
+			}
+		})
+	}
+}
+
+func TestWebhookURLFromForm(t *testing.T) {
+	tests := []struct {
+		name          string
+		url           string
+		expectedURL   string
+		expectedError string
+	}{
+		{name: "https", url: "https://example.com/hook?team=1", expectedURL: "https://example.com/hook?team=1"},
+		{name: "http with port", url: " http://localhost:8080/swims ", expectedURL: "http://localhost:8080/swims"},
+		{name: "missing url", url: "  ", expectedError: "it needs a URL"},
+		{name: "relative url", url: "/hook", expectedError: `"/hook" is not an http or https URL`},
+		{name: "other scheme", url: "mailto:team@example.com", expectedError: `"mailto:team@example.com" is not an http or https URL`},
+		{name: "url too long", url: "https://example.com/" + strings.Repeat("a", models.MaxWebhookURLLength), expectedError: "the URL is longer than 2000 characters"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, err := webhookURLFromForm(url.Values{"url": {tt.url}})
+
+			if tt.expectedError != "" {
+				assert.EqualError(t, err, tt.expectedError)
+				assert.Empty(t, target)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedURL, target)
+		})
+	}
+}
+
+// enqueued is a webhook delivery queued by webhookSwimModel.
+type enqueued struct {
+	userId  int
+	event   models.WebhookEvent
+	payload webhookPayload
+	body    []byte
+}
+
+func newWebhookSwimModel(t *testing.T, swims models.SwimModel, queued *[]enqueued, enqueueErr error) *webhookSwimModel {
+	hooks := &testutils.MockWebhookModel{
+		EnqueueFunc: func(userId int, event models.WebhookEvent, payload []byte) (int, error) {
+			var decoded webhookPayload
+			assert.NoError(t, json.Unmarshal(payload, &decoded))
+			*queued = append(*queued, enqueued{userId: userId, event: event, payload: decoded, body: payload})
+			return 1, enqueueErr
+		},
+	}
+	return &webhookSwimModel{
+		SwimModel:  swims,
+		webhooks:   hooks,
+		dispatcher: webhooks.NewDispatcher(hooks, http.DefaultClient, testutils.NewTestLogger()),
+		logger:     testutils.NewTestLogger(),
+	}
+}
+
+func TestWebhookSwimModel(t *testing.T) {
+	spec := loadOpenAPISpec(t)
+	payloadSchema := map[string]any{"$ref": "#/components/schemas/WebhookPayload"}
+	date := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	t.Run("insert", func(t *testing.T) {
+		var queued []enqueued
+		swims := &testutils.MockSwimModel{
+			InsertFunc: func(swim *models.Swim, userId int) error {
+				swim.Id = 42
+				return nil
+			},
+		}
+		model := newWebhookSwimModel(t, swims, &queued, nil)
+
+		err := model.Insert(&models.Swim{Date: date, DistanceM: 1500, Stroke: models.StrokeFreestyle, Feel: models.FeelGood, Tags: []string{"lake"}}, 2)
+
+		assert.NoError(t, err)
+		if assert.Len(t, queued, 1) {
+			assert.Equal(t, 2, queued[0].userId)
+			assert.Equal(t, models.EventSwimCreated, queued[0].event)
+			assert.Equal(t, models.EventSwimCreated, queued[0].payload.Event)
+			assert.Equal(t, 42, queued[0].payload.SwimId)
+			if assert.NotNil(t, queued[0].payload.Swim) {
+				assert.Equal(t, apiSwim{Id: 42, Date: "2024-01-15", DistanceM: 1500, Stroke: models.StrokeFreestyle, Feel: models.FeelGood, Tags: []string{"lake"}},
+					*queued[0].payload.Swim, "swims are sent like in the API")
+			}
+			assert.False(t, queued[0].payload.OccurredAt.IsZero())
+			spec.validate(t, payloadSchema, queued[0].body)
+		}
+	})
+
+	t.Run("insert many", func(t *testing.T) {
+		var queued []enqueued
+		swims := &testutils.MockSwimModel{
+			InsertManyFunc: func(swims []*models.Swim, userId int) error {
+				for i, swim := range swims {
+					swim.Id = i + 1
+				}
+				return nil
+			},
+		}
+		model := newWebhookSwimModel(t, swims, &queued, nil)
+
+		err := model.InsertMany([]*models.Swim{{Date: date, DistanceM: 1000}, {Date: date, DistanceM: 2000}}, 2)
+
+		assert.NoError(t, err)
+		if assert.Len(t, queued, 2, "one event for each swim") {
+			assert.Equal(t, 1, queued[0].payload.SwimId)
+			assert.Equal(t, 2, queued[1].payload.SwimId)
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		var queued []enqueued
+		model := newWebhookSwimModel(t, &testutils.MockSwimModel{}, &queued, nil)
+
+		err := model.Update(&models.Swim{Id: 42, Date: date, DistanceM: 1500, Stroke: models.StrokeFreestyle, Feel: models.FeelGood}, 2)
+
+		assert.NoError(t, err)
+		if assert.Len(t, queued, 1) {
+			assert.Equal(t, models.EventSwimUpdated, queued[0].event)
+			assert.Equal(t, 42, queued[0].payload.SwimId)
+			assert.NotNil(t, queued[0].payload.Swim)
+			spec.validate(t, payloadSchema, queued[0].body)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		var queued []enqueued
+		model := newWebhookSwimModel(t, &testutils.MockSwimModel{}, &queued, nil)
+
+		err := model.Delete(42, 2)
+
+		assert.NoError(t, err)
+		if assert.Len(t, queued, 1) {
+			assert.Equal(t, models.EventSwimDeleted, queued[0].event)
+			assert.Equal(t, 42, queued[0].payload.SwimId)
+			assert.Nil(t, queued[0].payload.Swim, "deleted swims are only sent by their id")
+			spec.validate(t, payloadSchema, queued[0].body)
+		}
+	})
+
+	t.Run("failed change", func(t *testing.T) {
+		var queued []enqueued
+		swims := &testutils.MockSwimModel{
+			DeleteFunc: func(id int, userId int) error {
+				return models.ErrNoRecord
+			},
+		}
+		model := newWebhookSwimModel(t, swims, &queued, nil)
+
+		err := model.Delete(42, 2)
+
+		assert.ErrorIs(t, err, models.ErrNoRecord)
+		assert.Empty(t, queued, "nothing changed, so no webhook is called")
+	})
+
+	t.Run("queue error", func(t *testing.T) {
+		var queued []enqueued
+		model := newWebhookSwimModel(t, &testutils.MockSwimModel{}, &queued, errors.New("database error"))
+
+		err := model.Delete(42, 2)
+
+		assert.NoError(t, err, "the swim is deleted regardless")
+		assert.Len(t, queued, 1)
+	})
+
+	t.Run("queue error is not retried", func(t *testing.T) {
+		var queued []enqueued
+		stored := []*models.Swim{{Id: 20, Date: date, DistanceM: 1500}, {Id: 21, Date: date, DistanceM: 800}}
+		model := newWebhookSwimModel(t, &testutils.MockSwimModel{}, &queued, errors.New("database error"))
+
+		err := model.InsertMany(stored, 2)
+		assert.NoError(t, err, "the swims are stored regardless")
+		err = model.Update(stored[0], 2)
+		assert.NoError(t, err)
+
+		// Each event is queued once; the ones that failed are not queued again
+		// with later events.
+		if assert.Len(t, queued, 3) {
+			assert.Equal(t, models.EventSwimCreated, queued[0].event)
+			assert.Equal(t, 20, queued[0].payload.SwimId)
+			assert.Equal(t, models.EventSwimCreated, queued[1].event)
+			assert.Equal(t, 21, queued[1].payload.SwimId)
+			assert.Equal(t, models.EventSwimUpdated, queued[2].event)
+			assert.Equal(t, 20, queued[2].payload.SwimId)
+		}
+	})
+}
+
+func TestWebhookBackupModel(t *testing.T) {
+	date := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	t.Run("restore", func(t *testing.T) {
+		var queued []enqueued
+		backups := &testutils.MockBackupModel{
+			RestoreFunc: func(userId int, backup *models.Backup, mode models.RestoreMode) (*models.Restored, error) {
+				return &models.Restored{
+					Swims:      []*models.Swim{{Id: 20, Date: date, DistanceM: 1500}, {Id: 21, Date: date, DistanceM: 800}},
+					DeletedIds: []int{4},
+				}, nil
+			},
+		}
+		model := &webhookBackupModel{BackupModel: backups, swims: newWebhookSwimModel(t, &testutils.MockSwimModel{}, &queued, nil)}
+
+		_, err := model.Restore(2, &models.Backup{}, models.RestoreReplace)
+
+		assert.NoError(t, err)
+		if assert.Len(t, queued, 3, "one event for each deleted and stored swim") {
+			assert.Equal(t, 2, queued[0].userId)
+			assert.Equal(t, models.EventSwimDeleted, queued[0].event)
+			assert.Equal(t, 4, queued[0].payload.SwimId)
+			assert.Equal(t, models.EventSwimCreated, queued[1].event)
+			assert.Equal(t, 20, queued[1].payload.SwimId)
+			assert.NotNil(t, queued[1].payload.Swim)
+			assert.Equal(t, 21, queued[2].payload.SwimId)
+		}
+	})
+
+	t.Run("failed restore", func(t *testing.T) {
+		var queued []enqueued
+		backups := &testutils.MockBackupModel{
+			RestoreFunc: func(userId int, backup *models.Backup, mode models.RestoreMode) (*models.Restored, error) {
+				return nil, errors.New("database error")
+			},
+		}
+		model := &webhookBackupModel{BackupModel: backups, swims: newWebhookSwimModel(t, &testutils.MockSwimModel{}, &queued, nil)}
+
+		_, err := model.Restore(2, &models.Backup{}, models.RestoreMerge)
+
+		assert.Error(t, err)
+		assert.Empty(t, queued, "the restore was rolled back, so no webhook is called")
+	})
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	type request struct {
+		header http.Header
+		body   []byte
+	}
+	received := make(chan request, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- request{header: r.Header, body: body}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer receiver.Close()
+
+	// The webhook model keeps the queued deliveries in memory
+	var pending []*models.WebhookDelivery
+	var recorded []*models.WebhookDelivery
+	hooks := &testutils.MockWebhookModel{
+		EnqueueFunc: func(userId int, event models.WebhookEvent, payload []byte) (int, error) {
+			pending = append(pending, &models.WebhookDelivery{
+				Id: len(pending) + 1, URL: receiver.URL, Secret: "SECRET", Event: event, Payload: payload, State: models.DeliveryPending,
+			})
+			return 1, nil
+		},
+		DueFunc: func(limit int, lease time.Duration) ([]*models.WebhookDelivery, error) {
+			due := pending
+			pending = nil
+			return due, nil
+		},
+		RecordFunc: func(delivery *models.WebhookDelivery) error {
+			recorded = append(recorded, delivery)
+			return nil
+		},
+	}
+	app := newTestApplication()
+	app.dispatcher = webhooks.NewDispatcher(hooks, receiver.Client(), app.logger)
+	app.swims = &webhookSwimModel{
+		SwimModel: &testutils.MockSwimModel{
+			InsertFunc: func(swim *models.Swim, userId int) error {
+				swim.Id = 42
+				return nil
+			},
+		},
+		webhooks:   hooks,
+		dispatcher: app.dispatcher,
+		logger:     app.logger,
+	}
+
+	rr := httptest.NewRecorder()
+	r := newAPIRequest(t, app, http.MethodPost, "/api/v1/swims", "",
+		`{"date": "2024-01-15", "distance_m": 1500, "feel": 4}`)
+	app.apiStoreSwim(rr, r)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+
+	sent := app.dispatcher.DispatchDue(context.Background())
+
+	assert.Equal(t, 1, sent)
+	select {
+	case req := <-received:
+		assert.Equal(t, "swim.created", req.header.Get(webhooks.EventHeader))
+		assert.True(t, webhooks.Verify("SECRET", req.body, req.header.Get(webhooks.SignatureHeader)), "the payload is signed with the secret")
+		var payload webhookPayload
+		assert.NoError(t, json.Unmarshal(req.body, &payload))
+		assert.Equal(t, 42, payload.SwimId)
+	default:
+		t.Fatal("the receiver was not called")
+	}
+	if assert.Len(t, recorded, 1) {
+		assert.Equal(t, models.DeliveryDelivered, recorded[0].State)
+		assert.Equal(t, http.StatusAccepted, recorded[0].StatusCode)
+	}
+}
@@ -0,0 +1,288 @@
+// Package backup writes and reads the JSON document that holds all data of an
+// account, so accounts can be moved between SwimMate instances. The format is
+// described in docs/backup-format.md.
+package backup
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rockstaedt/swimmate/internal/models"
+)
+
+const (
+	// Format identifies SwimMate backups among other JSON documents.
+	Format = "swimmate-backup"
+	// Version is the version of the format written by Encode. It is raised
+	// whenever a change would make older instances misread a document.
+	Version = 1
+)
+
+var ErrUnsupportedVersion = errors.New("backup: unsupported version")
+
+var ErrNotABackup = errors.New("backup: not a SwimMate backup")
+
+type document struct {
+	Format     string     `json:"format"`
+	Version    int        `json:"version"`
+	ExportedAt time.Time  `json:"exported_at"`
+	Profile    profile    `json:"profile"`
+	Locations  []location `json:"locations"`
+	Swims      []swim     `json:"swims"`
+}
+
+type profile struct {
+	FirstName    string      `json:"first_name"`
+	LastName     string      `json:"last_name"`
+	Username     string      `json:"username"`
+	Email        string      `json:"email"`
+	DateJoined   time.Time   `json:"date_joined"`
+	LastLogin    time.Time   `json:"last_login,omitzero"`
+	DistanceUnit models.Unit `json:"distance_unit"`
+}
+
+type location struct {
+	Name       string      `json:"name"`
+	PoolLength int         `json:"pool_length,omitempty"`
+	PoolUnit   models.Unit `json:"pool_unit,omitempty"`
+	Indoor     bool        `json:"indoor"`
+}
+
+type swim struct {
+	Date       string        `json:"date"`
+	DistanceM  int           `json:"distance_m"`
+	Stroke     models.Stroke `json:"stroke"`
+	Feel       models.Feel   `json:"feel"`
+	Effort     models.Effort `json:"effort,omitempty"`
+	DurationS  int           `json:"duration_s,omitempty"`
+	PoolLength int           `json:"pool_length,omitempty"`
+	PoolUnit   models.Unit   `json:"pool_unit,omitempty"`
+	Laps       int           `json:"laps,omitempty"`
+	Notes      string        `json:"notes,omitempty"`
+	Location   string        `json:"location,omitempty"`
+	Tags       []string      `json:"tags,omitempty"`
+	Sets       []set         `json:"sets,omitempty"`
+	Source     string        `json:"source,omitempty"`
+}
+
+type set struct {
+	Repetitions int           `json:"repetitions"`
+	DistanceM   int           `json:"distance_m"`
+	Stroke      models.Stroke `json:"stroke"`
+	IntervalS   int           `json:"interval_s,omitempty"`
+	RestS       int           `json:"rest_s,omitempty"`
+}
+
+// Encode writes a backup as an indented JSON document.
+func Encode(w io.Writer, b *models.Backup, exportedAt time.Time) error {
+	doc := document{
+		Format:     Format,
+		Version:    Version,
+		ExportedAt: exportedAt.UTC(),
+		Profile: profile{
+			FirstName:    b.User.FirstName,
+			LastName:     b.User.LastName,
+			Username:     b.User.Username,
+			Email:        b.User.Email,
+			DateJoined:   b.User.DateJoined.UTC(),
+			LastLogin:    b.User.LastLogin.UTC(),
+			DistanceUnit: b.User.DistanceUnit,
+		},
+		Locations: make([]location, len(b.Locations)),
+		Swims:     make([]swim, len(b.Swims)),
+	}
+
+	locationNames := make(map[int]string, len(b.Locations))
+	for i, l := range b.Locations {
+		locationNames[l.Id] = l.Name
+		doc.Locations[i] = location{Name: l.Name, PoolLength: l.Pool.Length, PoolUnit: l.Pool.Unit, Indoor: l.Indoor}
+	}
+
+	for i, s := range b.Swims {
+		doc.Swims[i] = swim{
+			Date:       s.Date.Format("2006-01-02"),
+			DistanceM:  s.DistanceM,
+			Stroke:     s.Stroke,
+			Feel:       s.Feel,
+			Effort:     s.Effort,
+			DurationS:  int(s.Duration / time.Second),
+			PoolLength: s.Pool.Length,
+			PoolUnit:   s.Pool.Unit,
+			Laps:       s.Laps,
+			Notes:      s.Notes,
+			Location:   locationNames[s.LocationId],
+			Tags:       s.Tags,
+			Source:     s.Source,
+		}
+		for _, st := range s.Sets {
+			doc.Swims[i].Sets = append(doc.Swims[i].Sets, set{
+				Repetitions: st.Repetitions,
+				DistanceM:   st.DistanceM,
+				Stroke:      st.Stroke,
+				IntervalS:   int(st.Interval / time.Second),
+				RestS:       int(st.Rest / time.Second),
+			})
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+// Decode reads a backup written by Encode and checks it, so that a document
+// that passes can be restored without violating any constraint. Locations are
+// numbered from 1 in the order of the document.
+func Decode(r io.Reader) (*models.Backup, error) {
+	var doc document
+	err := json.NewDecoder(r).Decode(&doc)
+	if err != nil {
+		return nil, ErrNotABackup
+	}
+	if doc.Format != Format {
+		return nil, ErrNotABackup
+	}
+	if doc.Version < 1 || doc.Version > Version {
+		return nil, fmt.Errorf("%w %d", ErrUnsupportedVersion, doc.Version)
+	}
+	if !doc.Profile.DistanceUnit.Valid() {
+		return nil, fmt.Errorf("profile: invalid distance unit %q", doc.Profile.DistanceUnit)
+	}
+
+	b := &models.Backup{
+		User: models.User{
+			FirstName:    doc.Profile.FirstName,
+			LastName:     doc.Profile.LastName,
+			Username:     doc.Profile.Username,
+			Email:        doc.Profile.Email,
+			DateJoined:   doc.Profile.DateJoined,
+			LastLogin:    doc.Profile.LastLogin,
+			DistanceUnit: doc.Profile.DistanceUnit,
+		},
+	}
+
+	locationIds := make(map[string]int, len(doc.Locations))
+	for i, l := range doc.Locations {
+		if l.Name == "" || utf8.RuneCountInString(l.Name) > models.MaxLocationNameLength {
+			return nil, fmt.Errorf("location %d: invalid name %q", i+1, l.Name)
+		}
+		if locationIds[l.Name] != 0 {
+			return nil, fmt.Errorf("location %d: duplicate name %q", i+1, l.Name)
+		}
+		pool, err := decodePool(l.PoolLength, l.PoolUnit)
+		if err != nil {
+			return nil, fmt.Errorf("location %d: %w", i+1, err)
+		}
+
+		locationIds[l.Name] = i + 1
+		b.Locations = append(b.Locations, &models.Location{Id: i + 1, Name: l.Name, Pool: pool, Indoor: l.Indoor})
+	}
+
+	sources := make(map[string]bool)
+	for i, s := range doc.Swims {
+		decoded, err := decodeSwim(s, locationIds)
+		if err != nil {
+			return nil, fmt.Errorf("swim %d: %w", i+1, err)
+		}
+		if sources[s.Source] {
+			return nil, fmt.Errorf("swim %d: duplicate source %q", i+1, s.Source)
+		}
+		if s.Source != "" {
+			sources[s.Source] = true
+		}
+		b.Swims = append(b.Swims, decoded)
+	}
+
+	return b, nil
+}
+
+func decodeSwim(s swim, locationIds map[string]int) (*models.Swim, error) {
+	date, err := time.Parse("2006-01-02", s.Date)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date %q", s.Date)
+	}
+	if s.DistanceM <= 0 {
+		return nil, fmt.Errorf("invalid distance %d", s.DistanceM)
+	}
+	if !s.Stroke.Valid() {
+		return nil, fmt.Errorf("invalid stroke %q", s.Stroke)
+	}
+	if !s.Feel.Valid() {
+		return nil, fmt.Errorf("invalid feel %d", s.Feel)
+	}
+	if s.Effort != 0 && !s.Effort.Valid() {
+		return nil, fmt.Errorf("invalid effort %d", s.Effort)
+	}
+	if s.DurationS < 0 || s.Laps < 0 {
+		return nil, errors.New("negative duration or laps")
+	}
+	if utf8.RuneCountInString(s.Notes) > models.MaxNotesLength {
+		return nil, errors.New("notes too long")
+	}
+	if len(s.Source) > models.MaxSourceLength {
+		return nil, errors.New("source too long")
+	}
+
+	pool, err := decodePool(s.PoolLength, s.PoolUnit)
+	if err != nil {
+		return nil, err
+	}
+
+	locationId := 0
+	if s.Location != "" {
+		locationId = locationIds[s.Location]
+		if locationId == 0 {
+			return nil, fmt.Errorf("unknown location %q", s.Location)
+		}
+	}
+
+	for _, tag := range s.Tags {
+		if tag == "" || tag != models.NormalizeTag(tag) || utf8.RuneCountInString(tag) > models.MaxTagLength {
+			return nil, fmt.Errorf("invalid tag %q", tag)
+		}
+	}
+
+	decoded := &models.Swim{
+		Date:       date,
+		DistanceM:  s.DistanceM,
+		Effort:     s.Effort,
+		Feel:       s.Feel,
+		Duration:   time.Duration(s.DurationS) * time.Second,
+		Stroke:     s.Stroke,
+		Pool:       pool,
+		Laps:       s.Laps,
+		Notes:      s.Notes,
+		LocationId: locationId,
+		Tags:       s.Tags,
+		Source:     s.Source,
+	}
+
+	for j, st := range s.Sets {
+		if st.Repetitions <= 0 || st.DistanceM <= 0 || !st.Stroke.Valid() || st.IntervalS < 0 || st.RestS < 0 {
+			return nil, fmt.Errorf("set %d: invalid repetitions, distance, stroke or times", j+1)
+		}
+		decoded.Sets = append(decoded.Sets, models.SwimSet{
+			Repetitions: st.Repetitions,
+			DistanceM:   st.DistanceM,
+			Stroke:      st.Stroke,
+			Interval:    time.Duration(st.IntervalS) * time.Second,
+			Rest:        time.Duration(st.RestS) * time.Second,
+		})
+	}
+
+	return decoded, nil
+}
+
+func decodePool(length int, unit models.Unit) (models.Pool, error) {
+	if length == 0 && unit == "" {
+		return models.Pool{}, nil
+	}
+	if length <= 0 || !unit.Valid() {
+		return models.Pool{}, fmt.Errorf("invalid pool length %d or unit %q", length, unit)
+	}
+	return models.Pool{Length: length, Unit: unit}, nil
+}
@@ -0,0 +1,189 @@
+package backup
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rockstaedt/swimmate/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func testBackup() *models.Backup {
+	return &models.Backup{
+		User: models.User{
+			FirstName:    "Jane",
+			LastName:     "Doe",
+			Username:     "jane",
+			Email:        "jane@example.com",
+			DateJoined:   time.Date(2020, 3, 1, 9, 30, 0, 0, time.UTC),
+			LastLogin:    time.Date(2024, 1, 20, 18, 0, 0, 0, time.UTC),
+			DistanceUnit: models.UnitYards,
+		},
+		Locations: []*models.Location{
+			{Id: 1, Name: "City Pool", Pool: models.Pool{Length: 50, Unit: models.UnitMeters}, Indoor: true},
+			{Id: 2, Name: "Lake"},
+		},
+		Swims: []*models.Swim{
+			{
+				Date:       time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+				DistanceM:  1500,
+				Effort:     7,
+				Feel:       models.FeelGood,
+				Duration:   30 * time.Minute,
+				Stroke:     models.StrokeMixed,
+				Pool:       models.Pool{Length: 50, Unit: models.UnitMeters},
+				Laps:       30,
+				Notes:      "Cold, windy",
+				LocationId: 1,
+				Tags:       []string{"open water", "technique"},
+				Sets: []models.SwimSet{
+					{Repetitions: 1, DistanceM: 300, Stroke: models.StrokeFreestyle},
+					{Repetitions: 12, DistanceM: 100, Stroke: models.StrokeMixed, Interval: 105 * time.Second, Rest: 15 * time.Second},
+				},
+			},
+			{
+				Date:      time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+				DistanceM: 1500,
+				Feel:      models.FeelOkay,
+				Stroke:    models.StrokeFreestyle,
+				Source:    "fit:3912345678:1074160800",
+			},
+			{
+				Date:       time.Date(2024, 1, 17, 0, 0, 0, 0, time.UTC),
+				DistanceM:  800,
+				Feel:       models.FeelTerrible,
+				Stroke:     models.StrokeBreaststroke,
+				LocationId: 2,
+			},
+		},
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	err := Encode(&buf, testBackup(), time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+
+	decoded, err := Decode(&buf)
+
+	assert.NoError(t, err)
+	assert.Equal(t, testBackup(), decoded)
+}
+
+func TestRoundTripEmptyAccount(t *testing.T) {
+	b := &models.Backup{User: models.User{Username: "new", DateJoined: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), DistanceUnit: models.UnitMeters}}
+
+	var buf bytes.Buffer
+	err := Encode(&buf, b, time.Now())
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), `"swims": []`)
+	assert.NotContains(t, buf.String(), "last_login")
+
+	decoded, err := Decode(&buf)
+
+	assert.NoError(t, err)
+	assert.Equal(t, b, decoded)
+}
+
+func TestEncode(t *testing.T) {
+	var buf bytes.Buffer
+	err := Encode(&buf, testBackup(), time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC))
+
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), `"format": "swimmate-backup"`)
+	assert.Contains(t, buf.String(), `"version": 1`)
+	assert.Contains(t, buf.String(), `"exported_at": "2024-02-01T12:00:00Z"`)
+	assert.Contains(t, buf.String(), `"date": "2024-01-15"`)
+	assert.Contains(t, buf.String(), `"location": "City Pool"`)
+	assert.NotContains(t, buf.String(), "password")
+}
+
+func TestDecode(t *testing.T) {
+	valid := `{"format": "swimmate-backup", "version": 1, "profile": {"distance_unit": "m"},
+		"locations": [{"name": "City Pool", "pool_length": 25, "pool_unit": "m", "indoor": true}],
+		"swims": [%s]}`
+
+	tests := []struct {
+		name          string
+		input         string
+		expectedError string
+	}{
+		{
+			name:  "minimal swim",
+			input: strings.Replace(valid, "%s", `{"date": "2024-01-15", "distance_m": 1000, "stroke": "freestyle", "feel": 3}`, 1),
+		},
+		{
+			name:          "not JSON",
+			input:         "date,distance\n",
+			expectedError: ErrNotABackup.Error(),
+		},
+		{
+			name:          "other JSON document",
+			input:         `{"swims": []}`,
+			expectedError: ErrNotABackup.Error(),
+		},
+		{
+			name:          "newer version",
+			input:         `{"format": "swimmate-backup", "version": 2}`,
+			expectedError: "backup: unsupported version 2",
+		},
+		{
+			name:          "unknown location",
+			input:         strings.Replace(valid, "%s", `{"date": "2024-01-15", "distance_m": 1000, "stroke": "freestyle", "feel": 3, "location": "Lake"}`, 1),
+			expectedError: `swim 1: unknown location "Lake"`,
+		},
+		{
+			name:          "invalid feel",
+			input:         strings.Replace(valid, "%s", `{"date": "2024-01-15", "distance_m": 1000, "stroke": "freestyle", "feel": 0}`, 1),
+			expectedError: "swim 1: invalid feel 0",
+		},
+		{
+			name:          "invalid date",
+			input:         strings.Replace(valid, "%s", `{"date": "15.01.2024", "distance_m": 1000, "stroke": "freestyle", "feel": 3}`, 1),
+			expectedError: `swim 1: invalid date "15.01.2024"`,
+		},
+		{
+			name:          "tag not normalized",
+			input:         strings.Replace(valid, "%s", `{"date": "2024-01-15", "distance_m": 1000, "stroke": "freestyle", "feel": 3, "tags": ["Open Water"]}`, 1),
+			expectedError: `swim 1: invalid tag "Open Water"`,
+		},
+		{
+			name:          "invalid set",
+			input:         strings.Replace(valid, "%s", `{"date": "2024-01-15", "distance_m": 1000, "stroke": "freestyle", "feel": 3, "sets": [{"repetitions": 0, "distance_m": 100, "stroke": "freestyle"}]}`, 1),
+			expectedError: "swim 1: set 1: invalid repetitions, distance, stroke or times",
+		},
+		{
+			name:          "duplicate source",
+			input:         strings.Replace(valid, "%s", `{"date": "2024-01-15", "distance_m": 1000, "stroke": "freestyle", "feel": 3, "source": "fit:1:2"}, {"date": "2024-01-16", "distance_m": 1000, "stroke": "freestyle", "feel": 3, "source": "fit:1:2"}`, 1),
+			expectedError: `swim 2: duplicate source "fit:1:2"`,
+		},
+		{
+			name: "duplicate location",
+			input: `{"format": "swimmate-backup", "version": 1, "profile": {"distance_unit": "m"},
+				"locations": [{"name": "Lake"}, {"name": "Lake"}]}`,
+			expectedError: `location 2: duplicate name "Lake"`,
+		},
+		{
+			name: "pool without unit",
+			input: `{"format": "swimmate-backup", "version": 1, "profile": {"distance_unit": "m"},
+				"locations": [{"name": "Lake", "pool_length": 50}]}`,
+			expectedError: `location 1: invalid pool length 50 or unit ""`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := Decode(strings.NewReader(tt.input))
+
+			if tt.expectedError != "" {
+				assert.EqualError(t, err, tt.expectedError)
+				assert.Nil(t, b)
+			} else {
+				assert.NoError(t, err)
+				assert.Len(t, b.Swims, 1)
+			}
+		})
+	}
+}
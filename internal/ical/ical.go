@@ -0,0 +1,139 @@
+// Package ical writes the swims of a user as an iCalendar feed (RFC 5545),
+// which calendar apps subscribe to by its URL.
+package ical
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rockstaedt/swimmate/internal/models"
+)
+
+const (
+	prodID = "-//SwimMate//Swims//EN"
+	// refreshInterval is how often calendar apps are asked to fetch the feed
+	// again.
+	refreshInterval = "PT1H"
+	// maxLineLength is the length in octets after which content lines are
+	// folded.
+	maxLineLength = 75
+)
+
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// Calendar is the feed of a user.
+type Calendar struct {
+	Name string
+	// Domain makes the UIDs of the events globally unique. It must not
+	// change, or calendar apps show each swim twice.
+	Domain string
+	// Unit is the unit distances and paces are given in.
+	Unit  models.Unit
+	Swims []*models.Swim
+}
+
+// Encode writes a calendar with an all-day event for each swim. The events
+// are stamped with the time the feed was generated.
+func Encode(w io.Writer, c Calendar, stamp time.Time) error {
+	e := &encoder{w: w}
+
+	e.line("BEGIN", "VCALENDAR")
+	e.line("VERSION", "2.0")
+	e.line("PRODID", prodID)
+	e.line("CALSCALE", "GREGORIAN")
+	e.line("METHOD", "PUBLISH")
+	e.line("X-WR-CALNAME", text(c.Name))
+	e.line("REFRESH-INTERVAL;VALUE=DURATION", refreshInterval)
+	e.line("X-PUBLISHED-TTL", refreshInterval)
+
+	for _, swim := range c.Swims {
+		e.line("BEGIN", "VEVENT")
+		e.line("UID", fmt.Sprintf("swim-%d@%s", swim.Id, c.Domain))
+		e.line("DTSTAMP", stamp.UTC().Format("20060102T150405Z"))
+		e.line("DTSTART;VALUE=DATE", swim.Date.Format("20060102"))
+		e.line("DTEND;VALUE=DATE", swim.Date.AddDate(0, 0, 1).Format("20060102"))
+		e.line("SUMMARY", text(summary(swim, c.Unit)))
+		e.line("DESCRIPTION", text(description(swim, c.Unit)))
+		// Swims do not mark the whole day as busy
+		e.line("TRANSP", "TRANSPARENT")
+		e.line("END", "VEVENT")
+	}
+
+	e.line("END", "VCALENDAR")
+
+	return e.err
+}
+
+// summary is the title of the event of a swim, e.g. "Swim 1500 m · Good ·
+// RPE 7".
+func summary(swim *models.Swim, unit models.Unit) string {
+	parts := []string{
+		fmt.Sprintf("Swim %d %s", unit.FromMeters(swim.DistanceM), unit),
+		swim.Feel.Label(),
+	}
+	if swim.Effort.Valid() {
+		parts = append(parts, fmt.Sprintf("RPE %d", swim.Effort))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// description lists the details of a swim, followed by its notes.
+func description(swim *models.Swim, unit models.Unit) string {
+	var lines []string
+	if swim.Duration > 0 {
+		lines = append(lines, fmt.Sprintf("Duration: %s", models.Clock(swim.Duration)))
+		lines = append(lines, fmt.Sprintf("Pace: %s /100 %s", models.Clock(unit.Pace(swim.Pace())), unit))
+	}
+	lines = append(lines, fmt.Sprintf("Stroke: %s", swim.Stroke.Label()))
+	if !swim.Pool.IsZero() {
+		lines = append(lines, fmt.Sprintf("Pool: %s", swim.Pool.Label()))
+	}
+	if swim.Effort.Valid() {
+		lines = append(lines, fmt.Sprintf("Effort: %d (%s)", swim.Effort, swim.Effort.Label()))
+	}
+	if len(swim.Tags) > 0 {
+		lines = append(lines, fmt.Sprintf("Tags: %s", strings.Join(swim.Tags, ", ")))
+	}
+	if swim.Notes != "" {
+		lines = append(lines, "", swim.Notes)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// text escapes a value of the TEXT type.
+func text(value string) string {
+	return textEscaper.Replace(value)
+}
+
+// encoder writes content lines and keeps the first error.
+type encoder struct {
+	w   io.Writer
+	err error
+}
+
+// line writes a content line, folded into lines of at most 75 octets without
+// splitting a UTF-8 character.
+func (e *encoder) line(name string, value string) {
+	if e.err != nil {
+		return
+	}
+
+	content := name + ":" + value
+	var b strings.Builder
+	for limit := maxLineLength; len(content) > limit; limit = maxLineLength - 1 {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(content[cut]) {
+			cut--
+		}
+		b.WriteString(content[:cut])
+		b.WriteString("\r\n ")
+		content = content[cut:]
+	}
+	b.WriteString(content)
+	b.WriteString("\r\n")
+
+	_, e.err = io.WriteString(e.w, b.String())
+}
@@ -0,0 +1,134 @@
+package ical
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rockstaedt/swimmate/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+var stamp = time.Date(2024, 2, 1, 12, 30, 0, 0, time.FixedZone("CET", 3600))
+
+// unfold joins folded content lines again, as calendar apps do.
+func unfold(feed string) []string {
+	return strings.Split(strings.TrimSuffix(strings.ReplaceAll(feed, "\r\n ", ""), "\r\n"), "\r\n")
+}
+
+func TestEncode(t *testing.T) {
+	var buf bytes.Buffer
+	err := Encode(&buf, Calendar{
+		Name:   "Jane's swims",
+		Domain: "swimmate.example.com",
+		Unit:   models.UnitMeters,
+		Swims: []*models.Swim{
+			{
+				Id:        12,
+				Date:      time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC),
+				DistanceM: 1500,
+				Effort:    7,
+				Feel:      models.FeelGood,
+				Duration:  30 * time.Minute,
+				Stroke:    models.StrokeFreestyle,
+				Pool:      models.Pool{Length: 25, Unit: models.UnitMeters},
+				Notes:     "Cold; windy, but fine\nNew goggles",
+				Tags:      []string{"open water", "technique"},
+			},
+			{
+				Id:        13,
+				Date:      time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+				DistanceM: 800,
+				Feel:      models.FeelOkay,
+				Stroke:    models.StrokeBreaststroke,
+			},
+		},
+	}, stamp)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//SwimMate//Swims//EN",
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+		"X-WR-CALNAME:Jane's swims",
+		"REFRESH-INTERVAL;VALUE=DURATION:PT1H",
+		"X-PUBLISHED-TTL:PT1H",
+		"BEGIN:VEVENT",
+		"UID:swim-12@swimmate.example.com",
+		"DTSTAMP:20240201T113000Z",
+		"DTSTART;VALUE=DATE:20240131",
+		"DTEND;VALUE=DATE:20240201",
+		"SUMMARY:Swim 1500 m · Good · RPE 7",
+		`DESCRIPTION:Duration: 30:00\nPace: 2:00 /100 m\nStroke: Freestyle\nPool: 25 m\nEffort: 7 (Hard)\nTags: open water\, technique\n\nCold\; windy\, but fine\nNew goggles`,
+		"TRANSP:TRANSPARENT",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:swim-13@swimmate.example.com",
+		"DTSTAMP:20240201T113000Z",
+		"DTSTART;VALUE=DATE:20240201",
+		"DTEND;VALUE=DATE:20240202",
+		"SUMMARY:Swim 800 m · Okay",
+		"DESCRIPTION:Stroke: Breaststroke",
+		"TRANSP:TRANSPARENT",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, unfold(buf.String()))
+}
+
+func TestEncodeInYards(t *testing.T) {
+	var buf bytes.Buffer
+	err := Encode(&buf, Calendar{
+		Unit:  models.UnitYards,
+		Swims: []*models.Swim{{Id: 1, Date: time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC), DistanceM: 1463, Feel: models.FeelGreat, Duration: 32 * time.Minute, Stroke: models.StrokeFreestyle}},
+	}, stamp)
+
+	assert.NoError(t, err)
+	lines := unfold(buf.String())
+	assert.Contains(t, lines, "SUMMARY:Swim 1600 yd · Great")
+	assert.Contains(t, lines, `DESCRIPTION:Duration: 32:00\nPace: 2:00 /100 yd\nStroke: Freestyle`)
+}
+
+func TestEncodeWithoutSwims(t *testing.T) {
+	var buf bytes.Buffer
+	err := Encode(&buf, Calendar{Name: "Swims"}, stamp)
+
+	assert.NoError(t, err)
+	assert.NotContains(t, buf.String(), "VEVENT")
+	assert.True(t, strings.HasSuffix(buf.String(), "END:VCALENDAR\r\n"))
+}
+
+func TestEncodeFoldsLongLines(t *testing.T) {
+	notes := strings.Repeat("Schwimmen im Freibad, ", 10) + "äöü"
+
+	var buf bytes.Buffer
+	err := Encode(&buf, Calendar{
+		Swims: []*models.Swim{{Id: 1, Date: time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC), DistanceM: 1000, Feel: models.FeelOkay, Stroke: models.StrokeMixed, Notes: notes}},
+	}, stamp)
+
+	assert.NoError(t, err)
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n") {
+		assert.LessOrEqual(t, len(line), 75, "line %q is not folded", line)
+		assert.True(t, strings.ToValidUTF8(line, "") == line, "line %q splits a character", line)
+	}
+	assert.Contains(t, unfold(buf.String()), `DESCRIPTION:Stroke: Mixed\n\n`+text(notes))
+}
+
+func TestText(t *testing.T) {
+	assert.Equal(t, `a\\b\;c\,d\ne\nf`, text("a\\b;c,d\ne\r\nf"))
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("connection closed")
+}
+
+func TestEncodeWriteError(t *testing.T) {
+	err := Encode(failingWriter{}, Calendar{}, stamp)
+
+	assert.EqualError(t, err, "connection closed")
+}
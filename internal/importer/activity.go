@@ -0,0 +1,93 @@
+package importer
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/rockstaedt/swimmate/internal/models"
+)
+
+// MaxActivitySize is the largest activity file that is read, after it was
+// decompressed. A FIT file of a pool swim has a few hundred kilobytes, GPX
+// and TCX files of long open water swims a few megabytes.
+const MaxActivitySize = 32 << 20
+
+var ErrActivityTooLarge = fmt.Errorf("importer: activity file larger than %d MB", MaxActivitySize>>20)
+
+// ParseActivity reads the swims of an activity file by the extension of its
+// name. FIT, TCX and GPX files are supported, also when compressed with
+// gzip as in export archives. Files that are larger than MaxActivitySize
+// once decompressed return ErrActivityTooLarge, so that a small upload
+// cannot unpack into more than the server's memory. Tracks of GPX and TCX
+// files without a date or distance are left out and counted in the returned
+// number of failed activities.
+func ParseActivity(name string, r io.Reader) ([]*models.Swim, int, error) {
+	ext := strings.ToLower(path.Ext(name))
+	if ext == ".gz" {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, 0, err
+		}
+		defer func() {
+			_ = gz.Close()
+		}()
+
+		r = gz
+		ext = strings.ToLower(path.Ext(strings.TrimSuffix(name, path.Ext(name))))
+	}
+
+	var parse func(io.Reader) ([]*models.Swim, int, error)
+	switch ext {
+	case ".fit":
+		parse = func(r io.Reader) ([]*models.Swim, int, error) {
+			swims, err := ParseFIT(r)
+			return swims, 0, err
+		}
+	case ".tcx":
+		parse = ParseTCX
+	case ".gpx":
+		parse = ParseGPX
+	default:
+		return nil, 0, fmt.Errorf("unsupported file type %q", path.Ext(name))
+	}
+
+	limited := &sizeLimitedReader{r: r, left: MaxActivitySize}
+	swims, failed, err := parse(limited)
+	if limited.exceeded() {
+		// The parsers wrap or replace read errors
+		return nil, 0, ErrActivityTooLarge
+	}
+	return swims, failed, err
+}
+
+// sizeLimitedReader reads up to left bytes from r and fails once there is
+// more. Unlike io.LimitReader it does not end the file early, which the
+// parsers could take for a complete file.
+type sizeLimitedReader struct {
+	r    io.Reader
+	left int64
+}
+
+func (l *sizeLimitedReader) Read(p []byte) (int, error) {
+	if l.exceeded() {
+		return 0, ErrActivityTooLarge
+	}
+
+	// One byte more than left tells whether the file goes on
+	if int64(len(p)) > l.left+1 {
+		p = p[:l.left+1]
+	}
+	n, err := l.r.Read(p)
+	l.left -= int64(n)
+	if l.exceeded() {
+		return n, ErrActivityTooLarge
+	}
+	return n, err
+}
+
+func (l *sizeLimitedReader) exceeded() bool {
+	return l.left < 0
+}
@@ -0,0 +1,109 @@
+package importer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testGPX = `<gpx><trk><type>swimming</type><trkseg>
+	<trkpt lat="0" lon="0"><time>2024-07-01T08:00:00Z</time></trkpt>
+	<trkpt lat="0.01" lon="0"><time>2024-07-01T08:25:00Z</time></trkpt>
+</trkseg></trk></gpx>`
+
+func gzipped(t *testing.T, content []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, err := gz.Write(content)
+	assert.NoError(t, err)
+	assert.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func TestParseActivity(t *testing.T) {
+	start := time.Date(2024, 1, 15, 8, 0, 0, 0, time.UTC)
+	fit := encodeFIT(testFITMessage{global: fitSession, fields: []testFITField{
+		{num: fitFieldStartTime, baseType: testUint32, value: fitTime(start)},
+		{num: fitFieldDistance, baseType: testUint32, value: 100000},
+	}})
+
+	tests := []struct {
+		name           string
+		file           string
+		content        []byte
+		expectedSource string
+	}{
+		{"FIT", "Swim.FIT", fit, "fit:0:1074240000"},
+		{"gzipped FIT", "activities/123.fit.gz", gzipped(t, fit), "fit:0:1074240000"},
+		{"GPX", "lake.gpx", []byte(testGPX), "gpx:1719820800"},
+		{"gzipped GPX", "lake.GPX.GZ", gzipped(t, []byte(testGPX)), "gpx:1719820800"},
+		{"TCX", "pool.tcx", []byte(`<TrainingCenterDatabase><Activities><Activity Sport="Other">
+			<Id>2024-07-01T08:00:00Z</Id><Lap><DistanceMeters>1000</DistanceMeters></Lap>
+		</Activity></Activities></TrainingCenterDatabase>`), "tcx:1719820800"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			swims, failed, err := ParseActivity(tt.file, bytes.NewReader(tt.content))
+
+			assert.NoError(t, err)
+			assert.Len(t, swims, 1)
+			assert.Equal(t, tt.expectedSource, swims[0].Source)
+			assert.Equal(t, 0, failed)
+		})
+	}
+}
+
+func TestParseActivityTooLarge(t *testing.T) {
+	for _, name := range []string{"activities/123.fit.gz", "activities/123.gpx.gz", "lake.tcx"} {
+		t.Run(name, func(t *testing.T) {
+			content := make([]byte, MaxActivitySize+1)
+			if strings.HasSuffix(name, ".gz") {
+				// A few kilobytes that unpack to more than the limit
+				content = gzipped(t, content)
+			}
+
+			swims, _, err := ParseActivity(name, bytes.NewReader(content))
+
+			assert.ErrorIs(t, err, ErrActivityTooLarge)
+			assert.Nil(t, swims)
+		})
+	}
+
+	t.Run("at the limit", func(t *testing.T) {
+		content := append([]byte(testGPX), bytes.Repeat([]byte(" "), MaxActivitySize-len(testGPX))...)
+
+		swims, _, err := ParseActivity("lake.gpx.gz", bytes.NewReader(gzipped(t, content)))
+
+		assert.NoError(t, err)
+		assert.Len(t, swims, 1)
+	})
+}
+
+func TestParseActivityInvalidFiles(t *testing.T) {
+	tests := map[string]struct {
+		file          string
+		content       []byte
+		expectedError string
+	}{
+		"unsupported":           {"swims.xlsx", nil, `unsupported file type ".xlsx"`},
+		"unsupported gzip":      {"activities/123.csv.gz", gzipped(t, []byte("a,b")), `unsupported file type ".gz"`},
+		"not gzipped":           {"activities/123.fit.gz", []byte(testGPX), "gzip: invalid header"},
+		"content of other type": {"lake.fit", []byte(testGPX), ErrInvalidFIT.Error()},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			swims, _, err := ParseActivity(tt.file, strings.NewReader(string(tt.content)))
+
+			assert.ErrorContains(t, err, tt.expectedError)
+			assert.Nil(t, swims)
+		})
+	}
+}
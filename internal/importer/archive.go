@@ -0,0 +1,221 @@
+package importer
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rockstaedt/swimmate/internal/models"
+)
+
+// archiveIndex is the name of the file listing the activities of an export
+// archive.
+const archiveIndex = "activities.csv"
+
+var ErrNoArchiveIndex = fmt.Errorf("importer: archive without %s", archiveIndex)
+
+// archiveDateLayouts are the formats of the activity dates in the index,
+// which depend on the language of the account.
+var archiveDateLayouts = []string{
+	"Jan 2, 2006, 3:04:05 PM",
+	"2 Jan 2006, 15:04:05",
+	"2006-01-02 15:04:05",
+}
+
+// ArchiveActivity is an activity listed in the index of an export archive.
+type ArchiveActivity struct {
+	Id string
+	// Start is the start of the activity in UTC.
+	Start     time.Time
+	Elapsed   time.Duration
+	DistanceM float64
+	// File is the path of the activity file within the archive, empty for
+	// activities that were entered by hand.
+	File string
+}
+
+// Archive is an account export ZIP as offered by Strava and similar
+// platforms, with an activities.csv index next to a folder of activity
+// files.
+type Archive struct {
+	zip *zip.Reader
+	// dir is the folder of the index, which file paths are relative to.
+	dir string
+}
+
+func NewArchive(r io.ReaderAt, size int64) (*Archive, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &Archive{zip: zr, dir: "."}
+	found := false
+	for _, f := range zr.File {
+		dir, name := path.Split(f.Name)
+		if strings.EqualFold(name, archiveIndex) && (!found || len(dir) < len(a.dir)) {
+			a.dir = path.Clean(dir)
+			found = true
+		}
+	}
+	if !found {
+		return nil, ErrNoArchiveIndex
+	}
+
+	return a, nil
+}
+
+// Swims returns the swim activities of the index in the order they are
+// listed. The order is stable, so an import can be continued by position.
+func (a *Archive) Swims() ([]ArchiveActivity, error) {
+	f, err := a.zip.Open(path.Join(a.dir, archiveIndex))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", archiveIndex, err)
+	}
+	columns := archiveColumns(header)
+	// Older exports only have the distance in kilometers
+	distanceFactor := 1.0
+	if archiveColumnCount(header, "distance") == 1 {
+		distanceFactor = 1000
+	}
+	for _, required := range []string{"activity id", "activity date", "activity type"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("%s: no %q column", archiveIndex, required)
+		}
+	}
+
+	var swims []ArchiveActivity
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", archiveIndex, err)
+		}
+
+		value := func(column string) string {
+			i, ok := columns[column]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+		if !strings.Contains(strings.ToLower(value("activity type")), "swim") {
+			continue
+		}
+
+		activity, err := archiveActivity(value, distanceFactor)
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", archiveIndex, line, err)
+		}
+		swims = append(swims, activity)
+	}
+
+	return swims, nil
+}
+
+// Swim reads an activity of the archive. The details come from its activity
+// file, with the Source of the file so that activities uploaded on their own
+// are not imported twice. Activities without a readable file are taken from
+// the index.
+func (a *Archive) Swim(activity ArchiveActivity) (*models.Swim, error) {
+	if activity.File != "" {
+		swims, _, err := a.parseFile(activity.File)
+		if err == nil && len(swims) > 0 {
+			return swims[0], nil
+		}
+	}
+
+	if activity.DistanceM <= 0 {
+		return nil, fmt.Errorf("activity %s without distance", activity.Id)
+	}
+
+	return &models.Swim{
+		Date:      time.Date(activity.Start.Year(), activity.Start.Month(), activity.Start.Day(), 0, 0, 0, 0, time.UTC),
+		DistanceM: int(math.Round(activity.DistanceM)),
+		Stroke:    models.StrokeFreestyle,
+		Feel:      models.FeelOkay,
+		Duration:  activity.Elapsed,
+		Source:    "archive:" + activity.Id,
+	}, nil
+}
+
+func (a *Archive) parseFile(name string) ([]*models.Swim, int, error) {
+	f, err := a.zip.Open(path.Join(a.dir, name))
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	return ParseActivity(name, f)
+}
+
+// archiveColumns maps the lower case column names of the index to their
+// position. Exports repeat some columns, such as the distance first in the
+// unit of the account and then in meters, so the last one wins.
+func archiveColumns(header []string) map[string]int {
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, bom)))] = i
+	}
+	return columns
+}
+
+func archiveColumnCount(header []string, column string) int {
+	count := 0
+	for _, name := range header {
+		if strings.EqualFold(strings.TrimSpace(name), column) {
+			count++
+		}
+	}
+	return count
+}
+
+func archiveActivity(value func(column string) string, distanceFactor float64) (ArchiveActivity, error) {
+	activity := ArchiveActivity{Id: value("activity id"), File: value("filename")}
+	if activity.Id == "" || len(activity.Id) > models.MaxSourceLength-len("archive:") {
+		return ArchiveActivity{}, errors.New("invalid activity id")
+	}
+
+	var err error
+	for _, layout := range archiveDateLayouts {
+		activity.Start, err = time.Parse(layout, value("activity date"))
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return ArchiveActivity{}, fmt.Errorf("invalid date %q", value("activity date"))
+	}
+
+	if seconds, err := strconv.ParseFloat(value("elapsed time"), 64); err == nil && seconds > 0 {
+		activity.Elapsed = time.Duration(seconds) * time.Second
+	}
+	if distance, err := strconv.ParseFloat(strings.ReplaceAll(value("distance"), ",", ""), 64); err == nil {
+		activity.DistanceM = distance * distanceFactor
+	}
+
+	return activity, nil
+}
@@ -0,0 +1,172 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/rockstaedt/swimmate/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// testArchive writes a ZIP with the given files.
+func testArchive(t *testing.T, files map[string][]byte) *bytes.Reader {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		assert.NoError(t, err)
+		_, err = w.Write(content)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, zw.Close())
+	return bytes.NewReader(buf.Bytes())
+}
+
+func openTestArchive(t *testing.T, files map[string][]byte) *Archive {
+	t.Helper()
+
+	r := testArchive(t, files)
+	archive, err := NewArchive(r, r.Size())
+	assert.NoError(t, err)
+	return archive
+}
+
+const testArchiveIndex = `Activity ID,Activity Date,Activity Name,Activity Type,Activity Description,Elapsed Time,Distance,Filename,Elapsed Time,Moving Time,Distance
+101,"Jan 15, 2024, 7:00:00 AM",Morning Swim,Swim,"Cold water,
+long rest",1800,"1,500",activities/101.fit.gz,1800.0,1700.0,1500.0
+102,"Jan 16, 2024, 6:00:00 PM",Evening Run,Run,,1900,5.0,activities/102.gpx.gz,1900.0,1900.0,5000.0
+103,"Jul 1, 2024, 8:00:00 AM",Lake,Swim,,1500,1.1,activities/103.gpx,1500.0,1500.0,1112.0
+104,"Jul 3, 2024, 11:30:00 PM",Manual entry,Swim,,2400,2.0,,2400.0,2400.0,2000.0
+`
+
+func TestArchiveSwims(t *testing.T) {
+	archive := openTestArchive(t, map[string][]byte{"activities.csv": []byte(testArchiveIndex)})
+
+	swims, err := archive.Swims()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []ArchiveActivity{
+		{Id: "101", Start: time.Date(2024, 1, 15, 7, 0, 0, 0, time.UTC), Elapsed: 30 * time.Minute, DistanceM: 1500, File: "activities/101.fit.gz"},
+		{Id: "103", Start: time.Date(2024, 7, 1, 8, 0, 0, 0, time.UTC), Elapsed: 25 * time.Minute, DistanceM: 1112, File: "activities/103.gpx"},
+		{Id: "104", Start: time.Date(2024, 7, 3, 23, 30, 0, 0, time.UTC), Elapsed: 40 * time.Minute, DistanceM: 2000},
+	}, swims)
+}
+
+func TestArchiveSwimsOfOlderExports(t *testing.T) {
+	archive := openTestArchive(t, map[string][]byte{
+		"export_1234/activities.csv": []byte(bom + "Activity ID,Activity Date,Activity Name,Activity Type,Elapsed Time,Distance,Filename\n" +
+			"7,\"15 Jan 2024, 07:00:00\",Swim,Swim,600,1.25,activities/7.tcx\n"),
+		"export_1234/activities/7.tcx": nil,
+	})
+
+	swims, err := archive.Swims()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []ArchiveActivity{
+		{Id: "7", Start: time.Date(2024, 1, 15, 7, 0, 0, 0, time.UTC), Elapsed: 10 * time.Minute, DistanceM: 1250, File: "activities/7.tcx"},
+	}, swims)
+}
+
+func TestArchiveSwim(t *testing.T) {
+	start := time.Date(2024, 1, 15, 7, 0, 0, 0, time.UTC)
+	fit := encodeFIT(testFITMessage{global: fitSession, fields: []testFITField{
+		{num: fitFieldStartTime, baseType: testUint32, value: fitTime(start)},
+		{num: fitFieldDistance, baseType: testUint32, value: 150000},
+		{num: fitFieldTimerTime, baseType: testUint32, value: 1700000},
+	}})
+	archive := openTestArchive(t, map[string][]byte{
+		"activities.csv":        []byte(testArchiveIndex),
+		"activities/101.fit.gz": gzipped(t, fit),
+		"activities/103.gpx":    []byte("<gpx"),
+	})
+	activities, err := archive.Swims()
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		activity ArchiveActivity
+		expected *models.Swim
+	}{
+		{
+			name:     "from the activity file",
+			activity: activities[0],
+			expected: &models.Swim{
+				Date:      time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+				DistanceM: 1500,
+				Stroke:    models.StrokeFreestyle,
+				Feel:      models.FeelOkay,
+				Duration:  1700 * time.Second,
+				Source:    "fit:0:1074236400",
+			},
+		},
+		{
+			name:     "unreadable activity file",
+			activity: activities[1],
+			expected: &models.Swim{
+				Date:      time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC),
+				DistanceM: 1112,
+				Stroke:    models.StrokeFreestyle,
+				Feel:      models.FeelOkay,
+				Duration:  25 * time.Minute,
+				Source:    "archive:103",
+			},
+		},
+		{
+			name:     "entered by hand",
+			activity: activities[2],
+			expected: &models.Swim{
+				Date:      time.Date(2024, 7, 3, 0, 0, 0, 0, time.UTC),
+				DistanceM: 2000,
+				Stroke:    models.StrokeFreestyle,
+				Feel:      models.FeelOkay,
+				Duration:  40 * time.Minute,
+				Source:    "archive:104",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			swim, err := archive.Swim(tt.activity)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, swim)
+		})
+	}
+
+	_, err = archive.Swim(ArchiveActivity{Id: "105"})
+	assert.EqualError(t, err, "activity 105 without distance")
+}
+
+func TestNewArchiveInvalidFiles(t *testing.T) {
+	r := testArchive(t, map[string][]byte{"activities/1.fit": nil})
+	_, err := NewArchive(r, r.Size())
+	assert.ErrorIs(t, err, ErrNoArchiveIndex)
+
+	_, err = NewArchive(bytes.NewReader([]byte("not a zip")), 9)
+	assert.ErrorIs(t, err, zip.ErrFormat)
+}
+
+func TestArchiveSwimsInvalidIndex(t *testing.T) {
+	tests := map[string]string{
+		"empty":          "",
+		"missing column": "Activity ID,Activity Date,Distance\n1,\"Jan 15, 2024, 7:00:00 AM\",1.5\n",
+		"invalid date":   "Activity ID,Activity Date,Activity Type,Distance\n1,yesterday,Swim,1.5\n",
+		"without id":     "Activity ID,Activity Date,Activity Type,Distance\n,\"Jan 15, 2024, 7:00:00 AM\",Swim,1.5\n",
+	}
+
+	for name, index := range tests {
+		t.Run(name, func(t *testing.T) {
+			archive := openTestArchive(t, map[string][]byte{"activities.csv": []byte(index)})
+
+			swims, err := archive.Swims()
+
+			assert.Error(t, err)
+			assert.Nil(t, swims)
+		})
+	}
+}
@@ -0,0 +1,299 @@
+// Package importer turns files exported by spreadsheets and other tools into
+// swims.
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rockstaedt/swimmate/internal/models"
+)
+
+// MaxCSVRows limits the number of rows read from a single CSV file.
+const MaxCSVRows = 5000
+
+// NoColumn marks a field of a CSVMapping that is not read from the file.
+const NoColumn = -1
+
+var ErrTooManyRows = fmt.Errorf("importer: more than %d rows", MaxCSVRows)
+
+// bom is the byte order mark spreadsheet applications put in front of UTF-8
+// files.
+const bom = "\ufeff"
+
+// dateLayouts are the accepted date formats, ISO first.
+var dateLayouts = []string{"2006-01-02", "02.01.2006", "2006/01/02"}
+
+// CSVMapping assigns the columns of a CSV file to swim fields by index.
+type CSVMapping struct {
+	// Header tells that the first row holds column names instead of a swim.
+	Header   bool
+	Date     int
+	Distance int
+	// Unit is the unit of the distance column.
+	Unit models.Unit
+	// Feel is read on the 1–5 feel scale, Assessment on the former 0–2
+	// scale. At most one of them can be mapped, swims without either feel
+	// okay.
+	Feel       int
+	Assessment int
+	Effort     int
+	Notes      int
+}
+
+// Validate checks that the mapping reads the required fields from columns
+// that exist.
+func (m CSVMapping) Validate(columns int) error {
+	if m.Date == NoColumn || m.Distance == NoColumn {
+		return errors.New("date and distance need a column")
+	}
+	if m.Feel != NoColumn && m.Assessment != NoColumn {
+		return errors.New("map either feel or assessment, not both")
+	}
+	if !m.Unit.Valid() {
+		return errors.New("unknown distance unit")
+	}
+	for _, column := range []int{m.Date, m.Distance, m.Feel, m.Assessment, m.Effort, m.Notes} {
+		if column < NoColumn || column >= columns {
+			return fmt.Errorf("column %d does not exist", column+1)
+		}
+	}
+	return nil
+}
+
+// DetectMapping guesses the mapping from the first row of a file. Columns are
+// recognized by name, which also covers files from the CSV export. Without
+// known names the file is read as date, distance and assessment columns
+// without a header.
+func DetectMapping(first []string, unit models.Unit) CSVMapping {
+	m := CSVMapping{
+		Date:       NoColumn,
+		Distance:   NoColumn,
+		Unit:       unit,
+		Feel:       NoColumn,
+		Assessment: NoColumn,
+		Effort:     NoColumn,
+		Notes:      NoColumn,
+	}
+
+	for i, name := range first {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "date", "datum":
+			m.Date = i
+		case "distance", "distanz":
+			m.Distance = i
+		case "distance_m", "distance (m)", "meters":
+			m.Distance = i
+			m.Unit = models.UnitMeters
+		case "distance_yd", "distance (yd)", "yards":
+			m.Distance = i
+			m.Unit = models.UnitYards
+		case "feel":
+			m.Feel = i
+		case "assessment", "rating":
+			m.Assessment = i
+		case "effort", "rpe":
+			m.Effort = i
+		case "notes", "note", "comment":
+			m.Notes = i
+		}
+	}
+
+	if m.Date != NoColumn && m.Distance != NoColumn {
+		m.Header = true
+		if m.Feel != NoColumn {
+			m.Assessment = NoColumn
+		}
+		return m
+	}
+
+	m = CSVMapping{
+		Date:       0,
+		Distance:   1,
+		Unit:       unit,
+		Feel:       NoColumn,
+		Assessment: NoColumn,
+		Effort:     NoColumn,
+		Notes:      NoColumn,
+	}
+	if len(first) > 2 {
+		m.Assessment = 2
+	}
+	return m
+}
+
+// ReadCSV reads all rows of a CSV file. Both comma and semicolon separated
+// files are accepted, as spreadsheets in many locales export the latter. A
+// leading byte order mark is dropped.
+func ReadCSV(r io.Reader) ([][]string, error) {
+	br := bufio.NewReader(r)
+
+	firstLine, err := br.Peek(br.Size())
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		return nil, err
+	}
+	if bytes.HasPrefix(firstLine, []byte(bom)) {
+		_, _ = br.Discard(len(bom))
+		firstLine = firstLine[len(bom):]
+	}
+	if i := bytes.IndexByte(firstLine, '\n'); i >= 0 {
+		firstLine = firstLine[:i]
+	}
+
+	cr := csv.NewReader(br)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	if bytes.Count(firstLine, []byte(";")) > bytes.Count(firstLine, []byte(",")) {
+		cr.Comma = ';'
+	}
+
+	var rows [][]string
+	for {
+		row, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(rows) == MaxCSVRows {
+			return nil, ErrTooManyRows
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// CSVRow is a row of an imported file with the swim read from it.
+type CSVRow struct {
+	// Number is the position of the row in the file, starting at 1.
+	Number int
+	Values []string
+	Swim   *models.Swim
+	// Err tells why the row cannot be imported.
+	Err error
+	// Duplicate is set for rows with the date and distance of an existing
+	// swim or of an earlier row.
+	Duplicate bool
+}
+
+// Importable reports whether the row holds a valid swim that is not known
+// yet.
+func (r CSVRow) Importable() bool {
+	return r.Err == nil && !r.Duplicate
+}
+
+// ParseCSV reads a swim from every row of a file according to the mapping.
+// Invalid rows are kept with their error, so they can be shown to the user.
+func ParseCSV(rows [][]string, m CSVMapping) []CSVRow {
+	var parsed []CSVRow
+	for i, values := range rows {
+		if i == 0 && m.Header {
+			continue
+		}
+
+		swim, err := parseCSVSwim(values, m)
+		parsed = append(parsed, CSVRow{Number: i + 1, Values: values, Swim: swim, Err: err})
+	}
+	return parsed
+}
+
+// MarkDuplicates flags the rows that repeat the date and distance of one of
+// the existing swims or of an earlier row of the file.
+func MarkDuplicates(rows []CSVRow, existing []*models.Swim) {
+	type key struct {
+		date      string
+		distanceM int
+	}
+
+	seen := make(map[key]bool, len(existing)+len(rows))
+	for _, swim := range existing {
+		seen[key{swim.Date.Format("2006-01-02"), swim.DistanceM}] = true
+	}
+
+	for i := range rows {
+		if rows[i].Swim == nil {
+			continue
+		}
+		k := key{rows[i].Swim.Date.Format("2006-01-02"), rows[i].Swim.DistanceM}
+		rows[i].Duplicate = seen[k]
+		seen[k] = true
+	}
+}
+
+func parseCSVSwim(values []string, m CSVMapping) (*models.Swim, error) {
+	value := func(column int) string {
+		if column == NoColumn || column >= len(values) {
+			return ""
+		}
+		return strings.TrimSpace(values[column])
+	}
+
+	date, err := parseCSVDate(value(m.Date))
+	if err != nil {
+		return nil, err
+	}
+
+	distance, err := strconv.ParseFloat(value(m.Distance), 64)
+	if err != nil || distance <= 0 {
+		return nil, fmt.Errorf("invalid distance %q", value(m.Distance))
+	}
+
+	feel := models.FeelOkay
+	if v := value(m.Feel); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || !models.Feel(n).Valid() {
+			return nil, fmt.Errorf("invalid feel %q, expected 1 to 5", v)
+		}
+		feel = models.Feel(n)
+	}
+	if v := value(m.Assessment); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > 2 {
+			return nil, fmt.Errorf("invalid assessment %q, expected 0 to 2", v)
+		}
+		feel = models.FeelFromAssessment(n)
+	}
+
+	var effort models.Effort
+	if v := value(m.Effort); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || !models.Effort(n).Valid() {
+			return nil, fmt.Errorf("invalid effort %q, expected 1 to 10", v)
+		}
+		effort = models.Effort(n)
+	}
+
+	notes := value(m.Notes)
+	if utf8.RuneCountInString(notes) > models.MaxNotesLength {
+		return nil, errors.New("notes too long")
+	}
+
+	return &models.Swim{
+		Date:      date,
+		DistanceM: m.Unit.ToMeters(distance),
+		Stroke:    models.StrokeFreestyle,
+		Effort:    effort,
+		Feel:      feel,
+		Notes:     notes,
+	}, nil
+}
+
+func parseCSVDate(value string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		date, err := time.Parse(layout, value)
+		if err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", value)
+}
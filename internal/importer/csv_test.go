@@ -0,0 +1,194 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rockstaedt/swimmate/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadCSV(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        string
+		expectedRows [][]string
+		expectError  bool
+	}{
+		{
+			name:         "comma separated",
+			input:        "date,distance\n2024-01-15,1500\n",
+			expectedRows: [][]string{{"date", "distance"}, {"2024-01-15", "1500"}},
+		},
+		{
+			name:         "semicolon separated with byte order mark",
+			input:        "\ufeffDatum;Distanz\n15.01.2024;1500\n",
+			expectedRows: [][]string{{"Datum", "Distanz"}, {"15.01.2024", "1500"}},
+		},
+		{
+			name:         "quoted notes with separators",
+			input:        "2024-01-15, 1500, 2, \"cold, windy\"\n",
+			expectedRows: [][]string{{"2024-01-15", "1500", "2", "cold, windy"}},
+		},
+		{
+			name:        "too many rows",
+			input:       strings.Repeat("2024-01-15,1500\n", MaxCSVRows+1),
+			expectError: true,
+		},
+		{
+			name:        "broken quotes",
+			input:       "2024-01-15,\"1500\n",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := ReadCSV(strings.NewReader(tt.input))
+
+			if tt.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedRows, rows)
+			}
+		})
+	}
+}
+
+func TestDetectMapping(t *testing.T) {
+	tests := []struct {
+		name     string
+		first    []string
+		expected CSVMapping
+	}{
+		{
+			name:  "CSV export",
+			first: []string{"date", "distance_m", "stroke", "duration_s", "pool_length", "pool_unit", "laps", "effort", "feel", "notes"},
+			expected: CSVMapping{
+				Header: true, Date: 0, Distance: 1, Unit: models.UnitMeters,
+				Feel: 8, Assessment: NoColumn, Effort: 7, Notes: 9,
+			},
+		},
+		{
+			name:  "named columns in the user's unit",
+			first: []string{"Date", "Distance", "Assessment"},
+			expected: CSVMapping{
+				Header: true, Date: 0, Distance: 1, Unit: models.UnitYards,
+				Feel: NoColumn, Assessment: 2, Effort: NoColumn, Notes: NoColumn,
+			},
+		},
+		{
+			name:  "no header",
+			first: []string{"2024-01-15", "1500", "2"},
+			expected: CSVMapping{
+				Date: 0, Distance: 1, Unit: models.UnitYards,
+				Feel: NoColumn, Assessment: 2, Effort: NoColumn, Notes: NoColumn,
+			},
+		},
+		{
+			name:  "no header without assessment",
+			first: []string{"2024-01-15", "1500"},
+			expected: CSVMapping{
+				Date: 0, Distance: 1, Unit: models.UnitYards,
+				Feel: NoColumn, Assessment: NoColumn, Effort: NoColumn, Notes: NoColumn,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, DetectMapping(tt.first, models.UnitYards))
+		})
+	}
+}
+
+func TestCSVMappingValidate(t *testing.T) {
+	valid := CSVMapping{Date: 0, Distance: 1, Unit: models.UnitMeters, Feel: NoColumn, Assessment: 2, Effort: NoColumn, Notes: NoColumn}
+	assert.NoError(t, valid.Validate(3))
+
+	assert.Error(t, valid.Validate(2), "assessment column missing in file")
+
+	noDate := valid
+	noDate.Date = NoColumn
+	assert.Error(t, noDate.Validate(3))
+
+	both := valid
+	both.Feel = 1
+	assert.Error(t, both.Validate(3))
+
+	noUnit := valid
+	noUnit.Unit = ""
+	assert.Error(t, noUnit.Validate(3))
+}
+
+func TestParseCSV(t *testing.T) {
+	rows := [][]string{
+		{"date", "distance", "assessment", "effort", "notes"},
+		{"2024-01-15", "1500", "2", "7", " Cold "},
+		{"16.01.2024", "1000", "0", "", ""},
+		{"2024-01-17", "800", "", "", ""},
+		{"15/01/2024", "1500", "1", "", ""},
+		{"2024-01-18", "-5", "1", "", ""},
+		{"2024-01-19", "1500", "3", "", ""},
+		{"2024-01-20", "1500", "1", "11", ""},
+		{"2024-01-21"},
+	}
+	m := CSVMapping{Header: true, Date: 0, Distance: 1, Unit: models.UnitYards, Feel: NoColumn, Assessment: 2, Effort: 3, Notes: 4}
+
+	parsed := ParseCSV(rows, m)
+
+	assert.Len(t, parsed, 8)
+
+	assert.Equal(t, 2, parsed[0].Number)
+	assert.NoError(t, parsed[0].Err)
+	assert.Equal(t, &models.Swim{
+		Date:      time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+		DistanceM: 1372,
+		Stroke:    models.StrokeFreestyle,
+		Effort:    7,
+		Feel:      models.FeelGood,
+		Notes:     "Cold",
+	}, parsed[0].Swim)
+
+	assert.NoError(t, parsed[1].Err)
+	assert.Equal(t, time.Date(2024, 1, 16, 0, 0, 0, 0, time.UTC), parsed[1].Swim.Date)
+	assert.Equal(t, models.FeelBad, parsed[1].Swim.Feel)
+	assert.Zero(t, parsed[1].Swim.Effort)
+
+	assert.NoError(t, parsed[2].Err)
+	assert.Equal(t, models.FeelOkay, parsed[2].Swim.Feel)
+
+	assert.EqualError(t, parsed[3].Err, `invalid date "15/01/2024", expected YYYY-MM-DD`)
+	assert.EqualError(t, parsed[4].Err, `invalid distance "-5"`)
+	assert.EqualError(t, parsed[5].Err, `invalid assessment "3", expected 0 to 2`)
+	assert.EqualError(t, parsed[6].Err, `invalid effort "11", expected 1 to 10`)
+	assert.EqualError(t, parsed[7].Err, `invalid distance ""`)
+	assert.False(t, parsed[7].Importable())
+}
+
+func TestMarkDuplicates(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC)
+	}
+	rows := []CSVRow{
+		{Number: 1, Swim: &models.Swim{Date: day(1), DistanceM: 1500}},
+		{Number: 2, Swim: &models.Swim{Date: day(2), DistanceM: 1500}},
+		{Number: 3, Swim: &models.Swim{Date: day(2), DistanceM: 1000}},
+		{Number: 4, Swim: &models.Swim{Date: day(2), DistanceM: 1000}},
+		{Number: 5, Err: assert.AnError},
+	}
+	existing := []*models.Swim{{Date: day(1), DistanceM: 1500}}
+
+	MarkDuplicates(rows, existing)
+
+	assert.True(t, rows[0].Duplicate, "same date and distance as an existing swim")
+	assert.False(t, rows[1].Duplicate)
+	assert.False(t, rows[2].Duplicate)
+	assert.True(t, rows[3].Duplicate, "repeats an earlier row")
+	assert.False(t, rows[4].Duplicate)
+
+	assert.False(t, rows[0].Importable())
+	assert.True(t, rows[1].Importable())
+}
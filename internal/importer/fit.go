@@ -0,0 +1,423 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/rockstaedt/swimmate/internal/models"
+)
+
+var ErrInvalidFIT = errors.New("importer: not a valid FIT file")
+
+// FIT global message numbers of the messages read from activity files.
+const (
+	fitFileID   = 0
+	fitSession  = 18
+	fitLap      = 19
+	fitActivity = 34
+	fitLength   = 101
+)
+
+// FIT field numbers of the fields read, by message. Session and lap share
+// their numbers for start time, times and distance.
+const (
+	fitFieldTimestamp      = 253
+	fitFieldFileType       = 0
+	fitFieldSerialNumber   = 3
+	fitFieldLocalTimestamp = 5
+	fitFieldStartTime      = 2
+	fitFieldSport          = 5
+	fitFieldElapsedTime    = 7
+	fitFieldTimerTime      = 8
+	fitFieldDistance       = 9
+	fitFieldLapSport       = 25
+	fitFieldPoolLength     = 44
+	fitFieldPoolLengthUnit = 46
+	fitFieldActiveLengths  = 47
+	fitFieldLengthStroke   = 7
+	fitFieldLengthType     = 12
+)
+
+const (
+	fitFileTypeActivity = 4
+	fitSportSwimming    = 5
+	fitLengthActive     = 1
+	fitUnitStatute      = 1
+)
+
+const metersPerYard = 0.9144
+
+// fitEpoch is the zero time of FIT timestamps.
+var fitEpoch = time.Date(1989, 12, 31, 0, 0, 0, 0, time.UTC)
+
+// fitStrokes maps the FIT swim_stroke enum to strokes. Drills are left out,
+// they do not tell the stroke of a swim.
+var fitStrokes = map[uint64]models.Stroke{
+	0: models.StrokeFreestyle,
+	1: models.StrokeBackstroke,
+	2: models.StrokeBreaststroke,
+	3: models.StrokeButterfly,
+	5: models.StrokeMixed,
+	6: models.StrokeMixed,
+}
+
+// ParseFIT reads the swim sessions of a FIT activity file as recorded by
+// sports watches. Every session becomes a swim with its date, distance and,
+// where recorded, duration, pool and stroke. Sessions of other sports are
+// skipped, so a file without swims results in no swims and no error.
+//
+// The Source of each swim is derived from the device and start time of the
+// session, so uploading the same file twice yields the same swims.
+func ParseFIT(r io.Reader) ([]*models.Swim, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	messages, err := decodeFIT(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var serial uint64
+	var offset time.Duration
+	var sessions, laps, lengths []fitMessage
+	for _, m := range messages {
+		switch m.global {
+		case fitFileID:
+			if fileType, ok := m.fields[fitFieldFileType]; ok && fileType != fitFileTypeActivity {
+				return nil, nil
+			}
+			serial = m.fields[fitFieldSerialNumber]
+		case fitActivity:
+			// The activity tells the offset of the local time of the
+			// recording, swims are dated by the local day.
+			timestamp, okTimestamp := m.fields[fitFieldTimestamp]
+			local, okLocal := m.fields[fitFieldLocalTimestamp]
+			if okTimestamp && okLocal {
+				offset = time.Duration(int64(local)-int64(timestamp)) * time.Second
+			}
+		case fitSession:
+			sessions = append(sessions, m)
+		case fitLap:
+			laps = append(laps, m)
+		case fitLength:
+			lengths = append(lengths, m)
+		}
+	}
+
+	if len(sessions) == 0 {
+		sessions = sessionFromLaps(laps, lengths)
+	}
+
+	var swims []*models.Swim
+	for _, session := range sessions {
+		if sport, ok := session.fields[fitFieldSport]; ok && sport != fitSportSwimming {
+			continue
+		}
+
+		swim, err := fitSwim(session, lengths, offset)
+		if err != nil {
+			return nil, err
+		}
+		swim.Source = fmt.Sprintf("fit:%d:%d", serial, session.fields[fitFieldStartTime])
+		swims = append(swims, swim)
+	}
+
+	return swims, nil
+}
+
+// sessionFromLaps stands in for the session of files that were cut short
+// before the session message was written. The laps are summed up if they
+// belong to a swim, which files with lengths always do.
+func sessionFromLaps(laps []fitMessage, lengths []fitMessage) []fitMessage {
+	if len(laps) == 0 {
+		return nil
+	}
+
+	session := fitMessage{global: fitSession, fields: map[byte]uint64{
+		fitFieldStartTime: laps[0].fields[fitFieldStartTime],
+	}}
+	swimming := len(lengths) > 0
+	for _, lap := range laps {
+		if sport, ok := lap.fields[fitFieldLapSport]; ok {
+			swimming = sport == fitSportSwimming
+		}
+		session.fields[fitFieldTimerTime] += lap.fields[fitFieldTimerTime]
+		session.fields[fitFieldDistance] += lap.fields[fitFieldDistance]
+	}
+	if !swimming {
+		return nil
+	}
+
+	return []fitMessage{session}
+}
+
+func fitSwim(session fitMessage, lengths []fitMessage, offset time.Duration) (*models.Swim, error) {
+	startTime, ok := session.fields[fitFieldStartTime]
+	if !ok {
+		startTime, ok = session.fields[fitFieldTimestamp]
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: session without start time", ErrInvalidFIT)
+	}
+	start := fitEpoch.Add(time.Duration(startTime) * time.Second)
+	elapsed, ok := session.fields[fitFieldElapsedTime]
+	if !ok {
+		// A session without an end covers all lengths
+		elapsed = math.MaxUint32
+	}
+	end := start.Add(time.Duration(elapsed) * time.Millisecond)
+
+	local := start.Add(offset)
+	swim := &models.Swim{
+		Date:   time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC),
+		Stroke: models.StrokeFreestyle,
+		Feel:   models.FeelOkay,
+		// Timer time leaves out pauses, like the time shown by watches
+		Duration: (time.Duration(session.fields[fitFieldTimerTime]) * time.Millisecond).Round(time.Second),
+	}
+
+	activeLengths := 0
+	strokes := make(map[models.Stroke]bool)
+	for _, length := range lengths {
+		lengthStart := fitEpoch.Add(time.Duration(length.fields[fitFieldStartTime]) * time.Second)
+		if lengthStart.Before(start) || lengthStart.After(end) || length.fields[fitFieldLengthType] != fitLengthActive {
+			continue
+		}
+		activeLengths++
+		if stroke, ok := fitStrokes[length.fields[fitFieldLengthStroke]]; ok {
+			strokes[stroke] = true
+		}
+	}
+	if n, ok := session.fields[fitFieldActiveLengths]; ok {
+		activeLengths = int(n)
+	}
+	switch len(strokes) {
+	case 0:
+	case 1:
+		for stroke := range strokes {
+			swim.Stroke = stroke
+		}
+	default:
+		swim.Stroke = models.StrokeMixed
+	}
+
+	// Pool lengths are recorded in meters, also for pools measured in yards
+	poolM := float64(session.fields[fitFieldPoolLength]) / 100
+	pool := models.Pool{Length: int(math.Round(poolM)), Unit: models.UnitMeters}
+	if session.fields[fitFieldPoolLengthUnit] == fitUnitStatute {
+		pool = models.Pool{Length: int(math.Round(poolM / metersPerYard)), Unit: models.UnitYards}
+	}
+
+	swim.DistanceM = int(math.Round(float64(session.fields[fitFieldDistance]) / 100))
+	if swim.DistanceM == 0 && activeLengths > 0 && poolM > 0 {
+		swim.DistanceM = int(math.Round(float64(activeLengths) * poolM))
+	}
+	if swim.DistanceM <= 0 {
+		return nil, fmt.Errorf("%w: session without distance", ErrInvalidFIT)
+	}
+
+	// Only pools that can be entered by hand are taken over, the laps of a
+	// swim in another pool could not be edited later on.
+	for _, known := range models.Pools {
+		if known == pool {
+			swim.Pool = pool
+			if activeLengths > 0 {
+				swim.Laps = activeLengths
+			}
+		}
+	}
+
+	return swim, nil
+}
+
+// fitMessage is a decoded data message with its valid numeric fields. Fields
+// holding strings, arrays or invalid values are left out.
+type fitMessage struct {
+	global uint16
+	fields map[byte]uint64
+}
+
+type fitDefinition struct {
+	global    uint16
+	byteOrder binary.ByteOrder
+	fields    []fitFieldDefinition
+	// devSize is the number of bytes of developer fields, which are skipped.
+	devSize int
+}
+
+type fitFieldDefinition struct {
+	num      byte
+	size     int
+	baseType byte
+}
+
+// decodeFIT decodes the data messages of a FIT file after checking its
+// header and checksum.
+func decodeFIT(data []byte) ([]fitMessage, error) {
+	if len(data) < 12 || !bytes.Equal(data[8:12], []byte(".FIT")) {
+		return nil, ErrInvalidFIT
+	}
+	headerSize := int(data[0])
+	dataSize := int(binary.LittleEndian.Uint32(data[4:8]))
+	if headerSize < 12 || len(data) < headerSize+dataSize+2 {
+		return nil, fmt.Errorf("%w: file is truncated", ErrInvalidFIT)
+	}
+	if fitCRC(data[:headerSize+dataSize+2]) != 0 {
+		return nil, fmt.Errorf("%w: checksum mismatch", ErrInvalidFIT)
+	}
+
+	records := data[headerSize : headerSize+dataSize]
+	definitions := make(map[byte]*fitDefinition)
+	var messages []fitMessage
+
+	pos := 0
+	for pos < len(records) {
+		header := records[pos]
+		pos++
+
+		var localType byte
+		switch {
+		case header&0x80 != 0:
+			// Compressed timestamp header, always followed by a data message
+			localType = (header >> 5) & 0x03
+		case header&0x40 != 0:
+			def, n, err := decodeFITDefinition(records[pos:], header&0x20 != 0)
+			if err != nil {
+				return nil, err
+			}
+			definitions[header&0x0F] = def
+			pos += n
+			continue
+		default:
+			localType = header & 0x0F
+		}
+
+		def, ok := definitions[localType]
+		if !ok {
+			return nil, fmt.Errorf("%w: data message without definition", ErrInvalidFIT)
+		}
+
+		m := fitMessage{global: def.global, fields: make(map[byte]uint64, len(def.fields))}
+		for _, field := range def.fields {
+			if pos+field.size > len(records) {
+				return nil, fmt.Errorf("%w: file is truncated", ErrInvalidFIT)
+			}
+			if value, ok := fitValue(records[pos:pos+field.size], field.baseType, def.byteOrder); ok {
+				m.fields[field.num] = value
+			}
+			pos += field.size
+		}
+		pos += def.devSize
+		if pos > len(records) {
+			return nil, fmt.Errorf("%w: file is truncated", ErrInvalidFIT)
+		}
+
+		messages = append(messages, m)
+	}
+
+	return messages, nil
+}
+
+func decodeFITDefinition(b []byte, developerData bool) (*fitDefinition, int, error) {
+	truncated := fmt.Errorf("%w: file is truncated", ErrInvalidFIT)
+	if len(b) < 5 {
+		return nil, 0, truncated
+	}
+
+	def := &fitDefinition{byteOrder: binary.LittleEndian}
+	if b[1] == 1 {
+		def.byteOrder = binary.BigEndian
+	}
+	def.global = def.byteOrder.Uint16(b[2:4])
+
+	count := int(b[4])
+	pos := 5
+	if len(b) < pos+count*3 {
+		return nil, 0, truncated
+	}
+	for i := 0; i < count; i++ {
+		def.fields = append(def.fields, fitFieldDefinition{num: b[pos], size: int(b[pos+1]), baseType: b[pos+2]})
+		pos += 3
+	}
+
+	if developerData {
+		if len(b) < pos+1 {
+			return nil, 0, truncated
+		}
+		devCount := int(b[pos])
+		pos++
+		if len(b) < pos+devCount*3 {
+			return nil, 0, truncated
+		}
+		for i := 0; i < devCount; i++ {
+			def.devSize += int(b[pos+1])
+			pos += 3
+		}
+	}
+
+	return def, pos, nil
+}
+
+// fitValue reads a single unsigned or enum value of a field. ok is false for
+// other types, arrays and values marked as invalid.
+func fitValue(b []byte, baseType byte, order binary.ByteOrder) (value uint64, ok bool) {
+	zeroInvalid := false
+	switch baseType & 0x1F {
+	case 0x00, 0x02, 0x0D:
+		// enum, uint8, byte
+	case 0x0A, 0x0B, 0x0C, 0x10:
+		// uint8z, uint16z, uint32z, uint64z
+		zeroInvalid = true
+	case 0x04, 0x06, 0x0F:
+		// uint16, uint32, uint64
+	default:
+		return 0, false
+	}
+
+	switch len(b) {
+	case 1:
+		value = uint64(b[0])
+	case 2:
+		value = uint64(order.Uint16(b))
+	case 4:
+		value = uint64(order.Uint32(b))
+	case 8:
+		value = order.Uint64(b)
+	default:
+		return 0, false
+	}
+
+	invalid := uint64(math.MaxUint64) >> (64 - 8*len(b))
+	if zeroInvalid {
+		invalid = 0
+	}
+	return value, value != invalid
+}
+
+var fitCRCTable = [16]uint16{
+	0x0000, 0xCC01, 0xD801, 0x1400, 0xF001, 0x3C00, 0x2800, 0xE401,
+	0xA001, 0x6C00, 0x7800, 0xB401, 0x5000, 0x9C01, 0x8801, 0x4400,
+}
+
+// fitCRC computes the CRC-16 of FIT files. Over data that ends in its own
+// checksum, the result is zero.
+func fitCRC(data []byte) uint16 {
+	var crc uint16
+	for _, b := range data {
+		tmp := fitCRCTable[crc&0xF]
+		crc = (crc >> 4) & 0x0FFF
+		crc = crc ^ tmp ^ fitCRCTable[b&0xF]
+
+		tmp = fitCRCTable[crc&0xF]
+		crc = (crc >> 4) & 0x0FFF
+		crc = crc ^ tmp ^ fitCRCTable[(b>>4)&0xF]
+	}
+	return crc
+}
@@ -0,0 +1,341 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/rockstaedt/swimmate/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// FIT base types used by the test files.
+const (
+	testEnum    = 0x00
+	testUint16  = 0x84
+	testUint32  = 0x86
+	testUint32z = 0x8C
+	testString  = 0x07
+)
+
+type testFITField struct {
+	num      byte
+	baseType byte
+	value    uint64
+}
+
+type testFITMessage struct {
+	global    uint16
+	bigEndian bool
+	fields    []testFITField
+}
+
+// encodeFIT writes a FIT file with a definition in front of every message.
+func encodeFIT(messages ...testFITMessage) []byte {
+	var records bytes.Buffer
+	for _, m := range messages {
+		var order binary.ByteOrder = binary.LittleEndian
+		architecture := byte(0)
+		if m.bigEndian {
+			order = binary.BigEndian
+			architecture = 1
+		}
+
+		records.Write([]byte{0x40, 0, architecture})
+		_ = binary.Write(&records, order, m.global)
+		records.WriteByte(byte(len(m.fields)))
+		for _, f := range m.fields {
+			records.Write([]byte{f.num, testFITSize(f.baseType), f.baseType})
+		}
+
+		records.WriteByte(0x00)
+		for _, f := range m.fields {
+			switch testFITSize(f.baseType) {
+			case 1:
+				records.WriteByte(byte(f.value))
+			case 2:
+				_ = binary.Write(&records, order, uint16(f.value))
+			case 4:
+				_ = binary.Write(&records, order, uint32(f.value))
+			default:
+				records.Write(make([]byte, testFITSize(f.baseType)))
+			}
+		}
+	}
+
+	return withFITHeader(records.Bytes())
+}
+
+func withFITHeader(records []byte) []byte {
+	header := []byte{14, 0x20, 0, 0, 0, 0, 0, 0, '.', 'F', 'I', 'T', 0, 0}
+	binary.LittleEndian.PutUint16(header[2:4], 2132)
+	binary.LittleEndian.PutUint32(header[4:8], uint32(len(records)))
+	binary.LittleEndian.PutUint16(header[12:14], fitCRC(header[:12]))
+
+	file := append(header, records...)
+	return binary.LittleEndian.AppendUint16(file, fitCRC(file))
+}
+
+func testFITSize(baseType byte) byte {
+	switch baseType {
+	case testUint16:
+		return 2
+	case testUint32, testUint32z:
+		return 4
+	case testString:
+		return 8
+	}
+	return 1
+}
+
+// fitTime returns a time as FIT timestamp.
+func fitTime(t time.Time) uint64 {
+	return uint64(t.Sub(fitEpoch) / time.Second)
+}
+
+func fitLengthMessage(start time.Time, active bool, stroke uint64) testFITMessage {
+	lengthType := uint64(0)
+	if active {
+		lengthType = fitLengthActive
+	}
+	return testFITMessage{global: fitLength, fields: []testFITField{
+		{num: fitFieldStartTime, baseType: testUint32, value: fitTime(start)},
+		{num: fitFieldLengthStroke, baseType: testEnum, value: stroke},
+		{num: fitFieldLengthType, baseType: testEnum, value: lengthType},
+	}}
+}
+
+func TestParseFIT(t *testing.T) {
+	start := time.Date(2024, 1, 15, 23, 30, 0, 0, time.UTC)
+	fileID := testFITMessage{global: fitFileID, fields: []testFITField{
+		{num: fitFieldFileType, baseType: testEnum, value: fitFileTypeActivity},
+		{num: fitFieldSerialNumber, baseType: testUint32z, value: 3912345678},
+	}}
+	activity := testFITMessage{global: fitActivity, fields: []testFITField{
+		{num: fitFieldTimestamp, baseType: testUint32, value: fitTime(start.Add(time.Hour))},
+		{num: fitFieldLocalTimestamp, baseType: testUint32, value: fitTime(start.Add(3 * time.Hour))},
+	}}
+
+	tests := []struct {
+		name     string
+		file     []byte
+		expected []*models.Swim
+	}{
+		{
+			name: "pool swim in local time",
+			file: encodeFIT(
+				fileID,
+				fitLengthMessage(start.Add(time.Minute), true, 0),
+				fitLengthMessage(start.Add(2*time.Minute), true, 0),
+				testFITMessage{global: fitSession, fields: []testFITField{
+					{num: fitFieldTimestamp, baseType: testUint32, value: fitTime(start.Add(time.Hour))},
+					{num: fitFieldStartTime, baseType: testUint32, value: fitTime(start)},
+					{num: fitFieldSport, baseType: testEnum, value: fitSportSwimming},
+					{num: fitFieldElapsedTime, baseType: testUint32, value: 1900000},
+					{num: fitFieldTimerTime, baseType: testUint32, value: 1800400},
+					{num: fitFieldDistance, baseType: testUint32, value: 150000},
+					{num: fitFieldPoolLength, baseType: testUint16, value: 2500},
+					{num: fitFieldPoolLengthUnit, baseType: testEnum, value: 0},
+					{num: fitFieldActiveLengths, baseType: testUint16, value: 60},
+				}},
+				activity,
+			),
+			expected: []*models.Swim{{
+				Date:      time.Date(2024, 1, 16, 0, 0, 0, 0, time.UTC),
+				DistanceM: 1500,
+				Feel:      models.FeelOkay,
+				Duration:  30 * time.Minute,
+				Stroke:    models.StrokeFreestyle,
+				Pool:      models.Pool{Length: 25, Unit: models.UnitMeters},
+				Laps:      60,
+				Source:    "fit:3912345678:1074295800",
+			}},
+		},
+		{
+			name: "yard pool with counted lengths of several strokes",
+			file: encodeFIT(
+				fileID,
+				fitLengthMessage(start.Add(time.Minute), true, 0),
+				fitLengthMessage(start.Add(2*time.Minute), false, 0),
+				fitLengthMessage(start.Add(3*time.Minute), true, 2),
+				fitLengthMessage(start.Add(4*time.Minute), true, 4),
+				testFITMessage{global: fitSession, bigEndian: true, fields: []testFITField{
+					{num: fitFieldStartTime, baseType: testUint32, value: fitTime(start)},
+					{num: fitFieldSport, baseType: testEnum, value: fitSportSwimming},
+					{num: fitFieldElapsedTime, baseType: testUint32, value: 300000},
+					{num: fitFieldDistance, baseType: testUint32, value: 6858},
+					{num: fitFieldPoolLength, baseType: testUint16, value: 2286},
+					{num: fitFieldPoolLengthUnit, baseType: testEnum, value: fitUnitStatute},
+				}},
+			),
+			expected: []*models.Swim{{
+				Date:      time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+				DistanceM: 69,
+				Feel:      models.FeelOkay,
+				Stroke:    models.StrokeMixed,
+				Pool:      models.Pool{Length: 25, Unit: models.UnitYards},
+				Laps:      3,
+				Source:    "fit:3912345678:1074295800",
+			}},
+		},
+		{
+			name: "open water swim in a pool that cannot be entered",
+			file: encodeFIT(
+				testFITMessage{global: fitSession, fields: []testFITField{
+					{num: fitFieldStartTime, baseType: testUint32, value: fitTime(start)},
+					{num: fitFieldSport, baseType: testEnum, value: fitSportSwimming},
+					{num: fitFieldDistance, baseType: testUint32, value: 200000},
+					{num: fitFieldPoolLength, baseType: testUint16, value: 3333},
+					{num: fitFieldActiveLengths, baseType: testUint16, value: 60},
+				}},
+			),
+			expected: []*models.Swim{{
+				Date:      time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+				DistanceM: 2000,
+				Feel:      models.FeelOkay,
+				Stroke:    models.StrokeFreestyle,
+				Source:    "fit:0:1074295800",
+			}},
+		},
+		{
+			name: "laps of a file without session",
+			file: encodeFIT(
+				fileID,
+				fitLengthMessage(start.Add(time.Minute), true, 1),
+				testFITMessage{global: fitLap, fields: []testFITField{
+					{num: fitFieldStartTime, baseType: testUint32, value: fitTime(start)},
+					{num: fitFieldTimerTime, baseType: testUint32, value: 600000},
+					{num: fitFieldDistance, baseType: testUint32, value: 50000},
+				}},
+				testFITMessage{global: fitLap, fields: []testFITField{
+					{num: fitFieldStartTime, baseType: testUint32, value: fitTime(start.Add(10 * time.Minute))},
+					{num: fitFieldTimerTime, baseType: testUint32, value: 300000},
+					{num: fitFieldDistance, baseType: testUint32, value: 25000},
+				}},
+			),
+			expected: []*models.Swim{{
+				Date:      time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+				DistanceM: 750,
+				Feel:      models.FeelOkay,
+				Duration:  15 * time.Minute,
+				Stroke:    models.StrokeBackstroke,
+				Source:    "fit:3912345678:1074295800",
+			}},
+		},
+		{
+			name: "run",
+			file: encodeFIT(
+				fileID,
+				testFITMessage{global: fitSession, fields: []testFITField{
+					{num: fitFieldStartTime, baseType: testUint32, value: fitTime(start)},
+					{num: fitFieldSport, baseType: testEnum, value: 1},
+					{num: fitFieldDistance, baseType: testUint32, value: 1000000},
+				}},
+			),
+		},
+		{
+			name: "laps of a run without session",
+			file: encodeFIT(
+				testFITMessage{global: fitLap, fields: []testFITField{
+					{num: fitFieldStartTime, baseType: testUint32, value: fitTime(start)},
+					{num: fitFieldDistance, baseType: testUint32, value: 1000000},
+					{num: fitFieldLapSport, baseType: testEnum, value: 1},
+				}},
+			),
+		},
+		{
+			name: "course file",
+			file: encodeFIT(testFITMessage{global: fitFileID, fields: []testFITField{
+				{num: fitFieldFileType, baseType: testEnum, value: 6},
+			}}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			swims, err := ParseFIT(bytes.NewReader(tt.file))
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, swims)
+		})
+	}
+}
+
+func TestParseFITSkipsUnknownFields(t *testing.T) {
+	start := time.Date(2024, 1, 15, 8, 0, 0, 0, time.UTC)
+	file := encodeFIT(testFITMessage{global: fitSession, fields: []testFITField{
+		{num: 254, baseType: testString},
+		{num: fitFieldStartTime, baseType: testUint32, value: fitTime(start)},
+		{num: fitFieldTimerTime, baseType: testUint32, value: 0xFFFFFFFF},
+		{num: fitFieldDistance, baseType: testUint32, value: 100000},
+	}})
+
+	swims, err := ParseFIT(bytes.NewReader(file))
+
+	assert.NoError(t, err)
+	assert.Len(t, swims, 1)
+	assert.Equal(t, 1000, swims[0].DistanceM)
+	assert.Zero(t, swims[0].Duration, "invalid values are left out")
+}
+
+func TestParseFITDeveloperDataAndCompressedTimestamps(t *testing.T) {
+	start := time.Date(2024, 1, 15, 8, 0, 0, 0, time.UTC)
+
+	var records bytes.Buffer
+	// Definition of local type 1 with a 2 byte developer field
+	records.Write([]byte{0x61, 0, 0, fitSession, 0, 2,
+		fitFieldStartTime, 4, testUint32,
+		fitFieldDistance, 4, testUint32,
+		1, 0, 2, 0,
+	})
+	// Data message with a compressed timestamp header for local type 1
+	records.WriteByte(0x80 | 1<<5 | 5)
+	_ = binary.Write(&records, binary.LittleEndian, uint32(fitTime(start)))
+	_ = binary.Write(&records, binary.LittleEndian, uint32(80000))
+	records.Write([]byte{0xAB, 0xCD})
+
+	swims, err := ParseFIT(bytes.NewReader(withFITHeader(records.Bytes())))
+
+	assert.NoError(t, err)
+	assert.Len(t, swims, 1)
+	assert.Equal(t, 800, swims[0].DistanceM)
+}
+
+func TestParseFITInvalidFiles(t *testing.T) {
+	valid := encodeFIT(testFITMessage{global: fitSession, fields: []testFITField{
+		{num: fitFieldStartTime, baseType: testUint32, value: 1},
+		{num: fitFieldDistance, baseType: testUint32, value: 100000},
+	}})
+
+	corrupted := bytes.Clone(valid)
+	corrupted[20] ^= 0xFF
+
+	tests := map[string][]byte{
+		"not a FIT file":         []byte("<?xml version=\"1.0\"?><gpx></gpx>"),
+		"truncated":              valid[:len(valid)-4],
+		"checksum mismatch":      corrupted,
+		"data before definition": withFITHeader([]byte{0x00, 0x01}),
+		"session without distance": encodeFIT(testFITMessage{global: fitSession, fields: []testFITField{
+			{num: fitFieldStartTime, baseType: testUint32, value: 1},
+		}}),
+	}
+
+	for name, file := range tests {
+		t.Run(name, func(t *testing.T) {
+			swims, err := ParseFIT(bytes.NewReader(file))
+
+			assert.ErrorIs(t, err, ErrInvalidFIT)
+			assert.Nil(t, swims)
+		})
+	}
+}
+
+func TestFITCRC(t *testing.T) {
+	assert.Equal(t, uint16(0), fitCRC(nil))
+
+	data := []byte("123456789")
+	crc := fitCRC(data)
+	assert.Equal(t, uint16(0xBB3D), crc, "CRC-16/ARC check value")
+	assert.Equal(t, uint16(0), fitCRC(binary.LittleEndian.AppendUint16(data, crc)))
+}
@@ -0,0 +1,148 @@
+package importer
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/rockstaedt/swimmate/internal/models"
+)
+
+var ErrInvalidGPX = errors.New("importer: not a valid GPX file")
+
+// earthRadiusM is the mean radius of the earth used for distances between
+// track points.
+const earthRadiusM = 6371008.8
+
+type gpxDocument struct {
+	XMLName xml.Name   `xml:"gpx"`
+	Time    string     `xml:"metadata>time"`
+	Tracks  []gpxTrack `xml:"trk"`
+}
+
+type gpxTrack struct {
+	Type     string       `xml:"type"`
+	Segments []gpxSegment `xml:"trkseg"`
+}
+
+type gpxSegment struct {
+	Points []gpxPoint `xml:"trkpt"`
+}
+
+type gpxPoint struct {
+	Lat  float64 `xml:"lat,attr"`
+	Lon  float64 `xml:"lon,attr"`
+	Time string  `xml:"time"`
+}
+
+// ParseGPX reads the swims of a GPX file, as exported for open water swims.
+// Every track becomes a swim with the distance along its points and the
+// time between its first and last point. Tracks typed as another sport are
+// skipped, tracks without a type are taken as swims.
+//
+// Swims are dated by the day of their first point in the time zone of the
+// file, or of the file's metadata for tracks without times. Tracks without
+// a distance or time are left out and counted in the returned number of
+// failed tracks instead of failing the whole file.
+func ParseGPX(r io.Reader) ([]*models.Swim, int, error) {
+	var doc gpxDocument
+	err := xml.NewDecoder(r).Decode(&doc)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: %v", ErrInvalidGPX, err)
+	}
+
+	var swims []*models.Swim
+	failed := 0
+	for _, track := range doc.Tracks {
+		if track.Type != "" && !strings.Contains(strings.ToLower(track.Type), "swim") {
+			continue
+		}
+
+		swim, err := gpxSwim(track, doc.Time)
+		if err != nil {
+			failed++
+			continue
+		}
+		swims = append(swims, swim)
+	}
+
+	return swims, failed, nil
+}
+
+func gpxSwim(track gpxTrack, fileTime string) (*models.Swim, error) {
+	distance := 0.0
+	var first, last time.Time
+	for _, segment := range track.Segments {
+		points := make([]trackPoint, 0, len(segment.Points))
+		for _, point := range segment.Points {
+			points = append(points, trackPoint{point.Lat, point.Lon})
+
+			t, err := time.Parse(time.RFC3339, strings.TrimSpace(point.Time))
+			if err != nil {
+				continue
+			}
+			if first.IsZero() {
+				first = t
+			}
+			last = t
+		}
+		// Segments are separate pieces of a track, the gap between them
+		// was not swum.
+		distance += trackLength(points)
+	}
+
+	swim := &models.Swim{
+		DistanceM: int(math.Round(distance)),
+		Stroke:    models.StrokeFreestyle,
+		Feel:      models.FeelOkay,
+		Duration:  last.Sub(first).Round(time.Second),
+	}
+	if swim.DistanceM <= 0 {
+		return nil, fmt.Errorf("%w: track without distance", ErrInvalidGPX)
+	}
+
+	start := first
+	if start.IsZero() {
+		var err error
+		start, err = time.Parse(time.RFC3339, strings.TrimSpace(fileTime))
+		if err != nil {
+			return nil, fmt.Errorf("%w: track without time", ErrInvalidGPX)
+		}
+	}
+	swim.Date = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+	swim.Source = fmt.Sprintf("gpx:%d", start.Unix())
+
+	return swim, nil
+}
+
+// trackPoint is a position in degrees.
+type trackPoint struct {
+	lat, lon float64
+}
+
+// trackLength sums up the great-circle distances in meters between the
+// points of a track.
+func trackLength(points []trackPoint) float64 {
+	length := 0.0
+	for i := 1; i < len(points); i++ {
+		length += haversine(points[i-1], points[i])
+	}
+	return length
+}
+
+// haversine returns the great-circle distance in meters between two points.
+func haversine(a, b trackPoint) float64 {
+	rad := func(deg float64) float64 {
+		return deg * math.Pi / 180
+	}
+
+	dLat := rad(b.lat - a.lat)
+	dLon := rad(b.lon - a.lon)
+	h := math.Pow(math.Sin(dLat/2), 2) + math.Cos(rad(a.lat))*math.Cos(rad(b.lat))*math.Pow(math.Sin(dLon/2), 2)
+
+	return 2 * earthRadiusM * math.Asin(math.Sqrt(min(h, 1)))
+}
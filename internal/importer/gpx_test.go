@@ -0,0 +1,137 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rockstaedt/swimmate/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseGPX(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		expected []*models.Swim
+	}{
+		{
+			name: "open water swim",
+			file: `<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" creator="Watch" xmlns="http://www.topografix.com/GPX/1/1">
+  <metadata><time>2024-07-01T07:59:00Z</time></metadata>
+  <trk>
+    <name>Lake Swim</name>
+    <type>open_water_swimming</type>
+    <trkseg>
+      <trkpt lat="0" lon="0"><time>2024-07-01T08:00:00+02:00</time></trkpt>
+      <trkpt lat="0.005" lon="0"><time>2024-07-01T08:10:00+02:00</time></trkpt>
+    </trkseg>
+    <trkseg>
+      <trkpt lat="0.006" lon="0"><time>2024-07-01T08:11:00+02:00</time></trkpt>
+      <trkpt lat="0.006" lon="0.005"><time>2024-07-01T08:20:30+02:00</time></trkpt>
+    </trkseg>
+  </trk>
+</gpx>`,
+			expected: []*models.Swim{{
+				Date:      time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC),
+				DistanceM: 1112,
+				Stroke:    models.StrokeFreestyle,
+				Feel:      models.FeelOkay,
+				Duration:  20*time.Minute + 30*time.Second,
+				Source:    "gpx:1719813600",
+			}},
+		},
+		{
+			name: "track without type and times",
+			file: `<gpx>
+  <metadata><time>2024-07-01T07:59:00Z</time></metadata>
+  <trk><trkseg><trkpt lat="47.5" lon="8.5"/><trkpt lat="47.5" lon="8.51"/></trkseg></trk>
+</gpx>`,
+			expected: []*models.Swim{{
+				Date:      time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC),
+				DistanceM: 751,
+				Stroke:    models.StrokeFreestyle,
+				Feel:      models.FeelOkay,
+				Source:    "gpx:1719820740",
+			}},
+		},
+		{
+			name: "ride",
+			file: `<gpx><trk><type>cycling</type><trkseg><trkpt lat="0" lon="0"/><trkpt lat="1" lon="0"/></trkseg></trk></gpx>`,
+		},
+		{
+			name: "waypoints only",
+			file: `<gpx><wpt lat="0" lon="0"/></gpx>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			swims, failed, err := ParseGPX(strings.NewReader(tt.file))
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, swims)
+			assert.Equal(t, 0, failed)
+		})
+	}
+}
+
+func TestParseGPXInvalidTracks(t *testing.T) {
+	swim := `<trk><trkseg>
+		<trkpt lat="0" lon="0"><time>2024-07-01T08:00:00Z</time></trkpt>
+		<trkpt lat="0.01" lon="0"><time>2024-07-01T08:25:00Z</time></trkpt>
+	</trkseg></trk>`
+
+	tests := map[string]string{
+		"single point":       `<trk><trkseg><trkpt lat="0" lon="0"><time>2024-07-01T08:00:00Z</time></trkpt></trkseg></trk>`,
+		"track without time": `<trk><trkseg><trkpt lat="0" lon="0"/><trkpt lat="0.01" lon="0"/></trkseg></trk>`,
+	}
+
+	for name, track := range tests {
+		t.Run(name, func(t *testing.T) {
+			swims, failed, err := ParseGPX(strings.NewReader("<gpx>" + track + swim + "</gpx>"))
+
+			assert.NoError(t, err)
+			assert.Len(t, swims, 1)
+			assert.Equal(t, "gpx:1719820800", swims[0].Source)
+			assert.Equal(t, 1, failed)
+		})
+	}
+}
+
+func TestParseGPXInvalidFiles(t *testing.T) {
+	tests := map[string]string{
+		"not XML":   "FIT",
+		"not a GPX": `<TrainingCenterDatabase/>`,
+	}
+
+	for name, file := range tests {
+		t.Run(name, func(t *testing.T) {
+			swims, failed, err := ParseGPX(strings.NewReader(file))
+
+			assert.ErrorIs(t, err, ErrInvalidGPX)
+			assert.Nil(t, swims)
+			assert.Equal(t, 0, failed)
+		})
+	}
+}
+
+func TestHaversine(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     trackPoint
+		expected float64
+	}{
+		{"same point", trackPoint{47.5, 8.5}, trackPoint{47.5, 8.5}, 0},
+		{"one degree along the equator", trackPoint{0, 0}, trackPoint{0, 1}, 111195},
+		{"across the date line", trackPoint{0, 179.5}, trackPoint{0, -179.5}, 111195},
+		{"antipodes", trackPoint{0, 0}, trackPoint{0, 180}, 20015114},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.expected, haversine(tt.a, tt.b), 1)
+		})
+	}
+}
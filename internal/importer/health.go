@@ -0,0 +1,212 @@
+package importer
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rockstaedt/swimmate/internal/models"
+)
+
+var ErrInvalidHealth = errors.New("importer: not a valid Apple Health export")
+
+const (
+	healthSwimming = "HKWorkoutActivityTypeSwimming"
+	// healthDistanceSwimming is the statistic holding the distance of
+	// workouts in exports of iOS 16 and later.
+	healthDistanceSwimming = "HKQuantityTypeIdentifierDistanceSwimming"
+	healthLapLength        = "HKLapLength"
+	healthDateLayout       = "2006-01-02 15:04:05 -0700"
+)
+
+// healthDistanceUnits are the factors to meters of the distance units Apple
+// Health writes.
+var healthDistanceUnits = map[string]float64{
+	"m":  1,
+	"km": 1000,
+	"yd": metersPerYard,
+	"mi": 1609.344,
+}
+
+// healthDurationUnits are the duration units Apple Health writes.
+var healthDurationUnits = map[string]time.Duration{
+	"s":   time.Second,
+	"min": time.Minute,
+	"hr":  time.Hour,
+}
+
+type healthWorkout struct {
+	Duration          string `xml:"duration,attr"`
+	DurationUnit      string `xml:"durationUnit,attr"`
+	TotalDistance     string `xml:"totalDistance,attr"`
+	TotalDistanceUnit string `xml:"totalDistanceUnit,attr"`
+	StartDate         string `xml:"startDate,attr"`
+	Metadata          []struct {
+		Key   string `xml:"key,attr"`
+		Value string `xml:"value,attr"`
+	} `xml:"MetadataEntry"`
+	Statistics []struct {
+		Type string `xml:"type,attr"`
+		Sum  string `xml:"sum,attr"`
+		Unit string `xml:"unit,attr"`
+	} `xml:"WorkoutStatistics"`
+}
+
+// ParseHealth reads the swimming workouts of the export.xml of Apple Health.
+// The file is read as a stream, since it holds every record of the phone and
+// easily grows to gigabytes. Only swimming workouts are decoded, everything
+// else is skipped.
+//
+// Swims are dated by the local day of their start. Their Source is the start
+// time, which is unique per person. Workouts without a usable date or
+// distance, such as some open water swims, are left out and counted in the
+// returned number of failed workouts instead of failing the whole export.
+func ParseHealth(r io.Reader) ([]*models.Swim, int, error) {
+	d := xml.NewDecoder(r)
+
+	root := false
+	var swims []*models.Swim
+	failed := 0
+	for {
+		token, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("%w: %v", ErrInvalidHealth, err)
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if !root {
+			if start.Name.Local != "HealthData" {
+				return nil, 0, ErrInvalidHealth
+			}
+			root = true
+			continue
+		}
+		if start.Name.Local != "Workout" {
+			continue
+		}
+		if !isHealthSwim(start) {
+			err = d.Skip()
+			if err != nil {
+				return nil, 0, fmt.Errorf("%w: %v", ErrInvalidHealth, err)
+			}
+			continue
+		}
+
+		var workout healthWorkout
+		err = d.DecodeElement(&workout, &start)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%w: %v", ErrInvalidHealth, err)
+		}
+
+		swim, err := healthSwim(workout)
+		if err != nil {
+			failed++
+			continue
+		}
+		swims = append(swims, swim)
+	}
+
+	if !root {
+		return nil, 0, ErrInvalidHealth
+	}
+
+	return swims, failed, nil
+}
+
+func isHealthSwim(start xml.StartElement) bool {
+	for _, attr := range start.Attr {
+		if attr.Name.Local == "workoutActivityType" {
+			return attr.Value == healthSwimming
+		}
+	}
+	return false
+}
+
+func healthSwim(workout healthWorkout) (*models.Swim, error) {
+	start, err := time.Parse(healthDateLayout, workout.StartDate)
+	if err != nil {
+		return nil, fmt.Errorf("%w: workout without start date", ErrInvalidHealth)
+	}
+
+	distanceM, ok := healthDistance(workout.TotalDistance, workout.TotalDistanceUnit)
+	for _, statistic := range workout.Statistics {
+		if !ok && statistic.Type == healthDistanceSwimming {
+			distanceM, ok = healthDistance(statistic.Sum, statistic.Unit)
+		}
+	}
+	if !ok || distanceM <= 0 {
+		return nil, fmt.Errorf("%w: swim of %s without distance", ErrInvalidHealth, start.Format("2006-01-02"))
+	}
+
+	swim := &models.Swim{
+		Date:      time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC),
+		DistanceM: int(math.Round(distanceM)),
+		Stroke:    models.StrokeFreestyle,
+		Feel:      models.FeelOkay,
+		Source:    fmt.Sprintf("health:%d", start.Unix()),
+	}
+
+	duration, err := strconv.ParseFloat(workout.Duration, 64)
+	if unit, ok := healthDurationUnits[workout.DurationUnit]; ok && err == nil && duration > 0 {
+		swim.Duration = time.Duration(duration * float64(unit)).Round(time.Second)
+	}
+
+	for _, entry := range workout.Metadata {
+		if entry.Key == healthLapLength {
+			healthPool(swim, entry.Value, distanceM)
+		}
+	}
+
+	return swim, nil
+}
+
+// healthDistance converts a distance of the export to meters.
+func healthDistance(value string, unit string) (float64, bool) {
+	factor, ok := healthDistanceUnits[unit]
+	if !ok {
+		return 0, false
+	}
+	distance, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, false
+	}
+	return distance * factor, true
+}
+
+// healthPool sets the pool of a swim from a lap length such as "25 m".
+// Like for FIT files, only pools that can be entered by hand are taken over,
+// and laps only if the distance is made of whole lengths.
+func healthPool(swim *models.Swim, lapLength string, distanceM float64) {
+	value, unit, _ := strings.Cut(lapLength, " ")
+	lengthM, ok := healthDistance(value, unit)
+	if !ok || lengthM <= 0 {
+		return
+	}
+
+	pool := models.Pool{Length: int(math.Round(lengthM)), Unit: models.UnitMeters}
+	if unit == "yd" {
+		pool = models.Pool{Length: int(math.Round(lengthM / metersPerYard)), Unit: models.UnitYards}
+	}
+
+	for _, known := range models.Pools {
+		if known != pool {
+			continue
+		}
+		swim.Pool = pool
+		laps := distanceM / lengthM
+		if math.Abs(laps-math.Round(laps)) < 0.01 {
+			swim.Laps = int(math.Round(laps))
+		}
+	}
+}
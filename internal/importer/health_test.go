@@ -0,0 +1,132 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rockstaedt/swimmate/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+const healthHeader = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE HealthData [
+<!ELEMENT HealthData (ExportDate,Me,(Record|Correlation|Workout|ActivitySummary|ClinicalRecord)*)>
+<!ATTLIST HealthData
+  locale CDATA #REQUIRED
+>
+<!ELEMENT Workout ((MetadataEntry|WorkoutEvent|WorkoutRoute|WorkoutStatistics)*)>
+<!ATTLIST Workout
+  workoutActivityType CDATA #REQUIRED
+  durationUnit        CDATA #IMPLIED
+>
+]>
+<HealthData locale="de_DE">
+ <ExportDate value="2024-02-01 12:00:00 +0100"/>
+ <Me HKCharacteristicTypeIdentifierBiologicalSex="HKBiologicalSexNotSet"/>
+ <Record type="HKQuantityTypeIdentifierStepCount" sourceName="iPhone" unit="count" startDate="2024-01-15 07:00:00 +0100" endDate="2024-01-15 07:10:00 +0100" value="830"/>
+`
+
+func TestParseHealth(t *testing.T) {
+	file := healthHeader + `
+ <Workout workoutActivityType="HKWorkoutActivityTypeSwimming" duration="32.5" durationUnit="min" totalDistance="1500" totalDistanceUnit="m" sourceName="Apple Watch" startDate="2024-01-15 23:30:00 +0100" endDate="2024-01-16 00:03:00 +0100">
+  <MetadataEntry key="HKIndoorWorkout" value="0"/>
+  <MetadataEntry key="HKLapLength" value="25 m"/>
+  <WorkoutEvent type="HKWorkoutEventTypeLap" date="2024-01-15 23:31:00 +0100"/>
+ </Workout>
+ <Workout workoutActivityType="HKWorkoutActivityTypeRunning" duration="30" durationUnit="min" totalDistance="5" totalDistanceUnit="km" startDate="2024-01-16 18:00:00 +0100">
+  <WorkoutStatistics type="HKQuantityTypeIdentifierDistanceWalkingRunning" sum="5" unit="km"/>
+ </Workout>
+ <Workout workoutActivityType="HKWorkoutActivityTypeSwimming" duration="1500" durationUnit="s" startDate="2024-01-17 07:00:00 -0500">
+  <MetadataEntry key="HKLapLength" value="25 yd"/>
+  <WorkoutStatistics type="HKQuantityTypeIdentifierActiveEnergyBurned" sum="320" unit="kcal"/>
+  <WorkoutStatistics type="HKQuantityTypeIdentifierDistanceSwimming" sum="1010" unit="yd"/>
+ </Workout>
+ <Workout workoutActivityType="HKWorkoutActivityTypeSwimming" duration="50" durationUnit="min" totalDistance="1.2" totalDistanceUnit="mi" startDate="2024-07-01 08:00:00 +0200">
+  <MetadataEntry key="HKSwimmingLocationType" value="2"/>
+  <WorkoutRoute sourceName="Apple Watch"><FileReference path="/workout-routes/route_2024-07-01_8.00am.gpx"/></WorkoutRoute>
+ </Workout>
+ <ActivitySummary dateComponents="2024-01-15" activeEnergyBurned="500"/>
+</HealthData>
+`
+
+	swims, failed, err := ParseHealth(strings.NewReader(file))
+
+	assert.NoError(t, err)
+	assert.Zero(t, failed)
+	assert.Equal(t, []*models.Swim{
+		{
+			Date:      time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+			DistanceM: 1500,
+			Stroke:    models.StrokeFreestyle,
+			Feel:      models.FeelOkay,
+			Duration:  32*time.Minute + 30*time.Second,
+			Pool:      models.Pool{Length: 25, Unit: models.UnitMeters},
+			Laps:      60,
+			Source:    "health:1705357800",
+		},
+		{
+			Date:      time.Date(2024, 1, 17, 0, 0, 0, 0, time.UTC),
+			DistanceM: 924,
+			Stroke:    models.StrokeFreestyle,
+			Feel:      models.FeelOkay,
+			Duration:  25 * time.Minute,
+			Pool:      models.Pool{Length: 25, Unit: models.UnitYards},
+			Source:    "health:1705492800",
+		},
+		{
+			Date:      time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC),
+			DistanceM: 1931,
+			Stroke:    models.StrokeFreestyle,
+			Feel:      models.FeelOkay,
+			Duration:  50 * time.Minute,
+			Source:    "health:1719813600",
+		},
+	}, swims)
+}
+
+func TestParseHealthWithoutSwims(t *testing.T) {
+	swims, failed, err := ParseHealth(strings.NewReader(healthHeader + "</HealthData>"))
+
+	assert.NoError(t, err)
+	assert.Zero(t, failed)
+	assert.Empty(t, swims)
+}
+
+func TestParseHealthSkipsBadWorkouts(t *testing.T) {
+	file := healthHeader + `
+ <Workout workoutActivityType="HKWorkoutActivityTypeSwimming" duration="30" durationUnit="min" totalDistance="1500" totalDistanceUnit="m" startDate="2024-01-15 08:00:00 +0100"/>
+ <Workout workoutActivityType="HKWorkoutActivityTypeSwimming" duration="30" durationUnit="min" startDate="2024-01-16 08:00:00 +0100"/>
+ <Workout workoutActivityType="HKWorkoutActivityTypeSwimming" totalDistance="1500" totalDistanceUnit="m"/>
+ <Workout workoutActivityType="HKWorkoutActivityTypeSwimming" totalDistance="1500" totalDistanceUnit="furlong" startDate="2024-01-17 08:00:00 +0100"/>
+ <Workout workoutActivityType="HKWorkoutActivityTypeSwimming" duration="20" durationUnit="min" totalDistance="1000" totalDistanceUnit="m" startDate="2024-01-18 08:00:00 +0100"/>
+</HealthData>
+`
+
+	swims, failed, err := ParseHealth(strings.NewReader(file))
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, failed, "the workouts without distance, date or known unit")
+	if assert.Len(t, swims, 2) {
+		assert.Equal(t, 1500, swims[0].DistanceM)
+		assert.Equal(t, 1000, swims[1].DistanceM)
+	}
+}
+
+func TestParseHealthInvalidFiles(t *testing.T) {
+	tests := map[string]string{
+		"not XML":   "FIT",
+		"empty":     "",
+		"other XML": `<gpx><trk/></gpx>`,
+		"truncated": healthHeader + `<Workout workoutActivityType="HKWorkoutActivityTypeSwimming" startDate="2024-01-15 08:00:00 +0100">`,
+	}
+
+	for name, file := range tests {
+		t.Run(name, func(t *testing.T) {
+			swims, _, err := ParseHealth(strings.NewReader(file))
+
+			assert.ErrorIs(t, err, ErrInvalidHealth)
+			assert.Nil(t, swims)
+		})
+	}
+}
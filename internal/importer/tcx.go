@@ -0,0 +1,132 @@
+package importer
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/rockstaedt/swimmate/internal/models"
+)
+
+var ErrInvalidTCX = errors.New("importer: not a valid TCX file")
+
+// tcxOtherSports are the sports of TCX activities that are not swims. The
+// schema has no sport for swimming, so swims are recorded as Other or with
+// a sport name of the app that wrote the file.
+var tcxOtherSports = []string{"running", "biking"}
+
+type tcxDocument struct {
+	XMLName    xml.Name      `xml:"TrainingCenterDatabase"`
+	Activities []tcxActivity `xml:"Activities>Activity"`
+}
+
+type tcxActivity struct {
+	Sport string   `xml:"Sport,attr"`
+	Id    string   `xml:"Id"`
+	Laps  []tcxLap `xml:"Lap"`
+}
+
+type tcxLap struct {
+	StartTime        string          `xml:"StartTime,attr"`
+	TotalTimeSeconds float64         `xml:"TotalTimeSeconds"`
+	DistanceMeters   float64         `xml:"DistanceMeters"`
+	Trackpoints      []tcxTrackpoint `xml:"Track>Trackpoint"`
+}
+
+type tcxTrackpoint struct {
+	DistanceMeters float64      `xml:"DistanceMeters"`
+	Position       *tcxPosition `xml:"Position"`
+}
+
+type tcxPosition struct {
+	Latitude  float64 `xml:"LatitudeDegrees"`
+	Longitude float64 `xml:"LongitudeDegrees"`
+}
+
+// ParseTCX reads the swims of a TCX file, the Garmin Training Center format
+// many apps export pool and open water swims in. Every activity becomes a
+// swim dated by the day of its start in the time zone of the file. Running
+// and biking activities are skipped.
+//
+// The distance is the sum of the laps. Laps without a distance fall back to
+// their last track point, or to the track itself for files that only
+// recorded positions. Activities without a start time or distance are left
+// out and counted in the returned number of failed activities instead of
+// failing the whole file.
+func ParseTCX(r io.Reader) ([]*models.Swim, int, error) {
+	var doc tcxDocument
+	err := xml.NewDecoder(r).Decode(&doc)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: %v", ErrInvalidTCX, err)
+	}
+
+	var swims []*models.Swim
+	failed := 0
+	for _, activity := range doc.Activities {
+		for _, sport := range tcxOtherSports {
+			if strings.EqualFold(activity.Sport, sport) {
+				activity.Laps = nil
+			}
+		}
+		if len(activity.Laps) == 0 {
+			continue
+		}
+
+		swim, err := tcxSwim(activity)
+		if err != nil {
+			failed++
+			continue
+		}
+		swims = append(swims, swim)
+	}
+
+	return swims, failed, nil
+}
+
+func tcxSwim(activity tcxActivity) (*models.Swim, error) {
+	id := strings.TrimSpace(activity.Id)
+	if id == "" {
+		id = activity.Laps[0].StartTime
+	}
+	start, err := time.Parse(time.RFC3339, id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: activity without start time", ErrInvalidTCX)
+	}
+
+	distance, seconds := 0.0, 0.0
+	for _, lap := range activity.Laps {
+		seconds += lap.TotalTimeSeconds
+		switch {
+		case lap.DistanceMeters > 0:
+			distance += lap.DistanceMeters
+		case len(lap.Trackpoints) > 0 && lap.Trackpoints[len(lap.Trackpoints)-1].DistanceMeters > 0:
+			distance += lap.Trackpoints[len(lap.Trackpoints)-1].DistanceMeters
+		default:
+			var track []trackPoint
+			for _, point := range lap.Trackpoints {
+				if point.Position != nil {
+					track = append(track, trackPoint{point.Position.Latitude, point.Position.Longitude})
+				}
+			}
+			distance += trackLength(track)
+		}
+	}
+
+	swim := &models.Swim{
+		Date:      time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC),
+		DistanceM: int(math.Round(distance)),
+		Stroke:    models.StrokeFreestyle,
+		Feel:      models.FeelOkay,
+		Duration:  time.Duration(math.Round(seconds)) * time.Second,
+		Source:    fmt.Sprintf("tcx:%d", start.Unix()),
+	}
+	if swim.DistanceM <= 0 {
+		return nil, fmt.Errorf("%w: activity without distance", ErrInvalidTCX)
+	}
+
+	return swim, nil
+}
@@ -0,0 +1,162 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rockstaedt/swimmate/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTCX(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		expected []*models.Swim
+	}{
+		{
+			name: "pool swim with laps",
+			file: `<?xml version="1.0" encoding="UTF-8"?>
+<TrainingCenterDatabase xmlns="http://www.garmin.com/xmlschemas/TrainingCenterDatabase/v2">
+  <Activities>
+    <Activity Sport="Other">
+      <Id>2024-01-15T23:30:00+01:00</Id>
+      <Lap StartTime="2024-01-15T23:30:00+01:00">
+        <TotalTimeSeconds>600.4</TotalTimeSeconds>
+        <DistanceMeters>500</DistanceMeters>
+      </Lap>
+      <Lap StartTime="2024-01-15T23:41:00+01:00">
+        <TotalTimeSeconds>1200</TotalTimeSeconds>
+        <DistanceMeters>1000.2</DistanceMeters>
+      </Lap>
+    </Activity>
+  </Activities>
+</TrainingCenterDatabase>`,
+			expected: []*models.Swim{{
+				Date:      time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+				DistanceM: 1500,
+				Stroke:    models.StrokeFreestyle,
+				Feel:      models.FeelOkay,
+				Duration:  1800 * time.Second,
+				Source:    "tcx:1705357800",
+			}},
+		},
+		{
+			name: "open water swim with track distances",
+			file: `<TrainingCenterDatabase>
+  <Activities>
+    <Activity Sport="Swimming">
+      <Id>2024-07-01T08:00:00Z</Id>
+      <Lap StartTime="2024-07-01T08:00:00Z">
+        <TotalTimeSeconds>900</TotalTimeSeconds>
+        <Track>
+          <Trackpoint><DistanceMeters>0</DistanceMeters></Trackpoint>
+          <Trackpoint><DistanceMeters>812.6</DistanceMeters></Trackpoint>
+        </Track>
+      </Lap>
+    </Activity>
+  </Activities>
+</TrainingCenterDatabase>`,
+			expected: []*models.Swim{{
+				Date:      time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC),
+				DistanceM: 813,
+				Stroke:    models.StrokeFreestyle,
+				Feel:      models.FeelOkay,
+				Duration:  900 * time.Second,
+				Source:    "tcx:1719820800",
+			}},
+		},
+		{
+			name: "open water swim with positions only",
+			file: `<TrainingCenterDatabase>
+  <Activities>
+    <Activity Sport="Other">
+      <Id>2024-07-01T08:00:00Z</Id>
+      <Lap StartTime="2024-07-01T08:00:00Z">
+        <Track>
+          <Trackpoint><Position><LatitudeDegrees>0</LatitudeDegrees><LongitudeDegrees>0</LongitudeDegrees></Position></Trackpoint>
+          <Trackpoint><Time>2024-07-01T08:01:00Z</Time></Trackpoint>
+          <Trackpoint><Position><LatitudeDegrees>0.01</LatitudeDegrees><LongitudeDegrees>0</LongitudeDegrees></Position></Trackpoint>
+        </Track>
+      </Lap>
+    </Activity>
+  </Activities>
+</TrainingCenterDatabase>`,
+			expected: []*models.Swim{{
+				Date:      time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC),
+				DistanceM: 1112,
+				Stroke:    models.StrokeFreestyle,
+				Feel:      models.FeelOkay,
+				Source:    "tcx:1719820800",
+			}},
+		},
+		{
+			name: "run",
+			file: `<TrainingCenterDatabase>
+  <Activities>
+    <Activity Sport="Running">
+      <Id>2024-07-01T08:00:00Z</Id>
+      <Lap StartTime="2024-07-01T08:00:00Z"><DistanceMeters>5000</DistanceMeters></Lap>
+    </Activity>
+  </Activities>
+</TrainingCenterDatabase>`,
+		},
+		{
+			name: "no activities",
+			file: `<TrainingCenterDatabase><Courses/></TrainingCenterDatabase>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			swims, failed, err := ParseTCX(strings.NewReader(tt.file))
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, swims)
+			assert.Equal(t, 0, failed)
+		})
+	}
+}
+
+func TestParseTCXInvalidActivities(t *testing.T) {
+	swim := `<Activity Sport="Other">
+		<Id>2024-07-01T08:00:00Z</Id><Lap><DistanceMeters>1000</DistanceMeters></Lap>
+	</Activity>`
+
+	tests := map[string]string{
+		"without start": `<Activity><Lap><DistanceMeters>100</DistanceMeters></Lap></Activity>`,
+		"without distance": `<Activity>
+			<Id>2024-07-02T08:00:00Z</Id><Lap><TotalTimeSeconds>60</TotalTimeSeconds></Lap>
+		</Activity>`,
+	}
+
+	for name, activity := range tests {
+		t.Run(name, func(t *testing.T) {
+			file := "<TrainingCenterDatabase><Activities>" + activity + swim + "</Activities></TrainingCenterDatabase>"
+			swims, failed, err := ParseTCX(strings.NewReader(file))
+
+			assert.NoError(t, err)
+			assert.Len(t, swims, 1)
+			assert.Equal(t, "tcx:1719820800", swims[0].Source)
+			assert.Equal(t, 1, failed)
+		})
+	}
+}
+
+func TestParseTCXInvalidFiles(t *testing.T) {
+	tests := map[string]string{
+		"not XML":   "FIT",
+		"not a TCX": `<gpx></gpx>`,
+	}
+
+	for name, file := range tests {
+		t.Run(name, func(t *testing.T) {
+			swims, failed, err := ParseTCX(strings.NewReader(file))
+
+			assert.ErrorIs(t, err, ErrInvalidTCX)
+			assert.Nil(t, swims)
+			assert.Equal(t, 0, failed)
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package mailer
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testMessage = Message{
+	To:      "jane@example.com",
+	Subject: "Reset your SwimMate password",
+	Body:    "Hello Jane,\n\nopen this link: https://swimmate.example.com/reset-password/TOKEN\n",
+}
+
+// smtpSession is what a fake SMTP server received.
+type smtpSession struct {
+	from string
+	to   string
+	data string
+}
+
+// startSMTPServer runs an SMTP server without TLS and authentication that
+// accepts one email.
+func startSMTPServer(t *testing.T) (string, <-chan smtpSession) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+
+	received := make(chan smtpSession, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer func() {
+			_ = conn.Close()
+		}()
+
+		text := textproto.NewConn(conn)
+		var session smtpSession
+		_ = text.PrintfLine("220 localhost ESMTP")
+		for {
+			line, err := text.ReadLine()
+			if err != nil {
+				return
+			}
+
+			command, argument, _ := strings.Cut(line, " ")
+			switch strings.ToUpper(command) {
+			case "EHLO", "HELO":
+				_ = text.PrintfLine("250-localhost")
+				_ = text.PrintfLine("250 8BITMIME")
+			case "MAIL":
+				session.from = argument
+				_ = text.PrintfLine("250 OK")
+			case "RCPT":
+				session.to = argument
+				_ = text.PrintfLine("250 OK")
+			case "DATA":
+				_ = text.PrintfLine("354 Go ahead")
+				data, err := io.ReadAll(text.DotReader())
+				if err != nil {
+					return
+				}
+				session.data = string(data)
+				_ = text.PrintfLine("250 OK")
+			case "QUIT":
+				_ = text.PrintfLine("221 Bye")
+				received <- session
+				return
+			default:
+				_ = text.PrintfLine("502 Not implemented")
+			}
+		}
+	}()
+
+	return listener.Addr().String(), received
+}
+
+func TestSMTPMailerSend(t *testing.T) {
+	addr, received := startSMTPServer(t)
+	host, port, _ := net.SplitHostPort(addr)
+	m := NewSMTPMailer(host, 0, "", "", "SwimMate <swimmate@example.com>")
+	m.addr = net.JoinHostPort(host, port)
+
+	err := m.Send(testMessage)
+
+	assert.NoError(t, err)
+	select {
+	case session := <-received:
+		assert.Equal(t, "FROM:<swimmate@example.com>", strings.Fields(session.from)[0])
+		assert.Equal(t, "TO:<jane@example.com>", session.to)
+
+		msg, err := mail.ReadMessage(strings.NewReader(session.data))
+		if assert.NoError(t, err) {
+			assert.Equal(t, "SwimMate <swimmate@example.com>", msg.Header.Get("From"))
+			assert.Equal(t, "jane@example.com", msg.Header.Get("To"))
+			assert.Equal(t, "Reset your SwimMate password", msg.Header.Get("Subject"))
+			assert.True(t, strings.HasSuffix(msg.Header.Get("Message-Id"), "@example.com>"))
+		}
+		assert.Contains(t, session.data, "https://swimmate.example.com/reset-password/TOKEN")
+	case <-time.After(5 * time.Second):
+		t.Fatal("the server received no email")
+	}
+}
+
+func TestSMTPMailerSendUnreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	_ = listener.Close()
+
+	m := NewSMTPMailer("127.0.0.1", 0, "", "", "swimmate@example.com")
+	m.addr = addr
+
+	assert.Error(t, m.Send(testMessage))
+}
+
+func TestLogMailerSend(t *testing.T) {
+	var buf bytes.Buffer
+	m := NewLogMailer(slog.New(slog.NewTextHandler(&buf, nil)))
+
+	err := m.Send(testMessage)
+
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), "to=jane@example.com")
+	assert.Contains(t, buf.String(), "https://swimmate.example.com/reset-password/TOKEN")
+}
+
+func TestFileMailerSend(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	m := NewFileMailer(dir, "swimmate@example.com")
+
+	assert.NoError(t, m.Send(testMessage))
+	assert.NoError(t, m.Send(testMessage))
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	assert.NoError(t, err)
+	if assert.Len(t, files, 2, "each email gets its own file") {
+		data, err := os.ReadFile(files[0])
+		assert.NoError(t, err)
+
+		msg, err := mail.ReadMessage(bytes.NewReader(data))
+		if assert.NoError(t, err) {
+			assert.Equal(t, "jane@example.com", msg.Header.Get("To"))
+			body, _ := io.ReadAll(msg.Body)
+			assert.Contains(t, string(body), "Hello Jane,\r\n\r\nopen this link")
+		}
+	}
+}
+
+func TestCompose(t *testing.T) {
+	date := time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("encoded subject and body", func(t *testing.T) {
+		data, err := compose("swimmate@example.com", Message{
+			To:      "jane@example.com",
+			Subject: "Schwimmbäder",
+			Body:    "Grüße\n",
+		}, date)
+
+		assert.NoError(t, err)
+		assert.Contains(t, string(data), "Subject: =?utf-8?q?Schwimmb=C3=A4der?=\r\n")
+		assert.Contains(t, string(data), "Date: Thu, 01 Feb 2024 12:00:00 +0000\r\n")
+		assert.True(t, strings.HasSuffix(string(data), "\r\n\r\nGr=C3=BC=C3=9Fe\r\n"))
+	})
+
+	tests := []struct {
+		name string
+		msg  Message
+	}{
+		{name: "line break in the subject", msg: Message{To: "jane@example.com", Subject: "Hi\r\nBcc: john@example.com"}},
+		{name: "line break in the recipient", msg: Message{To: "jane@example.com\nBcc: john@example.com"}},
+		{name: "invalid recipient", msg: Message{To: "jane"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := compose("swimmate@example.com", tt.msg, date)
+
+			assert.Error(t, err)
+		})
+	}
+}
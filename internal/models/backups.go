@@ -0,0 +1,311 @@
+package models
+
+import (
+	"database/sql"
+)
+
+// RestoreMode tells how a backup is loaded into an account.
+type RestoreMode string
+
+const (
+	// RestoreMerge adds the swims of a backup to the account, skipping swims
+	// with the date and distance of one the account already has and swims of
+	// activities it has imported.
+	RestoreMerge RestoreMode = "merge"
+	// RestoreReplace deletes all swims, tags and locations of the account
+	// and takes over the profile of the backup before loading it.
+	RestoreReplace RestoreMode = "replace"
+)
+
+func (m RestoreMode) Valid() bool {
+	return m == RestoreMerge || m == RestoreReplace
+}
+
+// Backup holds all data of an account. The LocationId of its swims refers to
+// the Id of one of its locations, which is only meaningful within the backup.
+type Backup struct {
+	User      User
+	Locations []*Location
+	// Swims are sorted by date and carry their sets and tags.
+	Swims []*Swim
+}
+
+// Restored tells how a restore changed the swims of an account.
+type Restored struct {
+	// Swims are the swims of the backup that were stored, with their ids in
+	// the account.
+	Swims []*Swim
+	// DeletedIds are the ids of the swims a replace deleted.
+	DeletedIds []int
+}
+
+type BackupModel interface {
+	Get(userId int) (*Backup, error)
+	Restore(userId int, backup *Backup, mode RestoreMode) (*Restored, error)
+}
+
+type backupModel struct {
+	DB *sql.DB
+}
+
+func NewBackupModel(db *sql.DB) BackupModel {
+	return &backupModel{DB: db}
+}
+
+// Get collects the profile, locations and swims of a user.
+func (bm *backupModel) Get(userId int) (*Backup, error) {
+	user, err := NewUserModel(bm.DB).Get(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	locations, err := NewLocationModel(bm.DB).GetAll(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	sw := &swimModel{DB: bm.DB}
+	swims, err := sw.GetAll(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = sw.attachSets(swims)
+	if err != nil {
+		return nil, err
+	}
+
+	err = bm.attachSources(userId, swims)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Backup{User: *user, Locations: locations, Swims: swims}, nil
+}
+
+// Restore loads a backup into the account of a user in one transaction and
+// returns the swims it stored and deleted. Locations are matched by name, so
+// restoring into an account that already has a location of the same name
+// reuses it. Username, email and password of the account are never changed.
+func (bm *backupModel) Restore(userId int, backup *Backup, mode RestoreMode) (*Restored, error) {
+	tx, err := bm.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		// Rollback is a no-op once the transaction has been committed
+		_ = tx.Rollback()
+	}()
+
+	restored := &Restored{}
+	existing, sources := make(map[swimKey]bool), make(map[string]bool)
+	if mode == RestoreReplace {
+		restored.DeletedIds, err = clearAccount(tx, userId, backup.User)
+	} else {
+		existing, sources, err = existingSwims(tx, userId)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	locationIds := make(map[int]int, len(backup.Locations))
+	for _, location := range backup.Locations {
+		locationIds[location.Id], err = upsertLocation(tx, userId, location)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, swim := range backup.Swims {
+		if existing[keyOf(swim)] || sources[swim.Source] {
+			continue
+		}
+
+		s := *swim
+		s.LocationId = locationIds[swim.LocationId]
+		err = insertSwim(tx, &s, userId)
+		if err != nil {
+			return nil, err
+		}
+		restored.Swims = append(restored.Swims, &s)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return restored, nil
+}
+
+// swimKey identifies swims that are considered the same when merging.
+type swimKey struct {
+	date      string
+	distanceM int
+}
+
+func keyOf(swim *Swim) swimKey {
+	return swimKey{swim.Date.Format("2006-01-02"), swim.DistanceM}
+}
+
+// clearAccount deletes the swims, tags and locations of a user, takes over
+// the profile and returns the ids of the deleted swims.
+func clearAccount(tx *sql.Tx, userId int, profile User) ([]int, error) {
+	ids, err := deleteSwims(tx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, stmt := range []string{
+		`DELETE FROM tags WHERE user_id = $1;`,
+		`DELETE FROM locations WHERE user_id = $1;`,
+	} {
+		_, err := tx.Exec(stmt, userId)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	stmt := `UPDATE users SET first_name = $1, last_name = $2, distance_unit = $3 WHERE id = $4;`
+
+	result, err := tx.Exec(stmt, profile.FirstName, profile.LastName, profile.DistanceUnit, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	if rowsAffected == 0 {
+		return nil, ErrNoRecord
+	}
+
+	return ids, nil
+}
+
+// deleteSwims deletes all swims of a user and returns their ids.
+func deleteSwims(tx *sql.Tx, userId int) ([]int, error) {
+	rows, err := tx.Query(`DELETE FROM swims WHERE user_id = $1 RETURNING id;`, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err := rows.Close(); err != nil {
+			// Ignore Close error to avoid overriding return error
+			_ = err
+		}
+	}()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		errScan := rows.Scan(&id)
+		if errScan != nil {
+			return nil, errScan
+		}
+		ids = append(ids, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// existingSwims returns the keys and sources of the swims a user already has.
+func existingSwims(tx *sql.Tx, userId int) (map[swimKey]bool, map[string]bool, error) {
+	rows, err := tx.Query(`SELECT date, distance_m, source FROM swims WHERE user_id = $1;`, userId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer func() {
+		if err := rows.Close(); err != nil {
+			// Ignore Close error to avoid overriding return error
+			_ = err
+		}
+	}()
+
+	keys := make(map[swimKey]bool)
+	sources := make(map[string]bool)
+	for rows.Next() {
+		var s Swim
+		var source sql.NullString
+		errScan := rows.Scan(&s.Date, &s.DistanceM, &source)
+		if errScan != nil {
+			return nil, nil, errScan
+		}
+
+		keys[keyOf(&s)] = true
+		if source.Valid {
+			sources[source.String] = true
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return keys, sources, nil
+}
+
+// attachSources loads the sources of imported swims, which lists of swims
+// leave out.
+func (bm *backupModel) attachSources(userId int, swims []*Swim) error {
+	rows, err := bm.DB.Query(`SELECT id, source FROM swims WHERE user_id = $1 AND source IS NOT NULL;`, userId)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err := rows.Close(); err != nil {
+			// Ignore Close error to avoid overriding return error
+			_ = err
+		}
+	}()
+
+	sources := make(map[int]string)
+	for rows.Next() {
+		var id int
+		var source string
+		errScan := rows.Scan(&id, &source)
+		if errScan != nil {
+			return errScan
+		}
+
+		sources[id] = source
+	}
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for _, swim := range swims {
+		swim.Source = sources[swim.Id]
+	}
+
+	return nil
+}
+
+// upsertLocation stores a location of a backup and returns its id. An
+// existing location of the same name is kept as it is.
+func upsertLocation(tx *sql.Tx, userId int, location *Location) (int, error) {
+	stmt := `INSERT INTO locations (user_id, name, pool_length, pool_unit, indoor) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, name) DO UPDATE SET name = EXCLUDED.name RETURNING id;`
+
+	var id int
+	err := tx.QueryRow(
+		stmt,
+		userId,
+		location.Name,
+		nullableInt(location.Pool.Length),
+		nullableUnit(location.Pool.Unit),
+		location.Indoor,
+	).Scan(&id)
+
+	return id, err
+}
@@ -0,0 +1,210 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRestoreModeValid(t *testing.T) {
+	assert.True(t, RestoreMerge.Valid())
+	assert.True(t, RestoreReplace.Valid())
+	assert.False(t, RestoreMode("append").Valid())
+}
+
+func TestBackupModelGet(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer func() {
+		_ = db.Close()
+	}()
+
+	joined := time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT id, first_name, last_name, username, email, date_joined, last_login, distance_unit FROM users").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "first_name", "last_name", "username", "email", "date_joined", "last_login", "distance_unit"}).
+			AddRow(1, "Jane", "Doe", "jane", "jane@example.com", joined, nil, "m"))
+	mock.ExpectQuery("SELECT id, name, pool_length, pool_unit, indoor FROM locations").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "pool_length", "pool_unit", "indoor"}).
+			AddRow(4, "City Pool", 50, "m", true))
+	mock.ExpectQuery("SELECT id, date, distance_m, effort, feel, duration_s, stroke, pool_length, pool_unit, laps, notes, location_id FROM swims").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "date", "distance_m", "effort", "feel", "duration_s", "stroke", "pool_length", "pool_unit", "laps", "notes", "location_id"}).
+			AddRow(10, time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), 1500, 7, 4, 1800, "mixed", nil, nil, nil, "", 4).
+			AddRow(11, time.Date(2024, 1, 17, 0, 0, 0, 0, time.UTC), 800, nil, 3, nil, "freestyle", nil, nil, nil, "", nil))
+	mock.ExpectQuery("SELECT st.swim_id, t.name FROM swim_tags").
+		WillReturnRows(sqlmock.NewRows([]string{"swim_id", "name"}).AddRow(11, "technique"))
+	mock.ExpectQuery("SELECT swim_id, repetitions, distance_m, stroke, interval_s, rest_s FROM swim_sets").
+		WillReturnRows(sqlmock.NewRows([]string{"swim_id", "repetitions", "distance_m", "stroke", "interval_s", "rest_s"}).
+			AddRow(10, 1, 300, "freestyle", nil, nil).
+			AddRow(10, 12, 100, "mixed", 105, 15))
+	mock.ExpectQuery("SELECT id, source FROM swims WHERE user_id = \\$1 AND source IS NOT NULL").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "source"}).AddRow(10, "fit:3912345678:1052000000"))
+
+	b, err := NewBackupModel(db).Get(1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "jane", b.User.Username)
+	assert.Equal(t, []*Location{{Id: 4, Name: "City Pool", Pool: Pool{Length: 50, Unit: UnitMeters}, Indoor: true}}, b.Locations)
+	assert.Len(t, b.Swims, 2)
+	assert.Equal(t, 4, b.Swims[0].LocationId)
+	assert.Equal(t, "fit:3912345678:1052000000", b.Swims[0].Source)
+	assert.Empty(t, b.Swims[1].Source)
+	assert.Equal(t, []SwimSet{
+		{Repetitions: 1, DistanceM: 300, Stroke: StrokeFreestyle},
+		{Repetitions: 12, DistanceM: 100, Stroke: StrokeMixed, Interval: 105 * time.Second, Rest: 15 * time.Second},
+	}, b.Swims[0].Sets)
+	assert.Nil(t, b.Swims[1].Sets)
+	assert.Equal(t, []string{"technique"}, b.Swims[1].Tags)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBackupModelGetUnknownUser(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer func() {
+		_ = db.Close()
+	}()
+
+	mock.ExpectQuery("SELECT id, first_name").WithArgs(1).WillReturnError(sql.ErrNoRows)
+
+	b, err := NewBackupModel(db).Get(1)
+
+	assert.ErrorIs(t, err, ErrNoRecord)
+	assert.Nil(t, b)
+}
+
+func TestBackupModelRestore(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC)
+	}
+	backup := func() *Backup {
+		return &Backup{
+			User:      User{FirstName: "Jane", LastName: "Doe", DistanceUnit: UnitYards},
+			Locations: []*Location{{Id: 1, Name: "Lake"}},
+			Swims: []*Swim{
+				{Date: day(15), DistanceM: 1500, Stroke: StrokeFreestyle, Feel: FeelGood, LocationId: 1, Tags: []string{"open water"}},
+				{Date: day(17), DistanceM: 800, Stroke: StrokeFreestyle, Feel: FeelOkay},
+				{Date: day(19), DistanceM: 2000, Stroke: StrokeFreestyle, Feel: FeelOkay, Source: "fit:1:2"},
+			},
+		}
+	}
+
+	tests := []struct {
+		name             string
+		mode             RestoreMode
+		setupMock        func(mock sqlmock.Sqlmock)
+		expectedRestored []int
+		expectedDeleted  []int
+		expectedError    string
+	}{
+		{
+			name: "merge skips existing swims and imported activities",
+			mode: RestoreMerge,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT date, distance_m, source FROM swims WHERE user_id = \\$1").
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows([]string{"date", "distance_m", "source"}).
+						AddRow(day(17), 800, nil).
+						AddRow(day(18), 2000, "fit:1:2"))
+				mock.ExpectQuery("INSERT INTO locations .* ON CONFLICT \\(user_id, name\\)").
+					WithArgs(1, "Lake", nil, nil, false).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+				mock.ExpectQuery("INSERT INTO swims").
+					WithArgs(day(15), 1500, nil, "freestyle", nil, nil, nil, "", 9, nil, 4, nil, 1).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(20))
+				mock.ExpectQuery("INSERT INTO tags").
+					WithArgs(1, "open water").
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+				mock.ExpectExec("INSERT INTO swim_tags").
+					WithArgs(20, 3).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			expectedRestored: []int{20},
+		},
+		{
+			name: "replace clears the account first",
+			mode: RestoreReplace,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery("DELETE FROM swims WHERE user_id = \\$1 RETURNING id").
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4).AddRow(5))
+				mock.ExpectExec("DELETE FROM tags WHERE user_id = \\$1").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec("DELETE FROM locations WHERE user_id = \\$1").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("UPDATE users SET first_name = \\$1, last_name = \\$2, distance_unit = \\$3 WHERE id = \\$4").
+					WithArgs("Jane", "Doe", "yd", 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("INSERT INTO locations").
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+				mock.ExpectQuery("INSERT INTO swims").
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(20))
+				mock.ExpectQuery("INSERT INTO tags").
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+				mock.ExpectExec("INSERT INTO swim_tags").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("INSERT INTO swims").
+					WithArgs(day(17), 800, nil, "freestyle", nil, nil, nil, "", nil, nil, 3, nil, 1).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(21))
+				mock.ExpectQuery("INSERT INTO swims").
+					WithArgs(day(19), 2000, nil, "freestyle", nil, nil, nil, "", nil, nil, 3, "fit:1:2", 1).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(22))
+				mock.ExpectCommit()
+			},
+			expectedRestored: []int{20, 21, 22},
+			expectedDeleted:  []int{4, 5},
+		},
+		{
+			name: "rolls back on error",
+			mode: RestoreMerge,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT date, distance_m, source FROM swims").
+					WillReturnRows(sqlmock.NewRows([]string{"date", "distance_m", "source"}))
+				mock.ExpectQuery("INSERT INTO locations").
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+				mock.ExpectQuery("INSERT INTO swims").
+					WillReturnError(errors.New("check constraint violated"))
+				mock.ExpectRollback()
+			},
+			expectedError: "check constraint violated",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer func() {
+				_ = db.Close()
+			}()
+
+			tt.setupMock(mock)
+
+			b := backup()
+			restored, err := NewBackupModel(db).Restore(1, b, tt.mode)
+
+			if tt.expectedError != "" {
+				assert.EqualError(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+				var ids []int
+				for _, swim := range restored.Swims {
+					ids = append(ids, swim.Id)
+				}
+				assert.Equal(t, tt.expectedRestored, ids)
+				assert.Equal(t, tt.expectedDeleted, restored.DeletedIds)
+			}
+			assert.Equal(t, backup(), b, "the backup is left as it is")
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package models
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// CalendarFeed is the calendar feed of a user. Its token is only known when
+// it is created, since just a hash of it is stored.
+type CalendarFeed struct {
+	Created time.Time
+}
+
+type CalendarModel interface {
+	Get(userId int) (*CalendarFeed, error)
+	Reset(userId int) (string, error)
+	Delete(userId int) error
+	UserID(token string) (int, error)
+}
+
+type calendarModel struct {
+	DB *sql.DB
+}
+
+func NewCalendarModel(db *sql.DB) CalendarModel {
+	return &calendarModel{DB: db}
+}
+
+func (cm *calendarModel) Get(userId int) (*CalendarFeed, error) {
+	stmt := `SELECT created FROM calendar_feeds WHERE user_id = $1;`
+
+	var feed CalendarFeed
+	err := cm.DB.QueryRow(stmt, userId).Scan(&feed.Created)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		}
+		return nil, err
+	}
+
+	return &feed, nil
+}
+
+// Reset creates a new feed token for a user and returns it. A previous token
+// is replaced, so its URL stops working.
+func (cm *calendarModel) Reset(userId int) (string, error) {
+	stmt := `INSERT INTO calendar_feeds (user_id, token_hash) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET token_hash = EXCLUDED.token_hash, created = now();`
+
+	token := rand.Text()
+	_, err := cm.DB.Exec(stmt, userId, tokenHash(token))
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// Delete revokes the feed of a user.
+func (cm *calendarModel) Delete(userId int) error {
+	stmt := `DELETE FROM calendar_feeds WHERE user_id = $1;`
+
+	result, err := cm.DB.Exec(stmt, userId)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
+
+// UserID returns the user a feed token belongs to.
+func (cm *calendarModel) UserID(token string) (int, error) {
+	stmt := `SELECT user_id FROM calendar_feeds WHERE token_hash = $1;`
+
+	var userId int
+	err := cm.DB.QueryRow(stmt, tokenHash(token)).Scan(&userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrNoRecord
+		}
+		return 0, err
+	}
+
+	return userId, nil
+}
@@ -0,0 +1,174 @@
+package models
+
+import (
+	"crypto/sha256"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+// capturedArg matches any argument and keeps it for later checks.
+type capturedArg struct {
+	value driver.Value
+}
+
+func (a *capturedArg) Match(v driver.Value) bool {
+	a.value = v
+	return true
+}
+
+func TestCalendarModelGet(t *testing.T) {
+	created := time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		rows          *sqlmock.Rows
+		expectedFeed  *CalendarFeed
+		expectedError error
+	}{
+		{
+			name:         "feed of the user",
+			rows:         sqlmock.NewRows([]string{"created"}).AddRow(created),
+			expectedFeed: &CalendarFeed{Created: created},
+		},
+		{
+			name:          "no feed",
+			rows:          sqlmock.NewRows([]string{"created"}),
+			expectedError: ErrNoRecord,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer func() {
+				_ = db.Close()
+			}()
+
+			mock.ExpectQuery("SELECT created FROM calendar_feeds WHERE user_id = \\$1").
+				WithArgs(1).
+				WillReturnRows(tt.rows)
+
+			feed, err := NewCalendarModel(db).Get(1)
+
+			assert.ErrorIs(t, err, tt.expectedError)
+			assert.Equal(t, tt.expectedFeed, feed)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestCalendarModelReset(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer func() {
+		_ = db.Close()
+	}()
+
+	hash := &capturedArg{}
+	mock.ExpectExec("INSERT INTO calendar_feeds \\(user_id, token_hash\\) VALUES \\(\\$1, \\$2\\)\\s+ON CONFLICT \\(user_id\\) DO UPDATE").
+		WithArgs(1, hash).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	token, err := NewCalendarModel(db).Reset(1)
+
+	assert.NoError(t, err)
+	assert.Len(t, token, 26)
+	expected := sha256.Sum256([]byte(token))
+	assert.Equal(t, expected[:], hash.value, "only the hash of the token is stored")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCalendarModelResetError(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer func() {
+		_ = db.Close()
+	}()
+
+	dbErr := errors.New("database error")
+	mock.ExpectExec("INSERT INTO calendar_feeds").WillReturnError(dbErr)
+
+	token, err := NewCalendarModel(db).Reset(1)
+
+	assert.ErrorIs(t, err, dbErr)
+	assert.Empty(t, token)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCalendarModelDelete(t *testing.T) {
+	tests := []struct {
+		name          string
+		rowsAffected  int64
+		expectedError error
+	}{
+		{name: "feed revoked", rowsAffected: 1},
+		{name: "no feed", rowsAffected: 0, expectedError: ErrNoRecord},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer func() {
+				_ = db.Close()
+			}()
+
+			mock.ExpectExec("DELETE FROM calendar_feeds WHERE user_id = \\$1").
+				WithArgs(1).
+				WillReturnResult(sqlmock.NewResult(0, tt.rowsAffected))
+
+			err = NewCalendarModel(db).Delete(1)
+
+			assert.ErrorIs(t, err, tt.expectedError)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestCalendarModelUserID(t *testing.T) {
+	hash := sha256.Sum256([]byte("SECRETTOKEN"))
+
+	tests := []struct {
+		name          string
+		rows          *sqlmock.Rows
+		expectedId    int
+		expectedError error
+	}{
+		{
+			name:       "known token",
+			rows:       sqlmock.NewRows([]string{"user_id"}).AddRow(3),
+			expectedId: 3,
+		},
+		{
+			name:          "unknown or revoked token",
+			rows:          sqlmock.NewRows([]string{"user_id"}),
+			expectedError: ErrNoRecord,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer func() {
+				_ = db.Close()
+			}()
+
+			mock.ExpectQuery("SELECT user_id FROM calendar_feeds WHERE token_hash = \\$1").
+				WithArgs(hash[:]).
+				WillReturnRows(tt.rows)
+
+			userId, err := NewCalendarModel(db).UserID("SECRETTOKEN")
+
+			assert.ErrorIs(t, err, tt.expectedError)
+			assert.Equal(t, tt.expectedId, userId)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -5,3 +5,19 @@ import "errors"
 var ErrNoRecord = errors.New("models: no matching record found")
 
 var ErrInvalidCredentials = errors.New("models: invalid credentials")
+
+var ErrDuplicateName = errors.New("models: duplicate name")
+
+var ErrDuplicateSource = errors.New("models: activity already imported")
+
+var ErrInvalidSwim = errors.New("models: invalid swim")
+
+var ErrDuplicateURL = errors.New("models: duplicate url")
+
+var ErrDuplicateUsername = errors.New("models: duplicate username")
+
+var ErrDuplicateEmail = errors.New("models: duplicate email")
+
+var ErrTwoFactorEnabled = errors.New("models: two-factor authentication already enabled")
+
+var ErrTwoFactorLocked = errors.New("models: two-factor authentication locked after too many wrong codes")
@@ -0,0 +1,155 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ImportJob is the progress of an archive import, which is read in batches
+// over several requests.
+type ImportJob struct {
+	Id int
+	// File is the path of the uploaded archive. It is kept until the job is
+	// done.
+	File string
+	// Total is the number of swims listed in the archive, Processed the
+	// number of them read so far.
+	Total     int
+	Processed int
+	Imported  int
+	Skipped   int
+	Failed    int
+	Done      bool
+	Created   time.Time
+}
+
+// Percent is the share of processed swims, 100 for archives without swims.
+func (j *ImportJob) Percent() int {
+	if j.Total == 0 {
+		return 100
+	}
+	return j.Processed * 100 / j.Total
+}
+
+type ImportJobModel interface {
+	Insert(job *ImportJob, userId int) error
+	Get(id int, userId int) (*ImportJob, error)
+	GetRunning(userId int) (*ImportJob, error)
+	CountRunning(userId int) (int, error)
+	Update(job *ImportJob, userId int) error
+	Expire(before time.Time) ([]string, error)
+}
+
+type importJobModel struct {
+	DB *sql.DB
+}
+
+func NewImportJobModel(db *sql.DB) ImportJobModel {
+	return &importJobModel{DB: db}
+}
+
+func (im *importJobModel) Insert(job *ImportJob, userId int) error {
+	stmt := `INSERT INTO import_jobs (user_id, file, total, processed, imported, skipped, failed, done)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, created;`
+
+	return im.DB.QueryRow(
+		stmt,
+		userId,
+		job.File,
+		job.Total,
+		job.Processed,
+		job.Imported,
+		job.Skipped,
+		job.Failed,
+		job.Done,
+	).Scan(&job.Id, &job.Created)
+}
+
+func (im *importJobModel) Get(id int, userId int) (*ImportJob, error) {
+	stmt := `SELECT id, file, total, processed, imported, skipped, failed, done, created FROM import_jobs
+		WHERE id = $1 AND user_id = $2;`
+
+	return scanImportJob(im.DB.QueryRow(stmt, id, userId))
+}
+
+// GetRunning returns the latest job of a user that is not done yet, so the
+// import page can offer to continue it.
+func (im *importJobModel) GetRunning(userId int) (*ImportJob, error) {
+	stmt := `SELECT id, file, total, processed, imported, skipped, failed, done, created FROM import_jobs
+		WHERE user_id = $1 AND NOT done ORDER BY created DESC, id DESC LIMIT 1;`
+
+	return scanImportJob(im.DB.QueryRow(stmt, userId))
+}
+
+// CountRunning returns the number of jobs of a user that are not done yet.
+func (im *importJobModel) CountRunning(userId int) (int, error) {
+	stmt := `SELECT count(*) FROM import_jobs WHERE user_id = $1 AND NOT done;`
+
+	var count int
+	err := im.DB.QueryRow(stmt, userId).Scan(&count)
+	return count, err
+}
+
+// Update stores the progress of a job.
+func (im *importJobModel) Update(job *ImportJob, userId int) error {
+	stmt := `UPDATE import_jobs SET processed = $1, imported = $2, skipped = $3, failed = $4, done = $5
+		WHERE id = $6 AND user_id = $7;`
+
+	result, err := im.DB.Exec(stmt, job.Processed, job.Imported, job.Skipped, job.Failed, job.Done, job.Id, userId)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
+
+// Expire ends the jobs of all users that were started before a time and
+// are still not done. Their remaining swims count as failed. It returns the
+// files of the jobs, which are no longer needed.
+func (im *importJobModel) Expire(before time.Time) ([]string, error) {
+	stmt := `UPDATE import_jobs SET failed = failed + total - processed, processed = total, done = true
+		WHERE NOT done AND created < $1 RETURNING file;`
+
+	rows, err := im.DB.Query(stmt, before)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var files []string
+	for rows.Next() {
+		var file string
+		err = rows.Scan(&file)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	return files, rows.Err()
+}
+
+func scanImportJob(row rowScanner) (*ImportJob, error) {
+	var j ImportJob
+	err := row.Scan(&j.Id, &j.File, &j.Total, &j.Processed, &j.Imported, &j.Skipped, &j.Failed, &j.Done, &j.Created)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		}
+		return nil, err
+	}
+
+	return &j, nil
+}
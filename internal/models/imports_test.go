@@ -0,0 +1,173 @@
+package models
+
+import (
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var importJobColumns = []string{"id", "file", "total", "processed", "imported", "skipped", "failed", "done", "created"}
+
+func TestImportJobPercent(t *testing.T) {
+	assert.Equal(t, 0, (&ImportJob{Total: 3}).Percent())
+	assert.Equal(t, 66, (&ImportJob{Total: 3, Processed: 2}).Percent())
+	assert.Equal(t, 100, (&ImportJob{Total: 3, Processed: 3}).Percent())
+	assert.Equal(t, 100, (&ImportJob{}).Percent())
+}
+
+func TestImportJobModelInsert(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer func() {
+		_ = db.Close()
+	}()
+
+	created := time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("INSERT INTO import_jobs \\(user_id, file, total, processed, imported, skipped, failed, done\\)").
+		WithArgs(1, "/tmp/archive-1.zip", 40, 0, 0, 0, 0, false).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created"}).AddRow(7, created))
+
+	job := &ImportJob{File: "/tmp/archive-1.zip", Total: 40}
+	err = NewImportJobModel(db).Insert(job, 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 7, job.Id)
+	assert.Equal(t, created, job.Created)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestImportJobModelGet(t *testing.T) {
+	created := time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		get           func(m ImportJobModel) (*ImportJob, error)
+		query         string
+		args          []driver.Value
+		rows          *sqlmock.Rows
+		expectedJob   *ImportJob
+		expectedError error
+	}{
+		{
+			name:        "by id",
+			get:         func(m ImportJobModel) (*ImportJob, error) { return m.Get(7, 1) },
+			query:       "SELECT id, file, total, processed, imported, skipped, failed, done, created FROM import_jobs\\s+WHERE id = \\$1 AND user_id = \\$2",
+			args:        []driver.Value{7, 1},
+			rows:        sqlmock.NewRows(importJobColumns).AddRow(7, "/tmp/archive-1.zip", 40, 12, 10, 1, 1, false, created),
+			expectedJob: &ImportJob{Id: 7, File: "/tmp/archive-1.zip", Total: 40, Processed: 12, Imported: 10, Skipped: 1, Failed: 1, Created: created},
+		},
+		{
+			name:          "job of another user",
+			get:           func(m ImportJobModel) (*ImportJob, error) { return m.Get(7, 2) },
+			query:         "FROM import_jobs\\s+WHERE id = \\$1 AND user_id = \\$2",
+			args:          []driver.Value{7, 2},
+			rows:          sqlmock.NewRows(importJobColumns),
+			expectedError: ErrNoRecord,
+		},
+		{
+			name:        "running",
+			get:         func(m ImportJobModel) (*ImportJob, error) { return m.GetRunning(1) },
+			query:       "FROM import_jobs\\s+WHERE user_id = \\$1 AND NOT done ORDER BY created DESC, id DESC LIMIT 1",
+			args:        []driver.Value{1},
+			rows:        sqlmock.NewRows(importJobColumns).AddRow(8, "/tmp/archive-2.zip", 5, 0, 0, 0, 0, false, created),
+			expectedJob: &ImportJob{Id: 8, File: "/tmp/archive-2.zip", Total: 5, Created: created},
+		},
+		{
+			name:          "nothing running",
+			get:           func(m ImportJobModel) (*ImportJob, error) { return m.GetRunning(1) },
+			query:         "FROM import_jobs\\s+WHERE user_id = \\$1 AND NOT done",
+			args:          []driver.Value{1},
+			rows:          sqlmock.NewRows(importJobColumns),
+			expectedError: ErrNoRecord,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer func() {
+				_ = db.Close()
+			}()
+
+			mock.ExpectQuery(tt.query).WithArgs(tt.args...).WillReturnRows(tt.rows)
+
+			job, err := tt.get(NewImportJobModel(db))
+
+			assert.ErrorIs(t, err, tt.expectedError)
+			assert.Equal(t, tt.expectedJob, job)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestImportJobModelUpdate(t *testing.T) {
+	tests := []struct {
+		name          string
+		rowsAffected  int64
+		expectedError error
+	}{
+		{name: "progress stored", rowsAffected: 1},
+		{name: "job of another user", rowsAffected: 0, expectedError: ErrNoRecord},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer func() {
+				_ = db.Close()
+			}()
+
+			mock.ExpectExec("UPDATE import_jobs SET processed = \\$1, imported = \\$2, skipped = \\$3, failed = \\$4, done = \\$5\\s+WHERE id = \\$6 AND user_id = \\$7").
+				WithArgs(40, 37, 2, 1, true, 7, 1).
+				WillReturnResult(sqlmock.NewResult(0, tt.rowsAffected))
+
+			job := &ImportJob{Id: 7, Total: 40, Processed: 40, Imported: 37, Skipped: 2, Failed: 1, Done: true}
+			err = NewImportJobModel(db).Update(job, 1)
+
+			assert.ErrorIs(t, err, tt.expectedError)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestImportJobModelCountRunning(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer func() {
+		_ = db.Close()
+	}()
+
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM import_jobs WHERE user_id = \\$1 AND NOT done").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+
+	count, err := NewImportJobModel(db).CountRunning(1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestImportJobModelExpire(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer func() {
+		_ = db.Close()
+	}()
+
+	before := time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("UPDATE import_jobs SET failed = failed \\+ total - processed, processed = total, done = true\\s+WHERE NOT done AND created < \\$1 RETURNING file").
+		WithArgs(before).
+		WillReturnRows(sqlmock.NewRows([]string{"file"}).AddRow("/tmp/archive-1.zip").AddRow("/tmp/archive-2.zip"))
+
+	files, err := NewImportJobModel(db).Expire(before)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/tmp/archive-1.zip", "/tmp/archive-2.zip"}, files)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -3,15 +3,18 @@
 package models
 
 import (
+	"bytes"
 	"database/sql"
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/ory/dockertest/v3"
 	"github.com/ory/dockertest/v3/docker"
+	"github.com/rockstaedt/swimmate/internal/totp"
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/crypto/bcrypt"
 
@@ -93,19 +96,165 @@ func createSchema(db *sql.DB) error {
 			first_name character varying(150) NOT NULL,
 			last_name character varying(150) NOT NULL,
 			email character varying(254) NOT NULL,
-			date_joined timestamp with time zone NOT NULL
+			date_joined timestamp with time zone NOT NULL,
+			distance_unit varchar(2) NOT NULL DEFAULT 'm' CHECK (distance_unit IN ('m', 'yd'))
+		);
+
+		DO $$ BEGIN
+			CREATE TYPE swim_stroke AS ENUM ('freestyle', 'breaststroke', 'backstroke', 'butterfly', 'mixed');
+		EXCEPTION
+			WHEN duplicate_object THEN NULL;
+		END $$;
+
+		CREATE TABLE IF NOT EXISTS locations (
+			id bigserial PRIMARY KEY,
+			user_id integer NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			name varchar(100) NOT NULL CHECK (name <> ''),
+			pool_length integer CHECK (pool_length > 0),
+			pool_unit varchar(2) CHECK (pool_unit IN ('m', 'yd')),
+			indoor boolean NOT NULL DEFAULT true,
+			UNIQUE (user_id, name)
 		);
 
 		CREATE TABLE IF NOT EXISTS swims (
 			id bigint PRIMARY KEY DEFAULT nextval('tracks_track_id_seq'),
 			date date NOT NULL,
 			distance_m integer NOT NULL,
-			assessment integer NOT NULL,
-			user_id integer NOT NULL REFERENCES users(id)
+			effort integer CHECK (effort BETWEEN 1 AND 10),
+			feel integer NOT NULL DEFAULT 3 CHECK (feel BETWEEN 1 AND 5),
+			user_id integer NOT NULL REFERENCES users(id),
+			duration_s integer CHECK (duration_s > 0),
+			stroke swim_stroke NOT NULL DEFAULT 'freestyle',
+			pool_length integer CHECK (pool_length > 0),
+			pool_unit varchar(2) CHECK (pool_unit IN ('m', 'yd')),
+			laps integer CHECK (laps > 0),
+			notes text NOT NULL DEFAULT '',
+			notes_tsv tsvector GENERATED ALWAYS AS (to_tsvector('simple', notes)) STORED,
+			location_id bigint REFERENCES locations(id) ON DELETE SET NULL,
+			source varchar(200),
+			CONSTRAINT swims_user_id_source_key UNIQUE (user_id, source)
+		);
+
+		CREATE TABLE IF NOT EXISTS swim_sets (
+			id bigserial PRIMARY KEY,
+			swim_id bigint NOT NULL REFERENCES swims(id) ON DELETE CASCADE,
+			position integer NOT NULL CHECK (position > 0),
+			repetitions integer NOT NULL CHECK (repetitions > 0),
+			distance_m integer NOT NULL CHECK (distance_m > 0),
+			stroke swim_stroke NOT NULL DEFAULT 'freestyle',
+			interval_s integer CHECK (interval_s > 0),
+			rest_s integer CHECK (rest_s > 0),
+			UNIQUE (swim_id, position)
+		);
+
+		CREATE TABLE IF NOT EXISTS tags (
+			id bigserial PRIMARY KEY,
+			user_id integer NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			name varchar(50) NOT NULL CHECK (name <> ''),
+			UNIQUE (user_id, name)
+		);
+
+		CREATE TABLE IF NOT EXISTS swim_tags (
+			swim_id bigint NOT NULL REFERENCES swims(id) ON DELETE CASCADE,
+			tag_id bigint NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+			PRIMARY KEY (swim_id, tag_id)
+		);
+
+		CREATE TABLE IF NOT EXISTS import_jobs (
+			id bigserial PRIMARY KEY,
+			user_id integer NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			file text NOT NULL,
+			total integer NOT NULL CHECK (total >= 0),
+			processed integer NOT NULL DEFAULT 0 CHECK (processed BETWEEN 0 AND total),
+			imported integer NOT NULL DEFAULT 0,
+			skipped integer NOT NULL DEFAULT 0,
+			failed integer NOT NULL DEFAULT 0,
+			done boolean NOT NULL DEFAULT false,
+			created timestamptz NOT NULL DEFAULT now()
+		);
+
+		CREATE TABLE IF NOT EXISTS calendar_feeds (
+			user_id integer PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+			token_hash bytea NOT NULL UNIQUE,
+			created timestamptz NOT NULL DEFAULT now()
+		);
+
+		CREATE TABLE IF NOT EXISTS access_tokens (
+			id bigserial PRIMARY KEY,
+			user_id integer NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			name varchar(100) NOT NULL CHECK (name <> ''),
+			token_hash bytea NOT NULL UNIQUE,
+			scopes text[] NOT NULL CHECK (cardinality(scopes) > 0),
+			last_used timestamptz,
+			created timestamptz NOT NULL DEFAULT now(),
+			UNIQUE (user_id, name)
+		);
+
+		CREATE TABLE IF NOT EXISTS webhooks (
+			id bigserial PRIMARY KEY,
+			user_id integer NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			url varchar(2000) NOT NULL CHECK (url <> ''),
+			secret text NOT NULL,
+			created timestamptz NOT NULL DEFAULT now(),
+			UNIQUE (user_id, url)
+		);
+
+		CREATE TABLE IF NOT EXISTS webhook_deliveries (
+			id bigserial PRIMARY KEY,
+			webhook_id bigint NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+			event varchar(50) NOT NULL,
+			payload jsonb NOT NULL,
+			state varchar(10) NOT NULL DEFAULT 'pending' CHECK (state IN ('pending', 'delivered', 'failed')),
+			attempts integer NOT NULL DEFAULT 0,
+			status_code integer,
+			error text NOT NULL DEFAULT '',
+			next_attempt timestamptz NOT NULL DEFAULT now(),
+			created timestamptz NOT NULL DEFAULT now(),
+			updated timestamptz NOT NULL DEFAULT now()
+		);
+
+		CREATE UNIQUE INDEX IF NOT EXISTS users_username_lower_key ON users (lower(username));
+		CREATE UNIQUE INDEX IF NOT EXISTS users_email_lower_key ON users (lower(email)) WHERE email <> '';
+
+		CREATE TABLE IF NOT EXISTS invites (
+			id bigserial PRIMARY KEY,
+			user_id integer NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			code_hash bytea NOT NULL UNIQUE,
+			expires timestamptz NOT NULL,
+			used timestamptz,
+			created timestamptz NOT NULL DEFAULT now()
+		);
+
+		CREATE TABLE IF NOT EXISTS password_resets (
+			id bigserial PRIMARY KEY,
+			user_id integer NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			token_hash bytea NOT NULL UNIQUE,
+			expires timestamptz NOT NULL,
+			used timestamptz,
+			created timestamptz NOT NULL DEFAULT now()
+		);
+
+		CREATE TABLE IF NOT EXISTS two_factor (
+			user_id integer PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+			secret bytea NOT NULL,
+			enabled timestamptz,
+			last_counter bigint NOT NULL DEFAULT 0,
+			failed_attempts integer NOT NULL DEFAULT 0,
+			locked_until timestamptz,
+			created timestamptz NOT NULL DEFAULT now()
+		);
+
+		CREATE TABLE IF NOT EXISTS recovery_codes (
+			id bigserial PRIMARY KEY,
+			user_id integer NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			code_hash bytea NOT NULL,
+			used timestamptz,
+			UNIQUE (user_id, code_hash)
 		);
 
 		CREATE INDEX IF NOT EXISTS idx_swims_user_id ON swims(user_id);
 		CREATE INDEX IF NOT EXISTS idx_swims_date ON swims(date);
+		CREATE INDEX IF NOT EXISTS idx_swims_notes_tsv ON swims USING gin (notes_tsv);
 	`
 
 	_, err := db.Exec(schema)
@@ -202,14 +351,62 @@ func TestIntegrationSwimCRUD(t *testing.T) {
 
 	t.Run("insert and retrieve swim", func(t *testing.T) {
 		date := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
-		err := swimModel.Insert(date, 1500, 2, userID)
+		err := swimModel.Insert(&Swim{
+			Date:      date,
+			DistanceM: 1500,
+			Effort:    7,
+			Feel:      FeelGood,
+			Duration:  25 * time.Minute,
+			Stroke:    StrokeBackstroke,
+			Pool:      Pool{Length: 50, Unit: UnitMeters},
+			Laps:      30,
+		}, userID)
 		assert.NoError(t, err)
 
 		swim, err := swimModel.Get()
 		assert.NoError(t, err)
 		assert.Equal(t, date.Format("2006-01-02"), swim.Date.Format("2006-01-02"))
 		assert.Equal(t, 1500, swim.DistanceM)
-		assert.Equal(t, 2, swim.Assessment)
+		assert.Equal(t, Effort(7), swim.Effort)
+		assert.Equal(t, FeelGood, swim.Feel)
+		assert.Equal(t, 25*time.Minute, swim.Duration)
+		assert.Equal(t, StrokeBackstroke, swim.Stroke)
+		assert.Equal(t, Pool{Length: 50, Unit: UnitMeters}, swim.Pool)
+		assert.Equal(t, 30, swim.Laps)
+	})
+
+	t.Run("insert, update and retrieve sets", func(t *testing.T) {
+		swim := &Swim{
+			Date:      time.Date(2024, 1, 20, 0, 0, 0, 0, time.UTC),
+			DistanceM: 1400,
+			Feel:      3,
+			Stroke:    StrokeFreestyle,
+			Sets: []SwimSet{
+				{Repetitions: 1, DistanceM: 400, Stroke: StrokeFreestyle},
+				{Repetitions: 8, DistanceM: 100, Stroke: StrokeFreestyle, Interval: 105 * time.Second},
+				{Repetitions: 1, DistanceM: 200, Stroke: StrokeBackstroke, Rest: 30 * time.Second},
+			},
+		}
+		err := swimModel.Insert(swim, userID)
+		assert.NoError(t, err)
+		assert.NotZero(t, swim.Id)
+
+		stored, err := swimModel.GetByID(userID, swim.Id)
+		assert.NoError(t, err)
+		assert.Equal(t, swim.Sets, stored.Sets)
+
+		swim.Sets = swim.Sets[1:]
+		swim.DistanceM = SetsDistanceM(swim.Sets)
+		err = swimModel.Update(swim, userID)
+		assert.NoError(t, err)
+
+		stored, err = swimModel.GetByID(userID, swim.Id)
+		assert.NoError(t, err)
+		assert.Equal(t, 1000, stored.DistanceM)
+		assert.Equal(t, swim.Sets, stored.Sets)
+
+		err = swimModel.Delete(swim.Id, userID)
+		assert.NoError(t, err)
 	})
 
 	t.Run("get all swims ordered by date ASC", func(t *testing.T) {
@@ -221,7 +418,7 @@ func TestIntegrationSwimCRUD(t *testing.T) {
 		}
 
 		for _, date := range dates {
-			err := swimModel.Insert(date, 1000, 1, userID)
+			err := swimModel.Insert(&Swim{Date: date, DistanceM: 1000, Feel: 3, Stroke: StrokeFreestyle}, userID)
 			assert.NoError(t, err)
 		}
 
@@ -235,14 +432,36 @@ func TestIntegrationSwimCRUD(t *testing.T) {
 		}
 	})
 
+	t.Run("pagination after a key", func(t *testing.T) {
+		page1, err := swimModel.GetAfter(userID, 2, nil, SwimSortDate, SortDirectionDesc, SwimFilter{})
+		assert.NoError(t, err)
+		assert.Len(t, page1, 2)
+
+		// A swim added before the key does not move the next page
+		added := &Swim{Date: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC), DistanceM: 1000, Feel: 3, Stroke: StrokeFreestyle}
+		err = swimModel.Insert(added, userID)
+		assert.NoError(t, err)
+		defer func() {
+			assert.NoError(t, swimModel.Delete(added.Id, userID))
+		}()
+
+		key := page1[1].Key()
+		page2, err := swimModel.GetAfter(userID, 2, &key, SwimSortDate, SortDirectionDesc, SwimFilter{})
+		assert.NoError(t, err)
+		assert.NotEmpty(t, page2)
+		for _, swim := range page2 {
+			assert.True(t, swim.Date.Before(key.Date) || swim.Date.Equal(key.Date) && swim.Id < key.Id)
+		}
+	})
+
 	t.Run("pagination", func(t *testing.T) {
 		// Get first page
-		page1, err := swimModel.GetPaginated(userID, 2, 0, SwimSortDate, SortDirectionDesc)
+		page1, err := swimModel.GetPaginated(userID, 2, 0, SwimSortDate, SortDirectionDesc, SwimFilter{})
 		assert.NoError(t, err)
 		assert.LessOrEqual(t, len(page1), 2)
 
 		// Get second page
-		page2, err := swimModel.GetPaginated(userID, 2, 2, SwimSortDate, SortDirectionDesc)
+		page2, err := swimModel.GetPaginated(userID, 2, 2, SwimSortDate, SortDirectionDesc, SwimFilter{})
 		assert.NoError(t, err)
 
 		// Verify DESC ordering (most recent first)
@@ -255,6 +474,103 @@ func TestIntegrationSwimCRUD(t *testing.T) {
 			assert.NotEqual(t, page1[0].Date, page2[0].Date)
 		}
 	})
+
+	t.Run("tags", func(t *testing.T) {
+		tagModel := NewTagModel(db)
+
+		tagged := &Swim{Date: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), DistanceM: 2500, Feel: 4, Stroke: StrokeFreestyle, Tags: []string{"open-water", "with club"}}
+		err := swimModel.Insert(tagged, userID)
+		assert.NoError(t, err)
+		err = swimModel.Insert(&Swim{Date: time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC), DistanceM: 1000, Feel: 3, Stroke: StrokeFreestyle, Tags: []string{"technique"}}, userID)
+		assert.NoError(t, err)
+
+		tags, err := tagModel.GetAll(userID)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"open-water", "technique", "with club"}, tags)
+
+		swims, err := swimModel.GetPaginated(userID, 10, 0, SwimSortDate, SortDirectionAsc, SwimFilter{Tags: []string{"open-water", "with club"}})
+		assert.NoError(t, err)
+		if assert.Len(t, swims, 1) {
+			assert.Equal(t, tagged.Id, swims[0].Id)
+			assert.Equal(t, []string{"open-water", "with club"}, swims[0].Tags)
+		}
+
+		summary := swimModel.Summarize(userID, SwimFilter{Tags: []string{"technique"}})
+		assert.Equal(t, 1, summary.TotalCount)
+		assert.Equal(t, 1000, summary.TotalDistance)
+
+		all, err := swimModel.GetAll(userID)
+		assert.NoError(t, err)
+		for _, swim := range all {
+			if swim.Id == tagged.Id {
+				assert.Equal(t, []string{"open-water", "with club"}, swim.Tags)
+			}
+		}
+
+		tagged.Tags = []string{"open-water"}
+		err = swimModel.Update(tagged, userID)
+		assert.NoError(t, err)
+
+		stored, err := swimModel.GetByID(userID, tagged.Id)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"open-water"}, stored.Tags)
+	})
+
+	t.Run("locations", func(t *testing.T) {
+		locationModel := NewLocationModel(db)
+
+		lake := &Location{Name: "Lake"}
+		err := locationModel.Insert(lake, userID)
+		assert.NoError(t, err)
+		stadtbad := &Location{Name: "Stadtbad", Pool: Pool{Length: 25, Unit: UnitMeters}, Indoor: true}
+		err = locationModel.Insert(stadtbad, userID)
+		assert.NoError(t, err)
+
+		err = locationModel.Insert(&Location{Name: "Lake"}, userID)
+		assert.ErrorIs(t, err, ErrDuplicateName)
+
+		err = swimModel.Insert(&Swim{Date: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), DistanceM: 2000, Feel: 3, Stroke: StrokeFreestyle, LocationId: stadtbad.Id}, userID)
+		assert.NoError(t, err)
+		err = swimModel.Insert(&Swim{Date: time.Date(2024, 5, 3, 0, 0, 0, 0, time.UTC), DistanceM: 1500, Feel: 3, Stroke: StrokeFreestyle, LocationId: stadtbad.Id}, userID)
+		assert.NoError(t, err)
+
+		lastUsed, err := locationModel.LastUsedID(userID)
+		assert.NoError(t, err)
+		assert.Equal(t, stadtbad.Id, lastUsed)
+
+		stats, err := locationModel.Stats(userID)
+		assert.NoError(t, err)
+		if assert.Len(t, stats, 2) {
+			assert.Equal(t, "Lake", stats[0].Name)
+			assert.Equal(t, 0, stats[0].Count)
+			assert.True(t, stats[0].LastVisit.IsZero())
+			assert.Equal(t, 2, stats[1].Count)
+			assert.Equal(t, 3500, stats[1].DistanceM)
+			assert.Equal(t, "2024-05-03", stats[1].LastVisit.Format("2006-01-02"))
+		}
+	})
+
+	t.Run("search notes", func(t *testing.T) {
+		notes := []string{"Shoulder felt tight", "New goggles, no leaks", "Goggles fogged up"}
+		for i, note := range notes {
+			date := time.Date(2024, 2, i+1, 0, 0, 0, 0, time.UTC)
+			err := swimModel.Insert(&Swim{Date: date, DistanceM: 1000, Feel: 3, Stroke: StrokeFreestyle, Notes: note}, userID)
+			assert.NoError(t, err)
+		}
+
+		swims, err := swimModel.GetPaginated(userID, 10, 0, SwimSortDate, SortDirectionAsc, SwimFilter{Search: "goggles"})
+		assert.NoError(t, err)
+		if assert.Len(t, swims, 2) {
+			assert.Equal(t, notes[1], swims[0].Notes)
+			assert.Equal(t, notes[2], swims[1].Notes)
+		}
+
+		swims, err = swimModel.GetPaginated(userID, 10, 0, SwimSortDate, SortDirectionAsc, SwimFilter{Search: "goggles -fogged"})
+		assert.NoError(t, err)
+		if assert.Len(t, swims, 1) {
+			assert.Equal(t, notes[1], swims[0].Notes)
+		}
+	})
 }
 
 func TestIntegrationSummarize(t *testing.T) {
@@ -273,23 +589,23 @@ func TestIntegrationSummarize(t *testing.T) {
 
 	// Insert swims across different months and years
 	testData := []struct {
-		date       time.Time
-		distanceM  int
-		assessment int
+		date      time.Time
+		distanceM int
+		feel      Feel
 	}{
-		{time.Date(2023, 12, 15, 0, 0, 0, 0, time.UTC), 1000, 1},
-		{time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC), 1500, 2},
-		{time.Date(2024, 1, 20, 0, 0, 0, 0, time.UTC), 2000, 2},
-		{time.Date(2024, 2, 5, 0, 0, 0, 0, time.UTC), 1200, 1},
-		{time.Now().AddDate(0, 0, -1), 800, 1}, // Yesterday (for weekly test)
+		{time.Date(2023, 12, 15, 0, 0, 0, 0, time.UTC), 1000, FeelOkay},
+		{time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC), 1500, FeelGood},
+		{time.Date(2024, 1, 20, 0, 0, 0, 0, time.UTC), 2000, FeelGood},
+		{time.Date(2024, 2, 5, 0, 0, 0, 0, time.UTC), 1200, FeelOkay},
+		{time.Now().AddDate(0, 0, -1), 800, FeelOkay}, // Yesterday (for weekly test)
 	}
 
 	for _, td := range testData {
-		err := swimModel.Insert(td.date, td.distanceM, td.assessment, userID)
+		err := swimModel.Insert(&Swim{Date: td.date, DistanceM: td.distanceM, Feel: td.feel, Stroke: StrokeFreestyle}, userID)
 		assert.NoError(t, err)
 	}
 
-	summary := swimModel.Summarize(userID)
+	summary := swimModel.Summarize(userID, SwimFilter{})
 
 	t.Run("total aggregations", func(t *testing.T) {
 		expectedTotal := 1000 + 1500 + 2000 + 1200 + 800
@@ -370,10 +686,10 @@ func TestIntegrationMultipleUsers(t *testing.T) {
 
 	// Insert swims for both users
 	date := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
-	err = swimModel.Insert(date, 1000, 1, user1ID)
+	err = swimModel.Insert(&Swim{Date: date, DistanceM: 1000, Feel: 3, Stroke: StrokeFreestyle}, user1ID)
 	assert.NoError(t, err)
 
-	err = swimModel.Insert(date, 2000, 2, user2ID)
+	err = swimModel.Insert(&Swim{Date: date, DistanceM: 2000, Feel: 4, Stroke: StrokeMixed}, user2ID)
 	assert.NoError(t, err)
 
 	// Verify user isolation
@@ -388,11 +704,506 @@ func TestIntegrationMultipleUsers(t *testing.T) {
 	assert.Equal(t, 2000, user2Swims[0].DistanceM)
 
 	// Verify summaries are isolated
-	summary1 := swimModel.Summarize(user1ID)
+	summary1 := swimModel.Summarize(user1ID, SwimFilter{})
 	assert.Equal(t, 1000, summary1.TotalDistance)
 	assert.Equal(t, 1, summary1.TotalCount)
 
-	summary2 := swimModel.Summarize(user2ID)
+	summary2 := swimModel.Summarize(user2ID, SwimFilter{})
 	assert.Equal(t, 2000, summary2.TotalDistance)
 	assert.Equal(t, 1, summary2.TotalCount)
 }
+
+func TestIntegrationBackupRestore(t *testing.T) {
+	cleanupTables(t)
+
+	swimModel := NewSwimModel(db)
+	locationModel := NewLocationModel(db)
+	backupModel := NewBackupModel(db)
+
+	var sourceID, targetID int
+	err := db.QueryRow(`
+		INSERT INTO users (username, password, first_name, last_name, email, date_joined)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`, "source", "pass1", "Source", "User", "source@example.com", time.Now()).Scan(&sourceID)
+	assert.NoError(t, err)
+
+	err = db.QueryRow(`
+		INSERT INTO users (username, password, first_name, last_name, email, date_joined)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`, "target", "pass2", "Target", "User", "target@example.com", time.Now()).Scan(&targetID)
+	assert.NoError(t, err)
+
+	lake := &Location{Name: "Lake", Indoor: false}
+	assert.NoError(t, locationModel.Insert(lake, sourceID))
+
+	date := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	assert.NoError(t, swimModel.Insert(&Swim{
+		Date: date, DistanceM: 1500, Effort: 6, Feel: FeelGood, Stroke: StrokeFreestyle,
+		LocationId: lake.Id, Tags: []string{"open water"},
+		Sets: []SwimSet{{Repetitions: 3, DistanceM: 500, Stroke: StrokeFreestyle}},
+	}, sourceID))
+	assert.NoError(t, swimModel.Insert(&Swim{Date: date.AddDate(0, 0, 2), DistanceM: 800, Feel: FeelOkay, Stroke: StrokeMixed}, sourceID))
+	assert.NoError(t, swimModel.Insert(&Swim{Date: date.AddDate(0, 0, 2), DistanceM: 800, Feel: FeelBad, Stroke: StrokeMixed}, targetID))
+
+	source, err := backupModel.Get(sourceID)
+	assert.NoError(t, err)
+
+	t.Run("merge skips swims the account has", func(t *testing.T) {
+		restored, err := backupModel.Restore(targetID, source, RestoreMerge)
+		assert.NoError(t, err)
+		assert.Len(t, restored.Swims, 1)
+		assert.Empty(t, restored.DeletedIds)
+
+		swims, err := swimModel.GetAll(targetID)
+		assert.NoError(t, err)
+		assert.Len(t, swims, 2)
+
+		target, err := NewUserModel(db).Get(targetID)
+		assert.NoError(t, err)
+		assert.Equal(t, "Target", target.FirstName)
+	})
+
+	t.Run("replace restores the same data", func(t *testing.T) {
+		restored, err := backupModel.Restore(targetID, source, RestoreReplace)
+		assert.NoError(t, err)
+		assert.Len(t, restored.Swims, 2)
+		assert.Len(t, restored.DeletedIds, 2, "the swim the account had and the one the merge added")
+
+		target, err := backupModel.Get(targetID)
+		assert.NoError(t, err)
+		assert.Equal(t, "Source", target.User.FirstName)
+		assert.Equal(t, "target", target.User.Username)
+		assert.Len(t, target.Locations, 1)
+		assert.Equal(t, "Lake", target.Locations[0].Name)
+		assert.Len(t, target.Swims, 2)
+		assert.Equal(t, target.Locations[0].Id, target.Swims[0].LocationId)
+		assert.Equal(t, source.Swims[0].Sets, target.Swims[0].Sets)
+		assert.Equal(t, source.Swims[0].Tags, target.Swims[0].Tags)
+		assert.Equal(t, FeelOkay, target.Swims[1].Feel)
+	})
+}
+
+func TestIntegrationImportJobs(t *testing.T) {
+	cleanupTables(t)
+
+	importJobModel := NewImportJobModel(db)
+
+	var userID, otherID int
+	err := db.QueryRow(`
+		INSERT INTO users (username, password, first_name, last_name, email, date_joined)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`, "importer", "pass1", "Import", "User", "import@example.com", time.Now()).Scan(&userID)
+	assert.NoError(t, err)
+
+	err = db.QueryRow(`
+		INSERT INTO users (username, password, first_name, last_name, email, date_joined)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`, "other", "pass2", "Other", "User", "other@example.com", time.Now()).Scan(&otherID)
+	assert.NoError(t, err)
+
+	_, err = importJobModel.GetRunning(userID)
+	assert.ErrorIs(t, err, ErrNoRecord)
+
+	job := &ImportJob{File: "/tmp/archive-1.zip", Total: 3}
+	assert.NoError(t, importJobModel.Insert(job, userID))
+	assert.NotZero(t, job.Id)
+	assert.False(t, job.Created.IsZero())
+
+	running, err := importJobModel.GetRunning(userID)
+	assert.NoError(t, err)
+	assert.Equal(t, job.Id, running.Id)
+
+	count, err := importJobModel.CountRunning(userID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	_, err = importJobModel.Get(job.Id, otherID)
+	assert.ErrorIs(t, err, ErrNoRecord)
+
+	job.Processed, job.Imported, job.Failed = 3, 2, 1
+	job.Done = true
+	assert.NoError(t, importJobModel.Update(job, userID))
+	assert.ErrorIs(t, importJobModel.Update(job, otherID), ErrNoRecord)
+
+	stored, err := importJobModel.Get(job.Id, userID)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, stored.Processed)
+	assert.True(t, stored.Done)
+
+	_, err = importJobModel.GetRunning(userID)
+	assert.ErrorIs(t, err, ErrNoRecord)
+
+	job.Processed = 4
+	assert.Error(t, importJobModel.Update(job, userID), "more processed swims than listed are rejected")
+
+	stale := &ImportJob{File: "/tmp/archive-2.zip", Total: 5, Processed: 2, Imported: 2}
+	assert.NoError(t, importJobModel.Insert(stale, userID))
+
+	files, err := importJobModel.Expire(stale.Created)
+	assert.NoError(t, err)
+	assert.Empty(t, files, "jobs started since are kept")
+
+	files, err = importJobModel.Expire(stale.Created.Add(time.Second))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/tmp/archive-2.zip"}, files, "done jobs are not expired again")
+
+	stored, err = importJobModel.Get(stale.Id, userID)
+	assert.NoError(t, err)
+	assert.True(t, stored.Done)
+	assert.Equal(t, 5, stored.Processed)
+	assert.Equal(t, 3, stored.Failed)
+}
+
+func TestIntegrationCalendarFeeds(t *testing.T) {
+	cleanupTables(t)
+
+	calendarModel := NewCalendarModel(db)
+
+	var userID int
+	err := db.QueryRow(`
+		INSERT INTO users (username, password, first_name, last_name, email, date_joined)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`, "subscriber", "pass1", "Calendar", "User", "calendar@example.com", time.Now()).Scan(&userID)
+	assert.NoError(t, err)
+
+	_, err = calendarModel.Get(userID)
+	assert.ErrorIs(t, err, ErrNoRecord)
+
+	token, err := calendarModel.Reset(userID)
+	assert.NoError(t, err)
+
+	feed, err := calendarModel.Get(userID)
+	assert.NoError(t, err)
+	assert.False(t, feed.Created.IsZero())
+
+	owner, err := calendarModel.UserID(token)
+	assert.NoError(t, err)
+	assert.Equal(t, userID, owner)
+
+	newToken, err := calendarModel.Reset(userID)
+	assert.NoError(t, err)
+	assert.NotEqual(t, token, newToken)
+
+	_, err = calendarModel.UserID(token)
+	assert.ErrorIs(t, err, ErrNoRecord, "a reset revokes the old token")
+
+	assert.NoError(t, calendarModel.Delete(userID))
+	assert.ErrorIs(t, calendarModel.Delete(userID), ErrNoRecord)
+
+	_, err = calendarModel.UserID(newToken)
+	assert.ErrorIs(t, err, ErrNoRecord)
+}
+
+func TestIntegrationAccessTokens(t *testing.T) {
+	cleanupTables(t)
+
+	tokenModel := NewAccessTokenModel(db)
+
+	var userID int
+	err := db.QueryRow(`
+		INSERT INTO users (username, password, first_name, last_name, email, date_joined)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`, "scripter", "pass1", "Script", "User", "script@example.com", time.Now()).Scan(&userID)
+	assert.NoError(t, err)
+
+	token := &AccessToken{Name: "backup script", Scopes: []Scope{ScopeSwimsRead}}
+	secret, err := tokenModel.Insert(token, userID)
+	assert.NoError(t, err)
+	assert.NotZero(t, token.Id)
+
+	_, err = tokenModel.Insert(&AccessToken{Name: "backup script", Scopes: []Scope{ScopeSwimsWrite}}, userID)
+	assert.ErrorIs(t, err, ErrDuplicateName)
+
+	tokens, err := tokenModel.GetAll(userID)
+	assert.NoError(t, err)
+	assert.Len(t, tokens, 1)
+	assert.True(t, tokens[0].LastUsed.IsZero())
+
+	owner, used, err := tokenModel.Authenticate(secret)
+	assert.NoError(t, err)
+	assert.Equal(t, userID, owner)
+	assert.Equal(t, []Scope{ScopeSwimsRead}, used.Scopes)
+	assert.False(t, used.LastUsed.IsZero())
+
+	_, _, err = tokenModel.Authenticate(secret + "x")
+	assert.ErrorIs(t, err, ErrNoRecord)
+
+	assert.ErrorIs(t, tokenModel.Delete(token.Id, userID+1), ErrNoRecord)
+	assert.NoError(t, tokenModel.Delete(token.Id, userID))
+
+	_, _, err = tokenModel.Authenticate(secret)
+	assert.ErrorIs(t, err, ErrNoRecord, "a revoked token no longer authenticates")
+}
+
+func TestIntegrationWebhooks(t *testing.T) {
+	cleanupTables(t)
+
+	webhookModel := NewWebhookModel(db)
+
+	var userID int
+	err := db.QueryRow(`
+		INSERT INTO users (username, password, first_name, last_name, email, date_joined)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`, "hooker", "pass1", "Hook", "User", "hook@example.com", time.Now()).Scan(&userID)
+	assert.NoError(t, err)
+
+	queued, err := webhookModel.Enqueue(userID, EventSwimCreated, []byte(`{"swim_id": 1}`))
+	assert.NoError(t, err)
+	assert.Zero(t, queued, "without webhooks nothing is queued")
+
+	hook := &Webhook{URL: "https://example.com/hook"}
+	assert.NoError(t, webhookModel.Insert(hook, userID))
+	assert.NotEmpty(t, hook.Secret)
+	assert.ErrorIs(t, webhookModel.Insert(&Webhook{URL: "https://example.com/hook"}, userID), ErrDuplicateURL)
+
+	queued, err = webhookModel.Enqueue(userID, EventSwimCreated, []byte(`{"swim_id": 1}`))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, queued)
+
+	due, err := webhookModel.Due(10, time.Minute)
+	assert.NoError(t, err)
+	if assert.Len(t, due, 1) {
+		assert.Equal(t, hook.Secret, due[0].Secret)
+		assert.JSONEq(t, `{"swim_id": 1}`, string(due[0].Payload))
+
+		again, err := webhookModel.Due(10, time.Minute)
+		assert.NoError(t, err)
+		assert.Empty(t, again, "claimed deliveries are not due until the lease runs out")
+
+		due[0].State = DeliveryFailed
+		due[0].Attempts = 8
+		due[0].Error = "connection refused"
+		assert.NoError(t, webhookModel.Record(due[0]))
+	}
+
+	deliveries, err := webhookModel.GetDeliveries(userID, 50)
+	assert.NoError(t, err)
+	if assert.Len(t, deliveries, 1) {
+		assert.Equal(t, DeliveryFailed, deliveries[0].State)
+		assert.Equal(t, 8, deliveries[0].Attempts)
+		assert.Zero(t, deliveries[0].StatusCode)
+
+		assert.ErrorIs(t, webhookModel.Redeliver(deliveries[0].Id, userID+1), ErrNoRecord)
+		assert.NoError(t, webhookModel.Redeliver(deliveries[0].Id, userID))
+	}
+
+	due, err = webhookModel.Due(10, time.Minute)
+	assert.NoError(t, err)
+	assert.Len(t, due, 1, "a redelivery is due right away")
+
+	assert.NoError(t, webhookModel.Delete(hook.Id, userID))
+	deliveries, err = webhookModel.GetDeliveries(userID, 50)
+	assert.NoError(t, err)
+	assert.Empty(t, deliveries, "the deliveries are removed with their webhook")
+}
+
+func TestIntegrationSignup(t *testing.T) {
+	cleanupTables(t)
+
+	userModel := NewUserModel(db)
+	inviteModel := NewInviteModel(db)
+
+	user := &User{Username: "Jane", FirstName: "Jane", LastName: "Doe", Email: "Jane@example.com"}
+	assert.NoError(t, userModel.Insert(user, "correct horse battery"))
+	assert.NotZero(t, user.ID)
+
+	id, err := userModel.Authenticate("Jane", "correct horse battery")
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, id)
+
+	err = userModel.Insert(&User{Username: "jane", Email: "other@example.com"}, "correct horse battery")
+	assert.ErrorIs(t, err, ErrDuplicateUsername, "usernames are unique regardless of case")
+	err = userModel.Insert(&User{Username: "john", Email: "jane@EXAMPLE.com"}, "correct horse battery")
+	assert.ErrorIs(t, err, ErrDuplicateEmail, "email addresses are unique regardless of case")
+	assert.NoError(t, userModel.Insert(&User{Username: "john"}, "correct horse battery"))
+	assert.NoError(t, userModel.Insert(&User{Username: "jim"}, "correct horse battery"), "accounts may have no email address")
+
+	code, err := inviteModel.Insert(user.ID)
+	assert.NoError(t, err)
+
+	inviteID, err := inviteModel.Redeem(code)
+	assert.NoError(t, err)
+	_, err = inviteModel.Redeem(code)
+	assert.ErrorIs(t, err, ErrNoRecord, "an invite is used once")
+
+	assert.NoError(t, inviteModel.Release(inviteID))
+	_, err = inviteModel.Redeem(code)
+	assert.NoError(t, err, "a released invite can be used again")
+
+	_, err = db.Exec(`UPDATE invites SET used = NULL, expires = now() - interval '1 minute'`)
+	assert.NoError(t, err)
+	_, err = inviteModel.Redeem(code)
+	assert.ErrorIs(t, err, ErrNoRecord, "expired invites cannot be used")
+}
+
+func TestIntegrationProfile(t *testing.T) {
+	cleanupTables(t)
+
+	userModel := NewUserModel(db)
+
+	user := &User{Username: "jane", FirstName: "Jane", LastName: "Doe", Email: "jane@example.com"}
+	assert.NoError(t, userModel.Insert(user, "old password"))
+	assert.NoError(t, userModel.Insert(&User{Username: "john", Email: "john@example.com"}, "old password"))
+
+	user.LastName = "Smith"
+	user.Email = "jane@example.org"
+	assert.NoError(t, userModel.UpdateProfile(user))
+
+	stored, err := userModel.Get(user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Smith", stored.LastName)
+	assert.Equal(t, "jane@example.org", stored.Email)
+
+	user.Email = "John@example.com"
+	assert.ErrorIs(t, userModel.UpdateProfile(user), ErrDuplicateEmail)
+
+	assert.ErrorIs(t, userModel.ChangePassword(user.ID, "guess", "new password"), ErrInvalidCredentials)
+	assert.NoError(t, userModel.ChangePassword(user.ID, "old password", "new password"))
+
+	_, err = userModel.Authenticate("jane", "old password")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	id, err := userModel.Authenticate("jane", "new password")
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, id)
+}
+
+func TestIntegrationPasswordReset(t *testing.T) {
+	cleanupTables(t)
+
+	userModel := NewUserModel(db)
+	resetModel := NewPasswordResetModel(db)
+
+	user := &User{Username: "jane", FirstName: "Jane", Email: "jane@example.com"}
+	assert.NoError(t, userModel.Insert(user, "old password"))
+
+	found, err := userModel.GetByEmail("JANE@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, found.ID)
+	_, err = userModel.GetByEmail("john@example.com")
+	assert.ErrorIs(t, err, ErrNoRecord)
+
+	older, err := resetModel.Insert(user.ID)
+	assert.NoError(t, err)
+	token, err := resetModel.Insert(user.ID)
+	assert.NoError(t, err)
+
+	_, err = resetModel.Lookup(older)
+	assert.ErrorIs(t, err, ErrNoRecord, "a new token replaces the older ones")
+	userId, err := resetModel.Lookup(token)
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, userId)
+
+	userId, err = resetModel.Consume(token)
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, userId)
+	_, err = resetModel.Consume(token)
+	assert.ErrorIs(t, err, ErrNoRecord, "tokens work once")
+
+	assert.NoError(t, userModel.SetPassword(user.ID, "new password"))
+	_, err = userModel.Authenticate("jane", "new password")
+	assert.NoError(t, err)
+
+	token, err = resetModel.Insert(user.ID)
+	assert.NoError(t, err)
+	_, err = db.Exec(`UPDATE password_resets SET expires = now() - interval '1 minute'`)
+	assert.NoError(t, err)
+	_, err = resetModel.Consume(token)
+	assert.ErrorIs(t, err, ErrNoRecord, "expired tokens cannot be used")
+}
+
+func TestIntegrationTwoFactor(t *testing.T) {
+	cleanupTables(t)
+
+	userModel := NewUserModel(db)
+	twoFactorModel := NewTwoFactorModel(db, bytes.Repeat([]byte{7}, TwoFactorKeySize))
+
+	user := &User{Username: "jane", FirstName: "Jane", Email: "jane@example.com"}
+	assert.NoError(t, userModel.Insert(user, "old password"))
+
+	_, err := twoFactorModel.Get(user.ID)
+	assert.ErrorIs(t, err, ErrNoRecord)
+
+	// A setup that is not finished does not count
+	_, err = twoFactorModel.Enroll(user.ID)
+	assert.NoError(t, err)
+	secret, err := twoFactorModel.Enroll(user.ID)
+	assert.NoError(t, err)
+	enabled, err := twoFactorModel.Enabled(user.ID)
+	assert.NoError(t, err)
+	assert.False(t, enabled)
+
+	var stored []byte
+	assert.NoError(t, db.QueryRow(`SELECT secret FROM two_factor WHERE user_id = $1`, user.ID).Scan(&stored))
+	assert.False(t, bytes.Contains(stored, secret), "the secret is encrypted at rest")
+
+	_, err = twoFactorModel.Enable(user.ID, "abcdef")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	// Enabling uses up the current code, so the login takes the next one
+	now := time.Now()
+	codes, err := twoFactorModel.Enable(user.ID, totp.Code(secret, totp.Counter(now)-1))
+	assert.NoError(t, err)
+	assert.Len(t, codes, RecoveryCodeCount)
+	_, err = twoFactorModel.Enroll(user.ID)
+	assert.ErrorIs(t, err, ErrTwoFactorEnabled)
+
+	tf, err := twoFactorModel.Get(user.ID)
+	assert.NoError(t, err)
+	assert.True(t, tf.IsEnabled())
+	assert.Equal(t, secret, tf.Secret)
+	assert.Equal(t, RecoveryCodeCount, tf.RecoveryCodes)
+
+	recovery, err := twoFactorModel.Verify(user.ID, totp.Code(secret, totp.Counter(now)))
+	assert.NoError(t, err)
+	assert.False(t, recovery)
+	_, err = twoFactorModel.Verify(user.ID, totp.Code(secret, totp.Counter(now)))
+	assert.ErrorIs(t, err, ErrInvalidCredentials, "a code works once")
+	_, err = twoFactorModel.Verify(user.ID, totp.Code(secret, totp.Counter(now)-1))
+	assert.ErrorIs(t, err, ErrInvalidCredentials, "older codes do not work after a newer one")
+
+	recovery, err = twoFactorModel.Verify(user.ID, strings.ToLower(codes[0]))
+	assert.NoError(t, err)
+	assert.True(t, recovery)
+	_, err = twoFactorModel.Verify(user.ID, codes[0])
+	assert.ErrorIs(t, err, ErrInvalidCredentials, "a recovery code works once")
+
+	newCodes, err := twoFactorModel.RegenerateRecoveryCodes(user.ID)
+	assert.NoError(t, err)
+	_, err = twoFactorModel.Verify(user.ID, codes[1])
+	assert.ErrorIs(t, err, ErrInvalidCredentials, "new recovery codes replace the old ones")
+	_, err = twoFactorModel.Verify(user.ID, newCodes[1])
+	assert.NoError(t, err)
+
+	// Wrong codes add up until no code is accepted for a while
+	for i := 1; i < MaxTwoFactorAttempts; i++ {
+		_, err = twoFactorModel.Verify(user.ID, "wrong")
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	}
+	_, err = twoFactorModel.Verify(user.ID, "wrong")
+	assert.ErrorIs(t, err, ErrTwoFactorLocked)
+	_, err = twoFactorModel.Verify(user.ID, newCodes[2])
+	assert.ErrorIs(t, err, ErrTwoFactorLocked, "right codes are refused while locked")
+
+	_, err = db.Exec(`UPDATE two_factor SET locked_until = now() - interval '1 second' WHERE user_id = $1`, user.ID)
+	assert.NoError(t, err)
+	_, err = twoFactorModel.Verify(user.ID, "wrong")
+	assert.ErrorIs(t, err, ErrInvalidCredentials, "the count starts over after the lock")
+	_, err = twoFactorModel.Verify(user.ID, newCodes[2])
+	assert.NoError(t, err)
+	var failedAttempts int
+	assert.NoError(t, db.QueryRow(`SELECT failed_attempts FROM two_factor WHERE user_id = $1`, user.ID).Scan(&failedAttempts))
+	assert.Zero(t, failedAttempts, "a right code resets the count")
+
+	assert.NoError(t, twoFactorModel.Disable(user.ID))
+	enabled, err = twoFactorModel.Enabled(user.ID)
+	assert.NoError(t, err)
+	assert.False(t, enabled)
+	assert.ErrorIs(t, twoFactorModel.Disable(user.ID), ErrNoRecord)
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// InviteLifetime is how long an invite can be used after it was created.
+const InviteLifetime = 7 * 24 * time.Hour
+
+// InviteModel stores the invites users hand out while registration is
+// invite-only. The codes themselves are only known when they are created,
+// since just a hash of them is stored.
+type InviteModel interface {
+	Insert(userId int) (string, error)
+	Redeem(code string) (int, error)
+	Release(id int) error
+}
+
+type inviteModel struct {
+	DB *sql.DB
+}
+
+func NewInviteModel(db *sql.DB) InviteModel {
+	return &inviteModel{DB: db}
+}
+
+// Insert creates an invite of a user and returns its secret code.
+func (im *inviteModel) Insert(userId int) (string, error) {
+	stmt := `INSERT INTO invites (user_id, code_hash, expires) VALUES ($1, $2, now() + make_interval(secs => $3));`
+
+	code := rand.Text()
+	_, err := im.DB.Exec(stmt, userId, tokenHash(code), InviteLifetime.Seconds())
+	if err != nil {
+		return "", err
+	}
+
+	return code, nil
+}
+
+// Redeem marks the invite of a code as used and returns its id. Unknown,
+// used and expired codes return ErrNoRecord.
+func (im *inviteModel) Redeem(code string) (int, error) {
+	stmt := `UPDATE invites SET used = now() WHERE code_hash = $1 AND used IS NULL AND expires > now()
+		RETURNING id;`
+
+	var id int
+	err := im.DB.QueryRow(stmt, tokenHash(code)).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrNoRecord
+		}
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// Release makes a redeemed invite usable again, for a sign up that failed
+// after the invite was redeemed.
+func (im *inviteModel) Release(id int) error {
+	stmt := `UPDATE invites SET used = NULL WHERE id = $1;`
+
+	_, err := im.DB.Exec(stmt, id)
+	return err
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInviteModelInsert(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer func() {
+		_ = db.Close()
+	}()
+
+	hash := &capturedArg{}
+	mock.ExpectExec("INSERT INTO invites \\(user_id, code_hash, expires\\) VALUES \\(\\$1, \\$2, now\\(\\) \\+ make_interval\\(secs => \\$3\\)\\)").
+		WithArgs(1, hash, float64(7*24*60*60)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	code, err := NewInviteModel(db).Insert(1)
+
+	assert.NoError(t, err)
+	assert.Len(t, code, 26)
+	assert.Equal(t, tokenHash(code), hash.value, "only the hash of the code is stored")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInviteModelRedeem(t *testing.T) {
+	tests := []struct {
+		name        string
+		rows        *sqlmock.Rows
+		expectedId  int
+		expectedErr error
+	}{
+		{name: "open invite", rows: sqlmock.NewRows([]string{"id"}).AddRow(3), expectedId: 3},
+		{name: "unknown, used or expired invite", rows: sqlmock.NewRows([]string{"id"}), expectedErr: ErrNoRecord},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer func() {
+				_ = db.Close()
+			}()
+
+			mock.ExpectQuery("UPDATE invites SET used = now\\(\\) WHERE code_hash = \\$1 AND used IS NULL AND expires > now\\(\\) RETURNING id").
+				WithArgs(tokenHash("CODE")).
+				WillReturnRows(tt.rows)
+
+			id, err := NewInviteModel(db).Redeem("CODE")
+
+			assert.ErrorIs(t, err, tt.expectedErr)
+			assert.Equal(t, tt.expectedId, id)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestInviteModelRelease(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer func() {
+		_ = db.Close()
+	}()
+
+	mock.ExpectExec("UPDATE invites SET used = NULL WHERE id = \\$1").
+		WithArgs(3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = NewInviteModel(db).Release(3)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
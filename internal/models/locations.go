@@ -0,0 +1,196 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// MaxLocationNameLength is the maximum length of a location name, matching
+// the locations.name column.
+const MaxLocationNameLength = 100
+
+// Location is a pool a user swims at.
+type Location struct {
+	Id     int
+	Name   string
+	Pool   Pool
+	Indoor bool
+}
+
+// LocationStats are the figures of all swims at a location.
+type LocationStats struct {
+	Location
+	Count     int
+	DistanceM int
+	// LastVisit is the date of the latest swim, zero if the location has not
+	// been used yet.
+	LastVisit time.Time
+}
+
+type LocationModel interface {
+	GetAll(userId int) ([]*Location, error)
+	GetByID(userId int, locationId int) (*Location, error)
+	Insert(location *Location, userId int) error
+	LastUsedID(userId int) (int, error)
+	Stats(userId int) ([]*LocationStats, error)
+}
+
+type locationModel struct {
+	DB *sql.DB
+}
+
+func NewLocationModel(db *sql.DB) LocationModel {
+	return &locationModel{DB: db}
+}
+
+// GetAll returns all locations of a user, sorted by name.
+func (lm *locationModel) GetAll(userId int) ([]*Location, error) {
+	stmt := `SELECT id, name, pool_length, pool_unit, indoor FROM locations WHERE user_id = $1 ORDER BY name ASC;`
+
+	rows, err := lm.DB.Query(stmt, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err := rows.Close(); err != nil {
+			// Ignore Close error to avoid overriding return error
+			_ = err
+		}
+	}()
+
+	var locations []*Location
+	for rows.Next() {
+		var l Location
+		errScan := scanLocation(rows, &l)
+		if errScan != nil {
+			return nil, errScan
+		}
+
+		locations = append(locations, &l)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return locations, nil
+}
+
+func (lm *locationModel) GetByID(userId int, locationId int) (*Location, error) {
+	stmt := `SELECT id, name, pool_length, pool_unit, indoor FROM locations WHERE id = $1 AND user_id = $2;`
+
+	var l Location
+	err := scanLocation(lm.DB.QueryRow(stmt, locationId, userId), &l)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		}
+		return nil, err
+	}
+
+	return &l, nil
+}
+
+// Insert stores a new location. Names are unique per user, reusing one
+// returns ErrDuplicateName.
+func (lm *locationModel) Insert(location *Location, userId int) error {
+	stmt := `INSERT INTO locations (user_id, name, pool_length, pool_unit, indoor) VALUES ($1, $2, $3, $4, $5) RETURNING id;`
+
+	err := lm.DB.QueryRow(
+		stmt,
+		userId,
+		location.Name,
+		nullableInt(location.Pool.Length),
+		nullableUnit(location.Pool.Unit),
+		location.Indoor,
+	).Scan(&location.Id)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" && strings.Contains(pqErr.Constraint, "name") {
+			return ErrDuplicateName
+		}
+		return err
+	}
+
+	return nil
+}
+
+// LastUsedID returns the location of the latest swim that has one, or zero if
+// the user never picked a location.
+func (lm *locationModel) LastUsedID(userId int) (int, error) {
+	stmt := `SELECT location_id FROM swims WHERE user_id = $1 AND location_id IS NOT NULL ORDER BY date DESC, id DESC LIMIT 1;`
+
+	var id int
+	err := lm.DB.QueryRow(stmt, userId).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// Stats returns all locations of a user with the figures of their swims,
+// sorted by name. Locations without swims are included with zero figures.
+func (lm *locationModel) Stats(userId int) ([]*LocationStats, error) {
+	stmt := `SELECT l.id, l.name, l.pool_length, l.pool_unit, l.indoor, COUNT(s.id), COALESCE(SUM(s.distance_m), 0), MAX(s.date)
+		FROM locations l LEFT JOIN swims s ON s.location_id = l.id AND s.user_id = l.user_id
+		WHERE l.user_id = $1 GROUP BY l.id ORDER BY l.name ASC;`
+
+	rows, err := lm.DB.Query(stmt, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err := rows.Close(); err != nil {
+			// Ignore Close error to avoid overriding return error
+			_ = err
+		}
+	}()
+
+	var stats []*LocationStats
+	for rows.Next() {
+		var ls LocationStats
+		var poolLength sql.NullInt64
+		var poolUnit sql.NullString
+		var lastVisit sql.NullTime
+
+		errScan := rows.Scan(&ls.Id, &ls.Name, &poolLength, &poolUnit, &ls.Indoor, &ls.Count, &ls.DistanceM, &lastVisit)
+		if errScan != nil {
+			return nil, errScan
+		}
+
+		ls.Pool = Pool{Length: int(poolLength.Int64), Unit: Unit(poolUnit.String)}
+		ls.LastVisit = lastVisit.Time
+
+		stats = append(stats, &ls)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+func scanLocation(row rowScanner, l *Location) error {
+	var poolLength sql.NullInt64
+	var poolUnit sql.NullString
+
+	err := row.Scan(&l.Id, &l.Name, &poolLength, &poolUnit, &l.Indoor)
+	if err != nil {
+		return err
+	}
+
+	l.Pool = Pool{Length: int(poolLength.Int64), Unit: Unit(poolUnit.String)}
+
+	return nil
+}
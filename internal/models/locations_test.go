@@ -0,0 +1,236 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocationModelGetAll(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer func() {
+		_ = db.Close()
+	}()
+
+	rows := sqlmock.NewRows([]string{"id", "name", "pool_length", "pool_unit", "indoor"}).
+		AddRow(2, "Lake", nil, nil, false).
+		AddRow(1, "Stadtbad", 25, "m", true)
+	mock.ExpectQuery("SELECT id, name, pool_length, pool_unit, indoor FROM locations WHERE user_id = \\$1 ORDER BY name ASC").
+		WithArgs(1).
+		WillReturnRows(rows)
+
+	locations, err := NewLocationModel(db).GetAll(1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []*Location{
+		{Id: 2, Name: "Lake"},
+		{Id: 1, Name: "Stadtbad", Pool: Pool{Length: 25, Unit: UnitMeters}, Indoor: true},
+	}, locations)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLocationModelGetByID(t *testing.T) {
+	tests := []struct {
+		name             string
+		setupMock        func(mock sqlmock.Sqlmock)
+		expectedLocation *Location
+		expectedError    error
+	}{
+		{
+			name: "successful fetch",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "name", "pool_length", "pool_unit", "indoor"}).
+					AddRow(3, "Olympiabad", 50, "m", false)
+				mock.ExpectQuery("SELECT id, name, pool_length, pool_unit, indoor FROM locations WHERE id = \\$1 AND user_id = \\$2").
+					WithArgs(3, 1).
+					WillReturnRows(rows)
+			},
+			expectedLocation: &Location{Id: 3, Name: "Olympiabad", Pool: Pool{Length: 50, Unit: UnitMeters}},
+		},
+		{
+			name: "location of another user",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT id, name, pool_length, pool_unit, indoor FROM locations WHERE id = \\$1 AND user_id = \\$2").
+					WithArgs(3, 1).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "name", "pool_length", "pool_unit", "indoor"}))
+			},
+			expectedError: ErrNoRecord,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer func() {
+				_ = db.Close()
+			}()
+
+			tt.setupMock(mock)
+
+			location, err := NewLocationModel(db).GetByID(1, 3)
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedLocation, location)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestLocationModelInsert(t *testing.T) {
+	tests := []struct {
+		name          string
+		location      *Location
+		setupMock     func(mock sqlmock.Sqlmock)
+		expectedError error
+	}{
+		{
+			name:     "insert with pool",
+			location: &Location{Name: "Stadtbad", Pool: Pool{Length: 25, Unit: UnitMeters}, Indoor: true},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("INSERT INTO locations \\(user_id, name, pool_length, pool_unit, indoor\\)").
+					WithArgs(1, "Stadtbad", 25, "m", true).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+			},
+		},
+		{
+			name:     "insert without pool",
+			location: &Location{Name: "Lake"},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("INSERT INTO locations \\(user_id, name, pool_length, pool_unit, indoor\\)").
+					WithArgs(1, "Lake", nil, nil, false).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+			},
+		},
+		{
+			name:     "duplicate name",
+			location: &Location{Name: "Lake"},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("INSERT INTO locations").
+					WillReturnError(&pq.Error{Code: "23505", Constraint: "locations_user_id_name_key"})
+			},
+			expectedError: ErrDuplicateName,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer func() {
+				_ = db.Close()
+			}()
+
+			tt.setupMock(mock)
+
+			err = NewLocationModel(db).Insert(tt.location, 1)
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, 4, tt.location.Id)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestLocationModelLastUsedID(t *testing.T) {
+	tests := []struct {
+		name        string
+		setupMock   func(mock sqlmock.Sqlmock)
+		expectedId  int
+		expectError bool
+	}{
+		{
+			name: "location of the latest swim",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT location_id FROM swims WHERE user_id = \\$1 AND location_id IS NOT NULL ORDER BY date DESC, id DESC LIMIT 1").
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows([]string{"location_id"}).AddRow(3))
+			},
+			expectedId: 3,
+		},
+		{
+			name: "no location used yet",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT location_id FROM swims").
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows([]string{"location_id"}))
+			},
+		},
+		{
+			name: "database error",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT location_id FROM swims").
+					WithArgs(1).
+					WillReturnError(errors.New("connection lost"))
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer func() {
+				_ = db.Close()
+			}()
+
+			tt.setupMock(mock)
+
+			id, err := NewLocationModel(db).LastUsedID(1)
+
+			if tt.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedId, id)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestLocationModelStats(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer func() {
+		_ = db.Close()
+	}()
+
+	rows := sqlmock.NewRows([]string{"id", "name", "pool_length", "pool_unit", "indoor", "count", "distance_m", "last_visit"}).
+		AddRow(2, "Lake", nil, nil, false, 0, 0, nil).
+		AddRow(1, "Stadtbad", 25, "m", true, 12, 24000, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC))
+	mock.ExpectQuery("SELECT l.id, l.name, l.pool_length, l.pool_unit, l.indoor, COUNT\\(s.id\\), COALESCE\\(SUM\\(s.distance_m\\), 0\\), MAX\\(s.date\\)\\s+FROM locations l LEFT JOIN swims s").
+		WithArgs(1).
+		WillReturnRows(rows)
+
+	stats, err := NewLocationModel(db).Stats(1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []*LocationStats{
+		{Location: Location{Id: 2, Name: "Lake"}},
+		{
+			Location:  Location{Id: 1, Name: "Stadtbad", Pool: Pool{Length: 25, Unit: UnitMeters}, Indoor: true},
+			Count:     12,
+			DistanceM: 24000,
+			LastVisit: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}, stats)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
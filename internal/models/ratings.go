@@ -0,0 +1,84 @@
+package models
+
+// Effort is the rate of perceived exertion (RPE) of a swim. The zero value
+// means that no effort was recorded.
+type Effort int
+
+// MinEffort and MaxEffort bound the effort scale, matching the check on the
+// swims.effort column.
+const (
+	MinEffort Effort = 1
+	MaxEffort Effort = 10
+)
+
+// Efforts lists all steps of the effort scale in ascending order.
+var Efforts = func() []Effort {
+	efforts := make([]Effort, 0, MaxEffort-MinEffort+1)
+	for e := MinEffort; e <= MaxEffort; e++ {
+		efforts = append(efforts, e)
+	}
+	return efforts
+}()
+
+func (e Effort) Valid() bool {
+	return e >= MinEffort && e <= MaxEffort
+}
+
+func (e Effort) Label() string {
+	switch {
+	case !e.Valid():
+		return ""
+	case e == 1:
+		return "Very light"
+	case e <= 3:
+		return "Light"
+	case e <= 6:
+		return "Moderate"
+	case e <= 8:
+		return "Hard"
+	case e == 9:
+		return "Very hard"
+	}
+	return "Max effort"
+}
+
+// Feel is how a swim felt, independent of how hard it was.
+type Feel int
+
+const (
+	FeelTerrible Feel = iota + 1
+	FeelBad
+	FeelOkay
+	FeelGood
+	FeelGreat
+)
+
+// Feels lists all feel ratings in display order.
+var Feels = []Feel{FeelGreat, FeelGood, FeelOkay, FeelBad, FeelTerrible}
+
+func (f Feel) Valid() bool {
+	return f >= FeelTerrible && f <= FeelGreat
+}
+
+func (f Feel) Label() string {
+	switch f {
+	case FeelTerrible:
+		return "Terrible"
+	case FeelBad:
+		return "Bad"
+	case FeelOkay:
+		return "Okay"
+	case FeelGood:
+		return "Good"
+	case FeelGreat:
+		return "Great"
+	}
+	return ""
+}
+
+// FeelFromAssessment maps a rating of the former three level assessment (0 bad,
+// 1 neutral, 2 good) onto the feel scale, the same way migration 0009 moved
+// the stored swims over.
+func FeelFromAssessment(assessment int) Feel {
+	return Feel(min(max(assessment, 0), 2)) + FeelBad
+}
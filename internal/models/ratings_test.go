@@ -0,0 +1,67 @@
+package models
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEffort(t *testing.T) {
+	tests := []struct {
+		effort        Effort
+		expectedValid bool
+		expectedLabel string
+	}{
+		{0, false, ""},
+		{1, true, "Very light"},
+		{3, true, "Light"},
+		{5, true, "Moderate"},
+		{8, true, "Hard"},
+		{9, true, "Very hard"},
+		{10, true, "Max effort"},
+		{11, false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(int(tt.effort)), func(t *testing.T) {
+			assert.Equal(t, tt.expectedValid, tt.effort.Valid())
+			assert.Equal(t, tt.expectedLabel, tt.effort.Label())
+		})
+	}
+
+	assert.Len(t, Efforts, 10)
+	assert.Equal(t, MinEffort, Efforts[0])
+	assert.Equal(t, MaxEffort, Efforts[len(Efforts)-1])
+}
+
+func TestFeel(t *testing.T) {
+	tests := []struct {
+		feel          Feel
+		expectedValid bool
+		expectedLabel string
+	}{
+		{0, false, ""},
+		{FeelTerrible, true, "Terrible"},
+		{FeelBad, true, "Bad"},
+		{FeelOkay, true, "Okay"},
+		{FeelGood, true, "Good"},
+		{FeelGreat, true, "Great"},
+		{6, false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(int(tt.feel)), func(t *testing.T) {
+			assert.Equal(t, tt.expectedValid, tt.feel.Valid())
+			assert.Equal(t, tt.expectedLabel, tt.feel.Label())
+		})
+	}
+}
+
+func TestFeelFromAssessment(t *testing.T) {
+	assert.Equal(t, FeelBad, FeelFromAssessment(0))
+	assert.Equal(t, FeelOkay, FeelFromAssessment(1))
+	assert.Equal(t, FeelGood, FeelFromAssessment(2))
+	assert.Equal(t, FeelGood, FeelFromAssessment(3))
+	assert.Equal(t, FeelBad, FeelFromAssessment(-1))
+}
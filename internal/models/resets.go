@@ -0,0 +1,90 @@
+package models
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// PasswordResetLifetime is how long the link to reset a password works.
+const PasswordResetLifetime = time.Hour
+
+// PasswordResetModel stores the tokens of the links that reset a forgotten
+// password. Like invites, just a hash of a token is stored.
+type PasswordResetModel interface {
+	Insert(userId int) (string, error)
+	Lookup(token string) (int, error)
+	Consume(token string) (int, error)
+}
+
+type passwordResetModel struct {
+	DB *sql.DB
+}
+
+func NewPasswordResetModel(db *sql.DB) PasswordResetModel {
+	return &passwordResetModel{DB: db}
+}
+
+// Insert creates a reset token of a user and returns it. Older tokens of the
+// user stop working, so that only the latest email is valid.
+func (pm *passwordResetModel) Insert(userId int) (string, error) {
+	tx, err := pm.DB.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		// Rollback is a no-op once the transaction has been committed
+		_ = tx.Rollback()
+	}()
+
+	stmt := `UPDATE password_resets SET used = now() WHERE user_id = $1 AND used IS NULL;`
+	_, err = tx.Exec(stmt, userId)
+	if err != nil {
+		return "", err
+	}
+
+	stmt = `INSERT INTO password_resets (user_id, token_hash, expires) VALUES ($1, $2, now() + make_interval(secs => $3));`
+	token := rand.Text()
+	_, err = tx.Exec(stmt, userId, tokenHash(token), PasswordResetLifetime.Seconds())
+	if err != nil {
+		return "", err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// Lookup returns the user of a token without using it up. Unknown, used and
+// expired tokens return ErrNoRecord.
+func (pm *passwordResetModel) Lookup(token string) (int, error) {
+	stmt := `SELECT user_id FROM password_resets WHERE token_hash = $1 AND used IS NULL AND expires > now();`
+
+	return pm.userId(stmt, token)
+}
+
+// Consume marks a token as used and returns its user. Unknown, used and
+// expired tokens return ErrNoRecord.
+func (pm *passwordResetModel) Consume(token string) (int, error) {
+	stmt := `UPDATE password_resets SET used = now() WHERE token_hash = $1 AND used IS NULL AND expires > now()
+		RETURNING user_id;`
+
+	return pm.userId(stmt, token)
+}
+
+func (pm *passwordResetModel) userId(stmt, token string) (int, error) {
+	var userId int
+	err := pm.DB.QueryRow(stmt, tokenHash(token)).Scan(&userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrNoRecord
+		}
+		return 0, err
+	}
+
+	return userId, nil
+}
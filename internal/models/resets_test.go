@@ -0,0 +1,116 @@
+package models
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordResetModelInsert(t *testing.T) {
+	tests := []struct {
+		name        string
+		setupMock   func(mock sqlmock.Sqlmock, hash *capturedArg)
+		expectedErr bool
+	}{
+		{
+			name: "token created",
+			setupMock: func(mock sqlmock.Sqlmock, hash *capturedArg) {
+				mock.ExpectBegin()
+				mock.ExpectExec("UPDATE password_resets SET used = now\\(\\) WHERE user_id = \\$1 AND used IS NULL").
+					WithArgs(1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("INSERT INTO password_resets \\(user_id, token_hash, expires\\) VALUES \\(\\$1, \\$2, now\\(\\) \\+ make_interval\\(secs => \\$3\\)\\)").
+					WithArgs(1, hash, float64(60*60)).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "database error",
+			setupMock: func(mock sqlmock.Sqlmock, hash *capturedArg) {
+				mock.ExpectBegin()
+				mock.ExpectExec("UPDATE password_resets").
+					WithArgs(1).
+					WillReturnError(errors.New("database error"))
+				mock.ExpectRollback()
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer func() {
+				_ = db.Close()
+			}()
+
+			hash := &capturedArg{}
+			tt.setupMock(mock, hash)
+
+			token, err := NewPasswordResetModel(db).Insert(1)
+
+			if tt.expectedErr {
+				assert.Error(t, err)
+				assert.Empty(t, token)
+			} else {
+				assert.NoError(t, err)
+				assert.Len(t, token, 26)
+				assert.Equal(t, tokenHash(token), hash.value, "only the hash of the token is stored")
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPasswordResetModelLookupAndConsume(t *testing.T) {
+	lookup := "SELECT user_id FROM password_resets WHERE token_hash = \\$1 AND used IS NULL AND expires > now\\(\\)"
+	consume := "UPDATE password_resets SET used = now\\(\\) WHERE token_hash = \\$1 AND used IS NULL AND expires > now\\(\\) RETURNING user_id"
+
+	tests := []struct {
+		name           string
+		query          string
+		call           func(pm PasswordResetModel) (int, error)
+		userIds        []int
+		expectedUserId int
+		expectedErr    error
+	}{
+		{name: "lookup open token", query: lookup, call: lookupToken, userIds: []int{3}, expectedUserId: 3},
+		{name: "lookup unknown, used or expired token", query: lookup, call: lookupToken, expectedErr: ErrNoRecord},
+		{name: "consume open token", query: consume, call: consumeToken, userIds: []int{3}, expectedUserId: 3},
+		{name: "consume unknown, used or expired token", query: consume, call: consumeToken, expectedErr: ErrNoRecord},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer func() {
+				_ = db.Close()
+			}()
+
+			rows := sqlmock.NewRows([]string{"user_id"})
+			for _, userId := range tt.userIds {
+				rows.AddRow(userId)
+			}
+			mock.ExpectQuery(tt.query).WithArgs(tokenHash("TOKEN")).WillReturnRows(rows)
+
+			userId, err := tt.call(NewPasswordResetModel(db))
+
+			assert.ErrorIs(t, err, tt.expectedErr)
+			assert.Equal(t, tt.expectedUserId, userId)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func lookupToken(pm PasswordResetModel) (int, error) {
+	return pm.Lookup("TOKEN")
+}
+
+func consumeToken(pm PasswordResetModel) (int, error) {
+	return pm.Consume("TOKEN")
+}
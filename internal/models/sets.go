@@ -0,0 +1,161 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// SwimSet is one block of a structured swim, e.g. "8×100 on 1:45". The sets
+// of a swim are ordered by their position in Swim.Sets.
+type SwimSet struct {
+	Repetitions int
+	// DistanceM is the distance of a single repetition.
+	DistanceM int
+	Stroke    Stroke
+	// Interval is the send-off time per repetition, zero if swum without one.
+	Interval time.Duration
+	// Rest is the rest after each repetition, zero if none was planned.
+	Rest time.Duration
+}
+
+// TotalDistanceM returns the distance of all repetitions of the set.
+func (s SwimSet) TotalDistanceM() int {
+	return s.Repetitions * s.DistanceM
+}
+
+// SetsDistanceM returns the total distance of the given sets.
+func SetsDistanceM(sets []SwimSet) int {
+	total := 0
+	for _, set := range sets {
+		total += set.TotalDistanceM()
+	}
+	return total
+}
+
+// SetsDistance returns the total distance of the given sets in whole units of
+// unit. Each repetition is converted before it is added up, like the sets are
+// shown, so that 10×100 yd add up to 1000 yd although a repetition is stored
+// as 91 m.
+func SetsDistance(sets []SwimSet, unit Unit) int {
+	total := 0
+	for _, set := range sets {
+		total += set.Repetitions * unit.FromMeters(set.DistanceM)
+	}
+	return total
+}
+
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+func insertSets(db execer, swimId int, sets []SwimSet) error {
+	stmt := `INSERT INTO swim_sets (swim_id, position, repetitions, distance_m, stroke, interval_s, rest_s)
+		VALUES ($1, $2, $3, $4, $5, $6, $7);`
+
+	for i, set := range sets {
+		_, err := db.Exec(
+			stmt,
+			swimId,
+			i+1,
+			set.Repetitions,
+			set.DistanceM,
+			set.Stroke,
+			durationSeconds(set.Interval),
+			durationSeconds(set.Rest),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (sw *swimModel) getSets(swimId int) ([]SwimSet, error) {
+	stmt := `SELECT repetitions, distance_m, stroke, interval_s, rest_s FROM swim_sets WHERE swim_id = $1 ORDER BY position ASC;`
+
+	rows, err := sw.DB.Query(stmt, swimId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err := rows.Close(); err != nil {
+			// Ignore Close error to avoid overriding return error
+			_ = err
+		}
+	}()
+
+	var sets []SwimSet
+	for rows.Next() {
+		var set SwimSet
+		var intervalS, restS sql.NullInt64
+
+		errScan := rows.Scan(&set.Repetitions, &set.DistanceM, &set.Stroke, &intervalS, &restS)
+		if errScan != nil {
+			return nil, errScan
+		}
+
+		set.Interval = time.Duration(intervalS.Int64) * time.Second
+		set.Rest = time.Duration(restS.Int64) * time.Second
+
+		sets = append(sets, set)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return sets, nil
+}
+
+// attachSets loads the sets of the given swims with a single query.
+func (sw *swimModel) attachSets(swims []*Swim) error {
+	if len(swims) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(swims))
+	byId := make(map[int]*Swim, len(swims))
+	for i, swim := range swims {
+		ids[i] = int64(swim.Id)
+		byId[swim.Id] = swim
+	}
+
+	stmt := `SELECT swim_id, repetitions, distance_m, stroke, interval_s, rest_s FROM swim_sets
+		WHERE swim_id = ANY($1) ORDER BY swim_id ASC, position ASC;`
+
+	rows, err := sw.DB.Query(stmt, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err := rows.Close(); err != nil {
+			// Ignore Close error to avoid overriding return error
+			_ = err
+		}
+	}()
+
+	for rows.Next() {
+		var swimId int
+		var set SwimSet
+		var intervalS, restS sql.NullInt64
+
+		errScan := rows.Scan(&swimId, &set.Repetitions, &set.DistanceM, &set.Stroke, &intervalS, &restS)
+		if errScan != nil {
+			return errScan
+		}
+
+		set.Interval = time.Duration(intervalS.Int64) * time.Second
+		set.Rest = time.Duration(restS.Int64) * time.Second
+
+		if swim, ok := byId[swimId]; ok {
+			swim.Sets = append(swim.Sets, set)
+		}
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetsDistanceM(t *testing.T) {
+	sets := []SwimSet{
+		{Repetitions: 1, DistanceM: 400},
+		{Repetitions: 8, DistanceM: 100},
+		{Repetitions: 1, DistanceM: 200},
+	}
+
+	assert.Equal(t, 800, sets[1].TotalDistanceM())
+	assert.Equal(t, 1400, SetsDistanceM(sets))
+	assert.Equal(t, 0, SetsDistanceM(nil))
+}
+
+func TestSetsDistance(t *testing.T) {
+	sets := []SwimSet{
+		{Repetitions: 10, DistanceM: 91},
+		{Repetitions: 1, DistanceM: 183},
+	}
+
+	assert.Equal(t, 1093, SetsDistance(sets, UnitMeters))
+	assert.Equal(t, 1200, SetsDistance(sets, UnitYards), "10×100 yd and 200 yd")
+	assert.Equal(t, 0, SetsDistance(nil, UnitYards))
+}
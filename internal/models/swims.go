@@ -6,22 +6,95 @@ import (
 	"fmt"
 	"strings"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 const (
-	SwimSortDate       = "date"
-	SwimSortDistance   = "distance"
-	SwimSortAssessment = "assessment"
+	SwimSortDate     = "date"
+	SwimSortDistance = "distance"
+	SwimSortEffort   = "effort"
 
 	SortDirectionAsc  = "asc"
 	SortDirectionDesc = "desc"
 )
 
+// Stroke is the predominant stroke of a swim. It maps to the swim_stroke enum
+// type in the database.
+type Stroke string
+
+const (
+	StrokeFreestyle    Stroke = "freestyle"
+	StrokeBreaststroke Stroke = "breaststroke"
+	StrokeBackstroke   Stroke = "backstroke"
+	StrokeButterfly    Stroke = "butterfly"
+	StrokeMixed        Stroke = "mixed"
+)
+
+// Strokes lists all strokes in display order.
+var Strokes = []Stroke{StrokeFreestyle, StrokeBreaststroke, StrokeBackstroke, StrokeButterfly, StrokeMixed}
+
+func (st Stroke) Valid() bool {
+	for _, stroke := range Strokes {
+		if st == stroke {
+			return true
+		}
+	}
+	return false
+}
+
+func (st Stroke) Label() string {
+	switch st {
+	case StrokeFreestyle:
+		return "Freestyle"
+	case StrokeBreaststroke:
+		return "Breaststroke"
+	case StrokeBackstroke:
+		return "Backstroke"
+	case StrokeButterfly:
+		return "Butterfly"
+	case StrokeMixed:
+		return "Mixed"
+	}
+	return ""
+}
+
+// MaxNotesLength is the maximum length of the notes of a swim.
+const MaxNotesLength = 2000
+
+// MaxSourceLength is the maximum length of the source of a swim, matching the
+// swims.source column.
+const MaxSourceLength = 200
+
 type Swim struct {
-	Id         int
-	Date       time.Time
-	DistanceM  int
-	Assessment int
+	Id        int
+	Date      time.Time
+	DistanceM int
+	Effort    Effort
+	Feel      Feel
+	Duration  time.Duration
+	Stroke    Stroke
+	Pool      Pool
+	// Laps is the number of pool lengths as entered, zero if the swim was
+	// entered as a distance.
+	Laps  int
+	Notes string
+	// LocationId is the location the swim took place at, zero if unknown.
+	LocationId int
+	// Tags are the names of the user-defined tags of the swim, sorted by name.
+	Tags []string
+	// Sets is the structured breakdown of the swim. It is only loaded by
+	// GetByID; lists and summaries work on the swim totals.
+	Sets []SwimSet
+	// Source identifies the activity a swim was imported from, e.g. a
+	// session of a FIT file, and is empty for swims entered by hand. A user
+	// cannot import the same activity twice. It is only loaded by backups.
+	Source string
+}
+
+// Pace returns the average time per 100 m, or zero if no duration was recorded.
+func (s *Swim) Pace() time.Duration {
+	return pacePer100m(s.Duration, s.DistanceM)
 }
 
 type SwimSummary struct {
@@ -32,28 +105,143 @@ type SwimSummary struct {
 	WeeklyDistance   int
 	WeeklyCount      int
 	MaxActivityCount int
+	WeeklyPace       time.Duration
+	MonthlyPace      time.Duration
+	YearlyPace       time.Duration
+	WeeklyEffort     float64
+	MonthlyEffort    float64
 	YearMap          map[int]YearMap
+	StrokeMap        map[Stroke]SwimFigures
+
+	weekly SwimFigures
 }
 
 type YearMap struct {
 	SwimFigures
-	MonthMap map[time.Month]SwimFigures
+	MonthMap  map[time.Month]SwimFigures
+	StrokeMap map[Stroke]SwimFigures
 }
 
 type SwimFigures struct {
 	Count     int
 	DistanceM int
+	// Duration and TimedDistanceM only account for swims with a recorded
+	// duration, so that the pace is not skewed by untimed swims.
+	Duration       time.Duration
+	TimedDistanceM int
+	// EffortTotal and RatedCount only account for swims with a recorded
+	// effort.
+	EffortTotal int
+	RatedCount  int
+}
+
+// Pace returns the average time per 100 m over all timed swims.
+func (f SwimFigures) Pace() time.Duration {
+	return pacePer100m(f.Duration, f.TimedDistanceM)
+}
+
+// Effort returns the average effort over all swims with a recorded effort, or
+// zero if there are none.
+func (f SwimFigures) Effort() float64 {
+	if f.RatedCount == 0 {
+		return 0
+	}
+	return float64(f.EffortTotal) / float64(f.RatedCount)
+}
+
+func (f *SwimFigures) add(swim *Swim) {
+	f.Count++
+	f.DistanceM += swim.DistanceM
+	if swim.Duration > 0 {
+		f.Duration += swim.Duration
+		f.TimedDistanceM += swim.DistanceM
+	}
+	if swim.Effort.Valid() {
+		f.EffortTotal += int(swim.Effort)
+		f.RatedCount++
+	}
+}
+
+// SwimFilter narrows down the swims of a user. The zero value matches all
+// swims.
+type SwimFilter struct {
+	// Search is a full-text query on the notes in web search syntax, e.g.
+	// `goggles -new` or `"felt tight"`.
+	Search string
+	// Tags only matches swims that carry all of the given tags.
+	Tags []string
+	// From and To limit the swims to a date range, both inclusive. A zero
+	// value leaves that end of the range open.
+	From time.Time
+	To   time.Time
+}
+
+// HasTag reports whether the filter requires the tag.
+func (f SwimFilter) HasTag(tag string) bool {
+	for _, t := range f.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// WithTag returns a copy of the filter that additionally requires the tag.
+func (f SwimFilter) WithTag(tag string) SwimFilter {
+	if f.HasTag(tag) {
+		return f
+	}
+	f.Tags = append(append([]string{}, f.Tags...), tag)
+	return f
+}
+
+// WithoutTag returns a copy of the filter that no longer requires the tag.
+func (f SwimFilter) WithoutTag(tag string) SwimFilter {
+	var tags []string
+	for _, t := range f.Tags {
+		if t != tag {
+			tags = append(tags, t)
+		}
+	}
+	f.Tags = tags
+	return f
+}
+
+// where returns the conditions for the filter to be appended to a WHERE
+// clause, numbering its placeholders after the given args.
+func (f SwimFilter) where(args []any) (string, []any) {
+	var conditions strings.Builder
+	if f.Search != "" {
+		args = append(args, f.Search)
+		fmt.Fprintf(&conditions, " AND notes_tsv @@ websearch_to_tsquery('simple', $%d)", len(args))
+	}
+	for _, tag := range f.Tags {
+		args = append(args, tag)
+		fmt.Fprintf(&conditions, " AND EXISTS (SELECT 1 FROM swim_tags st JOIN tags t ON t.id = st.tag_id WHERE st.swim_id = swims.id AND t.name = $%d)", len(args))
+	}
+	if !f.From.IsZero() {
+		args = append(args, f.From)
+		fmt.Fprintf(&conditions, " AND date >= $%d", len(args))
+	}
+	if !f.To.IsZero() {
+		args = append(args, f.To)
+		fmt.Fprintf(&conditions, " AND date <= $%d", len(args))
+	}
+	return conditions.String(), args
 }
 
 type SwimModel interface {
 	Get() (*Swim, error)
 	GetByID(userId int, swimId int) (*Swim, error)
 	GetAll(userId int) ([]*Swim, error)
-	GetPaginated(userId int, limit int, offset int, sort string, direction string) ([]*Swim, error)
-	Insert(date time.Time, distanceM int, assessment int, userId int) error
-	Update(id int, userId int, date time.Time, distanceM int, assessment int) error
+	GetPaginated(userId int, limit int, offset int, sort string, direction string, filter SwimFilter) ([]*Swim, error)
+	GetAfter(userId int, limit int, after *SwimKey, sort string, direction string, filter SwimFilter) ([]*Swim, error)
+	Export(userId int, sort string, direction string, filter SwimFilter, each func(*Swim) error) error
+	Insert(swim *Swim, userId int) error
+	InsertMany(swims []*Swim, userId int) error
+	Update(swim *Swim, userId int) error
 	Delete(id int, userId int) error
-	Summarize(userId int) *SwimSummary
+	Summarize(userId int, filter SwimFilter) *SwimSummary
 }
 
 type swimModel struct {
@@ -65,13 +253,13 @@ func NewSwimModel(db *sql.DB) SwimModel {
 }
 
 func (sw *swimModel) Get() (*Swim, error) {
-	stmt := `SELECT id, date, distance_m, assessment FROM swims ORDER BY date ASC LIMIT 1;`
+	stmt := `SELECT id, date, distance_m, effort, feel, duration_s, stroke, pool_length, pool_unit, laps, notes, location_id FROM swims ORDER BY date ASC LIMIT 1;`
 
 	row := sw.DB.QueryRow(stmt)
 
 	var s Swim
 
-	err := row.Scan(&s.Id, &s.Date, &s.DistanceM, &s.Assessment)
+	err := scanSwim(row, &s)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return &Swim{}, ErrNoRecord
@@ -84,12 +272,12 @@ func (sw *swimModel) Get() (*Swim, error) {
 }
 
 func (sw *swimModel) GetByID(userId int, swimId int) (*Swim, error) {
-	stmt := `SELECT id, date, distance_m, assessment FROM swims WHERE id = $1 AND user_id = $2;`
+	stmt := `SELECT id, date, distance_m, effort, feel, duration_s, stroke, pool_length, pool_unit, laps, notes, location_id FROM swims WHERE id = $1 AND user_id = $2;`
 
 	row := sw.DB.QueryRow(stmt, swimId, userId)
 
 	var s Swim
-	err := row.Scan(&s.Id, &s.Date, &s.DistanceM, &s.Assessment)
+	err := scanSwim(row, &s)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return &Swim{}, ErrNoRecord
@@ -97,13 +285,41 @@ func (sw *swimModel) GetByID(userId int, swimId int) (*Swim, error) {
 		return &Swim{}, err
 	}
 
+	s.Sets, err = sw.getSets(s.Id)
+	if err != nil {
+		return &Swim{}, err
+	}
+
+	err = sw.attachTags([]*Swim{&s})
+	if err != nil {
+		return &Swim{}, err
+	}
+
 	return &s, nil
 }
 
+// GetAll returns all swims of a user with their tags, the oldest first.
 func (sw *swimModel) GetAll(userId int) ([]*Swim, error) {
-	stmt := `SELECT id, date, distance_m, assessment FROM swims WHERE user_id = $1 ORDER BY date ASC;`
+	swims, err := sw.getFiltered(userId, SwimFilter{})
+	if err != nil {
+		return nil, err
+	}
 
-	rows, err := sw.DB.Query(stmt, userId)
+	err = sw.attachTags(swims)
+	if err != nil {
+		return nil, err
+	}
+
+	return swims, nil
+}
+
+func (sw *swimModel) getFiltered(userId int, filter SwimFilter) ([]*Swim, error) {
+	conditions, args := filter.where([]any{userId})
+
+	stmt := `SELECT id, date, distance_m, effort, feel, duration_s, stroke, pool_length, pool_unit, laps, notes, location_id FROM swims WHERE user_id = $1` +
+		conditions + ` ORDER BY date ASC;`
+
+	rows, err := sw.DB.Query(stmt, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -118,7 +334,7 @@ func (sw *swimModel) GetAll(userId int) ([]*Swim, error) {
 	var swims []*Swim
 	for rows.Next() {
 		var s Swim
-		errScan := rows.Scan(&s.Id, &s.Date, &s.DistanceM, &s.Assessment)
+		errScan := scanSwim(rows, &s)
 		if errScan != nil {
 			return nil, errScan
 		}
@@ -133,10 +349,10 @@ func (sw *swimModel) GetAll(userId int) ([]*Swim, error) {
 	return swims, nil
 }
 
-func (sw *swimModel) Summarize(userId int) *SwimSummary {
-	summary := &SwimSummary{YearMap: make(map[int]YearMap)}
+func (sw *swimModel) Summarize(userId int, filter SwimFilter) *SwimSummary {
+	summary := &SwimSummary{YearMap: make(map[int]YearMap), StrokeMap: make(map[Stroke]SwimFigures)}
 
-	swims, err := sw.GetAll(userId)
+	swims, err := sw.getFiltered(userId, filter)
 	if err != nil {
 		return summary
 	}
@@ -152,6 +368,13 @@ func (sw *swimModel) Summarize(userId int) *SwimSummary {
 	summary.MonthlyDistance = summary.YearMap[time.Now().Year()].MonthMap[time.Now().Month()].DistanceM
 	summary.MonthlyCount = summary.YearMap[time.Now().Year()].MonthMap[time.Now().Month()].Count
 
+	summary.WeeklyPace = summary.weekly.Pace()
+	summary.MonthlyPace = summary.YearMap[time.Now().Year()].MonthMap[time.Now().Month()].Pace()
+	summary.YearlyPace = summary.YearMap[time.Now().Year()].Pace()
+
+	summary.WeeklyEffort = summary.weekly.Effort()
+	summary.MonthlyEffort = summary.YearMap[time.Now().Year()].MonthMap[time.Now().Month()].Effort()
+
 	// Calculate max activity count for chart scaling
 	summary.MaxActivityCount = summary.MonthlyCount
 	if summary.WeeklyCount > summary.MaxActivityCount {
@@ -164,17 +387,89 @@ func (sw *swimModel) Summarize(userId int) *SwimSummary {
 	return summary
 }
 
-func (sw *swimModel) GetPaginated(userId int, limit int, offset int, sort string, direction string) ([]*Swim, error) {
+// GetPaginated returns a page of the swims of the user that match the filter.
+// Swims that sort equally are ordered by id, so pages neither repeat nor skip
+// swims.
+func (sw *swimModel) GetPaginated(userId int, limit int, offset int, sort string, direction string, filter SwimFilter) ([]*Swim, error) {
+	sortColumn := sanitizeSortColumn(sort)
+	sortDirection := sanitizeSortDirection(direction)
+
+	conditions, args := filter.where([]any{userId})
+	args = append(args, limit, offset)
+
+	stmt := fmt.Sprintf(
+		`SELECT id, date, distance_m, effort, feel, duration_s, stroke, pool_length, pool_unit, laps, notes, location_id FROM swims WHERE user_id = $1%s ORDER BY %s %s, id %s LIMIT $%d OFFSET $%d;`,
+		conditions,
+		sortColumn,
+		sortDirection,
+		sortDirection,
+		len(args)-1,
+		len(args),
+	)
+
+	return sw.list(stmt, args)
+}
+
+// SwimKey is the place of a swim in a sorted swim list: the values it can be
+// sorted by and its id, which orders swims that sort equally.
+type SwimKey struct {
+	Id        int
+	Date      time.Time
+	DistanceM int
+	Effort    Effort
+}
+
+// Key returns the place of the swim in a sorted swim list.
+func (s *Swim) Key() SwimKey {
+	return SwimKey{Id: s.Id, Date: s.Date, DistanceM: s.DistanceM, Effort: s.Effort}
+}
+
+// GetAfter returns the swims of the user that match the filter and come after
+// the key in the given order, or the first ones if the key is nil. Unlike an
+// offset, the key keeps its place when swims are added or deleted between
+// two pages, so pages neither repeat nor skip swims.
+func (sw *swimModel) GetAfter(userId int, limit int, after *SwimKey, sort string, direction string, filter SwimFilter) ([]*Swim, error) {
 	sortColumn := sanitizeSortColumn(sort)
 	sortDirection := sanitizeSortDirection(direction)
 
+	conditions, args := filter.where([]any{userId})
+	if after != nil {
+		comparison := "<"
+		if sortDirection == "ASC" {
+			comparison = ">"
+		}
+		args = append(args, after.sortValue(sort), after.Id)
+		conditions += fmt.Sprintf(" AND (%s, id) %s ($%d, $%d)", sortColumn, comparison, len(args)-1, len(args))
+	}
+	args = append(args, limit)
+
 	stmt := fmt.Sprintf(
-		`SELECT id, date, distance_m, assessment FROM swims WHERE user_id = $1 ORDER BY %s %s LIMIT $2 OFFSET $3;`,
+		`SELECT id, date, distance_m, effort, feel, duration_s, stroke, pool_length, pool_unit, laps, notes, location_id FROM swims WHERE user_id = $1%s ORDER BY %s %s, id %s LIMIT $%d;`,
+		conditions,
 		sortColumn,
 		sortDirection,
+		sortDirection,
+		len(args),
 	)
 
-	rows, err := sw.DB.Query(stmt, userId, limit, offset)
+	return sw.list(stmt, args)
+}
+
+// sortValue returns the value of the key for the column of the sort.
+func (k *SwimKey) sortValue(sort string) any {
+	switch sort {
+	case SwimSortDistance:
+		return k.DistanceM
+	case SwimSortEffort:
+		return int(k.Effort)
+	default:
+		return k.Date
+	}
+}
+
+// list returns the swims of a query with their tags.
+func (sw *swimModel) list(stmt string, args []any) ([]*Swim, error) {
+	rows, err := sw.DB.Query(stmt, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -189,7 +484,7 @@ func (sw *swimModel) GetPaginated(userId int, limit int, offset int, sort string
 	var swims []*Swim
 	for rows.Next() {
 		var s Swim
-		errScan := rows.Scan(&s.Id, &s.Date, &s.DistanceM, &s.Assessment)
+		errScan := scanSwim(rows, &s)
 		if errScan != nil {
 			return nil, errScan
 		}
@@ -201,24 +496,170 @@ func (sw *swimModel) GetPaginated(userId int, limit int, offset int, sort string
 		return nil, err
 	}
 
+	err = sw.attachTags(swims)
+	if err != nil {
+		return nil, err
+	}
+
 	return swims, nil
 }
 
-func (sw *swimModel) Insert(date time.Time, distanceM int, assessment int, userId int) error {
-	stmt := `INSERT INTO swims (date, distance_m, assessment, user_id) VALUES ($1, $2, $3, $4);`
+// Export calls each for every swim of the user that matches the filter, in the
+// given order. Rows are handed over one by one as they are read, so exporting a
+// long history does not hold all swims in memory. Sets and tags are not loaded.
+// Iteration stops at the first error returned by each.
+func (sw *swimModel) Export(userId int, sort string, direction string, filter SwimFilter, each func(*Swim) error) error {
+	sortColumn := sanitizeSortColumn(sort)
+	sortDirection := sanitizeSortDirection(direction)
+
+	conditions, args := filter.where([]any{userId})
+
+	stmt := fmt.Sprintf(
+		`SELECT id, date, distance_m, effort, feel, duration_s, stroke, pool_length, pool_unit, laps, notes, location_id FROM swims WHERE user_id = $1%s ORDER BY %s %s, id %s;`,
+		conditions,
+		sortColumn,
+		sortDirection,
+		sortDirection,
+	)
+
+	rows, err := sw.DB.Query(stmt, args...)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err := rows.Close(); err != nil {
+			// Ignore Close error to avoid overriding return error
+			_ = err
+		}
+	}()
+
+	for rows.Next() {
+		var s Swim
+		err = scanSwim(rows, &s)
+		if err != nil {
+			return err
+		}
+
+		err = each(&s)
+		if err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// Insert stores a new swim. Importing an activity again returns
+// ErrDuplicateSource, values the database rejects, such as a distance out
+// of range, ErrInvalidSwim.
+func (sw *swimModel) Insert(swim *Swim, userId int) error {
+	return sw.InsertMany([]*Swim{swim}, userId)
+}
 
-	_, err := sw.DB.Exec(stmt, date, distanceM, assessment, userId)
+// InsertMany stores several swims in one transaction, so either all of them
+// are stored or none is.
+func (sw *swimModel) InsertMany(swims []*Swim, userId int) error {
+	tx, err := sw.DB.Begin()
 	if err != nil {
 		return err
 	}
+	defer func() {
+		// Rollback is a no-op once the transaction has been committed
+		_ = tx.Rollback()
+	}()
 
-	return nil
+	for _, swim := range swims {
+		err = insertSwim(tx, swim, userId)
+		if err != nil {
+			return invalidSwim(err)
+		}
+	}
+
+	return tx.Commit()
 }
 
-func (sw *swimModel) Update(id int, userId int, date time.Time, distanceM int, assessment int) error {
-	stmt := `UPDATE swims SET date = $1, distance_m = $2, assessment = $3 WHERE id = $4 AND user_id = $5;`
+// invalidSwim wraps errors of the database about the values of a swim in
+// ErrInvalidSwim, so that importers can skip the swim and go on. Other
+// errors, such as a lost connection, are returned as they are.
+func invalidSwim(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		// Class 22 are data exceptions, class 23 integrity constraint
+		// violations
+		switch pqErr.Code.Class() {
+		case "22", "23":
+			return fmt.Errorf("%w: %v", ErrInvalidSwim, err)
+		}
+	}
+	return err
+}
 
-	result, err := sw.DB.Exec(stmt, date, distanceM, assessment, id, userId)
+func insertSwim(tx *sql.Tx, swim *Swim, userId int) error {
+	stmt := `INSERT INTO swims (date, distance_m, duration_s, stroke, pool_length, pool_unit, laps, notes, location_id, effort, feel, source, user_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13) RETURNING id;`
+
+	err := tx.QueryRow(
+		stmt,
+		swim.Date,
+		swim.DistanceM,
+		durationSeconds(swim.Duration),
+		swim.Stroke,
+		nullableInt(swim.Pool.Length),
+		nullableUnit(swim.Pool.Unit),
+		nullableInt(swim.Laps),
+		swim.Notes,
+		nullableInt(swim.LocationId),
+		nullableInt(int(swim.Effort)),
+		swim.Feel,
+		nullableString(swim.Source),
+		userId,
+	).Scan(&swim.Id)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" && strings.Contains(pqErr.Constraint, "source") {
+			return ErrDuplicateSource
+		}
+		return err
+	}
+
+	err = insertSets(tx, swim.Id, swim.Sets)
+	if err != nil {
+		return err
+	}
+
+	return insertSwimTags(tx, userId, swim.Id, swim.Tags)
+}
+
+func (sw *swimModel) Update(swim *Swim, userId int) error {
+	stmt := `UPDATE swims SET date = $1, distance_m = $2, duration_s = $3, stroke = $4, pool_length = $5, pool_unit = $6,
+		laps = $7, notes = $8, location_id = $9, effort = $10, feel = $11 WHERE id = $12 AND user_id = $13;`
+
+	tx, err := sw.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// Rollback is a no-op once the transaction has been committed
+		_ = tx.Rollback()
+	}()
+
+	result, err := tx.Exec(
+		stmt,
+		swim.Date,
+		swim.DistanceM,
+		durationSeconds(swim.Duration),
+		swim.Stroke,
+		nullableInt(swim.Pool.Length),
+		nullableUnit(swim.Pool.Unit),
+		nullableInt(swim.Laps),
+		swim.Notes,
+		nullableInt(swim.LocationId),
+		nullableInt(int(swim.Effort)),
+		swim.Feel,
+		swim.Id,
+		userId,
+	)
 	if err != nil {
 		return err
 	}
@@ -232,7 +673,28 @@ func (sw *swimModel) Update(id int, userId int, date time.Time, distanceM int, a
 		return ErrNoRecord
 	}
 
-	return nil
+	// The sets are replaced as a whole, their positions follow the new order
+	_, err = tx.Exec(`DELETE FROM swim_sets WHERE swim_id = $1;`, swim.Id)
+	if err != nil {
+		return err
+	}
+
+	err = insertSets(tx, swim.Id, swim.Sets)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`DELETE FROM swim_tags WHERE swim_id = $1;`, swim.Id)
+	if err != nil {
+		return err
+	}
+
+	err = insertSwimTags(tx, userId, swim.Id, swim.Tags)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 func (sw *swimModel) Delete(id int, userId int) error {
@@ -258,6 +720,11 @@ func (sw *swimModel) Delete(id int, userId int) error {
 func (s *SwimSummary) pushYearlyFigures(swim *Swim) {
 	s.TotalDistance += swim.DistanceM
 	s.TotalCount++
+
+	if s.StrokeMap == nil {
+		s.StrokeMap = make(map[Stroke]SwimFigures)
+	}
+	addToStrokeMap(s.StrokeMap, swim)
 }
 
 func (s *SwimSummary) pushWeeklyFigures(swim *Swim) {
@@ -267,6 +734,7 @@ func (s *SwimSummary) pushWeeklyFigures(swim *Swim) {
 	if week == currentWeek && year == currentYear {
 		s.WeeklyDistance += swim.DistanceM
 		s.WeeklyCount++
+		s.weekly.add(swim)
 	}
 }
 
@@ -280,10 +748,11 @@ func (s *SwimSummary) updateYearMap(swim *Swim) {
 		for i := 1; i <= 12; i++ {
 			yearMap.MonthMap[time.Month(i)] = SwimFigures{Count: 0, DistanceM: 0}
 		}
+		yearMap.StrokeMap = make(map[Stroke]SwimFigures)
 	}
 
-	yearMap.Count++
-	yearMap.DistanceM += swim.DistanceM
+	yearMap.add(swim)
+	addToStrokeMap(yearMap.StrokeMap, swim)
 
 	s.YearMap[year] = yearMap
 }
@@ -293,17 +762,73 @@ func (s *SwimSummary) updateMonthMap(swim *Swim) {
 	yearMap := s.YearMap[swim.Date.Year()]
 	monthMap := yearMap.MonthMap[month]
 
-	monthMap.Count++
-	monthMap.DistanceM += swim.DistanceM
+	monthMap.add(swim)
 
 	yearMap.MonthMap[month] = monthMap
+}
+
+func addToStrokeMap(strokeMap map[Stroke]SwimFigures, swim *Swim) {
+	figures := strokeMap[swim.Stroke]
+	figures.add(swim)
+	strokeMap[swim.Stroke] = figures
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSwim(row rowScanner, s *Swim) error {
+	var effort, durationS, poolLength, laps, locationId sql.NullInt64
+	var poolUnit sql.NullString
 
+	err := row.Scan(&s.Id, &s.Date, &s.DistanceM, &effort, &s.Feel, &durationS, &s.Stroke, &poolLength, &poolUnit, &laps, &s.Notes, &locationId)
+	if err != nil {
+		return err
+	}
+
+	s.Effort = Effort(effort.Int64)
+	s.Duration = time.Duration(durationS.Int64) * time.Second
+	s.Pool = Pool{Length: int(poolLength.Int64), Unit: Unit(poolUnit.String)}
+	s.Laps = int(laps.Int64)
+	s.LocationId = int(locationId.Int64)
+
+	return nil
+}
+
+// durationSeconds maps a swim duration to its nullable column value, storing
+// NULL for swims without a recorded duration.
+func durationSeconds(d time.Duration) sql.NullInt64 {
+	return nullableInt(int(d / time.Second))
+}
+
+// nullableInt stores NULL for the zero value of optional numeric columns.
+func nullableInt(n int) sql.NullInt64 {
+	if n <= 0 {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: int64(n), Valid: true}
+}
+
+func nullableUnit(u Unit) sql.NullString {
+	return nullableString(string(u))
+}
+
+// nullableString stores NULL for empty optional text columns.
+func nullableString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
+
+func pacePer100m(d time.Duration, distanceM int) time.Duration {
+	if d <= 0 || distanceM <= 0 {
+		return 0
+	}
+	return (d * 100 / time.Duration(distanceM)).Round(time.Second)
 }
 
 var sortColumnMap = map[string]string{
-	SwimSortDate:       "date",
-	SwimSortDistance:   "distance_m",
-	SwimSortAssessment: "assessment",
+	SwimSortDate:     "date",
+	SwimSortDistance: "distance_m",
+	SwimSortEffort:   "COALESCE(effort, 0)",
 }
 
 func sanitizeSortColumn(sort string) string {
@@ -2,6 +2,7 @@ package models
 
 import (
 	"database/sql"
+	"database/sql/driver"
 	"errors"
 	"fmt"
 	"regexp"
@@ -10,6 +11,7 @@ import (
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -18,62 +20,233 @@ func TestSwimModelInsert(t *testing.T) {
 		name        string
 		date        time.Time
 		distanceM   int
-		assessment  int
+		duration    time.Duration
+		stroke      Stroke
+		pool        Pool
+		laps        int
+		sets        []SwimSet
+		notes       string
+		tags        []string
+		locationId  int
+		feel        Feel
 		userId      int
 		setupMock   func(mock sqlmock.Sqlmock)
 		expectError bool
 		errorMsg    string
 	}{
 		{
-			name:       "successful insert",
-			date:       time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
-			distanceM:  1000,
-			assessment: 2,
-			userId:     1,
+			name:      "successful insert",
+			date:      time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+			distanceM: 1000,
+			stroke:    StrokeFreestyle,
+			feel:      4,
+			userId:    1,
 			setupMock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectExec("INSERT INTO swims").
-					WithArgs(time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), 1000, 2, 1).
-					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectBegin()
+				mock.ExpectQuery("INSERT INTO swims").
+					WithArgs(time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), 1000, nil, "freestyle", nil, nil, nil, "", nil, nil, 4, nil, 1).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				mock.ExpectCommit()
 			},
 			expectError: false,
 		},
 		{
-			name:       "database error on insert",
-			date:       time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
-			distanceM:  1000,
-			assessment: 2,
-			userId:     1,
+			name:      "database error on insert",
+			date:      time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+			distanceM: 1000,
+			stroke:    StrokeFreestyle,
+			feel:      4,
+			userId:    1,
 			setupMock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectExec("INSERT INTO swims").
-					WithArgs(time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), 1000, 2, 1).
+				mock.ExpectBegin()
+				mock.ExpectQuery("INSERT INTO swims").
+					WithArgs(time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), 1000, nil, "freestyle", nil, nil, nil, "", nil, nil, 4, nil, 1).
 					WillReturnError(errors.New("database connection lost"))
+				mock.ExpectRollback()
 			},
 			expectError: true,
 			errorMsg:    "database connection lost",
 		},
 		{
-			name:       "insert with zero distance",
-			date:       time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
-			distanceM:  0,
-			assessment: 0,
-			userId:     1,
+			name:      "insert with zero distance",
+			date:      time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+			distanceM: 0,
+			stroke:    StrokeFreestyle,
+			feel:      2,
+			userId:    1,
 			setupMock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectExec("INSERT INTO swims").
-					WithArgs(time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), 0, 0, 1).
+				mock.ExpectBegin()
+				mock.ExpectQuery("INSERT INTO swims").
+					WithArgs(time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), 0, nil, "freestyle", nil, nil, nil, "", nil, nil, 2, nil, 1).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				mock.ExpectCommit()
+			},
+			expectError: false,
+		},
+		{
+			name:      "insert with duration and stroke",
+			date:      time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+			distanceM: 2000,
+			duration:  42*time.Minute + 30*time.Second,
+			stroke:    StrokeBackstroke,
+			feel:      3,
+			userId:    1,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery("INSERT INTO swims").
+					WithArgs(time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), 2000, 2550, "backstroke", nil, nil, nil, "", nil, nil, 3, nil, 1).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				mock.ExpectCommit()
+			},
+			expectError: false,
+		},
+		{
+			name:      "insert with laps in a yard pool",
+			date:      time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+			distanceM: 1829,
+			stroke:    StrokeFreestyle,
+			pool:      Pool{Length: 25, Unit: UnitYards},
+			laps:      80,
+			feel:      3,
+			userId:    1,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery("INSERT INTO swims").
+					WithArgs(time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), 1829, nil, "freestyle", 25, "yd", 80, "", nil, nil, 3, nil, 1).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				mock.ExpectCommit()
+			},
+			expectError: false,
+		},
+		{
+			name:      "insert with sets",
+			date:      time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+			distanceM: 1400,
+			stroke:    StrokeFreestyle,
+			sets: []SwimSet{
+				{Repetitions: 1, DistanceM: 400, Stroke: StrokeFreestyle},
+				{Repetitions: 8, DistanceM: 100, Stroke: StrokeFreestyle, Interval: 105 * time.Second},
+				{Repetitions: 1, DistanceM: 200, Stroke: StrokeBackstroke, Rest: 30 * time.Second},
+			},
+			feel:   4,
+			userId: 1,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery("INSERT INTO swims").
+					WithArgs(time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), 1400, nil, "freestyle", nil, nil, nil, "", nil, nil, 4, nil, 1).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+				mock.ExpectExec("INSERT INTO swim_sets").
+					WithArgs(7, 1, 1, 400, "freestyle", nil, nil).
 					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec("INSERT INTO swim_sets").
+					WithArgs(7, 2, 8, 100, "freestyle", 105, nil).
+					WillReturnResult(sqlmock.NewResult(2, 1))
+				mock.ExpectExec("INSERT INTO swim_sets").
+					WithArgs(7, 3, 1, 200, "backstroke", nil, 30).
+					WillReturnResult(sqlmock.NewResult(3, 1))
+				mock.ExpectCommit()
+			},
+			expectError: false,
+		},
+		{
+			name:      "database error on set insert",
+			date:      time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+			distanceM: 400,
+			stroke:    StrokeFreestyle,
+			sets: []SwimSet{
+				{Repetitions: 4, DistanceM: 100, Stroke: StrokeFreestyle},
+			},
+			feel:   3,
+			userId: 1,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery("INSERT INTO swims").
+					WithArgs(time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), 400, nil, "freestyle", nil, nil, nil, "", nil, nil, 3, nil, 1).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+				mock.ExpectExec("INSERT INTO swim_sets").
+					WithArgs(7, 1, 4, 100, "freestyle", nil, nil).
+					WillReturnError(errors.New("constraint violation"))
+				mock.ExpectRollback()
+			},
+			expectError: true,
+			errorMsg:    "constraint violation",
+		},
+		{
+			name:      "insert with notes",
+			date:      time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+			distanceM: 1500,
+			stroke:    StrokeFreestyle,
+			notes:     "Shoulder felt tight",
+			feel:      2,
+			userId:    1,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery("INSERT INTO swims").
+					WithArgs(time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), 1500, nil, "freestyle", nil, nil, nil, "Shoulder felt tight", nil, nil, 2, nil, 1).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				mock.ExpectCommit()
 			},
 			expectError: false,
 		},
 		{
-			name:       "insert with large distance",
+			name:       "insert with location",
 			date:       time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
-			distanceM:  10000,
-			assessment: 2,
-			userId:     99,
+			distanceM:  1500,
+			stroke:     StrokeFreestyle,
+			locationId: 3,
+			feel:       3,
+			userId:     1,
 			setupMock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectExec("INSERT INTO swims").
-					WithArgs(time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), 10000, 2, 99).
-					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectBegin()
+				mock.ExpectQuery("INSERT INTO swims").
+					WithArgs(time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), 1500, nil, "freestyle", nil, nil, nil, "", 3, nil, 3, nil, 1).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				mock.ExpectCommit()
+			},
+			expectError: false,
+		},
+		{
+			name:      "insert with tags",
+			date:      time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+			distanceM: 2000,
+			stroke:    StrokeFreestyle,
+			tags:      []string{"open-water", "with club"},
+			feel:      4,
+			userId:    1,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery("INSERT INTO swims").
+					WithArgs(time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), 2000, nil, "freestyle", nil, nil, nil, "", nil, nil, 4, nil, 1).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+				mock.ExpectQuery("INSERT INTO tags \\(user_id, name\\) VALUES \\(\\$1, \\$2\\)\\s+ON CONFLICT \\(user_id, name\\)").
+					WithArgs(1, "open-water").
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+				mock.ExpectExec("INSERT INTO swim_tags \\(swim_id, tag_id\\) VALUES \\(\\$1, \\$2\\)").
+					WithArgs(7, 3).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("INSERT INTO tags \\(user_id, name\\) VALUES \\(\\$1, \\$2\\)\\s+ON CONFLICT \\(user_id, name\\)").
+					WithArgs(1, "with club").
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+				mock.ExpectExec("INSERT INTO swim_tags \\(swim_id, tag_id\\) VALUES \\(\\$1, \\$2\\)").
+					WithArgs(7, 4).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			expectError: false,
+		},
+		{
+			name:      "insert with large distance",
+			date:      time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+			distanceM: 10000,
+			stroke:    StrokeFreestyle,
+			feel:      4,
+			userId:    99,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery("INSERT INTO swims").
+					WithArgs(time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), 10000, nil, "freestyle", nil, nil, nil, "", nil, nil, 4, nil, 99).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				mock.ExpectCommit()
 			},
 			expectError: false,
 		},
@@ -90,7 +263,19 @@ func TestSwimModelInsert(t *testing.T) {
 			tt.setupMock(mock)
 
 			model := NewSwimModel(db)
-			err = model.Insert(tt.date, tt.distanceM, tt.assessment, tt.userId)
+			err = model.Insert(&Swim{
+				Date:       tt.date,
+				DistanceM:  tt.distanceM,
+				Feel:       tt.feel,
+				Duration:   tt.duration,
+				Stroke:     tt.stroke,
+				Pool:       tt.pool,
+				Laps:       tt.laps,
+				Sets:       tt.sets,
+				Notes:      tt.notes,
+				Tags:       tt.tags,
+				LocationId: tt.locationId,
+			}, tt.userId)
 
 			if tt.expectError {
 				assert.Error(t, err)
@@ -106,6 +291,97 @@ func TestSwimModelInsert(t *testing.T) {
 	}
 }
 
+func TestSwimModelInsertMany(t *testing.T) {
+	swims := func() []*Swim {
+		return []*Swim{
+			{Date: time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC), DistanceM: 1500, Stroke: StrokeFreestyle, Feel: FeelOkay},
+			{Date: time.Date(2019, 5, 3, 0, 0, 0, 0, time.UTC), DistanceM: 2000, Stroke: StrokeFreestyle, Effort: 7, Feel: FeelGood},
+		}
+	}
+
+	t.Run("all swims in one transaction", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer func() {
+			_ = db.Close()
+		}()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO swims").
+			WithArgs(time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC), 1500, nil, "freestyle", nil, nil, nil, "", nil, nil, 3, nil, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10))
+		mock.ExpectQuery("INSERT INTO swims").
+			WithArgs(time.Date(2019, 5, 3, 0, 0, 0, 0, time.UTC), 2000, nil, "freestyle", nil, nil, nil, "", nil, 7, 4, nil, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(11))
+		mock.ExpectCommit()
+
+		imported := swims()
+		err = NewSwimModel(db).InsertMany(imported, 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 10, imported[0].Id)
+		assert.Equal(t, 11, imported[1].Id)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rolls back all swims on error", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer func() {
+			_ = db.Close()
+		}()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO swims").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10))
+		mock.ExpectQuery("INSERT INTO swims").
+			WillReturnError(errors.New("check constraint violated"))
+		mock.ExpectRollback()
+
+		err = NewSwimModel(db).InsertMany(swims(), 1)
+
+		assert.EqualError(t, err, "check constraint violated")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("values the database rejects", func(t *testing.T) {
+		tests := []struct {
+			name        string
+			err         error
+			expectedErr error
+		}{
+			{name: "out of range", err: &pq.Error{Code: "22003"}, expectedErr: ErrInvalidSwim},
+			{name: "check constraint", err: &pq.Error{Code: "23514"}, expectedErr: ErrInvalidSwim},
+			{name: "connection lost", err: &pq.Error{Code: "08006"}},
+			{name: "duplicate source", err: &pq.Error{Code: "23505", Constraint: "swims_user_id_source_key"}, expectedErr: ErrDuplicateSource},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				db, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				defer func() {
+					_ = db.Close()
+				}()
+
+				mock.ExpectBegin()
+				mock.ExpectQuery("INSERT INTO swims").WillReturnError(tt.err)
+				mock.ExpectRollback()
+
+				err = NewSwimModel(db).InsertMany(swims()[:1], 1)
+
+				if tt.expectedErr != nil {
+					assert.ErrorIs(t, err, tt.expectedErr)
+				} else {
+					assert.NotErrorIs(t, err, ErrInvalidSwim)
+					assert.ErrorIs(t, err, tt.err)
+				}
+				assert.NoError(t, mock.ExpectationsWereMet())
+			})
+		}
+	})
+}
+
 func TestSwimModelUpdate(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -113,50 +389,119 @@ func TestSwimModelUpdate(t *testing.T) {
 		swimId      int
 		date        time.Time
 		distanceM   int
-		assessment  int
+		duration    time.Duration
+		stroke      Stroke
+		pool        Pool
+		laps        int
+		sets        []SwimSet
+		feel        Feel
 		setupMock   func(mock sqlmock.Sqlmock)
 		expectError bool
 		errorType   error
 	}{
 		{
-			name:       "successful update",
-			userId:     1,
-			swimId:     10,
-			date:       time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
-			distanceM:  2000,
-			assessment: 2,
+			name:      "successful update",
+			userId:    1,
+			swimId:    10,
+			date:      time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+			distanceM: 2000,
+			stroke:    StrokeFreestyle,
+			feel:      4,
 			setupMock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectExec("UPDATE swims SET date = \\$1, distance_m = \\$2, assessment = \\$3 WHERE id = \\$4 AND user_id = \\$5").
-					WithArgs(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), 2000, 2, 10, 1).
+				mock.ExpectBegin()
+				mock.ExpectExec("UPDATE swims SET date = \\$1, distance_m = \\$2, duration_s = \\$3, stroke = \\$4, pool_length = \\$5, pool_unit = \\$6,\\s+laps = \\$7, notes = \\$8, location_id = \\$9, effort = \\$10, feel = \\$11 WHERE id = \\$12 AND user_id = \\$13").
+					WithArgs(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), 2000, nil, "freestyle", nil, nil, nil, "", nil, nil, 4, 10, 1).
 					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("DELETE FROM swim_sets WHERE swim_id = \\$1").
+					WithArgs(10).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("DELETE FROM swim_tags WHERE swim_id = \\$1").
+					WithArgs(10).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectCommit()
 			},
 		},
 		{
-			name:       "no rows updated",
-			userId:     1,
-			swimId:     999,
-			date:       time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
-			distanceM:  1000,
-			assessment: 1,
+			name:      "successful update with duration",
+			userId:    1,
+			swimId:    10,
+			date:      time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+			distanceM: 1500,
+			duration:  30 * time.Minute,
+			stroke:    StrokeFreestyle,
+			feel:      3,
 			setupMock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectExec("UPDATE swims SET date = \\$1, distance_m = \\$2, assessment = \\$3 WHERE id = \\$4 AND user_id = \\$5").
-					WithArgs(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), 1000, 1, 999, 1).
+				mock.ExpectBegin()
+				mock.ExpectExec("UPDATE swims SET date = \\$1, distance_m = \\$2, duration_s = \\$3, stroke = \\$4, pool_length = \\$5, pool_unit = \\$6,\\s+laps = \\$7, notes = \\$8, location_id = \\$9, effort = \\$10, feel = \\$11 WHERE id = \\$12 AND user_id = \\$13").
+					WithArgs(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), 1500, 1800, "freestyle", nil, nil, nil, "", nil, nil, 3, 10, 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("DELETE FROM swim_sets WHERE swim_id = \\$1").
+					WithArgs(10).
 					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("DELETE FROM swim_tags WHERE swim_id = \\$1").
+					WithArgs(10).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name:      "successful update replaces sets",
+			userId:    1,
+			swimId:    10,
+			date:      time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+			distanceM: 1000,
+			stroke:    StrokeFreestyle,
+			sets:      []SwimSet{{Repetitions: 10, DistanceM: 100, Stroke: StrokeFreestyle, Interval: 2 * time.Minute}},
+			feel:      3,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec("UPDATE swims SET date = \\$1, distance_m = \\$2, duration_s = \\$3, stroke = \\$4, pool_length = \\$5, pool_unit = \\$6,\\s+laps = \\$7, notes = \\$8, location_id = \\$9, effort = \\$10, feel = \\$11 WHERE id = \\$12 AND user_id = \\$13").
+					WithArgs(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), 1000, nil, "freestyle", nil, nil, nil, "", nil, nil, 3, 10, 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("DELETE FROM swim_sets WHERE swim_id = \\$1").
+					WithArgs(10).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec("INSERT INTO swim_sets").
+					WithArgs(10, 1, 10, 100, "freestyle", 120, nil).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec("DELETE FROM swim_tags WHERE swim_id = \\$1").
+					WithArgs(10).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name:      "no rows updated",
+			userId:    1,
+			swimId:    999,
+			date:      time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+			distanceM: 1000,
+			stroke:    StrokeFreestyle,
+			feel:      3,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec("UPDATE swims SET date = \\$1, distance_m = \\$2, duration_s = \\$3, stroke = \\$4, pool_length = \\$5, pool_unit = \\$6,\\s+laps = \\$7, notes = \\$8, location_id = \\$9, effort = \\$10, feel = \\$11 WHERE id = \\$12 AND user_id = \\$13").
+					WithArgs(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), 1000, nil, "freestyle", nil, nil, nil, "", nil, nil, 3, 999, 1).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
 			},
 			expectError: true,
 			errorType:   ErrNoRecord,
 		},
 		{
-			name:       "database error",
-			userId:     1,
-			swimId:     5,
-			date:       time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC),
-			distanceM:  1500,
-			assessment: 0,
+			name:      "database error",
+			userId:    1,
+			swimId:    5,
+			date:      time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC),
+			distanceM: 1500,
+			stroke:    StrokeFreestyle,
+			feel:      2,
 			setupMock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectExec("UPDATE swims SET date = \\$1, distance_m = \\$2, assessment = \\$3 WHERE id = \\$4 AND user_id = \\$5").
-					WithArgs(time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), 1500, 0, 5, 1).
+				mock.ExpectBegin()
+				mock.ExpectExec("UPDATE swims SET date = \\$1, distance_m = \\$2, duration_s = \\$3, stroke = \\$4, pool_length = \\$5, pool_unit = \\$6,\\s+laps = \\$7, notes = \\$8, location_id = \\$9, effort = \\$10, feel = \\$11 WHERE id = \\$12 AND user_id = \\$13").
+					WithArgs(time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), 1500, nil, "freestyle", nil, nil, nil, "", nil, nil, 2, 5, 1).
 					WillReturnError(errors.New("update failed"))
+				mock.ExpectRollback()
 			},
 			expectError: true,
 		},
@@ -173,7 +518,17 @@ func TestSwimModelUpdate(t *testing.T) {
 			tt.setupMock(mock)
 
 			model := NewSwimModel(db)
-			err = model.Update(tt.swimId, tt.userId, tt.date, tt.distanceM, tt.assessment)
+			err = model.Update(&Swim{
+				Id:        tt.swimId,
+				Date:      tt.date,
+				DistanceM: tt.distanceM,
+				Feel:      tt.feel,
+				Duration:  tt.duration,
+				Stroke:    tt.stroke,
+				Pool:      tt.pool,
+				Laps:      tt.laps,
+				Sets:      tt.sets,
+			}, tt.userId)
 
 			if tt.expectError {
 				assert.Error(t, err)
@@ -200,22 +555,22 @@ func TestSwimModelGet(t *testing.T) {
 		{
 			name: "successful get earliest swim",
 			setupMock: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "date", "distance_m", "assessment"}).
-					AddRow(1, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 1500, 2)
-				mock.ExpectQuery("SELECT id, date, distance_m, assessment FROM swims ORDER BY date ASC LIMIT 1").
+				rows := sqlmock.NewRows([]string{"id", "date", "distance_m", "effort", "feel", "duration_s", "stroke", "pool_length", "pool_unit", "laps", "notes", "location_id"}).
+					AddRow(1, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 1500, nil, 4, nil, "freestyle", nil, nil, nil, "", nil)
+				mock.ExpectQuery("SELECT id, date, distance_m, effort, feel, duration_s, stroke, pool_length, pool_unit, laps, notes, location_id FROM swims ORDER BY date ASC LIMIT 1").
 					WillReturnRows(rows)
 			},
 			expectError: false,
 			expectedSwim: &Swim{
-				Date:       time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
-				DistanceM:  1500,
-				Assessment: 2,
+				Date:      time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				DistanceM: 1500,
+				Feel:      4,
 			},
 		},
 		{
 			name: "no records found",
 			setupMock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery("SELECT id, date, distance_m, assessment FROM swims ORDER BY date ASC LIMIT 1").
+				mock.ExpectQuery("SELECT id, date, distance_m, effort, feel, duration_s, stroke, pool_length, pool_unit, laps, notes, location_id FROM swims ORDER BY date ASC LIMIT 1").
 					WillReturnError(sql.ErrNoRows)
 			},
 			expectError:  true,
@@ -225,7 +580,7 @@ func TestSwimModelGet(t *testing.T) {
 		{
 			name: "database error",
 			setupMock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery("SELECT id, date, distance_m, assessment FROM swims ORDER BY date ASC LIMIT 1").
+				mock.ExpectQuery("SELECT id, date, distance_m, effort, feel, duration_s, stroke, pool_length, pool_unit, laps, notes, location_id FROM swims ORDER BY date ASC LIMIT 1").
 					WillReturnError(errors.New("connection timeout"))
 			},
 			expectError:  true,
@@ -256,7 +611,7 @@ func TestSwimModelGet(t *testing.T) {
 				assert.NoError(t, err)
 				assert.Equal(t, tt.expectedSwim.Date, swim.Date)
 				assert.Equal(t, tt.expectedSwim.DistanceM, swim.DistanceM)
-				assert.Equal(t, tt.expectedSwim.Assessment, swim.Assessment)
+				assert.Equal(t, tt.expectedSwim.Feel, swim.Feel)
 			}
 
 			assert.NoError(t, mock.ExpectationsWereMet())
@@ -279,17 +634,85 @@ func TestSwimModelGetByID(t *testing.T) {
 			userId: 1,
 			swimId: 10,
 			setupMock: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "date", "distance_m", "assessment"}).
-					AddRow(10, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), 1800, 1)
-				mock.ExpectQuery("SELECT id, date, distance_m, assessment FROM swims WHERE id = \\$1 AND user_id = \\$2").
+				rows := sqlmock.NewRows([]string{"id", "date", "distance_m", "effort", "feel", "duration_s", "stroke", "pool_length", "pool_unit", "laps", "notes", "location_id"}).
+					AddRow(10, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), 1800, nil, 3, nil, "freestyle", nil, nil, nil, "", nil)
+				mock.ExpectQuery("SELECT id, date, distance_m, effort, feel, duration_s, stroke, pool_length, pool_unit, laps, notes, location_id FROM swims WHERE id = \\$1 AND user_id = \\$2").
 					WithArgs(10, 1).
 					WillReturnRows(rows)
+				mock.ExpectQuery("SELECT repetitions, distance_m, stroke, interval_s, rest_s FROM swim_sets WHERE swim_id = \\$1 ORDER BY position ASC").
+					WithArgs(10).
+					WillReturnRows(sqlmock.NewRows([]string{"repetitions", "distance_m", "stroke", "interval_s", "rest_s"}))
+				mock.ExpectQuery("SELECT st.swim_id, t.name FROM swim_tags st JOIN tags t ON t.id = st.tag_id\\s+WHERE st.swim_id = ANY\\(\\$1\\) ORDER BY t.name ASC").
+					WillReturnRows(sqlmock.NewRows([]string{"swim_id", "name"}))
+			},
+			expectedSwim: &Swim{
+				Id:        10,
+				Date:      time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+				DistanceM: 1800,
+				Feel:      3,
+				Stroke:    StrokeFreestyle,
+			},
+		},
+		{
+			name:   "successful fetch of lap-based swim",
+			userId: 1,
+			swimId: 11,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "date", "distance_m", "effort", "feel", "duration_s", "stroke", "pool_length", "pool_unit", "laps", "notes", "location_id"}).
+					AddRow(11, time.Date(2024, 2, 2, 0, 0, 0, 0, time.UTC), 1500, nil, 4, 1800, "butterfly", 50, "m", 30, "", 3)
+				mock.ExpectQuery("SELECT id, date, distance_m, effort, feel, duration_s, stroke, pool_length, pool_unit, laps, notes, location_id FROM swims WHERE id = \\$1 AND user_id = \\$2").
+					WithArgs(11, 1).
+					WillReturnRows(rows)
+				mock.ExpectQuery("SELECT repetitions, distance_m, stroke, interval_s, rest_s FROM swim_sets WHERE swim_id = \\$1 ORDER BY position ASC").
+					WithArgs(11).
+					WillReturnRows(sqlmock.NewRows([]string{"repetitions", "distance_m", "stroke", "interval_s", "rest_s"}))
+				mock.ExpectQuery("SELECT st.swim_id, t.name FROM swim_tags st JOIN tags t ON t.id = st.tag_id\\s+WHERE st.swim_id = ANY\\(\\$1\\) ORDER BY t.name ASC").
+					WillReturnRows(sqlmock.NewRows([]string{"swim_id", "name"}))
+			},
+			expectedSwim: &Swim{
+				Id:         11,
+				Date:       time.Date(2024, 2, 2, 0, 0, 0, 0, time.UTC),
+				DistanceM:  1500,
+				Feel:       4,
+				Duration:   30 * time.Minute,
+				Stroke:     StrokeButterfly,
+				Pool:       Pool{Length: 50, Unit: UnitMeters},
+				Laps:       30,
+				LocationId: 3,
+			},
+		},
+		{
+			name:   "successful fetch with sets and tags",
+			userId: 1,
+			swimId: 12,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "date", "distance_m", "effort", "feel", "duration_s", "stroke", "pool_length", "pool_unit", "laps", "notes", "location_id"}).
+					AddRow(12, time.Date(2024, 2, 3, 0, 0, 0, 0, time.UTC), 1400, nil, 4, nil, "freestyle", nil, nil, nil, "", nil)
+				mock.ExpectQuery("SELECT id, date, distance_m, effort, feel, duration_s, stroke, pool_length, pool_unit, laps, notes, location_id FROM swims WHERE id = \\$1 AND user_id = \\$2").
+					WithArgs(12, 1).
+					WillReturnRows(rows)
+				setRows := sqlmock.NewRows([]string{"repetitions", "distance_m", "stroke", "interval_s", "rest_s"}).
+					AddRow(1, 400, "freestyle", nil, nil).
+					AddRow(8, 100, "freestyle", 105, nil).
+					AddRow(1, 200, "backstroke", nil, 30)
+				mock.ExpectQuery("SELECT repetitions, distance_m, stroke, interval_s, rest_s FROM swim_sets WHERE swim_id = \\$1 ORDER BY position ASC").
+					WithArgs(12).
+					WillReturnRows(setRows)
+				mock.ExpectQuery("SELECT st.swim_id, t.name FROM swim_tags st JOIN tags t ON t.id = st.tag_id\\s+WHERE st.swim_id = ANY\\(\\$1\\) ORDER BY t.name ASC").
+					WillReturnRows(sqlmock.NewRows([]string{"swim_id", "name"}).AddRow(12, "race-pace").AddRow(12, "technique"))
 			},
 			expectedSwim: &Swim{
-				Id:         10,
-				Date:       time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
-				DistanceM:  1800,
-				Assessment: 1,
+				Id:        12,
+				Date:      time.Date(2024, 2, 3, 0, 0, 0, 0, time.UTC),
+				DistanceM: 1400,
+				Feel:      4,
+				Stroke:    StrokeFreestyle,
+				Sets: []SwimSet{
+					{Repetitions: 1, DistanceM: 400, Stroke: StrokeFreestyle},
+					{Repetitions: 8, DistanceM: 100, Stroke: StrokeFreestyle, Interval: 105 * time.Second},
+					{Repetitions: 1, DistanceM: 200, Stroke: StrokeBackstroke, Rest: 30 * time.Second},
+				},
+				Tags: []string{"race-pace", "technique"},
 			},
 		},
 		{
@@ -297,7 +720,7 @@ func TestSwimModelGetByID(t *testing.T) {
 			userId: 1,
 			swimId: 999,
 			setupMock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery("SELECT id, date, distance_m, assessment FROM swims WHERE id = \\$1 AND user_id = \\$2").
+				mock.ExpectQuery("SELECT id, date, distance_m, effort, feel, duration_s, stroke, pool_length, pool_unit, laps, notes, location_id FROM swims WHERE id = \\$1 AND user_id = \\$2").
 					WithArgs(999, 1).
 					WillReturnError(sql.ErrNoRows)
 			},
@@ -310,7 +733,7 @@ func TestSwimModelGetByID(t *testing.T) {
 			userId: 1,
 			swimId: 5,
 			setupMock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery("SELECT id, date, distance_m, assessment FROM swims WHERE id = \\$1 AND user_id = \\$2").
+				mock.ExpectQuery("SELECT id, date, distance_m, effort, feel, duration_s, stroke, pool_length, pool_unit, laps, notes, location_id FROM swims WHERE id = \\$1 AND user_id = \\$2").
 					WithArgs(5, 1).
 					WillReturnError(errors.New("query failed"))
 			},
@@ -361,27 +784,31 @@ func TestSwimModelGetAll(t *testing.T) {
 			name:   "successful get all swims",
 			userId: 1,
 			setupMock: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "date", "distance_m", "assessment"}).
-					AddRow(1, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 1000, 1).
-					AddRow(2, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), 1500, 2).
-					AddRow(3, time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC), 2000, 2)
-				mock.ExpectQuery("SELECT id, date, distance_m, assessment FROM swims WHERE user_id = \\$1 ORDER BY date ASC").
+				rows := sqlmock.NewRows([]string{"id", "date", "distance_m", "effort", "feel", "duration_s", "stroke", "pool_length", "pool_unit", "laps", "notes", "location_id"}).
+					AddRow(1, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 1000, nil, 3, nil, "freestyle", nil, nil, nil, "", nil).
+					AddRow(2, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), 1500, nil, 4, nil, "freestyle", nil, nil, nil, "", nil).
+					AddRow(3, time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC), 2000, nil, 4, nil, "freestyle", nil, nil, nil, "", nil)
+				mock.ExpectQuery("SELECT id, date, distance_m, effort, feel, duration_s, stroke, pool_length, pool_unit, laps, notes, location_id FROM swims WHERE user_id = \\$1 ORDER BY date ASC").
 					WithArgs(1).
 					WillReturnRows(rows)
+				mock.ExpectQuery("SELECT st.swim_id, t.name FROM swim_tags st JOIN tags t ON t.id = st.tag_id\\s+WHERE st.swim_id = ANY\\(\\$1\\) ORDER BY t.name ASC").
+					WillReturnRows(sqlmock.NewRows([]string{"swim_id", "name"}).
+						AddRow(2, "intervals").
+						AddRow(2, "open water"))
 			},
 			expectError: false,
 			expectedSwims: []*Swim{
-				{Date: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), DistanceM: 1000, Assessment: 1},
-				{Date: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), DistanceM: 1500, Assessment: 2},
-				{Date: time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC), DistanceM: 2000, Assessment: 2},
+				{Date: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), DistanceM: 1000, Feel: 3},
+				{Date: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), DistanceM: 1500, Feel: 4, Tags: []string{"intervals", "open water"}},
+				{Date: time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC), DistanceM: 2000, Feel: 4},
 			},
 		},
 		{
 			name:   "empty result set",
 			userId: 1,
 			setupMock: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "date", "distance_m", "assessment"})
-				mock.ExpectQuery("SELECT id, date, distance_m, assessment FROM swims WHERE user_id = \\$1 ORDER BY date ASC").
+				rows := sqlmock.NewRows([]string{"id", "date", "distance_m", "effort", "feel", "duration_s", "stroke", "pool_length", "pool_unit", "laps", "notes", "location_id"})
+				mock.ExpectQuery("SELECT id, date, distance_m, effort, feel, duration_s, stroke, pool_length, pool_unit, laps, notes, location_id FROM swims WHERE user_id = \\$1 ORDER BY date ASC").
 					WithArgs(1).
 					WillReturnRows(rows)
 			},
@@ -392,7 +819,7 @@ func TestSwimModelGetAll(t *testing.T) {
 			name:   "database error on query",
 			userId: 1,
 			setupMock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery("SELECT id, date, distance_m, assessment FROM swims WHERE user_id = \\$1 ORDER BY date ASC").
+				mock.ExpectQuery("SELECT id, date, distance_m, effort, feel, duration_s, stroke, pool_length, pool_unit, laps, notes, location_id FROM swims WHERE user_id = \\$1 ORDER BY date ASC").
 					WithArgs(1).
 					WillReturnError(errors.New("query execution failed"))
 			},
@@ -404,10 +831,10 @@ func TestSwimModelGetAll(t *testing.T) {
 			name:   "scan error on rows",
 			userId: 1,
 			setupMock: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "date", "distance_m", "assessment"}).
-					AddRow(1, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 1000, 1).
-					AddRow(2, "invalid-date", 1500, 2) // Invalid date will cause scan error
-				mock.ExpectQuery("SELECT id, date, distance_m, assessment FROM swims WHERE user_id = \\$1 ORDER BY date ASC").
+				rows := sqlmock.NewRows([]string{"id", "date", "distance_m", "effort", "feel", "duration_s", "stroke", "pool_length", "pool_unit", "laps", "notes", "location_id"}).
+					AddRow(1, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 1000, nil, 3, nil, "freestyle", nil, nil, nil, "", nil).
+					AddRow(2, "invalid-date", 1500, nil, 4, nil, "freestyle", nil, nil, nil, "", nil) // Invalid date will cause scan error
+				mock.ExpectQuery("SELECT id, date, distance_m, effort, feel, duration_s, stroke, pool_length, pool_unit, laps, notes, location_id FROM swims WHERE user_id = \\$1 ORDER BY date ASC").
 					WithArgs(1).
 					WillReturnRows(rows)
 			},
@@ -418,17 +845,19 @@ func TestSwimModelGetAll(t *testing.T) {
 			name:   "ordering verification - dates in ascending order",
 			userId: 5,
 			setupMock: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "date", "distance_m", "assessment"}).
-					AddRow(1, time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC), 500, 1).
-					AddRow(2, time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), 750, 2)
-				mock.ExpectQuery("SELECT id, date, distance_m, assessment FROM swims WHERE user_id = \\$1 ORDER BY date ASC").
+				rows := sqlmock.NewRows([]string{"id", "date", "distance_m", "effort", "feel", "duration_s", "stroke", "pool_length", "pool_unit", "laps", "notes", "location_id"}).
+					AddRow(1, time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC), 500, nil, 3, nil, "freestyle", nil, nil, nil, "", nil).
+					AddRow(2, time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), 750, nil, 4, nil, "freestyle", nil, nil, nil, "", nil)
+				mock.ExpectQuery("SELECT id, date, distance_m, effort, feel, duration_s, stroke, pool_length, pool_unit, laps, notes, location_id FROM swims WHERE user_id = \\$1 ORDER BY date ASC").
 					WithArgs(5).
 					WillReturnRows(rows)
+				mock.ExpectQuery("SELECT st.swim_id, t.name FROM swim_tags st JOIN tags t ON t.id = st.tag_id\\s+WHERE st.swim_id = ANY\\(\\$1\\) ORDER BY t.name ASC").
+					WillReturnRows(sqlmock.NewRows([]string{"swim_id", "name"}))
 			},
 			expectError: false,
 			expectedSwims: []*Swim{
-				{Date: time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC), DistanceM: 500, Assessment: 1},
-				{Date: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), DistanceM: 750, Assessment: 2},
+				{Date: time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC), DistanceM: 500, Feel: 3},
+				{Date: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), DistanceM: 750, Feel: 4},
 			},
 		},
 	}
@@ -457,7 +886,8 @@ func TestSwimModelGetAll(t *testing.T) {
 				for i, expectedSwim := range tt.expectedSwims {
 					assert.Equal(t, expectedSwim.Date, swims[i].Date)
 					assert.Equal(t, expectedSwim.DistanceM, swims[i].DistanceM)
-					assert.Equal(t, expectedSwim.Assessment, swims[i].Assessment)
+					assert.Equal(t, expectedSwim.Feel, swims[i].Feel)
+					assert.Equal(t, expectedSwim.Tags, swims[i].Tags)
 				}
 			}
 
@@ -474,6 +904,7 @@ func TestSwimModelGetPaginated(t *testing.T) {
 		offset        int
 		sort          string
 		direction     string
+		filter        SwimFilter
 		setupMock     func(mock sqlmock.Sqlmock)
 		expectError   bool
 		expectedSwims []*Swim
@@ -488,21 +919,79 @@ func TestSwimModelGetPaginated(t *testing.T) {
 			direction: SortDirectionDesc,
 			setupMock: func(mock sqlmock.Sqlmock) {
 				query := regexp.QuoteMeta(fmt.Sprintf(
-					"SELECT id, date, distance_m, assessment FROM swims WHERE user_id = $1 ORDER BY %s %s LIMIT $2 OFFSET $3",
+					"SELECT id, date, distance_m, effort, feel, duration_s, stroke, pool_length, pool_unit, laps, notes, location_id FROM swims WHERE user_id = $1 ORDER BY %s %[2]s, id %[2]s LIMIT $2 OFFSET $3",
 					sortColumnMap[SwimSortDate],
 					"DESC",
 				))
-				rows := sqlmock.NewRows([]string{"id", "date", "distance_m", "assessment"}).
-					AddRow(1, time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC), 2000, 2).
-					AddRow(2, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), 1500, 2)
+				rows := sqlmock.NewRows([]string{"id", "date", "distance_m", "effort", "feel", "duration_s", "stroke", "pool_length", "pool_unit", "laps", "notes", "location_id"}).
+					AddRow(1, time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC), 2000, nil, 4, nil, "freestyle", nil, nil, nil, "", nil).
+					AddRow(2, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), 1500, nil, 4, nil, "freestyle", nil, nil, nil, "", nil)
 				mock.ExpectQuery(query).
 					WithArgs(1, 2, 0).
 					WillReturnRows(rows)
+				mock.ExpectQuery("SELECT st.swim_id, t.name FROM swim_tags st JOIN tags t ON t.id = st.tag_id\\s+WHERE st.swim_id = ANY\\(\\$1\\) ORDER BY t.name ASC").
+					WillReturnRows(sqlmock.NewRows([]string{"swim_id", "name"}))
 			},
 			expectError: false,
 			expectedSwims: []*Swim{
-				{Date: time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC), DistanceM: 2000, Assessment: 2},
-				{Date: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), DistanceM: 1500, Assessment: 2},
+				{Date: time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC), DistanceM: 2000, Feel: 4},
+				{Date: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), DistanceM: 1500, Feel: 4},
+			},
+		},
+		{
+			name:      "search in notes",
+			userId:    1,
+			limit:     20,
+			offset:    20,
+			sort:      SwimSortDistance,
+			direction: SortDirectionAsc,
+			filter:    SwimFilter{Search: "new goggles"},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				query := regexp.QuoteMeta(fmt.Sprintf(
+					"SELECT id, date, distance_m, effort, feel, duration_s, stroke, pool_length, pool_unit, laps, notes, location_id FROM swims WHERE user_id = $1 AND notes_tsv @@ websearch_to_tsquery('simple', $2) ORDER BY %s %[2]s, id %[2]s LIMIT $3 OFFSET $4",
+					sortColumnMap[SwimSortDistance],
+					"ASC",
+				))
+				rows := sqlmock.NewRows([]string{"id", "date", "distance_m", "effort", "feel", "duration_s", "stroke", "pool_length", "pool_unit", "laps", "notes", "location_id"}).
+					AddRow(4, time.Date(2024, 1, 4, 0, 0, 0, 0, time.UTC), 1200, nil, 4, nil, "freestyle", nil, nil, nil, "Tried the new goggles", nil)
+				mock.ExpectQuery(query).
+					WithArgs(1, "new goggles", 20, 20).
+					WillReturnRows(rows)
+				mock.ExpectQuery("SELECT st.swim_id, t.name FROM swim_tags st JOIN tags t ON t.id = st.tag_id\\s+WHERE st.swim_id = ANY\\(\\$1\\) ORDER BY t.name ASC").
+					WillReturnRows(sqlmock.NewRows([]string{"swim_id", "name"}))
+			},
+			expectedSwims: []*Swim{
+				{Date: time.Date(2024, 1, 4, 0, 0, 0, 0, time.UTC), DistanceM: 1200, Feel: 4, Notes: "Tried the new goggles"},
+			},
+		},
+		{
+			name:      "filter by tags",
+			userId:    1,
+			limit:     20,
+			offset:    0,
+			sort:      SwimSortDate,
+			direction: SortDirectionDesc,
+			filter:    SwimFilter{Search: "tight", Tags: []string{"technique", "with club"}},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				query := regexp.QuoteMeta(fmt.Sprintf(
+					"SELECT id, date, distance_m, effort, feel, duration_s, stroke, pool_length, pool_unit, laps, notes, location_id FROM swims WHERE user_id = $1"+
+						" AND notes_tsv @@ websearch_to_tsquery('simple', $2)"+
+						" AND EXISTS (SELECT 1 FROM swim_tags st JOIN tags t ON t.id = st.tag_id WHERE st.swim_id = swims.id AND t.name = $3)"+
+						" AND EXISTS (SELECT 1 FROM swim_tags st JOIN tags t ON t.id = st.tag_id WHERE st.swim_id = swims.id AND t.name = $4)"+
+						" ORDER BY %s %[2]s, id %[2]s LIMIT $5 OFFSET $6",
+					sortColumnMap[SwimSortDate],
+					"DESC",
+				))
+				rows := sqlmock.NewRows([]string{"id", "date", "distance_m", "effort", "feel", "duration_s", "stroke", "pool_length", "pool_unit", "laps", "notes", "location_id"}).
+					AddRow(5, time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC), 1800, nil, 3, nil, "freestyle", nil, nil, nil, "Shoulder felt tight", nil)
+				mock.ExpectQuery(query).
+					WithArgs(1, "tight", "technique", "with club", 20, 0).
+					WillReturnRows(rows)
+				mock.ExpectQuery("SELECT st.swim_id, t.name FROM swim_tags st JOIN tags t ON t.id = st.tag_id\\s+WHERE st.swim_id = ANY\\(\\$1\\) ORDER BY t.name ASC").
+					WillReturnRows(sqlmock.NewRows([]string{"swim_id", "name"}).AddRow(5, "technique").AddRow(5, "with club"))
+			},
+			expectedSwims: []*Swim{
+				{Date: time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC), DistanceM: 1800, Feel: 3, Notes: "Shoulder felt tight", Tags: []string{"technique", "with club"}},
 			},
 		},
 		{
@@ -514,19 +1003,21 @@ func TestSwimModelGetPaginated(t *testing.T) {
 			direction: SortDirectionDesc,
 			setupMock: func(mock sqlmock.Sqlmock) {
 				query := regexp.QuoteMeta(fmt.Sprintf(
-					"SELECT id, date, distance_m, assessment FROM swims WHERE user_id = $1 ORDER BY %s %s LIMIT $2 OFFSET $3",
+					"SELECT id, date, distance_m, effort, feel, duration_s, stroke, pool_length, pool_unit, laps, notes, location_id FROM swims WHERE user_id = $1 ORDER BY %s %[2]s, id %[2]s LIMIT $2 OFFSET $3",
 					sortColumnMap[SwimSortDate],
 					"DESC",
 				))
-				rows := sqlmock.NewRows([]string{"id", "date", "distance_m", "assessment"}).
-					AddRow(3, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 1000, 1)
+				rows := sqlmock.NewRows([]string{"id", "date", "distance_m", "effort", "feel", "duration_s", "stroke", "pool_length", "pool_unit", "laps", "notes", "location_id"}).
+					AddRow(3, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 1000, nil, 3, nil, "freestyle", nil, nil, nil, "", nil)
 				mock.ExpectQuery(query).
 					WithArgs(1, 2, 2).
 					WillReturnRows(rows)
+				mock.ExpectQuery("SELECT st.swim_id, t.name FROM swim_tags st JOIN tags t ON t.id = st.tag_id\\s+WHERE st.swim_id = ANY\\(\\$1\\) ORDER BY t.name ASC").
+					WillReturnRows(sqlmock.NewRows([]string{"swim_id", "name"}))
 			},
 			expectError: false,
 			expectedSwims: []*Swim{
-				{Date: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), DistanceM: 1000, Assessment: 1},
+				{Date: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), DistanceM: 1000, Feel: 3},
 			},
 		},
 		{
@@ -538,11 +1029,11 @@ func TestSwimModelGetPaginated(t *testing.T) {
 			direction: SortDirectionDesc,
 			setupMock: func(mock sqlmock.Sqlmock) {
 				query := regexp.QuoteMeta(fmt.Sprintf(
-					"SELECT id, date, distance_m, assessment FROM swims WHERE user_id = $1 ORDER BY %s %s LIMIT $2 OFFSET $3",
+					"SELECT id, date, distance_m, effort, feel, duration_s, stroke, pool_length, pool_unit, laps, notes, location_id FROM swims WHERE user_id = $1 ORDER BY %s %[2]s, id %[2]s LIMIT $2 OFFSET $3",
 					sortColumnMap[SwimSortDate],
 					"DESC",
 				))
-				rows := sqlmock.NewRows([]string{"id", "date", "distance_m", "assessment"})
+				rows := sqlmock.NewRows([]string{"id", "date", "distance_m", "effort", "feel", "duration_s", "stroke", "pool_length", "pool_unit", "laps", "notes", "location_id"})
 				mock.ExpectQuery(query).
 					WithArgs(1, 20, 100).
 					WillReturnRows(rows)
@@ -559,27 +1050,29 @@ func TestSwimModelGetPaginated(t *testing.T) {
 			direction: SortDirectionDesc,
 			setupMock: func(mock sqlmock.Sqlmock) {
 				query := regexp.QuoteMeta(fmt.Sprintf(
-					"SELECT id, date, distance_m, assessment FROM swims WHERE user_id = $1 ORDER BY %s %s LIMIT $2 OFFSET $3",
+					"SELECT id, date, distance_m, effort, feel, duration_s, stroke, pool_length, pool_unit, laps, notes, location_id FROM swims WHERE user_id = $1 ORDER BY %s %[2]s, id %[2]s LIMIT $2 OFFSET $3",
 					sortColumnMap[SwimSortDate],
 					"DESC",
 				))
 				// Simulate exactly 20 records
-				rows := sqlmock.NewRows([]string{"id", "date", "distance_m", "assessment"})
+				rows := sqlmock.NewRows([]string{"id", "date", "distance_m", "effort", "feel", "duration_s", "stroke", "pool_length", "pool_unit", "laps", "notes", "location_id"})
 				for i := 20; i > 0; i-- {
-					rows.AddRow(i, time.Date(2024, 1, i, 0, 0, 0, 0, time.UTC), 1000*i, 2)
+					rows.AddRow(i, time.Date(2024, 1, i, 0, 0, 0, 0, time.UTC), 1000*i, nil, 4, nil, "freestyle", nil, nil, nil, "", nil)
 				}
 				mock.ExpectQuery(query).
 					WithArgs(1, 20, 0).
 					WillReturnRows(rows)
+				mock.ExpectQuery("SELECT st.swim_id, t.name FROM swim_tags st JOIN tags t ON t.id = st.tag_id\\s+WHERE st.swim_id = ANY\\(\\$1\\) ORDER BY t.name ASC").
+					WillReturnRows(sqlmock.NewRows([]string{"swim_id", "name"}))
 			},
 			expectError: false,
 			expectedSwims: func() []*Swim {
 				swims := make([]*Swim, 20)
 				for i := 0; i < 20; i++ {
 					swims[i] = &Swim{
-						Date:       time.Date(2024, 1, 20-i, 0, 0, 0, 0, time.UTC),
-						DistanceM:  1000 * (20 - i),
-						Assessment: 2,
+						Date:      time.Date(2024, 1, 20-i, 0, 0, 0, 0, time.UTC),
+						DistanceM: 1000 * (20 - i),
+						Feel:      4,
 					}
 				}
 				return swims
@@ -594,7 +1087,7 @@ func TestSwimModelGetPaginated(t *testing.T) {
 			direction: SortDirectionDesc,
 			setupMock: func(mock sqlmock.Sqlmock) {
 				query := regexp.QuoteMeta(fmt.Sprintf(
-					"SELECT id, date, distance_m, assessment FROM swims WHERE user_id = $1 ORDER BY %s %s LIMIT $2 OFFSET $3",
+					"SELECT id, date, distance_m, effort, feel, duration_s, stroke, pool_length, pool_unit, laps, notes, location_id FROM swims WHERE user_id = $1 ORDER BY %s %[2]s, id %[2]s LIMIT $2 OFFSET $3",
 					sortColumnMap[SwimSortDate],
 					"DESC",
 				))
@@ -615,23 +1108,25 @@ func TestSwimModelGetPaginated(t *testing.T) {
 			direction: SortDirectionDesc,
 			setupMock: func(mock sqlmock.Sqlmock) {
 				query := regexp.QuoteMeta(fmt.Sprintf(
-					"SELECT id, date, distance_m, assessment FROM swims WHERE user_id = $1 ORDER BY %s %s LIMIT $2 OFFSET $3",
+					"SELECT id, date, distance_m, effort, feel, duration_s, stroke, pool_length, pool_unit, laps, notes, location_id FROM swims WHERE user_id = $1 ORDER BY %s %[2]s, id %[2]s LIMIT $2 OFFSET $3",
 					sortColumnMap[SwimSortDate],
 					"DESC",
 				))
-				rows := sqlmock.NewRows([]string{"id", "date", "distance_m", "assessment"}).
-					AddRow(1, time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC), 3000, 2).
-					AddRow(2, time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC), 2000, 2).
-					AddRow(3, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 1000, 1)
+				rows := sqlmock.NewRows([]string{"id", "date", "distance_m", "effort", "feel", "duration_s", "stroke", "pool_length", "pool_unit", "laps", "notes", "location_id"}).
+					AddRow(1, time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC), 3000, nil, 4, nil, "freestyle", nil, nil, nil, "", nil).
+					AddRow(2, time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC), 2000, nil, 4, nil, "freestyle", nil, nil, nil, "", nil).
+					AddRow(3, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 1000, nil, 3, nil, "freestyle", nil, nil, nil, "", nil)
 				mock.ExpectQuery(query).
 					WithArgs(2, 3, 0).
 					WillReturnRows(rows)
+				mock.ExpectQuery("SELECT st.swim_id, t.name FROM swim_tags st JOIN tags t ON t.id = st.tag_id\\s+WHERE st.swim_id = ANY\\(\\$1\\) ORDER BY t.name ASC").
+					WillReturnRows(sqlmock.NewRows([]string{"swim_id", "name"}))
 			},
 			expectError: false,
 			expectedSwims: []*Swim{
-				{Date: time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC), DistanceM: 3000, Assessment: 2},
-				{Date: time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC), DistanceM: 2000, Assessment: 2},
-				{Date: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), DistanceM: 1000, Assessment: 1},
+				{Date: time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC), DistanceM: 3000, Feel: 4},
+				{Date: time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC), DistanceM: 2000, Feel: 4},
+				{Date: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), DistanceM: 1000, Feel: 3},
 			},
 		},
 		{
@@ -643,11 +1138,11 @@ func TestSwimModelGetPaginated(t *testing.T) {
 			direction: SortDirectionAsc,
 			setupMock: func(mock sqlmock.Sqlmock) {
 				query := regexp.QuoteMeta(fmt.Sprintf(
-					"SELECT id, date, distance_m, assessment FROM swims WHERE user_id = $1 ORDER BY %s %s LIMIT $2 OFFSET $3",
+					"SELECT id, date, distance_m, effort, feel, duration_s, stroke, pool_length, pool_unit, laps, notes, location_id FROM swims WHERE user_id = $1 ORDER BY %s %[2]s, id %[2]s LIMIT $2 OFFSET $3",
 					sortColumnMap[SwimSortDistance],
 					"ASC",
 				))
-				rows := sqlmock.NewRows([]string{"id", "date", "distance_m", "assessment"})
+				rows := sqlmock.NewRows([]string{"id", "date", "distance_m", "effort", "feel", "duration_s", "stroke", "pool_length", "pool_unit", "laps", "notes", "location_id"})
 				mock.ExpectQuery(query).
 					WithArgs(1, 5, 0).
 					WillReturnRows(rows)
@@ -662,11 +1157,11 @@ func TestSwimModelGetPaginated(t *testing.T) {
 			direction: "weird",
 			setupMock: func(mock sqlmock.Sqlmock) {
 				query := regexp.QuoteMeta(fmt.Sprintf(
-					"SELECT id, date, distance_m, assessment FROM swims WHERE user_id = $1 ORDER BY %s %s LIMIT $2 OFFSET $3",
+					"SELECT id, date, distance_m, effort, feel, duration_s, stroke, pool_length, pool_unit, laps, notes, location_id FROM swims WHERE user_id = $1 ORDER BY %s %[2]s, id %[2]s LIMIT $2 OFFSET $3",
 					sortColumnMap[SwimSortDate],
 					"DESC",
 				))
-				rows := sqlmock.NewRows([]string{"id", "date", "distance_m", "assessment"})
+				rows := sqlmock.NewRows([]string{"id", "date", "distance_m", "effort", "feel", "duration_s", "stroke", "pool_length", "pool_unit", "laps", "notes", "location_id"})
 				mock.ExpectQuery(query).
 					WithArgs(1, 5, 0).
 					WillReturnRows(rows)
@@ -681,11 +1176,11 @@ func TestSwimModelGetPaginated(t *testing.T) {
 			direction: strings.ToUpper(SortDirectionAsc),
 			setupMock: func(mock sqlmock.Sqlmock) {
 				query := regexp.QuoteMeta(fmt.Sprintf(
-					"SELECT id, date, distance_m, assessment FROM swims WHERE user_id = $1 ORDER BY %s %s LIMIT $2 OFFSET $3",
+					"SELECT id, date, distance_m, effort, feel, duration_s, stroke, pool_length, pool_unit, laps, notes, location_id FROM swims WHERE user_id = $1 ORDER BY %s %[2]s, id %[2]s LIMIT $2 OFFSET $3",
 					sortColumnMap[SwimSortDate],
 					"ASC",
 				))
-				rows := sqlmock.NewRows([]string{"id", "date", "distance_m", "assessment"})
+				rows := sqlmock.NewRows([]string{"id", "date", "distance_m", "effort", "feel", "duration_s", "stroke", "pool_length", "pool_unit", "laps", "notes", "location_id"})
 				mock.ExpectQuery(query).
 					WithArgs(1, 5, 0).
 					WillReturnRows(rows)
@@ -704,7 +1199,7 @@ func TestSwimModelGetPaginated(t *testing.T) {
 			tt.setupMock(mock)
 
 			model := NewSwimModel(db)
-			swims, err := model.GetPaginated(tt.userId, tt.limit, tt.offset, tt.sort, tt.direction)
+			swims, err := model.GetPaginated(tt.userId, tt.limit, tt.offset, tt.sort, tt.direction, tt.filter)
 
 			if tt.expectError {
 				assert.Error(t, err)
@@ -717,10 +1212,186 @@ func TestSwimModelGetPaginated(t *testing.T) {
 				for i, expectedSwim := range tt.expectedSwims {
 					assert.Equal(t, expectedSwim.Date, swims[i].Date)
 					assert.Equal(t, expectedSwim.DistanceM, swims[i].DistanceM)
-					assert.Equal(t, expectedSwim.Assessment, swims[i].Assessment)
+					assert.Equal(t, expectedSwim.Feel, swims[i].Feel)
+					assert.Equal(t, expectedSwim.Notes, swims[i].Notes)
+					assert.Equal(t, expectedSwim.Tags, swims[i].Tags)
+				}
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestSwimModelGetAfter(t *testing.T) {
+	columns := []string{"id", "date", "distance_m", "effort", "feel", "duration_s", "stroke", "pool_length", "pool_unit", "laps", "notes", "location_id"}
+	after := &SwimKey{Id: 5, Date: time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC), DistanceM: 2000, Effort: 6}
+
+	tests := []struct {
+		name         string
+		after        *SwimKey
+		sort         string
+		direction    string
+		filter       SwimFilter
+		expectedStmt string
+		expectedArgs []driver.Value
+	}{
+		{
+			name:         "first page",
+			sort:         SwimSortDate,
+			direction:    SortDirectionDesc,
+			expectedStmt: "WHERE user_id = $1 ORDER BY date DESC, id DESC LIMIT $2",
+			expectedArgs: []driver.Value{1, 3},
+		},
+		{
+			name:         "after a date",
+			after:        after,
+			sort:         SwimSortDate,
+			direction:    SortDirectionDesc,
+			expectedStmt: "WHERE user_id = $1 AND (date, id) < ($2, $3) ORDER BY date DESC, id DESC LIMIT $4",
+			expectedArgs: []driver.Value{1, after.Date, 5, 3},
+		},
+		{
+			name:         "after a distance",
+			after:        after,
+			sort:         SwimSortDistance,
+			direction:    SortDirectionAsc,
+			expectedStmt: "WHERE user_id = $1 AND (distance_m, id) > ($2, $3) ORDER BY distance_m ASC, id ASC LIMIT $4",
+			expectedArgs: []driver.Value{1, 2000, 5, 3},
+		},
+		{
+			name:         "after an effort with a filter",
+			after:        after,
+			sort:         SwimSortEffort,
+			direction:    SortDirectionDesc,
+			filter:       SwimFilter{Search: "goggles"},
+			expectedStmt: "WHERE user_id = $1 AND notes_tsv @@ websearch_to_tsquery('simple', $2) AND (COALESCE(effort, 0), id) < ($3, $4) ORDER BY COALESCE(effort, 0) DESC, id DESC LIMIT $5",
+			expectedArgs: []driver.Value{1, "goggles", 6, 5, 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer func() {
+				_ = db.Close()
+			}()
+
+			mock.ExpectQuery(regexp.QuoteMeta(tt.expectedStmt)).
+				WithArgs(tt.expectedArgs...).
+				WillReturnRows(sqlmock.NewRows(columns).
+					AddRow(4, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), 1500, nil, 4, nil, "freestyle", nil, nil, nil, "", nil))
+			mock.ExpectQuery("SELECT st.swim_id, t.name FROM swim_tags st JOIN tags t ON t.id = st.tag_id\\s+WHERE st.swim_id = ANY\\(\\$1\\) ORDER BY t.name ASC").
+				WillReturnRows(sqlmock.NewRows([]string{"swim_id", "name"}))
+
+			model := NewSwimModel(db)
+			swims, err := model.GetAfter(1, 3, tt.after, tt.sort, tt.direction, tt.filter)
+
+			assert.NoError(t, err)
+			assert.Len(t, swims, 1)
+			assert.Equal(t, 4, swims[0].Id)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestSwimModelExport(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		sort          string
+		direction     string
+		filter        SwimFilter
+		each          func(*Swim) error
+		setupMock     func(mock sqlmock.Sqlmock)
+		expectedSwims []*Swim
+		expectedError string
+	}{
+		{
+			name:      "all swims in the given order",
+			sort:      SwimSortDistance,
+			direction: SortDirectionAsc,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "date", "distance_m", "effort", "feel", "duration_s", "stroke", "pool_length", "pool_unit", "laps", "notes", "location_id"}).
+					AddRow(2, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), 1000, 6, 3, 1200, "backstroke", 25, "m", 40, "Easy", nil).
+					AddRow(1, time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC), 2000, nil, 4, nil, "freestyle", nil, nil, nil, "", nil)
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT id, date, distance_m, effort, feel, duration_s, stroke, pool_length, pool_unit, laps, notes, location_id FROM swims WHERE user_id = $1 ORDER BY distance_m ASC, id ASC")).
+					WithArgs(1).
+					WillReturnRows(rows)
+			},
+			expectedSwims: []*Swim{
+				{Id: 2, Date: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), DistanceM: 1000, Effort: 6, Feel: 3, Duration: 20 * time.Minute, Stroke: StrokeBackstroke, Pool: Pool{Length: 25, Unit: UnitMeters}, Laps: 40, Notes: "Easy"},
+				{Id: 1, Date: time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC), DistanceM: 2000, Feel: 4, Stroke: StrokeFreestyle},
+			},
+		},
+		{
+			name:      "date range",
+			sort:      SwimSortDate,
+			direction: SortDirectionDesc,
+			filter:    SwimFilter{From: from, To: to},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT id, date, distance_m, effort, feel, duration_s, stroke, pool_length, pool_unit, laps, notes, location_id FROM swims WHERE user_id = $1 AND date >= $2 AND date <= $3 ORDER BY date DESC, id DESC")).
+					WithArgs(1, from, to).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "date", "distance_m", "effort", "feel", "duration_s", "stroke", "pool_length", "pool_unit", "laps", "notes", "location_id"}))
+			},
+		},
+		{
+			name: "stops at the first error of the callback",
+			each: func(*Swim) error {
+				return errors.New("client gone")
+			},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "date", "distance_m", "effort", "feel", "duration_s", "stroke", "pool_length", "pool_unit", "laps", "notes", "location_id"}).
+					AddRow(1, time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC), 2000, nil, 4, nil, "freestyle", nil, nil, nil, "", nil).
+					AddRow(2, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), 1500, nil, 4, nil, "freestyle", nil, nil, nil, "", nil)
+				mock.ExpectQuery("SELECT id, date, distance_m").
+					WithArgs(1).
+					WillReturnRows(rows)
+			},
+			expectedError: "client gone",
+		},
+		{
+			name: "database error",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT id, date, distance_m").
+					WithArgs(1).
+					WillReturnError(errors.New("connection lost"))
+			},
+			expectedError: "connection lost",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer func() {
+				_ = db.Close()
+			}()
+
+			tt.setupMock(mock)
+
+			var swims []*Swim
+			each := tt.each
+			if each == nil {
+				each = func(swim *Swim) error {
+					swims = append(swims, swim)
+					return nil
 				}
 			}
 
+			err = NewSwimModel(db).Export(1, tt.sort, tt.direction, tt.filter, each)
+
+			if tt.expectedError != "" {
+				assert.EqualError(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedSwims, swims)
+			}
+
 			assert.NoError(t, mock.ExpectationsWereMet())
 		})
 	}
@@ -737,8 +1408,8 @@ func TestSwimModelSummarize(t *testing.T) {
 			name:   "empty dataset",
 			userId: 1,
 			setupMock: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "date", "distance_m", "assessment"})
-				mock.ExpectQuery("SELECT id, date, distance_m, assessment FROM swims WHERE user_id = \\$1 ORDER BY date ASC").
+				rows := sqlmock.NewRows([]string{"id", "date", "distance_m", "effort", "feel", "duration_s", "stroke", "pool_length", "pool_unit", "laps", "notes", "location_id"})
+				mock.ExpectQuery("SELECT id, date, distance_m, effort, feel, duration_s, stroke, pool_length, pool_unit, laps, notes, location_id FROM swims WHERE user_id = \\$1 ORDER BY date ASC").
 					WithArgs(1).
 					WillReturnRows(rows)
 			},
@@ -756,9 +1427,9 @@ func TestSwimModelSummarize(t *testing.T) {
 			name:   "single swim from past year",
 			userId: 1,
 			setupMock: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "date", "distance_m", "assessment"}).
-					AddRow(1, time.Date(2020, 6, 15, 0, 0, 0, 0, time.UTC), 1500, 2)
-				mock.ExpectQuery("SELECT id, date, distance_m, assessment FROM swims WHERE user_id = \\$1 ORDER BY date ASC").
+				rows := sqlmock.NewRows([]string{"id", "date", "distance_m", "effort", "feel", "duration_s", "stroke", "pool_length", "pool_unit", "laps", "notes", "location_id"}).
+					AddRow(1, time.Date(2020, 6, 15, 0, 0, 0, 0, time.UTC), 1500, nil, 4, nil, "freestyle", nil, nil, nil, "", nil)
+				mock.ExpectQuery("SELECT id, date, distance_m, effort, feel, duration_s, stroke, pool_length, pool_unit, laps, notes, location_id FROM swims WHERE user_id = \\$1 ORDER BY date ASC").
 					WithArgs(1).
 					WillReturnRows(rows)
 			},
@@ -794,11 +1465,11 @@ func TestSwimModelSummarize(t *testing.T) {
 			name:   "multiple swims across different months in same year",
 			userId: 1,
 			setupMock: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "date", "distance_m", "assessment"}).
-					AddRow(1, time.Date(2020, 1, 10, 0, 0, 0, 0, time.UTC), 1000, 1).
-					AddRow(2, time.Date(2020, 3, 15, 0, 0, 0, 0, time.UTC), 1500, 2).
-					AddRow(3, time.Date(2020, 3, 20, 0, 0, 0, 0, time.UTC), 2000, 2)
-				mock.ExpectQuery("SELECT id, date, distance_m, assessment FROM swims WHERE user_id = \\$1 ORDER BY date ASC").
+				rows := sqlmock.NewRows([]string{"id", "date", "distance_m", "effort", "feel", "duration_s", "stroke", "pool_length", "pool_unit", "laps", "notes", "location_id"}).
+					AddRow(1, time.Date(2020, 1, 10, 0, 0, 0, 0, time.UTC), 1000, nil, 3, nil, "freestyle", nil, nil, nil, "", nil).
+					AddRow(2, time.Date(2020, 3, 15, 0, 0, 0, 0, time.UTC), 1500, nil, 4, nil, "freestyle", nil, nil, nil, "", nil).
+					AddRow(3, time.Date(2020, 3, 20, 0, 0, 0, 0, time.UTC), 2000, nil, 4, nil, "freestyle", nil, nil, nil, "", nil)
+				mock.ExpectQuery("SELECT id, date, distance_m, effort, feel, duration_s, stroke, pool_length, pool_unit, laps, notes, location_id FROM swims WHERE user_id = \\$1 ORDER BY date ASC").
 					WithArgs(1).
 					WillReturnRows(rows)
 			},
@@ -834,11 +1505,11 @@ func TestSwimModelSummarize(t *testing.T) {
 			name:   "multiple swims across different years",
 			userId: 1,
 			setupMock: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "date", "distance_m", "assessment"}).
-					AddRow(1, time.Date(2019, 12, 25, 0, 0, 0, 0, time.UTC), 1000, 1).
-					AddRow(2, time.Date(2020, 1, 5, 0, 0, 0, 0, time.UTC), 1500, 2).
-					AddRow(3, time.Date(2021, 6, 15, 0, 0, 0, 0, time.UTC), 2000, 2)
-				mock.ExpectQuery("SELECT id, date, distance_m, assessment FROM swims WHERE user_id = \\$1 ORDER BY date ASC").
+				rows := sqlmock.NewRows([]string{"id", "date", "distance_m", "effort", "feel", "duration_s", "stroke", "pool_length", "pool_unit", "laps", "notes", "location_id"}).
+					AddRow(1, time.Date(2019, 12, 25, 0, 0, 0, 0, time.UTC), 1000, nil, 3, nil, "freestyle", nil, nil, nil, "", nil).
+					AddRow(2, time.Date(2020, 1, 5, 0, 0, 0, 0, time.UTC), 1500, nil, 4, nil, "freestyle", nil, nil, nil, "", nil).
+					AddRow(3, time.Date(2021, 6, 15, 0, 0, 0, 0, time.UTC), 2000, nil, 4, nil, "freestyle", nil, nil, nil, "", nil)
+				mock.ExpectQuery("SELECT id, date, distance_m, effort, feel, duration_s, stroke, pool_length, pool_unit, laps, notes, location_id FROM swims WHERE user_id = \\$1 ORDER BY date ASC").
 					WithArgs(1).
 					WillReturnRows(rows)
 			},
@@ -909,9 +1580,9 @@ func TestSwimModelSummarize(t *testing.T) {
 			userId: 1,
 			setupMock: func(mock sqlmock.Sqlmock) {
 				now := time.Now()
-				rows := sqlmock.NewRows([]string{"id", "date", "distance_m", "assessment"}).
-					AddRow(1, now, 1500, 2)
-				mock.ExpectQuery("SELECT id, date, distance_m, assessment FROM swims WHERE user_id = \\$1 ORDER BY date ASC").
+				rows := sqlmock.NewRows([]string{"id", "date", "distance_m", "effort", "feel", "duration_s", "stroke", "pool_length", "pool_unit", "laps", "notes", "location_id"}).
+					AddRow(1, now, 1500, nil, 4, nil, "freestyle", nil, nil, nil, "", nil)
+				mock.ExpectQuery("SELECT id, date, distance_m, effort, feel, duration_s, stroke, pool_length, pool_unit, laps, notes, location_id FROM swims WHERE user_id = \\$1 ORDER BY date ASC").
 					WithArgs(1).
 					WillReturnRows(rows)
 			},
@@ -941,7 +1612,7 @@ func TestSwimModelSummarize(t *testing.T) {
 			name:   "database error returns empty summary",
 			userId: 1,
 			setupMock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery("SELECT id, date, distance_m, assessment FROM swims WHERE user_id = \\$1 ORDER BY date ASC").
+				mock.ExpectQuery("SELECT id, date, distance_m, effort, feel, duration_s, stroke, pool_length, pool_unit, laps, notes, location_id FROM swims WHERE user_id = \\$1 ORDER BY date ASC").
 					WithArgs(1).
 					WillReturnError(errors.New("database error"))
 			},
@@ -968,7 +1639,7 @@ func TestSwimModelSummarize(t *testing.T) {
 			tt.setupMock(mock)
 
 			model := NewSwimModel(db)
-			summary := model.Summarize(tt.userId)
+			summary := model.Summarize(tt.userId, SwimFilter{})
 
 			assert.Equal(t, tt.expectedSummary.TotalDistance, summary.TotalDistance)
 			assert.Equal(t, tt.expectedSummary.TotalCount, summary.TotalCount)
@@ -1081,6 +1752,170 @@ func TestSwimSummaryHelperMethods(t *testing.T) {
 	})
 }
 
+func TestSwimModelSummarizePace(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer func() {
+		_ = db.Close()
+	}()
+
+	now := time.Now()
+	rows := sqlmock.NewRows([]string{"id", "date", "distance_m", "effort", "feel", "duration_s", "stroke", "pool_length", "pool_unit", "laps", "notes", "location_id"}).
+		AddRow(1, now, 2000, nil, 4, 2400, "freestyle", nil, nil, nil, "", nil).
+		AddRow(2, now, 1000, nil, 3, 1500, "freestyle", nil, nil, nil, "", nil).
+		AddRow(3, now, 1500, nil, 3, nil, "freestyle", nil, nil, nil, "", nil)
+	mock.ExpectQuery("SELECT id, date, distance_m, effort, feel, duration_s, stroke, pool_length, pool_unit, laps, notes, location_id FROM swims WHERE user_id = \\$1 ORDER BY date ASC").
+		WithArgs(1).
+		WillReturnRows(rows)
+
+	summary := NewSwimModel(db).Summarize(1, SwimFilter{})
+
+	// 3900 s over 3000 timed meters, the untimed swim is ignored.
+	expectedPace := 2*time.Minute + 10*time.Second
+	assert.Equal(t, expectedPace, summary.WeeklyPace)
+	assert.Equal(t, expectedPace, summary.MonthlyPace)
+	assert.Equal(t, expectedPace, summary.YearlyPace)
+	assert.Equal(t, expectedPace, summary.YearMap[now.Year()].MonthMap[now.Month()].Pace())
+	assert.Equal(t, 4500, summary.WeeklyDistance)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSwimModelSummarizeEffort(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer func() {
+		_ = db.Close()
+	}()
+
+	now := time.Now()
+	rows := sqlmock.NewRows([]string{"id", "date", "distance_m", "effort", "feel", "duration_s", "stroke", "pool_length", "pool_unit", "laps", "notes", "location_id"}).
+		AddRow(1, now, 2000, 8, 4, nil, "freestyle", nil, nil, nil, "", nil).
+		AddRow(2, now, 1000, 5, 3, nil, "freestyle", nil, nil, nil, "", nil).
+		AddRow(3, now, 1500, nil, 3, nil, "freestyle", nil, nil, nil, "", nil)
+	mock.ExpectQuery("SELECT id, date, distance_m, effort, feel, duration_s, stroke, pool_length, pool_unit, laps, notes, location_id FROM swims WHERE user_id = \\$1 ORDER BY date ASC").
+		WithArgs(1).
+		WillReturnRows(rows)
+
+	summary := NewSwimModel(db).Summarize(1, SwimFilter{})
+
+	// The swim without a recorded effort is ignored.
+	assert.Equal(t, 6.5, summary.WeeklyEffort)
+	assert.Equal(t, 6.5, summary.MonthlyEffort)
+	assert.Equal(t, 2, summary.YearMap[now.Year()].RatedCount)
+	assert.Equal(t, 3, summary.YearMap[now.Year()].Count)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSwimModelSummarizeStrokes(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer func() {
+		_ = db.Close()
+	}()
+
+	rows := sqlmock.NewRows([]string{"id", "date", "distance_m", "effort", "feel", "duration_s", "stroke", "pool_length", "pool_unit", "laps", "notes", "location_id"}).
+		AddRow(1, time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC), 2000, nil, 4, nil, "freestyle", nil, nil, nil, "", nil).
+		AddRow(2, time.Date(2020, 4, 1, 0, 0, 0, 0, time.UTC), 1000, nil, 3, nil, "breaststroke", nil, nil, nil, "", nil).
+		AddRow(3, time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC), 1500, nil, 3, nil, "freestyle", nil, nil, nil, "", nil)
+	mock.ExpectQuery("SELECT id, date, distance_m, effort, feel, duration_s, stroke, pool_length, pool_unit, laps, notes, location_id FROM swims WHERE user_id = \\$1 ORDER BY date ASC").
+		WithArgs(1).
+		WillReturnRows(rows)
+
+	summary := NewSwimModel(db).Summarize(1, SwimFilter{})
+
+	assert.Equal(t, SwimFigures{Count: 2, DistanceM: 3500}, summary.StrokeMap[StrokeFreestyle])
+	assert.Equal(t, SwimFigures{Count: 1, DistanceM: 1000}, summary.StrokeMap[StrokeBreaststroke])
+	assert.NotContains(t, summary.StrokeMap, StrokeBackstroke)
+
+	assert.Equal(t, SwimFigures{Count: 1, DistanceM: 2000}, summary.YearMap[2020].StrokeMap[StrokeFreestyle])
+	assert.Equal(t, SwimFigures{Count: 1, DistanceM: 1000}, summary.YearMap[2020].StrokeMap[StrokeBreaststroke])
+	assert.Equal(t, SwimFigures{Count: 1, DistanceM: 1500}, summary.YearMap[2021].StrokeMap[StrokeFreestyle])
+	assert.Len(t, summary.YearMap[2021].StrokeMap, 1)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSwimModelSummarizeByTag(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer func() {
+		_ = db.Close()
+	}()
+
+	rows := sqlmock.NewRows([]string{"id", "date", "distance_m", "effort", "feel", "duration_s", "stroke", "pool_length", "pool_unit", "laps", "notes", "location_id"}).
+		AddRow(1, time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC), 2000, nil, 4, nil, "freestyle", nil, nil, nil, "", nil)
+	mock.ExpectQuery("SELECT id, date, distance_m, effort, feel, duration_s, stroke, pool_length, pool_unit, laps, notes, location_id FROM swims WHERE user_id = \\$1 AND EXISTS \\(.+ AND t.name = \\$2\\) ORDER BY date ASC").
+		WithArgs(1, "open-water").
+		WillReturnRows(rows)
+
+	summary := NewSwimModel(db).Summarize(1, SwimFilter{Tags: []string{"open-water"}})
+
+	assert.Equal(t, 1, summary.TotalCount)
+	assert.Equal(t, 2000, summary.TotalDistance)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSwimFilterTags(t *testing.T) {
+	filter := SwimFilter{Search: "goggles", Tags: []string{"technique"}}
+
+	assert.True(t, filter.HasTag("technique"))
+	assert.False(t, filter.HasTag("race-pace"))
+
+	added := filter.WithTag("race-pace")
+	assert.Equal(t, []string{"technique", "race-pace"}, added.Tags)
+	assert.Equal(t, "goggles", added.Search)
+	assert.Equal(t, []string{"technique"}, filter.Tags, "WithTag must not modify the original filter")
+	assert.Equal(t, added, added.WithTag("race-pace"))
+
+	removed := added.WithoutTag("technique")
+	assert.Equal(t, []string{"race-pace"}, removed.Tags)
+	assert.Nil(t, removed.WithoutTag("race-pace").Tags)
+}
+
+func TestStroke(t *testing.T) {
+	tests := []struct {
+		stroke        Stroke
+		expectedValid bool
+		expectedLabel string
+	}{
+		{StrokeFreestyle, true, "Freestyle"},
+		{StrokeBreaststroke, true, "Breaststroke"},
+		{StrokeBackstroke, true, "Backstroke"},
+		{StrokeButterfly, true, "Butterfly"},
+		{StrokeMixed, true, "Mixed"},
+		{Stroke(""), false, ""},
+		{Stroke("doggy"), false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.stroke), func(t *testing.T) {
+			assert.Equal(t, tt.expectedValid, tt.stroke.Valid())
+			assert.Equal(t, tt.expectedLabel, tt.stroke.Label())
+		})
+	}
+}
+
+func TestSwimPace(t *testing.T) {
+	tests := []struct {
+		name     string
+		swim     Swim
+		expected time.Duration
+	}{
+		{"no duration", Swim{DistanceM: 1000}, 0},
+		{"no distance", Swim{Duration: 20 * time.Minute}, 0},
+		{"even pace", Swim{DistanceM: 1000, Duration: 20 * time.Minute}, 2 * time.Minute},
+		{"rounds to seconds", Swim{DistanceM: 1500, Duration: 31 * time.Minute}, 2*time.Minute + 4*time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.swim.Pace())
+		})
+	}
+}
+
 func TestSwimModelDelete(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -0,0 +1,132 @@
+package models
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// MaxTagLength is the maximum length of a tag name, matching the tags.name
+// column.
+const MaxTagLength = 50
+
+// NormalizeTag brings a tag name into its stored form: trimmed, lower case and
+// with inner whitespace collapsed, so that "With  Club" and "with club" are the
+// same tag.
+func NormalizeTag(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+type TagModel interface {
+	GetAll(userId int) ([]string, error)
+}
+
+type tagModel struct {
+	DB *sql.DB
+}
+
+func NewTagModel(db *sql.DB) TagModel {
+	return &tagModel{DB: db}
+}
+
+// GetAll returns the names of all tags a user has created, sorted by name.
+func (tm *tagModel) GetAll(userId int) ([]string, error) {
+	stmt := `SELECT name FROM tags WHERE user_id = $1 ORDER BY name ASC;`
+
+	rows, err := tm.DB.Query(stmt, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err := rows.Close(); err != nil {
+			// Ignore Close error to avoid overriding return error
+			_ = err
+		}
+	}()
+
+	var tags []string
+	for rows.Next() {
+		var name string
+		errScan := rows.Scan(&name)
+		if errScan != nil {
+			return nil, errScan
+		}
+
+		tags = append(tags, name)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// insertSwimTags attaches the given tags to a swim. Tags the user has not used
+// before are created on the fly.
+func insertSwimTags(tx *sql.Tx, userId int, swimId int, tags []string) error {
+	tagStmt := `INSERT INTO tags (user_id, name) VALUES ($1, $2)
+		ON CONFLICT (user_id, name) DO UPDATE SET name = EXCLUDED.name RETURNING id;`
+	linkStmt := `INSERT INTO swim_tags (swim_id, tag_id) VALUES ($1, $2);`
+
+	for _, name := range tags {
+		var tagId int
+		err := tx.QueryRow(tagStmt, userId, name).Scan(&tagId)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(linkStmt, swimId, tagId)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// attachTags loads the tags of the given swims with a single query.
+func (sw *swimModel) attachTags(swims []*Swim) error {
+	if len(swims) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(swims))
+	byId := make(map[int]*Swim, len(swims))
+	for i, swim := range swims {
+		ids[i] = int64(swim.Id)
+		byId[swim.Id] = swim
+	}
+
+	stmt := `SELECT st.swim_id, t.name FROM swim_tags st JOIN tags t ON t.id = st.tag_id
+		WHERE st.swim_id = ANY($1) ORDER BY t.name ASC;`
+
+	rows, err := sw.DB.Query(stmt, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err := rows.Close(); err != nil {
+			// Ignore Close error to avoid overriding return error
+			_ = err
+		}
+	}()
+
+	for rows.Next() {
+		var swimId int
+		var name string
+		errScan := rows.Scan(&swimId, &name)
+		if errScan != nil {
+			return errScan
+		}
+
+		if swim, ok := byId[swimId]; ok {
+			swim.Tags = append(swim.Tags, name)
+		}
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeTag(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"already normalized", "technique", "technique"},
+		{"upper case", "Race-Pace", "race-pace"},
+		{"surrounding whitespace", "  open-water ", "open-water"},
+		{"inner whitespace collapsed", "With   Club", "with club"},
+		{"empty", "   ", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, NormalizeTag(tt.input))
+		})
+	}
+}
+
+func TestTagModelGetAll(t *testing.T) {
+	tests := []struct {
+		name         string
+		setupMock    func(mock sqlmock.Sqlmock)
+		expectedTags []string
+		expectError  bool
+	}{
+		{
+			name: "tags sorted by name",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"name"}).
+					AddRow("open-water").
+					AddRow("technique")
+				mock.ExpectQuery("SELECT name FROM tags WHERE user_id = \\$1 ORDER BY name ASC").
+					WithArgs(1).
+					WillReturnRows(rows)
+			},
+			expectedTags: []string{"open-water", "technique"},
+		},
+		{
+			name: "no tags",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT name FROM tags WHERE user_id = \\$1 ORDER BY name ASC").
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows([]string{"name"}))
+			},
+		},
+		{
+			name: "database error",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT name FROM tags WHERE user_id = \\$1 ORDER BY name ASC").
+					WithArgs(1).
+					WillReturnError(errors.New("connection lost"))
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer func() {
+				_ = db.Close()
+			}()
+
+			tt.setupMock(mock)
+
+			tags, err := NewTagModel(db).GetAll(1)
+
+			if tt.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedTags, tags)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
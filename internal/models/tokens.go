@@ -0,0 +1,215 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// MaxTokenNameLength is the maximum length of an access token name, matching
+// the access_tokens.name column.
+const MaxTokenNameLength = 100
+
+// AccessTokenPrefix starts every access token, so that a leaked token is
+// easy to recognize.
+const AccessTokenPrefix = "swm_"
+
+// Scope is a permission of an access token.
+type Scope string
+
+const (
+	ScopeSwimsRead  Scope = "swims:read"
+	ScopeSwimsWrite Scope = "swims:write"
+)
+
+// Scopes are the scopes an access token can be given, in the order of the
+// token form.
+var Scopes = []Scope{ScopeSwimsRead, ScopeSwimsWrite}
+
+func (s Scope) Valid() bool {
+	return s == ScopeSwimsRead || s == ScopeSwimsWrite
+}
+
+// Label describes what a scope allows.
+func (s Scope) Label() string {
+	switch s {
+	case ScopeSwimsRead:
+		return "Read swims and the summary"
+	case ScopeSwimsWrite:
+		return "Create, change and delete swims"
+	default:
+		return string(s)
+	}
+}
+
+// AccessToken is a personal access token of a user. The token itself is only
+// known when it is created, since just a hash of it is stored.
+type AccessToken struct {
+	Id     int
+	Name   string
+	Scopes []Scope
+	// LastUsed is the time of the latest request with the token, zero if it
+	// has not been used yet.
+	LastUsed time.Time
+	Created  time.Time
+}
+
+// HasScope reports whether the token grants a scope.
+func (t *AccessToken) HasScope(scope Scope) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type AccessTokenModel interface {
+	GetAll(userId int) ([]*AccessToken, error)
+	Insert(token *AccessToken, userId int) (string, error)
+	Delete(id int, userId int) error
+	Authenticate(token string) (int, *AccessToken, error)
+}
+
+type accessTokenModel struct {
+	DB *sql.DB
+}
+
+func NewAccessTokenModel(db *sql.DB) AccessTokenModel {
+	return &accessTokenModel{DB: db}
+}
+
+// GetAll returns the access tokens of a user, the newest first.
+func (am *accessTokenModel) GetAll(userId int) ([]*AccessToken, error) {
+	stmt := `SELECT id, name, scopes, last_used, created FROM access_tokens WHERE user_id = $1
+		ORDER BY created DESC, id DESC;`
+
+	rows, err := am.DB.Query(stmt, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err := rows.Close(); err != nil {
+			// Ignore Close error to avoid overriding return error
+			_ = err
+		}
+	}()
+
+	var tokens []*AccessToken
+	for rows.Next() {
+		token, errScan := scanAccessToken(rows)
+		if errScan != nil {
+			return nil, errScan
+		}
+
+		tokens = append(tokens, token)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// Insert creates an access token with the name and scopes of token and
+// returns the secret token. Names are unique per user, reusing one returns
+// ErrDuplicateName.
+func (am *accessTokenModel) Insert(token *AccessToken, userId int) (string, error) {
+	stmt := `INSERT INTO access_tokens (user_id, name, token_hash, scopes) VALUES ($1, $2, $3, $4)
+		RETURNING id, created;`
+
+	secret := AccessTokenPrefix + rand.Text()
+	scopes := make([]string, len(token.Scopes))
+	for i, scope := range token.Scopes {
+		scopes[i] = string(scope)
+	}
+
+	err := am.DB.QueryRow(stmt, userId, token.Name, tokenHash(secret), pq.Array(scopes)).Scan(&token.Id, &token.Created)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" && strings.Contains(pqErr.Constraint, "name") {
+			return "", ErrDuplicateName
+		}
+		return "", err
+	}
+
+	return secret, nil
+}
+
+// Delete revokes an access token of a user.
+func (am *accessTokenModel) Delete(id int, userId int) error {
+	stmt := `DELETE FROM access_tokens WHERE id = $1 AND user_id = $2;`
+
+	result, err := am.DB.Exec(stmt, id, userId)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
+
+// Authenticate returns the user and the details of a secret token and marks
+// the token as used. Unknown and revoked tokens return ErrNoRecord.
+func (am *accessTokenModel) Authenticate(token string) (int, *AccessToken, error) {
+	stmt := `UPDATE access_tokens SET last_used = now() WHERE token_hash = $1
+		RETURNING user_id, id, name, scopes, last_used, created;`
+
+	var userId int
+	var scopes pq.StringArray
+	var t AccessToken
+	err := am.DB.QueryRow(stmt, tokenHash(token)).Scan(&userId, &t.Id, &t.Name, &scopes, &t.LastUsed, &t.Created)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil, ErrNoRecord
+		}
+		return 0, nil, err
+	}
+	t.Scopes = toScopes(scopes)
+
+	return userId, &t, nil
+}
+
+func scanAccessToken(row rowScanner) (*AccessToken, error) {
+	var t AccessToken
+	var scopes pq.StringArray
+	var lastUsed sql.NullTime
+	err := row.Scan(&t.Id, &t.Name, &scopes, &lastUsed, &t.Created)
+	if err != nil {
+		return nil, err
+	}
+	t.Scopes = toScopes(scopes)
+	t.LastUsed = lastUsed.Time
+
+	return &t, nil
+}
+
+func toScopes(values []string) []Scope {
+	scopes := make([]Scope, len(values))
+	for i, value := range values {
+		scopes[i] = Scope(value)
+	}
+	return scopes
+}
+
+// tokenHash is the stored form of a secret token. The tokens are random, so
+// a fast hash suffices to make a leaked table useless.
+func tokenHash(token string) []byte {
+	hash := sha256.Sum256([]byte(token))
+	return hash[:]
+}
@@ -0,0 +1,220 @@
+package models
+
+import (
+	"crypto/sha256"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccessTokenModelGetAll(t *testing.T) {
+	created := time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC)
+	lastUsed := time.Date(2024, 2, 3, 8, 30, 0, 0, time.UTC)
+	columns := []string{"id", "name", "scopes", "last_used", "created"}
+
+	tests := []struct {
+		name           string
+		setupMock      func(mock sqlmock.Sqlmock)
+		expectedTokens []*AccessToken
+		expectedError  bool
+	}{
+		{
+			name: "tokens of the user",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT id, name, scopes, last_used, created FROM access_tokens WHERE user_id = \\$1").
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows(columns).
+						AddRow(2, "backup script", "{swims:read}", nil, created).
+						AddRow(1, "phone", "{swims:read,swims:write}", lastUsed, created))
+			},
+			expectedTokens: []*AccessToken{
+				{Id: 2, Name: "backup script", Scopes: []Scope{ScopeSwimsRead}, Created: created},
+				{Id: 1, Name: "phone", Scopes: []Scope{ScopeSwimsRead, ScopeSwimsWrite}, LastUsed: lastUsed, Created: created},
+			},
+		},
+		{
+			name: "no tokens",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT id, name, scopes, last_used, created FROM access_tokens").
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows(columns))
+			},
+		},
+		{
+			name: "database error",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT id, name, scopes, last_used, created FROM access_tokens").
+					WithArgs(1).
+					WillReturnError(errors.New("database error"))
+			},
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer func() {
+				_ = db.Close()
+			}()
+
+			tt.setupMock(mock)
+
+			tokens, err := NewAccessTokenModel(db).GetAll(1)
+
+			if tt.expectedError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedTokens, tokens)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestAccessTokenModelInsert(t *testing.T) {
+	created := time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("token created", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer func() {
+			_ = db.Close()
+		}()
+
+		hash := &capturedArg{}
+		mock.ExpectQuery("INSERT INTO access_tokens \\(user_id, name, token_hash, scopes\\) VALUES \\(\\$1, \\$2, \\$3, \\$4\\)").
+			WithArgs(1, "phone", hash, "{\"swims:read\",\"swims:write\"}").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created"}).AddRow(3, created))
+
+		token := &AccessToken{Name: "phone", Scopes: []Scope{ScopeSwimsRead, ScopeSwimsWrite}}
+		secret, err := NewAccessTokenModel(db).Insert(token, 1)
+
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(secret, AccessTokenPrefix))
+		assert.Len(t, secret, len(AccessTokenPrefix)+26)
+		expectedHash := sha256.Sum256([]byte(secret))
+		assert.Equal(t, expectedHash[:], hash.value, "only the hash of the token is stored")
+		assert.Equal(t, 3, token.Id)
+		assert.Equal(t, created, token.Created)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("duplicate name", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer func() {
+			_ = db.Close()
+		}()
+
+		mock.ExpectQuery("INSERT INTO access_tokens").
+			WillReturnError(&pq.Error{Code: "23505", Constraint: "access_tokens_user_id_name_key"})
+
+		secret, err := NewAccessTokenModel(db).Insert(&AccessToken{Name: "phone", Scopes: []Scope{ScopeSwimsRead}}, 1)
+
+		assert.ErrorIs(t, err, ErrDuplicateName)
+		assert.Empty(t, secret)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestAccessTokenModelDelete(t *testing.T) {
+	tests := []struct {
+		name          string
+		rowsAffected  int64
+		expectedError error
+	}{
+		{name: "token revoked", rowsAffected: 1},
+		{name: "token of another user", rowsAffected: 0, expectedError: ErrNoRecord},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer func() {
+				_ = db.Close()
+			}()
+
+			mock.ExpectExec("DELETE FROM access_tokens WHERE id = \\$1 AND user_id = \\$2").
+				WithArgs(3, 1).
+				WillReturnResult(sqlmock.NewResult(0, tt.rowsAffected))
+
+			err = NewAccessTokenModel(db).Delete(3, 1)
+
+			assert.ErrorIs(t, err, tt.expectedError)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestAccessTokenModelAuthenticate(t *testing.T) {
+	created := time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC)
+	lastUsed := time.Date(2024, 2, 3, 8, 30, 0, 0, time.UTC)
+	columns := []string{"user_id", "id", "name", "scopes", "last_used", "created"}
+	hash := sha256.Sum256([]byte("swm_SECRET"))
+
+	tests := []struct {
+		name           string
+		rows           *sqlmock.Rows
+		expectedUserId int
+		expectedToken  *AccessToken
+		expectedError  error
+	}{
+		{
+			name:           "known token",
+			rows:           sqlmock.NewRows(columns).AddRow(1, 3, "phone", "{swims:read}", lastUsed, created),
+			expectedUserId: 1,
+			expectedToken:  &AccessToken{Id: 3, Name: "phone", Scopes: []Scope{ScopeSwimsRead}, LastUsed: lastUsed, Created: created},
+		},
+		{
+			name:          "unknown token",
+			rows:          sqlmock.NewRows(columns),
+			expectedError: ErrNoRecord,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer func() {
+				_ = db.Close()
+			}()
+
+			mock.ExpectQuery("UPDATE access_tokens SET last_used = now\\(\\) WHERE token_hash = \\$1").
+				WithArgs(hash[:]).
+				WillReturnRows(tt.rows)
+
+			userId, token, err := NewAccessTokenModel(db).Authenticate("swm_SECRET")
+
+			assert.ErrorIs(t, err, tt.expectedError)
+			assert.Equal(t, tt.expectedUserId, userId)
+			assert.Equal(t, tt.expectedToken, token)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestAccessTokenHasScope(t *testing.T) {
+	token := &AccessToken{Scopes: []Scope{ScopeSwimsRead}}
+
+	assert.True(t, token.HasScope(ScopeSwimsRead))
+	assert.False(t, token.HasScope(ScopeSwimsWrite))
+}
+
+func TestScopeValid(t *testing.T) {
+	for _, scope := range Scopes {
+		assert.True(t, scope.Valid(), scope)
+		assert.NotEqual(t, string(scope), scope.Label(), scope)
+	}
+	assert.False(t, Scope("swims:admin").Valid())
+	assert.False(t, Scope("").Valid())
+}
@@ -0,0 +1,419 @@
+package models
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rockstaedt/swimmate/internal/totp"
+)
+
+// TwoFactorKeySize is the size of the key that encrypts the TOTP secrets,
+// which makes it AES-256.
+const TwoFactorKeySize = 32
+
+// RecoveryCodeCount is how many recovery codes a user gets at once.
+const RecoveryCodeCount = 10
+
+const (
+	// MaxTwoFactorAttempts is how many wrong codes in a row lock the
+	// two-factor authentication of a user for TwoFactorLockout.
+	MaxTwoFactorAttempts = 5
+	TwoFactorLockout     = 15 * time.Minute
+)
+
+// errNoTwoFactorKey is returned when a secret is needed but the app has no
+// key to encrypt it.
+var errNoTwoFactorKey = errors.New("models: no key to encrypt two-factor secrets is set")
+
+// TwoFactor is the two-factor authentication of a user.
+type TwoFactor struct {
+	Secret []byte
+	// Enabled is when the user confirmed the secret with a code. It is zero
+	// while the setup is not finished.
+	Enabled time.Time
+	// RecoveryCodes is the number of unused recovery codes.
+	RecoveryCodes int
+}
+
+func (tf *TwoFactor) IsEnabled() bool {
+	return !tf.Enabled.IsZero()
+}
+
+// TwoFactorModel stores the TOTP secrets and recovery codes of users. The
+// secrets are encrypted at rest, the recovery codes are hashed.
+type TwoFactorModel interface {
+	Enabled(userId int) (bool, error)
+	Get(userId int) (*TwoFactor, error)
+	Enroll(userId int) ([]byte, error)
+	Enable(userId int, code string) ([]string, error)
+	Verify(userId int, code string) (bool, error)
+	RegenerateRecoveryCodes(userId int) ([]string, error)
+	Disable(userId int) error
+}
+
+type twoFactorModel struct {
+	DB   *sql.DB
+	aead cipher.AEAD
+}
+
+// NewTwoFactorModel returns a model that encrypts the secrets with key.
+// Without a key of TwoFactorKeySize bytes, users can log in but no secret
+// can be stored or checked.
+func NewTwoFactorModel(db *sql.DB, key []byte) TwoFactorModel {
+	tm := &twoFactorModel{DB: db}
+	if len(key) == TwoFactorKeySize {
+		block, _ := aes.NewCipher(key)
+		tm.aead, _ = cipher.NewGCM(block)
+	}
+	return tm
+}
+
+// Enabled reports whether logging in needs a code. It works without the key.
+func (tm *twoFactorModel) Enabled(userId int) (bool, error) {
+	stmt := `SELECT EXISTS (SELECT 1 FROM two_factor WHERE user_id = $1 AND enabled IS NOT NULL);`
+
+	var enabled bool
+	err := tm.DB.QueryRow(stmt, userId).Scan(&enabled)
+	return enabled, err
+}
+
+// Get returns the two-factor authentication of a user with the decrypted
+// secret. Users that never started the setup return ErrNoRecord.
+func (tm *twoFactorModel) Get(userId int) (*TwoFactor, error) {
+	stmt := `SELECT secret, enabled,
+		(SELECT count(*) FROM recovery_codes WHERE user_id = $1 AND used IS NULL)
+		FROM two_factor WHERE user_id = $1;`
+
+	var tf TwoFactor
+	var sealed []byte
+	var enabled sql.NullTime
+	err := tm.DB.QueryRow(stmt, userId).Scan(&sealed, &enabled, &tf.RecoveryCodes)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		}
+		return nil, err
+	}
+
+	tf.Secret, err = tm.open(userId, sealed)
+	if err != nil {
+		return nil, err
+	}
+	tf.Enabled = enabled.Time
+
+	return &tf, nil
+}
+
+// Enroll starts the setup with a new secret and returns it. A setup that
+// was not finished is replaced, an enabled one returns ErrTwoFactorEnabled.
+func (tm *twoFactorModel) Enroll(userId int) ([]byte, error) {
+	secret := totp.NewSecret()
+	sealed, err := tm.seal(userId, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := `INSERT INTO two_factor (user_id, secret) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, last_counter = 0, created = now()
+		WHERE two_factor.enabled IS NULL;`
+
+	result, err := tm.DB.Exec(stmt, userId, sealed)
+	if err != nil {
+		return nil, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	if rowsAffected == 0 {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	return secret, nil
+}
+
+// Enable finishes the setup once the user entered a code of the new secret
+// and returns the recovery codes. Without a setup it returns ErrNoRecord, a
+// wrong code returns ErrInvalidCredentials.
+func (tm *twoFactorModel) Enable(userId int, code string) ([]string, error) {
+	tx, err := tm.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		// Rollback is a no-op once the transaction has been committed
+		_ = tx.Rollback()
+	}()
+
+	stmt := `SELECT secret FROM two_factor WHERE user_id = $1 AND enabled IS NULL FOR UPDATE;`
+
+	var sealed []byte
+	err = tx.QueryRow(stmt, userId).Scan(&sealed)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		}
+		return nil, err
+	}
+
+	secret, err := tm.open(userId, sealed)
+	if err != nil {
+		return nil, err
+	}
+
+	counter, ok := totp.Validate(secret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+
+	stmt = `UPDATE two_factor SET enabled = now(), last_counter = $2 WHERE user_id = $1;`
+	_, err = tx.Exec(stmt, userId, counter)
+	if err != nil {
+		return nil, err
+	}
+
+	codes, err := replaceRecoveryCodes(tx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// Verify checks a code of the authenticator app or a recovery code while
+// logging in and reports whether it was a recovery code. Either works only
+// once. Wrong codes return ErrInvalidCredentials, users without enabled
+// two-factor authentication ErrNoRecord. The wrong codes are counted in the
+// database, so that they add up across sessions: after MaxTwoFactorAttempts
+// in a row every code returns ErrTwoFactorLocked for TwoFactorLockout.
+func (tm *twoFactorModel) Verify(userId int, code string) (bool, error) {
+	stmt := `SELECT secret, failed_attempts, COALESCE(locked_until > now(), false)
+		FROM two_factor WHERE user_id = $1 AND enabled IS NOT NULL;`
+
+	var sealed []byte
+	var failedAttempts int
+	var locked bool
+	err := tm.DB.QueryRow(stmt, userId).Scan(&sealed, &failedAttempts, &locked)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, ErrNoRecord
+		}
+		return false, err
+	}
+
+	if locked {
+		return false, ErrTwoFactorLocked
+	}
+
+	secret, err := tm.open(userId, sealed)
+	if err != nil {
+		return false, err
+	}
+
+	recovery := false
+	if counter, ok := totp.Validate(secret, code, time.Now()); ok {
+		// The condition on last_counter lets only one of two requests with
+		// the same code through
+		stmt = `UPDATE two_factor SET last_counter = $2 WHERE user_id = $1 AND last_counter < $2;`
+		err = tm.updatedOne(stmt, userId, counter)
+	} else {
+		stmt = `UPDATE recovery_codes SET used = now() WHERE user_id = $1 AND code_hash = $2 AND used IS NULL;`
+		err = tm.updatedOne(stmt, userId, tokenHash(normalizeRecoveryCode(code)))
+		recovery = err == nil
+	}
+
+	switch {
+	case errors.Is(err, ErrInvalidCredentials):
+		return false, tm.countFailure(userId)
+	case err != nil:
+		return false, err
+	case failedAttempts > 0:
+		stmt = `UPDATE two_factor SET failed_attempts = 0, locked_until = NULL WHERE user_id = $1;`
+		_, err = tm.DB.Exec(stmt, userId)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return recovery, nil
+}
+
+// countFailure counts a wrong code and locks the two-factor authentication
+// once there were too many. It returns ErrTwoFactorLocked if this code locked
+// it and ErrInvalidCredentials otherwise.
+func (tm *twoFactorModel) countFailure(userId int) error {
+	// The values on the right are those before the update, and the count
+	// starts over with the lock
+	stmt := `UPDATE two_factor SET
+		failed_attempts = CASE WHEN failed_attempts + 1 >= $2 THEN 0 ELSE failed_attempts + 1 END,
+		locked_until = CASE WHEN failed_attempts + 1 >= $2 THEN now() + make_interval(secs => $3) ELSE locked_until END
+		WHERE user_id = $1 RETURNING locked_until IS NOT NULL AND locked_until > now();`
+
+	var locked bool
+	err := tm.DB.QueryRow(stmt, userId, MaxTwoFactorAttempts, TwoFactorLockout.Seconds()).Scan(&locked)
+	if err != nil {
+		return err
+	}
+
+	if locked {
+		return ErrTwoFactorLocked
+	}
+	return ErrInvalidCredentials
+}
+
+// updatedOne runs an update and returns ErrInvalidCredentials unless it
+// changed a row.
+func (tm *twoFactorModel) updatedOne(stmt string, args ...any) error {
+	result, err := tm.DB.Exec(stmt, args...)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrInvalidCredentials
+	}
+
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes of a user with new
+// ones. Users without enabled two-factor authentication return ErrNoRecord.
+func (tm *twoFactorModel) RegenerateRecoveryCodes(userId int) ([]string, error) {
+	tx, err := tm.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		// Rollback is a no-op once the transaction has been committed
+		_ = tx.Rollback()
+	}()
+
+	stmt := `SELECT 1 FROM two_factor WHERE user_id = $1 AND enabled IS NOT NULL FOR UPDATE;`
+
+	var found int
+	err = tx.QueryRow(stmt, userId).Scan(&found)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		}
+		return nil, err
+	}
+
+	codes, err := replaceRecoveryCodes(tx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// Disable removes the secret and the recovery codes of a user.
+func (tm *twoFactorModel) Disable(userId int) error {
+	tx, err := tm.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// Rollback is a no-op once the transaction has been committed
+		_ = tx.Rollback()
+	}()
+
+	result, err := tx.Exec(`DELETE FROM two_factor WHERE user_id = $1;`, userId)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNoRecord
+	}
+
+	_, err = tx.Exec(`DELETE FROM recovery_codes WHERE user_id = $1;`, userId)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// replaceRecoveryCodes deletes the recovery codes of a user and stores
+// RecoveryCodeCount new ones, which it returns as XXXX-XXXX-XXXX-XXXX.
+func replaceRecoveryCodes(tx *sql.Tx, userId int) ([]string, error) {
+	_, err := tx.Exec(`DELETE FROM recovery_codes WHERE user_id = $1;`, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := `INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2);`
+
+	codes := make([]string, RecoveryCodeCount)
+	for i := range codes {
+		code := rand.Text()[:16]
+		_, err = tx.Exec(stmt, userId, tokenHash(code))
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = code[:4] + "-" + code[4:8] + "-" + code[8:12] + "-" + code[12:]
+	}
+
+	return codes, nil
+}
+
+// normalizeRecoveryCode accepts recovery codes typed in lower case or
+// without dashes.
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToUpper(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// seal encrypts a secret of a user. The user id is authenticated with it, so
+// that a secret copied to another row does not decrypt.
+func (tm *twoFactorModel) seal(userId int, secret []byte) ([]byte, error) {
+	if tm.aead == nil {
+		return nil, errNoTwoFactorKey
+	}
+
+	nonce := make([]byte, tm.aead.NonceSize())
+	_, _ = rand.Read(nonce)
+
+	return tm.aead.Seal(nonce, nonce, secret, []byte(strconv.Itoa(userId))), nil
+}
+
+func (tm *twoFactorModel) open(userId int, sealed []byte) ([]byte, error) {
+	if tm.aead == nil {
+		return nil, errNoTwoFactorKey
+	}
+	if len(sealed) < tm.aead.NonceSize() {
+		return nil, errors.New("models: two-factor secret is too short")
+	}
+
+	nonce, ciphertext := sealed[:tm.aead.NonceSize()], sealed[tm.aead.NonceSize():]
+	return tm.aead.Open(nil, nonce, ciphertext, []byte(strconv.Itoa(userId)))
+}
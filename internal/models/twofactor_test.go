@@ -0,0 +1,387 @@
+package models
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/rockstaedt/swimmate/internal/totp"
+	"github.com/stretchr/testify/assert"
+)
+
+var testTwoFactorKey = bytes.Repeat([]byte{7}, TwoFactorKeySize)
+
+// sealedSecret is a secret as the model stores it for a user.
+func sealedSecret(t *testing.T, userId int, secret []byte) []byte {
+	t.Helper()
+
+	sealed, err := NewTwoFactorModel(nil, testTwoFactorKey).(*twoFactorModel).seal(userId, secret)
+	assert.NoError(t, err)
+
+	return sealed
+}
+
+func currentCode(secret []byte) string {
+	return totp.Code(secret, totp.Counter(time.Now()))
+}
+
+func TestTwoFactorModelSealAndOpen(t *testing.T) {
+	tm := NewTwoFactorModel(nil, testTwoFactorKey).(*twoFactorModel)
+	secret := totp.NewSecret()
+
+	sealed, err := tm.seal(1, secret)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(sealed), string(secret), "the secret is encrypted")
+
+	opened, err := tm.open(1, sealed)
+	assert.NoError(t, err)
+	assert.Equal(t, secret, opened)
+
+	_, err = tm.open(2, sealed)
+	assert.Error(t, err, "the secret of one user does not open for another")
+
+	other := NewTwoFactorModel(nil, bytes.Repeat([]byte{8}, TwoFactorKeySize)).(*twoFactorModel)
+	_, err = other.open(1, sealed)
+	assert.Error(t, err, "another key does not open the secret")
+
+	withoutKey := NewTwoFactorModel(nil, nil).(*twoFactorModel)
+	_, err = withoutKey.seal(1, secret)
+	assert.ErrorIs(t, err, errNoTwoFactorKey)
+	_, err = withoutKey.open(1, sealed)
+	assert.ErrorIs(t, err, errNoTwoFactorKey)
+}
+
+func TestTwoFactorModelEnabled(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer func() {
+		_ = db.Close()
+	}()
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT EXISTS (SELECT 1 FROM two_factor WHERE user_id = $1 AND enabled IS NOT NULL)")).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	enabled, err := NewTwoFactorModel(db, nil).Enabled(1)
+
+	assert.NoError(t, err)
+	assert.True(t, enabled, "no key is needed")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTwoFactorModelGet(t *testing.T) {
+	secret := totp.NewSecret()
+	enabled := time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		rows        *sqlmock.Rows
+		expected    *TwoFactor
+		expectedErr error
+	}{
+		{
+			name:     "enabled",
+			rows:     sqlmock.NewRows([]string{"secret", "enabled", "count"}).AddRow(sealedSecret(t, 1, secret), enabled, 8),
+			expected: &TwoFactor{Secret: secret, Enabled: enabled, RecoveryCodes: 8},
+		},
+		{
+			name:     "setup not finished",
+			rows:     sqlmock.NewRows([]string{"secret", "enabled", "count"}).AddRow(sealedSecret(t, 1, secret), nil, 0),
+			expected: &TwoFactor{Secret: secret},
+		},
+		{
+			name:        "no setup",
+			rows:        sqlmock.NewRows([]string{"secret", "enabled", "count"}),
+			expectedErr: ErrNoRecord,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer func() {
+				_ = db.Close()
+			}()
+
+			mock.ExpectQuery("SELECT secret, enabled,\\s+\\(SELECT count\\(\\*\\) FROM recovery_codes WHERE user_id = \\$1 AND used IS NULL\\)\\s+FROM two_factor WHERE user_id = \\$1").
+				WithArgs(1).
+				WillReturnRows(tt.rows)
+
+			tf, err := NewTwoFactorModel(db, testTwoFactorKey).Get(1)
+
+			assert.ErrorIs(t, err, tt.expectedErr)
+			assert.Equal(t, tt.expected, tf)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestTwoFactorModelEnroll(t *testing.T) {
+	tests := []struct {
+		name         string
+		rowsAffected int64
+		expectedErr  error
+	}{
+		{name: "new setup", rowsAffected: 1},
+		{name: "already enabled", rowsAffected: 0, expectedErr: ErrTwoFactorEnabled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer func() {
+				_ = db.Close()
+			}()
+
+			sealed := &capturedArg{}
+			mock.ExpectExec("INSERT INTO two_factor \\(user_id, secret\\) VALUES \\(\\$1, \\$2\\)\\s+ON CONFLICT \\(user_id\\) DO UPDATE SET secret = EXCLUDED.secret, last_counter = 0, created = now\\(\\)\\s+WHERE two_factor.enabled IS NULL").
+				WithArgs(1, sealed).
+				WillReturnResult(sqlmock.NewResult(0, tt.rowsAffected))
+
+			tm := NewTwoFactorModel(db, testTwoFactorKey)
+			secret, err := tm.Enroll(1)
+
+			assert.ErrorIs(t, err, tt.expectedErr)
+			if tt.expectedErr == nil {
+				assert.Len(t, secret, totp.SecretSize)
+				opened, err := tm.(*twoFactorModel).open(1, sealed.value.([]byte))
+				assert.NoError(t, err)
+				assert.Equal(t, secret, opened, "the secret is stored encrypted")
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestTwoFactorModelEnable(t *testing.T) {
+	secret := totp.NewSecret()
+
+	tests := []struct {
+		name        string
+		code        string
+		setupMock   func(mock sqlmock.Sqlmock)
+		expectedErr error
+	}{
+		{
+			name: "right code",
+			code: currentCode(secret),
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("UPDATE two_factor SET enabled = now\\(\\), last_counter = \\$2 WHERE user_id = \\$1").
+					WithArgs(1, sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("DELETE FROM recovery_codes WHERE user_id = \\$1").
+					WithArgs(1).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				for range RecoveryCodeCount {
+					mock.ExpectExec("INSERT INTO recovery_codes \\(user_id, code_hash\\) VALUES \\(\\$1, \\$2\\)").
+						WithArgs(1, sqlmock.AnyArg()).
+						WillReturnResult(sqlmock.NewResult(1, 1))
+				}
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "wrong code",
+			code: "abcdef",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectRollback()
+			},
+			expectedErr: ErrInvalidCredentials,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer func() {
+				_ = db.Close()
+			}()
+
+			mock.ExpectBegin()
+			mock.ExpectQuery("SELECT secret FROM two_factor WHERE user_id = \\$1 AND enabled IS NULL FOR UPDATE").
+				WithArgs(1).
+				WillReturnRows(sqlmock.NewRows([]string{"secret"}).AddRow(sealedSecret(t, 1, secret)))
+			tt.setupMock(mock)
+
+			codes, err := NewTwoFactorModel(db, testTwoFactorKey).Enable(1, tt.code)
+
+			assert.ErrorIs(t, err, tt.expectedErr)
+			if tt.expectedErr == nil {
+				assert.Len(t, codes, RecoveryCodeCount)
+				assert.Regexp(t, "^[A-Z2-7]{4}-[A-Z2-7]{4}-[A-Z2-7]{4}-[A-Z2-7]{4}$", codes[0])
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestTwoFactorModelVerify(t *testing.T) {
+	secret := totp.NewSecret()
+	totpUpdate := "UPDATE two_factor SET last_counter = \\$2 WHERE user_id = \\$1 AND last_counter < \\$2"
+	recoveryUpdate := "UPDATE recovery_codes SET used = now\\(\\) WHERE user_id = \\$1 AND code_hash = \\$2 AND used IS NULL"
+	failureUpdate := "UPDATE two_factor SET failed_attempts = .* RETURNING locked_until IS NOT NULL AND locked_until > now\\(\\)"
+	resetUpdate := "UPDATE two_factor SET failed_attempts = 0, locked_until = NULL WHERE user_id = \\$1"
+
+	tests := []struct {
+		name             string
+		code             string
+		failedAttempts   int
+		locked           bool
+		setupMock        func(mock sqlmock.Sqlmock)
+		expectedRecovery bool
+		expectedErr      error
+	}{
+		{
+			name: "code of the app",
+			code: currentCode(secret),
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(totpUpdate).WithArgs(1, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "code of the app used before",
+			code: currentCode(secret),
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(totpUpdate).WithArgs(1, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(failureUpdate).
+					WithArgs(1, MaxTwoFactorAttempts, TwoFactorLockout.Seconds()).
+					WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(false))
+			},
+			expectedErr: ErrInvalidCredentials,
+		},
+		{
+			name: "recovery code",
+			code: "abcd-efgh ijkl-mnop",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(recoveryUpdate).WithArgs(1, tokenHash("ABCDEFGHIJKLMNOP")).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			expectedRecovery: true,
+		},
+		{
+			name: "wrong or used recovery code",
+			code: "ABCD-EFGH-IJKL-MNOP",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(recoveryUpdate).WithArgs(1, tokenHash("ABCDEFGHIJKLMNOP")).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(failureUpdate).
+					WithArgs(1, MaxTwoFactorAttempts, TwoFactorLockout.Seconds()).
+					WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(false))
+			},
+			expectedErr: ErrInvalidCredentials,
+		},
+		{
+			name:           "wrong code that locks",
+			code:           "ABCD-EFGH-IJKL-MNOP",
+			failedAttempts: MaxTwoFactorAttempts - 1,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(recoveryUpdate).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(failureUpdate).
+					WithArgs(1, MaxTwoFactorAttempts, TwoFactorLockout.Seconds()).
+					WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(true))
+			},
+			expectedErr: ErrTwoFactorLocked,
+		},
+		{
+			name:        "right code while locked",
+			code:        currentCode(secret),
+			locked:      true,
+			setupMock:   func(mock sqlmock.Sqlmock) {},
+			expectedErr: ErrTwoFactorLocked,
+		},
+		{
+			name:           "right code after wrong ones",
+			code:           currentCode(secret),
+			failedAttempts: 2,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(totpUpdate).WithArgs(1, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(resetUpdate).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer func() {
+				_ = db.Close()
+			}()
+
+			mock.ExpectQuery("SELECT secret, failed_attempts, COALESCE\\(locked_until > now\\(\\), false\\)\\s+FROM two_factor WHERE user_id = \\$1 AND enabled IS NOT NULL").
+				WithArgs(1).
+				WillReturnRows(sqlmock.NewRows([]string{"secret", "failed_attempts", "locked"}).AddRow(sealedSecret(t, 1, secret), tt.failedAttempts, tt.locked))
+			tt.setupMock(mock)
+
+			recovery, err := NewTwoFactorModel(db, testTwoFactorKey).Verify(1, tt.code)
+
+			assert.ErrorIs(t, err, tt.expectedErr)
+			assert.Equal(t, tt.expectedRecovery, recovery)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestTwoFactorModelVerifyWithoutSetup(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer func() {
+		_ = db.Close()
+	}()
+
+	mock.ExpectQuery("SELECT secret, failed_attempts, .* FROM two_factor").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"secret", "failed_attempts", "locked"}))
+
+	_, err = NewTwoFactorModel(db, testTwoFactorKey).Verify(1, "123456")
+
+	assert.ErrorIs(t, err, ErrNoRecord)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTwoFactorModelDisable(t *testing.T) {
+	tests := []struct {
+		name         string
+		rowsAffected int64
+		expectedErr  error
+	}{
+		{name: "disabled", rowsAffected: 1},
+		{name: "no setup", rowsAffected: 0, expectedErr: ErrNoRecord},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer func() {
+				_ = db.Close()
+			}()
+
+			mock.ExpectBegin()
+			mock.ExpectExec("DELETE FROM two_factor WHERE user_id = \\$1").
+				WithArgs(1).
+				WillReturnResult(sqlmock.NewResult(0, tt.rowsAffected))
+			if tt.expectedErr == nil {
+				mock.ExpectExec("DELETE FROM recovery_codes WHERE user_id = \\$1").
+					WithArgs(1).
+					WillReturnResult(sqlmock.NewResult(0, 10))
+				mock.ExpectCommit()
+			} else {
+				mock.ExpectRollback()
+			}
+
+			err = NewTwoFactorModel(db, nil).Disable(1)
+
+			assert.ErrorIs(t, err, tt.expectedErr)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestNormalizeRecoveryCode(t *testing.T) {
+	assert.Equal(t, "ABCDEFGHIJKLMNOP", normalizeRecoveryCode("abcd-efgh-ijkl-mnop"))
+	assert.Equal(t, "ABCDEFGHIJKLMNOP", normalizeRecoveryCode(" ABCD EFGH IJKL MNOP "))
+}
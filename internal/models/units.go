@@ -0,0 +1,121 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Unit is a unit of length used for distances and pool lengths.
+type Unit string
+
+const (
+	UnitMeters Unit = "m"
+	UnitYards  Unit = "yd"
+)
+
+const metersPerYard = 0.9144
+
+func (u Unit) Valid() bool {
+	return u == UnitMeters || u == UnitYards
+}
+
+// Units lists all units in display order.
+var Units = []Unit{UnitMeters, UnitYards}
+
+func (u Unit) Label() string {
+	switch u {
+	case UnitMeters:
+		return "Meters"
+	case UnitYards:
+		return "Yards"
+	}
+	return ""
+}
+
+// ToMeters converts a length given in this unit to whole meters.
+func (u Unit) ToMeters(length float64) int {
+	if u == UnitYards {
+		length *= metersPerYard
+	}
+	return int(math.Round(length))
+}
+
+// FromMeters converts a length in meters to whole units of this unit.
+func (u Unit) FromMeters(meters int) int {
+	if u == UnitYards {
+		return int(math.Round(float64(meters) / metersPerYard))
+	}
+	return meters
+}
+
+// Pace converts a pace per 100 m into the pace per 100 of this unit.
+func (u Unit) Pace(pacePer100m time.Duration) time.Duration {
+	if u == UnitYards {
+		return time.Duration(float64(pacePer100m) * metersPerYard).Round(time.Second)
+	}
+	return pacePer100m
+}
+
+// Clock formats a duration as "m:ss", or "h:mm:ss" from one hour on, the way
+// durations and paces are shown everywhere. Zero durations format as an
+// empty string.
+func Clock(d time.Duration) string {
+	if d <= 0 {
+		return ""
+	}
+
+	total := int(d.Round(time.Second) / time.Second)
+	hours, minutes, seconds := total/3600, (total%3600)/60, total%60
+	if hours > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", hours, minutes, seconds)
+	}
+	return fmt.Sprintf("%d:%02d", minutes, seconds)
+}
+
+// Pool describes the length of the pool a swim took place in.
+type Pool struct {
+	Length int
+	Unit   Unit
+}
+
+// Pools lists the pool lengths that can be selected for a swim.
+var Pools = []Pool{
+	{Length: 25, Unit: UnitMeters},
+	{Length: 50, Unit: UnitMeters},
+	{Length: 25, Unit: UnitYards},
+}
+
+// ParsePool parses a pool in its String form, e.g. "25m" or "25yd".
+func ParsePool(value string) (Pool, error) {
+	for _, pool := range Pools {
+		if pool.String() == value {
+			return pool, nil
+		}
+	}
+	return Pool{}, fmt.Errorf("models: unknown pool %q", value)
+}
+
+func (p Pool) IsZero() bool {
+	return p.Length == 0
+}
+
+func (p Pool) String() string {
+	if p.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf("%d%s", p.Length, p.Unit)
+}
+
+func (p Pool) Label() string {
+	if p.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf("%d %s", p.Length, p.Unit)
+}
+
+// DistanceM returns the distance in meters covered by swimming the given
+// number of laps in this pool.
+func (p Pool) DistanceM(laps int) int {
+	return p.Unit.ToMeters(float64(laps * p.Length))
+}
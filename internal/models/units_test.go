@@ -0,0 +1,135 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnitToMeters(t *testing.T) {
+	tests := []struct {
+		name     string
+		unit     Unit
+		length   float64
+		expected int
+	}{
+		{"meters unchanged", UnitMeters, 1500, 1500},
+		{"meters rounded", UnitMeters, 1499.6, 1500},
+		{"yards converted", UnitYards, 100, 91},
+		{"yards rounded", UnitYards, 2000, 1829},
+		{"zero", UnitYards, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.unit.ToMeters(tt.length))
+		})
+	}
+}
+
+func TestUnitFromMeters(t *testing.T) {
+	tests := []struct {
+		name     string
+		unit     Unit
+		meters   int
+		expected int
+	}{
+		{"meters unchanged", UnitMeters, 1500, 1500},
+		{"yards converted", UnitYards, 1500, 1640},
+		{"round trip", UnitYards, UnitYards.ToMeters(1000), 1000},
+		{"zero", UnitYards, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.unit.FromMeters(tt.meters))
+		})
+	}
+}
+
+func TestUnitPace(t *testing.T) {
+	assert.Equal(t, 2*time.Minute, UnitMeters.Pace(2*time.Minute))
+	assert.Equal(t, 110*time.Second, UnitYards.Pace(2*time.Minute))
+	assert.Equal(t, time.Duration(0), UnitYards.Pace(0))
+}
+
+func TestClock(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    time.Duration
+		expected string
+	}{
+		{"zero", 0, ""},
+		{"negative", -time.Minute, ""},
+		{"seconds only", 45 * time.Second, "0:45"},
+		{"minutes and seconds", 2*time.Minute + 5*time.Second, "2:05"},
+		{"rounds to seconds", 99*time.Second + 600*time.Millisecond, "1:40"},
+		{"one hour", time.Hour, "1:00:00"},
+		{"hours, minutes and seconds", time.Hour + 2*time.Minute + 3*time.Second, "1:02:03"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Clock(tt.input))
+		})
+	}
+}
+
+func TestUnitLabel(t *testing.T) {
+	assert.Equal(t, "Meters", UnitMeters.Label())
+	assert.Equal(t, "Yards", UnitYards.Label())
+	assert.True(t, UnitMeters.Valid())
+	assert.False(t, Unit("km").Valid())
+}
+
+func TestParsePool(t *testing.T) {
+	tests := []struct {
+		value       string
+		expected    Pool
+		expectError bool
+	}{
+		{value: "25m", expected: Pool{Length: 25, Unit: UnitMeters}},
+		{value: "50m", expected: Pool{Length: 50, Unit: UnitMeters}},
+		{value: "25yd", expected: Pool{Length: 25, Unit: UnitYards}},
+		{value: "33m", expectError: true},
+		{value: "", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			pool, err := ParsePool(tt.value)
+
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, pool)
+		})
+	}
+}
+
+func TestPool(t *testing.T) {
+	t.Run("zero value", func(t *testing.T) {
+		pool := Pool{}
+		assert.True(t, pool.IsZero())
+		assert.Equal(t, "", pool.String())
+		assert.Equal(t, "", pool.Label())
+	})
+
+	t.Run("meter pool", func(t *testing.T) {
+		pool := Pool{Length: 50, Unit: UnitMeters}
+		assert.False(t, pool.IsZero())
+		assert.Equal(t, "50m", pool.String())
+		assert.Equal(t, "50 m", pool.Label())
+		assert.Equal(t, 1500, pool.DistanceM(30))
+	})
+
+	t.Run("yard pool", func(t *testing.T) {
+		pool := Pool{Length: 25, Unit: UnitYards}
+		assert.Equal(t, "25yd", pool.String())
+		assert.Equal(t, "25 yd", pool.Label())
+		assert.Equal(t, 1829, pool.DistanceM(80))
+	})
+}
@@ -3,8 +3,19 @@ package models
 import (
 	"database/sql"
 	"errors"
-	"golang.org/x/crypto/bcrypt"
+	"strings"
 	"time"
+
+	"github.com/lib/pq"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MaxUsernameLength, MaxNameLength and MaxEmailLength match the columns of
+// the users table.
+const (
+	MaxUsernameLength = 150
+	MaxNameLength     = 150
+	MaxEmailLength    = 254
 )
 
 type User struct {
@@ -16,10 +27,19 @@ type User struct {
 	Password   []byte
 	DateJoined time.Time
 	LastLogin  time.Time
+	// DistanceUnit is the unit distances are displayed and entered in.
+	DistanceUnit Unit
 }
 
 type UserModel interface {
 	Authenticate(username, password string) (int, error)
+	Get(id int) (*User, error)
+	GetByEmail(email string) (*User, error)
+	Insert(user *User, password string) error
+	UpdateProfile(user *User) error
+	ChangePassword(id int, currentPassword, newPassword string) error
+	SetPassword(id int, password string) error
+	UpdateDistanceUnit(id int, unit Unit) error
 }
 
 type userModel struct {
@@ -62,3 +82,175 @@ func (um userModel) Authenticate(username, password string) (int, error) {
 
 	return id, nil
 }
+
+func (um userModel) Get(id int) (*User, error) {
+	stmt := `SELECT id, first_name, last_name, username, email, date_joined, last_login, distance_unit FROM users WHERE id = $1`
+
+	return um.get(stmt, id)
+}
+
+// GetByEmail returns the account of an email address, regardless of its
+// case.
+func (um userModel) GetByEmail(email string) (*User, error) {
+	stmt := `SELECT id, first_name, last_name, username, email, date_joined, last_login, distance_unit FROM users
+		WHERE lower(email) = lower($1) AND email <> ''`
+
+	return um.get(stmt, email)
+}
+
+func (um userModel) get(stmt string, arg any) (*User, error) {
+	var u User
+	var lastLogin sql.NullTime
+
+	err := um.DB.QueryRow(stmt, arg).Scan(
+		&u.ID, &u.FirstName, &u.LastName, &u.Username, &u.Email, &u.DateJoined, &lastLogin, &u.DistanceUnit,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		}
+		return nil, err
+	}
+
+	u.LastLogin = lastLogin.Time
+
+	return &u, nil
+}
+
+// Insert creates the account of user with a bcrypt hash of password and sets
+// its ID, password hash and join date. Usernames and email addresses are
+// unique regardless of case, reusing one returns ErrDuplicateUsername or
+// ErrDuplicateEmail.
+func (um userModel) Insert(user *User, password string) error {
+	stmt := `INSERT INTO users (password, username, first_name, last_name, email, date_joined, distance_unit)
+		VALUES ($1, $2, $3, $4, $5, now(), $6) RETURNING id, date_joined;`
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	unit := user.DistanceUnit
+	if !unit.Valid() {
+		unit = UnitMeters
+	}
+
+	err = um.DB.QueryRow(stmt, string(hashedPassword), user.Username, user.FirstName, user.LastName, user.Email, unit).
+		Scan(&user.ID, &user.DateJoined)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			switch {
+			case strings.Contains(pqErr.Constraint, "username"):
+				return ErrDuplicateUsername
+			case strings.Contains(pqErr.Constraint, "email"):
+				return ErrDuplicateEmail
+			}
+		}
+		return err
+	}
+
+	user.Password = hashedPassword
+	user.DistanceUnit = unit
+
+	return nil
+}
+
+// UpdateProfile saves the names and email address of user. Reusing the
+// email address of another account returns ErrDuplicateEmail.
+func (um userModel) UpdateProfile(user *User) error {
+	stmt := `UPDATE users SET first_name = $1, last_name = $2, email = $3 WHERE id = $4`
+
+	result, err := um.DB.Exec(stmt, user.FirstName, user.LastName, user.Email, user.ID)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" && strings.Contains(pqErr.Constraint, "email") {
+			return ErrDuplicateEmail
+		}
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
+
+// ChangePassword replaces the password of a user, who has to know the
+// current one. A wrong current password returns ErrInvalidCredentials.
+func (um userModel) ChangePassword(id int, currentPassword, newPassword string) error {
+	var hashedPassword []byte
+
+	stmt := `SELECT password FROM users WHERE id = $1`
+
+	err := um.DB.QueryRow(stmt, id).Scan(&hashedPassword)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNoRecord
+		}
+		return err
+	}
+
+	err = bcrypt.CompareHashAndPassword(hashedPassword, []byte(currentPassword))
+	if err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return ErrInvalidCredentials
+		}
+		return err
+	}
+
+	return um.SetPassword(id, newPassword)
+}
+
+// SetPassword replaces the password of a user without asking for the
+// current one, for instance after the user proved to own the email address.
+func (um userModel) SetPassword(id int, password string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	stmt := `UPDATE users SET password = $1 WHERE id = $2`
+
+	result, err := um.DB.Exec(stmt, string(hashedPassword), id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
+
+func (um userModel) UpdateDistanceUnit(id int, unit Unit) error {
+	stmt := `UPDATE users SET distance_unit = $1 WHERE id = $2`
+
+	result, err := um.DB.Exec(stmt, unit, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
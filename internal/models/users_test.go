@@ -4,8 +4,10 @@ import (
 	"database/sql"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -232,3 +234,386 @@ func TestUserModelAuthenticateLastLoginUpdate(t *testing.T) {
 	assert.Equal(t, 1, id)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
+
+func TestUserModelGet(t *testing.T) {
+	joined := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	lastLogin := time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC)
+	query := "SELECT id, first_name, last_name, username, email, date_joined, last_login, distance_unit FROM users WHERE id = \\$1"
+	columns := []string{"id", "first_name", "last_name", "username", "email", "date_joined", "last_login", "distance_unit"}
+
+	tests := []struct {
+		name         string
+		setupMock    func(mock sqlmock.Sqlmock)
+		expectedUser *User
+		expectedErr  error
+	}{
+		{
+			name: "existing user",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows(columns).
+					AddRow(1, "Jane", "Doe", "jane", "jane@example.com", joined, lastLogin, "yd")
+				mock.ExpectQuery(query).WithArgs(1).WillReturnRows(rows)
+			},
+			expectedUser: &User{
+				ID:           1,
+				FirstName:    "Jane",
+				LastName:     "Doe",
+				Username:     "jane",
+				Email:        "jane@example.com",
+				DateJoined:   joined,
+				LastLogin:    lastLogin,
+				DistanceUnit: UnitYards,
+			},
+		},
+		{
+			name: "never logged in",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows(columns).
+					AddRow(1, "Jane", "Doe", "jane", "jane@example.com", joined, nil, "m")
+				mock.ExpectQuery(query).WithArgs(1).WillReturnRows(rows)
+			},
+			expectedUser: &User{
+				ID:           1,
+				FirstName:    "Jane",
+				LastName:     "Doe",
+				Username:     "jane",
+				Email:        "jane@example.com",
+				DateJoined:   joined,
+				DistanceUnit: UnitMeters,
+			},
+		},
+		{
+			name: "no record",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(1).WillReturnError(sql.ErrNoRows)
+			},
+			expectedErr: ErrNoRecord,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer func() {
+				_ = db.Close()
+			}()
+
+			tt.setupMock(mock)
+
+			model := NewUserModel(db)
+			user, err := model.Get(1)
+
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				assert.Nil(t, user)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedUser, user)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestUserModelGetByEmail(t *testing.T) {
+	joined := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	query := "SELECT id, first_name, last_name, username, email, date_joined, last_login, distance_unit FROM users\\s+WHERE lower\\(email\\) = lower\\(\\$1\\) AND email <> ''"
+
+	t.Run("existing user", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer func() {
+			_ = db.Close()
+		}()
+
+		rows := sqlmock.NewRows([]string{"id", "first_name", "last_name", "username", "email", "date_joined", "last_login", "distance_unit"}).
+			AddRow(1, "Jane", "Doe", "jane", "jane@example.com", joined, nil, "m")
+		mock.ExpectQuery(query).WithArgs("Jane@Example.com").WillReturnRows(rows)
+
+		user, err := NewUserModel(db).GetByEmail("Jane@Example.com")
+
+		assert.NoError(t, err)
+		assert.Equal(t, &User{ID: 1, FirstName: "Jane", LastName: "Doe", Username: "jane", Email: "jane@example.com", DateJoined: joined, DistanceUnit: UnitMeters}, user)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("no record", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer func() {
+			_ = db.Close()
+		}()
+
+		mock.ExpectQuery(query).WithArgs("john@example.com").WillReturnError(sql.ErrNoRows)
+
+		user, err := NewUserModel(db).GetByEmail("john@example.com")
+
+		assert.ErrorIs(t, err, ErrNoRecord)
+		assert.Nil(t, user)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestUserModelUpdateDistanceUnit(t *testing.T) {
+	tests := []struct {
+		name        string
+		setupMock   func(mock sqlmock.Sqlmock)
+		expectedErr error
+	}{
+		{
+			name: "successful update",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("UPDATE users SET distance_unit = \\$1 WHERE id = \\$2").
+					WithArgs(UnitYards, 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "no record",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("UPDATE users SET distance_unit = \\$1 WHERE id = \\$2").
+					WithArgs(UnitYards, 1).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			expectedErr: ErrNoRecord,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer func() {
+				_ = db.Close()
+			}()
+
+			tt.setupMock(mock)
+
+			model := NewUserModel(db)
+			err = model.UpdateDistanceUnit(1, UnitYards)
+
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestUserModelInsert(t *testing.T) {
+	joined := time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC)
+	query := "INSERT INTO users \\(password, username, first_name, last_name, email, date_joined, distance_unit\\) " +
+		"VALUES \\(\\$1, \\$2, \\$3, \\$4, \\$5, now\\(\\), \\$6\\) RETURNING id, date_joined"
+
+	t.Run("account created", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer func() {
+			_ = db.Close()
+		}()
+
+		hash := &capturedArg{}
+		mock.ExpectQuery(query).
+			WithArgs(hash, "jane", "Jane", "Doe", "jane@example.com", UnitMeters).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "date_joined"}).AddRow(7, joined))
+
+		user := &User{Username: "jane", FirstName: "Jane", LastName: "Doe", Email: "jane@example.com"}
+		err = NewUserModel(db).Insert(user, "correct horse battery")
+
+		assert.NoError(t, err)
+		assert.Equal(t, 7, user.ID)
+		assert.Equal(t, joined, user.DateJoined)
+		assert.Equal(t, UnitMeters, user.DistanceUnit, "new accounts use meters unless told otherwise")
+		assert.Equal(t, string(user.Password), hash.value)
+		assert.NoError(t, bcrypt.CompareHashAndPassword(user.Password, []byte("correct horse battery")))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	tests := []struct {
+		name        string
+		constraint  string
+		expectedErr error
+	}{
+		{name: "username taken", constraint: "users_username_lower_key", expectedErr: ErrDuplicateUsername},
+		{name: "email taken", constraint: "users_email_lower_key", expectedErr: ErrDuplicateEmail},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer func() {
+				_ = db.Close()
+			}()
+
+			mock.ExpectQuery("INSERT INTO users").
+				WillReturnError(&pq.Error{Code: "23505", Constraint: tt.constraint})
+
+			user := &User{Username: "jane", Email: "jane@example.com", DistanceUnit: UnitYards}
+			err = NewUserModel(db).Insert(user, "correct horse battery")
+
+			assert.ErrorIs(t, err, tt.expectedErr)
+			assert.Zero(t, user.ID)
+			assert.Empty(t, user.Password)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestUserModelUpdateProfile(t *testing.T) {
+	query := "UPDATE users SET first_name = \\$1, last_name = \\$2, email = \\$3 WHERE id = \\$4"
+
+	tests := []struct {
+		name        string
+		setupMock   func(mock sqlmock.Sqlmock)
+		expectedErr error
+	}{
+		{
+			name: "profile saved",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).
+					WithArgs("Jane", "Smith", "jane@example.org", 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "email taken",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).
+					WillReturnError(&pq.Error{Code: "23505", Constraint: "users_email_lower_key"})
+			},
+			expectedErr: ErrDuplicateEmail,
+		},
+		{
+			name: "no record",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			expectedErr: ErrNoRecord,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer func() {
+				_ = db.Close()
+			}()
+
+			tt.setupMock(mock)
+
+			err = NewUserModel(db).UpdateProfile(&User{ID: 1, FirstName: "Jane", LastName: "Smith", Email: "jane@example.org"})
+
+			assert.ErrorIs(t, err, tt.expectedErr)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestUserModelChangePassword(t *testing.T) {
+	currentHash, err := bcrypt.GenerateFromPassword([]byte("old password"), bcrypt.DefaultCost)
+	if err != nil {
+		t.Fatalf("Failed to generate test password hash: %v", err)
+	}
+
+	tests := []struct {
+		name            string
+		currentPassword string
+		setupMock       func(mock sqlmock.Sqlmock, newHash *capturedArg)
+		expectedErr     error
+	}{
+		{
+			name:            "password changed",
+			currentPassword: "old password",
+			setupMock: func(mock sqlmock.Sqlmock, newHash *capturedArg) {
+				mock.ExpectQuery("SELECT password FROM users WHERE id = \\$1").
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows([]string{"password"}).AddRow(currentHash))
+				mock.ExpectExec("UPDATE users SET password = \\$1 WHERE id = \\$2").
+					WithArgs(newHash, 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name:            "wrong current password",
+			currentPassword: "guess",
+			setupMock: func(mock sqlmock.Sqlmock, newHash *capturedArg) {
+				mock.ExpectQuery("SELECT password FROM users WHERE id = \\$1").
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows([]string{"password"}).AddRow(currentHash))
+			},
+			expectedErr: ErrInvalidCredentials,
+		},
+		{
+			name:            "no record",
+			currentPassword: "old password",
+			setupMock: func(mock sqlmock.Sqlmock, newHash *capturedArg) {
+				mock.ExpectQuery("SELECT password FROM users WHERE id = \\$1").
+					WithArgs(1).
+					WillReturnError(sql.ErrNoRows)
+			},
+			expectedErr: ErrNoRecord,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer func() {
+				_ = db.Close()
+			}()
+
+			newHash := &capturedArg{}
+			tt.setupMock(mock, newHash)
+
+			err = NewUserModel(db).ChangePassword(1, tt.currentPassword, "new password")
+
+			assert.ErrorIs(t, err, tt.expectedErr)
+			if tt.expectedErr == nil {
+				hash, _ := newHash.value.(string)
+				assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(hash), []byte("new password")))
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestUserModelSetPassword(t *testing.T) {
+	tests := []struct {
+		name         string
+		rowsAffected int64
+		expectedErr  error
+	}{
+		{name: "password set", rowsAffected: 1},
+		{name: "no record", rowsAffected: 0, expectedErr: ErrNoRecord},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer func() {
+				_ = db.Close()
+			}()
+
+			newHash := &capturedArg{}
+			mock.ExpectExec("UPDATE users SET password = \\$1 WHERE id = \\$2").
+				WithArgs(newHash, 1).
+				WillReturnResult(sqlmock.NewResult(0, tt.rowsAffected))
+
+			err = NewUserModel(db).SetPassword(1, "new password")
+
+			assert.ErrorIs(t, err, tt.expectedErr)
+			hash, _ := newHash.value.(string)
+			assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(hash), []byte("new password")))
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,306 @@
+package models
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// MaxWebhookURLLength is the maximum length of a webhook URL, matching the
+// webhooks.url column.
+const MaxWebhookURLLength = 2000
+
+// WebhookEvent is the change of a swim a webhook is called for.
+type WebhookEvent string
+
+const (
+	EventSwimCreated WebhookEvent = "swim.created"
+	EventSwimUpdated WebhookEvent = "swim.updated"
+	EventSwimDeleted WebhookEvent = "swim.deleted"
+)
+
+// DeliveryState is the progress of a webhook delivery.
+type DeliveryState string
+
+const (
+	// DeliveryPending deliveries are sent, or sent again, once their next
+	// attempt is due.
+	DeliveryPending   DeliveryState = "pending"
+	DeliveryDelivered DeliveryState = "delivered"
+	// DeliveryFailed deliveries ran out of attempts. They are only sent
+	// again when they are redelivered.
+	DeliveryFailed DeliveryState = "failed"
+)
+
+// Webhook is a URL that is called when a swim of its user changes.
+type Webhook struct {
+	Id  int
+	URL string
+	// Secret signs the payloads, so that the receiver can check they come
+	// from SwimMate. It is only set when the webhook is created.
+	Secret  string
+	Created time.Time
+}
+
+// WebhookDelivery is a call of a webhook with the payload of an event.
+type WebhookDelivery struct {
+	Id        int
+	WebhookId int
+	URL       string
+	// Secret is only set for deliveries that are due, which need it to be
+	// signed.
+	Secret   string
+	Event    WebhookEvent
+	Payload  []byte
+	State    DeliveryState
+	Attempts int
+	// StatusCode is the status of the latest response, zero if there was
+	// none.
+	StatusCode  int
+	Error       string
+	NextAttempt time.Time
+	Created     time.Time
+	Updated     time.Time
+}
+
+type WebhookModel interface {
+	GetAll(userId int) ([]*Webhook, error)
+	Insert(hook *Webhook, userId int) error
+	Delete(id int, userId int) error
+	Enqueue(userId int, event WebhookEvent, payload []byte) (int, error)
+	GetDeliveries(userId int, limit int) ([]*WebhookDelivery, error)
+	Redeliver(id int, userId int) error
+	Due(limit int, lease time.Duration) ([]*WebhookDelivery, error)
+	Record(delivery *WebhookDelivery) error
+}
+
+type webhookModel struct {
+	DB *sql.DB
+}
+
+func NewWebhookModel(db *sql.DB) WebhookModel {
+	return &webhookModel{DB: db}
+}
+
+// GetAll returns the webhooks of a user, the oldest first.
+func (wm *webhookModel) GetAll(userId int) ([]*Webhook, error) {
+	stmt := `SELECT id, url, created FROM webhooks WHERE user_id = $1 ORDER BY created, id;`
+
+	rows, err := wm.DB.Query(stmt, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err := rows.Close(); err != nil {
+			// Ignore Close error to avoid overriding return error
+			_ = err
+		}
+	}()
+
+	var hooks []*Webhook
+	for rows.Next() {
+		var hook Webhook
+		err = rows.Scan(&hook.Id, &hook.URL, &hook.Created)
+		if err != nil {
+			return nil, err
+		}
+
+		hooks = append(hooks, &hook)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return hooks, nil
+}
+
+// Insert creates a webhook for the URL of hook and sets its id, secret and
+// creation time. A URL can be added once per user, adding it again returns
+// ErrDuplicateURL.
+func (wm *webhookModel) Insert(hook *Webhook, userId int) error {
+	stmt := `INSERT INTO webhooks (user_id, url, secret) VALUES ($1, $2, $3) RETURNING id, created;`
+
+	secret := rand.Text()
+	err := wm.DB.QueryRow(stmt, userId, hook.URL, secret).Scan(&hook.Id, &hook.Created)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" && strings.Contains(pqErr.Constraint, "url") {
+			return ErrDuplicateURL
+		}
+		return err
+	}
+	hook.Secret = secret
+
+	return nil
+}
+
+// Delete removes a webhook of a user together with its deliveries.
+func (wm *webhookModel) Delete(id int, userId int) error {
+	stmt := `DELETE FROM webhooks WHERE id = $1 AND user_id = $2;`
+
+	result, err := wm.DB.Exec(stmt, id, userId)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
+
+// Enqueue creates a pending delivery of the JSON payload for each webhook of
+// a user and returns how many there are.
+func (wm *webhookModel) Enqueue(userId int, event WebhookEvent, payload []byte) (int, error) {
+	stmt := `INSERT INTO webhook_deliveries (webhook_id, event, payload)
+		SELECT id, $2, $3::jsonb FROM webhooks WHERE user_id = $1;`
+
+	// jsonb is sent as text, a []byte would be sent as bytea
+	result, err := wm.DB.Exec(stmt, userId, string(event), string(payload))
+	if err != nil {
+		return 0, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(rowsAffected), nil
+}
+
+// GetDeliveries returns the latest deliveries of the webhooks of a user, the
+// newest first.
+func (wm *webhookModel) GetDeliveries(userId int, limit int) ([]*WebhookDelivery, error) {
+	stmt := `SELECT d.id, d.webhook_id, w.url, d.event, d.payload, d.state, d.attempts, d.status_code, d.error,
+		d.next_attempt, d.created, d.updated
+		FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id
+		WHERE w.user_id = $1 ORDER BY d.created DESC, d.id DESC LIMIT $2;`
+
+	rows, err := wm.DB.Query(stmt, userId, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err := rows.Close(); err != nil {
+			// Ignore Close error to avoid overriding return error
+			_ = err
+		}
+	}()
+
+	var deliveries []*WebhookDelivery
+	for rows.Next() {
+		var d WebhookDelivery
+		var statusCode sql.NullInt64
+		err = rows.Scan(&d.Id, &d.WebhookId, &d.URL, &d.Event, &d.Payload, &d.State, &d.Attempts, &statusCode,
+			&d.Error, &d.NextAttempt, &d.Created, &d.Updated)
+		if err != nil {
+			return nil, err
+		}
+		d.StatusCode = int(statusCode.Int64)
+
+		deliveries = append(deliveries, &d)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// Redeliver sends the payload of a delivery of a user again, as a new
+// delivery that is due right away.
+func (wm *webhookModel) Redeliver(id int, userId int) error {
+	stmt := `INSERT INTO webhook_deliveries (webhook_id, event, payload)
+		SELECT d.webhook_id, d.event, d.payload FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id
+		WHERE d.id = $1 AND w.user_id = $2;`
+
+	result, err := wm.DB.Exec(stmt, id, userId)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
+
+// Due claims up to limit pending deliveries whose next attempt has passed.
+// Their next attempt is moved by the lease, so that no one else sends them
+// while they are sent; Record sets it once they were.
+func (wm *webhookModel) Due(limit int, lease time.Duration) ([]*WebhookDelivery, error) {
+	stmt := `UPDATE webhook_deliveries d SET next_attempt = now() + make_interval(secs => $2)
+		FROM webhooks w
+		WHERE w.id = d.webhook_id AND d.id IN (
+			SELECT id FROM webhook_deliveries WHERE state = 'pending' AND next_attempt <= now()
+			ORDER BY next_attempt, id LIMIT $1 FOR UPDATE SKIP LOCKED)
+		RETURNING d.id, d.webhook_id, w.url, w.secret, d.event, d.payload, d.attempts, d.created;`
+
+	rows, err := wm.DB.Query(stmt, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err := rows.Close(); err != nil {
+			// Ignore Close error to avoid overriding return error
+			_ = err
+		}
+	}()
+
+	var deliveries []*WebhookDelivery
+	for rows.Next() {
+		d := WebhookDelivery{State: DeliveryPending}
+		err = rows.Scan(&d.Id, &d.WebhookId, &d.URL, &d.Secret, &d.Event, &d.Payload, &d.Attempts, &d.Created)
+		if err != nil {
+			return nil, err
+		}
+
+		deliveries = append(deliveries, &d)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// Record stores the outcome of an attempt to send a delivery: its state,
+// attempts, status code, error and, unless it is zero, next attempt.
+func (wm *webhookModel) Record(delivery *WebhookDelivery) error {
+	stmt := `UPDATE webhook_deliveries SET state = $2, attempts = $3, status_code = $4, error = $5,
+		next_attempt = COALESCE($6, next_attempt), updated = now() WHERE id = $1;`
+
+	_, err := wm.DB.Exec(
+		stmt,
+		delivery.Id,
+		string(delivery.State),
+		delivery.Attempts,
+		nullableInt(delivery.StatusCode),
+		delivery.Error,
+		sql.NullTime{Time: delivery.NextAttempt, Valid: !delivery.NextAttempt.IsZero()},
+	)
+	return err
+}
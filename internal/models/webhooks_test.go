@@ -0,0 +1,279 @@
+package models
+
+import (
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookModelGetAll(t *testing.T) {
+	created := time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC)
+
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer func() {
+		_ = db.Close()
+	}()
+
+	mock.ExpectQuery("SELECT id, url, created FROM webhooks WHERE user_id = \\$1").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "url", "created"}).
+			AddRow(1, "https://example.com/hook", created).
+			AddRow(2, "https://example.org/swims", created))
+
+	hooks, err := NewWebhookModel(db).GetAll(1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []*Webhook{
+		{Id: 1, URL: "https://example.com/hook", Created: created},
+		{Id: 2, URL: "https://example.org/swims", Created: created},
+	}, hooks, "the secrets are not read again")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWebhookModelInsert(t *testing.T) {
+	created := time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("webhook created", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer func() {
+			_ = db.Close()
+		}()
+
+		secret := &capturedArg{}
+		mock.ExpectQuery("INSERT INTO webhooks \\(user_id, url, secret\\) VALUES \\(\\$1, \\$2, \\$3\\)").
+			WithArgs(1, "https://example.com/hook", secret).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created"}).AddRow(3, created))
+
+		hook := &Webhook{URL: "https://example.com/hook"}
+		err = NewWebhookModel(db).Insert(hook, 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 3, hook.Id)
+		assert.Equal(t, created, hook.Created)
+		assert.Len(t, hook.Secret, 26)
+		assert.Equal(t, hook.Secret, secret.value)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("duplicate url", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer func() {
+			_ = db.Close()
+		}()
+
+		mock.ExpectQuery("INSERT INTO webhooks").
+			WillReturnError(&pq.Error{Code: "23505", Constraint: "webhooks_user_id_url_key"})
+
+		hook := &Webhook{URL: "https://example.com/hook"}
+		err = NewWebhookModel(db).Insert(hook, 1)
+
+		assert.ErrorIs(t, err, ErrDuplicateURL)
+		assert.Empty(t, hook.Secret)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestWebhookModelDelete(t *testing.T) {
+	tests := []struct {
+		name          string
+		rowsAffected  int64
+		expectedError error
+	}{
+		{name: "webhook removed", rowsAffected: 1},
+		{name: "webhook of another user", rowsAffected: 0, expectedError: ErrNoRecord},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer func() {
+				_ = db.Close()
+			}()
+
+			mock.ExpectExec("DELETE FROM webhooks WHERE id = \\$1 AND user_id = \\$2").
+				WithArgs(3, 1).
+				WillReturnResult(sqlmock.NewResult(0, tt.rowsAffected))
+
+			err = NewWebhookModel(db).Delete(3, 1)
+
+			assert.ErrorIs(t, err, tt.expectedError)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestWebhookModelEnqueue(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer func() {
+		_ = db.Close()
+	}()
+
+	mock.ExpectExec("INSERT INTO webhook_deliveries \\(webhook_id, event, payload\\)\\s+SELECT id, \\$2, \\$3::jsonb FROM webhooks WHERE user_id = \\$1").
+		WithArgs(1, "swim.created", `{"event":"swim.created"}`).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	queued, err := NewWebhookModel(db).Enqueue(1, EventSwimCreated, []byte(`{"event":"swim.created"}`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, queued, "one delivery for each webhook of the user")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWebhookModelGetDeliveries(t *testing.T) {
+	created := time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC)
+	next := created.Add(time.Minute)
+	columns := []string{"id", "webhook_id", "url", "event", "payload", "state", "attempts", "status_code", "error",
+		"next_attempt", "created", "updated"}
+
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer func() {
+		_ = db.Close()
+	}()
+
+	mock.ExpectQuery("SELECT d.id, d.webhook_id, w.url, d.event, d.payload, d.state, .+ WHERE w.user_id = \\$1 .+ LIMIT \\$2").
+		WithArgs(1, 50).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(5, 1, "https://example.com/hook", "swim.deleted", []byte(`{}`), "pending", 1, nil, "connection refused", next, created, created).
+			AddRow(4, 1, "https://example.com/hook", "swim.created", []byte(`{}`), "delivered", 1, 204, "", created, created, created))
+
+	deliveries, err := NewWebhookModel(db).GetDeliveries(1, 50)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []*WebhookDelivery{
+		{Id: 5, WebhookId: 1, URL: "https://example.com/hook", Event: EventSwimDeleted, Payload: []byte(`{}`), State: DeliveryPending,
+			Attempts: 1, Error: "connection refused", NextAttempt: next, Created: created, Updated: created},
+		{Id: 4, WebhookId: 1, URL: "https://example.com/hook", Event: EventSwimCreated, Payload: []byte(`{}`), State: DeliveryDelivered,
+			Attempts: 1, StatusCode: 204, NextAttempt: created, Created: created, Updated: created},
+	}, deliveries)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWebhookModelRedeliver(t *testing.T) {
+	tests := []struct {
+		name          string
+		rowsAffected  int64
+		expectedError error
+	}{
+		{name: "delivery queued again", rowsAffected: 1},
+		{name: "delivery of another user", rowsAffected: 0, expectedError: ErrNoRecord},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer func() {
+				_ = db.Close()
+			}()
+
+			mock.ExpectExec("INSERT INTO webhook_deliveries .+ WHERE d.id = \\$1 AND w.user_id = \\$2").
+				WithArgs(7, 1).
+				WillReturnResult(sqlmock.NewResult(8, tt.rowsAffected))
+
+			err = NewWebhookModel(db).Redeliver(7, 1)
+
+			assert.ErrorIs(t, err, tt.expectedError)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestWebhookModelDue(t *testing.T) {
+	created := time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("deliveries claimed", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer func() {
+			_ = db.Close()
+		}()
+
+		mock.ExpectQuery("UPDATE webhook_deliveries d SET next_attempt = now\\(\\) \\+ make_interval\\(secs => \\$2\\) .+ FOR UPDATE SKIP LOCKED").
+			WithArgs(10, float64(300)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "webhook_id", "url", "secret", "event", "payload", "attempts", "created"}).
+				AddRow(5, 1, "https://example.com/hook", "SECRET", "swim.created", []byte(`{}`), 2, created))
+
+		deliveries, err := NewWebhookModel(db).Due(10, 5*time.Minute)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []*WebhookDelivery{
+			{Id: 5, WebhookId: 1, URL: "https://example.com/hook", Secret: "SECRET", Event: EventSwimCreated, Payload: []byte(`{}`),
+				State: DeliveryPending, Attempts: 2, Created: created},
+		}, deliveries)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer func() {
+			_ = db.Close()
+		}()
+
+		mock.ExpectQuery("UPDATE webhook_deliveries").WillReturnError(errors.New("database error"))
+
+		deliveries, err := NewWebhookModel(db).Due(10, 5*time.Minute)
+
+		assert.Error(t, err)
+		assert.Nil(t, deliveries)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestWebhookModelRecord(t *testing.T) {
+	next := time.Date(2024, 2, 1, 12, 0, 30, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		delivery     *WebhookDelivery
+		expectedArgs []driver.Value
+	}{
+		{
+			name:         "retry",
+			delivery:     &WebhookDelivery{Id: 5, State: DeliveryPending, Attempts: 1, StatusCode: 500, Error: "unexpected status 500", NextAttempt: next},
+			expectedArgs: []driver.Value{5, "pending", 1, 500, "unexpected status 500", next},
+		},
+		{
+			name:         "delivered",
+			delivery:     &WebhookDelivery{Id: 5, State: DeliveryDelivered, Attempts: 2, StatusCode: 204},
+			expectedArgs: []driver.Value{5, "delivered", 2, 204, "", nil},
+		},
+		{
+			name:         "no response",
+			delivery:     &WebhookDelivery{Id: 5, State: DeliveryFailed, Attempts: 8, Error: "connection refused"},
+			expectedArgs: []driver.Value{5, "failed", 8, nil, "connection refused", nil},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer func() {
+				_ = db.Close()
+			}()
+
+			args := make([]driver.Value, len(tt.expectedArgs))
+			copy(args, tt.expectedArgs)
+			mock.ExpectExec("UPDATE webhook_deliveries SET state = \\$2, attempts = \\$3, status_code = \\$4, error = \\$5,\\s+next_attempt = COALESCE\\(\\$6, next_attempt\\)").
+				WithArgs(args...).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			err = NewWebhookModel(db).Record(tt.delivery)
+
+			assert.NoError(t, err)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
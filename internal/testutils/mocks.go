@@ -3,6 +3,7 @@ package testutils
 import (
 	"time"
 
+	"github.com/rockstaedt/swimmate/internal/mailer"
 	"github.com/rockstaedt/swimmate/internal/models"
 )
 
@@ -11,11 +12,14 @@ type MockSwimModel struct {
 	GetFunc          func() (*models.Swim, error)
 	GetByIDFunc      func(userId int, swimId int) (*models.Swim, error)
 	GetAllFunc       func(userId int) ([]*models.Swim, error)
-	GetPaginatedFunc func(userId int, limit int, offset int, sort string, direction string) ([]*models.Swim, error)
-	InsertFunc       func(date time.Time, distanceM int, assessment int, userId int) error
-	UpdateFunc       func(id int, userId int, date time.Time, distanceM int, assessment int) error
+	GetPaginatedFunc func(userId int, limit int, offset int, sort string, direction string, filter models.SwimFilter) ([]*models.Swim, error)
+	GetAfterFunc     func(userId int, limit int, after *models.SwimKey, sort string, direction string, filter models.SwimFilter) ([]*models.Swim, error)
+	ExportFunc       func(userId int, sort string, direction string, filter models.SwimFilter, each func(*models.Swim) error) error
+	InsertFunc       func(swim *models.Swim, userId int) error
+	InsertManyFunc   func(swims []*models.Swim, userId int) error
+	UpdateFunc       func(swim *models.Swim, userId int) error
 	DeleteFunc       func(id int, userId int) error
-	SummarizeFunc    func(userId int) *models.SwimSummary
+	SummarizeFunc    func(userId int, filter models.SwimFilter) *models.SwimSummary
 }
 
 func (m *MockSwimModel) Get() (*models.Swim, error) {
@@ -39,23 +43,44 @@ func (m *MockSwimModel) GetAll(userId int) ([]*models.Swim, error) {
 	return []*models.Swim{}, nil
 }
 
-func (m *MockSwimModel) GetPaginated(userId int, limit int, offset int, sort string, direction string) ([]*models.Swim, error) {
+func (m *MockSwimModel) GetPaginated(userId int, limit int, offset int, sort string, direction string, filter models.SwimFilter) ([]*models.Swim, error) {
 	if m.GetPaginatedFunc != nil {
-		return m.GetPaginatedFunc(userId, limit, offset, sort, direction)
+		return m.GetPaginatedFunc(userId, limit, offset, sort, direction, filter)
 	}
 	return []*models.Swim{}, nil
 }
 
-func (m *MockSwimModel) Insert(date time.Time, distanceM int, assessment int, userId int) error {
+func (m *MockSwimModel) GetAfter(userId int, limit int, after *models.SwimKey, sort string, direction string, filter models.SwimFilter) ([]*models.Swim, error) {
+	if m.GetAfterFunc != nil {
+		return m.GetAfterFunc(userId, limit, after, sort, direction, filter)
+	}
+	return []*models.Swim{}, nil
+}
+
+func (m *MockSwimModel) Export(userId int, sort string, direction string, filter models.SwimFilter, each func(*models.Swim) error) error {
+	if m.ExportFunc != nil {
+		return m.ExportFunc(userId, sort, direction, filter, each)
+	}
+	return nil
+}
+
+func (m *MockSwimModel) Insert(swim *models.Swim, userId int) error {
 	if m.InsertFunc != nil {
-		return m.InsertFunc(date, distanceM, assessment, userId)
+		return m.InsertFunc(swim, userId)
 	}
 	return nil
 }
 
-func (m *MockSwimModel) Update(id int, userId int, date time.Time, distanceM int, assessment int) error {
+func (m *MockSwimModel) InsertMany(swims []*models.Swim, userId int) error {
+	if m.InsertManyFunc != nil {
+		return m.InsertManyFunc(swims, userId)
+	}
+	return nil
+}
+
+func (m *MockSwimModel) Update(swim *models.Swim, userId int) error {
 	if m.UpdateFunc != nil {
-		return m.UpdateFunc(id, userId, date, distanceM, assessment)
+		return m.UpdateFunc(swim, userId)
 	}
 	return nil
 }
@@ -67,9 +92,9 @@ func (m *MockSwimModel) Delete(id int, userId int) error {
 	return nil
 }
 
-func (m *MockSwimModel) Summarize(userId int) *models.SwimSummary {
+func (m *MockSwimModel) Summarize(userId int, filter models.SwimFilter) *models.SwimSummary {
 	if m.SummarizeFunc != nil {
-		return m.SummarizeFunc(userId)
+		return m.SummarizeFunc(userId, filter)
 	}
 	return &models.SwimSummary{
 		YearMap: make(map[int]models.YearMap),
@@ -78,7 +103,14 @@ func (m *MockSwimModel) Summarize(userId int) *models.SwimSummary {
 
 // MockUserModel is a mock implementation of models.UserModel for testing
 type MockUserModel struct {
-	AuthenticateFunc func(username, password string) (int, error)
+	AuthenticateFunc       func(username, password string) (int, error)
+	GetFunc                func(id int) (*models.User, error)
+	GetByEmailFunc         func(email string) (*models.User, error)
+	InsertFunc             func(user *models.User, password string) error
+	UpdateProfileFunc      func(user *models.User) error
+	ChangePasswordFunc     func(id int, currentPassword, newPassword string) error
+	SetPasswordFunc        func(id int, password string) error
+	UpdateDistanceUnitFunc func(id int, unit models.Unit) error
 }
 
 func (m *MockUserModel) Authenticate(username, password string) (int, error) {
@@ -87,3 +119,455 @@ func (m *MockUserModel) Authenticate(username, password string) (int, error) {
 	}
 	return 0, models.ErrInvalidCredentials
 }
+
+func (m *MockUserModel) Get(id int) (*models.User, error) {
+	if m.GetFunc != nil {
+		return m.GetFunc(id)
+	}
+	return &models.User{ID: id, DistanceUnit: models.UnitMeters}, nil
+}
+
+func (m *MockUserModel) GetByEmail(email string) (*models.User, error) {
+	if m.GetByEmailFunc != nil {
+		return m.GetByEmailFunc(email)
+	}
+	return nil, models.ErrNoRecord
+}
+
+func (m *MockUserModel) Insert(user *models.User, password string) error {
+	if m.InsertFunc != nil {
+		return m.InsertFunc(user, password)
+	}
+	user.ID = 1
+	if !user.DistanceUnit.Valid() {
+		user.DistanceUnit = models.UnitMeters
+	}
+	return nil
+}
+
+func (m *MockUserModel) UpdateProfile(user *models.User) error {
+	if m.UpdateProfileFunc != nil {
+		return m.UpdateProfileFunc(user)
+	}
+	return nil
+}
+
+func (m *MockUserModel) ChangePassword(id int, currentPassword, newPassword string) error {
+	if m.ChangePasswordFunc != nil {
+		return m.ChangePasswordFunc(id, currentPassword, newPassword)
+	}
+	return nil
+}
+
+func (m *MockUserModel) SetPassword(id int, password string) error {
+	if m.SetPasswordFunc != nil {
+		return m.SetPasswordFunc(id, password)
+	}
+	return nil
+}
+
+func (m *MockUserModel) UpdateDistanceUnit(id int, unit models.Unit) error {
+	if m.UpdateDistanceUnitFunc != nil {
+		return m.UpdateDistanceUnitFunc(id, unit)
+	}
+	return nil
+}
+
+// MockTagModel is a mock implementation of models.TagModel for testing
+type MockTagModel struct {
+	GetAllFunc func(userId int) ([]string, error)
+}
+
+func (m *MockTagModel) GetAll(userId int) ([]string, error) {
+	if m.GetAllFunc != nil {
+		return m.GetAllFunc(userId)
+	}
+	return []string{}, nil
+}
+
+// MockLocationModel is a mock implementation of models.LocationModel for testing
+type MockLocationModel struct {
+	GetAllFunc     func(userId int) ([]*models.Location, error)
+	GetByIDFunc    func(userId int, locationId int) (*models.Location, error)
+	InsertFunc     func(location *models.Location, userId int) error
+	LastUsedIDFunc func(userId int) (int, error)
+	StatsFunc      func(userId int) ([]*models.LocationStats, error)
+}
+
+func (m *MockLocationModel) GetAll(userId int) ([]*models.Location, error) {
+	if m.GetAllFunc != nil {
+		return m.GetAllFunc(userId)
+	}
+	return []*models.Location{}, nil
+}
+
+func (m *MockLocationModel) GetByID(userId int, locationId int) (*models.Location, error) {
+	if m.GetByIDFunc != nil {
+		return m.GetByIDFunc(userId, locationId)
+	}
+	return &models.Location{Id: locationId}, nil
+}
+
+func (m *MockLocationModel) Insert(location *models.Location, userId int) error {
+	if m.InsertFunc != nil {
+		return m.InsertFunc(location, userId)
+	}
+	return nil
+}
+
+func (m *MockLocationModel) LastUsedID(userId int) (int, error) {
+	if m.LastUsedIDFunc != nil {
+		return m.LastUsedIDFunc(userId)
+	}
+	return 0, nil
+}
+
+func (m *MockLocationModel) Stats(userId int) ([]*models.LocationStats, error) {
+	if m.StatsFunc != nil {
+		return m.StatsFunc(userId)
+	}
+	return []*models.LocationStats{}, nil
+}
+
+// MockBackupModel is a mock implementation of models.BackupModel for testing
+type MockBackupModel struct {
+	GetFunc     func(userId int) (*models.Backup, error)
+	RestoreFunc func(userId int, backup *models.Backup, mode models.RestoreMode) (*models.Restored, error)
+}
+
+func (m *MockBackupModel) Get(userId int) (*models.Backup, error) {
+	if m.GetFunc != nil {
+		return m.GetFunc(userId)
+	}
+	return &models.Backup{User: models.User{ID: userId, DistanceUnit: models.UnitMeters}}, nil
+}
+
+func (m *MockBackupModel) Restore(userId int, backup *models.Backup, mode models.RestoreMode) (*models.Restored, error) {
+	if m.RestoreFunc != nil {
+		return m.RestoreFunc(userId, backup, mode)
+	}
+	return &models.Restored{Swims: backup.Swims}, nil
+}
+
+// MockImportJobModel is a mock implementation of models.ImportJobModel for testing
+type MockImportJobModel struct {
+	InsertFunc       func(job *models.ImportJob, userId int) error
+	GetFunc          func(id int, userId int) (*models.ImportJob, error)
+	GetRunningFunc   func(userId int) (*models.ImportJob, error)
+	CountRunningFunc func(userId int) (int, error)
+	UpdateFunc       func(job *models.ImportJob, userId int) error
+	ExpireFunc       func(before time.Time) ([]string, error)
+}
+
+func (m *MockImportJobModel) Insert(job *models.ImportJob, userId int) error {
+	if m.InsertFunc != nil {
+		return m.InsertFunc(job, userId)
+	}
+	job.Id = 1
+	return nil
+}
+
+func (m *MockImportJobModel) Get(id int, userId int) (*models.ImportJob, error) {
+	if m.GetFunc != nil {
+		return m.GetFunc(id, userId)
+	}
+	return &models.ImportJob{Id: id, Done: true}, nil
+}
+
+func (m *MockImportJobModel) GetRunning(userId int) (*models.ImportJob, error) {
+	if m.GetRunningFunc != nil {
+		return m.GetRunningFunc(userId)
+	}
+	return nil, models.ErrNoRecord
+}
+
+func (m *MockImportJobModel) CountRunning(userId int) (int, error) {
+	if m.CountRunningFunc != nil {
+		return m.CountRunningFunc(userId)
+	}
+	return 0, nil
+}
+
+func (m *MockImportJobModel) Update(job *models.ImportJob, userId int) error {
+	if m.UpdateFunc != nil {
+		return m.UpdateFunc(job, userId)
+	}
+	return nil
+}
+
+func (m *MockImportJobModel) Expire(before time.Time) ([]string, error) {
+	if m.ExpireFunc != nil {
+		return m.ExpireFunc(before)
+	}
+	return nil, nil
+}
+
+// MockCalendarModel is a mock implementation of models.CalendarModel for testing
+type MockCalendarModel struct {
+	GetFunc    func(userId int) (*models.CalendarFeed, error)
+	ResetFunc  func(userId int) (string, error)
+	DeleteFunc func(userId int) error
+	UserIDFunc func(token string) (int, error)
+}
+
+func (m *MockCalendarModel) Get(userId int) (*models.CalendarFeed, error) {
+	if m.GetFunc != nil {
+		return m.GetFunc(userId)
+	}
+	return nil, models.ErrNoRecord
+}
+
+func (m *MockCalendarModel) Reset(userId int) (string, error) {
+	if m.ResetFunc != nil {
+		return m.ResetFunc(userId)
+	}
+	return "TESTTOKEN", nil
+}
+
+func (m *MockCalendarModel) Delete(userId int) error {
+	if m.DeleteFunc != nil {
+		return m.DeleteFunc(userId)
+	}
+	return nil
+}
+
+func (m *MockCalendarModel) UserID(token string) (int, error) {
+	if m.UserIDFunc != nil {
+		return m.UserIDFunc(token)
+	}
+	return 0, models.ErrNoRecord
+}
+
+// MockInviteModel is a mock implementation of models.InviteModel for testing
+type MockInviteModel struct {
+	InsertFunc  func(userId int) (string, error)
+	RedeemFunc  func(code string) (int, error)
+	ReleaseFunc func(id int) error
+}
+
+func (m *MockInviteModel) Insert(userId int) (string, error) {
+	if m.InsertFunc != nil {
+		return m.InsertFunc(userId)
+	}
+	return "TESTINVITE", nil
+}
+
+func (m *MockInviteModel) Redeem(code string) (int, error) {
+	if m.RedeemFunc != nil {
+		return m.RedeemFunc(code)
+	}
+	return 0, models.ErrNoRecord
+}
+
+func (m *MockInviteModel) Release(id int) error {
+	if m.ReleaseFunc != nil {
+		return m.ReleaseFunc(id)
+	}
+	return nil
+}
+
+// MockPasswordResetModel is a mock implementation of models.PasswordResetModel for testing
+type MockPasswordResetModel struct {
+	InsertFunc  func(userId int) (string, error)
+	LookupFunc  func(token string) (int, error)
+	ConsumeFunc func(token string) (int, error)
+}
+
+func (m *MockPasswordResetModel) Insert(userId int) (string, error) {
+	if m.InsertFunc != nil {
+		return m.InsertFunc(userId)
+	}
+	return "TESTRESET", nil
+}
+
+func (m *MockPasswordResetModel) Lookup(token string) (int, error) {
+	if m.LookupFunc != nil {
+		return m.LookupFunc(token)
+	}
+	return 0, models.ErrNoRecord
+}
+
+func (m *MockPasswordResetModel) Consume(token string) (int, error) {
+	if m.ConsumeFunc != nil {
+		return m.ConsumeFunc(token)
+	}
+	return 0, models.ErrNoRecord
+}
+
+// MockTwoFactorModel is a mock implementation of models.TwoFactorModel for testing
+type MockTwoFactorModel struct {
+	EnabledFunc                 func(userId int) (bool, error)
+	GetFunc                     func(userId int) (*models.TwoFactor, error)
+	EnrollFunc                  func(userId int) ([]byte, error)
+	EnableFunc                  func(userId int, code string) ([]string, error)
+	VerifyFunc                  func(userId int, code string) (bool, error)
+	RegenerateRecoveryCodesFunc func(userId int) ([]string, error)
+	DisableFunc                 func(userId int) error
+}
+
+func (m *MockTwoFactorModel) Enabled(userId int) (bool, error) {
+	if m.EnabledFunc != nil {
+		return m.EnabledFunc(userId)
+	}
+	return false, nil
+}
+
+func (m *MockTwoFactorModel) Get(userId int) (*models.TwoFactor, error) {
+	if m.GetFunc != nil {
+		return m.GetFunc(userId)
+	}
+	return nil, models.ErrNoRecord
+}
+
+func (m *MockTwoFactorModel) Enroll(userId int) ([]byte, error) {
+	if m.EnrollFunc != nil {
+		return m.EnrollFunc(userId)
+	}
+	return []byte("12345678901234567890"), nil
+}
+
+func (m *MockTwoFactorModel) Enable(userId int, code string) ([]string, error) {
+	if m.EnableFunc != nil {
+		return m.EnableFunc(userId, code)
+	}
+	return []string{"AAAA-BBBB-CCCC-DDDD"}, nil
+}
+
+func (m *MockTwoFactorModel) Verify(userId int, code string) (bool, error) {
+	if m.VerifyFunc != nil {
+		return m.VerifyFunc(userId, code)
+	}
+	return false, models.ErrInvalidCredentials
+}
+
+func (m *MockTwoFactorModel) RegenerateRecoveryCodes(userId int) ([]string, error) {
+	if m.RegenerateRecoveryCodesFunc != nil {
+		return m.RegenerateRecoveryCodesFunc(userId)
+	}
+	return []string{"AAAA-BBBB-CCCC-DDDD"}, nil
+}
+
+func (m *MockTwoFactorModel) Disable(userId int) error {
+	if m.DisableFunc != nil {
+		return m.DisableFunc(userId)
+	}
+	return nil
+}
+
+// MockAccessTokenModel is a mock implementation of models.AccessTokenModel for testing
+type MockAccessTokenModel struct {
+	GetAllFunc       func(userId int) ([]*models.AccessToken, error)
+	InsertFunc       func(token *models.AccessToken, userId int) (string, error)
+	DeleteFunc       func(id int, userId int) error
+	AuthenticateFunc func(token string) (int, *models.AccessToken, error)
+}
+
+func (m *MockAccessTokenModel) GetAll(userId int) ([]*models.AccessToken, error) {
+	if m.GetAllFunc != nil {
+		return m.GetAllFunc(userId)
+	}
+	return nil, nil
+}
+
+func (m *MockAccessTokenModel) Insert(token *models.AccessToken, userId int) (string, error) {
+	if m.InsertFunc != nil {
+		return m.InsertFunc(token, userId)
+	}
+	return "swm_TESTTOKEN", nil
+}
+
+func (m *MockAccessTokenModel) Delete(id int, userId int) error {
+	if m.DeleteFunc != nil {
+		return m.DeleteFunc(id, userId)
+	}
+	return nil
+}
+
+func (m *MockAccessTokenModel) Authenticate(token string) (int, *models.AccessToken, error) {
+	if m.AuthenticateFunc != nil {
+		return m.AuthenticateFunc(token)
+	}
+	return 0, nil, models.ErrNoRecord
+}
+
+// MockWebhookModel is a mock implementation of models.WebhookModel for testing
+type MockWebhookModel struct {
+	GetAllFunc        func(userId int) ([]*models.Webhook, error)
+	InsertFunc        func(hook *models.Webhook, userId int) error
+	DeleteFunc        func(id int, userId int) error
+	EnqueueFunc       func(userId int, event models.WebhookEvent, payload []byte) (int, error)
+	GetDeliveriesFunc func(userId int, limit int) ([]*models.WebhookDelivery, error)
+	RedeliverFunc     func(id int, userId int) error
+	DueFunc           func(limit int, lease time.Duration) ([]*models.WebhookDelivery, error)
+	RecordFunc        func(delivery *models.WebhookDelivery) error
+}
+
+func (m *MockWebhookModel) GetAll(userId int) ([]*models.Webhook, error) {
+	if m.GetAllFunc != nil {
+		return m.GetAllFunc(userId)
+	}
+	return nil, nil
+}
+
+func (m *MockWebhookModel) Insert(hook *models.Webhook, userId int) error {
+	if m.InsertFunc != nil {
+		return m.InsertFunc(hook, userId)
+	}
+	hook.Id = 1
+	hook.Secret = "TESTSECRET"
+	return nil
+}
+
+func (m *MockWebhookModel) Delete(id int, userId int) error {
+	if m.DeleteFunc != nil {
+		return m.DeleteFunc(id, userId)
+	}
+	return nil
+}
+
+func (m *MockWebhookModel) Enqueue(userId int, event models.WebhookEvent, payload []byte) (int, error) {
+	if m.EnqueueFunc != nil {
+		return m.EnqueueFunc(userId, event, payload)
+	}
+	return 0, nil
+}
+
+func (m *MockWebhookModel) GetDeliveries(userId int, limit int) ([]*models.WebhookDelivery, error) {
+	if m.GetDeliveriesFunc != nil {
+		return m.GetDeliveriesFunc(userId, limit)
+	}
+	return nil, nil
+}
+
+func (m *MockWebhookModel) Redeliver(id int, userId int) error {
+	if m.RedeliverFunc != nil {
+		return m.RedeliverFunc(id, userId)
+	}
+	return nil
+}
+
+func (m *MockWebhookModel) Due(limit int, lease time.Duration) ([]*models.WebhookDelivery, error) {
+	if m.DueFunc != nil {
+		return m.DueFunc(limit, lease)
+	}
+	return nil, nil
+}
+
+func (m *MockWebhookModel) Record(delivery *models.WebhookDelivery) error {
+	if m.RecordFunc != nil {
+		return m.RecordFunc(delivery)
+	}
+	return nil
+}
+
+// MockMailer is a mock implementation of mailer.Mailer for testing
+type MockMailer struct {
+	SendFunc func(msg mailer.Message) error
+}
+
+func (m *MockMailer) Send(msg mailer.Message) error {
+	if m.SendFunc != nil {
+		return m.SendFunc(msg)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+// Package totp implements the time-based one-time passwords of RFC 6238
+// that authenticator apps show. It uses the parameters every app supports:
+// HMAC-SHA1, six digits and a period of 30 seconds.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	Digits = 6
+	Period = 30 * time.Second
+	// SecretSize is the length of secrets in bytes, as RFC 4226 recommends.
+	SecretSize = 20
+	// Skew is how many periods a code may be off, for phones whose clock is
+	// not quite right and codes typed just before they change.
+	Skew = 1
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NewSecret returns a random secret.
+func NewSecret() []byte {
+	secret := make([]byte, SecretSize)
+	_, _ = rand.Read(secret)
+	return secret
+}
+
+// Encode returns a secret in base32, the form users type into their app.
+func Encode(secret []byte) string {
+	return encoding.EncodeToString(secret)
+}
+
+// URI returns the otpauth URI of a secret that authenticator apps read from
+// a QR code.
+func URI(issuer, account string, secret []byte) string {
+	query := url.Values{
+		"secret": {Encode(secret)},
+		"issuer": {issuer},
+	}
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(account)
+
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
+
+// Counter is the number of the period that t falls into.
+func Counter(t time.Time) int64 {
+	return t.Unix() / int64(Period/time.Second)
+}
+
+// Code returns the code of a secret for a counter as defined by RFC 4226.
+func Code(secret []byte, counter int64) string {
+	mac := hmac.New(sha1.New, secret)
+	_ = binary.Write(mac, binary.BigEndian, counter)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%0*d", Digits, value%1_000_000)
+}
+
+// Validate checks a code against the periods around t. It returns the
+// counter of the period the code belongs to, so that callers can refuse a
+// code that was used before.
+func Validate(secret []byte, code string, t time.Time) (int64, bool) {
+	code = strings.ReplaceAll(code, " ", "")
+	if len(code) != Digits {
+		return 0, false
+	}
+
+	now := Counter(t)
+	for counter := now - Skew; counter <= now+Skew; counter++ {
+		if subtle.ConstantTimeCompare([]byte(Code(secret, counter)), []byte(code)) == 1 {
+			return counter, true
+		}
+	}
+
+	return 0, false
+}
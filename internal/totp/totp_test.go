@@ -0,0 +1,86 @@
+package totp
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// rfcSecret is the SHA-1 secret of the test vectors of RFC 4226 and 6238.
+var rfcSecret = []byte("12345678901234567890")
+
+func TestCode(t *testing.T) {
+	// RFC 4226, appendix D
+	hotp := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+	for counter, expected := range hotp {
+		assert.Equal(t, expected, Code(rfcSecret, int64(counter)))
+	}
+
+	// RFC 6238, appendix B, with the last six of the eight digits
+	tests := []struct {
+		unix     int64
+		expected string
+	}{
+		{unix: 59, expected: "287082"},
+		{unix: 1111111109, expected: "081804"},
+		{unix: 1111111111, expected: "050471"},
+		{unix: 1234567890, expected: "005924"},
+		{unix: 2000000000, expected: "279037"},
+		{unix: 20000000000, expected: "353130"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, Code(rfcSecret, Counter(time.Unix(tt.unix, 0))), "time %d", tt.unix)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+
+	tests := []struct {
+		name            string
+		code            string
+		expectedCounter int64
+		expectedOk      bool
+	}{
+		{name: "current code", code: "050471", expectedCounter: Counter(now), expectedOk: true},
+		{name: "code with a space", code: "050 471", expectedCounter: Counter(now), expectedOk: true},
+		{name: "previous code", code: Code(rfcSecret, Counter(now)-1), expectedCounter: Counter(now) - 1, expectedOk: true},
+		{name: "next code", code: Code(rfcSecret, Counter(now)+1), expectedCounter: Counter(now) + 1, expectedOk: true},
+		{name: "code too old", code: Code(rfcSecret, Counter(now)-2)},
+		{name: "wrong code", code: "123456"},
+		{name: "too short", code: "05047"},
+		{name: "empty", code: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counter, ok := Validate(rfcSecret, tt.code, now)
+
+			assert.Equal(t, tt.expectedOk, ok)
+			assert.Equal(t, tt.expectedCounter, counter)
+		})
+	}
+}
+
+func TestNewSecret(t *testing.T) {
+	secret := NewSecret()
+
+	assert.Len(t, secret, SecretSize)
+	assert.NotEqual(t, secret, NewSecret())
+	assert.Len(t, Encode(secret), 32, "20 bytes are 32 base32 characters without padding")
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("SwimMate", "jane doe", rfcSecret)
+
+	parsed, err := url.Parse(uri)
+	assert.NoError(t, err)
+	assert.Equal(t, "otpauth", parsed.Scheme)
+	assert.Equal(t, "totp", parsed.Host)
+	assert.Equal(t, "/SwimMate:jane doe", parsed.Path)
+	assert.Equal(t, "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", parsed.Query().Get("secret"))
+	assert.Equal(t, "SwimMate", parsed.Query().Get("issuer"))
+}
@@ -0,0 +1,269 @@
+// Package webhooks sends the deliveries of webhooks. Payloads are signed with
+// HMAC-SHA256 using the secret of the webhook, and failed deliveries are sent
+// again with exponential backoff until they run out of attempts.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/rockstaedt/swimmate/internal/models"
+)
+
+const (
+	// SignatureHeader holds the signature of the payload, "sha256=" followed
+	// by the hex encoded HMAC-SHA256 of the body with the secret.
+	SignatureHeader = "X-SwimMate-Signature"
+	EventHeader     = "X-SwimMate-Event"
+	// DeliveryHeader holds the id of the delivery, which stays the same when
+	// a delivery is retried.
+	DeliveryHeader = "X-SwimMate-Delivery"
+)
+
+const (
+	// MaxAttempts is how often a delivery is sent before it fails. With the
+	// backoff, the last attempt is about an hour after the first.
+	MaxAttempts = 8
+	// Timeout is how long a receiver may take to answer.
+	Timeout = 10 * time.Second
+	// firstRetry is the wait after the first failed attempt, it doubles with
+	// every further one.
+	firstRetry = 30 * time.Second
+	// batchSize deliveries are claimed at once, for the time of the lease.
+	batchSize = 10
+	lease     = 5 * time.Minute
+)
+
+// ErrPrivateAddress is returned for deliveries to an address that is not
+// public, such as the host SwimMate runs on or its private network.
+var ErrPrivateAddress = errors.New("webhooks: address is not public")
+
+// NewClient returns the client to send deliveries with. Webhook URLs come
+// from users, so it only connects to public addresses. The address is checked
+// when connecting, after the name of the URL has been resolved, so names that
+// resolve to a private address are refused as well. Redirects are not
+// followed; the redirect itself is the response of the delivery.
+func NewClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: Timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !isPublic(addrPort.Addr()) {
+				return fmt.Errorf("%w: %s", ErrPrivateAddress, addrPort.Addr())
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would be connected to instead of the receiver, which leaves the
+	// address of the receiver unchecked
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   Timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// translatedPrefixes are ranges of addresses that look global but lead to
+// addresses behind a NAT or carry an IPv4 address that may be private.
+var translatedPrefixes = []netip.Prefix{
+	// Shared address space of carrier-grade NAT
+	netip.MustParsePrefix("100.64.0.0/10"),
+	// NAT64
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+	// 6to4
+	netip.MustParsePrefix("2002::/16"),
+	// Teredo
+	netip.MustParsePrefix("2001::/32"),
+}
+
+// isPublic reports whether deliveries may be sent to addr. Loopback, private,
+// link-local, unique local and unspecified addresses are not public, nor are
+// the addresses of carrier-grade NAT and of the IPv6 transition mechanisms
+// that tunnel to an IPv4 address.
+func isPublic(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range translatedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// Sign returns the signature of a payload for the SignatureHeader.
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is the signature of payload with secret,
+// which receivers use to check that a delivery comes from SwimMate.
+func Verify(secret string, payload []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, payload)), []byte(signature))
+}
+
+// Backoff is the wait before the next attempt of a delivery that failed the
+// given number of times.
+func Backoff(attempts int) time.Duration {
+	if attempts < 1 {
+		return 0
+	}
+	return firstRetry << (attempts - 1)
+}
+
+// Dispatcher sends the deliveries that are due. Several dispatchers may share
+// a database, since deliveries are claimed before they are sent.
+type Dispatcher struct {
+	webhooks models.WebhookModel
+	client   *http.Client
+	logger   *slog.Logger
+	wake     chan struct{}
+	now      func() time.Time
+}
+
+func NewDispatcher(webhooks models.WebhookModel, client *http.Client, logger *slog.Logger) *Dispatcher {
+	return &Dispatcher{
+		webhooks: webhooks,
+		client:   client,
+		logger:   logger,
+		wake:     make(chan struct{}, 1),
+		now:      time.Now,
+	}
+}
+
+// Notify makes Run send the due deliveries right away instead of at its next
+// check. It does not block.
+func (d *Dispatcher) Notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+		// A wake up is pending already
+	}
+}
+
+// Run sends the due deliveries every interval and whenever Notify is called,
+// until ctx is done.
+func (d *Dispatcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		d.DispatchDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// DispatchDue sends the deliveries that are due and returns how many were
+// sent, successfully or not.
+func (d *Dispatcher) DispatchDue(ctx context.Context) int {
+	sent := 0
+	for ctx.Err() == nil {
+		deliveries, err := d.webhooks.Due(batchSize, lease)
+		if err != nil {
+			d.logger.Error("claiming webhook deliveries", "error", err)
+			return sent
+		}
+
+		for _, delivery := range deliveries {
+			d.send(ctx, delivery)
+
+			err = d.webhooks.Record(delivery)
+			if err != nil {
+				// The lease runs out, so the delivery is sent again later
+				d.logger.Error("recording webhook delivery", "delivery", delivery.Id, "error", err)
+			}
+			sent++
+		}
+
+		if len(deliveries) < batchSize {
+			break
+		}
+	}
+	return sent
+}
+
+// send posts the payload of a delivery to its webhook and updates the
+// delivery with the outcome. Responses with a 2xx status are successful.
+func (d *Dispatcher) send(ctx context.Context, delivery *models.WebhookDelivery) {
+	delivery.Attempts++
+	delivery.StatusCode = 0
+	delivery.Error = ""
+
+	err := d.post(ctx, delivery)
+	if err != nil {
+		delivery.Error = err.Error()
+	} else if delivery.StatusCode < 200 || delivery.StatusCode > 299 {
+		delivery.Error = fmt.Sprintf("unexpected status %d", delivery.StatusCode)
+	} else {
+		delivery.State = models.DeliveryDelivered
+		return
+	}
+
+	if delivery.Attempts >= MaxAttempts {
+		delivery.State = models.DeliveryFailed
+		d.logger.Warn("webhook delivery failed", "delivery", delivery.Id, "url", delivery.URL, "error", delivery.Error)
+		return
+	}
+	delivery.State = models.DeliveryPending
+	delivery.NextAttempt = d.now().Add(Backoff(delivery.Attempts))
+}
+
+func (d *Dispatcher) post(ctx context.Context, delivery *models.WebhookDelivery) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "SwimMate-Webhook")
+	req.Header.Set(EventHeader, string(delivery.Event))
+	req.Header.Set(DeliveryHeader, strconv.Itoa(delivery.Id))
+	req.Header.Set(SignatureHeader, Sign(delivery.Secret, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	// Reading the body lets the connection be reused, it is not kept
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	delivery.StatusCode = resp.StatusCode
+
+	return nil
+}
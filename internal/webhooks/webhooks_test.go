@@ -0,0 +1,345 @@
+package webhooks
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/rockstaedt/swimmate/internal/models"
+	"github.com/rockstaedt/swimmate/internal/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSign(t *testing.T) {
+	// echo -n '{"event":"swim.created"}' | openssl dgst -sha256 -hmac SECRET
+	signature := Sign("SECRET", []byte(`{"event":"swim.created"}`))
+
+	assert.Equal(t, "sha256=91eccd0a411a71487c9e8013d99b7a0479c0f4cd590fb194c353dc32903d547e", signature)
+}
+
+func TestVerify(t *testing.T) {
+	payload := []byte(`{"event":"swim.created"}`)
+	signature := Sign("SECRET", payload)
+
+	assert.True(t, Verify("SECRET", payload, signature))
+	assert.False(t, Verify("OTHER", payload, signature), "another secret")
+	assert.False(t, Verify("SECRET", []byte(`{"event":"swim.deleted"}`), signature), "another payload")
+	assert.False(t, Verify("SECRET", payload, ""))
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, 30*time.Second, Backoff(1))
+	assert.Equal(t, time.Minute, Backoff(2))
+	assert.Equal(t, 2*time.Minute, Backoff(3))
+	assert.Equal(t, 32*time.Minute, Backoff(MaxAttempts-1))
+
+	var total time.Duration
+	for attempts := 1; attempts < MaxAttempts; attempts++ {
+		total += Backoff(attempts)
+	}
+	assert.InDelta(t, time.Hour, total, float64(5*time.Minute), "the last attempt is about an hour after the first")
+}
+
+func TestDispatchDue(t *testing.T) {
+	now := time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name             string
+		status           int
+		attempts         int
+		expectedState    models.DeliveryState
+		expectedStatus   int
+		expectedError    string
+		expectedNext     time.Time
+		expectedAttempts int
+	}{
+		{
+			name:             "delivered",
+			status:           http.StatusNoContent,
+			expectedState:    models.DeliveryDelivered,
+			expectedStatus:   http.StatusNoContent,
+			expectedAttempts: 1,
+		},
+		{
+			name:             "retried after an error of the receiver",
+			status:           http.StatusInternalServerError,
+			attempts:         2,
+			expectedState:    models.DeliveryPending,
+			expectedStatus:   http.StatusInternalServerError,
+			expectedError:    "unexpected status 500",
+			expectedNext:     now.Add(2 * time.Minute),
+			expectedAttempts: 3,
+		},
+		{
+			name:             "failed after the last attempt",
+			status:           http.StatusGone,
+			attempts:         MaxAttempts - 1,
+			expectedState:    models.DeliveryFailed,
+			expectedStatus:   http.StatusGone,
+			expectedError:    "unexpected status 410",
+			expectedAttempts: MaxAttempts,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload := []byte(`{"event":"swim.created","swim_id":42}`)
+			var received *http.Request
+			var body []byte
+			receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				received = r
+				body, _ = io.ReadAll(r.Body)
+				w.WriteHeader(tt.status)
+			}))
+			defer receiver.Close()
+
+			var recorded []*models.WebhookDelivery
+			claimed := false
+			store := &testutils.MockWebhookModel{
+				DueFunc: func(limit int, lease time.Duration) ([]*models.WebhookDelivery, error) {
+					if claimed {
+						return nil, nil
+					}
+					claimed = true
+					return []*models.WebhookDelivery{{
+						Id:       7,
+						URL:      receiver.URL + "/hook",
+						Secret:   "SECRET",
+						Event:    models.EventSwimCreated,
+						Payload:  payload,
+						State:    models.DeliveryPending,
+						Attempts: tt.attempts,
+					}}, nil
+				},
+				RecordFunc: func(delivery *models.WebhookDelivery) error {
+					recorded = append(recorded, delivery)
+					return nil
+				},
+			}
+			dispatcher := NewDispatcher(store, receiver.Client(), testutils.NewTestLogger())
+			dispatcher.now = func() time.Time { return now }
+
+			sent := dispatcher.DispatchDue(context.Background())
+
+			assert.Equal(t, 1, sent)
+			if assert.NotNil(t, received) {
+				assert.Equal(t, http.MethodPost, received.Method)
+				assert.Equal(t, "/hook", received.URL.Path)
+				assert.Equal(t, "application/json", received.Header.Get("Content-Type"))
+				assert.Equal(t, "swim.created", received.Header.Get(EventHeader))
+				assert.Equal(t, "7", received.Header.Get(DeliveryHeader))
+				assert.True(t, Verify("SECRET", body, received.Header.Get(SignatureHeader)), "the payload is signed")
+				assert.Equal(t, payload, body)
+			}
+			if assert.Len(t, recorded, 1) {
+				assert.Equal(t, tt.expectedState, recorded[0].State)
+				assert.Equal(t, tt.expectedStatus, recorded[0].StatusCode)
+				assert.Equal(t, tt.expectedError, recorded[0].Error)
+				assert.Equal(t, tt.expectedNext, recorded[0].NextAttempt)
+				assert.Equal(t, tt.expectedAttempts, recorded[0].Attempts)
+			}
+		})
+	}
+}
+
+func TestDispatchDueUnreachable(t *testing.T) {
+	receiver := httptest.NewServer(http.NotFoundHandler())
+	url := receiver.URL
+	receiver.Close()
+
+	var recorded *models.WebhookDelivery
+	claimed := false
+	store := &testutils.MockWebhookModel{
+		DueFunc: func(limit int, lease time.Duration) ([]*models.WebhookDelivery, error) {
+			if claimed {
+				return nil, nil
+			}
+			claimed = true
+			return []*models.WebhookDelivery{{Id: 7, URL: url, Secret: "SECRET", Event: models.EventSwimDeleted, Payload: []byte(`{}`)}}, nil
+		},
+		RecordFunc: func(delivery *models.WebhookDelivery) error {
+			recorded = delivery
+			return nil
+		},
+	}
+
+	NewDispatcher(store, http.DefaultClient, testutils.NewTestLogger()).DispatchDue(context.Background())
+
+	if assert.NotNil(t, recorded) {
+		assert.Equal(t, models.DeliveryPending, recorded.State)
+		assert.Zero(t, recorded.StatusCode, "there was no response")
+		assert.Contains(t, recorded.Error, "connection refused")
+		assert.Equal(t, 1, recorded.Attempts)
+	}
+}
+
+func TestNewClientRefusesPrivateAddresses(t *testing.T) {
+	called := false
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer receiver.Close()
+
+	var recorded *models.WebhookDelivery
+	claimed := false
+	store := &testutils.MockWebhookModel{
+		DueFunc: func(limit int, lease time.Duration) ([]*models.WebhookDelivery, error) {
+			if claimed {
+				return nil, nil
+			}
+			claimed = true
+			return []*models.WebhookDelivery{{Id: 7, URL: receiver.URL, Payload: []byte(`{}`)}}, nil
+		},
+		RecordFunc: func(delivery *models.WebhookDelivery) error {
+			recorded = delivery
+			return nil
+		},
+	}
+
+	NewDispatcher(store, NewClient(), testutils.NewTestLogger()).DispatchDue(context.Background())
+
+	assert.False(t, called, "the receiver on 127.0.0.1 is not connected to")
+	if assert.NotNil(t, recorded) {
+		assert.Equal(t, models.DeliveryPending, recorded.State)
+		assert.Contains(t, recorded.Error, ErrPrivateAddress.Error())
+	}
+}
+
+func TestNewClientDoesNotFollowRedirects(t *testing.T) {
+	followed := false
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/internal" {
+			followed = true
+			return
+		}
+		http.Redirect(w, r, "/internal", http.StatusFound)
+	}))
+	defer receiver.Close()
+
+	// The transport of the test server reaches 127.0.0.1, the redirect policy
+	// is that of the client
+	client := NewClient()
+	client.Transport = receiver.Client().Transport
+
+	resp, err := client.Post(receiver.URL, "application/json", nil)
+
+	if assert.NoError(t, err) {
+		_ = resp.Body.Close()
+		assert.Equal(t, http.StatusFound, resp.StatusCode)
+	}
+	assert.False(t, followed)
+}
+
+func TestIsPublic(t *testing.T) {
+	tests := []struct {
+		addr     string
+		expected bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"::ffff:127.0.0.1", false},
+		{"10.0.0.1", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"224.0.0.1", false},
+		{"100.64.0.1", false},
+		{"100.127.255.254", false},
+		{"100.128.0.1", true},
+		{"64:ff9b::7f00:1", false},
+		{"64:ff9b:1::a00:1", false},
+		{"2002:7f00:1::1", false},
+		{"2001:0:4136:e378::1", false},
+		{"2001:4860:4860::8888", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			assert.Equal(t, tt.expected, isPublic(netip.MustParseAddr(tt.addr)))
+		})
+	}
+}
+
+func TestDispatchDueInBatches(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+
+	pending := 25
+	recorded := 0
+	store := &testutils.MockWebhookModel{
+		DueFunc: func(limit int, lease time.Duration) ([]*models.WebhookDelivery, error) {
+			var deliveries []*models.WebhookDelivery
+			for len(deliveries) < limit && pending > 0 {
+				deliveries = append(deliveries, &models.WebhookDelivery{Id: pending, URL: receiver.URL, Payload: []byte(`{}`)})
+				pending--
+			}
+			return deliveries, nil
+		},
+		RecordFunc: func(delivery *models.WebhookDelivery) error {
+			recorded++
+			return nil
+		},
+	}
+
+	sent := NewDispatcher(store, receiver.Client(), testutils.NewTestLogger()).DispatchDue(context.Background())
+
+	assert.Equal(t, 25, sent)
+	assert.Equal(t, 25, recorded)
+	assert.Zero(t, pending, "all due deliveries are sent, not just the first batch")
+}
+
+func TestRun(t *testing.T) {
+	delivered := make(chan struct{}, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		delivered <- struct{}{}
+	}))
+	defer receiver.Close()
+
+	queued := make(chan *models.WebhookDelivery, 1)
+	store := &testutils.MockWebhookModel{
+		DueFunc: func(limit int, lease time.Duration) ([]*models.WebhookDelivery, error) {
+			select {
+			case delivery := <-queued:
+				return []*models.WebhookDelivery{delivery}, nil
+			default:
+				return nil, nil
+			}
+		},
+	}
+	dispatcher := NewDispatcher(store, receiver.Client(), testutils.NewTestLogger())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		dispatcher.Run(ctx, time.Hour)
+		close(done)
+	}()
+
+	queued <- &models.WebhookDelivery{Id: 1, URL: receiver.URL, Payload: []byte(`{}`)}
+	dispatcher.Notify()
+
+	select {
+	case <-delivered:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Notify did not wake the dispatcher")
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not stop with its context")
+	}
+}
@@ -0,0 +1,2 @@
+-- Optional swim duration in seconds, used to derive the pace per 100 m.
+ALTER TABLE swims ADD COLUMN duration_s integer CHECK (duration_s > 0);
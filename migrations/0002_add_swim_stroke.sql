@@ -0,0 +1,4 @@
+-- Predominant stroke of a swim. Existing swims are treated as freestyle.
+CREATE TYPE swim_stroke AS ENUM ('freestyle', 'breaststroke', 'backstroke', 'butterfly', 'mixed');
+
+ALTER TABLE swims ADD COLUMN stroke swim_stroke NOT NULL DEFAULT 'freestyle';
@@ -0,0 +1,8 @@
+-- Pool length and lap count of a swim as entered. distance_m stays the
+-- canonical distance and is computed from the laps for lap-based entries.
+ALTER TABLE swims
+    ADD COLUMN pool_length integer CHECK (pool_length > 0),
+    ADD COLUMN pool_unit varchar(2) CHECK (pool_unit IN ('m', 'yd')),
+    ADD COLUMN laps integer CHECK (laps > 0),
+    ADD CONSTRAINT swims_pool_complete CHECK ((pool_length IS NULL) = (pool_unit IS NULL)),
+    ADD CONSTRAINT swims_laps_need_pool CHECK (laps IS NULL OR pool_length IS NOT NULL);
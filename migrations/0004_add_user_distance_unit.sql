@@ -0,0 +1,3 @@
+-- Unit in which a user wants to see and enter distances. Swims are still
+-- stored in meters.
+ALTER TABLE users ADD COLUMN distance_unit varchar(2) NOT NULL DEFAULT 'm' CHECK (distance_unit IN ('m', 'yd'));
@@ -0,0 +1,13 @@
+-- Ordered sets of a structured swim, e.g. "8x100 on 1:45". distance_m is the
+-- distance of a single repetition; swims.distance_m stays the swim total.
+CREATE TABLE swim_sets (
+    id          bigserial PRIMARY KEY,
+    swim_id     bigint      NOT NULL REFERENCES swims (id) ON DELETE CASCADE,
+    position    integer     NOT NULL CHECK (position > 0),
+    repetitions integer     NOT NULL CHECK (repetitions > 0),
+    distance_m  integer     NOT NULL CHECK (distance_m > 0),
+    stroke      swim_stroke NOT NULL DEFAULT 'freestyle',
+    interval_s  integer CHECK (interval_s > 0),
+    rest_s      integer CHECK (rest_s > 0),
+    UNIQUE (swim_id, position)
+);
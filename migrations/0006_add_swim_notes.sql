@@ -0,0 +1,7 @@
+-- Free-text notes on a swim. notes_tsv backs the full-text search on the swims
+-- list; the 'simple' configuration keeps it independent of the note language.
+ALTER TABLE swims
+    ADD COLUMN notes text NOT NULL DEFAULT '',
+    ADD COLUMN notes_tsv tsvector GENERATED ALWAYS AS (to_tsvector('simple', notes)) STORED;
+
+CREATE INDEX idx_swims_notes_tsv ON swims USING gin (notes_tsv);
@@ -0,0 +1,16 @@
+-- User-defined tags such as "technique" or "open-water". Names are stored
+-- normalized (trimmed, lower case) so they are unique per user.
+CREATE TABLE tags (
+    id      bigserial PRIMARY KEY,
+    user_id integer     NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name    varchar(50) NOT NULL CHECK (name <> ''),
+    UNIQUE (user_id, name)
+);
+
+CREATE TABLE swim_tags (
+    swim_id bigint NOT NULL REFERENCES swims (id) ON DELETE CASCADE,
+    tag_id  bigint NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+    PRIMARY KEY (swim_id, tag_id)
+);
+
+CREATE INDEX idx_swim_tags_tag_id ON swim_tags (tag_id);
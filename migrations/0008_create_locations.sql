@@ -0,0 +1,16 @@
+-- Pools a user swims at. The pool length is optional, swims keep their own
+-- pool so that changing a location does not rewrite the history.
+CREATE TABLE locations (
+    id          bigserial PRIMARY KEY,
+    user_id     integer      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name        varchar(100) NOT NULL CHECK (name <> ''),
+    pool_length integer CHECK (pool_length > 0),
+    pool_unit   varchar(2) CHECK (pool_unit IN ('m', 'yd')),
+    indoor      boolean      NOT NULL DEFAULT true,
+    CONSTRAINT locations_pool_complete CHECK ((pool_length IS NULL) = (pool_unit IS NULL)),
+    UNIQUE (user_id, name)
+);
+
+ALTER TABLE swims ADD COLUMN location_id bigint REFERENCES locations (id) ON DELETE SET NULL;
+
+CREATE INDEX idx_swims_location_id ON swims (location_id);
@@ -0,0 +1,13 @@
+-- The three level assessment (0 bad, 1 neutral, 2 good) is replaced by two
+-- ratings: effort, the rate of perceived exertion (RPE) from 1 to 10, and
+-- feel, how the swim felt from 1 (terrible) to 5 (great).
+ALTER TABLE swims
+    ADD COLUMN effort integer CHECK (effort BETWEEN 1 AND 10),
+    ADD COLUMN feel integer NOT NULL DEFAULT 3 CHECK (feel BETWEEN 1 AND 5);
+
+-- Existing assessments rated how a swim felt, so they map onto the feel scale
+-- with matching labels: bad 2, neutral 3 (okay), good 4. The effort was never
+-- recorded and stays NULL.
+UPDATE swims SET feel = LEAST(GREATEST(assessment, 0), 2) + 2;
+
+ALTER TABLE swims DROP COLUMN assessment;
@@ -0,0 +1,6 @@
+-- Activity a swim was imported from, e.g. a session of a FIT file. Swims
+-- entered by hand have none. The constraint keeps a user from importing the
+-- same activity twice.
+ALTER TABLE swims ADD COLUMN source varchar(200);
+
+ALTER TABLE swims ADD CONSTRAINT swims_user_id_source_key UNIQUE (user_id, source);
@@ -0,0 +1,17 @@
+-- Progress of archive imports, which are read in batches over several
+-- requests. The uploaded archive is kept at file until the job is done, so an
+-- interrupted import can be continued where it stopped.
+CREATE TABLE import_jobs (
+    id        bigserial PRIMARY KEY,
+    user_id   integer     NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    file      text        NOT NULL,
+    total     integer     NOT NULL CHECK (total >= 0),
+    processed integer     NOT NULL DEFAULT 0 CHECK (processed BETWEEN 0 AND total),
+    imported  integer     NOT NULL DEFAULT 0,
+    skipped   integer     NOT NULL DEFAULT 0,
+    failed    integer     NOT NULL DEFAULT 0,
+    done      boolean     NOT NULL DEFAULT false,
+    created   timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_import_jobs_user_id ON import_jobs (user_id);
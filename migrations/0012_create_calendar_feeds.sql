@@ -0,0 +1,8 @@
+-- Secret tokens of the calendar feeds of users. Calendar apps cannot log in,
+-- so the feed URL carries the token. Only its SHA-256 hash is stored, a user
+-- has at most one token and replaces it to revoke the old URL.
+CREATE TABLE calendar_feeds (
+    user_id    integer     PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    token_hash bytea       NOT NULL UNIQUE,
+    created    timestamptz NOT NULL DEFAULT now()
+);
@@ -0,0 +1,13 @@
+-- Personal access tokens, which scripts and apps send as Bearer tokens to
+-- use the API without a browser session. Only the SHA-256 hash of a token is
+-- stored, the scopes limit what it may do.
+CREATE TABLE access_tokens (
+    id         bigserial PRIMARY KEY,
+    user_id    integer      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name       varchar(100) NOT NULL CHECK (name <> ''),
+    token_hash bytea        NOT NULL UNIQUE,
+    scopes     text[]       NOT NULL CHECK (cardinality(scopes) > 0),
+    last_used  timestamptz,
+    created    timestamptz  NOT NULL DEFAULT now(),
+    UNIQUE (user_id, name)
+);
@@ -0,0 +1,29 @@
+-- Webhook subscriptions of users, which are called when swims are created,
+-- changed or deleted. The secret signs the payloads, so it is stored as is.
+CREATE TABLE webhooks (
+    id      bigserial PRIMARY KEY,
+    user_id integer       NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    url     varchar(2000) NOT NULL CHECK (url <> ''),
+    secret  text          NOT NULL,
+    created timestamptz   NOT NULL DEFAULT now(),
+    UNIQUE (user_id, url)
+);
+
+-- Each call of a webhook, kept as the delivery log. Pending deliveries are
+-- sent by the dispatcher of the web app once next_attempt has passed.
+CREATE TABLE webhook_deliveries (
+    id           bigserial PRIMARY KEY,
+    webhook_id   bigint       NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+    event        varchar(50)  NOT NULL,
+    payload      jsonb        NOT NULL,
+    state        varchar(10)  NOT NULL DEFAULT 'pending' CHECK (state IN ('pending', 'delivered', 'failed')),
+    attempts     integer      NOT NULL DEFAULT 0,
+    status_code  integer,
+    error        text         NOT NULL DEFAULT '',
+    next_attempt timestamptz  NOT NULL DEFAULT now(),
+    created      timestamptz  NOT NULL DEFAULT now(),
+    updated      timestamptz  NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries (next_attempt) WHERE state = 'pending';
+CREATE INDEX idx_webhook_deliveries_webhook_id ON webhook_deliveries (webhook_id);
@@ -0,0 +1,16 @@
+-- Sign up checks that usernames and email addresses are not taken, ignoring
+-- case. Accounts that share one must be renamed before this migration runs.
+-- Accounts created before sign up may have no email address.
+CREATE UNIQUE INDEX users_username_lower_key ON users (lower(username));
+CREATE UNIQUE INDEX users_email_lower_key ON users (lower(email)) WHERE email <> '';
+
+-- Invitations to sign up while registration is invite-only. Only the SHA-256
+-- hash of a code is stored, a code can be used once until it expires.
+CREATE TABLE invites (
+    id        bigserial PRIMARY KEY,
+    user_id   integer     NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    code_hash bytea       NOT NULL UNIQUE,
+    expires   timestamptz NOT NULL,
+    used      timestamptz,
+    created   timestamptz NOT NULL DEFAULT now()
+);
@@ -0,0 +1,12 @@
+-- Tokens of the links that reset a forgotten password. Only the SHA-256 hash
+-- of a token is stored, a token can be used once until it expires.
+CREATE TABLE password_resets (
+    id         bigserial PRIMARY KEY,
+    user_id    integer     NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    token_hash bytea       NOT NULL UNIQUE,
+    expires    timestamptz NOT NULL,
+    used       timestamptz,
+    created    timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_password_resets_user_id ON password_resets (user_id);
@@ -0,0 +1,25 @@
+-- Optional two-factor authentication with the time-based codes of an
+-- authenticator app. The secret is encrypted with the TWO_FACTOR_KEY of the
+-- app, enabled stays NULL until the user confirmed the setup with a code.
+-- last_counter is the period of the last accepted code, so that a code
+-- cannot be used twice. failed_attempts counts the wrong codes since the last
+-- right one; once there are too many, no code is accepted until locked_until.
+CREATE TABLE two_factor (
+    user_id         integer     PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    secret          bytea       NOT NULL,
+    enabled         timestamptz,
+    last_counter    bigint      NOT NULL DEFAULT 0,
+    failed_attempts integer     NOT NULL DEFAULT 0,
+    locked_until    timestamptz,
+    created         timestamptz NOT NULL DEFAULT now()
+);
+
+-- One-time codes for logging in without the authenticator app. Only the
+-- SHA-256 hash of a code is stored.
+CREATE TABLE recovery_codes (
+    id        bigserial PRIMARY KEY,
+    user_id   integer     NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    code_hash bytea       NOT NULL,
+    used      timestamptz,
+    UNIQUE (user_id, code_hash)
+);